@@ -0,0 +1,127 @@
+package access
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	MaxFlag = tool.StringFlag{
+		Name:  "max",
+		Usage: "The longest access that may be requested (e.g. 8h)",
+	}
+
+	ApproversCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "approvers",
+			Usage: "approvers <item> [<group>] [--max <duration>]",
+			Info:  "Configure who may approve access to an item",
+			Help: `
+Configures the approvers of an item.  Anyone with sudo on the item
+may always approve its requests.  The members of the given group may
+also approve them.  Without a group, the current configuration is
+shown.
+
+Approvers encrypt the item's key for the requester, so the group
+must itself be a member of the item:
+
+    $ stash secret acl grant prod/db.pass group://dba
+    $ stash access approvers prod/db.pass dba --max 8h
+
+Requests may not exceed the maximum duration (24h by default).
+`,
+			Flags: tool.NewFlags(MaxFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 1 || len(c.Args()) > 2 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item and optionally a group")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				prev, ok, err := sdk.LoadApprovers(s, orgId, policyId)
+				if err != nil {
+					return
+				}
+				if !ok {
+					prev = access.NewApprovers(orgId, policyId, uuid.Nil, 0)
+					prev.Version = -1
+				}
+
+				if len(c.Args()) == 1 && c.String(MaxFlag.Name) == "" {
+					return displayApprovers(env, s, itemRef, prev)
+				}
+
+				groupId := prev.GroupId
+				if len(c.Args()) == 2 {
+					groupId, err = policies.GroupType.GetMemberId(s, orgId, c.Args().Get(1))
+					if err != nil {
+						return
+					}
+				}
+
+				max := prev.MaxDuration
+				if str := c.String(MaxFlag.Name); str != "" {
+					if max, err = time.ParseDuration(str); err != nil {
+						err = errors.Wrapf(errs.ArgError, "Invalid duration [%v]", str)
+						return
+					}
+				}
+
+				err = sdk.SaveApprovers(s, prev.Update(func(a *access.Approvers) {
+					a.GroupId = groupId
+					a.MaxDuration = max
+				}))
+				if err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nUpdated the approvers of [%v]\n", itemRef)
+				return
+			},
+		})
+)
+
+func displayApprovers(env tool.Environment, s session.Session, item policies.ItemRef, a access.Approvers) (err error) {
+	group := "(none)"
+	if a.GroupId != uuid.Nil {
+		g, err := policies.RequireGroupById(s, a.OrgId, a.GroupId)
+		if err != nil {
+			return err
+		}
+		group = g.Name
+	}
+
+	_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+		"\nApprovers of [%v]\n\n    Group:        %v\n    Max Duration: %v\n", item, group, a.Max())
+	return
+}
@@ -0,0 +1,410 @@
+package access
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/accounts"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	BreakGlassTools = tool.NewGroup(
+		tool.GroupDef{
+			Name: "breakglass",
+			Info: "Emergency access when no approver is reachable",
+		},
+		BreakGlassSetupCommand,
+		BreakGlassRequestCommand,
+		BreakGlassApproveCommand,
+		BreakGlassUnlockCommand,
+		BreakGlassLsCommand,
+	)
+)
+
+var (
+	QuorumFlag = tool.StringFlag{
+		Name:    "quorum",
+		Usage:   "How many custodians must hand over their shares",
+		Default: "2",
+	}
+
+	WindowFlag = tool.StringFlag{
+		Name:    "window",
+		Usage:   "How long an unlock remains open (e.g. 1h)",
+		Default: access.DefaultUnlockWindow.String(),
+	}
+
+	GrantFlag = tool.StringFlag{
+		Name:    "grant",
+		Usage:   "How long the emergency membership lasts (e.g. 4h)",
+		Default: access.DefaultEmergencyGrant.String(),
+	}
+
+	ActionsFlag = tool.StringsFlag{
+		Name:  "action",
+		Usage: "An action granted by the emergency membership.  May be repeated",
+	}
+
+	RemoveFlag = tool.BoolFlag{
+		Name:  "remove",
+		Usage: "Remove the escrow of the item",
+	}
+
+	BreakGlassSetupCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "setup",
+			Usage: "setup <item> <custodian>+ [--quorum <n>] [--window <duration>] [--grant <duration>] [--action <action>]*",
+			Info:  "Split the key of an item amongst custodians",
+			Help: `
+Splits the key of an item amongst custodians.  You must have sudo on
+the item.  This covers the case where every owner of the item is
+unreachable: anyone in the organization may request an unlock, and
+once a quorum of custodians have handed over their shares, the
+requester is granted a temporary membership.
+
+The key is split locally and each share is encrypted for its
+custodian.  The server never sees the key, and no single custodian
+is able to recover it.  Every step is audited, and each claim is
+published as a policy.breakglass webhook event.
+
+After an emergency, the membership expires on its own and expired
+memberships are unusable.  However, the requester has seen the key
+of the item, so the claim marks the item's policy as due a rekey
+and retires its escrow.  The owners should revoke the requester
+with --rekey, which replaces the key and clears the mark, and then
+run setup again.
+
+Examples:
+
+    $ stash access breakglass setup prod/db.pass bob carol dave --quorum 2
+    $ stash access breakglass setup prod/db.pass --remove
+
+    $ stash secret acl revoke prod/db.pass alice --rekey
+`,
+			Flags: tool.NewFlags(QuorumFlag, WindowFlag, GrantFlag, ActionsFlag, RemoveFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				if c.Bool(RemoveFlag.Name) {
+					if err = sdk.RemoveEscrow(s, orgId, policyId); err != nil {
+						return
+					}
+
+					_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nRemoved the escrow of [%v]\n", itemRef)
+					return
+				}
+
+				if len(c.Args()) < 2 {
+					err = errors.Wrapf(errs.ArgError, "Must provide at least [%v] custodians", access.MinQuorum)
+					return
+				}
+
+				var quorum int
+				if _, err = fmt.Sscanf(c.String(QuorumFlag.Name), "%d", &quorum); err != nil {
+					err = errors.Wrapf(errs.ArgError, "Invalid quorum [%v]", c.String(QuorumFlag.Name))
+					return
+				}
+
+				window, err := time.ParseDuration(c.String(WindowFlag.Name))
+				if err != nil {
+					err = errors.Wrapf(errs.ArgError, "Invalid window [%v]", c.String(WindowFlag.Name))
+					return
+				}
+
+				grant, err := time.ParseDuration(c.String(GrantFlag.Name))
+				if err != nil {
+					err = errors.Wrapf(errs.ArgError, "Invalid grant [%v]", c.String(GrantFlag.Name))
+					return
+				}
+
+				actions := itemRef.Type.DefaultActions()
+				if strs := c.StringSlice(ActionsFlag.Name); len(strs) > 0 {
+					actions = nil
+					for _, str := range strs {
+						act, err := itemRef.ParseAction(str)
+						if err != nil {
+							return err
+						}
+						actions = append(actions, act)
+					}
+				}
+
+				var custodianIds []uuid.UUID
+				for _, name := range c.Args()[1:] {
+					id, err := policies.UserType.GetMemberId(s, orgId, name)
+					if err != nil {
+						return err
+					}
+					custodianIds = append(custodianIds, id)
+				}
+
+				if err = sdk.SetupEscrow(s, orgId, policyId, quorum, window, grant, custodianIds, actions...); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+					"\nSplit the key of [%v] amongst %v custodians (quorum of %v)\n", itemRef, len(custodianIds), quorum)
+				return
+			},
+		})
+
+	BreakGlassRequestCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "request",
+			Usage: "request <item> --reason <reason>",
+			Info:  "Request emergency access to an item",
+			Help: `
+Requests emergency access to an item.  The custodians and owners of
+the item are notified.  Once a quorum of custodians have handed over
+their shares, claim the access with 'stash access breakglass unlock'.
+
+Examples:
+
+    $ stash access breakglass request prod/db.pass --reason "Outage 1234"
+`,
+			Flags: tool.NewFlags(ReasonFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				unlock, err := sdk.RequestUnlock(s, orgId, policyId, itemRef.String(), c.String(ReasonFlag.Name))
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, unlockTemplate, tool.WithData(unlock))
+			},
+		})
+
+	BreakGlassApproveCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "approve",
+			Usage: "approve <unlock>",
+			Info:  "Hand over your share to an unlock",
+			Help: `
+Hands your share of an item's key over to the requester of an unlock.
+The share is encrypted for the requester.  You must be a custodian of
+the item.
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				unlockId, err := parseUnlockId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				unlock, err := sdk.RequireUnlock(s, orgId, unlockId)
+				if err != nil {
+					return
+				}
+
+				if err = sdk.SubmitShare(s, unlock); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+					"\nHanded over your share of [%v] (%v of %v)\n", unlock.Item, len(unlock.Shares)+1, unlock.Quorum)
+				return
+			},
+		})
+
+	BreakGlassUnlockCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "unlock",
+			Usage: "unlock <unlock>",
+			Info:  "Claim emergency access once a quorum is reached",
+			Help: `
+Combines the shares handed over by the custodians and grants you
+a temporary membership to the item.  An unlock may only be claimed
+once, and only by its requester.
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				unlockId, err := parseUnlockId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				unlock, err := sdk.RequireUnlock(s, orgId, unlockId)
+				if err != nil {
+					return
+				}
+
+				member, err := sdk.ClaimUnlock(s, unlock)
+				if err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+					"\nGranted emergency access to [%v] until %v\n", unlock.Item, member.Conditions.NotAfter.Local().Format(time.RFC1123))
+				return
+			},
+		})
+
+	BreakGlassLsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls [--all]",
+			Info:  "List break-glass unlocks",
+			Help: `
+Lists break-glass unlocks, newest first.  Only pending unlocks are
+shown by default.  Directors may see every unlock of the organization.
+Everyone else may only see their own.
+`,
+			Flags: tool.NewFlags(AllFlag).Add(tool.PageFlags...),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				var filter []func(*access.Filter)
+				if !c.Bool(AllFlag.Name) {
+					filter = append(filter, access.FilterByStatus(access.Pending))
+				}
+
+				unlocks, err := sdk.ListUnlocks(s, orgId, access.BuildFilter(filter...), tool.ParsePageOpts(c)...)
+				if err != nil {
+					return
+				}
+
+				var acctIds []uuid.UUID
+				for _, u := range unlocks {
+					acctIds = append(acctIds, u.RequesterId)
+				}
+
+				ids, err := accounts.ListIdentitiesByAccountIds(s, acctIds)
+				if err != nil {
+					return
+				}
+
+				displays := accounts.LookupDisplays(ids)
+				return tool.DisplayStdOut(env, unlockLsTemplate,
+					tool.WithFunc("account", func(id uuid.UUID) string {
+						if ident, ok := displays[id]; ok {
+							return auth.FormatFriendlyIdentity(ident.Id)
+						}
+						return id.String()
+					}),
+					tool.WithData(unlocks))
+			},
+		})
+)
+
+func parseUnlockId(c *cli.Context) (ret uuid.UUID, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.Wrapf(errs.ArgError, "Must provide an unlock id")
+		return
+	}
+
+	ret, err = uuid.FromString(c.Args().Get(0))
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid unlock id [%v]", c.Args().Get(0))
+	}
+	return
+}
+
+var (
+	unlockTemplate = `
+Requested emergency access to {{ .Item | info }}
+
+    Unlock:  {{ .Id }}
+    Quorum:  {{ .Quorum }}
+    Expires: {{ .Expires | date }}
+
+You will be notified once a quorum of custodians have handed over their shares.
+`
+
+	unlockLsTemplate = `
+Unlocks(Total={{ len . }}):
+
+      {{ "#/id" | col 36 | header }} {{ "#/requester" | col 24 | header }} {{ "#/status" | col 10 | header }} {{ "#/shares" | col 8 | header }} {{ "#/item" | header }}
+
+{{- range . }}
+    {{ "*" | item }} {{ .Id | printf "%v" | col 36 }} {{ .RequesterId | account | col 24 }} {{ .Status | printf "%v" | col 10 }} {{ printf "%v/%v" (len .Shares) .Quorum | col 8 }} {{ .Item }}
+{{- end }}
+`
+)
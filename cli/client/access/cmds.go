@@ -0,0 +1,34 @@
+package access
+
+import (
+	"github.com/cott-io/stash/lang/ref"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/sdk/policies"
+)
+
+var (
+	Commands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "access",
+			Info: "Request, approve and explain access",
+		},
+		RequestCommand,
+		LsCommand,
+		ApproveCommand,
+		DenyCommand,
+		ApproversCommand,
+		BreakGlassTools,
+		ExplainCommand,
+		SimulateTools,
+	)
+)
+
+// Parses an item, assuming it is a secret when no protocol is given.
+func parseItemRef(item string) (ret policies.ItemRef, err error) {
+	if ref.Pointer(item).Protocol() == "" {
+		item = ref.Pointer(item).SetProtocol("secret").Raw()
+	}
+
+	ret, err = policies.ParseItemRef(item)
+	return
+}
@@ -0,0 +1,116 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	CommentFlag = tool.StringFlag{
+		Name:  "comment",
+		Usage: "A comment for the requester",
+	}
+
+	ApproveCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "approve",
+			Usage: "approve <request> [--comment <comment>]",
+			Info:  "Approve an access request",
+			Help: `
+Approves an access request.  The requester is granted the requested
+actions until the requested duration elapses.  You must be able to
+access the item yourself, and may not approve your own requests.
+
+Examples:
+
+    $ stash access approve 5c2f8a4e-0c3b-11eb-adc1-0242ac120002
+`,
+			Flags: tool.NewFlags(CommentFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				requestId, err := parseRequestId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				req, err := sdk.RequireRequest(s, orgId, requestId)
+				if err != nil {
+					return
+				}
+
+				if err = sdk.ApproveRequest(s, req, c.String(CommentFlag.Name)); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+					"\nApproved access to [%v] for %v\n", req.Item, req.Duration)
+				return
+			},
+		})
+
+	DenyCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "deny",
+			Usage: "deny <request> [--comment <comment>]",
+			Info:  "Deny an access request",
+			Help: `
+Denies an access request.  Requesters may also deny their own
+requests in order to withdraw them.
+`,
+			Flags: tool.NewFlags(CommentFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				requestId, err := parseRequestId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				if err = sdk.DenyRequest(s, orgId, requestId, c.String(CommentFlag.Name)); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nDenied request [%v]\n", requestId)
+				return
+			},
+		})
+)
+
+func parseRequestId(c *cli.Context) (ret uuid.UUID, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.Wrapf(errs.ArgError, "Must provide a request id")
+		return
+	}
+
+	ret, err = uuid.FromString(c.Args().Get(0))
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid request id [%v]", c.Args().Get(0))
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package access
+
+import (
+	"strings"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	MemberFlag = tool.StringFlag{
+		Name:  "member",
+		Usage: "The member to explain (e.g. user://alice@example.com).  Defaults to you",
+	}
+
+	ExplainCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "explain",
+			Usage: "explain <item> [--member <member>]",
+			Info:  "Explain how a member holds or lacks each action",
+			Help: `
+Shows every path by which a member reaches an item: a direct
+membership, a membership through (possibly nested) groups, or
+a membership through a folder.  For each action of the item,
+the paths granting or denying it are listed, along with the
+condition that blocks any path that is unusable.  A deny on any
+usable path overrides every grant.  A member must also belong to
+the org for any path to be usable.
+
+Only the validity window of conditions is evaluated when
+explaining the access of another member, as their network,
+login and device are unknown.  Items without a protocol are
+assumed to be secrets.
+
+Examples:
+
+    $ stash access explain prod/db.pass
+    $ stash access explain prod/db.pass --member user://alice@example.com
+`,
+			Flags: tool.NewFlags(MemberFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				member, memberId := "you", s.AccountId()
+				if raw := c.String(MemberFlag.Name); raw != "" {
+					memberRef, err := policies.ParseMemberRef(raw)
+					if err != nil {
+						return err
+					}
+
+					if memberId, err = memberRef.GetMemberId(s, orgId); err != nil {
+						return err
+					}
+					member = memberRef.String()
+				}
+
+				exp, err := policies.ExplainPolicy(s, orgId, policyId, memberId)
+				if err != nil {
+					return
+				}
+
+				enabled := exp.Enabled()
+
+				var actions []explainedAction
+				for _, info := range itemRef.Type.AllActions() {
+					actions = append(actions, explainedAction{
+						Action: info.Action,
+						Held:   enabled.Holds(info.Action),
+						Paths:  exp.PathsTo(info.Action),
+						Denies: exp.PathsDenying(info.Action),
+					})
+				}
+
+				return tool.DisplayStdOut(env, explainTemplate,
+					tool.WithFunc("path", formatPath),
+					tool.WithFunc("kind", formatKind),
+					tool.WithData(struct {
+						Item    string
+						Member  string
+						Role    auth.Role
+						InOrg   bool
+						Actions []explainedAction
+					}{
+						itemRef.String(),
+						member,
+						exp.Role,
+						exp.Role >= auth.Member,
+						actions,
+					}))
+			},
+		})
+)
+
+type explainedAction struct {
+	Action policy.Action
+	Held   bool
+	Paths  []policy.Path
+	Denies []policy.Path
+}
+
+// Formats a path from the member outwards, as it is most natural to
+// read a member's access in that direction.
+func formatPath(p policy.Path) string {
+	all := make([]string, 0, len(p.Hops))
+	for i := len(p.Hops) - 1; i >= 0; i-- {
+		all = append(all, p.Hops[i].Format())
+	}
+	return strings.Join(all, " -> ")
+}
+
+// Describes the kind of membership through which a path enters its
+// policy.
+func formatKind(p policy.Path) string {
+	if len(p.Hops) == 0 {
+		return ""
+	}
+
+	switch p.Hops[0].MemberType {
+	default:
+		return "direct"
+	case policy.GroupType:
+		return "group"
+	case policy.FolderType:
+		return "folder"
+	case policy.ProxyType:
+		return "proxy"
+	}
+}
+
+var (
+	explainTemplate = `
+Access of {{ .Member | info }} to {{ .Item | info }}:
+
+    Org Role: {{ .Role }}{{ if not .InOrg }} {{ "(not a member of the org, so no path is usable)" | error }}{{ end }}
+
+{{- range .Actions }}
+
+  {{ "*" | item }} {{ printf "%v" .Action | col 10 }} {{ if .Held }}{{ "held" | ok }}{{ else }}{{ "lacking" | error }}{{ end }}
+{{- range .Paths }}
+        {{ kind . | col 8 }} {{ path . }}{{ if .Error }} {{ printf "(blocked: %v)" .Error | error }}{{ end }}
+{{- else }}
+        {{ "No membership grants this action" | notice }}
+{{- end }}
+{{- range .Denies }}
+        {{ "deny" | col 8 }} {{ path . }}{{ if .Error }} {{ printf "(blocked: %v)" .Error | error }}{{ end }}
+{{- end }}
+{{- end }}
+`
+)
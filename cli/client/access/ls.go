@@ -0,0 +1,163 @@
+package access
+
+import (
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/accounts"
+	"github.com/cott-io/stash/sdk/session"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	StatusFlag = tool.StringFlag{
+		Name:    "status",
+		Usage:   "Only show requests of the given status (pending, approved, denied, expired)",
+		Default: string(access.Pending),
+	}
+
+	AllFlag = tool.BoolFlag{
+		Name:  "all",
+		Usage: "Show requests of every status",
+	}
+
+	MineFlag = tool.BoolFlag{
+		Name:  "mine",
+		Usage: "Only show your own requests",
+	}
+
+	ItemFlag = tool.StringFlag{
+		Name:  "item",
+		Usage: "Only show requests for the item",
+	}
+
+	LsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls [--status <status>] [--all] [--mine] [--item <item>]",
+			Info:  "List access requests",
+			Help: `
+Lists access requests, newest first.  Only pending requests are
+shown by default.
+
+Directors may see every request of the organization.  Approvers
+may see the requests of the items they approve by using --item.
+Everyone else may only see their own requests.
+
+Examples:
+
+    $ stash access ls
+    $ stash access ls --mine --all
+    $ stash access ls --item prod/db.pass --status approved
+`,
+			Flags: tool.NewFlags(tool.VFlag, StatusFlag, AllFlag, MineFlag, ItemFlag).Add(tool.PageFlags...),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				var filter []func(*access.Filter)
+				if !c.Bool(AllFlag.Name) {
+					status, err := access.ParseStatus(c.String(StatusFlag.Name))
+					if err != nil {
+						return err
+					}
+
+					filter = append(filter, access.FilterByStatus(status))
+				}
+				if c.Bool(MineFlag.Name) {
+					filter = append(filter, access.FilterByRequester(s.AccountId()))
+				}
+				if str := c.String(ItemFlag.Name); str != "" {
+					itemRef, err := parseItemRef(str)
+					if err != nil {
+						return err
+					}
+
+					policyId, err := itemRef.GetPolicyId(s, orgId)
+					if err != nil {
+						return err
+					}
+
+					filter = append(filter, access.FilterByPolicies(policyId))
+				}
+
+				requests, err := sdk.ListRequests(s, orgId, access.BuildFilter(filter...), tool.ParsePageOpts(c)...)
+				if err != nil {
+					return
+				}
+
+				ids, err := accounts.ListIdentitiesByAccountIds(s, collectAccountIds(requests))
+				if err != nil {
+					return
+				}
+
+				displays := accounts.LookupDisplays(ids)
+
+				template := accessLsTemplate
+				if c.Bool(tool.VFlag.Name) {
+					template = accessLsVTemplate
+				}
+
+				return tool.DisplayStdOut(env, template,
+					tool.WithFunc("account", func(id uuid.UUID) string {
+						if ident, ok := displays[id]; ok {
+							return auth.FormatFriendlyIdentity(ident.Id)
+						}
+						return id.String()
+					}),
+					tool.WithData(requests))
+			},
+		})
+)
+
+func collectAccountIds(requests []access.Request) (ret []uuid.UUID) {
+	seen := make(map[uuid.UUID]struct{})
+	for _, r := range requests {
+		for _, id := range []uuid.UUID{r.RequesterId, r.DeciderId} {
+			if _, ok := seen[id]; ok || id == uuid.Nil {
+				continue
+			}
+			seen[id] = struct{}{}
+			ret = append(ret, id)
+		}
+	}
+	return
+}
+
+var (
+	accessLsTemplate = `
+Requests(Total={{ len . }}):
+
+      {{ "#/id" | col 36 | header }} {{ "#/requester" | col 24 | header }} {{ "#/status" | col 10 | header }} {{ "#/for" | col 8 | header }} {{ "#/item" | header }}
+
+{{- range . }}
+    {{ "*" | item }} {{ .Id | printf "%v" | col 36 }} {{ .RequesterId | account | col 24 }} {{ .Status | printf "%v" | col 10 }} {{ .Duration | printf "%v" | col 8 }} {{ .Item }}
+{{- end }}
+`
+
+	accessLsVTemplate = `
+Requests(Total={{ len . }}):
+{{ range . }}
+{{ "*" | item }} {{ .Item | info }} ({{ .Status }})
+    Id:        {{ .Id }}
+    Requester: {{ .RequesterId | account }}
+    Actions:   {{ .Actions.Flatten }}
+    Duration:  {{ .Duration }}
+    Reason:    {{ .Reason }}
+    Requested: {{ .Created | date }} ({{ .Created | since }}){{ if ne .Status "pending" }}
+    Decider:   {{ .DeciderId | account }}{{ if .Comment }}
+    Comment:   {{ .Comment }}{{ end }}{{ end }}{{ if not .Expires.IsZero }}
+    Expires:   {{ .Expires | date }}{{ end }}
+{{ end }}
+`
+)
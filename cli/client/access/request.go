@@ -0,0 +1,114 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/policy"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	ForFlag = tool.StringFlag{
+		Name:    "for",
+		Usage:   "How long access is needed (e.g. 2h)",
+		Default: "1h",
+	}
+
+	ReasonFlag = tool.StringFlag{
+		Name:  "reason",
+		Usage: "Why access is needed",
+	}
+
+	RequestCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "request",
+			Usage: "request <item> [<action>]* --for <duration> --reason <reason>",
+			Info:  "Request temporary access to an item",
+			Help: `
+Requests temporary access to an item.  If no actions are given,
+the default actions of the item are requested.  Items without
+a protocol are assumed to be secrets.
+
+The approvers of the item are notified.  Once approved, you are
+granted a membership that expires after the requested duration.
+It is revoked automatically once it expires.
+
+Examples:
+
+    $ stash access request prod/db.pass view --for 2h --reason "Incident 1234"
+    $ stash access request group://ops --for 30m --reason "Rotating keys"
+`,
+			Flags: tool.NewFlags(ForFlag, ReasonFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				var actions []policy.Action
+				if len(c.Args()) > 1 {
+					for _, arg := range c.Args()[1:] {
+						act, err := itemRef.ParseAction(arg)
+						if err != nil {
+							return err
+						}
+
+						actions = append(actions, act)
+					}
+				} else {
+					actions = itemRef.Type.DefaultActions()
+				}
+
+				dur, err := time.ParseDuration(c.String(ForFlag.Name))
+				if err != nil {
+					err = errors.Wrapf(errs.ArgError, "Invalid duration [%v]", c.String(ForFlag.Name))
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				req, err := sdk.RequestAccess(s, orgId, policyId, itemRef.String(), dur, c.String(ReasonFlag.Name), actions...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, requestTemplate, tool.WithData(req))
+			},
+		})
+)
+
+var (
+	requestTemplate = `
+Requested access to {{ .Item | info }} for {{ .Duration }}
+
+    Request: {{ .Id }}
+    Actions: {{ .Actions.Flatten }}
+    Status:  {{ .Status }}
+
+You will be notified once your request has been decided.
+`
+)
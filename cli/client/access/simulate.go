@@ -0,0 +1,220 @@
+package access
+
+import (
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	SimulateTools = tool.NewGroup(
+		tool.GroupDef{
+			Name:  "simulate",
+			Usage: "simulate <command> [args]*",
+			Info:  "Preview the effect of a grant or revoke",
+		},
+		SimulateGrantCommand,
+		SimulateRevokeCommand,
+	)
+
+	SimulateGrantCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "grant",
+			Usage: "grant <item> <member> [<action>]* [--deny <action>]* [--not-after <time>] [--cidr <cidr>]*",
+			Info:  "Preview granting actions to a member",
+			Help: `
+Shows which users would gain or lose actions on an item were the
+member granted the actions.  Nothing is changed.  As with a grant,
+the actions replace those the member already holds.  If no actions
+are given, the default actions of the item are previewed, unless
+actions are denied with --deny.
+
+Only the validity window of conditions is evaluated, as the network,
+login and device of each user are unknown.  Items without a protocol
+are assumed to be secrets.
+
+Examples:
+
+    $ stash access simulate grant prod/db.pass group://payments-team view
+    $ stash access simulate grant prod/db.pass group://interns --deny view
+`,
+			Flags: client.GrantFlags,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 2 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item and a member")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				memberRef, err := policies.ParseMemberRef(c.Args().Get(1))
+				if err != nil {
+					return
+				}
+
+				actions, err := parseActions(itemRef, c.Args()[2:])
+				if err != nil {
+					return
+				}
+
+				denies, err := client.ParseDenies(c, itemRef)
+				if err != nil {
+					return
+				}
+				if len(actions) == 0 && len(denies) == 0 {
+					actions = itemRef.Type.DefaultActions()
+				}
+
+				cond, err := client.ParseConditions(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				memberId, err := memberRef.GetMemberId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				effects, err := policies.SimulateGrant(s, orgId, policyId, memberRef.Type, memberId, cond, denies, actions...)
+				if err != nil {
+					return
+				}
+
+				return displayEffects(env, itemRef, effects)
+			},
+		})
+
+	SimulateRevokeCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "revoke",
+			Usage: "revoke <item> <member> [<action>]*",
+			Info:  "Preview revoking actions from a member",
+			Help: `
+Shows which users would lose actions on an item were the actions
+revoked from the member.  Nothing is changed.  If no actions are
+given, the effect of removing the membership is previewed.  Users
+who also reach the item by another path keep the actions granted
+by that path.
+
+Examples:
+
+    $ stash access simulate revoke prod/db.pass group://payments-team
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 2 {
+					err = errors.Wrapf(errs.ArgError, "Must provide an item and a member")
+					return
+				}
+
+				itemRef, err := parseItemRef(c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				memberRef, err := policies.ParseMemberRef(c.Args().Get(1))
+				if err != nil {
+					return
+				}
+
+				actions, err := parseActions(itemRef, c.Args()[2:])
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				policyId, err := itemRef.GetPolicyId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				memberId, err := memberRef.GetMemberId(s, orgId)
+				if err != nil {
+					return
+				}
+
+				effects, err := policies.SimulateRevoke(s, orgId, policyId, memberId, actions...)
+				if err != nil {
+					return
+				}
+
+				return displayEffects(env, itemRef, effects)
+			},
+		})
+)
+
+func parseActions(itemRef policies.ItemRef, args []string) (ret []policy.Action, err error) {
+	for _, arg := range args {
+		act, err := itemRef.ParseAction(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, act)
+	}
+	return
+}
+
+func displayEffects(env tool.Environment, itemRef policies.ItemRef, effects []policy.Effect) error {
+	return tool.DisplayStdOut(env, simulateTemplate,
+		tool.WithFunc("actions", client.ActionsFormatter),
+		tool.WithFunc("list", policy.ToStrings),
+		tool.WithData(struct {
+			Item    string
+			Effects []policy.Effect
+		}{
+			itemRef.String(),
+			effects,
+		}))
+}
+
+var (
+	simulateTemplate = `
+Effect on {{ .Item | info }} (Total={{ len .Effects }}):
+{{- if .Effects }}
+
+    {{ "#/user" | col 40 | header }} {{ "#/before" | col 20 | header }} {{ "#/after" | header }}
+{{- end }}
+
+{{- range .Effects }}
+  {{ "*" | item }} {{ .Format | col 40 }} {{ .Before | actions | col 20 }} {{ .After | actions | info }}
+{{- if .Gained }} {{ printf "+%v" (list .Gained) | ok }}{{ end }}
+{{- if .Lost }} {{ printf "-%v" (list .Lost) | error }}{{ end }}
+{{- else }}
+    No user's actions would change.
+{{- end }}
+`
+)
@@ -0,0 +1,13 @@
+package audit
+
+import "github.com/cott-io/stash/lang/tool"
+
+var (
+	Commands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "audit",
+			Info: "Review your organization's audit log",
+		},
+		LsCommand,
+	)
+)
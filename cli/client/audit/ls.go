@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/sdk/accounts"
+	sdk "github.com/cott-io/stash/sdk/audit"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	ActorFlag = tool.StringFlag{
+		Name:  "actor",
+		Usage: "Only show events performed by the identity",
+	}
+
+	TargetFlag = tool.StringFlag{
+		Name:  "target",
+		Usage: "Only show events whose target begins with the value",
+	}
+
+	ActionFlag = tool.StringFlag{
+		Name:  "action",
+		Usage: "Only show events of the given action (e.g. secret.download)",
+	}
+
+	SinceFlag = tool.StringFlag{
+		Name:  "since",
+		Usage: "Only show events since a duration ago (e.g. 24h) or a date (e.g. 2020-01-31)",
+	}
+
+	LsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls [--actor <identity>] [--target <prefix>] [--since <time>]",
+			Info:  "List the events of your organization's audit log",
+			Help: `
+Lists the events of the audit log, newest first.  Every read and
+change of a secret, policy and membership is recorded, along with
+the actor, device and address that performed it.
+
+Examples:
+
+	$ stash audit ls --actor @fred --since 24h
+	$ stash audit ls --target secret:///prod --action secret.download
+
+`,
+			Flags: tool.NewFlags(tool.VFlag, ActorFlag, TargetFlag, ActionFlag, SinceFlag).Add(tool.PageFlags...),
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				var filter []func(*audit.Filter)
+				if str := cli.String(ActorFlag.Name); str != "" {
+					ident, err := client.LoadIdentity(env, str)
+					if err != nil {
+						return err
+					}
+
+					id, err := accounts.RequireIdentity(s, ident)
+					if err != nil {
+						return err
+					}
+
+					filter = append(filter, audit.FilterByActor(id.AccountId))
+				}
+				if str := cli.String(TargetFlag.Name); str != "" {
+					filter = append(filter, audit.FilterByTarget(str))
+				}
+				if str := cli.String(ActionFlag.Name); str != "" {
+					filter = append(filter, audit.FilterByAction(audit.Action(str)))
+				}
+				if str := cli.String(SinceFlag.Name); str != "" {
+					since, err := parseSince(str)
+					if err != nil {
+						return err
+					}
+
+					filter = append(filter, audit.FilterSince(since))
+				}
+
+				events, err := sdk.ListEvents(s, orgId, audit.BuildFilter(filter...), tool.ParsePageOpts(cli)...)
+				if err != nil {
+					return
+				}
+
+				ids, err := accounts.ListIdentitiesByAccountIds(s, collectActorIds(events))
+				if err != nil {
+					return
+				}
+
+				displays := accounts.LookupDisplays(ids)
+
+				template := auditLsTemplate
+				if cli.Bool(tool.VFlag.Name) {
+					template = auditLsVTemplate
+				}
+
+				return tool.DisplayStdOut(env, template,
+					tool.WithFunc("actor", func(id uuid.UUID) string {
+						if ident, ok := displays[id]; ok {
+							return auth.FormatFriendlyIdentity(ident.Id)
+						}
+						return id.String()
+					}),
+					tool.WithData(events))
+			},
+		})
+)
+
+func parseSince(str string) (ret time.Time, err error) {
+	if dur, e := time.ParseDuration(str); e == nil {
+		ret = time.Now().Add(-dur)
+		return
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, e := time.ParseInLocation(layout, str, time.Local); e == nil {
+			ret = t
+			return
+		}
+	}
+
+	err = errors.Wrapf(errs.ArgError, "Invalid since [%v]. Expected a duration or date", str)
+	return
+}
+
+func collectActorIds(events []audit.Event) (ret []uuid.UUID) {
+	seen := make(map[uuid.UUID]struct{})
+	for _, e := range events {
+		if _, ok := seen[e.ActorId]; ok {
+			continue
+		}
+		seen[e.ActorId] = struct{}{}
+		ret = append(ret, e.ActorId)
+	}
+	return
+}
+
+var (
+	auditLsTemplate = `
+Events(Total={{ len . }}):
+
+      {{ "#/time" | col 20 | header }} {{ "#/actor" | col 24 | header }} {{ "#/action" | col 16 | header }} {{ "#/target" | header }}
+
+{{- range . }}
+    {{ if eq .Result "success" }}{{ "*" | item }}{{ else }}{{ "!" | error }}{{ end }} {{ .Created | time | col 20 }} {{ .ActorId | actor | col 24 }} {{ .Action | printf "%v" | col 16 }} {{ .Target }}
+{{- end }}
+`
+
+	auditLsVTemplate = `
+Events(Total={{ len . }}):
+{{ range . }}
+{{ "*" | item }} {{ .Action | printf "%v" | info }} {{ .Target }}
+    Actor:   {{ .ActorId | actor }}
+    Result:  {{ .Result }}{{ if .Detail }} ({{ .Detail }}){{ end }}
+    Device:  {{ .Device }}
+    Login:   {{ .Login }}
+    Remote:  {{ .Remote }}
+    Time:    {{ .Created | date }} ({{ .Created | since }})
+{{ end }}
+`
+)
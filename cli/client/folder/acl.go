@@ -0,0 +1,85 @@
+package folder
+
+import (
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/urfave/cli"
+)
+
+var (
+	ACLTools = tool.NewGroup(
+		tool.GroupDef{
+			Name:  "acl",
+			Usage: "acl <command> [args]*",
+			Info:  "Manage folder access controls",
+		},
+		GrantCommand,
+		RevokeCommand,
+		RosterCommand,
+	)
+
+	GrantCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "grant",
+			Usage: "grant <folder> <member> [<action>]* [--deny <action>]* [--not-after <time>] [--cidr <cidr>]*",
+			Info:  "Grant actions to a member",
+			Help: `
+Grants privileges on every secret beneath a folder to a user or
+group.  If no actions are given, view is granted.  The actions of
+a secret are the union of its own and those of its folders.
+
+Actions may be denied with --deny.  A denied action is withheld
+from the member even when another membership, such as one through
+a group, grants it.  If only denies are given, nothing is granted.
+
+Conditions may be placed on the membership.  Each condition that
+is given must be satisfied for the membership to be used:
+
+    --not-before, --not-after  A validity window.  Either a time
+                               (RFC3339) or a duration from now
+    --cidr                     The networks requests must come from
+    --require-login            The login protocols that may be used
+    --device                   The devices requests must come from
+
+Examples:
+
+    $ stash folder acl grant /payments group://payments-team view
+    $ stash folder acl grant /payments user://contractor@example.com --not-after 72h
+    $ stash folder acl grant /prod group://interns --deny view
+`,
+			Flags: client.GrantFlags,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				return client.GrantPolicyMember(env, c, "folder")
+			},
+		})
+
+	RevokeCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "revoke",
+			Usage: "revoke <folder> <member> [<action>]* [--rekey]",
+			Info:  "Revoke actions from a member",
+			Help: `
+Revokes privileges on a folder.  If no actions are given, the
+membership is deleted.  Access granted directly on a secret
+is unaffected.
+
+With --rekey, the folder and every secret attached to it are
+rekeyed, so the revoked member's cached keys are of no further use.
+`,
+			Flags: tool.NewFlags(client.RekeyFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				return client.RevokePolicyMember(env, c, "folder")
+			},
+		})
+
+	RosterCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls <folder>",
+			Info:  "List members and their actions",
+			Help:  ``,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				return client.ListPolicyMembers(env, c, "folder")
+			},
+		})
+)
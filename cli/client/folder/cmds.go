@@ -0,0 +1,16 @@
+package folder
+
+import "github.com/cott-io/stash/lang/tool"
+
+var (
+	Commands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "folder",
+			Info: "Manage access to secrets by path",
+		},
+		CreateCommand,
+		AttachCommand,
+		LsCommand,
+		ACLTools,
+	)
+)
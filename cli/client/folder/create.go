@@ -0,0 +1,136 @@
+package folder
+
+import (
+	"fmt"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	StrengthFlag = tool.StringFlag{
+		Name:    "strength",
+		Usage:   "Define the security requirements of your folder (Weak,Moderate,Strong,Maximum)",
+		Default: crypto.Moderate.String(),
+	}
+
+	CreateCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "create",
+			Usage: "create <path>",
+			Info:  "Create a folder",
+			Help: `
+Creates a folder at a path.  Secrets created beneath the path
+automatically inherit the members of the folder, as do the
+existing secrets beneath it on which you hold sudo.
+
+Examples:
+
+    $ stash folder create /payments
+    $ stash folder acl grant /payments group://payments-team view
+`,
+			Flags: tool.NewFlags(StrengthFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide a path")
+					return
+				}
+
+				strength, err := crypto.ParseStrength(c.String(StrengthFlag.Name))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				folder, err := secrets.CreateFolder(s, orgId, c.Args().Get(0), strength)
+				if err != nil {
+					return
+				}
+
+				if _, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nCreated folder [%v]\n", folder.Path); err != nil {
+					return
+				}
+
+				return attach(env, s, folder)
+			},
+		})
+
+	AttachCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "attach",
+			Usage: "attach <path>",
+			Info:  "Attach a folder to the secrets beneath it",
+			Help: `
+Attaches a folder to the existing secrets beneath it on which you
+hold sudo.  Secrets created beneath a folder are attached when they
+are created, but secrets that were moved in, or that were created
+before the folder, must be attached explicitly.  Secrets on which
+you do not hold sudo are skipped and listed.
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Must provide a path")
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				folder, err := secrets.RequireFolderByPath(s, orgId, c.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				return attach(env, s, folder)
+			},
+		})
+)
+
+func attach(env tool.Environment, s session.Session, folder secret.Folder) (err error) {
+	attached, skipped, err := secrets.AttachExisting(s, folder)
+	if err != nil {
+		return
+	}
+
+	return tool.DisplayStdOut(env, attachTemplate,
+		tool.WithData(struct {
+			Attached []string
+			Skipped  []string
+		}{
+			attached,
+			skipped,
+		}))
+}
+
+var (
+	attachTemplate = `
+Attached to [{{ len .Attached }}] secrets
+{{- range .Skipped }}
+    {{ "*" | item }} Skipped {{ . | info }} (requires sudo)
+{{- end }}
+`
+)
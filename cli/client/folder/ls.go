@@ -0,0 +1,59 @@
+package folder
+
+import (
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/urfave/cli"
+)
+
+var (
+	LsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls [<prefix>]",
+			Info:  "List folders",
+			Help:  ``,
+			Flags: tool.NewFlags().Add(tool.PageFlags...),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				var filter []func(*secret.FolderFilter)
+				if len(c.Args()) > 0 {
+					filter = append(filter, secret.FilterFolderPrefix(c.Args().Get(0)))
+				}
+
+				folders, err := secrets.ListFolders(s, orgId, secret.BuildFolderFilter(filter...), tool.ParsePageOpts(c)...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, folderLsTemplate,
+					tool.WithFunc("actions", client.ActionsFormatter),
+					tool.WithData(folders))
+			},
+		})
+)
+
+var (
+	folderLsTemplate = `
+Folders(Total={{ len . }}):
+
+    {{ "#/path" | col 40 | header }} {{ "#/actions" | header }}
+
+{{- range . }}
+  {{ "*" | item }} {{ .Path | col 40 }} [{{ .Actions | actions | info }}]
+{{- end }}
+`
+)
@@ -8,6 +8,7 @@ import (
 	"github.com/cott-io/stash/libs/account"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
+	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/sdk/accounts"
 	"github.com/cott-io/stash/sdk/orgs"
 	"github.com/cott-io/stash/sdk/session"
@@ -39,7 +40,11 @@ Example:
 				}
 				defer s.Close()
 
-				members, err := orgs.ListMembersByOrgId(s, s.Options().OrgId)
+				var members []org.Member
+				err = orgs.EachMemberByOrgId(s, s.Options().OrgId, func(m org.Member) error {
+					members = append(members, m)
+					return nil
+				}, page.Limit(256))
 				if err != nil {
 					return
 				}
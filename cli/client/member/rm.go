@@ -6,10 +6,14 @@ import (
 	"github.com/cott-io/stash/cli/client"
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
 	"github.com/cott-io/stash/sdk/accounts"
 	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/policies"
 	"github.com/cott-io/stash/sdk/session"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 	"github.com/urfave/cli"
 )
 
@@ -22,6 +26,10 @@ var (
 			Help: `
 Delete a member from the organization.
 
+The member is revoked from every policy on which you have sudo
+and each of those policies is rekeyed, re-encrypting the latest
+version of every item it protects.
+
 Examples:
 
 	$ stash org add @fred --role Manager
@@ -59,12 +67,67 @@ Examples:
 					return
 				}
 
-				if err = orgs.DeleteMember(s, s.Options().OrgId, id.AccountId); err != nil {
+				orgId := s.Options().OrgId
+
+				// Policy memberships may only be revoked while the account
+				// is still a member of the org.
+				revoked, skipped, err := revokeMemberships(s, orgId, id.AccountId)
+				if err != nil {
+					return
+				}
+
+				if err = orgs.DeleteMember(s, orgId, id.AccountId); err != nil {
+					return
+				}
+
+				if _, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Successfully deleted member [%v]\n", cli.Args().Get(0)); err != nil {
 					return
 				}
 
-				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Successfully deleted member [%v]", cli.Args().Get(0))
+				if len(skipped) > 0 {
+					fmt.Fprintf(env.Terminal.IO.StdErr(),
+						"Unable to revoke the member from policies %v. Their owners must revoke and rekey them.\n", skipped)
+				}
+
+				err = client.RekeyPolicies(env, s, orgId, revoked...)
 				return
 			},
 		})
 )
+
+// Revokes the account from every policy it belongs to on which the caller
+// has sudo.  Returns the ids of the revoked policies, as well as those
+// that could not be revoked.
+func revokeMemberships(s session.Session, orgId, acctId uuid.UUID) (revoked, skipped []uuid.UUID, err error) {
+	memberships, err := policies.ListMemberships(s, orgId, acctId)
+	if err != nil {
+		return
+	}
+
+	for _, m := range memberships {
+		lock, ok, err := policies.LoadPolicyLock(s, orgId, m.PolicyId, s.AccountId())
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok || policy.Has(policy.Sudo)(lock.Actions()) != nil {
+			skipped = append(skipped, m.PolicyId)
+			continue
+		}
+
+		roster, err := policies.ListPolicyMembers(s, orgId, m.PolicyId, page.Limit(2))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(roster) <= 1 {
+			skipped = append(skipped, m.PolicyId)
+			continue
+		}
+
+		if err = policies.RevokePolicyMember(s, lock, acctId); err != nil {
+			return nil, nil, err
+		}
+
+		revoked = append(revoked, m.PolicyId)
+	}
+	return
+}
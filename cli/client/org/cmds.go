@@ -12,5 +12,6 @@ var (
 		UseCommand,
 		LsCommand,
 		RmCommand,
+		WebhookTools,
 	)
 )
@@ -0,0 +1,325 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/cott-io/stash/sdk/webhooks"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	MethodFlag = tool.StringFlag{
+		Name:    "method",
+		Usage:   "The payload signing method (hmac, server)",
+		Default: string(webhook.HMAC),
+	}
+
+	EventFlag = tool.StringsFlag{
+		Name:  "event",
+		Usage: "An event to subscribe to.  May be repeated.  Defaults to all events",
+	}
+
+	WebhookTools = tool.NewGroup(
+		tool.GroupDef{
+			Name:  "webhook",
+			Usage: "webhook <command> [args]*",
+			Info:  "Manage your organization's webhooks",
+		},
+		WebhookAddCommand,
+		WebhookLsCommand,
+		WebhookRmCommand,
+		WebhookTestCommand,
+		WebhookLogCommand,
+		WebhookRedeliverCommand,
+	)
+
+	WebhookAddCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "add",
+			Usage: "add <url>",
+			Info:  "Register a webhook",
+			Help: `
+Registers a webhook.  Events are POSTed to the url as json, along
+with a signature of the payload in the X-Stash-Signature header.
+
+The signature covers the time of the delivery and the payload, given
+as <unix>.<payload>, and is formatted as t=<unix>,sha256=<signature>.
+Receivers should reject deliveries whose time is more than a few
+minutes old, so that they may not be replayed.
+
+Available events:
+
+	secret.create, secret.update, secret.delete,
+	policy.grant, policy.revoke,
+	member.add, member.remove,
+	login
+
+Examples:
+
+	$ stash org webhook add https://chat.example.com/hooks/stash --event secret.update
+	$ stash org webhook add https://siem.example.com/ingest --method server
+
+`,
+			Flags: tool.NewFlags(MethodFlag, EventFlag),
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				if len(cli.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Expected a url")
+					return
+				}
+
+				method, err := webhook.ParseSignMethod(cli.String(MethodFlag.Name))
+				if err != nil {
+					return
+				}
+
+				events, err := webhook.ParseEventTypes(cli.StringSlice(EventFlag.Name)...)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				hook, err := webhooks.Create(s, orgId, cli.Args().Get(0), method, events...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, webhookAddTemplate,
+					tool.WithFunc("events", eventsFormatter),
+					tool.WithData(hook))
+			},
+		})
+
+	WebhookLsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls",
+			Info:  "List your organization's webhooks",
+			Flags: tool.PageFlags,
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				hooks, err := webhooks.List(s, orgId, tool.ParsePageOpts(cli)...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, webhookLsTemplate,
+					tool.WithFunc("events", eventsFormatter),
+					tool.WithData(hooks))
+			},
+		})
+
+	WebhookRmCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "rm",
+			Usage: "rm <id>",
+			Info:  "Delete a webhook",
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				hookId, err := parseId(cli, "webhook")
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				if err = webhooks.Delete(s, orgId, hookId); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Successfully deleted webhook [%v].\n", hookId)
+				return
+			},
+		})
+
+	WebhookTestCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "test",
+			Usage: "test <id>",
+			Info:  "Send a ping event to a webhook",
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				hookId, err := parseId(cli, "webhook")
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				delivery, err := webhooks.Test(s, orgId, hookId)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, webhookLogTemplate,
+					tool.WithData([]webhook.Delivery{delivery}))
+			},
+		})
+
+	WebhookLogCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "log",
+			Usage: "log <id>",
+			Info:  "List the recent deliveries of a webhook",
+			Flags: tool.PageFlags,
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				hookId, err := parseId(cli, "webhook")
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				deliveries, err := webhooks.ListDeliveries(s, orgId, hookId, tool.ParsePageOpts(cli)...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, webhookLogTemplate,
+					tool.WithData(deliveries))
+			},
+		})
+
+	WebhookRedeliverCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "redeliver",
+			Usage: "redeliver <delivery-id>",
+			Info:  "Attempt a previous delivery again",
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				deliveryId, err := parseId(cli, "delivery")
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				if err = webhooks.Redeliver(s, orgId, deliveryId); err != nil {
+					return
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Scheduled redelivery of [%v].\n", deliveryId)
+				return
+			},
+		})
+)
+
+func parseId(cli *cli.Context, name string) (ret uuid.UUID, err error) {
+	if len(cli.Args()) != 1 {
+		err = errors.Wrapf(errs.ArgError, "Expected a %v id", name)
+		return
+	}
+
+	ret, err = uuid.FromString(cli.Args().Get(0))
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid %v id [%v]", name, cli.Args().Get(0))
+	}
+	return
+}
+
+func eventsFormatter(events webhook.EventTypes) string {
+	strs := make([]string, 0, len(events))
+	for _, e := range events {
+		strs = append(strs, string(e))
+	}
+	return strings.Join(strs, ",")
+}
+
+var (
+	webhookAddTemplate = `
+{{ "# Webhook" | header }}
+
+    Id:      {{ .Id }}
+    Url:     {{ .Url }}
+    Method:  {{ .Method }}
+    Events:  {{ .Events | events }}
+{{- if .Secret }}
+
+{{ "# Secret" | header }}
+
+Use the following secret to verify the X-Stash-Signature header
+of deliveries.  It will not be displayed again!
+
+    {{ .Secret | printf "%x" | info }}
+{{- end }}
+`
+
+	webhookLsTemplate = `
+Webhooks(Total={{ len . }}):
+
+      {{ "#/id" | col 38 | header }} {{ "#/method" | col 8 | header }} {{ "#/url" | header }}
+
+{{- range . }}
+    {{ "*" | item }} {{ .Id | uuid | col 38 }} {{ .Method | printf "%v" | col 8 }} {{ .Url }} [{{ .Events | events | info }}]
+{{- end }}
+`
+
+	webhookLogTemplate = `
+Deliveries(Total={{ len . }}):
+
+      {{ "#/id" | col 38 | header }} {{ "#/event" | col 16 | header }} {{ "#/attempt" | col 9 | header }} {{ "#/status" | header }}
+
+{{- range . }}
+    {{ "*" | item }} {{ .Id | uuid | col 38 }} {{ .Event.Type | printf "%v" | col 16 }} {{ .Attempt | printf "%v" | col 9 }} {{ if .Success }}{{ .Code | printf "%v" | ok }}{{ else }}{{ .Error | error }}{{ end }} ({{ .Created | since }})
+{{- end }}
+`
+)
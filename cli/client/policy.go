@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/ref"
@@ -10,8 +11,10 @@ import (
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
 	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/secrets"
 	"github.com/cott-io/stash/sdk/session"
 	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 	"github.com/urfave/cli"
 )
 
@@ -42,7 +45,13 @@ func GrantPolicyMember(env tool.Environment, c *cli.Context, proto string) (err
 		return
 	}
 
-	// Determine which actions to grant
+	denies, err := ParseDenies(c, itemRef)
+	if err != nil {
+		return
+	}
+
+	// Determine which actions to grant.  A membership that only
+	// denies actions grants nothing by default.
 	var actions []policy.Action
 	if len(c.Args()) > 2 {
 		for _, arg := range c.Args()[2:] {
@@ -53,10 +62,15 @@ func GrantPolicyMember(env tool.Environment, c *cli.Context, proto string) (err
 
 			actions = append(actions, act)
 		}
-	} else {
+	} else if len(denies) == 0 {
 		actions = itemRef.Type.DefaultActions()
 	}
 
+	cond, err := ParseConditions(c)
+	if err != nil {
+		return
+	}
+
 	s, err := session.NewDefaultSession(env.Context, env.Config)
 	if err != nil {
 		return
@@ -83,12 +97,69 @@ func GrantPolicyMember(env tool.Environment, c *cli.Context, proto string) (err
 		return
 	}
 
-	if err = policies.GrantPolicyMember(s, itemLock, memberRef.Type, memberId, actions...); err != nil {
+	if err = policies.GrantPolicyMember(s, itemLock, memberRef.Type, memberId, cond, denies, actions...); err != nil {
 		return
 	}
 
 	_, err = fmt.Fprintf(
 		env.Terminal.IO.StdOut(), "\nSuccessfully granted actions %v to member [%v] for [%v]\n", actions, memberRef, itemRef)
+	if err == nil && len(denies) > 0 {
+		_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Actions denied to member: %v\n", denies)
+	}
+	if err != nil || cond.Empty() {
+		return
+	}
+
+	_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "Membership is restricted: %v\n", cond)
+	return
+}
+
+// Parses the actions denied to a membership from the command line.
+func ParseDenies(c *cli.Context, itemRef policies.ItemRef) (ret []policy.Action, err error) {
+	for _, str := range c.StringSlice(DenyFlag.Name) {
+		act, err := itemRef.ParseAction(str)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, act)
+	}
+	return
+}
+
+// Parses the conditions of a membership from the command line.
+func ParseConditions(c *cli.Context) (ret policy.Conditions, err error) {
+	now := time.Now().UTC()
+	if ret.NotBefore, err = ParseTime(now, c.String(NotBeforeFlag.Name)); err != nil {
+		return
+	}
+	if ret.NotAfter, err = ParseTime(now, c.String(NotAfterFlag.Name)); err != nil {
+		return
+	}
+
+	ret.Cidrs, ret.Logins, ret.Devices =
+		c.StringSlice(CidrFlag.Name),
+		c.StringSlice(RequireLoginFlag.Name),
+		c.StringSlice(DeviceFlag.Name)
+
+	err = ret.Validate()
+	return
+}
+
+// Parses either an absolute time (RFC3339) or a duration relative to now.
+func ParseTime(now time.Time, str string) (ret time.Time, err error) {
+	if str == "" {
+		return
+	}
+
+	if dur, e := time.ParseDuration(str); e == nil {
+		ret = now.Add(dur)
+		return
+	}
+
+	ret, err = time.Parse(time.RFC3339, str)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid time [%v]. Expected RFC3339 or a duration", str)
+	}
 	return
 }
 
@@ -171,7 +242,27 @@ func RevokePolicyMember(env tool.Environment, c *cli.Context, proto string) (err
 		return
 	}
 
-	_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nSucccessfully revoked %v from [%v] of [%v]\n", actions, memberRef, itemRef)
+	if _, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nSucccessfully revoked %v from [%v] of [%v]\n", actions, memberRef, itemRef); err != nil {
+		return
+	}
+
+	if !c.Bool(RekeyFlag.Name) {
+		return
+	}
+
+	err = RekeyPolicies(env, s, orgId, itemPolicyId)
+	return
+}
+
+// Rekeys the policies, reporting the progress of each to the terminal.
+func RekeyPolicies(env tool.Environment, s session.Session, orgId uuid.UUID, policyIds ...uuid.UUID) (err error) {
+	err = secrets.RekeyAll(s, orgId, policyIds, func(policyId uuid.UUID, num int, err error) {
+		if err != nil {
+			fmt.Fprintf(env.Terminal.IO.StdErr(), "Error rekeying policy [%v]: %v\n", policyId, err)
+			return
+		}
+		fmt.Fprintf(env.Terminal.IO.StdOut(), "Rekeyed policy [%v] and re-encrypted [%v] items\n", policyId, num)
+	})
 	return
 }
 
@@ -235,7 +326,7 @@ Members(Total={{len .Members}}):
       {{ "#/id" | col 64 | header }} {{ "#/enabled actions" | header }}
 
 {{- range .Members}}
-    {{"*" | item}} {{ .Format | col 64 }} [{{ .Actions | actions | info }}]
+    {{"*" | item}} {{ .Format | col 64 }} [{{ .Actions | actions | info }}]{{ if not .Conditions.Empty }} {{ .Conditions.String }}{{ end }}
 {{- end}}
 `
 )
@@ -345,8 +436,10 @@ func ActionsFormatter(a policy.Actions) string {
 	if len(a) == 0 {
 		return "unauthorized"
 	}
-	if a.Enabled("sudo") {
-		return "sudo"
+
+	all := policy.ToStrings(a.Flatten())
+	for _, act := range a.Denials() {
+		all = append(all, "!"+string(act))
 	}
-	return strings.Join(policy.ToStrings(a.Flatten()), ",")
+	return strings.Join(all, ",")
 }
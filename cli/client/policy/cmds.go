@@ -0,0 +1,14 @@
+package policy
+
+import "github.com/cott-io/stash/lang/tool"
+
+var (
+	Commands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "policy",
+			Info: "Manage access declaratively from acl files",
+		},
+		PlanCommand,
+		ApplyCommand,
+	)
+)
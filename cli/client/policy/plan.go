@@ -0,0 +1,195 @@
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	StrengthFlag = tool.StringFlag{
+		Name:    "strength",
+		Usage:   "The security requirements of created groups (Weak,Moderate,Strong,Maximum)",
+		Default: crypto.Moderate.String(),
+	}
+
+	YesFlag = tool.BoolFlag{
+		Name:  "yes",
+		Usage: "Apply the plan without asking for confirmation",
+	}
+
+	PlanCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "plan",
+			Usage: "plan <file>",
+			Info:  "Show the changes needed to match an acl file",
+			Help: `
+Compares an acl file against the live memberships of the items it
+declares and shows the changes that 'stash policy apply' would make.
+Nothing is changed.  Files may be yaml, json or toml, chosen by their
+extension.
+
+An acl file declares groups along with their members, and the members
+of secrets and folders.  A secret with an absolute path declares the
+members of the folder at that path.  An empty list of actions grants
+the defaults of the item.  Items that are not declared are left alone,
+as are owners (members with sudo) that are not declared.
+
+Example acl.yaml:
+
+    groups:
+      backend:
+        description: Backend engineers
+        members:
+          user://alice@example.com: [view]
+
+    grants:
+      secret:///app/prod:
+        group://backend: [view]
+      secret://app/db.pass:
+        group://backend: [view, edit]
+
+Examples:
+
+    $ stash policy plan acl.yaml
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				s, plan, err := loadPlan(env, c)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				return displayPlan(env, plan)
+			},
+		})
+
+	ApplyCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "apply",
+			Usage: "apply <file> [--yes] [--strength <strength>]",
+			Info:  "Make the changes needed to match an acl file",
+			Help: `
+Plans the changes needed to match an acl file and, once confirmed,
+applies them.  Groups are created before they are granted anything.
+You must have sudo on every item that is changed.  A change that
+depends upon a failed change is skipped.
+
+Revoking a member does not replace the key of an item.  Consider
+rekeying items whose revoked members have seen their contents.
+
+Examples:
+
+    $ stash policy apply acl.yaml
+    $ stash policy apply acl.yaml --yes
+`,
+			Flags: tool.NewFlags(YesFlag, StrengthFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				strength, err := crypto.ParseStrength(c.String(StrengthFlag.Name))
+				if err != nil {
+					return
+				}
+
+				s, plan, err := loadPlan(env, c)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				if err = displayPlan(env, plan); err != nil || plan.Empty() {
+					return
+				}
+
+				if !c.Bool(YesFlag.Name) {
+					if err = tool.Confirm(env, "Apply these changes?"); err != nil {
+						return
+					}
+				}
+
+				var failed int
+				err = policies.ApplyPlan(s, plan, strength, func(change policies.Change, err error) {
+					if err != nil {
+						failed++
+						fmt.Fprintf(env.Terminal.IO.StdOut(), "  Failed to %v: %v\n", change, err)
+						return
+					}
+					fmt.Fprintf(env.Terminal.IO.StdOut(), "  Applied %v\n", change)
+				})
+				if err != nil {
+					return errors.Wrapf(err, "Failed to apply [%v] of [%v] changes", failed, len(plan.Changes))
+				}
+
+				_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nApplied [%v] changes\n", len(plan.Changes))
+				return
+			},
+		})
+)
+
+func loadPlan(env tool.Environment, c *cli.Context) (s session.Session, ret policies.Plan, err error) {
+	if len(c.Args()) != 1 {
+		err = errors.Wrapf(errs.ArgError, "Must provide an acl file")
+		return
+	}
+
+	file := c.Args().Get(0)
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	acl, err := policies.ReadACLFile(file, raw)
+	if err != nil {
+		return
+	}
+
+	s, err = session.NewDefaultSession(env.Context, env.Config)
+	if err != nil {
+		return
+	}
+
+	orgId, err := s.Options().RequireOrgId()
+	if err != nil {
+		s.Close()
+		return
+	}
+
+	ret, err = policies.PlanACL(s, orgId, acl)
+	if err != nil {
+		s.Close()
+	}
+	return
+}
+
+func displayPlan(env tool.Environment, plan policies.Plan) error {
+	return tool.DisplayStdOut(env, planTemplate,
+		tool.WithFunc("actions", client.ActionsFormatter),
+		tool.WithData(plan))
+}
+
+var (
+	planTemplate = `
+Plan(Total={{ len .Changes }}):
+{{- range .Changes }}
+{{- if eq (printf "%v" .Kind) "create" }}
+  {{ "+" | ok }} create {{ .Item | info }}
+{{- else if eq (printf "%v" .Kind) "grant" }}
+  {{ "+" | ok }} grant  {{ .Member | col 40 }} on {{ .Item | info }} {{ .After | actions }}
+{{- else if eq (printf "%v" .Kind) "update" }}
+  {{ "~" | notice }} update {{ .Member | col 40 }} on {{ .Item | info }} {{ .Before | actions }} -> {{ .After | actions }}
+{{- else }}
+  {{ "-" | error }} revoke {{ .Member | col 40 }} on {{ .Item | info }} {{ .Before | actions }}
+{{- end }}
+{{- else }}
+    No changes.  Access matches the file.
+{{- end }}
+`
+)
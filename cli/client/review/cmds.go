@@ -0,0 +1,50 @@
+package review
+
+import (
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/ref"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	Commands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "review",
+			Info: "Periodically review who has access",
+		},
+		StartCommand,
+		LsCommand,
+		ShowCommand,
+		KeepCommand,
+		RevokeCommand,
+		CloseCommand,
+		ReportCommand,
+	)
+)
+
+// Parses an item, assuming it is a secret when no protocol is given.
+func parseItemRef(item string) (ret policies.ItemRef, err error) {
+	if ref.Pointer(item).Protocol() == "" {
+		item = ref.Pointer(item).SetProtocol("secret").Raw()
+	}
+
+	ret, err = policies.ParseItemRef(item)
+	return
+}
+
+func parseCampaignId(c *cli.Context) (ret uuid.UUID, err error) {
+	if len(c.Args()) < 1 {
+		err = errors.Wrapf(errs.ArgError, "Must provide a campaign id")
+		return
+	}
+
+	ret, err = uuid.FromString(c.Args().Get(0))
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid campaign id [%v]", c.Args().Get(0))
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package review
+
+import (
+	"fmt"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	CommentFlag = tool.StringFlag{
+		Name:  "comment",
+		Usage: "A comment recorded with the decision",
+	}
+
+	KeepCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "keep",
+			Usage: "keep <campaign> <item> <member> [--comment <comment>]",
+			Info:  "Keep a membership under review",
+			Help: `
+Keeps a membership under review.  You must be one of its reviewers.
+Decisions are final.
+
+Examples:
+
+    $ stash review keep 6c1f... prod/db.pass user://alice@example.com
+`,
+			Flags: tool.NewFlags(CommentFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				return decide(env, c, access.Keep)
+			},
+		})
+
+	RevokeCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "revoke",
+			Usage: "revoke <campaign> <item> <member> [--comment <comment>]",
+			Info:  "Revoke a membership under review",
+			Help: `
+Revokes a membership under review.  You must be one of its reviewers.
+The membership is removed immediately, unless it has changed since
+the campaign started or it is the last owner of its item.  Decisions
+are final.
+
+Removing a membership does not replace the key of the item.  Consider
+rekeying items whose revoked members have seen their contents.
+
+Examples:
+
+    $ stash review revoke 6c1f... prod/db.pass group://contractors --comment "Contract ended"
+`,
+			Flags: tool.NewFlags(CommentFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				return decide(env, c, access.Revoke)
+			},
+		})
+
+	CloseCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "close",
+			Usage: "close <campaign>",
+			Info:  "Close a review ahead of its deadline",
+			Help: `
+Closes a review ahead of its deadline.  You must be a director of the
+organization.  Entries that have not been reviewed are revoked and a
+signed report of every decision is produced.
+`,
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				campaignId, err := parseCampaignId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				campaign, err := sdk.CloseCampaign(s, orgId, campaignId)
+				if err != nil {
+					return
+				}
+
+				report, err := campaign.DecodeReport()
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, reportTemplate,
+					tool.WithData(report))
+			},
+		})
+)
+
+func decide(env tool.Environment, c *cli.Context, decision access.Decision) (err error) {
+	if len(c.Args()) != 3 {
+		err = errors.Wrapf(errs.ArgError, "Must provide a campaign, an item and a member")
+		return
+	}
+
+	campaignId, err := parseCampaignId(c)
+	if err != nil {
+		return
+	}
+
+	itemRef, err := parseItemRef(c.Args().Get(1))
+	if err != nil {
+		return
+	}
+
+	memberRef, err := policies.ParseMemberRef(c.Args().Get(2))
+	if err != nil {
+		return
+	}
+
+	s, err := session.NewDefaultSession(env.Context, env.Config)
+	if err != nil {
+		return
+	}
+	defer s.Close()
+
+	orgId, err := s.Options().RequireOrgId()
+	if err != nil {
+		return
+	}
+
+	policyId, err := itemRef.GetPolicyId(s, orgId)
+	if err != nil {
+		return
+	}
+
+	memberId, err := memberRef.GetMemberId(s, orgId)
+	if err != nil {
+		return
+	}
+
+	entry, err := sdk.DecideEntry(s, orgId, campaignId, policyId, memberId, decision, c.String(CommentFlag.Name))
+	if err != nil {
+		return
+	}
+
+	switch {
+	case entry.Decision == access.Keep:
+		_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nKept [%v] on [%v]\n", memberRef, itemRef)
+	case entry.Applied:
+		_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nRevoked [%v] from [%v]\n", memberRef, itemRef)
+	default:
+		_, err = fmt.Fprintf(env.Terminal.IO.StdOut(),
+			"\nRecorded the revocation of [%v] from [%v], but the membership has changed or is the last owner and was left in place\n", memberRef, itemRef)
+	}
+	return
+}
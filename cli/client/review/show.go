@@ -0,0 +1,257 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/accounts"
+	"github.com/cott-io/stash/sdk/session"
+	uuid "github.com/satori/go.uuid"
+	"github.com/urfave/cli"
+)
+
+var (
+	MineFlag = tool.BoolFlag{
+		Name:  "mine",
+		Usage: "Only show the entries assigned to you",
+	}
+
+	PendingFlag = tool.BoolFlag{
+		Name:  "pending",
+		Usage: "Only show the entries that have not been reviewed",
+	}
+
+	OutFlag = tool.StringFlag{
+		Name:  "out",
+		Usage: "Write the signed report to a file",
+	}
+
+	LsCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "ls",
+			Usage: "ls",
+			Info:  "List reviews",
+			Help: `
+Lists the review campaigns of the organization, newest first.
+`,
+			Flags: tool.NewFlags(tool.PageFlags...),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				campaigns, err := sdk.ListCampaigns(s, orgId, tool.ParsePageOpts(c)...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, lsTemplate,
+					tool.WithData(campaigns))
+			},
+		})
+
+	ShowCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "show",
+			Usage: "show <campaign> [--mine] [--pending]",
+			Info:  "Show the entries of a review",
+			Help: `
+Shows the entries of a review along with the actions each membership
+effectively grants.  Directors may see every entry.  Everyone else
+may only see the entries assigned to them.
+
+Examples:
+
+    $ stash review show 6c1f... --mine --pending
+`,
+			Flags: tool.NewFlags(MineFlag, PendingFlag).Add(tool.PageFlags...),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				campaignId, err := parseCampaignId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				campaign, err := sdk.RequireCampaign(s, orgId, campaignId)
+				if err != nil {
+					return
+				}
+
+				var filter []func(*access.ReviewFilter)
+				if c.Bool(MineFlag.Name) {
+					filter = append(filter, access.FilterByReviewer(s.AccountId()))
+				}
+				if c.Bool(PendingFlag.Name) {
+					filter = append(filter, access.FilterByDecision(access.Undecided))
+				}
+
+				entries, err := sdk.ListReviewEntries(s, orgId, campaignId, access.BuildReviewFilter(filter...), tool.ParsePageOpts(c)...)
+				if err != nil {
+					return
+				}
+
+				member, err := memberFormatter(s, entries)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, showTemplate,
+					tool.WithFunc("actions", client.ActionsFormatter),
+					tool.WithFunc("member", member),
+					tool.WithData(struct {
+						Campaign access.Campaign
+						Entries  []access.ReviewEntry
+					}{
+						campaign,
+						entries,
+					}))
+			},
+		})
+
+	ReportCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "report",
+			Usage: "report <campaign> [--out <file>]",
+			Info:  "Show or export the report of a closed review",
+			Help: `
+Shows the summary of a closed review.  With --out, the report is
+written to a file along with the signature of the server, which
+covers the exact bytes of the report.
+
+Examples:
+
+    $ stash review report 6c1f... --out q3-prod.json
+`,
+			Flags: tool.NewFlags(OutFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				campaignId, err := parseCampaignId(c)
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				campaign, err := sdk.RequireCampaign(s, orgId, campaignId)
+				if err != nil {
+					return
+				}
+
+				if out := c.String(OutFlag.Name); out != "" {
+					signed, err := campaign.SignedReport()
+					if err != nil {
+						return err
+					}
+
+					raw, err := json.MarshalIndent(signed, "", "  ")
+					if err != nil {
+						return err
+					}
+
+					if err := ioutil.WriteFile(out, raw, 0644); err != nil {
+						return err
+					}
+
+					_, err = fmt.Fprintf(env.Terminal.IO.StdOut(), "\nWrote the signed report of [%v] to [%v]\n", campaign.Name, out)
+					return err
+				}
+
+				report, err := campaign.DecodeReport()
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, reportTemplate,
+					tool.WithData(report))
+			},
+		})
+)
+
+// Returns a formatter of the members of the entries, naming users by
+// their identities.
+func memberFormatter(s session.Session, entries []access.ReviewEntry) (ret func(access.ReviewEntry) string, err error) {
+	var acctIds []uuid.UUID
+	for _, e := range entries {
+		if e.MemberType == policy.UserType {
+			acctIds = append(acctIds, e.MemberId)
+		}
+	}
+
+	ids, err := accounts.ListIdentitiesByAccountIds(s, acctIds)
+	if err != nil {
+		return
+	}
+
+	displays := accounts.LookupDisplays(ids)
+	ret = func(e access.ReviewEntry) string {
+		if ident, ok := displays[e.MemberId]; ok && e.MemberType == policy.UserType {
+			return e.MemberType.FormatName(auth.FormatFriendlyIdentity(ident.Id))
+		}
+		return e.MemberType.FormatId(e.MemberId)
+	}
+	return
+}
+
+var (
+	lsTemplate = `
+Reviews(Total={{ len . }}):
+
+      {{ "#/id" | col 36 | header }} {{ "#/name" | col 24 | header }} {{ "#/status" | col 8 | header }} {{ "#/deadline" | header }}
+
+{{- range . }}
+    {{ "*" | item }} {{ .Id | printf "%v" | col 36 }} {{ .Name | col 24 }} {{ .Status | printf "%v" | col 8 }} {{ .Deadline | date }}
+{{- end }}
+`
+
+	showTemplate = `
+Review {{ .Campaign.Name | info }} ({{ .Campaign.Status }}, deadline {{ .Campaign.Deadline | date }}):
+
+      {{ "#/item" | col 32 | header }} {{ "#/member" | col 40 | header }} {{ "#/effective" | col 20 | header }} {{ "#/decision" | header }}
+
+{{- range .Entries }}
+    {{ "*" | item }} {{ .Item | col 32 }} {{ member . | col 40 }} {{ .Effective | actions | col 20 }} {{ .Decision | printf "%v" }}
+{{- else }}
+    No entries.
+{{- end }}
+`
+
+	reportTemplate = `
+Report of review {{ .Name | info }} (closed {{ .Closed | date }}):
+
+    Kept:    {{ .Kept }}
+    Revoked: {{ .Revoked }}
+    Lapsed:  {{ .Lapsed }}
+`
+)
@@ -0,0 +1,106 @@
+package review
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/cli/client"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	sdk "github.com/cott-io/stash/sdk/access"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	DeadlineFlag = tool.StringFlag{
+		Name:    "deadline",
+		Usage:   "When unreviewed entries are revoked (RFC3339 or a duration from now)",
+		Default: "336h",
+	}
+
+	StartCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "start",
+			Usage: "start <name> <item>+ [--deadline <time>]",
+			Info:  "Start a review of who has access to items",
+			Help: `
+Starts a review campaign over a set of items.  You must be a director
+of the organization.  Every membership of the items is enumerated
+along with the actions it effectively grants, and is assigned to the
+owners (those with sudo) of its item.  Owners never review their own
+membership.  Memberships without any other owner are assigned to you.
+
+Reviewers are notified and keep or revoke each of their entries with
+'stash review keep' and 'stash review revoke'.  Any entry that is not
+reviewed by the deadline is revoked.  When the campaign closes, a
+signed report of every decision is produced.
+
+Items without a protocol are assumed to be secrets.
+
+Examples:
+
+    $ stash review start q3-prod prod/db.pass group://payments-team
+    $ stash review start q3-prod prod/db.pass --deadline 2026-10-01T00:00:00Z
+`,
+			Flags: tool.NewFlags(DeadlineFlag),
+			Exec: func(env tool.Environment, c *cli.Context) (err error) {
+				if len(c.Args()) < 2 {
+					err = errors.Wrapf(errs.ArgError, "Must provide a name and at least one item")
+					return
+				}
+
+				deadline, err := client.ParseTime(time.Now().UTC(), c.String(DeadlineFlag.Name))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+
+				orgId, err := s.Options().RequireOrgId()
+				if err != nil {
+					return
+				}
+
+				var scopes []access.Scope
+				for _, item := range c.Args()[1:] {
+					itemRef, err := parseItemRef(item)
+					if err != nil {
+						return err
+					}
+
+					policyId, err := itemRef.GetPolicyId(s, orgId)
+					if err != nil {
+						return err
+					}
+
+					scopes = append(scopes, access.Scope{PolicyId: policyId, Item: itemRef.String()})
+				}
+
+				campaign, err := sdk.StartCampaign(s, orgId, c.Args().Get(0), deadline, scopes...)
+				if err != nil {
+					return
+				}
+
+				return tool.DisplayStdOut(env, startTemplate,
+					tool.WithData(campaign))
+			},
+		})
+)
+
+var (
+	startTemplate = `
+Started review {{ .Name | info }}
+
+    Campaign: {{ .Id }}
+    Items:    {{ len .Scopes }}
+    Deadline: {{ .Deadline | date }}
+
+Reviewers have been notified.  Unreviewed entries will be revoked at the deadline.
+`
+)
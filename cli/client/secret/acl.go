@@ -21,9 +21,32 @@ var (
 	GrantCommand = tool.NewCommand(
 		tool.CommandDef{
 			Name:  "grant",
-			Usage: "grant <secret> <member> [<action>]*",
+			Usage: "grant <secret> <member> [<action>]* [--deny <action>]* [--not-after <time>] [--cidr <cidr>]*",
 			Info:  "Grant actions to a member",
-			Help:  ``,
+			Help: `
+Grants privileges to a user or group.  If no actions are given,
+the default actions are granted.
+
+Actions may be denied with --deny.  A denied action is withheld
+from the member even when another membership, such as one through
+a group, grants it.  If only denies are given, nothing is granted.
+
+Conditions may be placed on the membership.  Each condition that
+is given must be satisfied for the membership to be used:
+
+    --not-before, --not-after  A validity window.  Either a time
+                               (RFC3339) or a duration from now
+    --cidr                     The networks requests must come from
+    --require-login            The login protocols that may be used
+    --device                   The devices requests must come from
+
+Examples:
+
+    $ stash secret acl grant /prod/db/password user://contractor@example.com --not-after 72h
+    $ stash secret acl grant /prod/db/password group://ops --cidr 10.8.0.0/16
+    $ stash secret acl grant /prod/db/password group://interns --deny view
+`,
+			Flags: client.GrantFlags,
 			Exec: func(env tool.Environment, c *cli.Context) (err error) {
 				return client.GrantPolicyMember(env, c, "secret")
 			},
@@ -32,12 +55,18 @@ var (
 	RevokeCommand = tool.NewCommand(
 		tool.CommandDef{
 			Name:  "revoke",
-			Usage: "revoke <secret> <member> [<action>]*",
+			Usage: "revoke <secret> <member> [<action>]* [--rekey]",
 			Info:  "Revoke actions from a member",
 			Help: `
 Revokes privileges from a user.  If no actions are given,
 the membership is deleted.
+
+A revoked member may have already recovered the keys of the
+policy.  Use --rekey to replace the keys and re-encrypt the
+latest version of every item under the policy.  Previous
+versions are no longer readable once rekeyed.
 `,
+			Flags: tool.NewFlags(client.RekeyFlag),
 			Exec: func(env tool.Environment, c *cli.Context) (err error) {
 				return client.RevokePolicyMember(env, c, "secret")
 			},
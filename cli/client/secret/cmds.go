@@ -15,6 +15,8 @@ var (
 		LsCommand,
 		MvCommand,
 		RmCommand,
+		WatchCommand,
+		RotateCommand,
 		ACLTools,
 	)
 )
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/merge"
 	"github.com/cott-io/stash/lang/mime"
 	"github.com/cott-io/stash/lang/ref"
 	"github.com/cott-io/stash/lang/term"
@@ -27,6 +28,11 @@ var (
 			Help: `
 Edit a secret.
 
+If the secret is updated by someone else while you are editing
+it, you will be offered a merge of your changes into theirs.
+Changes that conflict are marked for you to resolve in the
+editor.
+
 Example:
 
 	$ stash secret edit /myproject/dev
@@ -90,25 +96,8 @@ Example:
 					return
 				}
 
-				switch ptr.Mime() {
-				case mime.Json:
-					var dst interface{}
-					if err = enc.Json.DecodeBinary(next, &dst); err != nil {
-						err = errors.Wrapf(err, "Invalid json [%v]", cli.Args().Get(0))
-						return
-					}
-				case mime.Yaml:
-					var dst interface{}
-					if err = enc.Yaml.DecodeBinary(next, &dst); err != nil {
-						err = errors.Wrapf(err, "Invalid yaml [%v]", cli.Args().Get(0))
-						return
-					}
-				case mime.Toml:
-					var dst interface{}
-					if err = enc.Toml.DecodeBinary(next, &dst); err != nil {
-						err = errors.Wrapf(err, "Invalid toml [%v]", cli.Args().Get(0))
-						return
-					}
+				if err = verifyFormat(ptr, next); err != nil {
+					return
 				}
 
 				if !ok {
@@ -120,8 +109,81 @@ Example:
 					return
 				}
 
-				_, err = secrets.Write(s, tmp.Update(), bytes.NewBuffer(next))
-				return
+				// The secret may be updated by someone else while it is being
+				// edited, in which case the edit is merged into their version.
+				cur := tmp.Secret
+				for {
+					_, err = secrets.Write(s, cur.Update(), bytes.NewBuffer(next))
+					if !errs.Is(err, errs.Conflict) {
+						return
+					}
+
+					var latest secret.SecretSummary
+					latest, err = secrets.RequireByName(s, s.Options().OrgId, cli.Args().Get(0))
+					if err != nil {
+						return
+					}
+
+					buf := &bytes.Buffer{}
+					if err = secrets.Read(s, latest.Secret, buf); err != nil {
+						return
+					}
+
+					fmt.Fprintf(env.Terminal.IO.StdOut(),
+						"Secret [%v] was updated to version [%v] while you were editing.\n", latest.Name, latest.Version)
+					if err = tool.Confirm(env, "Merge your changes?"); err != nil {
+						return
+					}
+
+					merged, conflicts := merge.Merge(val, next, buf.Bytes(),
+						merge.WithLabels("yours", fmt.Sprintf("version %v", latest.Version)))
+					if conflicts > 0 {
+						fmt.Fprintf(env.Terminal.IO.StdOut(),
+							"Found [%v] conflict(s).  Resolve them to continue.\n", conflicts)
+
+						var resolved []byte
+						if err = term.SystemEditor.Edit(format, merged, &resolved); err != nil {
+							return
+						}
+						if bytes.Contains(resolved, []byte(merge.MarkerOurs)) ||
+							bytes.Contains(resolved, []byte(merge.MarkerTheirs)) {
+							err = errors.Wrapf(errs.StateError, "Unresolved conflicts in [%v]", cli.Args().Get(0))
+							return
+						}
+						merged = resolved
+					}
+
+					if bytes.Equal(buf.Bytes(), merged) {
+						fmt.Fprintf(env.Terminal.IO.StdOut(), "Nothing updated.\n")
+						return
+					}
+
+					if err = verifyFormat(ptr, merged); err != nil {
+						return
+					}
+
+					val, next, cur = buf.Bytes(), merged, latest.Secret
+				}
 			},
 		})
 )
+
+// Verifies that the value is valid in the format of the secret.
+func verifyFormat(ptr ref.Pointer, val []byte) (err error) {
+	var dst interface{}
+	switch ptr.Mime() {
+	case mime.Json:
+		if err = enc.Json.DecodeBinary(val, &dst); err != nil {
+			err = errors.Wrapf(err, "Invalid json [%v]", ptr)
+		}
+	case mime.Yaml:
+		if err = enc.Yaml.DecodeBinary(val, &dst); err != nil {
+			err = errors.Wrapf(err, "Invalid yaml [%v]", ptr)
+		}
+	case mime.Toml:
+		if err = enc.Toml.DecodeBinary(val, &dst); err != nil {
+			err = errors.Wrapf(err, "Invalid toml [%v]", ptr)
+		}
+	}
+	return
+}
@@ -18,13 +18,25 @@ var (
 		Name:  "a",
 		Usage: "Include hidden secrets"}
 
+	AllFlag = tool.BoolFlag{
+		Name:  "all",
+		Usage: "List every result, fetching -n at a time"}
+
 	LsCommand = tool.NewCommand(
 		tool.CommandDef{
 			Name:  "ls",
 			Usage: "ls [/<prefix>]",
 			Info:  "List and search for secrets",
-			Help:  ``,
-			Flags: tool.NewFlags(tool.VFlag, HiddenFlag).Add(tool.PageFlags...),
+			Help: `
+List and search for secrets.  Results are listed a page at
+a time, of at most -n results.  Use --all to fetch every page.
+
+Example:
+
+	$ stash secret ls /myproject
+	$ stash secret ls --all -n 1000
+`,
+			Flags: tool.NewFlags(tool.VFlag, HiddenFlag, AllFlag).Add(tool.PageFlags...),
 			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
 				var filters []func(*secret.Filter)
 				if len(cli.Args()) > 0 {
@@ -51,7 +63,15 @@ var (
 					}
 
 					if ok {
-						revs, err := secrets.ListVersions(s, sec.OrgId, sec.Id, tool.ParsePageOpts(cli)...)
+						var revs []secret.Secret
+						if cli.Bool(AllFlag.Name) {
+							err = secrets.EachVersion(s, sec.OrgId, sec.Id, func(rev secret.Secret) error {
+								revs = append(revs, rev)
+								return nil
+							}, tool.ParsePageOpts(cli)...)
+						} else {
+							revs, err = secrets.ListVersions(s, sec.OrgId, sec.Id, tool.ParsePageOpts(cli)...)
+						}
 						if err != nil {
 							return err
 						}
@@ -73,9 +93,18 @@ var (
 					}
 				}
 
-				results, err := secrets.Search(s, s.Options().OrgId,
-					secret.BuildFilter(filters...),
-					tool.ParsePageOpts(cli)...)
+				var results []secret.SecretSummary
+				if cli.Bool(AllFlag.Name) {
+					err = secrets.SearchEach(s, s.Options().OrgId, secret.BuildFilter(filters...),
+						func(sec secret.SecretSummary) error {
+							results = append(results, sec)
+							return nil
+						}, tool.ParsePageOpts(cli)...)
+				} else {
+					results, err = secrets.Search(s, s.Options().OrgId,
+						secret.BuildFilter(filters...),
+						tool.ParsePageOpts(cli)...)
+				}
 				if err != nil {
 					return
 				}
@@ -152,8 +181,10 @@ func actionsFormatter(a policy.Actions) string {
 	if len(a) == 0 {
 		return "unauthorized"
 	}
-	if a.Enabled("sudo") {
-		return "sudo"
+
+	all := policy.ToStrings(a.Flatten())
+	for _, act := range a.Denials() {
+		all = append(all, "!"+string(act))
 	}
-	return strings.Join(policy.ToStrings(a.Flatten()), ",")
+	return strings.Join(all, ",")
 }
@@ -0,0 +1,302 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cott-io/stash/lang/config"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/path"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	RotatorFlag = tool.StringFlag{
+		Name:  "rotator",
+		Usage: "The rotator used to generate values (password, rsa, ed25519, command)",
+	}
+
+	EveryFlag = tool.StringFlag{
+		Name:  "every",
+		Usage: "The rotation schedule (e.g. 720h).  Rotated by the rotate-daemon",
+	}
+
+	RotateCmdFlag = tool.StringFlag{
+		Name:  "command",
+		Usage: "The name of a command rotator's command.  Commands are configured locally in the --commands file",
+	}
+
+	CommandsFlag = tool.StringFlag{
+		Name:    "commands",
+		Usage:   "A file of the commands available to command rotators, by name",
+		Default: "~/.stash/rotators.yaml",
+	}
+
+	DependsFlag = tool.StringsFlag{
+		Name:  "depends",
+		Usage: "A secret that must be rotated before this secret.  May be repeated",
+	}
+
+	IntervalFlag = tool.StringFlag{
+		Name:    "interval",
+		Usage:   "How often to check for due rotations",
+		Default: "1m",
+	}
+
+	ParallelismFlag = tool.IntFlag{
+		Name:    "parallelism",
+		Usage:   "The maximum number of concurrent rotations",
+		Default: 4,
+	}
+
+	RotateCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "rotate",
+			Usage: "rotate /<name> [--rotator <type>] [--every <duration>]",
+			Info:  "Rotate a secret",
+			Help: `
+Rotates a secret, writing a newly generated value as the next version.
+Any secret that depends on the rotated secret is rotated afterwards.
+
+The rotation of a secret is configured with the --rotator, --every,
+--command and --depends flags.  Once configured, the flags may be
+omitted.  The following rotators are supported:
+
+* password - A strong random password
+* rsa      - A PKCS1 encoded RSA private key
+* ed25519  - A PKCS8 encoded Ed25519 private key
+* command  - The output of a command.  The current value is provided
+             on stdin along with STASH_SECRET_NAME and STASH_SECRET_VERSION
+
+A secret only names the command of a command rotator.  The command
+itself is read from the --commands file of the machine that rotates
+the secret, so that editors of the secret cannot run commands on it:
+
+	# ~/.stash/rotators.yaml
+	db-url: echo "postgres://app:$(stash secret view /prod/db/password)@db/app"
+
+Examples:
+
+	$ stash secret rotate /prod/db/password --rotator password --every 720h
+	$ stash secret rotate /prod/db/url --rotator command --command db-url \
+		--depends /prod/db/password
+
+`,
+			Flags: tool.NewFlags(RotatorFlag, EveryFlag, RotateCmdFlag, DependsFlag, CommandsFlag, ParallelismFlag),
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				if len(cli.Args()) != 1 {
+					err = errors.Wrapf(errs.ArgError, "Expected a secret")
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return err
+				}
+				defer s.Close()
+
+				cur, err := secrets.RequireByName(s, s.Options().OrgId, cli.Args().Get(0))
+				if err != nil {
+					return
+				}
+
+				if err = policy.Has(policy.Edit)(cur.Actions); err != nil {
+					return
+				}
+
+				rot, changed, err := parseRotation(cli, cur.Rotation)
+				if err != nil {
+					return
+				}
+
+				if changed {
+					next, err := cur.Update().SetRotation(rot).Compile()
+					if err != nil {
+						return err
+					}
+
+					if err = secrets.SaveSecret(s, next); err != nil {
+						return err
+					}
+				}
+
+				cmds, err := readCommands(cli.String(CommandsFlag.Name))
+				if err != nil {
+					return
+				}
+
+				plan, err := secrets.PlanRotation(s, s.Options().OrgId, cur.Name)
+				if err != nil {
+					return
+				}
+
+				_, err = secrets.RotateAll(s, plan,
+					secrets.RotateParallelism(cli.Int(ParallelismFlag.Name)),
+					secrets.RotateCommands(cmds),
+					secrets.RotateObserver(displayRotation(env)))
+				return
+			},
+		})
+
+	RotateDaemonCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "rotate-daemon",
+			Usage: "rotate-daemon [--interval <duration>]",
+			Info:  "Rotate secrets on their schedules",
+			Help: `
+Runs in the foreground, periodically rotating any secret whose rotation
+schedule has elapsed.  Dependent secrets are rotated after the secrets
+they depend upon.  Command rotators run the commands of the --commands
+file.
+
+Example:
+
+	$ stash rotate-daemon --interval 5m
+
+`,
+			Flags: tool.NewFlags(IntervalFlag, CommandsFlag, ParallelismFlag),
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				interval, err := time.ParseDuration(cli.String(IntervalFlag.Name))
+				if err != nil {
+					err = errors.Wrapf(errs.ArgError, "Invalid interval [%v]", cli.String(IntervalFlag.Name))
+					return
+				}
+
+				cmds, err := readCommands(cli.String(CommandsFlag.Name))
+				if err != nil {
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return err
+				}
+				defer s.Close()
+
+				sig := make(chan os.Signal, 2)
+				signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+				ctrl := context.NewRootControl()
+				defer ctrl.Close()
+				go func() {
+					select {
+					case <-sig:
+						ctrl.Close()
+					case <-ctrl.Closed():
+					}
+				}()
+
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					if err := rotateDue(env, s, cmds, cli.Int(ParallelismFlag.Name), ctrl.Closed()); err != nil {
+						env.Context.Logger().Error("Error rotating secrets: %v", err)
+					}
+
+					select {
+					case <-ctrl.Closed():
+						return nil
+					case <-ticker.C:
+					}
+				}
+			},
+		})
+)
+
+func rotateDue(env tool.Environment, s session.Session, cmds map[string]string, parallelism int, cancel <-chan struct{}) (err error) {
+	orgId := s.Options().OrgId
+
+	all, err := secrets.ListRotations(s, orgId)
+	if err != nil {
+		return
+	}
+
+	due := secrets.CollectDue(all, time.Now())
+	if len(due) == 0 {
+		return
+	}
+
+	plan, err := secrets.PlanRotation(s, orgId, due...)
+	if err != nil {
+		return
+	}
+
+	_, err = secrets.RotateAll(s, plan,
+		secrets.RotateParallelism(parallelism),
+		secrets.RotateCommands(cmds),
+		secrets.RotateCanceler(cancel),
+		secrets.RotateObserver(displayRotation(env)))
+	return
+}
+
+// Reads the locally configured commands of command rotators.  A missing
+// file configures no commands.
+func readCommands(file string) (ret map[string]string, err error) {
+	ret = make(map[string]string)
+
+	expanded, err := path.Expand(file)
+	if err != nil {
+		return
+	}
+
+	if _, err = os.Stat(expanded); os.IsNotExist(err) {
+		err = nil
+		return
+	}
+
+	err = config.ReadFile(file, &ret)
+	return
+}
+
+func parseRotation(cli *cli.Context, cur secret.Rotation) (ret secret.Rotation, changed bool, err error) {
+	ret = cur
+	if str := cli.String(RotatorFlag.Name); str != "" {
+		ret.Rotator, err = secret.ParseRotatorType(str)
+		if err != nil {
+			return
+		}
+		changed = true
+	}
+	if str := cli.String(EveryFlag.Name); str != "" {
+		ret.Interval, err = time.ParseDuration(str)
+		if err != nil {
+			err = errors.Wrapf(errs.ArgError, "Invalid schedule [%v]", str)
+			return
+		}
+		changed = true
+	}
+	if str := cli.String(RotateCmdFlag.Name); str != "" {
+		ret.Command, changed = str, true
+	}
+	if deps := cli.StringSlice(DependsFlag.Name); len(deps) > 0 {
+		ret.Depends, changed = deps, true
+	}
+
+	if !ret.Enabled() {
+		err = errors.Wrapf(errs.ArgError, "No rotation configured. Please specify a --rotator")
+		return
+	}
+
+	err = ret.Validate()
+	return
+}
+
+func displayRotation(env tool.Environment) func(prev, next secret.Secret, err error) {
+	return func(prev, next secret.Secret, err error) {
+		if err != nil {
+			fmt.Fprintf(env.Terminal.IO.StdErr(), "Error rotating [%v]: %v\n", prev.Name, err)
+			return
+		}
+		fmt.Fprintf(env.Terminal.IO.StdOut(), "Rotated [%v] to version [%v]\n", next.Name, next.Version)
+	}
+}
@@ -0,0 +1,116 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/term"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var (
+	HookFlag = tool.StringFlag{
+		Name:  "exec",
+		Usage: "Run a command for every event",
+	}
+
+	AfterFlag = tool.IntFlag{
+		Name:  "after",
+		Usage: "Replay all events after the given sequence number",
+	}
+
+	WatchCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "watch",
+			Usage: "watch [/<prefix>]",
+			Info:  "Watch for changes to secrets",
+			Help: `
+Watch for changes to secrets under a prefix.  Each change is printed
+as it happens, or if a command is given, the command is run with the
+details of the event injected into the environment:
+
+	STASH_EVENT_SEQ, STASH_EVENT_TYPE, STASH_EVENT_NAME,
+	STASH_EVENT_VERSION, STASH_EVENT_DELETED
+
+Example:
+
+	$ stash secret watch /project --exec 'systemctl reload app'
+
+`,
+			Flags: tool.NewFlags(HookFlag, AfterFlag),
+			Exec: func(env tool.Environment, cli *cli.Context) (err error) {
+				if len(cli.Args()) > 1 {
+					err = errors.Wrapf(errs.ArgError, "Expected at most one prefix")
+					return
+				}
+
+				s, err := session.NewDefaultSession(env.Context, env.Config)
+				if err != nil {
+					return err
+				}
+				defer s.Close()
+
+				sig := make(chan os.Signal, 2)
+				signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+				ctrl := context.NewRootControl()
+				defer ctrl.Close()
+				go func() {
+					select {
+					case <-sig:
+						ctrl.Close()
+					case <-ctrl.Closed():
+					}
+				}()
+
+				watcher := secrets.Watch(s, s.Options().OrgId, cli.Args().Get(0),
+					secrets.WatchAfter(cli.Int(AfterFlag.Name)),
+					secrets.WatchCanceler(ctrl.Closed()))
+
+				hook := cli.String(HookFlag.Name)
+				for {
+					event, err := watcher.Next()
+					if err != nil {
+						if errs.Is(err, errs.CanceledError) {
+							return nil
+						}
+						return err
+					}
+
+					if hook == "" {
+						if err := tool.DisplayStdOut(env, secretEventTemplate, tool.WithData(event)); err != nil {
+							return err
+						}
+						continue
+					}
+
+					if err := term.Exec(term.SystemShell, env.Terminal.IO, hook, term.WithEnv(eventEnv(event))); err != nil {
+						env.Context.Logger().Error("Error running hook [%v] for event [%v]: %v", hook, event.Seq, err)
+					}
+				}
+			},
+		})
+)
+
+func eventEnv(e secret.Event) map[string]string {
+	return map[string]string{
+		"STASH_EVENT_SEQ":     fmt.Sprint(e.Seq),
+		"STASH_EVENT_TYPE":    string(e.Type),
+		"STASH_EVENT_NAME":    e.Name,
+		"STASH_EVENT_VERSION": fmt.Sprint(e.Version),
+		"STASH_EVENT_DELETED": fmt.Sprint(e.Deleted),
+	}
+}
+
+var (
+	secretEventTemplate = `{{"*" | item}} [{{ .Seq }}] {{ .Created | date }} {{ .Type | printf "%v" | col 8 | info }} {{ .Name }}@{{ .Version }} {{- if .Deleted }} [{{ "deleted" | notice }}] {{- end }}`
+)
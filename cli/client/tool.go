@@ -32,8 +32,49 @@ var (
 		Usage: "The org to use during login",
 	}
 
+	RekeyFlag = tool.BoolFlag{
+		Name:  "rekey",
+		Usage: "Replace the keys of the policy and re-encrypt its items",
+	}
+
+	NotBeforeFlag = tool.StringFlag{
+		Name:  "not-before",
+		Usage: "The membership is not valid before this time (RFC3339 or a duration from now)",
+	}
+
+	NotAfterFlag = tool.StringFlag{
+		Name:  "not-after",
+		Usage: "The membership expires at this time (RFC3339 or a duration from now)",
+	}
+
+	CidrFlag = tool.StringsFlag{
+		Name:  "cidr",
+		Usage: "A source network from which the membership may be used.  May be repeated",
+	}
+
+	RequireLoginFlag = tool.StringsFlag{
+		Name:  "require-login",
+		Usage: "A login protocol required to use the membership (password, signature).  May be repeated",
+	}
+
+	DeviceFlag = tool.StringsFlag{
+		Name:  "device",
+		Usage: "A device from which the membership may be used.  May be repeated",
+	}
+
+	DenyFlag = tool.StringsFlag{
+		Name:  "deny",
+		Usage: "An action denied to the member, overriding any grant.  May be repeated",
+	}
+
 	// Convenience aggregator (for use in individual commands)
 	AuthFlags = tool.NewFlags(LoginFlag, OrgFlag)
+
+	// Conditions that may be placed on a membership
+	ConditionFlags = tool.NewFlags(NotBeforeFlag, NotAfterFlag, CidrFlag, RequireLoginFlag, DeviceFlag)
+
+	// Flags of commands granting a membership
+	GrantFlags = tool.NewFlags(DenyFlag, NotBeforeFlag, NotAfterFlag, CidrFlag, RequireLoginFlag, DeviceFlag)
 )
 
 var zero uuid.UUID
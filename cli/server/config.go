@@ -0,0 +1,532 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/config"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// The environment variable naming the server's config file.  The
+// --config flag takes precedence.
+const ConfigFileEnv = "STASH_SERVER_CONFIG"
+
+// The value printed in place of secrets.
+const Redacted = "<redacted>"
+
+// The configuration of a server.  The configuration is layered: the
+// defaults are overridden by the config file (yaml, toml or json),
+// which is overridden by environment variables, which are overridden
+// by flags.
+type Config struct {
+	Addr         string   `yaml:"addr" toml:"addr" json:"addr"`
+	AdminAddr    string   `yaml:"admin_addr" toml:"admin_addr" json:"admin_addr"`
+	GrpcAddr     string   `yaml:"grpc_addr" toml:"grpc_addr" json:"grpc_addr"`
+	Logging      string   `yaml:"logging" toml:"logging" json:"logging"`
+	LogFormat    string   `yaml:"log_format" toml:"log_format" json:"log_format"`
+	DrainDelay   Duration `yaml:"drain_delay" toml:"drain_delay" json:"drain_delay"`
+	DrainTimeout Duration `yaml:"drain_timeout" toml:"drain_timeout" json:"drain_timeout"`
+	SigningKey   string   `yaml:"signing_key" toml:"signing_key" json:"signing_key"`
+
+	DB      DBConfig      `yaml:"db" toml:"db" json:"db"`
+	TLS     TLSConfig     `yaml:"tls" toml:"tls" json:"tls"`
+	Limits  LimitsConfig  `yaml:"limits" toml:"limits" json:"limits"`
+	Mail    MailConfig    `yaml:"mail" toml:"mail" json:"mail"`
+	SMS     SMSConfig     `yaml:"sms" toml:"sms" json:"sms"`
+	Billing BillingConfig `yaml:"billing" toml:"billing" json:"billing"`
+	Audit   AuditConfig   `yaml:"audit" toml:"audit" json:"audit"`
+}
+
+type DBConfig struct {
+	Driver string `yaml:"driver" toml:"driver" json:"driver"`
+	Addr   string `yaml:"addr" toml:"addr" json:"addr"`
+}
+
+type TLSConfig struct {
+	Cert       string `yaml:"cert" toml:"cert" json:"cert"`
+	Key        string `yaml:"key" toml:"key" json:"key"`
+	ClientCA   string `yaml:"client_ca" toml:"client_ca" json:"client_ca"`
+	ClientAuth string `yaml:"client_auth" toml:"client_auth" json:"client_auth"`
+	ClientMap  string `yaml:"client_map" toml:"client_map" json:"client_map"`
+}
+
+// Rates are given as a count per unit (e.g. 50/s).  Empty rates and
+// zero sizes are not limited.
+type LimitsConfig struct {
+	RateAccount   string `yaml:"rate_account" toml:"rate_account" json:"rate_account"`
+	RateOrg       string `yaml:"rate_org" toml:"rate_org" json:"rate_org"`
+	RateRemote    string `yaml:"rate_ip" toml:"rate_ip" json:"rate_ip"`
+	RateBulk      string `yaml:"rate_bulk" toml:"rate_bulk" json:"rate_bulk"`
+	MaxBody       int64  `yaml:"max_body" toml:"max_body" json:"max_body"`
+	MaxBlockBody  int64  `yaml:"max_block_body" toml:"max_block_body" json:"max_block_body"`
+	OrgBlockQuota int64  `yaml:"org_block_quota" toml:"org_block_quota" json:"org_block_quota"`
+}
+
+type MailConfig struct {
+	Domain string `yaml:"domain" toml:"domain" json:"domain"`
+	PubKey string `yaml:"pub_key" toml:"pub_key" json:"pub_key"`
+	ApiKey string `yaml:"api_key" toml:"api_key" json:"api_key"`
+}
+
+type SMSConfig struct {
+	Number string `yaml:"number" toml:"number" json:"number"`
+	AppSid string `yaml:"app_sid" toml:"app_sid" json:"app_sid"`
+	Token  string `yaml:"token" toml:"token" json:"token"`
+}
+
+type BillingConfig struct {
+	ApiKey string `yaml:"api_key" toml:"api_key" json:"api_key"`
+	PubKey string `yaml:"pub_key" toml:"pub_key" json:"pub_key"`
+}
+
+type AuditConfig struct {
+	Sinks         []string `yaml:"sinks" toml:"sinks" json:"sinks"`
+	Buffer        int      `yaml:"buffer" toml:"buffer" json:"buffer"`
+	File          string   `yaml:"file" toml:"file" json:"file"`
+	FileMaxSize   int      `yaml:"file_max_size" toml:"file_max_size" json:"file_max_size"`
+	FileMaxFiles  int      `yaml:"file_max_files" toml:"file_max_files" json:"file_max_files"`
+	SyslogAddr    string   `yaml:"syslog_addr" toml:"syslog_addr" json:"syslog_addr"`
+	SyslogNetwork string   `yaml:"syslog_network" toml:"syslog_network" json:"syslog_network"`
+}
+
+// A duration that is written as text (e.g. 30s) in config files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(raw []byte) (err error) {
+	dur, err := time.ParseDuration(string(raw))
+	if err != nil {
+		return
+	}
+	*d = Duration(dur)
+	return
+}
+
+var DefaultConfig = Config{
+	Addr:         ":8080",
+	Logging:      context.Info.String(),
+	LogFormat:    context.Text.String(),
+	DrainTimeout: Duration(30 * time.Second),
+	DB: DBConfig{
+		Driver: "sqlite",
+		Addr:   ":memory:",
+	},
+	TLS: TLSConfig{
+		ClientAuth: "require",
+	},
+	Limits: LimitsConfig{
+		RateAccount:  "50/s",
+		RateOrg:      "200/s",
+		RateRemote:   "100/s",
+		RateBulk:     "10/s",
+		MaxBody:      1 << 20,
+		MaxBlockBody: 4 << 20,
+	},
+	Audit: AuditConfig{
+		Buffer:        4096,
+		FileMaxSize:   100 << 20,
+		FileMaxFiles:  10,
+		SyslogNetwork: "udp",
+	},
+}
+
+// Loads the configuration of the server from its layers.  Flags are
+// only applied if they are defined by the command and set.
+func LoadConfig(c *cli.Context) (ret Config, err error) {
+	ret = DefaultConfig
+	ret.Audit.Sinks = append([]string(nil), DefaultConfig.Audit.Sinks...)
+
+	file := c.String(ConfigFlag.Name)
+	if file == "" {
+		file = os.Getenv(ConfigFileEnv)
+	}
+	if file != "" {
+		if err = config.ReadFile(file, &ret); err != nil {
+			return
+		}
+	}
+
+	if err = ret.applyEnv(); err != nil {
+		return
+	}
+	ret.applyFlags(c)
+	return
+}
+
+func (c *Config) applyEnv() (err error) {
+	envString(&c.SigningKey, "STASH_SIGNING_KEY")
+	envString(&c.DB.Driver, "STASH_DB_DRIVER")
+	envString(&c.DB.Addr, "STASH_DB_ADDR")
+	envString(&c.Mail.Domain, "STASH_MX_DOMAIN")
+	envString(&c.Mail.PubKey, "STASH_MX_PUBKEY")
+	envString(&c.Mail.ApiKey, "STASH_MX_APIKEY")
+	envString(&c.Billing.ApiKey, "STASH_CC_APIKEY")
+	envString(&c.Billing.PubKey, "STASH_CC_PUBKEY")
+	envString(&c.SMS.Number, "STASH_SMS_NUMBER")
+	envString(&c.SMS.AppSid, "STASH_SMS_APPSID")
+	envString(&c.SMS.Token, "STASH_SMS_TOKEN")
+	envString(&c.Audit.File, "STASH_AUDIT_FILE")
+	envString(&c.Audit.SyslogAddr, "STASH_AUDIT_SYSLOG_ADDR")
+	envString(&c.Audit.SyslogNetwork, "STASH_AUDIT_SYSLOG_NETWORK")
+
+	if str := os.Getenv("STASH_AUDIT_SINKS"); str != "" {
+		c.Audit.Sinks = nil
+		for _, name := range strings.Split(str, ",") {
+			c.Audit.Sinks = append(c.Audit.Sinks, strings.TrimSpace(name))
+		}
+	}
+
+	if err = envInt(&c.Audit.Buffer, "STASH_AUDIT_BUFFER"); err != nil {
+		return
+	}
+	if err = envInt(&c.Audit.FileMaxSize, "STASH_AUDIT_FILE_MAX_SIZE"); err != nil {
+		return
+	}
+	err = envInt(&c.Audit.FileMaxFiles, "STASH_AUDIT_FILE_MAX_FILES")
+	return
+}
+
+func (c *Config) applyFlags(ctx *cli.Context) {
+	for _, f := range []struct {
+		name string
+		ptr  *string
+	}{
+		{AddrFlag.Name, &c.Addr},
+		{AdminAddrFlag.Name, &c.AdminAddr},
+		{GrpcAddrFlag.Name, &c.GrpcAddr},
+		{LoggingFlag.Name, &c.Logging},
+		{LogFormatFlag.Name, &c.LogFormat},
+		{RateAccountFlag.Name, &c.Limits.RateAccount},
+		{RateOrgFlag.Name, &c.Limits.RateOrg},
+		{RateRemoteFlag.Name, &c.Limits.RateRemote},
+		{RateBulkFlag.Name, &c.Limits.RateBulk},
+		{TLSCertFlag.Name, &c.TLS.Cert},
+		{TLSKeyFlag.Name, &c.TLS.Key},
+		{TLSClientCAFlag.Name, &c.TLS.ClientCA},
+		{TLSClientAuthFlag.Name, &c.TLS.ClientAuth},
+		{TLSClientMapFlag.Name, &c.TLS.ClientMap},
+	} {
+		if ctx.IsSet(f.name) {
+			*f.ptr = ctx.String(f.name)
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		ptr  *int64
+	}{
+		{MaxBodyFlag.Name, &c.Limits.MaxBody},
+		{MaxBlockBodyFlag.Name, &c.Limits.MaxBlockBody},
+		{BlockQuotaFlag.Name, &c.Limits.OrgBlockQuota},
+	} {
+		if ctx.IsSet(f.name) {
+			*f.ptr = int64(ctx.Int(f.name))
+		}
+	}
+
+	if ctx.IsSet(DrainDelayFlag.Name) {
+		c.DrainDelay = Duration(ctx.Duration(DrainDelayFlag.Name))
+	}
+	if ctx.IsSet(DrainTimeoutFlag.Name) {
+		c.DrainTimeout = Duration(ctx.Duration(DrainTimeoutFlag.Name))
+	}
+}
+
+func envString(ptr *string, name string) {
+	if str := os.Getenv(name); str != "" {
+		*ptr = str
+	}
+}
+
+func envInt(ptr *int, name string) (err error) {
+	str := os.Getenv(name)
+	if str == "" {
+		return
+	}
+
+	if *ptr, err = strconv.Atoi(str); err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid value for %v [%v]. Expected an integer", name, str)
+	}
+	return
+}
+
+// Validates the configuration, returning an error that describes
+// every invalid value.
+func (c Config) Validate() (err error) {
+	var invalid []string
+	fail := func(format string, args ...interface{}) {
+		invalid = append(invalid, fmt.Sprintf(format, args...))
+	}
+
+	if c.Addr == "" {
+		fail("addr: Required")
+	}
+
+	if c.GrpcAddr != "" && c.GrpcAddr == c.Addr {
+		fail("grpc_addr: Must differ from addr")
+	}
+
+	if _, err := context.ParseLogLevel(c.Logging); err != nil {
+		fail("logging: Invalid level [%v]. Expected one of [Off, Debug, Info, Error]", c.Logging)
+	}
+
+	if _, err := context.ParseLogFormat(c.LogFormat); err != nil {
+		fail("log_format: Invalid format [%v]. Expected one of [Text, Json, Logfmt]", c.LogFormat)
+	}
+
+	if c.DrainDelay < 0 {
+		fail("drain_delay: Must not be negative")
+	}
+
+	if c.DrainTimeout < 0 {
+		fail("drain_timeout: Must not be negative")
+	}
+
+	if c.SigningKey != "" {
+		if _, err := crypto.ReadPrivateKey(bytes.NewBufferString(c.SigningKey), crypto.DecodePKCS1); err != nil {
+			fail("signing_key: Unable to decode PKCS1 private key: %v", err)
+		}
+	}
+
+	switch c.DB.Driver {
+	default:
+		fail("db.driver: Invalid driver [%v]. Expected one of [sqlite]", c.DB.Driver)
+	case "", "sqlite":
+	}
+
+	switch {
+	case c.TLS.Cert == "" && c.TLS.Key == "":
+		if c.TLS.ClientCA != "" {
+			fail("tls.client_ca: Client verification requires a tls certificate")
+		}
+	case c.TLS.Cert == "" || c.TLS.Key == "":
+		fail("tls: Must provide both a certificate and key")
+	}
+
+	for key, file := range map[string]string{
+		"tls.cert":       c.TLS.Cert,
+		"tls.key":        c.TLS.Key,
+		"tls.client_ca":  c.TLS.ClientCA,
+		"tls.client_map": c.TLS.ClientMap,
+	} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			fail("%v: Unable to read [%v]: %v", key, file, err)
+		}
+	}
+
+	switch c.TLS.ClientAuth {
+	default:
+		fail("tls.client_auth: Invalid value [%v]. Expected one of [require, optional]", c.TLS.ClientAuth)
+	case "", "require", "optional":
+	}
+
+	if c.TLS.ClientMap != "" && c.TLS.ClientCA == "" {
+		fail("tls.client_map: Client certificate bindings require tls.client_ca")
+	}
+
+	for key, rate := range map[string]string{
+		"limits.rate_account": c.Limits.RateAccount,
+		"limits.rate_org":     c.Limits.RateOrg,
+		"limits.rate_ip":      c.Limits.RateRemote,
+		"limits.rate_bulk":    c.Limits.RateBulk,
+	} {
+		if rate == "" {
+			continue
+		}
+		if _, err := http.ParseRate(rate); err != nil {
+			fail("%v: Invalid rate [%v]. Expected a positive <n>/<s|m|h> (e.g. 50/s)", key, rate)
+		}
+	}
+
+	for key, size := range map[string]int64{
+		"limits.max_body":        c.Limits.MaxBody,
+		"limits.max_block_body":  c.Limits.MaxBlockBody,
+		"limits.org_block_quota": c.Limits.OrgBlockQuota,
+	} {
+		if size < 0 {
+			fail("%v: Must not be negative", key)
+		}
+	}
+
+	if !allOrNone(c.Mail.Domain, c.Mail.PubKey, c.Mail.ApiKey) {
+		fail("mail: Must provide all or none of [domain, pub_key, api_key]")
+	}
+	if !allOrNone(c.SMS.Number, c.SMS.AppSid, c.SMS.Token) {
+		fail("sms: Must provide all or none of [number, app_sid, token]")
+	}
+	if !allOrNone(c.Billing.ApiKey, c.Billing.PubKey) {
+		fail("billing: Must provide all or none of [api_key, pub_key]")
+	}
+
+	for _, sink := range c.Audit.Sinks {
+		switch sink {
+		default:
+			fail("audit.sinks: Invalid sink [%v]. Expected one of [file, syslog, stdout]", sink)
+		case "stdout":
+		case "file":
+			if c.Audit.File == "" {
+				fail("audit.file: Required by the file sink")
+			}
+		case "syslog":
+			if c.Audit.SyslogAddr == "" {
+				fail("audit.syslog_addr: Required by the syslog sink")
+			}
+		}
+	}
+
+	if c.Audit.Buffer < 0 || c.Audit.FileMaxSize < 0 || c.Audit.FileMaxFiles < 0 {
+		fail("audit: Sizes must not be negative")
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		err = errors.Wrapf(errs.ArgError, "Invalid config:\n\t%v", strings.Join(invalid, "\n\t"))
+	}
+	return
+}
+
+func allOrNone(vals ...string) bool {
+	var n int
+	for _, v := range vals {
+		if v != "" {
+			n++
+		}
+	}
+	return n == 0 || n == len(vals)
+}
+
+// Returns a copy of the configuration with its secrets redacted.
+func (c Config) Redact() Config {
+	for _, ptr := range []*string{
+		&c.SigningKey,
+		&c.Mail.ApiKey,
+		&c.SMS.Token,
+		&c.Billing.ApiKey,
+	} {
+		if *ptr != "" {
+			*ptr = Redacted
+		}
+	}
+	return c
+}
+
+var (
+	ConfigFlag = tool.StringFlag{
+		Name:  "config",
+		Usage: "A yaml, toml or json config file. Defaults to $" + ConfigFileEnv,
+	}
+
+	RedactFlag = tool.BoolFlag{
+		Name:  "redact",
+		Usage: "Redact secrets (e.g. api keys and the signing key)",
+	}
+
+	FormatFlag = tool.StringFlag{
+		Name:    "format",
+		Usage:   "The format in which to print the config (yaml, toml, json)",
+		Default: "yaml",
+	}
+
+	ConfigCommands = tool.NewGroup(
+		tool.GroupDef{
+			Name: "config",
+			Info: "Inspect the server configuration",
+		},
+		ConfigPrintCommand,
+		ConfigValidateCommand,
+	)
+
+	ConfigPrintCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "print",
+			Usage: "config print",
+			Info:  "Prints the effective server configuration",
+			Help: `
+Prints the configuration the server would run with: the defaults,
+overridden by the config file, overridden by environment variables.
+Flags given to the run command take precedence over all of these.
+
+Examples:
+
+	$ stash config print --redact
+	$ stash config print --config server.yaml --format toml
+`,
+			Flags: tool.NewFlags(ConfigFlag, RedactFlag, FormatFlag),
+			Exec:  ServerConfigPrint,
+		})
+
+	ConfigValidateCommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "validate",
+			Usage: "config validate",
+			Info:  "Validates the server configuration",
+			Help: `
+Validates the configuration the server would run with, reporting
+every invalid value.
+
+Examples:
+
+	$ stash config validate --config server.yaml
+`,
+			Flags: tool.NewFlags(ConfigFlag),
+			Exec:  ServerConfigValidate,
+		})
+)
+
+func ServerConfigPrint(env tool.Environment, c *cli.Context) (err error) {
+	conf, err := LoadConfig(c)
+	if err != nil {
+		return
+	}
+
+	if c.Bool(RedactFlag.Name) {
+		conf = conf.Redact()
+	}
+
+	codec, err := config.Codec("config." + c.String(FormatFlag.Name))
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid --%v [%v]. Expected one of [yaml, toml, json]", FormatFlag.Name, c.String(FormatFlag.Name))
+		return
+	}
+
+	raw, err := enc.Encode(codec, conf)
+	if err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintln(env.Terminal.IO.StdOut(), strings.TrimSpace(string(raw)))
+	return
+}
+
+func ServerConfigValidate(env tool.Environment, c *cli.Context) (err error) {
+	conf, err := LoadConfig(c)
+	if err != nil {
+		return
+	}
+
+	if err = conf.Validate(); err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintln(env.Terminal.IO.StdOut(), "Config is valid.")
+	return
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func newContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("run", flag.ContinueOnError)
+	for _, f := range tool.NewFlags(ConfigFlag, AddrFlag, LoggingFlag, RateOrgFlag, DrainTimeoutFlag) {
+		f.Build().Apply(set)
+	}
+	if !assert.Nil(t, set.Parse(args)) {
+		t.FailNow()
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	file := filepath.Join(dir, name)
+	if !assert.Nil(t, ioutil.WriteFile(file, []byte(data), 0600)) {
+		t.FailNow()
+	}
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := writeFile(t, dir, "server.yaml", `
+addr: ":9000"
+logging: Debug
+drain_timeout: 10s
+db:
+  addr: file.db
+limits:
+  rate_org: 10/s
+`)
+
+	t.Run("Defaults", func(t *testing.T) {
+		conf, err := LoadConfig(newContext(t))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, DefaultConfig, conf)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		conf, err := LoadConfig(newContext(t, "--config", file))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, ":9000", conf.Addr)
+		assert.Equal(t, "Debug", conf.Logging)
+		assert.Equal(t, Duration(10*time.Second), conf.DrainTimeout)
+		assert.Equal(t, "file.db", conf.DB.Addr)
+		assert.Equal(t, "10/s", conf.Limits.RateOrg)
+
+		// Values missing from the file keep their defaults.
+		assert.Equal(t, DefaultConfig.DB.Driver, conf.DB.Driver)
+		assert.Equal(t, DefaultConfig.Limits.RateAccount, conf.Limits.RateAccount)
+	})
+
+	t.Run("FileEnv", func(t *testing.T) {
+		os.Setenv(ConfigFileEnv, file)
+		defer os.Unsetenv(ConfigFileEnv)
+
+		conf, err := LoadConfig(newContext(t))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, ":9000", conf.Addr)
+	})
+
+	t.Run("EnvOverridesFile", func(t *testing.T) {
+		os.Setenv("STASH_DB_ADDR", "env.db")
+		defer os.Unsetenv("STASH_DB_ADDR")
+
+		conf, err := LoadConfig(newContext(t, "--config", file))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "env.db", conf.DB.Addr)
+		assert.Equal(t, ":9000", conf.Addr)
+	})
+
+	t.Run("FlagOverridesFile", func(t *testing.T) {
+		conf, err := LoadConfig(newContext(t, "--config", file, "--addr", ":9001", "--drain-timeout", "1m"))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, ":9001", conf.Addr)
+		assert.Equal(t, Duration(time.Minute), conf.DrainTimeout)
+		assert.Equal(t, "Debug", conf.Logging)
+	})
+
+	t.Run("InvalidEnv", func(t *testing.T) {
+		os.Setenv("STASH_AUDIT_BUFFER", "lots")
+		defer os.Unsetenv("STASH_AUDIT_BUFFER")
+
+		_, err := LoadConfig(newContext(t))
+		assert.True(t, errs.Is(err, errs.ArgError), "%v", err)
+	})
+}
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"server.yaml", "adr: \":9000\"\n"},
+		{"nested.yaml", "db:\n  adress: file.db\n"},
+		{"server.toml", "adr = \":9000\"\n"},
+		{"nested.toml", "[db]\nadress = \"file.db\"\n"},
+		{"server.json", `{"adr": ":9000"}`},
+		{"nested.json", `{"db": {"adress": "file.db"}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadConfig(newContext(t, "--config", writeFile(t, dir, test.name, test.data)))
+			assert.True(t, errs.Is(err, errs.ArgError), "%v", err)
+		})
+	}
+
+	t.Run("UnsupportedFile", func(t *testing.T) {
+		_, err := LoadConfig(newContext(t, "--config", writeFile(t, dir, "server.ini", "addr=:9000")))
+		assert.True(t, errs.Is(err, errs.ArgError), "%v", err)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	cert := writeFile(t, dir, "server.pem", "cert")
+
+	tests := []struct {
+		name   string
+		fn     func(*Config)
+		expect string
+	}{
+		{"NoAddr", func(c *Config) { c.Addr = "" }, "addr: Required"},
+		{"Logging", func(c *Config) { c.Logging = "Loud" }, "logging: Invalid level [Loud]"},
+		{"LogFormat", func(c *Config) { c.LogFormat = "xml" }, "log_format: Invalid format [xml]"},
+		{"DrainDelay", func(c *Config) { c.DrainDelay = -1 }, "drain_delay: Must not be negative"},
+		{"DrainTimeout", func(c *Config) { c.DrainTimeout = -1 }, "drain_timeout: Must not be negative"},
+		{"SigningKey", func(c *Config) { c.SigningKey = "key" }, "signing_key: Unable to decode"},
+		{"Driver", func(c *Config) { c.DB.Driver = "mysql" }, "db.driver: Invalid driver [mysql]"},
+		{"TLSCertOnly", func(c *Config) { c.TLS.Cert = cert }, "tls: Must provide both"},
+		{"TLSMissingFile", func(c *Config) { c.TLS.Cert, c.TLS.Key = cert, filepath.Join(dir, "missing.key") }, "tls.key: Unable to read"},
+		{"ClientCANoCert", func(c *Config) { c.TLS.ClientCA = cert }, "tls.client_ca: Client verification requires"},
+		{"ClientAuth", func(c *Config) { c.TLS.ClientAuth = "maybe" }, "tls.client_auth: Invalid value [maybe]"},
+		{"ClientMapNoCA", func(c *Config) { c.TLS.ClientMap = cert }, "tls.client_map: Client certificate bindings require tls.client_ca"},
+		{"Rate", func(c *Config) { c.Limits.RateOrg = "fast" }, "limits.rate_org: Invalid rate [fast]"},
+		{"Size", func(c *Config) { c.Limits.MaxBody = -1 }, "limits.max_body: Must not be negative"},
+		{"Mail", func(c *Config) { c.Mail.Domain = "mail.example.com" }, "mail: Must provide all or none"},
+		{"SMS", func(c *Config) { c.SMS.Token = "token" }, "sms: Must provide all or none"},
+		{"Billing", func(c *Config) { c.Billing.ApiKey = "key" }, "billing: Must provide all or none"},
+		{"Sink", func(c *Config) { c.Audit.Sinks = []string{"kafka"} }, "audit.sinks: Invalid sink [kafka]"},
+		{"FileSink", func(c *Config) { c.Audit.Sinks = []string{"file"} }, "audit.file: Required by the file sink"},
+		{"SyslogSink", func(c *Config) { c.Audit.Sinks = []string{"syslog"} }, "audit.syslog_addr: Required by the syslog sink"},
+		{"AuditSizes", func(c *Config) { c.Audit.Buffer = -1 }, "audit: Sizes must not be negative"},
+	}
+
+	assert.Nil(t, DefaultConfig.Validate())
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf := DefaultConfig
+			test.fn(&conf)
+
+			err := conf.Validate()
+			assert.True(t, errs.Is(err, errs.ArgError), "%v", err)
+			if err != nil {
+				assert.Contains(t, err.Error(), test.expect)
+			}
+		})
+	}
+
+	t.Run("Many", func(t *testing.T) {
+		conf := DefaultConfig
+		conf.Addr, conf.DB.Driver = "", "mysql"
+
+		err := conf.Validate()
+		if !assert.NotNil(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "addr: Required")
+		assert.Contains(t, err.Error(), "db.driver: Invalid driver")
+	})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cott-io/stash/lang/http/openapi"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/lang/tool"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// The path at which servers publish their api document.
+const OpenAPIPath = "/v1/openapi.json"
+
+var (
+	OpenAPIInfo = openapi.Info{
+		Title:       "Stash",
+		Version:     "v1",
+		Description: "The api of the stash secret management platform.",
+	}
+
+	OutFlag = tool.StringFlag{
+		Name:  "out",
+		Usage: "The file to which the document is written. Defaults to stdout",
+	}
+
+	OpenAPICommand = tool.NewCommand(
+		tool.CommandDef{
+			Name:  "openapi",
+			Usage: "openapi",
+			Info:  "Generates the OpenAPI document of the server",
+			Help: `
+Generates the OpenAPI 3 document of the server's api, without
+starting a server.  The same document is served by running
+servers at ` + OpenAPIPath + `.  The document may be used to
+generate clients in other languages.  Generation fails if any
+route of the server is undocumented.
+
+Examples:
+
+	$ stash openapi
+	$ stash openapi --out openapi.json
+`,
+			Flags: tool.NewFlags(OutFlag),
+			Exec:  ServerOpenAPI,
+		})
+)
+
+func ServerOpenAPI(env tool.Environment, c *cli.Context) (err error) {
+	if missing := http.Undocumented(DefaultHandlers...); len(missing) > 0 {
+		routes := make([]string, 0, len(missing))
+		for _, r := range missing {
+			routes = append(routes, r.Method+" "+r.Path)
+		}
+		err = errors.Errorf("Undocumented routes [%v]", strings.Join(routes, ", "))
+		return
+	}
+
+	raw, err := json.MarshalIndent(http.OpenAPI(OpenAPIInfo, DefaultHandlers...), "", "  ")
+	if err != nil {
+		return
+	}
+
+	out := c.String(OutFlag.Name)
+	if out == "" {
+		fmt.Fprintln(env.Terminal.IO.StdOut(), string(raw))
+		return
+	}
+
+	if err = ioutil.WriteFile(out, append(raw, '\n'), 0644); err != nil {
+		err = errors.Wrapf(err, "Error writing document [%v]", out)
+	}
+	return
+}
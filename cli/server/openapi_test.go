@@ -0,0 +1,12 @@
+package server
+
+import (
+	"testing"
+
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultHandlers_Documented(t *testing.T) {
+	assert.Empty(t, http.Undocumented(DefaultHandlers...))
+}
@@ -2,28 +2,43 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
+	gohttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/cott-io/stash/grpc/grpcserver"
 	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/http/server/httpaccess"
 	"github.com/cott-io/stash/http/server/httpaccount"
+	"github.com/cott-io/stash/http/server/httpaudit"
 	"github.com/cott-io/stash/http/server/httporg"
 	"github.com/cott-io/stash/http/server/httppolicy"
 	"github.com/cott-io/stash/http/server/httpsecret"
+	"github.com/cott-io/stash/http/server/httpwebhook"
 	"github.com/cott-io/stash/lang/billing"
+	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/crypto"
 	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/lang/mail"
+	"github.com/cott-io/stash/lang/metrics"
 	"github.com/cott-io/stash/lang/net"
 	"github.com/cott-io/stash/lang/sms"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/lang/tool"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/cott-io/stash/sql/sqlaccess"
 	"github.com/cott-io/stash/sql/sqlaccount"
+	"github.com/cott-io/stash/sql/sqlaudit"
 	"github.com/cott-io/stash/sql/sqlorg"
 	"github.com/cott-io/stash/sql/sqlpolicy"
 	"github.com/cott-io/stash/sql/sqlsecret"
+	"github.com/cott-io/stash/sql/sqlwebhook"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
@@ -34,6 +49,9 @@ var (
 		httporg.Handlers,
 		httppolicy.Handlers,
 		httpsecret.Handlers,
+		httpwebhook.Handlers,
+		httpaudit.Handlers,
+		httpaccess.Handlers,
 	}
 )
 
@@ -41,13 +59,108 @@ var (
 	AddrFlag = tool.StringFlag{
 		Name:    "addr",
 		Usage:   "The address to bind",
-		Default: ":8080",
+		Default: DefaultConfig.Addr,
+	}
+
+	GrpcAddrFlag = tool.StringFlag{
+		Name:  "grpc-addr",
+		Usage: "An address on which to serve the api over grpc (e.g. :8081)",
 	}
 
 	LoggingFlag = tool.StringFlag{
 		Name:    "logging",
 		Usage:   "Set the log level (Debug, Info, Error, Off)",
-		Default: "Info",
+		Default: DefaultConfig.Logging,
+	}
+
+	LogFormatFlag = tool.StringFlag{
+		Name:    "log-format",
+		Usage:   "Set the log format (Text, Json, Logfmt)",
+		Default: DefaultConfig.LogFormat,
+	}
+
+	AdminAddrFlag = tool.StringFlag{
+		Name:  "admin-addr",
+		Usage: "An address on which to serve metrics (e.g. localhost:9090)",
+	}
+
+	RateAccountFlag = tool.StringFlag{
+		Name:    "rate-account",
+		Usage:   "The rate of requests allowed per account (e.g. 50/s, 600/m). Empty to disable",
+		Default: DefaultConfig.Limits.RateAccount,
+	}
+
+	RateOrgFlag = tool.StringFlag{
+		Name:    "rate-org",
+		Usage:   "The rate of requests allowed per org, counting those made with tokens of the org. Empty to disable",
+		Default: DefaultConfig.Limits.RateOrg,
+	}
+
+	RateRemoteFlag = tool.StringFlag{
+		Name:    "rate-ip",
+		Usage:   "The rate of requests allowed per source ip. Empty to disable",
+		Default: DefaultConfig.Limits.RateRemote,
+	}
+
+	RateBulkFlag = tool.StringFlag{
+		Name:    "rate-bulk",
+		Usage:   "The rate of block uploads and secret listings allowed per account. Empty to disable",
+		Default: DefaultConfig.Limits.RateBulk,
+	}
+
+	MaxBodyFlag = tool.IntFlag{
+		Name:    "max-body",
+		Usage:   "The maximum size in bytes of request bodies. Zero to disable",
+		Default: int(DefaultConfig.Limits.MaxBody),
+	}
+
+	MaxBlockBodyFlag = tool.IntFlag{
+		Name:    "max-block-body",
+		Usage:   "The maximum size in bytes of block uploads. Zero to disable",
+		Default: int(DefaultConfig.Limits.MaxBlockBody),
+	}
+
+	BlockQuotaFlag = tool.IntFlag{
+		Name:  "org-block-quota",
+		Usage: "The bytes of secret data each org may store. Zero to disable",
+	}
+
+	TLSCertFlag = tool.StringFlag{
+		Name:  "tls-cert",
+		Usage: "A PEM encoded certificate (chain) with which to serve https",
+	}
+
+	TLSKeyFlag = tool.StringFlag{
+		Name:  "tls-key",
+		Usage: "The PEM encoded private key of the tls certificate",
+	}
+
+	TLSClientCAFlag = tool.StringFlag{
+		Name:  "tls-client-ca",
+		Usage: "A bundle of authorities trusted to sign client certificates.  Enables mutual tls",
+	}
+
+	TLSClientAuthFlag = tool.StringFlag{
+		Name:    "tls-client-auth",
+		Usage:   "Whether client certificates are required (require, optional)",
+		Default: DefaultConfig.TLS.ClientAuth,
+	}
+
+	TLSClientMapFlag = tool.StringFlag{
+		Name:  "tls-client-map",
+		Usage: "A file binding client certificate identities to accounts",
+	}
+
+	DrainDelayFlag = tool.DurationFlag{
+		Name:    "drain-delay",
+		Usage:   "The time to keep serving after reporting not ready on shutdown",
+		Default: time.Duration(DefaultConfig.DrainDelay),
+	}
+
+	DrainTimeoutFlag = tool.DurationFlag{
+		Name:    "drain-timeout",
+		Usage:   "The time to wait for in-flight requests to complete on shutdown",
+		Default: time.Duration(DefaultConfig.DrainTimeout),
 	}
 
 	RunCommand = tool.NewCommand(
@@ -58,38 +171,109 @@ var (
 			Help: `
 Starts a local server.
 
+The server is configured by a yaml, toml or json file (given by
+--config or $STASH_SERVER_CONFIG), whose values are overridden by
+environment variables (e.g. STASH_DB_ADDR), whose values are in
+turn overridden by flags.  See 'stash config print'.
+
+To serve https, give a certificate and key.  To additionally
+verify client certificates (mutual tls), give a bundle of the
+authorities that sign them.  Client certificates may be bound
+to accounts (e.g. those of agents), in which case a request
+presenting the certificate must be made by the bound account,
+and the bound account must present it.  The certificates and bindings are read again on SIGHUP.
+
+The api may additionally be served over grpc on a separate address.
+Calls over grpc are served by the same handlers as those over http,
+using the same tls, and blocks of secrets are streamed.
+
+Metrics are served in the prometheus text format at /metrics on a
+separate admin address, which is not exposed by default.
+
+Logs may be written as text, json or logfmt.  Each request is given
+an id (or keeps the X-Request-Id given by its client), which is
+returned to the client and carried by every log of the request.
+Tokens and identities are redacted from logs.
+
+Requests are rate limited per account, org and source ip, with a
+stricter limit on block uploads and secret listings.  Clients that
+exceed a limit are told when to retry (429 Retry-After).  Request
+bodies are capped in size, and orgs may be given a storage quota.
+
+The server is alive while /healthz responds, and ready while /readyz
+responds with a 200, which requires that the database is reachable,
+its schemas are migrated and the signing key is loaded.  On SIGTERM
+or interrupt, the server reports that it is not ready, keeps
+serving for the drain delay so that load balancers stop routing to
+it, then stops accepting connections and waits for in-flight
+requests to complete before closing the database.
+
 Examples:
 
 	$ stash run
+	$ stash run --config server.yaml
+	$ stash run --tls-cert server.pem --tls-key server.key
+	$ stash run --tls-cert server.pem --tls-key server.key --tls-client-ca agents.pem --tls-client-map agents.yaml
+	$ stash run --admin-addr localhost:9090
+	$ stash run --grpc-addr :8081
+	$ stash run --rate-account 600/m --org-block-quota 104857600
+	$ stash run --drain-delay 5s --drain-timeout 1m
+	$ stash run --log-format json
 `,
-			Flags: tool.NewFlags(AddrFlag, LoggingFlag),
-			Exec:  ServerRun,
+			Flags: tool.NewFlags(ConfigFlag, AddrFlag, AdminAddrFlag, GrpcAddrFlag, LoggingFlag, LogFormatFlag,
+				RateAccountFlag, RateOrgFlag, RateRemoteFlag, RateBulkFlag, MaxBodyFlag, MaxBlockBodyFlag, BlockQuotaFlag,
+				TLSCertFlag, TLSKeyFlag, TLSClientCAFlag, TLSClientAuthFlag, TLSClientMapFlag,
+				DrainDelayFlag, DrainTimeoutFlag),
+			Exec: ServerRun,
 		})
 )
 
 func ServerRun(env tool.Environment, c *cli.Context) (err error) {
-	key, err := getSigningKey(env)
+	conf, err := LoadConfig(c)
+	if err != nil {
+		return
+	}
+
+	if err = conf.Validate(); err != nil {
+		return
+	}
+
+	lvl, err := context.ParseLogLevel(conf.Logging)
+	if err != nil {
+		return
+	}
+
+	format, err := context.ParseLogFormat(conf.LogFormat)
+	if err != nil {
+		return
+	}
+
+	ctx := context.NewContextWithLogger(context.NewFormattedLogger(os.Stdout, lvl, format, ""))
+	defer ctx.Close()
+	env.Context = ctx
+
+	key, err := getSigningKey(env, conf.SigningKey)
 	if err != nil {
 		return
 	}
 	env.Context.Logger().Info("Using signing key [%v]", key.Public().ID())
 
-	mailer, err := getMailer(env)
+	mailer, err := getMailer(env, conf.Mail)
 	if err != nil {
 		return
 	}
 
-	texter, err := getTexter(env)
+	texter, err := getTexter(env, conf.SMS)
 	if err != nil {
 		return
 	}
 
-	biller, billingKey, err := getBiller(env)
+	biller, billingKey, err := getBiller(env, conf.Billing)
 	if err != nil {
 		return
 	}
 
-	driver, err := getSqlDriver(env)
+	driver, err := getSqlDriver(env, conf.DB)
 	if err != nil {
 		return
 	}
@@ -120,34 +304,262 @@ func ServerRun(env tool.Environment, c *cli.Context) (err error) {
 		return
 	}
 
+	hooks, err := sqlwebhook.NewSqlStore(driver, registry)
+	if err != nil {
+		return
+	}
+
+	auditLog, err := sqlaudit.NewSqlStore(driver, registry)
+	if err != nil {
+		return
+	}
+
+	requests, err := sqlaccess.NewSqlStore(driver, registry)
+	if err != nil {
+		return
+	}
+
+	auditSink, err := getAuditSink(env, conf.Audit)
+	if err != nil {
+		return
+	}
+	if auditSink != nil {
+		defer auditSink.Close()
+		auditLog = audit.WithSink(auditLog, auditSink)
+	}
+
+	dispatcher := webhook.NewDispatcher(env.Context, hooks, key)
+	defer dispatcher.Close()
+
+	reaper := access.NewReaper(env.Context, requests, policies, auditLog, key, access.DefaultReapInterval)
+	defer reaper.Close()
+
+	network, certs, err := getNetwork(env, conf.TLS)
+	if err != nil {
+		return
+	}
+
+	bindings, err := getCertBindings(env, conf.TLS, certs)
+	if err != nil {
+		return
+	}
+
+	limits, err := getLimits(conf.Limits)
+	if err != nil {
+		return
+	}
+
+	// Middleware are listed from the innermost outwards.  Those reading
+	// the claims of a request must sit within the claims middleware.
+	middleware := []http.Middleware{
+		http.BodyLimitMiddleware(conf.Limits.MaxBody, map[http.Route]int64{
+			httpsecret.SaveBlocksRoute: conf.Limits.MaxBlockBody,
+		}),
+		http.RateLimitMiddleware(limits...),
+	}
+	if bindings != nil {
+		middleware = append(middleware, core.CertBindingMiddleware(bindings))
+	}
+	middleware = append(middleware,
+		core.ClaimsMiddleware,
+		http.TimerMiddleware,
+		http.RouteMiddleware,
+		http.MetricsMiddleware,
+		http.RequestIdMiddleware)
+
 	server, err := http.Serve(env.Context,
-		http.Build(DefaultHandlers...),
-		http.WithListener(&net.TCP4Network{}, c.String(AddrFlag.Name)),
+		http.Build(append(DefaultHandlers, http.OpenAPIHandlers(OpenAPIPath, OpenAPIInfo, DefaultHandlers...))...),
+		http.WithListener(network, conf.Addr),
 		http.WithDependency(core.Accounts, accounts),
 		http.WithDependency(core.Orgs, orgs),
 		http.WithDependency(core.Policies, policies),
 		http.WithDependency(core.Secrets, secrets),
+		http.WithDependency(core.Webhooks, hooks),
+		http.WithDependency(core.AuditLog, auditLog),
+		http.WithDependency(core.Access, requests),
+		http.WithDependency(core.Dispatcher, dispatcher),
 		http.WithDependency(core.BillingKey, billingKey),
 		http.WithDependency(core.Biller, biller),
-		http.WithDependency(core.Mailer, mailer),
-		http.WithDependency(core.Texter, texter),
+		http.WithDependency(core.Mailer, mail.NewLoggedClient(mailer, env.Context.Logger())),
+		http.WithDependency(core.Texter, sms.NewLoggedClient(texter, env.Context.Logger())),
 		http.WithDependency(core.Signer, key),
-		http.WithMiddleware(http.TimerMiddleware),
-		http.WithMiddleware(http.RouteMiddleware))
+		http.WithDependency(core.Quotas, core.OrgQuotas{BlockBytes: conf.Limits.OrgBlockQuota}),
+		http.WithMiddleware(middleware...),
+		http.WithReadiness("database", func() error {
+			return registry.Check(driver)
+		}),
+		http.WithReadiness("signing key", func() error {
+			return checkSigningKey(key)
+		}))
 	if err != nil {
 		return
 	}
 	defer server.Close()
 
+	if addr := conf.GrpcAddr; addr != "" {
+		listener, err := network.Listen(addr)
+		if err != nil {
+			return errors.Wrapf(err, "Error binding grpc address [%v]", addr)
+		}
+
+		grpc := grpcserver.Serve(env.Context, server.Handler(), listener)
+		defer grpc.Close()
+
+		// The grpc calls are drained along with those of http.
+		server.OnShutdown(grpc.Shutdown)
+	}
+
+	if addr := conf.AdminAddr; addr != "" {
+		admin, err := serveAdmin(env, addr)
+		if err != nil {
+			return err
+		}
+		defer admin.Close()
+	}
+
 	sig := make(chan os.Signal, 2)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	<-sig
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for s := range sig {
+		if s != syscall.SIGHUP {
+			return server.Shutdown(time.Duration(conf.DrainDelay), time.Duration(conf.DrainTimeout))
+		}
+		reloadCerts(env, certs, bindings)
+	}
+	return
+}
+
+// Returns the rate limits of the server.  Rates that are not given
+// are not limited.
+func getLimits(conf LimitsConfig) (ret []http.Limit, err error) {
+	for _, l := range []struct {
+		name   string
+		rate   string
+		key    http.KeyFunc
+		routes []http.Route
+	}{
+		{RateRemoteFlag.Name, conf.RateRemote, http.ByRemote, nil},
+		{RateAccountFlag.Name, conf.RateAccount, core.ByAccount, nil},
+		{RateOrgFlag.Name, conf.RateOrg, core.ByOrg, nil},
+		{RateBulkFlag.Name, conf.RateBulk, core.ByAccount, []http.Route{httpsecret.SaveBlocksRoute, httpsecret.ListSecretsRoute}},
+	} {
+		if l.rate == "" {
+			continue
+		}
+
+		rate, e := http.ParseRate(l.rate)
+		if e != nil {
+			err = errors.Wrapf(e, "Invalid rate [%v]", l.name)
+			return
+		}
+		ret = append(ret, http.NewLimit(l.name, rate, l.key, l.routes...))
+	}
+	return
+}
+
+// Serves the admin endpoints (i.e. metrics) on their own listener, so
+// that they need not be exposed alongside the api.
+func serveAdmin(env tool.Environment, addr string) (ret *gohttp.Server, err error) {
+	listener, err := (&net.TCP4Network{}).Listen(addr)
+	if err != nil {
+		err = errors.Wrapf(err, "Error binding admin address [%v]", addr)
+		return
+	}
+
+	mux := gohttp.NewServeMux()
+	mux.Handle("/metrics", metrics.Default)
+
+	ret = &gohttp.Server{Handler: mux}
+	go func() {
+		env.Context.Logger().Info("Serving metrics [%v/metrics]", listener.Address())
+		if err := ret.Serve(net.GoListener(listener)); err != nil && err != gohttp.ErrServerClosed {
+			env.Context.Logger().Error("Error serving admin endpoints: %+v", err)
+		}
+	}()
+	return
+}
+
+// Returns the network on which to serve, along with the store of
+// its certificates if serving tls.
+func getNetwork(env tool.Environment, conf TLSConfig) (ret net.Network, certs *net.CertStore, err error) {
+	certFile, keyFile, caFile := conf.Cert, conf.Key, conf.ClientCA
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			err = errors.Wrapf(errs.ArgError, "Client verification requires a tls certificate")
+			return
+		}
+
+		ret = &net.TCP4Network{}
+		return
+	}
+
+	if certFile == "" || keyFile == "" {
+		err = errors.Wrapf(errs.ArgError, "Must provide both a tls certificate and key")
+		return
+	}
+
+	var clientAuth tls.ClientAuthType
+	switch str := conf.ClientAuth; str {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid value for tls.client_auth [%v]. Expected ['require','optional']", str)
+		return
+	case "", "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		clientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if certs, err = net.NewCertStore(certFile, keyFile, caFile); err != nil {
+		return
+	}
+
+	if certs.VerifiesClients() {
+		env.Context.Logger().Info("Using mutual tls [%v] with client authorities [%v]", certFile, caFile)
+	} else {
+		env.Context.Logger().Info("Using tls [%v]", certFile)
+	}
+
+	ret = net.NewTLSNetworkWithConfig(certs.ServerConfig(clientAuth))
 	return
 }
 
+func getCertBindings(env tool.Environment, conf TLSConfig, certs *net.CertStore) (ret *core.CertBindings, err error) {
+	file := conf.ClientMap
+	if file == "" {
+		return
+	}
+
+	if certs == nil || !certs.VerifiesClients() {
+		err = errors.Wrapf(errs.ArgError, "Client certificate bindings require tls.client_ca")
+		return
+	}
+
+	env.Context.Logger().Info("Using client certificate bindings [%v]", file)
+	ret, err = core.NewCertBindings(file)
+	return
+}
+
+// Reloads the certificates and bindings.  Failures are logged, leaving
+// the previous certificates in use.
+func reloadCerts(env tool.Environment, certs *net.CertStore, bindings *core.CertBindings) {
+	if certs != nil {
+		if err := certs.Reload(); err != nil {
+			env.Context.Logger().Error("Error reloading certificates: %+v", err)
+		} else {
+			env.Context.Logger().Info("Reloaded certificates")
+		}
+	}
+
+	if bindings != nil {
+		if err := bindings.Reload(); err != nil {
+			env.Context.Logger().Error("Error reloading client certificate bindings: %+v", err)
+		} else {
+			env.Context.Logger().Info("Reloaded client certificate bindings")
+		}
+	}
+}
+
 // FIXME: Server signing key should be managed in the database!
-func getSigningKey(env tool.Environment) (ret crypto.PrivateKey, err error) {
-	pem := os.Getenv("STASH_SIGNING_KEY")
+func getSigningKey(env tool.Environment, pem string) (ret crypto.PrivateKey, err error) {
 	if pem != "" {
 		env.Context.Logger().Debug("Decoding private key from string literal")
 		ret, err = crypto.ReadPrivateKey(bytes.NewBuffer([]byte(pem)), crypto.DecodePKCS1)
@@ -159,21 +571,28 @@ func getSigningKey(env tool.Environment) (ret crypto.PrivateKey, err error) {
 	return
 }
 
-func getSqlDriver(env tool.Environment) (ret sql.Driver, err error) {
-	dbDriver := os.Getenv("STASH_DB_DRIVER")
-	switch dbDriver {
+// Returns an error if the key is unable to sign.
+func checkSigningKey(key crypto.PrivateKey) (err error) {
+	if key == nil {
+		return errors.New("No signing key")
+	}
+	_, err = key.Sign(crypto.Rand, crypto.SHA256, []byte("ready"))
+	return
+}
+
+func getSqlDriver(env tool.Environment, conf DBConfig) (ret sql.Driver, err error) {
+	switch conf.Driver {
 	default:
-		err = errors.Wrapf(errs.ArgError, "Invalid value for STASH_DB_DRIVER [%v]. Expected ['sqlite','postgres','<empty>']", dbDriver)
+		err = errors.Wrapf(errs.ArgError, "Invalid value for db.driver [%v]. Expected ['sqlite','<empty>']", conf.Driver)
 	case "", "sqlite":
-		ret, err = dialSqlite(env)
+		ret, err = dialSqlite(env, conf.Addr)
 		//case "postgres":
 		//ret, err = dialPostgres(env)
 	}
 	return
 }
 
-func dialSqlite(env tool.Environment) (ret sql.Driver, err error) {
-	dbAddr := os.Getenv("STASH_DB_ADDR")
+func dialSqlite(env tool.Environment, dbAddr string) (ret sql.Driver, err error) {
 	switch dbAddr {
 	case "", ":memory:":
 		env.Context.Logger().Info("Using in-memory sqlite instance")
@@ -248,10 +667,8 @@ func dialSqlite(env tool.Environment) (ret sql.Driver, err error) {
 //return
 //}
 
-func getMailer(env tool.Environment) (ret mail.Client, err error) {
-	mxDomain := os.Getenv("STASH_MX_DOMAIN")
-	mxPubKey := os.Getenv("STASH_MX_PUBKEY")
-	mxApiKey := os.Getenv("STASH_MX_APIKEY")
+func getMailer(env tool.Environment, conf MailConfig) (ret mail.Client, err error) {
+	mxDomain, mxPubKey, mxApiKey := conf.Domain, conf.PubKey, conf.ApiKey
 	if mxDomain == "" || mxPubKey == "" || mxApiKey == "" {
 		env.Context.Logger().Info("Mail disabled")
 		ret = mail.NewMemClient()
@@ -263,9 +680,8 @@ func getMailer(env tool.Environment) (ret mail.Client, err error) {
 	return
 }
 
-func getBiller(env tool.Environment) (ret billing.Client, pubKey string, err error) {
-	apiKey := os.Getenv("STASH_CC_APIKEY")
-	pubKey = os.Getenv("STASH_CC_PUBKEY")
+func getBiller(env tool.Environment, conf BillingConfig) (ret billing.Client, pubKey string, err error) {
+	apiKey, pubKey := conf.ApiKey, conf.PubKey
 	if apiKey == "" || pubKey == "" {
 		env.Context.Logger().Debug("Billing disabled")
 		ret = billing.NewNullClient()
@@ -277,10 +693,8 @@ func getBiller(env tool.Environment) (ret billing.Client, pubKey string, err err
 	return
 }
 
-func getTexter(env tool.Environment) (ret sms.Client, err error) {
-	smsNumber := os.Getenv("STASH_SMS_NUMBER")
-	smsAppId := os.Getenv("STASH_SMS_APPSID")
-	smsToken := os.Getenv("STASH_SMS_TOKEN")
+func getTexter(env tool.Environment, conf SMSConfig) (ret sms.Client, err error) {
+	smsNumber, smsAppId, smsToken := conf.Number, conf.AppSid, conf.Token
 	if smsNumber == "" || smsAppId == "" || smsToken == "" {
 		env.Context.Logger().Info("Texting disabled")
 		ret = sms.NewMemClient()
@@ -291,3 +705,68 @@ func getTexter(env tool.Environment) (ret sms.Client, err error) {
 	ret = sms.NewTwilioClient(smsNumber, smsAppId, smsToken)
 	return
 }
+
+func getAuditSink(env tool.Environment, conf AuditConfig) (ret audit.Sink, err error) {
+	if len(conf.Sinks) == 0 {
+		env.Context.Logger().Info("Audit sinks disabled")
+		return
+	}
+
+	var sinks audit.MultiSink
+	defer func() {
+		if err != nil {
+			sinks.Close()
+		}
+	}()
+
+	for _, name := range conf.Sinks {
+		var sink audit.Sink
+		switch name {
+		default:
+			err = errors.Wrapf(errs.ArgError, "Invalid value for audit.sinks [%v]. Expected ['file','syslog','stdout']", name)
+			return
+		case "stdout":
+			env.Context.Logger().Info("Using stdout audit sink")
+			sink = audit.NewJsonSink(os.Stdout)
+		case "file":
+			sink, err = getAuditFileSink(env, conf)
+		case "syslog":
+			sink, err = getAuditSyslogSink(env, conf)
+		}
+		if err != nil {
+			return
+		}
+
+		sinks = append(sinks, audit.NewBufferedSink(env.Context, sink, conf.Buffer))
+	}
+
+	ret = sinks
+	return
+}
+
+func getAuditFileSink(env tool.Environment, conf AuditConfig) (ret audit.Sink, err error) {
+	if conf.File == "" {
+		err = errors.Wrapf(errs.ArgError, "Missing required value for audit.file")
+		return
+	}
+
+	env.Context.Logger().Info("Using file audit sink [%v] (max size=%v, max files=%v)", conf.File, conf.FileMaxSize, conf.FileMaxFiles)
+	ret, err = audit.NewFileSink(conf.File, int64(conf.FileMaxSize), conf.FileMaxFiles)
+	return
+}
+
+func getAuditSyslogSink(env tool.Environment, conf AuditConfig) (ret audit.Sink, err error) {
+	if conf.SyslogAddr == "" {
+		err = errors.Wrapf(errs.ArgError, "Missing required value for audit.syslog_addr")
+		return
+	}
+
+	network := conf.SyslogNetwork
+	if network == "" {
+		network = "udp"
+	}
+
+	env.Context.Logger().Info("Using syslog audit sink [%v://%v]", network, conf.SyslogAddr)
+	ret, err = audit.NewSyslogSink(network, conf.SyslogAddr, "stash")
+	return
+}
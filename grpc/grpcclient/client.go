@@ -0,0 +1,320 @@
+package grpcclient
+
+import (
+	"bytes"
+	stdctx "context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/cott-io/stash/grpc/pb"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/secret"
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// A client of the api over grpc.  It carries the requests of the http
+// clients (e.g. httpsecret) in place of an http client, and streams
+// blocks rather than paging them.
+type GrpcClient struct {
+	Conn    *grpc.ClientConn
+	Raw     pb.TransportClient
+	Backoff client.Backoff
+}
+
+type Options struct {
+	TLS     *tls.Config
+	Backoff client.Backoff
+}
+
+type Option func(*Options)
+
+// Uses tls with the given config.  Without it, connections are not
+// encrypted.
+func WithTLS(c *tls.Config) Option {
+	return func(o *Options) {
+		o.TLS = c
+	}
+}
+
+// Retries calls that were rejected with a 429 according to the backoff.
+func WithBackoff(b client.Backoff) Option {
+	return func(o *Options) {
+		o.Backoff = b
+	}
+}
+
+// Returns a client of the address.  Connections are made lazily, so
+// an unreachable server fails the first call.
+func NewClient(addr string, fns ...Option) (ret *GrpcClient, err error) {
+	opts := Options{Backoff: client.DefaultBackoff}
+	for _, fn := range fns {
+		fn(&opts)
+	}
+
+	creds := grpc.WithInsecure()
+	if opts.TLS != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(opts.TLS))
+	}
+
+	conn, err := grpc.Dial(addr, creds)
+	if err != nil {
+		err = fmt.Errorf("Error dialing [%v]: %w", addr, err)
+		return
+	}
+
+	ret = &GrpcClient{conn, pb.NewTransportClient(conn), opts.Backoff}
+	return
+}
+
+func (g *GrpcClient) Close() error {
+	return g.Conn.Close()
+}
+
+func (g *GrpcClient) Call(reqFn client.Request, respFn func(client.Response) error) (err error) {
+	req, err := readRequest(reqFn)
+	if err != nil {
+		return
+	}
+
+	var raw *pb.Response
+	for retry := 0; ; retry++ {
+		raw, err = g.Raw.Call(stdctx.Background(), &pb.Request{
+			Method:  req.Method,
+			Path:    req.Path,
+			Query:   req.Query,
+			Headers: req.Headers,
+			Body:    req.Body,
+		})
+		if err != nil {
+			err = fmt.Errorf("Error calling [%v %v] [request-id=%v]: %w", req.Method, req.Path, req.Headers[headers.RequestId], err)
+			return
+		}
+
+		if raw.Code != http.StatusTooManyRequests || retry >= g.Backoff.Retries {
+			break
+		}
+
+		time.Sleep(g.Backoff.Wait(retry, newResponse(raw).header(headers.RetryAfter)))
+	}
+
+	if err = respFn(newResponse(raw)); err != nil {
+		err = fmt.Errorf("Error calling [%v %v] [request-id=%v]: %w", req.Method, req.Path, req.Headers[headers.RequestId], err)
+	}
+	return
+}
+
+func (g *GrpcClient) UploadBlocks(token auth.SignedToken) (ret secret.BlockUpload, err error) {
+	ctx, id, err := g.stream(token)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := stdctx.WithCancel(ctx)
+
+	raw, err := g.Raw.UploadBlocks(ctx)
+	if err != nil {
+		cancel()
+		err = fmt.Errorf("Error uploading blocks [request-id=%v]: %w", id, err)
+		return
+	}
+
+	upload := &blockUpload{id: id, raw: raw, cancel: cancel, done: make(chan struct{})}
+	go upload.recv()
+
+	ret = upload
+	return
+}
+
+func (g *GrpcClient) DownloadBlocks(token auth.SignedToken, orgId, secretId uuid.UUID, version int, limit uint64, fn func([]secret.Block) error) (err error) {
+	ctx, id, err := g.stream(token)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := stdctx.WithCancel(ctx)
+	defer cancel()
+
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("Error downloading blocks [request-id=%v]: %w", id, err)
+		}
+	}()
+
+	raw, err := g.Raw.DownloadBlocks(ctx, &pb.BlockQuery{
+		OrgId:    orgId.String(),
+		SecretId: secretId.String(),
+		Version:  int32(version),
+		Limit:    limit,
+	})
+	if err != nil {
+		return
+	}
+
+	for {
+		res, err := raw.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var blocks []secret.Block
+		if err = client.ExpectAll(
+			client.ExpectCode(http.StatusOK),
+			client.ExpectStruct(enc.DefaultRegistry, &blocks))(newResponse(res)); err != nil {
+			return err
+		}
+
+		if err = fn(blocks); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the context of a stream, whose metadata carries the headers
+// of its calls.
+func (g *GrpcClient) stream(token auth.SignedToken) (ret stdctx.Context, id string, err error) {
+	req, err := readRequest(client.WithBearer(token.String()))
+	if err != nil {
+		return
+	}
+
+	ret, id = metadata.NewOutgoingContext(stdctx.Background(), metadata.New(req.Headers)), req.Headers[headers.RequestId]
+	return
+}
+
+// Reads the request, giving it an id by which the server correlates
+// its logs.
+func readRequest(reqFn client.Request) (ret client.RawRequest, err error) {
+	if ret, err = client.ReadRequest(reqFn); err != nil {
+		return
+	}
+
+	if _, ok := ret.Headers[headers.RequestId]; !ok {
+		ret.Headers[headers.RequestId] = uuid.NewV4().String()
+	}
+	return
+}
+
+type blockUpload struct {
+	id     string
+	raw    pb.Transport_UploadBlocksClient
+	cancel stdctx.CancelFunc
+	done   chan struct{}
+
+	lock sync.Mutex
+	err  error
+}
+
+// Receives the acknowledgements of the batches, stopping at the first
+// failure.
+func (u *blockUpload) recv() {
+	defer close(u.done)
+	for {
+		res, err := u.raw.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err == nil {
+			err = client.ExpectCode(http.StatusNoContent)(newResponse(res))
+		}
+		if err != nil {
+			u.fail(err)
+			return
+		}
+	}
+}
+
+func (u *blockUpload) fail(err error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.err == nil {
+		u.err = fmt.Errorf("Error uploading blocks [request-id=%v]: %w", u.id, err)
+		u.cancel()
+	}
+}
+
+func (u *blockUpload) failure() error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.err
+}
+
+func (u *blockUpload) Send(blocks ...secret.Block) (err error) {
+	if len(blocks) == 0 {
+		return
+	}
+
+	if err = u.failure(); err != nil {
+		return
+	}
+
+	data, err := enc.Encode(enc.Json, blocks)
+	if err != nil {
+		return
+	}
+
+	// A failed send is reported by the stream's status, which is given
+	// to the receiver.
+	if err = u.raw.Send(&pb.BlockBatch{OrgId: blocks[0].OrgId.String(), Blocks: data}); err != nil {
+		<-u.done
+		err = errs.Or(u.failure(), err)
+	}
+	return
+}
+
+func (u *blockUpload) Close() (err error) {
+	defer u.cancel()
+	if err = u.raw.CloseSend(); err != nil {
+		return
+	}
+
+	<-u.done
+	return u.failure()
+}
+
+// A response of the transport, read as an http response.
+type response struct {
+	*bytes.Reader
+	raw *pb.Response
+}
+
+func newResponse(raw *pb.Response) *response {
+	return &response{bytes.NewReader(raw.Body), raw}
+}
+
+func (r *response) Close() error {
+	return nil
+}
+
+func (r *response) ReadCode() int {
+	return int(r.raw.Code)
+}
+
+func (r *response) header(key string) string {
+	return r.raw.Headers[textproto.CanonicalMIMEHeaderKey(key)]
+}
+
+func (r *response) ReadHeader(key string, val *string) bool {
+	*val = r.header(key)
+	return *val != ""
+}
+
+func (r *response) ReadBody(ptr *[]byte) (err error) {
+	*ptr = make([]byte, r.Len())
+	_, err = io.ReadFull(r, *ptr)
+	return
+}
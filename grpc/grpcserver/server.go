@@ -0,0 +1,291 @@
+package grpcserver
+
+import (
+	"bytes"
+	stdctx "context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	gonet "net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cott-io/stash/grpc/pb"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/http/headers"
+	"github.com/cott-io/stash/lang/net"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// A server of the api over grpc.  Calls are served by the handler of
+// an http server (see server.Handler), so are routed, authorized,
+// limited and audited exactly as their http counterparts.
+type Server struct {
+	ctx      context.Context
+	listener net.Listener
+	raw      *grpc.Server
+}
+
+// Serves the handler on the listener.  Listeners of a tls network
+// hand out tls connections, whose handshakes are completed by the
+// server so that the certificates of clients reach the handler.
+func Serve(ctx context.Context, handler http.Handler, listener net.Listener, opts ...grpc.ServerOption) (ret *Server) {
+	raw := grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(listenerCreds{})}, opts...)...)
+	pb.RegisterTransportServer(raw, &transport{handler: handler})
+
+	// The grpc server owns the listener, closing it along with any open
+	// calls.
+	ret = &Server{ctx.Sub("Grpc(%v)", listener.Address().String()), listener, raw}
+	ret.ctx.Control().Defer(func(error) {
+		raw.Stop()
+	})
+	go func() {
+		ret.ctx.Logger().Info("Serving grpc [%v]", listener.Address())
+		if err := raw.Serve(net.GoListener(listener)); err != nil {
+			ret.ctx.Logger().Error("Error serving grpc: %+v", err)
+		}
+	}()
+	return
+}
+
+func (s *Server) Address() net.Address {
+	return s.listener.Address()
+}
+
+// Stops accepting connections and waits for in-flight calls and
+// streams to complete.  Once the context is done, they are closed.
+func (s *Server) Shutdown(ctx stdctx.Context) (err error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.raw.GracefulStop()
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.ctx.Logger().Error("Unable to drain grpc calls. Closing: %v", ctx.Err())
+		s.raw.Stop()
+		err = ctx.Err()
+	}
+	return
+}
+
+// Closes the server, along with any open calls.
+func (s *Server) Close() error {
+	return s.ctx.Close()
+}
+
+type transport struct {
+	pb.UnimplementedTransportServer
+	handler http.Handler
+}
+
+func (t *transport) Call(ctx stdctx.Context, req *pb.Request) (*pb.Response, error) {
+	return t.serve(ctx, req)
+}
+
+// Each batch is saved as a call of the blocks route.  The batches share
+// the headers of the stream, including its request id.
+func (t *transport) UploadBlocks(stream pb.Transport_UploadBlocksServer) error {
+	hdrs := readHeaders(stream.Context())
+	hdrs[headers.ContentType] = enc.Json.Mime()
+
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		res, err := t.serve(stream.Context(), &pb.Request{
+			Method:  http.MethodPost,
+			Path:    fmt.Sprintf("/v1/orgs/%v/blocks", url.PathEscape(batch.OrgId)),
+			Headers: hdrs,
+			Body:    batch.Blocks,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// Pages through the blocks, ending after a short or failed page.
+func (t *transport) DownloadBlocks(q *pb.BlockQuery, stream pb.Transport_DownloadBlocksServer) error {
+	if q.Limit == 0 {
+		return status.Errorf(codes.InvalidArgument, "Missing required limit")
+	}
+
+	hdrs := readHeaders(stream.Context())
+	for offset := uint64(0); ; offset += q.Limit {
+		query := url.Values{}
+		query.Set("offset", strconv.FormatUint(offset, 10))
+		query.Set("limit", strconv.FormatUint(q.Limit, 10))
+		query.Set("version", strconv.Itoa(int(q.Version)))
+
+		res, err := t.serve(stream.Context(), &pb.Request{
+			Method:  http.MethodGet,
+			Path:    fmt.Sprintf("/v1/orgs/%v/blocks/%v", url.PathEscape(q.OrgId), url.PathEscape(q.SecretId)),
+			Query:   query.Encode(),
+			Headers: hdrs,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+
+		if res.Code != http.StatusOK {
+			return nil
+		}
+
+		var page []json.RawMessage
+		if err := json.Unmarshal(res.Body, &page); err != nil {
+			return status.Errorf(codes.Internal, "Unable to read page of blocks: %v", err)
+		}
+
+		if uint64(len(page)) < q.Limit {
+			return nil
+		}
+	}
+}
+
+// Serves the request as an http request, capturing its response.
+func (t *transport) serve(ctx stdctx.Context, req *pb.Request) (ret *pb.Response, err error) {
+	u, err := url.Parse("/" + strings.TrimLeft(req.Path, "/"))
+	if err != nil {
+		err = status.Errorf(codes.InvalidArgument, "Invalid path [%v]: %v", req.Path, err)
+		return
+	}
+	u.RawQuery = req.Query
+
+	raw, err := http.NewRequestWithContext(ctx, req.Method, u.String(), bytes.NewReader(req.Body))
+	if err != nil {
+		err = status.Errorf(codes.InvalidArgument, "Invalid request [%v %v]: %v", req.Method, req.Path, err)
+		return
+	}
+
+	for k, v := range req.Headers {
+		raw.Header.Set(k, v)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		raw.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			raw.TLS = &info.State
+		}
+	}
+
+	rec := &recorder{header: make(http.Header)}
+	t.handler.ServeHTTP(rec, raw)
+
+	ret = rec.response()
+	return
+}
+
+// Returns the headers given by the metadata of a stream, leaving out
+// those of grpc itself.
+func readHeaders(ctx stdctx.Context) (ret map[string]string) {
+	ret = make(map[string]string)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, v := range md {
+		if len(v) == 0 || strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") {
+			continue
+		}
+
+		switch k {
+		case "content-type", "user-agent", "te":
+			continue
+		}
+
+		ret[k] = v[0]
+	}
+	return
+}
+
+// Captures the response of an http handler.
+type recorder struct {
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (r *recorder) response() *pb.Response {
+	r.WriteHeader(http.StatusOK)
+
+	hdrs := make(map[string]string)
+	for k := range r.header {
+		hdrs[k] = r.header.Get(k)
+	}
+	return &pb.Response{Code: int32(r.code), Headers: hdrs, Body: r.body.Bytes()}
+}
+
+// The credentials of connections accepted from a lang/net listener.
+// Tls connections complete their handshake, and report its state.
+// Others are used as they are.
+type listenerCreds struct{}
+
+func (listenerCreds) ServerHandshake(conn gonet.Conn) (gonet.Conn, credentials.AuthInfo, error) {
+	raw, ok := conn.(*tls.Conn)
+	if !ok {
+		return conn, nil, nil
+	}
+
+	if err := raw.Handshake(); err != nil {
+		return nil, nil, err
+	}
+
+	return conn, credentials.TLSInfo{
+		State:          raw.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}, nil
+}
+
+func (listenerCreds) ClientHandshake(stdctx.Context, string, gonet.Conn) (gonet.Conn, credentials.AuthInfo, error) {
+	return nil, nil, fmt.Errorf("Listener credentials may only be used by servers")
+}
+
+func (listenerCreds) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "tls"}
+}
+
+func (c listenerCreds) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (listenerCreds) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,243 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// TransportClient is the client API for Transport service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TransportClient interface {
+	// Makes a single call of the api.
+	Call(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	// Saves batches of blocks, acknowledging each in the order sent.
+	// The headers of the calls (e.g. authorization) are given by the
+	// metadata of the stream.
+	UploadBlocks(ctx context.Context, opts ...grpc.CallOption) (Transport_UploadBlocksClient, error)
+	// Streams the blocks of a secret, a page at a time.  The stream ends
+	// after the last page or the first failed one.  The headers of the
+	// calls are given by the metadata of the stream.
+	DownloadBlocks(ctx context.Context, in *BlockQuery, opts ...grpc.CallOption) (Transport_DownloadBlocksClient, error)
+}
+
+type transportClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTransportClient(cc grpc.ClientConnInterface) TransportClient {
+	return &transportClient{cc}
+}
+
+func (c *transportClient) Call(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/stash.v1.Transport/Call", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transportClient) UploadBlocks(ctx context.Context, opts ...grpc.CallOption) (Transport_UploadBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Transport_serviceDesc.Streams[0], "/stash.v1.Transport/UploadBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &transportUploadBlocksClient{stream}
+	return x, nil
+}
+
+type Transport_UploadBlocksClient interface {
+	Send(*BlockBatch) error
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type transportUploadBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *transportUploadBlocksClient) Send(m *BlockBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *transportUploadBlocksClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *transportClient) DownloadBlocks(ctx context.Context, in *BlockQuery, opts ...grpc.CallOption) (Transport_DownloadBlocksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Transport_serviceDesc.Streams[1], "/stash.v1.Transport/DownloadBlocks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &transportDownloadBlocksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Transport_DownloadBlocksClient interface {
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type transportDownloadBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *transportDownloadBlocksClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TransportServer is the server API for Transport service.
+// All implementations must embed UnimplementedTransportServer
+// for forward compatibility
+type TransportServer interface {
+	// Makes a single call of the api.
+	Call(context.Context, *Request) (*Response, error)
+	// Saves batches of blocks, acknowledging each in the order sent.
+	// The headers of the calls (e.g. authorization) are given by the
+	// metadata of the stream.
+	UploadBlocks(Transport_UploadBlocksServer) error
+	// Streams the blocks of a secret, a page at a time.  The stream ends
+	// after the last page or the first failed one.  The headers of the
+	// calls are given by the metadata of the stream.
+	DownloadBlocks(*BlockQuery, Transport_DownloadBlocksServer) error
+	mustEmbedUnimplementedTransportServer()
+}
+
+// UnimplementedTransportServer must be embedded to have forward compatible implementations.
+type UnimplementedTransportServer struct {
+}
+
+func (UnimplementedTransportServer) Call(context.Context, *Request) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Call not implemented")
+}
+func (UnimplementedTransportServer) UploadBlocks(Transport_UploadBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadBlocks not implemented")
+}
+func (UnimplementedTransportServer) DownloadBlocks(*BlockQuery, Transport_DownloadBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadBlocks not implemented")
+}
+func (UnimplementedTransportServer) mustEmbedUnimplementedTransportServer() {}
+
+// UnsafeTransportServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TransportServer will
+// result in compilation errors.
+type UnsafeTransportServer interface {
+	mustEmbedUnimplementedTransportServer()
+}
+
+func RegisterTransportServer(s *grpc.Server, srv TransportServer) {
+	s.RegisterService(&_Transport_serviceDesc, srv)
+}
+
+func _Transport_Call_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransportServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stash.v1.Transport/Call",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransportServer).Call(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transport_UploadBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TransportServer).UploadBlocks(&transportUploadBlocksServer{stream})
+}
+
+type Transport_UploadBlocksServer interface {
+	Send(*Response) error
+	Recv() (*BlockBatch, error)
+	grpc.ServerStream
+}
+
+type transportUploadBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *transportUploadBlocksServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *transportUploadBlocksServer) Recv() (*BlockBatch, error) {
+	m := new(BlockBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Transport_DownloadBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlockQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TransportServer).DownloadBlocks(m, &transportDownloadBlocksServer{stream})
+}
+
+type Transport_DownloadBlocksServer interface {
+	Send(*Response) error
+	grpc.ServerStream
+}
+
+type transportDownloadBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *transportDownloadBlocksServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Transport_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "stash.v1.Transport",
+	HandlerType: (*TransportServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    _Transport_Call_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadBlocks",
+			Handler:       _Transport_UploadBlocks_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadBlocks",
+			Handler:       _Transport_DownloadBlocks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "transport.proto",
+}
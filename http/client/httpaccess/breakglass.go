@@ -0,0 +1,102 @@
+package httpaccess
+
+import (
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+)
+
+type CreateUnlockRequest struct {
+	PolicyId uuid.UUID `json:"policy_id"`
+	Item     string    `json:"item"`
+	Reason   string    `json:"reason"`
+}
+
+type SubmitShareRequest struct {
+	Share policy.MemberSecret `json:"share"`
+}
+
+type ClaimUnlockRequest struct {
+	Member policy.PolicyMember `json:"member"`
+}
+
+func (h *HttpClient) SaveEscrow(token auth.SignedToken, e access.Escrow) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/policies/%v/escrow", e.OrgId, e.PolicyId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, e)),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) LoadEscrow(token auth.SignedToken, orgId, policyId uuid.UUID) (ret access.Escrow, ok bool, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/policies/%v/escrow", orgId, policyId),
+			http.WithBearer(token.String())),
+		http.MaybeExpectStruct(h.Reg, &ok, &ret))
+	return
+}
+
+func (h *HttpClient) RequestUnlock(token auth.SignedToken, orgId, policyId uuid.UUID, item, reason string) (ret access.Unlock, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/breakglass", orgId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, CreateUnlockRequest{policyId, item, reason})),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) LoadUnlock(token auth.SignedToken, orgId, unlockId uuid.UUID) (ret access.Unlock, ok bool, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/breakglass/%v", orgId, unlockId),
+			http.WithBearer(token.String())),
+		http.MaybeExpectStruct(h.Reg, &ok, &ret))
+	return
+}
+
+func (h *HttpClient) ListUnlocks(token auth.SignedToken, orgId uuid.UUID, filter access.Filter, page page.Page) (ret []access.Unlock, err error) {
+	var status *string
+	if filter.Status != nil {
+		status = new(string)
+		*status = string(*filter.Status)
+	}
+
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/breakglass", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("status", status),
+			http.WithQueryParam("requester", filter.RequesterId),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) SubmitShare(token auth.SignedToken, orgId, unlockId uuid.UUID, share policy.MemberSecret) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/breakglass/%v/shares", orgId, unlockId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, SubmitShareRequest{share})),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) ClaimUnlock(token auth.SignedToken, orgId, unlockId uuid.UUID, member policy.PolicyMember) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/breakglass/%v/claim", orgId, unlockId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, ClaimUnlockRequest{member})),
+		http.ExpectCode(204))
+	return
+}
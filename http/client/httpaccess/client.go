@@ -0,0 +1,122 @@
+package httpaccess
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+)
+
+type CreateRequest struct {
+	PolicyId uuid.UUID       `json:"policy_id"`
+	Item     string          `json:"item"`
+	Duration time.Duration   `json:"duration"`
+	Reason   string          `json:"reason"`
+	Actions  []policy.Action `json:"actions"`
+}
+
+type ApproveRequest struct {
+	Member  policy.PolicyMember `json:"member"`
+	Comment string              `json:"comment"`
+}
+
+type DenyRequest struct {
+	Comment string `json:"comment"`
+}
+
+type HttpClient struct {
+	Raw http.Client
+	Reg enc.Registry
+}
+
+func NewClient(raw http.Client, reg enc.Registry) access.Transport {
+	return &HttpClient{raw, reg}
+}
+
+func (h *HttpClient) RequestAccess(token auth.SignedToken, orgId, policyId uuid.UUID, item string, dur time.Duration, reason string, actions []policy.Action) (ret access.Request, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/access", orgId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, CreateRequest{policyId, item, dur, reason, actions})),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) LoadRequest(token auth.SignedToken, orgId, requestId uuid.UUID) (ret access.Request, ok bool, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/access/%v", orgId, requestId),
+			http.WithBearer(token.String())),
+		http.MaybeExpectStruct(h.Reg, &ok, &ret))
+	return
+}
+
+func (h *HttpClient) ListRequests(token auth.SignedToken, orgId uuid.UUID, filter access.Filter, page page.Page) (ret []access.Request, err error) {
+	var status *string
+	if filter.Status != nil {
+		status = new(string)
+		*status = string(*filter.Status)
+	}
+
+	var policyId *uuid.UUID
+	if filter.PolicyIds != nil && len(*filter.PolicyIds) == 1 {
+		policyId = &(*filter.PolicyIds)[0]
+	}
+
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/access", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("status", status),
+			http.WithQueryParam("requester", filter.RequesterId),
+			http.WithQueryParam("policy", policyId),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) ApproveRequest(token auth.SignedToken, orgId, requestId uuid.UUID, member policy.PolicyMember, comment string) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/access/%v/approve", orgId, requestId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, ApproveRequest{member, comment})),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) DenyRequest(token auth.SignedToken, orgId, requestId uuid.UUID, comment string) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/access/%v/deny", orgId, requestId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, DenyRequest{comment})),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) SaveApprovers(token auth.SignedToken, a access.Approvers) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/policies/%v/approvers", a.OrgId, a.PolicyId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, a)),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) LoadApprovers(token auth.SignedToken, orgId, policyId uuid.UUID) (ret access.Approvers, ok bool, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/policies/%v/approvers", orgId, policyId),
+			http.WithBearer(token.String())),
+		http.MaybeExpectStruct(h.Reg, &ok, &ret))
+	return
+}
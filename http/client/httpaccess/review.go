@@ -0,0 +1,91 @@
+package httpaccess
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type StartCampaignRequest struct {
+	Name     string        `json:"name"`
+	Deadline time.Time     `json:"deadline"`
+	Scopes   access.Scopes `json:"scopes"`
+}
+
+type DecideEntryRequest struct {
+	Decision access.Decision `json:"decision"`
+	Comment  string          `json:"comment"`
+}
+
+func (h *HttpClient) StartCampaign(token auth.SignedToken, orgId uuid.UUID, name string, deadline time.Time, scopes []access.Scope) (ret access.Campaign, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/reviews", orgId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, StartCampaignRequest{name, deadline, scopes})),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) LoadCampaign(token auth.SignedToken, orgId, campaignId uuid.UUID) (ret access.Campaign, ok bool, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/reviews/%v", orgId, campaignId),
+			http.WithBearer(token.String())),
+		http.MaybeExpectStruct(h.Reg, &ok, &ret))
+	return
+}
+
+func (h *HttpClient) ListCampaigns(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []access.Campaign, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/reviews", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) ListReviewEntries(token auth.SignedToken, orgId, campaignId uuid.UUID, filter access.ReviewFilter, page page.Page) (ret []access.ReviewEntry, err error) {
+	var decision *string
+	if filter.Decision != nil {
+		decision = new(string)
+		*decision = string(*filter.Decision)
+	}
+
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/reviews/%v/entries", orgId, campaignId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("reviewer", filter.ReviewerId),
+			http.WithQueryParam("decision", decision),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) DecideEntry(token auth.SignedToken, orgId, campaignId, policyId, memberId uuid.UUID, decision access.Decision, comment string) (ret access.ReviewEntry, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/reviews/%v/entries/%v/%v", orgId, campaignId, policyId, memberId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, DecideEntryRequest{decision, comment})),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) CloseCampaign(token auth.SignedToken, orgId, campaignId uuid.UUID) (ret access.Campaign, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/reviews/%v/close", orgId, campaignId),
+			http.WithBearer(token.String())),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/cott-io/stash/lang/crypto"
 	"github.com/cott-io/stash/lang/enc"
 	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
 	"github.com/cott-io/stash/libs/account"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
@@ -113,15 +114,18 @@ func (h *HttpClient) LoadIdentity(token auth.SignedToken, id auth.Identity) (ret
 	return
 }
 
-func (h *HttpClient) ListIdentitiesByAccountId(token auth.SignedToken, acctId uuid.UUID, page page.Page) (ret []account.Identity, err error) {
+func (h *HttpClient) ListIdentitiesByAccountId(token auth.SignedToken, acctId uuid.UUID, page page.Page) (ret []account.Identity, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Get("/v1/identities"),
 			http.WithQueryParam("account_id", acctId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String())),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
@@ -0,0 +1,40 @@
+package httpaudit
+
+import (
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type HttpClient struct {
+	Raw http.Client
+	Reg enc.Registry
+}
+
+func NewClient(raw http.Client, reg enc.Registry) audit.Transport {
+	return &HttpClient{raw, reg}
+}
+
+func (h *HttpClient) ListEvents(token auth.SignedToken, orgId uuid.UUID, filter audit.Filter, page page.Page) (ret []audit.Event, err error) {
+	var since *int64
+	if filter.Since != nil {
+		since = new(int64)
+		*since = filter.Since.Unix()
+	}
+
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/audit", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("actor", filter.ActorId),
+			http.WithQueryParam("target", filter.Target),
+			http.WithQueryParam("action", filter.Action),
+			http.WithQueryParam("since", since),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
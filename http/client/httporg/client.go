@@ -4,6 +4,7 @@ import (
 	"github.com/cott-io/stash/lang/billing"
 	"github.com/cott-io/stash/lang/enc"
 	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
 	"github.com/cott-io/stash/libs/page"
@@ -97,10 +98,17 @@ func (h *HttpClient) LoadOrgByName(token auth.SignedToken, name string) (ret org
 }
 
 func (h *HttpClient) SaveOrg(token auth.SignedToken, o org.Org) (err error) {
+	// Only save over the version this one was derived from.
+	cond := http.WithIfNoneMatch("*")
+	if o.Version > 0 {
+		cond = http.WithIfMatch(http.ETag(o.Version - 1))
+	}
+
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Put("/v1/orgs/%v", o.Id),
 			http.WithBearer(token.String()),
+			cond,
 			http.WithStruct(enc.Json, o)),
 		http.ExpectCode(204))
 	return
@@ -146,25 +154,31 @@ func (h *HttpClient) LoadMember(token auth.SignedToken, orgId, acctId uuid.UUID)
 	return
 }
 
-func (h *HttpClient) ListMembersByOrgId(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []org.Member, err error) {
+func (h *HttpClient) ListMembersByOrgId(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []org.Member, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Get("/v1/orgs/%v/members", orgId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String())),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
-func (h *HttpClient) ListMembersByAccountId(token auth.SignedToken, acctId uuid.UUID, page page.Page) (ret []org.Member, err error) {
+func (h *HttpClient) ListMembersByAccountId(token auth.SignedToken, acctId uuid.UUID, page page.Page) (ret []org.Member, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Get("/v1/accounts/%v/members", acctId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String())),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
@@ -196,13 +210,16 @@ func (h *HttpClient) DeleteSubscription(token auth.SignedToken, orgId uuid.UUID)
 	return
 }
 
-func (h *HttpClient) ListInvoices(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []billing.Invoice, err error) {
+func (h *HttpClient) ListInvoices(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []billing.Invoice, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Get("/v1/subscriptions/%v/invoices", orgId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String())),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
@@ -3,6 +3,7 @@ package httppolicy
 import (
 	"github.com/cott-io/stash/lang/enc"
 	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
@@ -21,24 +22,34 @@ func NewClient(raw http.Client, reg enc.Registry) policy.Transport {
 }
 
 func (h *HttpClient) SaveGroup(token auth.SignedToken, group policy.Group) (err error) {
+	// Only save over the version this one was derived from.
+	cond := http.WithIfNoneMatch("*")
+	if group.Version > 0 {
+		cond = http.WithIfMatch(http.ETag(group.Version - 1))
+	}
+
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Put("/v1/orgs/%v/groups/%v", group.OrgId, group.Id),
 			http.WithBearer(token.String()),
+			cond,
 			http.WithStruct(enc.Json, group)),
 		http.ExpectCode(204))
 	return
 }
 
-func (h *HttpClient) ListGroups(token auth.SignedToken, orgId uuid.UUID, filter policy.GroupFilter, page page.Page) (ret []policy.GroupInfo, err error) {
+func (h *HttpClient) ListGroups(token auth.SignedToken, orgId uuid.UUID, filter policy.GroupFilter, page page.Page) (ret []policy.GroupInfo, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Post("/v1/orgs/%v/groups_list", orgId),
 			http.WithBearer(token.String()),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithStruct(enc.Json, filter)),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
@@ -68,10 +79,17 @@ func (h *HttpClient) UpdatePolicy(token auth.SignedToken, policy policy.Policy)
 }
 
 func (h *HttpClient) SavePolicyMember(token auth.SignedToken, member policy.PolicyMember) (err error) {
+	// Only save over the version this one was derived from.
+	cond := http.WithIfNoneMatch("*")
+	if member.Version > 0 {
+		cond = http.WithIfMatch(http.ETag(member.Version - 1))
+	}
+
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Put("/v1/orgs/%v/policies/%v/members/%v", member.OrgId, member.PolicyId, member.MemberId),
 			http.WithBearer(token.String()),
+			cond,
 			http.WithStruct(enc.Json, member)),
 		http.ExpectCode(204))
 	return
@@ -115,3 +133,44 @@ func (h *HttpClient) LoadPolicyLock(token auth.SignedToken, orgId, policyId, mem
 		http.MaybeExpectStruct(h.Reg, &ok, &ret))
 	return
 }
+
+func (h *HttpClient) ListMemberships(token auth.SignedToken, orgId, memberId uuid.UUID, page page.Page) (ret []policy.PolicyMember, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/memberships/%v", orgId, memberId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) RekeyPolicy(token auth.SignedToken, rekey policy.Rekey) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/policies/%v/key", rekey.Policy.OrgId, rekey.Policy.Id),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, rekey)),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) ExplainPolicy(token auth.SignedToken, orgId, policyId, memberId uuid.UUID) (ret policy.Explanation, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/policies/%v/explain", orgId, policyId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("member_id", memberId)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) SimulatePolicyMember(token auth.SignedToken, m policy.PolicyMember) (ret []policy.Effect, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/policies/%v/simulate", m.OrgId, m.PolicyId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, m)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
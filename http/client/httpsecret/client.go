@@ -1,8 +1,11 @@
 package httpsecret
 
 import (
+	"time"
+
 	"github.com/cott-io/stash/lang/enc"
 	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/secret"
@@ -19,10 +22,18 @@ func NewClient(raw http.Client, reg enc.Registry) secret.Transport {
 }
 
 func (h *HttpClient) SaveSecret(token auth.SignedToken, sec secret.Secret) (err error) {
+	// Every version is written over the one before it, so the save
+	// is conditional on that version still being the latest.
+	cond := http.WithIfNoneMatch("*")
+	if sec.Version > 0 {
+		cond = http.WithIfMatch(http.ETag(sec.Version - 1))
+	}
+
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Post("/v1/orgs/%v/secrets", sec.OrgId),
 			http.WithBearer(token.String()),
+			cond,
 			http.WithStruct(enc.Json, sec)),
 		http.ExpectCode(204))
 	return
@@ -42,26 +53,32 @@ type ListSecretRequest struct {
 	Filter secret.Filter `json:"filter"`
 }
 
-func (h *HttpClient) ListSecrets(token auth.SignedToken, orgId uuid.UUID, filter secret.Filter, page page.Page) (ret []secret.SecretSummary, err error) {
+func (h *HttpClient) ListSecrets(token auth.SignedToken, orgId uuid.UUID, filter secret.Filter, page page.Page) (ret []secret.SecretSummary, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Post("/v1/orgs/%v/secrets_list", orgId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String()),
 			http.WithStruct(enc.Json, filter)),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
-func (h *HttpClient) ListSecretVersions(token auth.SignedToken, orgId, secId uuid.UUID, page page.Page) (ret []secret.Secret, err error) {
+func (h *HttpClient) ListSecretVersions(token auth.SignedToken, orgId, secId uuid.UUID, page page.Page) (ret []secret.Secret, next page.Cursor, err error) {
 	err = h.Raw.Call(
 		http.BuildRequest(
 			http.Get("/v1/orgs/%v/secrets/%v/versions", orgId, secId),
 			http.WithQueryParam("offset", page.Offset),
 			http.WithQueryParam("limit", page.Limit),
+			http.WithQueryParam("cursor", page.Cursor),
 			http.WithBearer(token.String())),
-		http.ExpectStruct(h.Reg, &ret))
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.NextCursor, http.String, &next)))
 	return
 }
 
@@ -90,3 +107,18 @@ func (h *HttpClient) LoadBlocks(token auth.SignedToken, orgId, secId uuid.UUID,
 		http.ExpectStruct(h.Reg, &ret))
 	return
 }
+
+func (h *HttpClient) ListEvents(token auth.SignedToken, orgId uuid.UUID, prefix string, after int, wait time.Duration) (ret []secret.Event, next int, err error) {
+	next = after
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/events", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("prefix", prefix),
+			http.WithQueryParam("after", after),
+			http.WithQueryParam("wait", int(wait/time.Second))),
+		http.ExpectAll(
+			http.ExpectStruct(h.Reg, &ret),
+			http.MaybeExpectHeader(headers.EventSeq, http.Int, &next)))
+	return
+}
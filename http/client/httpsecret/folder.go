@@ -0,0 +1,32 @@
+package httpsecret
+
+import (
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/secret"
+	uuid "github.com/satori/go.uuid"
+)
+
+func (h *HttpClient) SaveFolder(token auth.SignedToken, f secret.Folder) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Put("/v1/orgs/%v/folders/%v", f.OrgId, f.Id),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, f)),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) ListFolders(token auth.SignedToken, orgId uuid.UUID, filter secret.FolderFilter, page page.Page) (ret []secret.FolderInfo, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/folders_list", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit),
+			http.WithStruct(enc.Json, filter)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
@@ -0,0 +1,84 @@
+package httpwebhook
+
+import (
+	"github.com/cott-io/stash/lang/enc"
+	http "github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
+	uuid "github.com/satori/go.uuid"
+)
+
+type CreateWebhookRequest struct {
+	Url    string              `json:"url"`
+	Method webhook.SignMethod  `json:"method"`
+	Events []webhook.EventType `json:"events"`
+}
+
+type HttpClient struct {
+	Raw http.Client
+	Reg enc.Registry
+}
+
+func NewClient(raw http.Client, reg enc.Registry) webhook.Transport {
+	return &HttpClient{raw, reg}
+}
+
+func (h *HttpClient) CreateWebhook(token auth.SignedToken, orgId uuid.UUID, url string, method webhook.SignMethod, events []webhook.EventType) (ret webhook.Webhook, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/webhooks", orgId),
+			http.WithBearer(token.String()),
+			http.WithStruct(enc.Json, CreateWebhookRequest{url, method, events})),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) ListWebhooks(token auth.SignedToken, orgId uuid.UUID, page page.Page) (ret []webhook.Webhook, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/webhooks", orgId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) DeleteWebhook(token auth.SignedToken, orgId, hookId uuid.UUID) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Delete("/v1/orgs/%v/webhooks/%v", orgId, hookId),
+			http.WithBearer(token.String())),
+		http.ExpectCode(204))
+	return
+}
+
+func (h *HttpClient) TestWebhook(token auth.SignedToken, orgId, hookId uuid.UUID) (ret webhook.Delivery, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/webhooks/%v/test", orgId, hookId),
+			http.WithBearer(token.String())),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) ListDeliveries(token auth.SignedToken, orgId, hookId uuid.UUID, page page.Page) (ret []webhook.Delivery, err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Get("/v1/orgs/%v/webhooks/%v/deliveries", orgId, hookId),
+			http.WithBearer(token.String()),
+			http.WithQueryParam("offset", page.Offset),
+			http.WithQueryParam("limit", page.Limit)),
+		http.ExpectStruct(h.Reg, &ret))
+	return
+}
+
+func (h *HttpClient) Redeliver(token auth.SignedToken, orgId, deliveryId uuid.UUID) (err error) {
+	err = h.Raw.Call(
+		http.BuildRequest(
+			http.Post("/v1/orgs/%v/deliveries/%v/redeliver", orgId, deliveryId),
+			http.WithBearer(token.String())),
+		http.ExpectCode(204))
+	return
+}
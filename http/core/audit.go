@@ -0,0 +1,39 @@
+package core
+
+import (
+	"net"
+
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Records an action performed by the bearer of the claim.  The
+// request is never failed because of the audit log, so errors are
+// only logged.
+func Audit(e env.Environment, req http.Request, claim auth.Claim, orgId uuid.UUID, action audit.Action, target string, fns ...func(*audit.Event)) {
+	event := audit.NewEvent(orgId, claim.Account.Id, action, target,
+		append([]func(*audit.Event){
+			audit.WithToken(claim.Account.DeviceId, claim.Account.LoginUri),
+			audit.WithRemote(RemoteHost(req))}, fns...)...)
+
+	if err := AssignAuditLog(e).SaveEvents(event); err != nil {
+		e.Logger().Error("Error recording audit event [%v] for org [%v]: %v", action, orgId, err)
+	}
+}
+
+// Records an action that was denied to the bearer of the claim.
+func AuditDenied(e env.Environment, req http.Request, claim auth.Claim, orgId uuid.UUID, action audit.Action, target string, cause error) {
+	Audit(e, req, claim, orgId, action, target, audit.WithResult(audit.Denied, cause.Error()))
+}
+
+// Returns the host portion of the request's remote address.
+func RemoteHost(req http.Request) string {
+	host, _, err := net.SplitHostPort(req.Remote())
+	if err != nil {
+		return req.Remote()
+	}
+	return host
+}
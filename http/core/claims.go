@@ -0,0 +1,41 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+)
+
+// A request whose bearer token is parsed at most once.
+type claimsRequest struct {
+	http.Request
+
+	once  sync.Once
+	claim auth.Claim
+	err   error
+}
+
+// Returns a middleware that lets the middleware within it share the
+// claims of each request's bearer token, rather than each verifying
+// the token again.  Middleware that wrap the request (e.g. body limits)
+// must be placed within those that read the claims.
+func ClaimsMiddleware(h http.Handler) http.Handler {
+	return func(e env.Environment, req http.Request) http.Response {
+		return h(e, &claimsRequest{Request: req})
+	}
+}
+
+// Returns the verified, unexpired claims of the request's bearer token.
+func RequestClaims(e env.Environment, req http.Request) (auth.Claim, error) {
+	cur, ok := req.(*claimsRequest)
+	if !ok {
+		return auth.ParseAndAssertClaims(req, AssignSigner(e).Public())
+	}
+
+	cur.once.Do(func() {
+		cur.claim, cur.err = auth.ParseAndAssertClaims(cur.Request, AssignSigner(e).Public())
+	})
+	return cur.claim, cur.err
+}
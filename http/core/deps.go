@@ -6,10 +6,13 @@ import (
 	"github.com/cott-io/stash/lang/env"
 	"github.com/cott-io/stash/lang/mail"
 	"github.com/cott-io/stash/lang/sms"
+	"github.com/cott-io/stash/libs/access"
 	"github.com/cott-io/stash/libs/account"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/org"
 	"github.com/cott-io/stash/libs/policy"
 	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/libs/webhook"
 )
 
 const (
@@ -22,6 +25,11 @@ const (
 	Orgs       = "deps.storage.orgs"
 	Policies   = "deps.storage.policies"
 	Secrets    = "deps.storage.secrets"
+	Webhooks   = "deps.storage.webhooks"
+	Dispatcher = "deps.webhooks.dispatcher"
+	AuditLog   = "deps.storage.audit"
+	Access     = "deps.storage.access"
+	Quotas     = "deps.quotas"
 )
 
 func AssignBillingKey(e env.Environment) (ret string) {
@@ -68,3 +76,23 @@ func AssignSecrets(e env.Environment) (ret secret.Storage) {
 	e.Assign(Secrets, &ret)
 	return
 }
+
+func AssignWebhooks(e env.Environment) (ret webhook.Storage) {
+	e.Assign(Webhooks, &ret)
+	return
+}
+
+func AssignDispatcher(e env.Environment) (ret *webhook.Dispatcher) {
+	e.Assign(Dispatcher, &ret)
+	return
+}
+
+func AssignAuditLog(e env.Environment) (ret audit.Storage) {
+	e.Assign(AuditLog, &ret)
+	return
+}
+
+func AssignAccess(e env.Environment) (ret access.Storage) {
+	e.Assign(Access, &ret)
+	return
+}
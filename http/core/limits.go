@@ -0,0 +1,43 @@
+package core
+
+import (
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The quotas of each org.  Quotas less than one are unlimited.
+type OrgQuotas struct {
+	BlockBytes int64 // the bytes of secret data an org may store
+}
+
+func AssignQuotas(e env.Environment) (ret OrgQuotas) {
+	e.Assign(Quotas, &ret)
+	return
+}
+
+// Keys requests by the account of their bearer token.  Requests without
+// a valid token are not keyed, and are left to the other limits.
+func ByAccount(e env.Environment, req http.Request) (string, bool) {
+	claim, err := RequestClaims(e, req)
+	if err != nil {
+		return "", false
+	}
+	return claim.Account.Id.String(), true
+}
+
+// Keys requests by the org in their path.  Only requests whose bearer
+// token is scoped to that org are keyed, so that a caller may not
+// exhaust the limit of an org of which it is not a member.
+func ByOrg(e env.Environment, req http.Request) (string, bool) {
+	var orgId uuid.UUID
+	if ok, err := http.ParsePathParam(req, "orgId", http.UUID, &orgId); !ok || err != nil {
+		return "", false
+	}
+
+	claim, err := RequestClaims(e, req)
+	if err != nil || claim.Member.OrgId != orgId {
+		return "", false
+	}
+	return orgId.String(), true
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	stdctx "context"
+	"crypto/tls"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/headers"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// A request carrying only a token and an org id.
+type tokenRequest struct {
+	*strings.Reader
+	token string
+	orgId string
+	reads int
+}
+
+func (r *tokenRequest) Close() error                                 { return nil }
+func (r *tokenRequest) URL() *url.URL                                { return &url.URL{} }
+func (r *tokenRequest) Method() string                               { return "GET" }
+func (r *tokenRequest) Remote() string                               { return "127.0.0.1:80" }
+func (r *tokenRequest) ReadQueryParam(string, *string) (bool, error) { return false, nil }
+func (r *tokenRequest) ReadBody(*[]byte) error                       { return nil }
+func (r *tokenRequest) Route() http.Route                            { return http.Get("/v1/orgs/{orgId}") }
+func (r *tokenRequest) TLS() *tls.ConnectionState                    { return nil }
+func (r *tokenRequest) Context() stdctx.Context                      { return stdctx.Background() }
+
+func (r *tokenRequest) ReadHeader(name string, val *string) bool {
+	if name != headers.Authorization || r.token == "" {
+		return false
+	}
+	r.reads++
+	*val = "Bearer " + r.token
+	return true
+}
+
+func (r *tokenRequest) ReadPathParam(name string, val *string) (bool, error) {
+	if name != "orgId" {
+		return false, nil
+	}
+	*val = r.orgId
+	return true, nil
+}
+
+func TestLimitKeys(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	signer, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	other, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	e := env.NewEnvironment(ctx, env.WithDependency(Signer, signer))
+
+	acctId, orgId := uuid.NewV1(), uuid.NewV1()
+
+	token := func(signer crypto.Signer, orgId uuid.UUID, ttl time.Duration) string {
+		tok, err := auth.SignClaims(signer, auth.BuildClaim(
+			auth.ClaimAccount(auth.ById(acctId), acctId),
+			auth.ClaimMember(orgId, auth.Member),
+			auth.ClaimExpires(ttl)))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return tok.String()
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		account bool
+		org     bool
+	}{
+		{"Member", token(signer, orgId, time.Minute), true, true},
+		{"OtherOrg", token(signer, uuid.NewV1(), time.Minute), true, false},
+		{"Expired", token(signer, orgId, -time.Minute), false, false},
+		{"Forged", token(other, orgId, time.Minute), false, false},
+		{"NoToken", "", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &tokenRequest{Reader: strings.NewReader(""), token: test.token, orgId: orgId.String()}
+
+			var account, org bool
+			ClaimsMiddleware(func(e env.Environment, req http.Request) http.Response {
+				_, account = ByAccount(e, req)
+				_, org = ByOrg(e, req)
+				return http.StatusOK
+			})(e, req)
+
+			assert.Equal(t, test.account, account)
+			assert.Equal(t, test.org, org)
+			if test.token != "" {
+				assert.Equal(t, 1, req.reads)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package core
+
+import "github.com/cott-io/stash/lang/metrics"
+
+// Counters of the operations handled by the server.  Request counts,
+// latencies and auth failures per route are recorded by the server's
+// metrics middleware.
+var (
+	LoginsTotal = metrics.NewCounter(
+		"stash_logins_total", "Number of login attempts", "protocol", "outcome")
+	SecretReadsTotal = metrics.NewCounter(
+		"stash_secret_reads_total", "Number of secrets read")
+	SecretWritesTotal = metrics.NewCounter(
+		"stash_secret_writes_total", "Number of secrets written")
+	BlockBytesTotal = metrics.NewCounter(
+		"stash_block_bytes_total", "Number of secret block bytes transferred", "direction")
+)
+
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+
+	BytesIn  = "in"
+	BytesOut = "out"
+)
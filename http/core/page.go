@@ -0,0 +1,101 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/headers"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/pkg/errors"
+)
+
+// The query params by which listings are paged, for their docs.
+var PageParams = []http.ParamID{
+	http.Param("cursor", http.String, new(string)),
+	http.Param("offset", http.Uint64, new(uint64)),
+	http.Param("limit", http.Uint64, new(uint64)),
+}
+
+// The names of the paging params.  They are not part of the scope of a
+// cursor, as they change from one page to the next.
+var pageParams = []string{"cursor", "offset", "limit"}
+
+// Parses the paging params of a listing.  A listing continues from
+// either an offset or a cursor, which must have been issued by this
+// server for the same listing.  Listings filtered by the body of the
+// request must supply the parsed filter; others supply nil.
+func ParsePage(env env.Environment, req http.Request, filter interface{}) (ret page.Page, err error) {
+	var cursor *string
+	if err = http.ParseQueryParams(req,
+		http.Param("cursor", http.String, &cursor),
+		http.Param("offset", http.Uint64, &ret.Offset),
+		http.Param("limit", http.Uint64, &ret.Limit),
+	); err != nil || cursor == nil {
+		return
+	}
+
+	if ret.Offset != nil {
+		err = errors.Wrapf(page.ErrCursor, "A listing may not be paged by both cursor and offset")
+		return
+	}
+
+	scope, err := listingScope(req, filter)
+	if err != nil {
+		return
+	}
+
+	ret.Cursor = (*page.Cursor)(cursor)
+	ret.After, err = ret.Cursor.Keys(AssignSigner(env).Public(), scope)
+	return
+}
+
+// Returns the cursor to the page after one of n items, if it was full.
+// The keys of the cursor are those of the last item, by which the store
+// ordered the listing.
+func WithNextPage(env env.Environment, req http.Request, filter interface{}, p page.Page, n int, keys func(last int) []interface{}) http.Response {
+	return func(r http.ResponseBuilder) (err error) {
+		if n == 0 || uint64(n) < p.Size() {
+			return
+		}
+
+		scope, err := listingScope(req, filter)
+		if err != nil {
+			return
+		}
+
+		next, err := page.NewCursor(AssignSigner(env), scope, keys(n-1)...)
+		if err != nil {
+			return
+		}
+
+		r.SetHeader(headers.NextCursor, string(next))
+		return
+	}
+}
+
+// Returns the scope of the cursors of a listing: its path along with
+// any params and body that filter it.  The body is reduced to a hash
+// of its encoding.
+func listingScope(req http.Request, filter interface{}) (ret string, err error) {
+	query := req.URL().Query()
+	for _, param := range pageParams {
+		query.Del(param)
+	}
+
+	ret = req.URL().Path + "?" + query.Encode()
+	if filter == nil {
+		return
+	}
+
+	raw, err := enc.Encode(enc.Json, filter)
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256(raw)
+	ret += "#" + base64.RawURLEncoding.EncodeToString(sum[:])
+	return
+}
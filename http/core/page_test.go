@@ -0,0 +1,116 @@
+package core
+
+import (
+	stdctx "context"
+	"crypto/tls"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/headers"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/stretchr/testify/assert"
+)
+
+// A listing request, paged by its query.
+type pageRequest struct {
+	*strings.Reader
+	url *url.URL
+}
+
+func (r *pageRequest) Close() error                                { return nil }
+func (r *pageRequest) URL() *url.URL                               { return r.url }
+func (r *pageRequest) Method() string                              { return "POST" }
+func (r *pageRequest) Remote() string                              { return "127.0.0.1:80" }
+func (r *pageRequest) ReadHeader(string, *string) bool             { return false }
+func (r *pageRequest) ReadPathParam(string, *string) (bool, error) { return false, nil }
+func (r *pageRequest) ReadBody(*[]byte) error                      { return nil }
+func (r *pageRequest) Route() http.Route                           { return http.Post("/v1/items_list") }
+func (r *pageRequest) TLS() *tls.ConnectionState                   { return nil }
+func (r *pageRequest) Context() stdctx.Context                     { return stdctx.Background() }
+
+func (r *pageRequest) ReadQueryParam(name string, val *string) (bool, error) {
+	*val = r.url.Query().Get(name)
+	return *val != "", nil
+}
+
+// Captures the headers of a response.
+type headerBuilder map[string]string
+
+func (h headerBuilder) SetCode(int)                       {}
+func (h headerBuilder) SetHeader(name string, val string) { h[name] = val }
+func (h headerBuilder) SetBody(string, []byte)            {}
+func (h headerBuilder) SetBodyRaw(string, io.Reader)      {}
+
+type itemFilter struct {
+	Names []string `json:"names"`
+}
+
+func TestPage_Scope(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	signer, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	e := env.NewEnvironment(ctx, env.WithDependency(Signer, signer))
+
+	request := func(query string) http.Request {
+		u, err := url.Parse("/v1/items_list?" + query)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return &pageRequest{strings.NewReader(""), u}
+	}
+
+	// Issues the cursor after the first page of a listing.
+	next := func(filter interface{}) string {
+		req := request("limit=1")
+
+		pg, err := ParsePage(e, req, filter)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		hdrs := headerBuilder{}
+		if !assert.Nil(t, WithNextPage(e, req, filter, pg, 1, func(int) []interface{} {
+			return []interface{}{"a"}
+		})(hdrs)) {
+			t.FailNow()
+		}
+		return hdrs[headers.NextCursor]
+	}
+
+	t.Run("SameFilter", func(t *testing.T) {
+		cursor := next(itemFilter{[]string{"a"}})
+
+		pg, err := ParsePage(e, request("cursor="+url.QueryEscape(cursor)), itemFilter{[]string{"a"}})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []interface{}{"a"}, pg.After)
+	})
+
+	t.Run("OtherFilter", func(t *testing.T) {
+		cursor := next(itemFilter{[]string{"a"}})
+
+		_, err := ParsePage(e, request("cursor="+url.QueryEscape(cursor)), itemFilter{[]string{"b"}})
+		assert.True(t, errs.Is(err, page.ErrCursor), "%v", err)
+	})
+
+	t.Run("NoFilter", func(t *testing.T) {
+		cursor := next(nil)
+
+		_, err := ParsePage(e, request("cursor="+url.QueryEscape(cursor)), itemFilter{})
+		assert.True(t, errs.Is(err, page.ErrCursor), "%v", err)
+	})
+}
@@ -1,11 +1,19 @@
 package core
 
 import (
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
 	uuid "github.com/satori/go.uuid"
 )
 
+// Returns the context against which the conditions of the bearer's
+// memberships are evaluated.
+func PolicyContext(req http.Request, claim auth.Claim) policy.Context {
+	return policy.NewContext(claim, RemoteHost(req))
+}
+
 func LoadGroupNames(db policy.Storage, orgId uuid.UUID, groupIds []uuid.UUID) (ret map[uuid.UUID]string, err error) {
 	if len(groupIds) == 0 {
 		return
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/lang/mime"
+	"github.com/cott-io/stash/lang/net"
+	"github.com/cott-io/stash/lang/ref"
+	"github.com/cott-io/stash/libs/account"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Cert bindings tie the identities of client certificates to the
+// (typically agent) accounts that may present them.  A request made
+// with a bound certificate must carry a token of the bound account.
+// The bindings are read from a file mapping certificate identities
+// to account identities, e.g.:
+//
+//	spiffe://ci/deployer: "@deploy-bot"
+//	backup.internal: backup@example.com
+type CertBindings struct {
+	file string
+
+	lock  sync.RWMutex
+	gen   int
+	ids   map[string]auth.Identity
+	accts map[string]uuid.UUID
+}
+
+func NewCertBindings(file string) (ret *CertBindings, err error) {
+	ret = &CertBindings{file: file}
+	err = ret.Reload()
+	return
+}
+
+// Reads the bindings file again.  On failure, the previous bindings
+// remain in use.
+func (c *CertBindings) Reload() (err error) {
+	raw, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Error reading cert bindings [%v]: %v", c.file, err)
+		return
+	}
+
+	var dec enc.Decoder = enc.Yaml
+	if ref.Pointer(c.file).Mime() == mime.Json {
+		dec = enc.Json
+	}
+
+	var all map[string]string
+	if err = dec.DecodeBinary(raw, &all); err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid cert bindings [%v]: %v", c.file, err)
+		return
+	}
+
+	ids := make(map[string]auth.Identity)
+	for cert, acct := range all {
+		if ids[cert], err = auth.ParseFriendlyIdentity(acct); err != nil {
+			err = errors.Wrapf(errs.ArgError, "Invalid account [%v] bound to [%v]: %v", acct, cert, err)
+			return
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ids, c.accts, c.gen = ids, nil, c.gen+1
+	return
+}
+
+// Returns the account identity bound to the certificate identity.
+func (c *CertBindings) Lookup(cert string) (ret auth.Identity, ok bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	ret, ok = c.ids[cert]
+	return
+}
+
+// Returns the ids of the accounts bound to each certificate identity.
+// Certificates bound to identities that cannot be resolved are mapped
+// to the nil id, so that they are bound to no account at all.  The
+// result is cached until the bindings are reloaded, unless an identity
+// could not be resolved.
+func (c *CertBindings) resolve(db account.Storage) (ret map[string]uuid.UUID, err error) {
+	c.lock.RLock()
+	gen, ids, ret := c.gen, c.ids, c.accts
+	c.lock.RUnlock()
+	if ret != nil {
+		return
+	}
+
+	resolved, complete := make(map[string]uuid.UUID), true
+	for cert, bound := range ids {
+		id, err := RequireIdentity(db, bound)
+		switch errors.Cause(err) {
+		default:
+			return nil, err
+		case account.ErrNoIdentity, account.ErrIdentityUnverified:
+			resolved[cert], complete = uuid.Nil, false
+		case nil:
+			resolved[cert] = id.AccountId
+		}
+	}
+
+	if complete {
+		c.lock.Lock()
+		if c.gen == gen {
+			c.accts = resolved
+		}
+		c.lock.Unlock()
+	}
+	ret = resolved
+	return
+}
+
+// Verifies that the account may make a request with the certificate,
+// if any was presented.  A certificate that is bound to an account may
+// only be presented by that account, and an account that is bound to a
+// certificate must present one.
+func verifyCertBinding(bound map[string]uuid.UUID, cert string, acctId uuid.UUID) (err error) {
+	if cert != "" {
+		owner, ok := bound[cert]
+		if ok && owner == acctId {
+			return
+		}
+		if ok {
+			err = errors.Wrapf(auth.ErrUnauthorized, "Client certificate [%v] is bound to another account", cert)
+			return
+		}
+	}
+
+	for _, owner := range bound {
+		if owner == acctId {
+			err = errors.Wrapf(auth.ErrUnauthorized, "Account [%v] must present its bound client certificate", acctId)
+			return
+		}
+	}
+	return
+}
+
+// Returns a middleware that rejects requests whose client certificate
+// is bound to an account other than that of the bearer's token, as well
+// as requests by bound accounts that do not present their certificate.
+// Requests without a token (e.g. logins) are passed through to the
+// handler.
+func CertBindingMiddleware(bindings *CertBindings) http.Middleware {
+	return func(h http.Handler) http.Handler {
+		return func(e env.Environment, req http.Request) http.Response {
+			bound, err := bindings.resolve(AssignAccounts(e))
+			if err != nil {
+				return http.Panic(err)
+			}
+			if len(bound) == 0 {
+				return h(e, req)
+			}
+
+			claim, err := RequestClaims(e, req)
+			if err != nil {
+				return h(e, req)
+			}
+
+			var cert string
+			if state := req.TLS(); state != nil && len(state.PeerCertificates) > 0 {
+				cert = net.CertIdentity(state.PeerCertificates[0])
+			}
+
+			if err := verifyCertBinding(bound, cert, claim.Account.Id); err != nil {
+				e.Logger().Error("Client certificate [%v] presented by account [%v]: %v", cert, claim.Account.Id, err)
+				return http.Unauthorized(err)
+			}
+			return h(e, req)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCertBinding(t *testing.T) {
+	agent, other := uuid.NewV1(), uuid.NewV1()
+
+	bound := map[string]uuid.UUID{
+		"spiffe://ci/deployer": agent,
+		"orphan.internal":      uuid.Nil,
+	}
+
+	tests := []struct {
+		name   string
+		cert   string
+		acctId uuid.UUID
+		ok     bool
+	}{
+		{"BoundCert", "spiffe://ci/deployer", agent, true},
+		{"BoundCert_OtherAccount", "spiffe://ci/deployer", other, false},
+		{"UnresolvedCert", "orphan.internal", other, false},
+		{"UnboundCert", "laptop.internal", other, true},
+		{"UnboundCert_BoundAccount", "laptop.internal", agent, false},
+		{"NoCert", "", other, true},
+		{"NoCert_BoundAccount", "", agent, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifyCertBinding(bound, test.cert, test.acctId)
+			if test.ok {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, errs.Is(err, auth.ErrUnauthorized), "%v", err)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package core
+
+import (
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/libs/webhook"
+)
+
+// Publishes an event to the org's webhooks.  Never blocks.
+func PublishEvent(e env.Environment, event webhook.Event) {
+	AssignDispatcher(e).Publish(event)
+}
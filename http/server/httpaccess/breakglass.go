@@ -0,0 +1,649 @@
+package httpaccess
+
+import (
+	"fmt"
+	gohttp "net/http"
+
+	client "github.com/cott-io/stash/http/client/httpaccess"
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Break-glass escrows are configured by those with sudo on a policy.
+// Any member of the org may request an unlock, but the unlock may only
+// be claimed once a quorum of custodians have handed over their shares.
+// The shares are encrypted end to end, so the server only ever sees
+// ciphertext.  Every step is audited and the claim is published as a
+// webhook event.
+func BreakGlassHandlers(svc *http.Service) {
+	svc.Register(http.Put("/v1/orgs/{orgId}/policies/{policyId}/escrow"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, orgs, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignOrgs(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var escrow access.Escrow
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &escrow); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.AssertTrue(escrow.OrgId == orgId, "Inconsistent org ids"),
+				http.AssertTrue(escrow.PolicyId == policyId, "Inconsistent policy ids"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := policy.EnsurePolicyActions(policies, core.PolicyContext(req, claim), orgId, claim.Account.Id, policyId, policy.Sudo); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.EscrowSave, audit.PolicyTarget(policyId), err)
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if !escrow.Deleted {
+				if err := escrow.Validate(); err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+
+				latest, ok, err := policies.LoadPolicy(orgId, policyId)
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				if !ok {
+					ret = http.StatusNotFound
+					return
+				}
+
+				if escrow.PolicyVer != latest.Version {
+					ret = http.Conflict(
+						errors.Wrapf(errs.StateError, "Escrow was split from version [%v] of the policy. Latest is [%v]", escrow.PolicyVer, latest.Version))
+					return
+				}
+
+				for _, id := range escrow.CustodianIds() {
+					if _, err := core.RequireOrgMembership(orgs, orgId, id); err != nil {
+						ret = http.BadRequest(err)
+						return
+					}
+				}
+			}
+
+			if err := requests.SaveEscrow(escrow); err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.EscrowSave, audit.PolicyTarget(policyId),
+				audit.WithDetail(fmt.Sprintf("%v of %v", escrow.Quorum, len(escrow.Custodies))))
+
+			if !escrow.Deleted {
+				notify(env, escrow.CustodianIds(), CustodyTemplate, newEscrowFields(escrow))
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Escrows the key of a policy", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(access.Escrow{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/escrow"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			escrow, ok, err := requests.LoadEscrow(orgId, policyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			ret = http.Ok(enc.Json, escrow)
+			return
+		},
+		http.DocSummary("Loads the escrow of a policy", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Escrow{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Post("/v1/orgs/{orgId}/breakglass"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.CreateUnlockRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(r.PolicyId, "Missing policy id"),
+				http.NotZero(r.Item, "Missing item"),
+				http.NotZero(r.Reason, "Missing reason"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			escrow, err := requireEscrow(requests, policies, orgId, r.PolicyId)
+			if err != nil {
+				ret = escrowError(err)
+				return
+			}
+
+			unlock, err := access.NewUnlock(escrow, claim.Account.Id, r.Item, r.Reason)
+			if err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := requests.SaveUnlock(unlock); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.UnlockRequest, audit.PolicyTarget(r.PolicyId),
+				audit.WithDetail(r.Reason))
+
+			recipients, err := listBreakGlassContacts(policies, escrow, claim.Account.Id)
+			if err != nil {
+				env.Logger().Error("Error listing sudoers of policy [%v]: %v", r.PolicyId, err)
+			}
+			notify(env, recipients, UnlockTemplate, newUnlockFields(unlock))
+
+			ret = http.Ok(enc.Json, unlock)
+			return
+		},
+		http.DocSummary("Requests an unlock of an escrowed policy", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.CreateUnlockRequest{}),
+		http.DocResponse(gohttp.StatusOK, access.Unlock{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/breakglass"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var status *string
+			var requesterId *uuid.UUID
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("status", http.String, &status),
+				http.Param("requester", http.UUID, &requesterId),
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			var filter []func(*access.Filter)
+			if status != nil {
+				parsed, err := access.ParseStatus(*status)
+				if err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+				filter = append(filter, access.FilterByStatus(parsed))
+			}
+			if requesterId != nil {
+				filter = append(filter, access.FilterByRequester(*requesterId))
+			}
+
+			// Directors may see every unlock.  Everyone else may only
+			// see their own.
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Director)); err != nil {
+				filter = append(filter, access.FilterByRequester(claim.Account.Id))
+			}
+
+			all, err := requests.ListUnlocks(orgId, access.BuildFilter(filter...), page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, all)
+			return
+		},
+		http.DocSummary("Lists the unlocks of an org", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("status", http.String, new(*string)),
+			http.Param("requester", http.UUID, new(*uuid.UUID)),
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []access.Unlock{}))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/breakglass/{unlockId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, unlockId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("unlockId", http.UUID, &unlockId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			unlock, ok, err := requests.LoadUnlock(orgId, unlockId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			// Custodians must be able to see the unlocks they are asked
+			// to hand their shares over to.
+			if unlock.RequesterId != claim.Account.Id {
+				if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Director)); err != nil {
+					escrow, _, err := requests.LoadEscrow(orgId, unlock.PolicyId)
+					if err != nil {
+						ret = http.Panic(err)
+						return
+					}
+					if _, ok := escrow.Custody(claim.Account.Id); !ok {
+						ret = http.Unauthorized(
+							errors.Wrapf(auth.ErrUnauthorized, "Not a custodian of policy [%v]", unlock.PolicyId))
+						return
+					}
+				}
+			}
+
+			ret = http.Ok(enc.Json, unlock)
+			return
+		},
+		http.DocSummary("Loads an unlock", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("unlockId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Unlock{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/breakglass/{unlockId}/shares"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, unlockId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("unlockId", http.UUID, &unlockId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.SubmitShareRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			unlock, ok, err := requests.LoadUnlock(orgId, unlockId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			escrow, ok, err := requests.LoadEscrow(orgId, unlock.PolicyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok || escrow.Version != unlock.EscrowVer {
+				ret = http.Conflict(
+					errors.Wrapf(errs.StateError, "Escrow of policy [%v] has changed since the unlock was requested", unlock.PolicyId))
+				return
+			}
+
+			if _, ok := escrow.Custody(claim.Account.Id); !ok {
+				err := errors.Wrapf(auth.ErrUnauthorized, "Not a custodian of policy [%v]", unlock.PolicyId)
+				core.AuditDenied(env, req, claim, orgId, audit.UnlockShare, audit.PolicyTarget(unlock.PolicyId), err)
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			submitted, err := unlock.Submit(core.PolicyContext(req, claim).Now, claim.Account.Id, r.Share)
+			if err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			if err := requests.SaveUnlock(submitted); err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.UnlockShare, audit.PolicyTarget(unlock.PolicyId),
+				audit.WithDetail(unlock.Id.String()))
+
+			if len(submitted.Shares) == submitted.Quorum {
+				notify(env, []uuid.UUID{unlock.RequesterId}, UnlockReadyTemplate, newUnlockFields(submitted))
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Submits a share of an escrowed key", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("unlockId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.SubmitShareRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/breakglass/{unlockId}/claim"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, unlockId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("unlockId", http.UUID, &unlockId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.ClaimUnlockRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			unlock, ok, err := requests.LoadUnlock(orgId, unlockId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			if unlock.RequesterId != claim.Account.Id {
+				err := errors.Wrapf(auth.ErrUnauthorized, "Unlocks may only be claimed by their requester")
+				core.AuditDenied(env, req, claim, orgId, audit.UnlockClaim, audit.PolicyTarget(unlock.PolicyId), err)
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			escrow, err := requireEscrow(requests, policies, orgId, unlock.PolicyId)
+			if err != nil {
+				ret = escrowError(err)
+				return
+			}
+			if escrow.Version != unlock.EscrowVer {
+				ret = http.Conflict(
+					errors.Wrapf(errs.StateError, "Escrow of policy [%v] has changed since the unlock was requested", unlock.PolicyId))
+				return
+			}
+
+			now := core.PolicyContext(req, claim).Now
+			claimed, err := unlock.Claim(now)
+			if err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			if err := unlock.VerifyGrant(now, escrow, r.Member); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			prev, ok, err := policies.LoadPolicyMember(orgId, unlock.PolicyId, unlock.RequesterId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			// Standing access must not be replaced by a temporary grant.
+			if ok && !prev.Deleted && prev.Conditions.NotAfter.IsZero() {
+				ret = http.Conflict(
+					errors.Wrapf(access.ErrDecided, "Requester is already a member of policy [%v]", unlock.PolicyId))
+				return
+			}
+			if ok && r.Member.Version != prev.Version+1 {
+				ret = http.Conflict(
+					errors.Wrapf(errs.StateError, "Membership version [%v] must follow [%v]", r.Member.Version, prev.Version))
+				return
+			}
+
+			latest, ok, err := policies.LoadPolicy(orgId, unlock.PolicyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			if err := requests.SaveUnlock(claimed); err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			// The requester keeps the pass once the grant expires, so the
+			// policy is due a rekey.  This also retires the escrow.
+			if !latest.RekeyDue {
+				if err := policies.UpdatePolicy(latest.Update(func(p *policy.Policy) { p.RekeyDue = true })); err != nil {
+					ret = http.Panic(err)
+					return
+				}
+			}
+
+			if err := policies.SavePolicyMember(r.Member); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			subject := r.Member.MemberType.FormatId(r.Member.MemberId)
+			core.Audit(env, req, claim, orgId, audit.UnlockClaim, audit.PolicyTarget(unlock.PolicyId),
+				audit.WithDetail(subject))
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, webhook.BreakGlass, claim.Account.Id, audit.PolicyTarget(unlock.PolicyId)).
+					WithSubject(subject))
+
+			recipients, err := listBreakGlassContacts(policies, escrow, claim.Account.Id)
+			if err != nil {
+				env.Logger().Error("Error listing sudoers of policy [%v]: %v", unlock.PolicyId, err)
+			}
+			notify(env, recipients, UnlockClaimedTemplate, newUnlockFields(claimed))
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Claims the policy of an approved unlock", "breakglass"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("unlockId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.ClaimUnlockRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+}
+
+// Loads the escrow of a policy, ensuring it is still bound to the
+// latest version of the policy.
+func requireEscrow(requests access.Storage, policies policy.Storage, orgId, policyId uuid.UUID) (ret access.Escrow, err error) {
+	ret, ok, err := requests.LoadEscrow(orgId, policyId)
+	if err != nil {
+		return
+	}
+	if !ok || ret.Deleted {
+		err = errors.Wrapf(access.ErrNoEscrow, "Policy [%v] has no break-glass escrow", policyId)
+		return
+	}
+
+	latest, ok, err := policies.LoadPolicy(orgId, policyId)
+	if err != nil {
+		return
+	}
+	if !ok || latest.Version != ret.PolicyVer {
+		err = errors.Wrapf(errs.StateError, "Escrow of policy [%v] is stale. It must be set up again", policyId)
+	}
+	return
+}
+
+func escrowError(err error) http.Response {
+	switch errors.Cause(err) {
+	case access.ErrNoEscrow:
+		return http.BadRequest(err)
+	case errs.StateError:
+		return http.Conflict(err)
+	default:
+		return http.Panic(err)
+	}
+}
+
+// Returns the accounts that should be told of a break-glass: the
+// custodians and the users with sudo on the policy.
+func listBreakGlassContacts(db policy.Storage, escrow access.Escrow, requesterId uuid.UUID) (ret []uuid.UUID, err error) {
+	ret, err = listApprovers(db, access.Approvers{OrgId: escrow.OrgId, PolicyId: escrow.PolicyId}, requesterId)
+	for _, id := range escrow.CustodianIds() {
+		if id != requesterId && !containsId(ret, id) {
+			ret = append(ret, id)
+		}
+	}
+	return
+}
+
+func containsId(all []uuid.UUID, id uuid.UUID) bool {
+	for _, cur := range all {
+		if cur == id {
+			return true
+		}
+	}
+	return false
+}
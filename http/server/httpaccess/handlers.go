@@ -0,0 +1,667 @@
+package httpaccess
+
+import (
+	gohttp "net/http"
+
+	client "github.com/cott-io/stash/http/client/httpaccess"
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Handlers(svc *http.Service) {
+	RequestHandlers(svc)
+	ApproverHandlers(svc)
+	BreakGlassHandlers(svc)
+	ReviewHandlers(svc)
+}
+
+// Access requests may be made by any member of an org.  They may be
+// decided by anyone with sudo on the policy or by the members of the
+// policy's approver group.  The approver is responsible for encrypting
+// the policy key for the requester, so the grant itself is performed
+// by the client.  The server merely verifies that the grant matches
+// the request.
+func RequestHandlers(svc *http.Service) {
+	svc.Register(http.Post("/v1/orgs/{orgId}/access"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.CreateRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(r.PolicyId, "Missing policy id"),
+				http.NotZero(r.Item, "Missing item"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			_, ok, err := policies.LoadPolicy(orgId, r.PolicyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			approvers, err := loadApprovers(requests, orgId, r.PolicyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if r.Duration > approvers.Max() {
+				ret = http.BadRequest(
+					errors.Wrapf(errs.ArgError, "Requested duration [%v] exceeds the maximum [%v]", r.Duration, approvers.Max()))
+				return
+			}
+
+			request, err := access.NewRequest(orgId, r.PolicyId, claim.Account.Id, r.Item, r.Duration, r.Reason, r.Actions...)
+			if err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := requests.SaveRequest(request); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.AccessRequest, audit.PolicyTarget(r.PolicyId),
+				audit.WithDetail(r.Item))
+
+			recipients, err := listApprovers(policies, approvers, claim.Account.Id)
+			if err != nil {
+				env.Logger().Error("Error listing approvers of policy [%v]: %v", r.PolicyId, err)
+			}
+			notify(env, recipients, RequestTemplate, newRequestFields(request))
+
+			ret = http.Ok(enc.Json, request)
+			return
+		},
+		http.DocSummary("Requests access to a policy", "access"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.CreateRequest{}),
+		http.DocResponse(gohttp.StatusOK, access.Request{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/access"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var status *string
+			var requesterId, policyId *uuid.UUID
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("status", http.String, &status),
+				http.Param("requester", http.UUID, &requesterId),
+				http.Param("policy", http.UUID, &policyId),
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			var filter []func(*access.Filter)
+			if status != nil {
+				parsed, err := access.ParseStatus(*status)
+				if err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+				filter = append(filter, access.FilterByStatus(parsed))
+			}
+			if requesterId != nil {
+				filter = append(filter, access.FilterByRequester(*requesterId))
+			}
+			if policyId != nil {
+				filter = append(filter, access.FilterByPolicies(*policyId))
+			}
+
+			// Directors may see every request.  Approvers may see the
+			// requests of their policies.  Everyone else may only see
+			// their own.
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Director)); err != nil {
+				switch {
+				case policyId != nil:
+					if err := ensureApprover(policies, requests, core.PolicyContext(req, claim), orgId, *policyId, claim.Account.Id); err != nil {
+						ret = http.Unauthorized(err)
+						return
+					}
+				default:
+					filter = append(filter, access.FilterByRequester(claim.Account.Id))
+				}
+			}
+
+			all, err := requests.ListRequests(orgId, access.BuildFilter(filter...), page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, all)
+			return
+		},
+		http.DocSummary("Lists the access requests of an org", "access"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("status", http.String, new(*string)),
+			http.Param("requester", http.UUID, new(*uuid.UUID)),
+			http.Param("policy", http.UUID, new(*uuid.UUID)),
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []access.Request{}))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/access/{requestId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, requestId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("requestId", http.UUID, &requestId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			request, ok, err := requests.LoadRequest(orgId, requestId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			if request.RequesterId != claim.Account.Id {
+				if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Director)); err != nil {
+					if err := ensureApprover(policies, requests, core.PolicyContext(req, claim), orgId, request.PolicyId, claim.Account.Id); err != nil {
+						ret = http.Unauthorized(err)
+						return
+					}
+				}
+			}
+
+			ret = http.Ok(enc.Json, request)
+			return
+		},
+		http.DocSummary("Loads an access request", "access"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("requestId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Request{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/access/{requestId}/approve"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, requestId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("requestId", http.UUID, &requestId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.ApproveRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			request, ok, err := requests.LoadRequest(orgId, requestId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			if request.RequesterId == claim.Account.Id {
+				ret = http.Unauthorized(
+					errors.Wrapf(auth.ErrUnauthorized, "Requests may not be approved by their requester"))
+				return
+			}
+
+			ctx := core.PolicyContext(req, claim)
+			if err := ensureApprover(policies, requests, ctx, orgId, request.PolicyId, claim.Account.Id); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.AccessApprove, audit.PolicyTarget(request.PolicyId), err)
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := request.VerifyGrant(ctx.Now, r.Member); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := r.Member.Conditions.Validate(); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			prev, ok, err := policies.LoadPolicyMember(orgId, request.PolicyId, request.RequesterId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			// Standing access must not be replaced by a temporary grant.
+			if ok && !prev.Deleted && prev.Conditions.NotAfter.IsZero() {
+				ret = http.Conflict(
+					errors.Wrapf(access.ErrDecided, "Requester is already a member of policy [%v]", request.PolicyId))
+				return
+			}
+			if ok && r.Member.Version != prev.Version+1 {
+				ret = http.Conflict(
+					errors.Wrapf(errs.StateError, "Membership version [%v] must follow [%v]", r.Member.Version, prev.Version))
+				return
+			}
+
+			approved, err := request.Approve(claim.Account.Id, r.Comment, r.Member)
+			if err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			if err := policies.SavePolicyMember(r.Member); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if err := requests.SaveRequest(approved); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			subject := r.Member.MemberType.FormatId(r.Member.MemberId)
+			core.Audit(env, req, claim, orgId, audit.AccessApprove, audit.PolicyTarget(request.PolicyId),
+				audit.WithDetail(subject))
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, webhook.PolicyGrant, claim.Account.Id, audit.PolicyTarget(request.PolicyId)).
+					WithSubject(subject))
+
+			notify(env, []uuid.UUID{request.RequesterId}, DecisionTemplate, newRequestFields(approved))
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Approves an access request", "access"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("requestId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.ApproveRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/access/{requestId}/deny"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, requestId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("requestId", http.UUID, &requestId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.DenyRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			request, ok, err := requests.LoadRequest(orgId, requestId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			// Requesters may always withdraw their own requests.
+			if request.RequesterId != claim.Account.Id {
+				if err := ensureApprover(policies, requests, core.PolicyContext(req, claim), orgId, request.PolicyId, claim.Account.Id); err != nil {
+					core.AuditDenied(env, req, claim, orgId, audit.AccessDeny, audit.PolicyTarget(request.PolicyId), err)
+					ret = http.Unauthorized(err)
+					return
+				}
+			}
+
+			denied, err := request.Deny(claim.Account.Id, r.Comment)
+			if err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			if err := requests.SaveRequest(denied); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.AccessDeny, audit.PolicyTarget(request.PolicyId),
+				audit.WithDetail(audit.AccountTarget(request.RequesterId)))
+
+			if request.RequesterId != claim.Account.Id {
+				notify(env, []uuid.UUID{request.RequesterId}, DecisionTemplate, newRequestFields(denied))
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Denies an access request", "access"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("requestId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.DenyRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+}
+
+// Approvers may only be configured by those with sudo on the policy.
+func ApproverHandlers(svc *http.Service) {
+	svc.Register(http.Put("/v1/orgs/{orgId}/policies/{policyId}/approvers"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var approvers access.Approvers
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &approvers); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.AssertTrue(approvers.OrgId == orgId, "Inconsistent org ids"),
+				http.AssertTrue(approvers.PolicyId == policyId, "Inconsistent policy ids"),
+				http.AssertTrue(approvers.MaxDuration >= 0, "Max duration must not be negative"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := policy.EnsurePolicyActions(policies, core.PolicyContext(req, claim), orgId, claim.Account.Id, policyId, policy.Sudo); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if approvers.GroupId != uuid.Nil {
+				_, ok, err := loadGroupPolicy(policies, orgId, approvers.GroupId)
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				if !ok {
+					ret = http.BadRequest(
+						errors.Wrapf(errs.ArgError, "No such group [%v]", approvers.GroupId))
+					return
+				}
+			}
+
+			if err := requests.SaveApprovers(approvers); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Saves the approvers of a policy", "access"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(access.Approvers{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/approvers"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			approvers, ok, err := requests.LoadApprovers(orgId, policyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			ret = http.Ok(enc.Json, approvers)
+			return
+		},
+		http.DocSummary("Loads the approvers of a policy", "access"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Approvers{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
+
+func loadApprovers(db access.Storage, orgId, policyId uuid.UUID) (ret access.Approvers, err error) {
+	ret, ok, err := db.LoadApprovers(orgId, policyId)
+	if err != nil || ok {
+		return
+	}
+
+	ret = access.Approvers{OrgId: orgId, PolicyId: policyId}
+	return
+}
+
+// Returns the id of the policy backing a group.
+func loadGroupPolicy(db policy.Storage, orgId, groupId uuid.UUID) (ret uuid.UUID, ok bool, err error) {
+	groups, err := db.ListGroups(orgId, policy.BuildGroupFilter(policy.WithGroupIds(groupId)), page.Page{})
+	if err != nil || len(groups) == 0 {
+		return
+	}
+
+	ret, ok = groups[0].PolicyId, true
+	return
+}
+
+// Ensures the user may decide the requests of a policy.
+func ensureApprover(policies policy.Storage, requests access.Storage, ctx policy.Context, orgId, policyId, userId uuid.UUID) (err error) {
+	cause := policy.EnsurePolicyActions(policies, ctx, orgId, userId, policyId, policy.Sudo)
+	if cause == nil {
+		return
+	}
+
+	approvers, err := loadApprovers(requests, orgId, policyId)
+	if err != nil || approvers.GroupId == uuid.Nil {
+		return firstErr(err, cause)
+	}
+
+	groupPolicyId, ok, err := loadGroupPolicy(policies, orgId, approvers.GroupId)
+	if err != nil || !ok {
+		return firstErr(err, cause)
+	}
+
+	enabled, err := policies.LoadEnabledActions(ctx, orgId, userId, groupPolicyId)
+	if err != nil {
+		return
+	}
+	if _, ok := enabled[groupPolicyId]; !ok {
+		err = cause
+	}
+	return
+}
+
+// Returns the accounts that should be notified of a request: the
+// users with sudo on the policy, and the users of the approver group.
+func listApprovers(db policy.Storage, approvers access.Approvers, requesterId uuid.UUID) (ret []uuid.UUID, err error) {
+	members, err := db.ListPolicyMembers(approvers.OrgId, approvers.PolicyId, page.Page{})
+	if err != nil {
+		return
+	}
+
+	uniq := make(map[uuid.UUID]bool)
+	for _, m := range members {
+		if m.MemberType == policy.UserType && m.Actions.Enabled(policy.Sudo) {
+			uniq[m.MemberId] = true
+		}
+	}
+
+	if approvers.GroupId != uuid.Nil {
+		groupPolicyId, ok, err := loadGroupPolicy(db, approvers.OrgId, approvers.GroupId)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			members, err := db.ListPolicyMembers(approvers.OrgId, groupPolicyId, page.Page{})
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range members {
+				if m.MemberType == policy.UserType {
+					uniq[m.MemberId] = true
+				}
+			}
+		}
+	}
+
+	delete(uniq, requesterId)
+	for id := range uniq {
+		ret = append(ret, id)
+	}
+	return
+}
+
+func firstErr(all ...error) error {
+	for _, err := range all {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package httpaccess
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/msgs"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Notifies the accounts of an access request.  Failures are logged
+// but otherwise ignored.  Requests may always be found by listing them.
+func notify(env env.Environment, acctIds []uuid.UUID, template msgs.Template, data interface{}) {
+	if len(acctIds) == 0 {
+		return
+	}
+
+	ids, err := core.AssignAccounts(env).ListIdentitiesByIds(acctIds)
+	if err != nil {
+		env.Logger().Error("Error loading identities to notify of [%v]: %v", template.Subject, err)
+		return
+	}
+
+	for _, id := range ids {
+		if !id.Verified || id.Deleted {
+			continue
+		}
+
+		var err error
+		switch id.Id.Protocol() {
+		default:
+			continue
+		case auth.Email:
+			err = msgs.SendMail(core.AssignMailer(env), msgs.Compile(template, id.Id.Value(), data))
+		case auth.Phone:
+			err = msgs.SendText(core.AssignTexter(env), msgs.Compile(template, id.Id.Value(), data))
+		}
+		if err != nil {
+			env.Logger().Error("Error notifying [%v] of [%v]: %v", id.Id, template.Subject, err)
+		}
+	}
+}
+
+type RequestFields struct {
+	Id       uuid.UUID
+	Item     string
+	Actions  []string
+	Duration time.Duration
+	Reason   string
+	Status   access.Status
+	Comment  string
+	Expires  string
+}
+
+func newRequestFields(req access.Request) RequestFields {
+	var expires string
+	if !req.Expires.IsZero() {
+		expires = req.Expires.Format(time.RFC3339)
+	}
+
+	var actions []string
+	for _, a := range req.Actions.Flatten() {
+		actions = append(actions, string(a))
+	}
+
+	return RequestFields{req.Id, req.Item, actions, req.Duration, req.Reason, req.Status, req.Comment, expires}
+}
+
+var RequestTemplate = msgs.BuildTemplate(
+	"Access Requested",
+
+	msgs.AsMicro(`
+Access to {{.Item}} was requested for {{.Duration}}: {{.Reason}}
+
+stash access approve {{.Id}}`),
+
+	msgs.AsText(`
+Access Requested
+
+Access to {{.Item}} has been requested for {{.Duration}}.
+
+Actions: {{range .Actions}}{{.}} {{end}}
+Reason: {{.Reason}}
+
+Approve the request on the command line by running the following command.
+
+stash access approve {{.Id}}
+
+Or deny it:
+
+stash access deny {{.Id}} --comment <reason>
+`),
+
+	msgs.AsMarkdown(`
+### Access Requested
+
+Access to **{{.Item}}** has been requested for **{{.Duration}}**.
+
+* Actions: {{range .Actions}}{{.}} {{end}}
+* Reason: {{.Reason}}
+
+#### Approving on the command line:
+
+    stash access approve {{.Id}}
+`))
+
+var DecisionTemplate = msgs.BuildTemplate(
+	"Access Request Decided",
+
+	msgs.AsMicro(`
+Your request for {{.Item}} was {{.Status}}.{{if .Expires}} Access expires at {{.Expires}}.{{end}}`),
+
+	msgs.AsText(`
+Access Request {{.Status}}
+
+Your request for access to {{.Item}} was {{.Status}}.
+{{if .Comment}}
+Comment: {{.Comment}}
+{{end}}{{if .Expires}}
+Your access expires at {{.Expires}}.
+{{end}}`),
+
+	msgs.AsMarkdown(`
+### Access Request {{.Status}}
+
+Your request for access to **{{.Item}}** was **{{.Status}}**.
+{{if .Comment}}
+> {{.Comment}}
+{{end}}{{if .Expires}}
+Your access expires at {{.Expires}}.
+{{end}}`))
+
+type EscrowFields struct {
+	PolicyId  uuid.UUID
+	Quorum    int
+	Custodies int
+	Grant     time.Duration
+}
+
+func newEscrowFields(e access.Escrow) EscrowFields {
+	return EscrowFields{e.PolicyId, e.Quorum, len(e.Custodies), e.Grant}
+}
+
+type UnlockFields struct {
+	Id      uuid.UUID
+	Item    string
+	Reason  string
+	Quorum  int
+	Shares  int
+	Status  access.Status
+	Expires string
+}
+
+func newUnlockFields(u access.Unlock) UnlockFields {
+	return UnlockFields{u.Id, u.Item, u.Reason, u.Quorum, len(u.Shares), u.Status, u.Expires.Format(time.RFC3339)}
+}
+
+var CustodyTemplate = msgs.BuildTemplate(
+	"Break-Glass Custody Assigned",
+
+	msgs.AsMicro(`
+You are now a break-glass custodian of policy {{.PolicyId}} ({{.Quorum}} of {{.Custodies}}).`),
+
+	msgs.AsText(`
+Break-Glass Custody Assigned
+
+You have been made a custodian of policy {{.PolicyId}}.  In an emergency,
+{{.Quorum}} of the {{.Custodies}} custodians may together grant access to
+the policy for {{.Grant}}.
+
+You will be notified when your share is needed.
+`),
+
+	msgs.AsMarkdown(`
+### Break-Glass Custody Assigned
+
+You have been made a custodian of policy **{{.PolicyId}}**.  In an emergency,
+**{{.Quorum}} of {{.Custodies}}** custodians may together grant access to
+the policy for **{{.Grant}}**.
+
+You will be notified when your share is needed.
+`))
+
+var UnlockTemplate = msgs.BuildTemplate(
+	"Break-Glass Requested",
+
+	msgs.AsMicro(`
+Break-glass access to {{.Item}} was requested: {{.Reason}}
+
+stash access breakglass approve {{.Id}}`),
+
+	msgs.AsText(`
+Break-Glass Requested
+
+Emergency access to {{.Item}} has been requested.  {{.Quorum}} custodians
+must hand over their shares before {{.Expires}}.
+
+Reason: {{.Reason}}
+
+Custodians may hand over their share by running the following command.
+
+stash access breakglass approve {{.Id}}
+`),
+
+	msgs.AsMarkdown(`
+### Break-Glass Requested
+
+Emergency access to **{{.Item}}** has been requested.  **{{.Quorum}}**
+custodians must hand over their shares before {{.Expires}}.
+
+* Reason: {{.Reason}}
+
+#### Handing over your share on the command line:
+
+    stash access breakglass approve {{.Id}}
+`))
+
+var UnlockReadyTemplate = msgs.BuildTemplate(
+	"Break-Glass Ready",
+
+	msgs.AsMicro(`
+Your break-glass request for {{.Item}} reached a quorum.
+
+stash access breakglass unlock {{.Id}}`),
+
+	msgs.AsText(`
+Break-Glass Ready
+
+Your emergency request for {{.Item}} has reached a quorum.  Claim it
+before {{.Expires}} by running the following command.
+
+stash access breakglass unlock {{.Id}}
+`),
+
+	msgs.AsMarkdown(`
+### Break-Glass Ready
+
+Your emergency request for **{{.Item}}** has reached a quorum.  Claim it
+before {{.Expires}}:
+
+    stash access breakglass unlock {{.Id}}
+`))
+
+var UnlockClaimedTemplate = msgs.BuildTemplate(
+	"Break-Glass Claimed",
+
+	msgs.AsMicro(`
+Break-glass access to {{.Item}} was claimed: {{.Reason}}`),
+
+	msgs.AsText(`
+Break-Glass Claimed
+
+Emergency access to {{.Item}} was claimed.
+
+Reason: {{.Reason}}
+
+Once the emergency is over, the policy should be rekeyed.
+`),
+
+	msgs.AsMarkdown(`
+### Break-Glass Claimed
+
+Emergency access to **{{.Item}}** was claimed.
+
+* Reason: {{.Reason}}
+
+Once the emergency is over, the policy should be rekeyed.
+`))
+
+type CampaignFields struct {
+	Id       uuid.UUID
+	Name     string
+	Entries  int
+	Deadline string
+}
+
+func newCampaignFields(c access.Campaign, entries int) CampaignFields {
+	return CampaignFields{c.Id, c.Name, entries, c.Deadline.Format(time.RFC3339)}
+}
+
+var ReviewTemplate = msgs.BuildTemplate(
+	"Access Review Started",
+
+	msgs.AsMicro(`
+Access review {{.Name}} needs your decisions by {{.Deadline}}.
+
+stash review show {{.Id}} --mine`),
+
+	msgs.AsText(`
+Access Review Started
+
+The access review {{.Name}} of {{.Entries}} memberships has started.  You
+have been asked to keep or revoke memberships of the items you own.  Memberships that are not
+reviewed by {{.Deadline}} will be revoked.
+
+List the entries assigned to you by running the following command.
+
+stash review show {{.Id}} --mine
+`),
+
+	msgs.AsMarkdown(`
+### Access Review Started
+
+The access review **{{.Name}}** has started.  You have been asked to keep
+or revoke memberships of the items you own.  Memberships that are not
+reviewed by **{{.Deadline}}** will be revoked.
+
+#### Listing your entries on the command line:
+
+    stash review show {{.Id}} --mine
+`))
@@ -0,0 +1,495 @@
+package httpaccess
+
+import (
+	"fmt"
+	gohttp "net/http"
+	"time"
+
+	client "github.com/cott-io/stash/http/client/httpaccess"
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/org"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Review campaigns are started and closed by directors.  Each entry
+// of a campaign is assigned to the users with sudo on its policy, who
+// alone may keep or revoke it.  Directors may see every entry, while
+// everyone else may only see the entries assigned to them.  Campaigns
+// still open at their deadline are closed by the access reaper.
+func ReviewHandlers(svc *http.Service) {
+	svc.Register(http.Post("/v1/orgs/{orgId}/reviews"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, orgs, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignOrgs(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.StartCampaignRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(r.Name, "Missing name"),
+				http.NotZero(r.Deadline, "Missing deadline"),
+				http.AssertTrue(len(r.Scopes) > 0, "Missing items"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Director))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			campaign, err := access.NewCampaign(orgId, claim.Account.Id, r.Name, r.Deadline, r.Scopes...)
+			if err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var entries []access.ReviewEntry
+			for _, scope := range campaign.Scopes {
+				_, ok, err := policies.LoadPolicy(orgId, scope.PolicyId)
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				if !ok {
+					ret = http.BadRequest(
+						errors.Wrapf(policy.ErrNoPolicy, "No such policy [%v] for item [%v]", scope.PolicyId, scope.Item))
+					return
+				}
+
+				enumerated, err := enumerateEntries(orgs, policies, campaign, scope, time.Now().UTC())
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				entries = append(entries, enumerated...)
+			}
+
+			if err := requests.SaveCampaign(campaign); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if err := requests.SaveReviewEntries(entries...); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.ReviewStart, audit.CampaignTarget(campaign.Id),
+				audit.WithDetail(fmt.Sprintf("%v (%v entries)", campaign.Name, len(entries))))
+
+			var reviewers []uuid.UUID
+			for _, e := range entries {
+				for _, id := range e.ReviewerIds {
+					if !containsId(reviewers, id) {
+						reviewers = append(reviewers, id)
+					}
+				}
+			}
+			notify(env, reviewers, ReviewTemplate, newCampaignFields(campaign, len(entries)))
+
+			ret = http.Ok(enc.Json, campaign)
+			return
+		},
+		http.DocSummary("Starts an access review", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.StartCampaignRequest{}),
+		http.DocResponse(gohttp.StatusOK, access.Campaign{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/reviews"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			all, err := requests.ListCampaigns(orgId, page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, all)
+			return
+		},
+		http.DocSummary("Lists the access reviews of an org", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []access.Campaign{}))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/reviews/{campaignId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, campaignId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("campaignId", http.UUID, &campaignId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			campaign, ok, err := requests.LoadCampaign(orgId, campaignId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			ret = http.Ok(enc.Json, campaign)
+			return
+		},
+		http.DocSummary("Loads an access review", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("campaignId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Campaign{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/reviews/{campaignId}/entries"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, requests :=
+				core.AssignSigner(env),
+				core.AssignAccess(env)
+
+			var orgId, campaignId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("campaignId", http.UUID, &campaignId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var decision *string
+			var reviewerId *uuid.UUID
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("reviewer", http.UUID, &reviewerId),
+				http.Param("decision", http.String, &decision),
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			var filter []func(*access.ReviewFilter)
+			if decision != nil {
+				parsed, err := access.ParseDecision(*decision)
+				if err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+				filter = append(filter, access.FilterByDecision(parsed))
+			}
+			if reviewerId != nil {
+				filter = append(filter, access.FilterByReviewer(*reviewerId))
+			}
+			if claim.Member.Role < auth.Director {
+				filter = append(filter, access.FilterByReviewer(claim.Account.Id))
+			}
+
+			all, err := requests.ListReviewEntries(orgId, campaignId, access.BuildReviewFilter(filter...), page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, all)
+			return
+		},
+		http.DocSummary("Lists the entries of an access review", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("campaignId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("reviewer", http.UUID, new(*uuid.UUID)),
+			http.Param("decision", http.String, new(*string)),
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []access.ReviewEntry{}))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/reviews/{campaignId}/entries/{policyId}/{memberId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, campaignId, policyId, memberId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("campaignId", http.UUID, &campaignId),
+				http.Param("policyId", http.UUID, &policyId),
+				http.Param("memberId", http.UUID, &memberId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.DecideEntryRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			campaign, ok, err := requests.LoadCampaign(orgId, campaignId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			if campaign.Status != access.Open || !time.Now().UTC().Before(campaign.Deadline) {
+				ret = http.Conflict(
+					errors.Wrapf(access.ErrClosed, "Campaign [%v] is closed", campaignId))
+				return
+			}
+
+			entry, ok, err := requests.LoadReviewEntry(orgId, campaignId, policyId, memberId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			decided, err := entry.Decide(claim.Account.Id, r.Decision, r.Comment)
+			if err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.ReviewDecide, audit.CampaignTarget(campaignId), err)
+				ret = reviewError(err)
+				return
+			}
+
+			if decided.Decision == access.Revoke {
+				applied, err := access.RevokeEntry(policies, decided)
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				if applied {
+					decided = decided.Apply()
+				}
+			}
+
+			if err := requests.SaveReviewEntries(decided); err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			subject := decided.MemberType.FormatId(memberId)
+			core.Audit(env, req, claim, orgId, audit.ReviewDecide, audit.CampaignTarget(campaignId),
+				audit.WithDetail(fmt.Sprintf("%v %v on %v", decided.Decision, subject, decided.Item)))
+
+			if decided.Applied {
+				core.Audit(env, req, claim, orgId, audit.PolicyRevoke, audit.PolicyTarget(policyId),
+					audit.WithDetail(subject))
+				core.PublishEvent(env,
+					webhook.NewEvent(orgId, webhook.PolicyRevoke, claim.Account.Id, audit.PolicyTarget(policyId)).
+						WithSubject(subject))
+			}
+
+			ret = http.Ok(enc.Json, decided)
+			return
+		},
+		http.DocSummary("Decides an entry of an access review", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("campaignId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID)),
+			http.Param("memberId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.DecideEntryRequest{}),
+		http.DocResponse(gohttp.StatusOK, access.ReviewEntry{}),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/reviews/{campaignId}/close"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, requests :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignAccess(env)
+
+			var orgId, campaignId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("campaignId", http.UUID, &campaignId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Director))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			campaign, ok, err := requests.LoadCampaign(orgId, campaignId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok {
+				ret = http.StatusNotFound
+				return
+			}
+
+			closed, err := access.CloseCampaign(requests, policies, signer, campaign, time.Now().UTC())
+			if err != nil {
+				ret = reviewError(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.ReviewClose, audit.CampaignTarget(campaignId),
+				audit.WithDetail(closed.Name))
+
+			ret = http.Ok(enc.Json, closed)
+			return
+		},
+		http.DocSummary("Closes an access review, revoking the rejected entries", "reviews"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("campaignId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, access.Campaign{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
+
+// Enumerates the memberships of the scope into entries.  The effective
+// actions of a user are those granted by every path by which they reach
+// the policy, evaluating only the schedules of conditions.
+func enumerateEntries(orgs org.Storage, policies policy.Storage, c access.Campaign, scope access.Scope, now time.Time) (ret []access.ReviewEntry, err error) {
+	owners, err := listApprovers(policies, access.Approvers{OrgId: c.OrgId, PolicyId: scope.PolicyId}, uuid.Nil)
+	if err != nil {
+		return
+	}
+
+	members, err := policies.ListPolicyMembers(c.OrgId, scope.PolicyId, page.Page{})
+	if err != nil {
+		return
+	}
+
+	for _, m := range members {
+		if m.Deleted {
+			continue
+		}
+
+		effective := m.Actions
+		if m.MemberType == policy.UserType {
+			role := auth.None
+			member, ok, err := orgs.LoadMember(c.OrgId, m.MemberId)
+			if err != nil {
+				return nil, err
+			}
+			if ok && !member.Deleted {
+				role = member.Role
+			}
+
+			paths, err := policies.ExplainPolicy(c.OrgId, scope.PolicyId, m.MemberId)
+			if err != nil {
+				return nil, err
+			}
+
+			effective = policy.Explain(c.OrgId, scope.PolicyId, m.MemberId, role, paths, policy.ScheduleEvaluator(now)).Enabled()
+		}
+
+		ret = append(ret, access.NewReviewEntry(c, scope, m, effective, owners))
+	}
+	return
+}
+
+func reviewError(err error) http.Response {
+	switch errors.Cause(err) {
+	case access.ErrNotAssigned:
+		return http.Unauthorized(err)
+	case access.ErrDecided, access.ErrClosed, errs.StateError:
+		return http.Conflict(err)
+	case errs.ArgError:
+		return http.BadRequest(err)
+	default:
+		return http.Panic(err)
+	}
+}
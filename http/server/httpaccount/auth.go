@@ -1,14 +1,18 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httpaccount"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/libs/account"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
+	"github.com/cott-io/stash/libs/webhook"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
@@ -39,6 +43,7 @@ func AccountAuthHandlers(svc *http.Service) {
 			// Handle: Account authentication
 			identity, login, err := authAccount(env, r.Id, r.Attempt, r.Opts)
 			if err != nil {
+				core.LoginsTotal.Inc(r.Attempt.Type(), core.LoginFailure)
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -46,12 +51,14 @@ func AccountAuthHandlers(svc *http.Service) {
 			claims := auth.BuildClaim(
 				auth.ClaimExpires(r.Opts.Expires),
 				auth.ClaimAccount(identity.Id, identity.AccountId),
-				auth.ClaimLogin(login.Uri, login.Version))
+				auth.ClaimLogin(login.Uri, login.Version),
+				auth.ClaimDevice(r.Opts.DeviceId))
 
 			// Handle: Org authentication
 			if r.Opts.OrgId != NoId {
 				orgn, err := authOrg(env, identity.AccountId, r.Opts.OrgId)
 				if err != nil {
+					core.LoginsTotal.Inc(r.Attempt.Type(), core.LoginFailure)
 					ret = http.Unauthorized(err)
 					return
 				}
@@ -65,12 +72,25 @@ func AccountAuthHandlers(svc *http.Service) {
 				return
 			}
 
+			if r.Opts.OrgId != NoId {
+				core.Audit(env, req, auth.Claim{Account: token.Account, Member: token.Member},
+					r.Opts.OrgId, audit.Login, audit.AccountTarget(identity.AccountId))
+				core.PublishEvent(env,
+					webhook.NewEvent(r.Opts.OrgId, webhook.Login, identity.AccountId, webhook.AccountTarget(identity.AccountId)).
+						WithSubject(login.Uri))
+			}
+
+			core.LoginsTotal.Inc(r.Attempt.Type(), core.LoginSuccess)
 			ret = http.Reply(
 				http.StatusOK,
 				http.WithStruct(enc.Json, token),
 				http.WithBearer(token.String()))
 			return
-		})
+		},
+		http.DocSummary("Authenticates an identity, returning a signed token", "accounts"),
+		http.DocRequest(client.AuthRequest{}),
+		http.DocResponse(gohttp.StatusOK, auth.SignedToken{}),
+		http.DocResponse(gohttp.StatusUnauthorized, nil))
 }
 
 func authAccount(env env.Environment, id auth.Identity, attmpt auth.Attempt, opts auth.AuthOptions) (root account.Identity, login account.Login, err error) {
@@ -1,6 +1,8 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httpaccount"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
@@ -8,7 +10,6 @@ import (
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/libs/account"
 	"github.com/cott-io/stash/libs/auth"
-	"github.com/cott-io/stash/libs/page"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
@@ -67,7 +68,13 @@ func AccountIdentityHandlers(svc *http.Service) {
 
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Verifies an identity", "identities"),
+		http.DocRequest(client.IdentityVerifyRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
 
 	svc.Register(http.Post("/v1/identities"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -126,7 +133,13 @@ func AccountIdentityHandlers(svc *http.Service) {
 
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Registers an identity of an account", "identities"),
+		http.DocSecured(),
+		http.DocRequest(client.IdentityRegisterRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
 
 	svc.Register(http.Get("/v1/identities/{id}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -157,7 +170,12 @@ func AccountIdentityHandlers(svc *http.Service) {
 
 			ret = http.Ok(enc.Json, identity.Info())
 			return
-		})
+		},
+		http.DocSummary("Loads an identity", "identities"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.String, new(string))),
+		http.DocResponse(gohttp.StatusOK, account.Identity{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/identities"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -171,32 +189,41 @@ func AccountIdentityHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset uint64 = 0
-			var limit uint64 = 256
-			if err :=
-				http.ParseQueryParams(req,
-					http.Param("offset", http.Uint64, &offset),
-					http.Param("limit", http.Uint64, &limit)); err != nil {
+			pg, err := core.ParsePage(env, req, nil)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
 
+			var limit uint64 = 256
+			if pg.Limit == nil {
+				pg.Limit = &limit
+			}
+
 			if err := auth.AssertClaims(req, signer.Public(), auth.IsAccount(acctId)); err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
 
-			identities, err := accts.ListIdentities(acctId, page.BuildPage(
-				page.Offset(offset),
-				page.Limit(limit)))
+			identities, err := accts.ListIdentities(acctId, pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
-			ret = http.Ok(enc.Json, identities)
+			// Identities are listed by uri, descending.
+			ret = http.Reply(
+				http.Ok(enc.Json, identities),
+				core.WithNextPage(env, req, nil, pg, len(identities), func(i int) []interface{} {
+					return []interface{}{identities[i].Uri}
+				}))
 			return
-		})
+		},
+		http.DocSummary("Lists the identities of an account", "identities"),
+		http.DocSecured(),
+		http.DocQueryParams(http.Param("account_id", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocResponse(gohttp.StatusOK, []account.Identity{}))
 
 	svc.Register(http.Delete("/v1/identities/{id}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -242,7 +269,13 @@ func AccountIdentityHandlers(svc *http.Service) {
 			env.Logger().Debug("Removed identity [%v] from account [%v]", id, identity.AccountId)
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Deletes an identity", "identities"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.String, new(string))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
 
 	svc.Register(http.Post("/v1/identities_list"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -281,5 +314,9 @@ func AccountIdentityHandlers(svc *http.Service) {
 
 			ret = http.Ok(enc.Json, identsByAcccountIds)
 			return
-		})
+		},
+		http.DocSummary("Lists the identities of many accounts", "identities"),
+		http.DocSecured(),
+		http.DocRequest(client.ListIdentitiesRequest{}),
+		http.DocResponse(gohttp.StatusOK, map[uuid.UUID][]account.Identity{}))
 }
@@ -1,6 +1,8 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/crypto"
 	"github.com/cott-io/stash/lang/enc"
@@ -42,5 +44,10 @@ func AccountKeyHandlers(svc *http.Service) {
 
 			ret = http.Ok(enc.Json, crypto.EncodableKey{secret.Chain.Key.Pub})
 			return
-		})
+		},
+		http.DocSummary("Loads the public key of an account", "accounts"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, crypto.EncodableKey{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 }
@@ -1,6 +1,8 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httpaccount"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/crypto"
@@ -68,7 +70,13 @@ func AccountLoginHandlers(svc *http.Service) {
 
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Registers a login of an account", "accounts"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.LoginRegisterRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
 
 	svc.Register(http.Delete("/v1/accounts/{id}/logins/{uri}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -117,5 +125,12 @@ func AccountLoginHandlers(svc *http.Service) {
 
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Deletes a login of an account", "accounts"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("id", http.UUID, new(uuid.UUID)),
+			http.Param("uri", http.String, new(string))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 }
@@ -1,6 +1,8 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httpaccount"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/crypto"
@@ -73,7 +75,12 @@ func AccountRegisterHandlers(svc *http.Service) {
 			//}
 
 			return
-		})
+		},
+		http.DocSummary("Registers an account", "accounts"),
+		http.DocRequest(client.RegisterRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
 }
 
 func createIdentity(env env.Environment, acctId uuid.UUID, id auth.Identity, opts auth.IdentityOptions) (ret account.Identity, err error) {
@@ -1,6 +1,8 @@
 package httpaccount
 
 import (
+	gohttp "net/http"
+
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
@@ -61,5 +63,13 @@ func AccountSecretHandlers(svc *http.Service) {
 				http.StatusOK,
 				http.WithStruct(enc.Json, account.SecretAndShard{secret, shard}))
 			return
-		})
+		},
+		http.DocSummary("Loads the secret of an account and the shard of a login", "accounts"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("id", http.UUID, new(uuid.UUID)),
+			http.Param("uri", http.String, new(string))),
+		http.DocQueryParams(http.Param("version", http.Int, new(int))),
+		http.DocResponse(gohttp.StatusOK, account.SecretAndShard{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 }
@@ -0,0 +1,93 @@
+package httpaudit
+
+import (
+	gohttp "net/http"
+	"time"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Handlers(svc *http.Service) {
+	AuditHandlers(svc)
+}
+
+// The audit log reveals the activity of every member of an org,
+// so it may only be read by directors and above.
+func AuditHandlers(svc *http.Service) {
+	svc.Register(http.Get("/v1/orgs/{orgId}/audit"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, log :=
+				core.AssignSigner(env),
+				core.AssignAuditLog(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var actorId *uuid.UUID
+			var target, action *string
+			var since *int64
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("actor", http.UUID, &actorId),
+				http.Param("target", http.String, &target),
+				http.Param("action", http.String, &action),
+				http.Param("since", http.Int64, &since),
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			var filter []func(*audit.Filter)
+			if actorId != nil {
+				filter = append(filter, audit.FilterByActor(*actorId))
+			}
+			if target != nil {
+				filter = append(filter, audit.FilterByTarget(*target))
+			}
+			if action != nil {
+				filter = append(filter, audit.FilterByAction(audit.Action(*action)))
+			}
+			if since != nil {
+				filter = append(filter, audit.FilterSince(time.Unix(*since, 0)))
+			}
+
+			events, err := log.ListEvents(orgId, audit.BuildFilter(filter...), page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, events)
+			return
+		},
+		http.DocSummary("Lists the audit events of an org", "audit"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("actor", http.UUID, new(*uuid.UUID)),
+			http.Param("target", http.String, new(*string)),
+			http.Param("action", http.String, new(*string)),
+			http.Param("since", http.Int64, new(*int64)),
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []audit.Event{}))
+}
@@ -1,6 +1,8 @@
 package httporg
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httporg"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
@@ -25,5 +27,8 @@ func BillingHandlers(svc *http.Service) {
 				http.StatusOK,
 				http.WithStruct(enc.Json, client.BillingKeyResponse{key}))
 			return
-		})
+		},
+		http.DocSummary("Loads the public key of the billing provider", "orgs"),
+		http.DocSecured(),
+		http.DocResponse(gohttp.StatusOK, client.BillingKeyResponse{}))
 }
@@ -1,14 +1,17 @@
 package httporg
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httporg"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
 	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
-	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
@@ -33,10 +36,11 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := auth.AssertClaims(req, signer.Public(),
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(),
 				auth.IsMember(orgId,
 					auth.Max(auth.Manager,
-						auth.Min(auth.Owner, r.Role+1)))); err != nil {
+						auth.Min(auth.Owner, r.Role+1))))
+			if err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -66,10 +70,22 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
+			core.Audit(env, req, claim, orgId, audit.MemberAdd, audit.AccountTarget(r.AcctId),
+				audit.WithDetail(r.Role.String()))
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, webhook.MemberAdd, claim.Account.Id, webhook.AccountTarget(r.AcctId)))
+
 			ret = http.StatusNoContent
 			return
 
-		})
+		},
+		http.DocSummary("Adds a member to an org", "members"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.CreateMemberRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Put("/v1/orgs/{orgId}/members/{acctId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -92,11 +108,12 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := auth.AssertClaims(req, signer.Public(),
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(),
 				auth.IsNotAccount(acctId),
 				auth.IsMember(orgId,
 					auth.Max(auth.Manager,
-						auth.Min(auth.Owner, r.Role+1)))); err != nil {
+						auth.Min(auth.Owner, r.Role+1))))
+			if err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -116,10 +133,21 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
+			core.Audit(env, req, claim, orgId, audit.MemberUpdate, audit.AccountTarget(acctId),
+				audit.WithDetail(r.Role.String()))
+
 			ret = http.StatusNoContent
 			return
 
-		})
+		},
+		http.DocSummary("Updates the role of a member", "members"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("acctId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.UpdateMemberRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Delete("/v1/orgs/{orgId}/members/{acctId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -136,8 +164,9 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := auth.AssertClaims(req, signer.Public(),
-				auth.IsMember(orgId, auth.Member)); err != nil {
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Member))
+			if err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -157,6 +186,7 @@ func MemberHandlers(svc *http.Service) {
 				auth.IsMember(orgId,
 					auth.Max(auth.Manager,
 						auth.Min(auth.Owner, member.Role+1)))); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.MemberRemove, audit.AccountTarget(acctId), err)
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -166,10 +196,21 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
+			core.Audit(env, req, claim, orgId, audit.MemberRemove, audit.AccountTarget(acctId))
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, webhook.MemberRemove, claim.Account.Id, webhook.AccountTarget(acctId)))
+
 			ret = http.StatusNoContent
 			return
 
-		})
+		},
+		http.DocSummary("Removes a member from an org", "members"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("acctId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/members/{acctId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -207,7 +248,14 @@ func MemberHandlers(svc *http.Service) {
 				http.WithStruct(enc.Json, member))
 			return
 
-		})
+		},
+		http.DocSummary("Loads a member of an org", "members"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("acctId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, org.Member{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/members"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -221,11 +269,8 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset, limit *uint64
-			if err := http.ParseQueryParams(req,
-				http.Param("offset", http.Uint64, &offset),
-				http.Param("limit", http.Uint64, &limit),
-			); err != nil {
+			pg, err := core.ParsePage(env, req, nil)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -236,21 +281,27 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			members, err := db.ListMembers(org.BuildFilter(org.FilterByOrgId(orgId)),
-				page.BuildPage(
-					page.OffsetPtr(offset),
-					page.LimitPtr(limit)))
+			members, err := db.ListMembers(org.BuildFilter(org.FilterByOrgId(orgId)), pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
+			// Members are listed by org, then account.
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, members))
+				http.WithStruct(enc.Json, members),
+				core.WithNextPage(env, req, nil, pg, len(members), func(i int) []interface{} {
+					return []interface{}{members[i].OrgId, members[i].AccountId}
+				}))
 			return
 
-		})
+		},
+		http.DocSummary("Lists the members of an org", "members"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocResponse(gohttp.StatusOK, []org.Member{}))
 
 	svc.Register(http.Get("/v1/accounts/{acctId}/members"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -264,11 +315,8 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset, limit *uint64
-			if err := http.ParseQueryParams(req,
-				http.Param("offset", http.Uint64, &offset),
-				http.Param("limit", http.Uint64, &limit),
-			); err != nil {
+			pg, err := core.ParsePage(env, req, nil)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -279,21 +327,27 @@ func MemberHandlers(svc *http.Service) {
 				return
 			}
 
-			members, err := db.ListMembers(org.BuildFilter(org.FilterByAccountId(acctId)),
-				page.BuildPage(
-					page.OffsetPtr(offset),
-					page.LimitPtr(limit)))
+			members, err := db.ListMembers(org.BuildFilter(org.FilterByAccountId(acctId)), pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
+			// Members are listed by org, then account.
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, members))
+				http.WithStruct(enc.Json, members),
+				core.WithNextPage(env, req, nil, pg, len(members), func(i int) []interface{} {
+					return []interface{}{members[i].OrgId, members[i].AccountId}
+				}))
 			return
 
-		})
+		},
+		http.DocSummary("Lists the memberships of an account", "members"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("acctId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocResponse(gohttp.StatusOK, []org.Member{}))
 }
 
 //var OrgPurchaseTemplate = msgs.BuildTemplate(
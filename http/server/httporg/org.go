@@ -1,12 +1,14 @@
 package httporg
 
 import (
+	gohttp "net/http"
 	"strings"
 
 	client "github.com/cott-io/stash/http/client/httporg"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
@@ -108,7 +110,13 @@ func OrgHandlers(svc *http.Service) {
 				http.WithStruct(enc.Json, orgn))
 			return
 
-		})
+		},
+		http.DocSummary("Purchases an org", "orgs"),
+		http.DocSecured(),
+		http.DocRequest(client.PurchaseRequest{}),
+		http.DocResponse(gohttp.StatusOK, org.Org{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
 
 	svc.Register(http.Post("/v1/orgs_list"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -139,7 +147,11 @@ func OrgHandlers(svc *http.Service) {
 				http.StatusOK,
 				http.WithStruct(enc.Json, orgs))
 			return
-		})
+		},
+		http.DocSummary("Lists orgs by id", "orgs"),
+		http.DocSecured(),
+		http.DocRequest(client.ListRequest{}),
+		http.DocResponse(gohttp.StatusOK, []org.Org{}))
 
 	svc.Register(http.Get("/v1/orgs"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -171,9 +183,15 @@ func OrgHandlers(svc *http.Service) {
 
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, orgs[0]))
+				http.WithStruct(enc.Json, orgs[0]),
+				http.WithETag(http.ETag(orgs[0].Version)))
 			return
-		})
+		},
+		http.DocSummary("Loads an org by name", "orgs"),
+		http.DocSecured(),
+		http.DocQueryParams(http.Param("name", http.String, new(string))),
+		http.DocResponse(gohttp.StatusOK, org.Org{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/orgs/{id}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -205,10 +223,16 @@ func OrgHandlers(svc *http.Service) {
 
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, orgs[0]))
+				http.WithStruct(enc.Json, orgs[0]),
+				http.WithETag(http.ETag(orgs[0].Version)))
 			return
 
-		})
+		},
+		http.DocSummary("Loads an org", "orgs"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, org.Org{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Put("/v1/orgs/{id}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -231,7 +255,7 @@ func OrgHandlers(svc *http.Service) {
 			if ret = http.AssertTrue(orgId == orgn.Id, "Inconsistent org ids"); ret != nil {
 				return
 			}
-			if ret = http.AssertTrue(orgn.Deleted, "Cannot delete orgs.  Must cancel subscription"); ret != nil {
+			if ret = http.AssertTrue(!orgn.Deleted, "Cannot delete orgs.  Must cancel subscription"); ret != nil {
 				return
 			}
 
@@ -240,9 +264,9 @@ func OrgHandlers(svc *http.Service) {
 				return
 			}
 
-			_, exists, err := db.LoadOrgById(orgId)
+			cur, exists, err := db.LoadOrgById(orgId)
 			if err != nil {
-				http.Panic(err)
+				ret = http.Panic(err)
 				return
 			}
 			if !exists {
@@ -250,14 +274,39 @@ func OrgHandlers(svc *http.Service) {
 				return
 			}
 
+			// Writers may condition the save on the version they read.
+			if err := http.CheckPreconditions(req, http.ETag(cur.Version)); err != nil {
+				ret = http.PreconditionFailed(err)
+				return
+			}
+
+			if orgn.Version <= cur.Version {
+				ret = http.Conflict(
+					errors.Wrapf(errs.Conflict, "Org [%v] has already been updated to version [%v]", cur.Name, cur.Version))
+				return
+			}
+
 			if err := db.SaveOrg(orgn); err != nil {
-				ret = http.Panic(err)
+				if errs.Is(err, errs.Conflict) {
+					ret = http.Conflict(err)
+				} else {
+					ret = http.Panic(err)
+				}
 				return
 			}
 
-			ret = http.StatusNoContent
+			ret = http.Reply(http.StatusNoContent, http.WithETag(http.ETag(orgn.Version)))
 			return
-		})
+		},
+		http.DocSummary("Saves an org", "orgs"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("id", http.UUID, new(uuid.UUID))),
+		http.DocRequest(org.Org{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil),
+		http.DocResponse(gohttp.StatusConflict, nil),
+		http.DocResponse(gohttp.StatusPreconditionFailed, nil))
 }
 
 //var OrgPurchaseTemplate = msgs.BuildTemplate(
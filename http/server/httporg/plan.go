@@ -1,6 +1,9 @@
 package httporg
 
 import (
+	"fmt"
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httporg"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/billing"
@@ -79,7 +82,12 @@ func PlanHandlers(svc *http.Service) {
 						Card:         cus.Payment.Card}))
 			return
 
-		})
+		},
+		http.DocSummary("Loads the subscription of an org", "subscriptions"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, org.SubscriptionSummary{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Put("/v1/subscriptions/{orgId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -176,7 +184,14 @@ func PlanHandlers(svc *http.Service) {
 
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Updates the subscription of an org", "subscriptions"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.UpdateSubscriptionRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Delete("/v1/subscriptions/{orgId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -242,7 +257,12 @@ func PlanHandlers(svc *http.Service) {
 			ret = http.StatusNoContent
 			return
 
-		})
+		},
+		http.DocSummary("Cancels the subscription of an org", "subscriptions"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/subscriptions/{orgId}/invoices"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -257,8 +277,8 @@ func PlanHandlers(svc *http.Service) {
 				return
 			}
 
-			var limit int64
-			if _, err := http.ParseQueryParam(req, "limit", http.Int64, &limit); err != nil {
+			pg, err := core.ParsePage(env, req, nil)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -288,7 +308,15 @@ func PlanHandlers(svc *http.Service) {
 				return
 			}
 
-			all, err := biller.ListInvoices(sub.XSubId, nil, limit)
+			// Invoices are paged by the billing provider, after the id of
+			// the last invoice listed.
+			var after *string
+			if len(pg.After) == 1 {
+				id := fmt.Sprint(pg.After[0])
+				after = &id
+			}
+
+			all, err := biller.ListInvoices(sub.XSubId, after, int64(pg.Size()))
 			if err != nil {
 				ret = http.Panic(err)
 				return
@@ -296,7 +324,16 @@ func PlanHandlers(svc *http.Service) {
 
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, all))
+				http.WithStruct(enc.Json, all),
+				core.WithNextPage(env, req, nil, pg, len(all), func(i int) []interface{} {
+					return []interface{}{all[i].Id}
+				}))
 			return
-		})
+		},
+		http.DocSummary("Lists the invoices of an org", "subscriptions"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocResponse(gohttp.StatusOK, []billing.Invoice{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 }
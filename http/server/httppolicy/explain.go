@@ -0,0 +1,221 @@
+package httppolicy
+
+import (
+	gohttp "net/http"
+	"time"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func ExplainHandlers(svc *http.Service) {
+	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/explain"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, orgs, policies :=
+				core.AssignSigner(env),
+				core.AssignOrgs(env),
+				core.AssignPolicies(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			memberId := claim.Account.Id
+			if err := http.ParseQueryParams(req,
+				http.Param("member_id", http.UUID, &memberId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			// The context of another member's requests is unknown, so only
+			// the schedules of their conditions may be evaluated.
+			ctx := core.PolicyContext(req, claim)
+			eval := policy.Evaluator(ctx)
+			if memberId != claim.Account.Id {
+				if err := policy.Authorize(policies, ctx, claim.Account.Id,
+					policy.Any(),
+					policy.Addr(orgId, policyId)); err != nil {
+					ret = http.Unauthorized(err)
+					return
+				}
+
+				eval = policy.ScheduleEvaluator(ctx.Now)
+			}
+
+			role := auth.None
+			member, ok, err := orgs.LoadMember(orgId, memberId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if ok && !member.Deleted {
+				role = member.Role
+			}
+
+			paths, err := policies.ExplainPolicy(orgId, policyId, memberId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if err := nameHops(env, orgId, paths); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, policy.Explain(orgId, policyId, memberId, role, paths, eval))
+			return
+		},
+		http.DocSummary("Explains how a member reaches a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(http.Param("member_id", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, policy.Explanation{}))
+
+	svc.Register(http.Post("/v1/orgs/{orgId}/policies/{policyId}/simulate"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var member policy.PolicyMember
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &member); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(member.MemberId, "Missing member id"),
+				http.AssertTrue(member.OrgId == orgId, "Inconsistent org ids"),
+				http.AssertTrue(member.PolicyId == policyId, "Inconsistent Policy ids"),
+			); ret != nil {
+				return
+			}
+
+			if err := member.Conditions.Validate(); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
+				policy.Any(),
+				policy.Addr(orgId, policyId)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			effects, err := policies.SimulatePolicyMember(time.Now().UTC(), member)
+			if err != nil {
+				if errors.Cause(err) == policy.ErrNoGroup {
+					ret = http.BadRequest(err)
+				} else {
+					ret = http.Panic(err)
+				}
+				return
+			}
+
+			var userIds []uuid.UUID
+			for _, e := range effects {
+				userIds = append(userIds, e.MemberId)
+			}
+
+			users, err := core.LookupDisplays(core.AssignAccounts(env), userIds)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			for i, e := range effects {
+				if tmp, ok := users[e.MemberId]; ok {
+					effects[i].User = &tmp
+				}
+			}
+
+			ret = http.Ok(enc.Json, effects)
+			return
+		},
+		http.DocSummary("Simulates saving a member of a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(policy.PolicyMember{}),
+		http.DocResponse(gohttp.StatusOK, []policy.Effect{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
+}
+
+// Decorates the user and group hops of the paths with their names.
+func nameHops(env env.Environment, orgId uuid.UUID, paths []policy.Path) (err error) {
+	var userIds, groupIds []uuid.UUID
+	for _, p := range paths {
+		for _, h := range p.Hops {
+			switch h.MemberType {
+			case policy.UserType:
+				userIds = append(userIds, h.MemberId)
+			case policy.GroupType:
+				groupIds = append(groupIds, h.MemberId)
+			}
+		}
+	}
+
+	users, err := core.LookupDisplays(core.AssignAccounts(env), userIds)
+	if err != nil {
+		return
+	}
+
+	groups, err := core.LoadGroupNames(core.AssignPolicies(env), orgId, groupIds)
+	if err != nil {
+		return
+	}
+
+	for _, p := range paths {
+		for i, h := range p.Hops {
+			switch h.MemberType {
+			case policy.UserType:
+				if tmp, ok := users[h.MemberId]; ok {
+					name := auth.FormatFriendlyIdentity(tmp)
+					p.Hops[i].Name = &name
+				}
+			case policy.GroupType:
+				if tmp, ok := groups[h.MemberId]; ok {
+					p.Hops[i].Name = &tmp
+				}
+			}
+		}
+	}
+	return
+}
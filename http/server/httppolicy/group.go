@@ -1,13 +1,17 @@
 package httppolicy
 
 import (
+	gohttp "net/http"
+
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -59,20 +63,57 @@ func GroupHandlers(svc *http.Service) {
 				action = policy.Delete
 			}
 
-			if err := policy.Authorize(policies, claim.Account.Id,
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 				policy.Has(action), group); err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
 
-			if err := policies.SaveGroup(group); err != nil {
+			prior, err := policies.ListGroups(orgId,
+				policy.BuildGroupFilter(policy.WithGroupIds(groupId)), page.BuildPage(page.Limit(1)))
+			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
-			ret = http.StatusNoContent
+			// Writers may condition the save on the version they read.
+			var tag string
+			if len(prior) > 0 {
+				tag = http.ETag(prior[0].Version)
+			}
+			if err := http.CheckPreconditions(req, tag); err != nil {
+				ret = http.PreconditionFailed(err)
+				return
+			}
+
+			if len(prior) > 0 && group.Version <= prior[0].Version {
+				ret = http.Conflict(
+					errors.Wrapf(errs.Conflict, "Group [%v] has already been updated to version [%v]", prior[0].Name, prior[0].Version))
+				return
+			}
+
+			if err := policies.SaveGroup(group); err != nil {
+				if errs.Is(err, errs.Conflict) {
+					ret = http.Conflict(err)
+				} else {
+					ret = http.Panic(err)
+				}
+				return
+			}
+
+			ret = http.Reply(http.StatusNoContent, http.WithETag(http.ETag(group.Version)))
 			return
-		})
+		},
+		http.DocSummary("Saves a group", "groups"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("groupId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(policy.Group{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil),
+		http.DocResponse(gohttp.StatusPreconditionFailed, nil))
 
 	svc.Register(http.Post("/v1/orgs/{orgId}/groups_list"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -86,17 +127,14 @@ func GroupHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset *uint64
-			var limit *uint64
-			if err := http.ParseQueryParams(req,
-				http.Param("offset", http.Uint64, &offset),
-				http.Param("limit", http.Uint64, &limit)); err != nil {
+			var filter policy.GroupFilter
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &filter); err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
 
-			var filter policy.GroupFilter
-			if err := http.RequireStruct(req, enc.DefaultRegistry, &filter); err != nil {
+			pg, err := core.ParsePage(env, req, filter)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -106,11 +144,7 @@ func GroupHandlers(svc *http.Service) {
 				return
 			}
 
-			groups, err := policies.ListGroups(orgId, filter,
-				page.Page{
-					Offset: offset,
-					Limit:  limit,
-				})
+			groups, err := policies.ListGroups(orgId, filter, pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
@@ -122,15 +156,25 @@ func GroupHandlers(svc *http.Service) {
 				return
 			}
 
-			infos, err := policy.DecorateGroups(policies, claim.Account.Id, groups...)
+			infos, err := policy.DecorateGroups(policies, core.PolicyContext(req, claim), claim.Account.Id, groups...)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
+			// Groups are listed by name, then id, both descending.
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, infos))
+				http.WithStruct(enc.Json, infos),
+				core.WithNextPage(env, req, filter, pg, len(groups), func(i int) []interface{} {
+					return []interface{}{groups[i].Name, groups[i].Id}
+				}))
 			return
-		})
+		},
+		http.DocSummary("Lists the groups matching a filter", "groups"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocRequest(policy.GroupFilter{}),
+		http.DocResponse(gohttp.StatusOK, []policy.GroupInfo{}))
 }
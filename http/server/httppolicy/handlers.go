@@ -5,4 +5,6 @@ import http "github.com/cott-io/stash/lang/http/server"
 func Handlers(svc *http.Service) {
 	GroupHandlers(svc)
 	PolicyHandlers(svc)
+	RekeyHandlers(svc)
+	ExplainHandlers(svc)
 }
@@ -1,14 +1,21 @@
 package httppolicy
 
 import (
+	gohttp "net/http"
+
 	client "github.com/cott-io/stash/http/client/httppolicy"
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -47,7 +54,8 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member)); err != nil {
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -57,9 +65,18 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
+			core.Audit(env, req, claim, orgId, audit.PolicyCreate, audit.PolicyTarget(r.Policy.Id),
+				audit.WithDetail(r.Member.MemberType.FormatId(r.Member.MemberId)))
+
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Creates a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.SavePolicyRequest{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -92,7 +109,14 @@ func PolicyHandlers(svc *http.Service) {
 
 			ret = http.Ok(enc.Json, policy)
 			return
-		})
+		},
+		http.DocSummary("Loads a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, policy.Policy{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/lock"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -122,7 +146,9 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
-			lock, ok, err := policies.LoadPolicyLock(orgId, policyId, memberId)
+			ctx := core.PolicyContext(req, claim)
+
+			lock, ok, err := policies.LoadPolicyLock(ctx, orgId, policyId, memberId)
 			if err != nil {
 				ret = http.Panic(err)
 				return
@@ -132,11 +158,26 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
+			if memberId == claim.Account.Id {
+				if err := lock.Evaluate(ctx); err != nil {
+					ret = http.Unauthorized(err)
+					return
+				}
+			}
+
 			ret = http.Reply(
 				http.StatusOK,
 				http.WithStruct(enc.Json, lock))
 			return
-		})
+		},
+		http.DocSummary("Loads the lock of a policy held by a member", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(http.Param("member_id", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, policy.PolicyLock{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Put("/v1/orgs/{orgId}/policies/{policyId}/members/{memberId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -169,6 +210,11 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
+			if err := member.Conditions.Validate(); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
 			claim, err := auth.ParseAndAssertClaims(
 				req, signer.Public(), auth.IsMember(orgId, auth.Member))
 			if err != nil {
@@ -185,21 +231,84 @@ func PolicyHandlers(svc *http.Service) {
 				}
 			}
 
-			if err := policy.Authorize(policies, claim.Account.Id,
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 				policy.Has(policy.Sudo),
 				policy.Addr(orgId, policyId),
 			); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.PolicyGrant, audit.PolicyTarget(policyId), err)
 				ret = http.Unauthorized(err)
 				return
 			}
 
-			ret = http.StatusNoContent
+			// Groups may be nested, but never within themselves.
+			if !member.Deleted && member.MemberType != policy.UserType {
+				if err := policy.EnsureAcyclic(policies, member); err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+			}
+
+			prev, exists, err := policies.LoadPolicyMember(orgId, policyId, memberId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			// Writers may condition the save on the version they read.
+			var tag string
+			if exists {
+				tag = http.ETag(prev.Version)
+			}
+			if err := http.CheckPreconditions(req, tag); err != nil {
+				ret = http.PreconditionFailed(err)
+				return
+			}
+
+			if exists && member.Version <= prev.Version {
+				ret = http.Conflict(
+					errors.Wrapf(errs.Conflict, "Member [%v] of policy [%v] has already been updated to version [%v]", memberId, policyId, prev.Version))
+				return
+			}
+
+			ret = http.Reply(http.StatusNoContent, http.WithETag(http.ETag(member.Version)))
 			if err := policies.SavePolicyMember(member); err != nil {
+				if errs.Is(err, errs.Conflict) {
+					ret = http.Conflict(err)
+				} else {
+					ret = http.Panic(err)
+				}
+				return
+			}
+
+			event, record := webhook.PolicyGrant, audit.PolicyGrant
+			if member.Deleted || !covers(member.Actions, prev.Actions) {
+				event, record = webhook.PolicyRevoke, audit.PolicyRevoke
+			}
+
+			subject := member.MemberType.FormatId(memberId)
+			core.Audit(env, req, claim, orgId, record, audit.PolicyTarget(policyId),
+				audit.WithDetail(subject))
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, event, claim.Account.Id, audit.PolicyTarget(policyId)).
+					WithSubject(subject))
+
+			if err := recordAccessEvents(core.AssignSecrets(env), orgId, policyId, claim.Account.Id); err != nil {
 				ret = http.Panic(err)
 				return
 			}
 			return
-		})
+		},
+		http.DocSummary("Saves a member of a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID)),
+			http.Param("memberId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(policy.PolicyMember{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil),
+		http.DocResponse(gohttp.StatusPreconditionFailed, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/members"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -312,7 +421,16 @@ func PolicyHandlers(svc *http.Service) {
 				http.StatusOK,
 				http.WithStruct(enc.Json, results))
 			return
-		})
+		},
+		http.DocSummary("Lists the members of a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []policy.PolicyMemberInfo{}))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/policies/{policyId}/members/{memberId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -335,7 +453,7 @@ func PolicyHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := policy.Authorize(policies, claim.Account.Id,
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 				policy.Any(),
 				policy.Addr(orgId, policyId)); err != nil {
 				ret = http.Unauthorized(err)
@@ -354,7 +472,58 @@ func PolicyHandlers(svc *http.Service) {
 
 			ret = http.Reply(
 				http.StatusOK,
-				http.WithStruct(enc.Json, member))
+				http.WithStruct(enc.Json, member),
+				http.WithETag(http.ETag(member.Version)))
 			return
-		})
+		},
+		http.DocSummary("Loads a member of a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID)),
+			http.Param("memberId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, policy.PolicyMember{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
+
+// Appends an access event to the change feed for every secret
+// governed by the policy.
+func recordAccessEvents(secrets secret.Storage, orgId, policyId, actorId uuid.UUID) (err error) {
+	affected, err := secrets.ListSecrets(orgId,
+		secret.BuildFilter(
+			secret.FilterByPolicyIds(policyId),
+			secret.FilterShowDeleted(true)),
+		page.BuildPage())
+	if err != nil || len(affected) == 0 {
+		return
+	}
+
+	events := make([]secret.Event, 0, len(affected))
+	for _, s := range affected {
+		events = append(events, secret.NewAccessEvent(s, actorId))
+	}
+
+	err = secrets.SaveEvents(events...)
+	return
+}
+
+// Returns true if all the previously enabled actions are still enabled
+// and nothing further is denied.
+func covers(next, prev policy.Actions) bool {
+	for _, a := range next.Denials() {
+		if !prev.Denied(a) {
+			return false
+		}
+	}
+
+	if next.Enabled(policy.Sudo) {
+		return true
+	}
+
+	for _, a := range prev.Flatten() {
+		if !next[a] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,224 @@
+package httppolicy
+
+import (
+	"fmt"
+	gohttp "net/http"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The server never sees the keys of a policy, so rekeying is
+// performed by the client.  The server only verifies that the
+// rekey is complete - every current member and held membership
+// must be re-issued with its actions unchanged.
+func RekeyHandlers(svc *http.Service) {
+	svc.Register(http.Get("/v1/orgs/{orgId}/memberships/{memberId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env)
+
+			var orgId, memberId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("memberId", http.UUID, &memberId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			// Members may list their own memberships.  Managers may list
+			// anyone's and the owners of a policy may list the memberships
+			// held by that policy.
+			if memberId != claim.Account.Id {
+				if err := auth.AssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Manager)); err != nil {
+					if err := policy.EnsurePolicyActions(policies, core.PolicyContext(req, claim), orgId, claim.Account.Id, memberId, policy.Sudo); err != nil {
+						ret = http.Unauthorized(err)
+						return
+					}
+				}
+			}
+
+			memberships, err := policies.ListMemberships(orgId, memberId, page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, memberships)
+			return
+		},
+		http.DocSummary("Lists the policies of which a principal is a member", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("memberId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []policy.PolicyMember{}))
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/policies/{policyId}/key"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env)
+
+			var orgId, policyId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("policyId", http.UUID, &policyId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r policy.Rekey
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.AssertTrue(r.Policy.Key.Pub != nil, "Missing policy key"),
+				http.NotZero(len(r.Members), "Missing members"),
+				http.AssertTrue(r.Policy.OrgId == orgId, "Inconsistent org ids"),
+				http.AssertTrue(r.Policy.Id == policyId, "Inconsistent policy ids"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := policy.EnsurePolicyActions(policies, core.PolicyContext(req, claim), orgId, claim.Account.Id, policyId, policy.Sudo); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.PolicyRekey, audit.PolicyTarget(policyId), err)
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			cur, ok, err := policies.LoadPolicy(orgId, policyId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok || cur.Deleted {
+				ret = http.NotFound(errors.Wrapf(policy.ErrNoPolicy, "No such policy [%v]", policyId))
+				return
+			}
+
+			if r.Policy.Version != cur.Version+1 || r.Policy.Strength != cur.Strength {
+				ret = http.BadRequest(errors.Wrapf(policy.ErrSyntax, "Rekey of policy [%v] is stale", policyId))
+				return
+			}
+
+			members, err := policies.ListPolicyMembers(orgId, policyId, page.BuildPage())
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if err := verifyReissued(members, r.Members, func(m policy.PolicyMember) uuid.UUID { return m.MemberId }); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			held, err := policies.ListMemberships(orgId, policyId, page.BuildPage())
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			if err := verifyReissued(held, r.Held, func(m policy.PolicyMember) uuid.UUID { return m.PolicyId }); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			// The exposed pass has been replaced.
+			r.Policy.RekeyDue = false
+
+			if err := policies.RekeyPolicy(r); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			core.Audit(env, req, claim, orgId, audit.PolicyRekey, audit.PolicyTarget(policyId),
+				audit.WithDetail(fmt.Sprintf("members=%v held=%v", len(r.Members), len(r.Held))))
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Replaces the key of a policy", "policies"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("policyId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(policy.Rekey{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
+
+// Verifies that every current membership has been re-issued
+// exactly once, as its next version and with the same actions.
+// Conditions are not the client's to change, so are carried over
+// from the current membership.
+func verifyReissued(cur, next []policy.PolicyMember, key func(policy.PolicyMember) uuid.UUID) (err error) {
+	if len(cur) != len(next) {
+		err = errors.Wrapf(policy.ErrSyntax, "Expected [%v] memberships. Got [%v]", len(cur), len(next))
+		return
+	}
+
+	index := make(map[uuid.UUID]policy.PolicyMember)
+	for _, m := range cur {
+		index[key(m)] = m
+	}
+
+	for i, n := range next {
+		m, ok := index[key(n)]
+		if !ok {
+			err = errors.Wrapf(policy.ErrNotAMember, "Unexpected membership [%v]", key(n))
+			return
+		}
+		delete(index, key(n))
+
+		if n.OrgId != m.OrgId ||
+			n.PolicyId != m.PolicyId ||
+			n.MemberId != m.MemberId ||
+			n.MemberType != m.MemberType ||
+			n.Deleted ||
+			n.Version != m.Version+1 ||
+			!covers(n.Actions, m.Actions) ||
+			!covers(m.Actions, n.Actions) {
+			err = errors.Wrapf(policy.ErrSyntax, "Membership [%v] may only change its pass", key(n))
+			return
+		}
+
+		next[i].Conditions = m.Conditions
+	}
+	return
+}
@@ -1,11 +1,15 @@
 package httpsecret
 
 import (
+	"fmt"
+	gohttp "net/http"
+
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
 	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
@@ -14,8 +18,14 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+// The route of block uploads, which are subject to their own limits.
+var SaveBlocksRoute = http.Post("/v1/orgs/{orgId}/blocks")
+
+// Reading the value of a secret requires viewing its policy.
+var readSecret = policy.Has(policy.View)
+
 func BlockHandlers(svc *http.Service) {
-	svc.Register(http.Post("/v1/orgs/{orgId}/blocks"),
+	svc.Register(SaveBlocksRoute,
 		func(env env.Environment, req http.Request) (ret http.Response) {
 			signer, secrets :=
 				core.AssignSigner(env),
@@ -62,14 +72,28 @@ func BlockHandlers(svc *http.Service) {
 
 			// TODO: VALIDATE POLICY!!
 
-			if err := secrets.SaveBlocks(blocks...); err != nil {
+			quota := core.AssignQuotas(env).BlockBytes
+			if err := secrets.SaveBlocksWithin(quota, blocks...); err != nil {
+				if errs.Is(err, secret.ErrQuota) {
+					ret = http.Reply(http.StatusTooLarge,
+						http.WithMessage(fmt.Sprintf("Org storage quota of [%v] bytes exceeded", quota)))
+					return
+				}
 				ret = http.Panic(err)
 				return
 			}
 
+			core.BlockBytesTotal.Add(float64(blockBytes(blocks)), core.BytesIn)
+
 			ret = http.StatusNoContent
 			return
-		})
+		},
+		http.DocSummary("Saves the encrypted blocks of a secret stream", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest([]secret.Block{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusRequestEntityTooLarge, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/blocks/{secretId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -115,8 +139,9 @@ func BlockHandlers(svc *http.Service) {
 				return
 			}
 
-			if err := policy.Authorize(policies, claim.Account.Id,
-				policy.Has(policy.View), sec); err != nil {
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
+				readSecret, sec); err != nil {
+				core.AuditDenied(env, req, claim, orgId, audit.SecretDownload, sec.Format(), err)
 				ret = http.Unauthorized(err)
 				return
 			}
@@ -129,7 +154,32 @@ func BlockHandlers(svc *http.Service) {
 				return
 			}
 
+			// Downloads are paged, so only the first page is recorded.
+			if offset == nil || *offset == 0 {
+				core.Audit(env, req, claim, orgId, audit.SecretDownload, sec.Format())
+			}
+
+			core.BlockBytesTotal.Add(float64(blockBytes(blocks)), core.BytesOut)
 			ret = http.Ok(enc.Json, blocks)
 			return
-		})
+		},
+		http.DocSummary("Loads the encrypted blocks of a secret", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("secretId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(uint64)),
+			http.Param("limit", http.Uint64, new(uint64)),
+			http.Param("version", http.Int, new(int))),
+		http.DocResponse(gohttp.StatusOK, []secret.Block{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
+
+// Returns the number of encrypted bytes in the blocks.
+func blockBytes(blocks []secret.Block) (ret int) {
+	for _, b := range blocks {
+		ret += len(b.Data.Data)
+	}
+	return
 }
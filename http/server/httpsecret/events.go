@@ -0,0 +1,144 @@
+package httpsecret
+
+import (
+	gohttp "net/http"
+	"strconv"
+	"time"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/headers"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	MaxEventWait  = 60 * time.Second
+	EventPollRate = 500 * time.Millisecond
+	EventPageSize = uint64(256)
+)
+
+func EventHandlers(svc *http.Service) {
+
+	// The events endpoint is a long-poll endpoint.  If there are no events
+	// visible to the caller after the given sequence number, the request
+	// is held open until one arrives or the wait duration elapses.
+	svc.Register(http.Get("/v1/orgs/{orgId}/events"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, secrets :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignSecrets(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var prefix string
+			var after, wait int
+			if err := http.ParseQueryParams(req,
+				http.Param("prefix", http.String, &prefix),
+				http.Param("after", http.Int, &after),
+				http.Param("wait", http.Int, &wait),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			timeout := time.Duration(wait) * time.Second
+			if timeout > MaxEventWait {
+				timeout = MaxEventWait
+			}
+
+			// The sequence to resume from is always returned, so that
+			// callers skip past the events they are not allowed to see.
+			reply := func(events []secret.Event, seq int) http.Response {
+				return http.Reply(
+					http.Ok(enc.Json, events),
+					http.WithHeader(headers.EventSeq, strconv.Itoa(seq)))
+			}
+
+			deadline := time.After(timeout)
+			for {
+				events, next, err := listVisibleEvents(secrets, policies, core.PolicyContext(req, claim), orgId, claim.Account.Id, prefix, after)
+				if err != nil {
+					ret = http.Panic(err)
+					return
+				}
+				if len(events) > 0 || timeout <= 0 {
+					ret = reply(events, next)
+					return
+				}
+
+				// Skip past any events the caller is not allowed to see.
+				after = next
+
+				select {
+				case <-env.Control().Closed():
+					ret = reply([]secret.Event{}, after)
+					return
+				case <-req.Context().Done():
+					ret = reply([]secret.Event{}, after)
+					return
+				case <-deadline:
+					ret = reply([]secret.Event{}, after)
+					return
+				case <-time.After(EventPollRate):
+				}
+			}
+		},
+		http.DocSummary("Waits for the secret events after a sequence", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("prefix", http.String, new(string)),
+			http.Param("after", http.Int, new(int)),
+			http.Param("wait", http.Int, new(int))),
+		http.DocResponse(gohttp.StatusOK, []secret.Event{}))
+}
+
+// Returns the page of events after the given sequence that the user may view
+// along with the sequence number of the last event that was inspected.  Events
+// are visible to those who may read their secrets.
+func listVisibleEvents(secrets secret.Storage, policies policy.Storage, ctx policy.Context, orgId, userId uuid.UUID, prefix string, after int) (ret []secret.Event, last int, err error) {
+	last, ret = after, []secret.Event{}
+
+	events, err := secrets.ListEvents(orgId, prefix, after, page.BuildPage(page.Limit(EventPageSize)))
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	policyIds := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		policyIds = append(policyIds, e.PolicyId)
+	}
+
+	actions, err := policies.LoadEnabledActions(ctx, orgId, userId, policyIds...)
+	if err != nil {
+		return
+	}
+
+	for _, e := range events {
+		if actions[e.PolicyId].Authorize(readSecret) == nil {
+			ret = append(ret, e)
+		}
+	}
+
+	last = events[len(events)-1].Seq
+	return
+}
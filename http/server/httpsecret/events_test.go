@@ -0,0 +1,131 @@
+package httpsecret_test
+
+import (
+	stdctx "context"
+	"fmt"
+	gohttp "net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListEvents(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	// Signals once each long-poll completes.
+	polls := make(chan struct{}, 8)
+	watcher := func(h http.Handler) http.Handler {
+		return func(e env.Environment, req http.Request) http.Response {
+			defer func() {
+				if strings.HasSuffix(req.URL().Path, "/events") {
+					polls <- struct{}{}
+				}
+			}()
+			return h(e, req)
+		}
+	}
+
+	server, err := httptest.StartDefaultServer(ctx, http.WithMiddleware(watcher))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	owner, member :=
+		newSession(t, ctx, server),
+		newSession(t, ctx, server)
+
+	o, err := orgs.Purchase(owner, "events")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	if !assert.Nil(t, orgs.CreateMember(owner, o.Id, member.AccountId(), auth.Member)) {
+		t.FailNow()
+		return
+	}
+
+	sec, err := secrets.Create(owner, secret.NewSecret().SetOrg(o.Id).SetName("/denied"), strings.NewReader("a"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	token, err := member.FetchToken(auth.WithOrgId(o.Id))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	t.Run("Denied", func(t *testing.T) {
+		// The member may edit the secret, but not read it.
+		lock, err := policies.RequirePolicyLock(owner, o.Id, sec.PolicyId, owner.AccountId())
+		if !assert.Nil(t, err) {
+			return
+		}
+		if !assert.Nil(t, policies.GrantPolicyMember(owner, lock, policies.UserType, member.AccountId(), policy.Conditions{}, []policy.Action{policy.View}, policy.Edit)) {
+			return
+		}
+
+		events, next, err := member.Options().Secrets().ListEvents(token, o.Id, "", 0, 0)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, events)
+		assert.True(t, next > 0)
+
+		ownerToken, err := owner.FetchToken(auth.WithOrgId(o.Id))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		events, _, err = owner.Options().Secrets().ListEvents(ownerToken, o.Id, "", 0, 0)
+		if !assert.Nil(t, err) || !assert.NotEmpty(t, events) {
+			return
+		}
+		assert.Equal(t, sec.Id, events[0].SecretId)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		for len(polls) > 0 {
+			<-polls
+		}
+
+		req, err := gohttp.NewRequest("GET",
+			fmt.Sprintf("http://%v/v1/orgs/%v/events?after=%v&wait=%v", server.Address(), o.Id, 1<<20, 30), nil)
+		if !assert.Nil(t, err) {
+			return
+		}
+		req.Header.Set("Authorization", "Bearer "+token.String())
+
+		cancelCtx, cancel := stdctx.WithTimeout(stdctx.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		_, err = gohttp.DefaultClient.Do(req.WithContext(cancelCtx))
+		assert.NotNil(t, err)
+
+		// The poll stops once the client goes away, rather than waiting
+		// out the full wait.
+		select {
+		case <-polls:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "Poll was not canceled")
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package httpsecret
+
+import (
+	gohttp "net/http"
+
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func FolderHandlers(svc *http.Service) {
+
+	svc.Register(http.Put("/v1/orgs/{orgId}/folders/{folderId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, secrets :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignSecrets(env)
+
+			var orgId, folderId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("folderId", http.UUID, &folderId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var folder secret.Folder
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &folder); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			path, err := secret.CleanFolderPath(folder.Path)
+			if err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(folder.PolicyId, "Missing policy id"),
+				http.AssertTrue(folder.OrgId == orgId, "Inconsistent org ids"),
+				http.AssertTrue(folder.Id == folderId, "Inconsistent folder ids"),
+				http.AssertTrue(folder.Path == path, "Folder paths must not end in a slash"),
+			); ret != nil {
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			ctx := core.PolicyContext(req, claim)
+
+			prior, err := secrets.ListFolders(orgId,
+				secret.BuildFolderFilter(secret.FilterFolderIds(folderId)), page.BuildPage(page.Limit(1)))
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			// New folders may only be created by directors or by those
+			// controlling the enclosing folder.
+			if len(prior) == 0 {
+				if err := authorizeNewFolder(policies, secrets, ctx, claim, folder); err != nil {
+					ret = http.Unauthorized(err)
+					return
+				}
+			}
+
+			var action policy.Action = policy.Edit
+			if folder.Deleted {
+				action = policy.Delete
+			}
+
+			// Moving a folder or replacing its policy changes who may
+			// reach the secrets beneath it.
+			if len(prior) > 0 {
+				if err := policy.Authorize(policies, ctx, claim.Account.Id,
+					policy.Has(action), prior[0]); err != nil {
+					ret = http.Unauthorized(err)
+					return
+				}
+
+				if prior[0].Path != folder.Path || prior[0].PolicyId != folder.PolicyId {
+					if err := policy.Authorize(policies, ctx, claim.Account.Id,
+						policy.Has(policy.Sudo), prior[0]); err != nil {
+						ret = http.Unauthorized(err)
+						return
+					}
+				}
+			}
+
+			if err := policy.Authorize(policies, ctx, claim.Account.Id,
+				policy.Has(action), folder); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := secrets.SaveFolder(folder); err != nil {
+				ret = http.Conflict(err)
+				return
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Saves a folder", "folders"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("folderId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(secret.Folder{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil))
+
+	svc.Register(http.Post("/v1/orgs/{orgId}/folders_list"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, policies, secrets :=
+				core.AssignSigner(env),
+				core.AssignPolicies(env),
+				core.AssignSecrets(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParam(req, "orgId", http.UUID, &orgId); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var filter secret.FolderFilter
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &filter); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(
+				req, signer.Public(), auth.IsMember(orgId, auth.Member))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			folders, err := secrets.ListFolders(orgId, filter, page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			infos, err := secret.DecorateFolders(policies, core.PolicyContext(req, claim), claim.Account.Id, folders...)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, infos)
+			return
+		},
+		http.DocSummary("Lists the folders matching a filter", "folders"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocRequest(secret.FolderFilter{}),
+		http.DocResponse(gohttp.StatusOK, []secret.FolderInfo{}))
+}
+
+// Authorizes the creation of a folder.  Directors may create folders
+// anywhere, while other members must hold sudo on the nearest folder
+// enclosing the new one.
+func authorizeNewFolder(policies policy.Storage, secrets secret.Storage, ctx policy.Context, claim auth.Claim, f secret.Folder) (err error) {
+	if auth.IsMember(f.OrgId, auth.Director)(claim) == nil {
+		return
+	}
+
+	paths := secret.FolderPaths(f.Path)
+	if len(paths) == 0 {
+		err = errors.Wrapf(auth.ErrUnauthorized, "Only directors may create top level folders")
+		return
+	}
+
+	parents, err := secrets.ListFolders(f.OrgId,
+		secret.BuildFolderFilter(secret.FilterFolderPaths(paths...)), page.BuildPage())
+	if err != nil {
+		return
+	}
+	if len(parents) == 0 {
+		err = errors.Wrapf(auth.ErrUnauthorized, "Only directors may create folders outside of an existing folder")
+		return
+	}
+
+	// Folders are ordered by path, so the last is the nearest.
+	err = policy.Authorize(policies, ctx, claim.Account.Id,
+		policy.Has(policy.Sudo), parents[len(parents)-1])
+	return
+}
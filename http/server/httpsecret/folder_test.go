@@ -0,0 +1,137 @@
+package httpsecret_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/secrets"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSession(t *testing.T, ctx context.Context, server *http.Server) session.Session {
+	key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	err = session.Register(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	s, err := session.Authenticate(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return s
+}
+
+func TestSaveFolder(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	server, err := httptest.StartDefaultServer(ctx)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	owner, member :=
+		newSession(t, ctx, server),
+		newSession(t, ctx, server)
+
+	o, err := orgs.Purchase(owner, "folders")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	if !assert.Nil(t, orgs.CreateMember(owner, o.Id, member.AccountId(), auth.Member)) {
+		t.FailNow()
+		return
+	}
+
+	grant := func(t *testing.T, f secret.Folder, actions ...policy.Action) {
+		lock, err := policies.RequirePolicyLock(owner, o.Id, f.PolicyId, owner.AccountId())
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if !assert.Nil(t, policies.GrantPolicyMember(owner, lock, policies.UserType, member.AccountId(), policy.Conditions{}, nil, actions...)) {
+			t.FailNow()
+		}
+	}
+
+	team, err := secrets.CreateFolder(owner, o.Id, "/team", crypto.Moderate)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	t.Run("Create_NotDirector", func(t *testing.T) {
+		_, err := secrets.CreateFolder(member, o.Id, "/mine", crypto.Moderate)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Create_NoParentSudo", func(t *testing.T) {
+		_, err := secrets.CreateFolder(member, o.Id, "/team/mine", crypto.Moderate)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Create_ParentSudo", func(t *testing.T) {
+		ops, err := secrets.CreateFolder(owner, o.Id, "/ops", crypto.Moderate)
+		if !assert.Nil(t, err) {
+			return
+		}
+		grant(t, ops, policy.Sudo)
+
+		_, err = secrets.CreateFolder(member, o.Id, "/ops/mine", crypto.Moderate)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Update_ReplacePolicy", func(t *testing.T) {
+		// The member fully controls the new policy, but not the folder.
+		lock, err := policies.CreatePolicy(member, o.Id, crypto.Moderate, policy.Sudo)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		next := team.Update(func(f *secret.Folder) {
+			f.PolicyId = lock.Id()
+		})
+		assert.NotNil(t, secrets.SaveFolder(member, next))
+	})
+
+	t.Run("Update_NoSudo", func(t *testing.T) {
+		dev, err := secrets.CreateFolder(owner, o.Id, "/dev", crypto.Moderate)
+		if !assert.Nil(t, err) {
+			return
+		}
+		grant(t, dev, policy.Edit)
+
+		moved := dev.Update(func(f *secret.Folder) {
+			f.Path = "/moved"
+		})
+		assert.NotNil(t, secrets.SaveFolder(member, moved))
+
+		// Edits that neither move the folder nor replace its policy
+		// only require edit.
+		assert.Nil(t, secrets.SaveFolder(member, dev.Update(func(*secret.Folder) {})))
+	})
+}
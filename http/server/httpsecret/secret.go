@@ -1,19 +1,25 @@
 package httpsecret
 
 import (
+	gohttp "net/http"
+
 	"github.com/cott-io/stash/http/core"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
 	"github.com/cott-io/stash/lang/errs"
 	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
-	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
 	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/libs/webhook"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
+// The route of secret listings, which are subject to their own limits.
+var ListSecretsRoute = http.Post("/v1/orgs/{orgId}/secrets_list")
+
 func SecretHandlers(svc *http.Service) {
 
 	svc.Register(http.Post("/v1/orgs/{orgId}/secrets"),
@@ -68,23 +74,64 @@ func SecretHandlers(svc *http.Service) {
 				action = policy.Delete
 			}
 
-			if err := policy.Authorize(policies, claim.Account.Id,
+			event, record := webhook.SecretUpdate, audit.SecretUpdate
+			if !exists {
+				event, record = webhook.SecretCreate, audit.SecretCreate
+			} else if sec.Deleted {
+				event, record = webhook.SecretDelete, audit.SecretDelete
+			}
+
+			if err := policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 				policy.Has(action),
 				policy.New(sec.OrgId, sec.PolicyId)); err != nil {
+				core.AuditDenied(env, req, claim, orgId, record, sec.Format(), err)
 				ret = http.Unauthorized(err)
 				return
 			}
 
+			// Writers may condition the save on the version they read.
+			var tag string
+			if exists {
+				tag = http.ETag(cur.Version)
+			}
+			if err := http.CheckPreconditions(req, tag); err != nil {
+				ret = http.PreconditionFailed(err)
+				return
+			}
+
+			if exists && sec.Version <= cur.Version {
+				ret = http.Conflict(
+					errors.Wrapf(errs.Conflict, "Secret [%v] has already been updated to version [%v]", cur.Name, cur.Version))
+				return
+			}
+
 			if err := secrets.SaveSecret(sec); err != nil {
-				ret = http.Panic(err)
+				if errs.Is(err, errs.Conflict) {
+					ret = http.Conflict(err)
+				} else {
+					ret = http.Panic(err)
+				}
 				return
 			}
 
-			ret = http.StatusNoContent
-			return
-		})
+			core.SecretWritesTotal.Inc()
+			core.Audit(env, req, claim, orgId, record, sec.Format())
+			core.PublishEvent(env,
+				webhook.NewEvent(orgId, event, claim.Account.Id, sec.Format()))
 
-	svc.Register(http.Post("/v1/orgs/{orgId}/secrets_list"),
+			ret = http.Reply(http.StatusNoContent, http.WithETag(http.ETag(sec.Version)))
+			return
+		},
+		http.DocSummary("Saves a version of a secret", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(secret.Secret{}),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusBadRequest, nil),
+		http.DocResponse(gohttp.StatusConflict, nil),
+		http.DocResponse(gohttp.StatusPreconditionFailed, nil))
+
+	svc.Register(ListSecretsRoute,
 		func(env env.Environment, req http.Request) (ret http.Response) {
 			signer, policies, secrets :=
 				core.AssignSigner(env),
@@ -104,11 +151,8 @@ func SecretHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset, limit *uint64
-			if err := http.ParseQueryParams(req,
-				http.Param("offset", http.Uint64, &offset),
-				http.Param("limit", http.Uint64, &limit),
-			); err != nil {
+			pg, err := core.ParsePage(env, req, filter)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -120,21 +164,32 @@ func SecretHandlers(svc *http.Service) {
 				return
 			}
 
-			results, err := secrets.ListSecrets(orgId, filter, page.Page{Offset: offset, Limit: limit})
+			results, err := secrets.ListSecrets(orgId, filter, pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
-			summaries, err := secret.DecorateSecrets(policies, claim.Account.Id, results...)
+			summaries, err := secret.DecorateSecrets(policies, core.PolicyContext(req, claim), claim.Account.Id, results...)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
-			ret = http.Ok(enc.Json, summaries)
+			// Secrets are listed by name, then id.
+			ret = http.Reply(
+				http.Ok(enc.Json, summaries),
+				core.WithNextPage(env, req, filter, pg, len(results), func(i int) []interface{} {
+					return []interface{}{results[i].Name, results[i].Id}
+				}))
 			return
-		})
+		},
+		http.DocSummary("Lists the secrets matching a filter", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocRequest(secret.Filter{}),
+		http.DocResponse(gohttp.StatusOK, []secret.SecretSummary{}))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/secrets/{secretId}"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -176,16 +231,27 @@ func SecretHandlers(svc *http.Service) {
 			}
 
 			if version >= 0 {
-				if err = policy.Authorize(policies, claim.Account.Id,
+				if err = policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 					policy.Has(secret.Restore, policy.Sudo)); err != nil {
+					core.AuditDenied(env, req, claim, orgId, audit.SecretRead, sec.Format(), err)
 					ret = http.Unauthorized(err)
 					return
 				}
 			}
 
-			ret = http.Ok(enc.Json, sec)
+			core.SecretReadsTotal.Inc()
+			core.Audit(env, req, claim, orgId, audit.SecretRead, sec.Format())
+			ret = http.Reply(http.Ok(enc.Json, sec), http.WithETag(http.ETag(sec.Version)))
 			return
-		})
+		},
+		http.DocSummary("Loads a version of a secret", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("secretId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(http.Param("version", http.Int, new(int))),
+		http.DocResponse(gohttp.StatusOK, secret.Secret{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
 
 	svc.Register(http.Get("/v1/orgs/{orgId}/secrets/{secretId}/versions"),
 		func(env env.Environment, req http.Request) (ret http.Response) {
@@ -203,11 +269,8 @@ func SecretHandlers(svc *http.Service) {
 				return
 			}
 
-			var offset, limit *uint64
-			if err := http.ParseQueryParams(req,
-				http.Param("offset", http.Uint64, &offset),
-				http.Param("limit", http.Uint64, &limit),
-			); err != nil {
+			pg, err := core.ParsePage(env, req, nil)
+			if err != nil {
 				ret = http.BadRequest(err)
 				return
 			}
@@ -219,14 +282,14 @@ func SecretHandlers(svc *http.Service) {
 				return
 			}
 
-			revs, err := secrets.ListSecretVersions(orgId, secretId, page.Page{Offset: offset, Limit: limit})
+			revs, err := secrets.ListSecretVersions(orgId, secretId, pg)
 			if err != nil {
 				ret = http.Panic(err)
 				return
 			}
 
 			if len(revs) > 0 {
-				if err = policy.Authorize(policies, claim.Account.Id,
+				if err = policy.Authorize(policies, core.PolicyContext(req, claim), claim.Account.Id,
 					policy.HasAny(
 						secret.Restore,
 						policy.Sudo),
@@ -236,7 +299,19 @@ func SecretHandlers(svc *http.Service) {
 				}
 			}
 
-			ret = http.Ok(enc.Json, revs)
+			// Versions are listed latest first.
+			ret = http.Reply(
+				http.Ok(enc.Json, revs),
+				core.WithNextPage(env, req, nil, pg, len(revs), func(i int) []interface{} {
+					return []interface{}{revs[i].Version}
+				}))
 			return
-		})
+		},
+		http.DocSummary("Lists the versions of a secret", "secrets"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("secretId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(core.PageParams...),
+		http.DocResponse(gohttp.StatusOK, []secret.Secret{}))
 }
@@ -2,20 +2,28 @@ package httptest
 
 import (
 	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/http/server/httpaccess"
 	"github.com/cott-io/stash/http/server/httpaccount"
+	"github.com/cott-io/stash/http/server/httpaudit"
 	"github.com/cott-io/stash/http/server/httporg"
 	"github.com/cott-io/stash/http/server/httppolicy"
 	"github.com/cott-io/stash/http/server/httpsecret"
+	"github.com/cott-io/stash/http/server/httpwebhook"
 	"github.com/cott-io/stash/lang/billing"
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/crypto"
 	http "github.com/cott-io/stash/lang/http/server"
 	"github.com/cott-io/stash/lang/mail"
+	"github.com/cott-io/stash/lang/sms"
 	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/cott-io/stash/sql/sqlaccess"
 	"github.com/cott-io/stash/sql/sqlaccount"
+	"github.com/cott-io/stash/sql/sqlaudit"
 	"github.com/cott-io/stash/sql/sqlorg"
 	"github.com/cott-io/stash/sql/sqlpolicy"
 	"github.com/cott-io/stash/sql/sqlsecret"
+	"github.com/cott-io/stash/sql/sqlwebhook"
 )
 
 var (
@@ -24,6 +32,9 @@ var (
 		httporg.Handlers,
 		httppolicy.Handlers,
 		httpsecret.Handlers,
+		httpwebhook.Handlers,
+		httpaudit.Handlers,
+		httpaccess.Handlers,
 	}
 )
 
@@ -60,6 +71,21 @@ func StartServer(ctx context.Context, handlers []http.ServiceBuilder, opts ...ht
 		return
 	}
 
+	hooks, err := sqlwebhook.NewSqlStore(driver, schema)
+	if err != nil {
+		return
+	}
+
+	auditLog, err := sqlaudit.NewSqlStore(driver, schema)
+	if err != nil {
+		return
+	}
+
+	requests, err := sqlaccess.NewSqlStore(driver, schema)
+	if err != nil {
+		return
+	}
+
 	key, err := crypto.GenRSAKey(crypto.Rand, 1024)
 	if err != nil {
 		return
@@ -72,10 +98,16 @@ func StartServer(ctx context.Context, handlers []http.ServiceBuilder, opts ...ht
 			http.WithDependency(core.Orgs, orgs),
 			http.WithDependency(core.Policies, policies),
 			http.WithDependency(core.Secrets, secrets),
+			http.WithDependency(core.Webhooks, hooks),
+			http.WithDependency(core.AuditLog, auditLog),
+			http.WithDependency(core.Access, requests),
+			http.WithDependency(core.Dispatcher, webhook.NewDispatcher(ctx, hooks, key)),
 			http.WithDependency(core.BillingKey, ""),
 			http.WithDependency(core.Biller, billing.NullClient{}),
 			http.WithDependency(core.Mailer, mail.MemClient{}),
+			http.WithDependency(core.Texter, sms.NewMemClient()),
 			http.WithDependency(core.Signer, key),
+			http.WithDependency(core.Quotas, core.OrgQuotas{}),
 			http.WithMiddleware(http.TimerMiddleware),
 			http.WithMiddleware(http.RouteMiddleware)}, opts...)...)
 	return
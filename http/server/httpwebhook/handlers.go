@@ -0,0 +1,325 @@
+package httpwebhook
+
+import (
+	gohttp "net/http"
+
+	client "github.com/cott-io/stash/http/client/httpwebhook"
+	"github.com/cott-io/stash/http/core"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Handlers(svc *http.Service) {
+	WebhookHandlers(svc)
+}
+
+// Webhooks expose the activity of an entire org, so they may
+// only be managed by directors and above.
+func WebhookHandlers(svc *http.Service) {
+	svc.Register(http.Post("/v1/orgs/{orgId}/webhooks"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, hooks :=
+				core.AssignSigner(env),
+				core.AssignWebhooks(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var r client.CreateWebhookRequest
+			if err := http.RequireStruct(req, enc.DefaultRegistry, &r); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if ret = http.First(
+				http.NotZero(r.Url, "Missing url"),
+				http.NotZero(r.Method, "Missing signing method"),
+			); ret != nil {
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if _, err := webhook.ParseSignMethod(string(r.Method)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			for _, e := range r.Events {
+				if _, err := webhook.ParseEventType(string(e)); err != nil {
+					ret = http.BadRequest(err)
+					return
+				}
+			}
+
+			hook, err := webhook.NewWebhook(orgId, r.Url, r.Method, r.Events...)
+			if err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := hooks.SaveWebhook(hook); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, hook)
+			return
+		},
+		http.DocSummary("Creates a webhook", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocRequest(client.CreateWebhookRequest{}),
+		http.DocResponse(gohttp.StatusOK, webhook.Webhook{}),
+		http.DocResponse(gohttp.StatusBadRequest, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/webhooks"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, hooks :=
+				core.AssignSigner(env),
+				core.AssignWebhooks(env)
+
+			var orgId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			all, err := hooks.ListWebhooks(orgId, page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			redacted := make([]webhook.Webhook, 0, len(all))
+			for _, h := range all {
+				redacted = append(redacted, h.Redact())
+			}
+
+			ret = http.Ok(enc.Json, redacted)
+			return
+		},
+		http.DocSummary("Lists the webhooks of an org", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(http.Param("orgId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []webhook.Webhook{}))
+
+	svc.Register(http.Delete("/v1/orgs/{orgId}/webhooks/{hookId}"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, hooks :=
+				core.AssignSigner(env),
+				core.AssignWebhooks(env)
+
+			var orgId, hookId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("hookId", http.UUID, &hookId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			hook, ok, err := hooks.LoadWebhook(orgId, hookId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok || hook.Deleted {
+				ret = http.NotFound(errors.Wrapf(webhook.ErrNoWebhook, "No such webhook [%v]", hookId))
+				return
+			}
+
+			if err := hooks.SaveWebhook(hook.Update(webhook.WebhookDelete)); err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Deletes a webhook", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("hookId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Post("/v1/orgs/{orgId}/webhooks/{hookId}/test"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, hooks, dispatcher :=
+				core.AssignSigner(env),
+				core.AssignWebhooks(env),
+				core.AssignDispatcher(env)
+
+			var orgId, hookId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("hookId", http.UUID, &hookId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			claim, err := auth.ParseAndAssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director))
+			if err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			hook, ok, err := hooks.LoadWebhook(orgId, hookId)
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+			if !ok || hook.Deleted {
+				ret = http.NotFound(errors.Wrapf(webhook.ErrNoWebhook, "No such webhook [%v]", hookId))
+				return
+			}
+
+			delivery, err := dispatcher.Deliver(hook,
+				webhook.NewEvent(orgId, webhook.Ping, claim.Account.Id, webhook.AccountTarget(claim.Account.Id)))
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, delivery)
+			return
+		},
+		http.DocSummary("Delivers a test event to a webhook", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("hookId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusOK, webhook.Delivery{}),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+
+	svc.Register(http.Get("/v1/orgs/{orgId}/webhooks/{hookId}/deliveries"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, hooks :=
+				core.AssignSigner(env),
+				core.AssignWebhooks(env)
+
+			var orgId, hookId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("hookId", http.UUID, &hookId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			var offset, limit *uint64
+			if err := http.ParseQueryParams(req,
+				http.Param("offset", http.Uint64, &offset),
+				http.Param("limit", http.Uint64, &limit),
+			); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			deliveries, err := hooks.ListDeliveries(orgId, hookId, page.Page{Offset: offset, Limit: limit})
+			if err != nil {
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.Ok(enc.Json, deliveries)
+			return
+		},
+		http.DocSummary("Lists the deliveries of a webhook", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("hookId", http.UUID, new(uuid.UUID))),
+		http.DocQueryParams(
+			http.Param("offset", http.Uint64, new(*uint64)),
+			http.Param("limit", http.Uint64, new(*uint64))),
+		http.DocResponse(gohttp.StatusOK, []webhook.Delivery{}))
+
+	svc.Register(http.Post("/v1/orgs/{orgId}/deliveries/{deliveryId}/redeliver"),
+		func(env env.Environment, req http.Request) (ret http.Response) {
+			signer, dispatcher :=
+				core.AssignSigner(env),
+				core.AssignDispatcher(env)
+
+			var orgId, deliveryId uuid.UUID
+			if err := http.RequirePathParams(req,
+				http.Param("orgId", http.UUID, &orgId),
+				http.Param("deliveryId", http.UUID, &deliveryId)); err != nil {
+				ret = http.BadRequest(err)
+				return
+			}
+
+			if err := auth.AssertClaims(req, signer.Public(),
+				auth.IsMember(orgId, auth.Director)); err != nil {
+				ret = http.Unauthorized(err)
+				return
+			}
+
+			if err := dispatcher.Redeliver(orgId, deliveryId); err != nil {
+				if errs.Is(err, webhook.ErrNoDelivery, webhook.ErrNoWebhook) {
+					ret = http.NotFound(err)
+					return
+				}
+
+				ret = http.Panic(err)
+				return
+			}
+
+			ret = http.StatusNoContent
+			return
+		},
+		http.DocSummary("Redelivers the event of a delivery", "webhooks"),
+		http.DocSecured(),
+		http.DocPathParams(
+			http.Param("orgId", http.UUID, new(uuid.UUID)),
+			http.Param("deliveryId", http.UUID, new(uuid.UUID))),
+		http.DocResponse(gohttp.StatusNoContent, nil),
+		http.DocResponse(gohttp.StatusNotFound, nil))
+}
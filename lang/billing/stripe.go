@@ -202,13 +202,22 @@ func (s *StripeClient) NextInvoice(customerId, subId string) (ret Invoice, err e
 	return
 }
 
+// The maximum number of invoices stripe returns in a request.
+const stripeMaxLimit int64 = 100
+
 func (s *StripeClient) ListInvoices(subId string, start *string, max int64) (ret []Invoice, err error) {
+	params := stripe.ListParams{StartingAfter: start}
+	if max > 0 {
+		limit := max
+		if limit > stripeMaxLimit {
+			limit = stripeMaxLimit
+		}
+		params.Limit = &limit
+	}
+
 	iter := s.raw.Invoices.List(&stripe.InvoiceListParams{
 		Subscription: &subId,
-		ListParams: stripe.ListParams{
-			StartingAfter: start,
-			Limit:         &max,
-		},
+		ListParams:   params,
 	})
 
 	if err = iter.Err(); err != nil {
@@ -216,8 +225,10 @@ func (s *StripeClient) ListInvoices(subId string, start *string, max int64) (ret
 		return
 	}
 
+	// The iterator fetches subsequent pages on its own, so is stopped
+	// once the page is full.
 	ret = make([]Invoice, 0, iter.Meta().TotalCount)
-	for iter.Next() {
+	for (max <= 0 || int64(len(ret)) < max) && iter.Next() {
 		if err = iter.Err(); err != nil {
 			err = newBillingError(err)
 			return
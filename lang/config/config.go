@@ -1,17 +1,21 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/path"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -91,6 +95,70 @@ func Parse(dec enc.Decoder, r io.Reader) (ret Config, err error) {
 	return
 }
 
+// Returns the codec of a config file by its extension.
+func Codec(file string) (ret enc.EncoderDecoder, err error) {
+	switch {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Unsupported config file [%v]. Expected one of [.yaml, .yml, .json, .toml]", file)
+	case strings.HasSuffix(file, ".yaml"), strings.HasSuffix(file, ".yml"):
+		ret = enc.Yaml
+	case strings.HasSuffix(file, ".json"):
+		ret = enc.Json
+	case strings.HasSuffix(file, ".toml"):
+		ret = enc.Toml
+	}
+	return
+}
+
+// Decodes a typed config file into the value.  Values that are missing
+// from the file are left as they are, so that the value may be given
+// its defaults beforehand.  Keys that the value does not define are
+// rejected, so that misspelled keys are not silently ignored.
+func ReadFile(file string, ptr interface{}) (err error) {
+	dec, err := Codec(file)
+	if err != nil {
+		return
+	}
+
+	file, err = path.Expand(file)
+	if err != nil {
+		return
+	}
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Error reading config file [%v]: %v", file, err)
+		return
+	}
+
+	if err = decodeStrict(dec, raw, ptr); err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid config file [%v]: %v", file, err)
+	}
+	return
+}
+
+func decodeStrict(dec enc.Decoder, raw []byte, ptr interface{}) (err error) {
+	switch dec {
+	default:
+		return dec.DecodeBinary(raw, ptr)
+	case enc.Yaml:
+		return yaml.UnmarshalStrict(raw, ptr)
+	case enc.Json:
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.DisallowUnknownFields()
+		return d.Decode(ptr)
+	case enc.Toml:
+		meta, err := toml.Decode(string(raw), ptr)
+		if err != nil {
+			return err
+		}
+		if keys := meta.Undecoded(); len(keys) > 0 {
+			return errors.Errorf("Unknown keys %v", keys)
+		}
+		return nil
+	}
+}
+
 type Config map[string]string
 
 func NewConfig() Config {
@@ -21,6 +21,16 @@ func NewContext(out io.Writer, lvl LogLevel) Context {
 	return &ctx{logger: NewLogger(out, lvl, ""), control: NewControl(nil)}
 }
 
+func NewContextWithLogger(l Logger) Context {
+	return &ctx{logger: l, control: NewControl(nil)}
+}
+
+// Returns a context that logs with the logger, but shares the control
+// of the given context.
+func WithLogger(c Context, l Logger) Context {
+	return &ctx{logger: l, control: c.Control()}
+}
+
 func NewDefaultContext() Context {
 	return NewContext(ioutil.Discard, Off)
 }
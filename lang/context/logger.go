@@ -1,15 +1,21 @@
 package context
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 var (
-	ErrLogLevel = errors.New("Context:LogLevel")
+	ErrLogLevel  = errors.New("Context:LogLevel")
+	ErrLogFormat = errors.New("Context:LogFormat")
 )
 
 const (
@@ -51,49 +57,190 @@ func (l LogLevel) String() string {
 	}
 }
 
+// The formats in which loggers write their lines.
+type LogFormat int
+
+const (
+	Text LogFormat = iota
+	Json
+	Logfmt
+)
+
+func ParseLogFormat(f string) (LogFormat, error) {
+	switch strings.ToLower(f) {
+	default:
+		return Text, errors.Wrapf(ErrLogFormat, "Invalid logging format [%v].  Must be one of [Text, Json, Logfmt]", f)
+	case "text", "":
+		return Text, nil
+	case "json":
+		return Json, nil
+	case "logfmt":
+		return Logfmt, nil
+	}
+}
+
+func (f LogFormat) String() string {
+	switch f {
+	default:
+		return "Unknown"
+	case Text:
+		return "Text"
+	case Json:
+		return "Json"
+	case Logfmt:
+		return "Logfmt"
+	}
+}
+
 type Logger interface {
 	Fmt(string, ...interface{}) Logger
+
+	// Returns a logger that adds the field to each of its lines.
+	With(key string, val interface{}) Logger
+
 	Debug(string, ...interface{})
 	Info(string, ...interface{})
 	Error(string, ...interface{})
 }
 
+type field struct {
+	key string
+	val interface{}
+}
+
 type logger struct {
-	out  io.Writer
-	lvl  LogLevel
-	fmt  string
-	args []interface{}
+	out    io.Writer
+	lvl    LogLevel
+	format LogFormat
+	scope  string
+	fields []field
 }
 
 func NewLogger(out io.Writer, lvl LogLevel, fmt string, args ...interface{}) Logger {
-	return &logger{out, lvl, fmt, args}
+	return NewFormattedLogger(out, lvl, Text, fmt, args...)
 }
 
-func (l *logger) println(format string, args ...interface{}) {
-	fmt.Fprintln(l.out,
-		fmt.Sprintf("%v: %v",
-			fmt.Sprintf(l.fmt, l.args...),
-			fmt.Sprintf(format, args...)))
+// Returns a logger that writes its lines in the given format.  Messages
+// and fields are redacted of tokens and identities.
+func NewFormattedLogger(out io.Writer, lvl LogLevel, format LogFormat, scope string, args ...interface{}) Logger {
+	return &logger{out, lvl, format, fmt.Sprintf(scope, args...), nil}
+}
+
+func (l *logger) println(lvl LogLevel, format string, args ...interface{}) {
+	msg := Redact(fmt.Sprintf(format, args...))
+
+	// Scopes are joined as text (e.g. ": Http: Env"), so are trimmed of
+	// the separator of the root scope.
+	scope := strings.TrimPrefix(l.scope, ": ")
+
+	var buf bytes.Buffer
+	switch l.format {
+	default:
+		fmt.Fprintf(&buf, "%v: %v", l.scope, msg)
+		for _, f := range l.fields {
+			fmt.Fprintf(&buf, " %v=%v", f.key, logfmtValue(f.val))
+		}
+	case Json:
+		buf.WriteString("{")
+		writeJsonField(&buf, "time", time.Now().UTC().Format(time.RFC3339Nano))
+		buf.WriteString(",")
+		writeJsonField(&buf, "level", strings.ToLower(lvl.String()))
+		if scope != "" {
+			buf.WriteString(",")
+			writeJsonField(&buf, "scope", scope)
+		}
+		buf.WriteString(",")
+		writeJsonField(&buf, "msg", msg)
+		for _, f := range l.fields {
+			buf.WriteString(",")
+			writeJsonField(&buf, f.key, f.val)
+		}
+		buf.WriteString("}")
+	case Logfmt:
+		fmt.Fprintf(&buf, "time=%v level=%v", time.Now().UTC().Format(time.RFC3339Nano), strings.ToLower(lvl.String()))
+		if scope != "" {
+			fmt.Fprintf(&buf, " scope=%v", logfmtValue(scope))
+		}
+		fmt.Fprintf(&buf, " msg=%v", logfmtValue(msg))
+		for _, f := range l.fields {
+			fmt.Fprintf(&buf, " %v=%v", f.key, logfmtValue(f.val))
+		}
+	}
+	buf.WriteString("\n")
+
+	// Lines are written whole, so that concurrent lines do not interleave.
+	l.out.Write(buf.Bytes())
 }
 
 func (l *logger) Fmt(format string, args ...interface{}) Logger {
-	return NewLogger(l.out, l.lvl, fmt.Sprintf("%v: %v", fmt.Sprintf(l.fmt, l.args...), fmt.Sprintf(format, args...)))
+	return &logger{l.out, l.lvl, l.format, fmt.Sprintf("%v: %v", l.scope, fmt.Sprintf(format, args...)), l.fields}
+}
+
+func (l *logger) With(key string, val interface{}) Logger {
+	fields := make([]field, 0, len(l.fields)+1)
+	fields = append(fields, l.fields...)
+	return &logger{l.out, l.lvl, l.format, l.scope, append(fields, field{key, val})}
 }
 
 func (s *logger) Debug(format string, args ...interface{}) {
 	if s.lvl >= Debug {
-		s.println(format, args...)
+		s.println(Debug, format, args...)
 	}
 }
 
 func (s *logger) Info(format string, args ...interface{}) {
 	if s.lvl >= Info {
-		s.println(format, args...)
+		s.println(Info, format, args...)
 	}
 }
 
 func (s *logger) Error(format string, args ...interface{}) {
 	if s.lvl >= Error {
-		s.println(format, args...)
+		s.println(Error, format, args...)
+	}
+}
+
+func writeJsonField(buf *bytes.Buffer, key string, val interface{}) {
+	raw, err := json.Marshal(key)
+	if err != nil {
+		return
+	}
+	buf.Write(raw)
+	buf.WriteString(":")
+
+	if str, ok := val.(string); ok {
+		val = Redact(str)
+	}
+	if raw, err = json.Marshal(val); err != nil {
+		raw, _ = json.Marshal(Redact(fmt.Sprint(val)))
+	}
+	buf.Write(raw)
+}
+
+// Returns the value as a logfmt value, quoted if necessary.
+func logfmtValue(val interface{}) string {
+	str := Redact(fmt.Sprint(val))
+	if str == "" || strings.ContainsAny(str, " =\"\t\n") {
+		return strconv.Quote(str)
+	}
+	return str
+}
+
+// The patterns of values that must never be logged.
+var redactions = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)bearer\s+[^\s"',\]]+`),                          // bearer tokens
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), // jwts
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+`), // emails
+	regexp.MustCompile(`\+[0-9]{7,15}`),                                     // phone numbers
+}
+
+// The value logged in place of redacted values.
+const Redacted = "<redacted>"
+
+// Redacts tokens and identities (e.g. emails) from the string.
+func Redact(str string) string {
+	for _, r := range redactions {
+		str = r.ReplaceAllString(str, Redacted)
 	}
+	return str
 }
@@ -0,0 +1,57 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := NewLogger(&buf, Info, "").Fmt("Http").With("request_id", "abc")
+	log.Debug("hidden")
+	log.Info("GET %v", "/v1/secrets")
+	assert.Equal(t, ": Http: GET /v1/secrets request_id=abc\n", buf.String())
+}
+
+func TestLogger_Json(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := NewFormattedLogger(&buf, Debug, Json, "").Fmt("Http").With("request_id", "abc").With("n", 1)
+	log.Error("Login failed [%v]", "user@example.com")
+
+	var line map[string]interface{}
+	if !assert.Nil(t, json.Unmarshal(buf.Bytes(), &line)) {
+		return
+	}
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, "Http", line["scope"])
+	assert.Equal(t, "Login failed [<redacted>]", line["msg"])
+	assert.Equal(t, "abc", line["request_id"])
+	assert.Equal(t, 1.0, line["n"])
+	assert.NotEmpty(t, line["time"])
+}
+
+func TestLogger_Logfmt(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := NewFormattedLogger(&buf, Info, Logfmt, "").With("request_id", "abc")
+	log.Info("Hello world")
+	assert.True(t, strings.HasSuffix(buf.String(), ` level=info msg="Hello world" request_id=abc`+"\n"), buf.String())
+}
+
+func TestRedact(t *testing.T) {
+	for in, out := range map[string]string{
+		"Authorization: Bearer abc.def-ghi":  "Authorization: <redacted>",
+		"token=eyJhbGci.eyJzdWIi.sig_nature": "token=<redacted>",
+		"GET /v1/identities/a.b+c@ex.co.uk":  "GET /v1/identities/<redacted>",
+		"Texting [+15555550100]":             "Texting [<redacted>]",
+		"Nothing to see [@alias]":            "Nothing to see [@alias]",
+	} {
+		assert.Equal(t, out, Redact(in))
+	}
+}
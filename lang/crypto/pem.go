@@ -44,6 +44,10 @@ func MarshalPemPrivateKey(key PrivateKey, enc PemKeyEncoder) (ret []byte, err er
 // Parses a binary PEM block into a private key
 func UnmarshalPemPrivateKey(raw []byte, fn PemKeyDecoder) (ret PrivateKey, err error) {
 	blk, _ := pem.Decode(raw)
+	if blk == nil {
+		err = errors.New("No PEM block found")
+		return
+	}
 	err = fn(blk, &ret)
 	return
 }
@@ -2,6 +2,7 @@ package env
 
 import (
 	"io"
+	"reflect"
 
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/dep"
@@ -68,3 +69,52 @@ func (e *env) Register(name string, val interface{}) {
 func (e *env) Assign(name string, ptr interface{}) {
 	e.dep.Assign(name, ptr)
 }
+
+// Dependencies that log may implement this to be given the logger of
+// the environment they are assigned from, e.g. one carrying the id of
+// a request.  The returned value must be assignable wherever the
+// dependency is.
+type Scoped interface {
+	WithLogger(context.Logger) interface{}
+}
+
+type scoped struct {
+	Environment
+	ctx context.Context
+}
+
+// Returns an environment that logs with the logger, but otherwise
+// shares the dependencies and control of the given environment.
+// Dependencies that are scoped are given the logger when assigned.
+func WithLogger(e Environment, l context.Logger) Environment {
+	return &scoped{e, context.WithLogger(e.Context(), l)}
+}
+
+// The environment is closed along with the one it was derived from.
+func (e *scoped) Close() error {
+	return nil
+}
+
+func (e *scoped) Context() context.Context {
+	return e.ctx
+}
+
+func (e *scoped) Logger() context.Logger {
+	return e.ctx.Logger()
+}
+
+func (e *scoped) Assign(name string, ptr interface{}) {
+	e.Environment.Assign(name, ptr)
+
+	target := reflect.ValueOf(ptr).Elem()
+	switch target.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if target.IsNil() {
+			return
+		}
+	}
+
+	if dep, ok := target.Interface().(Scoped); ok {
+		target.Set(reflect.ValueOf(dep.WithLogger(e.Logger())))
+	}
+}
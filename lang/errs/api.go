@@ -13,6 +13,7 @@ var (
 	StateError    = fmt.Errorf("Errs:StateError")
 	ClosedError   = fmt.Errorf("Errs:ClosedError")
 	CanceledError = errors.New("Errs:CanceledError")
+	Conflict      = errors.New("Errs:Conflict")
 )
 
 func Or(all ...error) error {
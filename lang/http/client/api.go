@@ -26,6 +26,7 @@ var (
 	Uint32        = headers.Uint32
 	Uint64        = headers.Uint64
 	UUID          = headers.UUID
+	ETag          = headers.EntityTag
 )
 
 type Client interface {
@@ -2,16 +2,21 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/http/headers"
+	uuid "github.com/satori/go.uuid"
 )
 
 var tlsMatch = regexp.MustCompile(":443$")
@@ -20,31 +25,85 @@ type HttpClient struct {
 	Raw     *http.Client
 	Proto   string
 	Address string
+	Backoff Backoff
 }
 
-func NewDefaultClient(addr string) (ret Client) {
+type Option func(*HttpClient)
+
+// The policy by which requests rejected with a 429 are retried.  The
+// server's Retry-After is honored when given, otherwise the wait
+// doubles (with jitter) from the minimum.  Waits never exceed the max.
+type Backoff struct {
+	Retries int
+	Min     time.Duration
+	Max     time.Duration
+}
+
+var DefaultBackoff = Backoff{Retries: 3, Min: 500 * time.Millisecond, Max: 30 * time.Second}
+
+// Returns the wait before the given retry (starting at 0).
+func (b Backoff) Wait(retry int, retryAfter string) (ret time.Duration) {
+	if secs, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && secs >= 0 {
+		ret = time.Duration(secs) * time.Second
+	} else {
+		ret = b.Min << uint(retry)
+		ret = ret/2 + time.Duration(rand.Int63n(int64(ret/2)+1))
+	}
+
+	if ret > b.Max {
+		ret = b.Max
+	}
+	return
+}
+
+// Retries requests that were rejected with a 429 according to the backoff.
+func WithBackoff(b Backoff) Option {
+	return func(h *HttpClient) {
+		h.Backoff = b
+	}
+}
+
+// Uses tls with the given config, regardless of the address.
+func WithTLS(c *tls.Config) Option {
+	return func(h *HttpClient) {
+		h.Proto = "https"
+		h.Raw = &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     c,
+				TLSHandshakeTimeout: 10 * time.Second,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		}
+	}
+}
+
+// Returns a client of the address.  Addresses ending in :443 or
+// starting with https:// are assumed to require tls.
+func NewDefaultClient(addr string, fns ...Option) (ret Client) {
 	var proto string
-	if tlsMatch.MatchString(addr) {
+	if strings.HasPrefix(addr, "https://") || tlsMatch.MatchString(addr) {
 		proto = "https"
 	} else {
 		proto = "http"
 	}
-	ret = &HttpClient{http.DefaultClient, proto, addr}
+
+	raw := &HttpClient{http.DefaultClient, proto, strings.TrimPrefix(strings.TrimPrefix(addr, "https://"), "http://"), DefaultBackoff}
+	for _, fn := range fns {
+		fn(raw)
+	}
+
+	ret = raw
 	return
 }
 
-func (h *HttpClient) url(rel string, queries map[string]string) (ret *url.URL, err error) {
+func (h *HttpClient) url(rel string, query string) (ret *url.URL, err error) {
 	ret, err = url.Parse(fmt.Sprintf("%v://%v/%v", h.Proto, h.Address, strings.Trim(rel, "/")))
 	if err != nil {
 		return
 	}
 
-	query := ret.Query()
-	for k, v := range queries {
-		query.Set(k, url.QueryEscape(v))
-	}
-
-	ret.RawQuery = query.Encode()
+	ret.RawQuery = query
 	return
 }
 
@@ -54,24 +113,41 @@ func (h *HttpClient) Call(reqFn Request, respFn func(Response) error) (err error
 		return
 	}
 
-	url, err := h.url(data.path, data.queries)
+	url, err := h.url(data.path, data.query())
 	if err != nil {
 		return
 	}
 
-	req, err := http.NewRequest(data.method, url.String(), data.body)
-	if err != nil {
-		return
+	// Every call is given an id, by which the server correlates its logs.
+	id, ok := data.headers[headers.RequestId]
+	if !ok {
+		id = uuid.NewV4().String()
+		data.headers[headers.RequestId] = id
 	}
 
-	for k, v := range data.headers {
-		req.Header.Set(k, v)
-	}
+	var raw *http.Response
+	for retry := 0; ; retry++ {
+		var req *http.Request
+		if req, err = http.NewRequest(data.method, url.String(), data.reader()); err != nil {
+			return
+		}
 
-	raw, err := h.Raw.Do(req)
-	if err != nil {
-		err = fmt.Errorf("Error calling [%v %v]: %w", data.method, url, err)
-		return
+		for k, v := range data.headers {
+			req.Header.Set(k, v)
+		}
+
+		if raw, err = h.Raw.Do(req); err != nil {
+			err = fmt.Errorf("Error calling [%v %v] [request-id=%v]: %w", data.method, url, id, err)
+			return
+		}
+
+		// Streamed bodies cannot be sent again, so are never retried.
+		if raw.StatusCode != http.StatusTooManyRequests || retry >= h.Backoff.Retries || !data.replayable() {
+			break
+		}
+
+		raw.Body.Close()
+		time.Sleep(h.Backoff.Wait(retry, raw.Header.Get(headers.RetryAfter)))
 	}
 
 	res := &response{raw}
@@ -79,7 +155,7 @@ func (h *HttpClient) Call(reqFn Request, respFn func(Response) error) (err error
 		err = errs.Or(err, res.Close())
 	}()
 	if err = respFn(res); err != nil {
-		err = fmt.Errorf("Error calling [%v %v]: %w", data.method, url, err)
+		err = fmt.Errorf("Error calling [%v %v] [request-id=%v]: %w", data.method, url, id, err)
 	}
 	return
 }
@@ -90,6 +166,61 @@ type requestBuilder struct {
 	headers map[string]string
 	queries map[string]string
 	body    io.Reader
+	raw     []byte
+}
+
+// Returns a reader of the body.  Bodies set from bytes may be read
+// again on each call.
+func (h *requestBuilder) reader() io.Reader {
+	if h.raw != nil {
+		return bytes.NewReader(h.raw)
+	}
+	return h.body
+}
+
+func (h *requestBuilder) replayable() bool {
+	return h.body == nil || h.raw != nil
+}
+
+// Returns the encoded query.  Values are escaped before encoding, as the
+// server unescapes them once more.
+func (h *requestBuilder) query() string {
+	query := url.Values{}
+	for k, v := range h.queries {
+		query.Set(k, url.QueryEscape(v))
+	}
+	return query.Encode()
+}
+
+// A request as it is sent over the wire: its path is escaped and its
+// query encoded.  Transports other than http carry these in place of
+// http requests.
+type RawRequest struct {
+	Method  string
+	Path    string
+	Query   string
+	Headers map[string]string
+	Body    []byte
+}
+
+// Builds the request, reading its body in full.
+func ReadRequest(req Request) (ret RawRequest, err error) {
+	data, err := buildRequest(req)
+	if err != nil {
+		return
+	}
+
+	ret = RawRequest{
+		Method:  data.method,
+		Path:    data.path,
+		Query:   data.query(),
+		Headers: data.headers,
+		Body:    data.raw,
+	}
+	if data.raw == nil && data.body != nil {
+		ret.Body, err = ioutil.ReadAll(data.body)
+	}
+	return
 }
 
 func buildRequest(req Request) (ret *requestBuilder, err error) {
@@ -123,12 +254,12 @@ func (h *requestBuilder) SetHeader(k, v string) {
 
 func (h *requestBuilder) SetBody(mime string, val []byte) {
 	h.SetHeader(headers.ContentType, mime)
-	h.body = bytes.NewBuffer(val)
+	h.body, h.raw = bytes.NewBuffer(val), val
 }
 
 func (h *requestBuilder) SetBodyRaw(mime string, val io.Reader) {
 	h.SetHeader(headers.ContentType, mime)
-	h.body = val
+	h.body, h.raw = val, nil
 }
 
 type response struct {
@@ -12,6 +12,8 @@ var (
 	ErrNoMethod     = errors.New("Http:MethodNotAllowed")
 	ErrConflict     = errors.New("Http:Conflict")
 	ErrPrecondition = errors.New("Http:PreconditionFailed")
+	ErrTooLarge     = errors.New("Http:RequestEntityTooLarge")
+	ErrTooMany      = errors.New("Http:TooManyRequests")
 )
 
 func ReadError(res Response) (err error) {
@@ -35,6 +37,10 @@ func ReadError(res Response) (err error) {
 		base = ErrConflict
 	case 412:
 		base = ErrPrecondition
+	case 413:
+		base = ErrTooLarge
+	case 429:
+		base = ErrTooMany
 	}
 
 	var msg string
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/http/headers"
@@ -136,3 +137,15 @@ func Either(val, def interface{}) interface{} {
 		return val
 	}
 }
+
+// Makes the request conditional on the resource being at one of the
+// tags, or on it existing at all if the tag is "*".
+func WithIfMatch(tags ...string) Request {
+	return WithHeader(headers.IfMatch, strings.Join(tags, ", "))
+}
+
+// Makes the request conditional on the resource being at none of the
+// tags, or on it not existing at all if the tag is "*".
+func WithIfNoneMatch(tags ...string) Request {
+	return WithHeader(headers.IfNoneMatch, strings.Join(tags, ", "))
+}
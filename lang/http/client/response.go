@@ -46,7 +46,7 @@ func ExpectMessage(msg string) func(Response) error {
 	return func(r Response) (err error) {
 		var body []byte
 		if err = r.ReadBody(&body); err != nil || body == nil {
-			err = fmt.Errorf("Unable to read body [%w], err")
+			err = fmt.Errorf("Unable to read body [%v]", err)
 			return
 		}
 
@@ -64,6 +64,14 @@ func ExpectHeader(name string, dec Decoder, ptr interface{}) func(Response) erro
 	}
 }
 
+// Parses the header, if the response has one.
+func MaybeExpectHeader(name string, dec Decoder, ptr interface{}) func(Response) error {
+	return func(r Response) (err error) {
+		_, err = ParseHeader(r, name, dec, ptr)
+		return
+	}
+}
+
 func MaybeExpectStruct(reg enc.Registry, found *bool, val interface{}) func(Response) error {
 	return func(r Response) (err error) {
 		if r.ReadCode() == http.StatusNotFound {
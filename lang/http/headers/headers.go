@@ -1,6 +1,7 @@
 package headers
 
 import (
+	"fmt"
 	"reflect"
 	"strconv"
 
@@ -24,11 +25,13 @@ const (
 	ContentLocation   = "Content-Location"
 	ContentRange      = "Content-Range"
 	Date              = "Date"
+	ETag              = "ETag"
 	EncodingChunked   = "chunked"
 	EncodingCompress  = "compress"
 	EncodingDeflate   = "deflate"
 	EncodingGzip      = "gzip"
 	Expect            = "Expect"
+	EventSeq          = "X-Event-Seq"
 	Expires           = "Expires"
 	IfMatch           = "If-Match"
 	IfModifiedSince   = "If-Modified-Since"
@@ -36,6 +39,9 @@ const (
 	IfUnmodifiedSince = "If-Unmodified-Since"
 	LastModified      = "Last-Modified"
 	Location          = "Location"
+	NextCursor        = "X-Next-Cursor"
+	RequestId         = "X-Request-Id"
+	RetryAfter        = "Retry-After"
 	UserAgent         = "User-Agent"
 	Warning           = "Warning"
 )
@@ -46,6 +52,12 @@ type Headers interface {
 
 type Decoder func(string, interface{}) error
 
+// Returns the (strong) entity tag of a value, typically the version
+// of a resource.
+func EntityTag(val interface{}) string {
+	return strconv.Quote(fmt.Sprint(val))
+}
+
 func ReadHeader(req Headers, name string, def string) (ret string) {
 	if ok := req.ReadHeader(name, &ret); ok {
 		return
@@ -99,6 +111,11 @@ func UUID(val string, raw interface{}) (err error) {
 func String(val string, raw interface{}) (err error) {
 	switch t := raw.(type) {
 	default:
+		// Named string types (e.g. cursors) are assigned by reflection.
+		if v := reflect.ValueOf(raw); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.String {
+			v.Elem().SetString(val)
+			return
+		}
 		err = errors.Errorf("Cannot assign value [%v] to [%v]", val, reflect.ValueOf(raw))
 	case *string:
 		*t = val
@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	typeUUID     = reflect.TypeOf(uuid.UUID{})
+	typeTime     = reflect.TypeOf(time.Time{})
+	typeDuration = reflect.TypeOf(time.Duration(0))
+	typeBytes    = reflect.TypeOf([]byte{})
+	typeJson     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeText     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Returns the schema of a value's type.  Named structs are added to
+// the document's components and referenced by name, so that recursive
+// types terminate.
+func (d *Document) SchemaOf(val interface{}) *Schema {
+	if val == nil {
+		return &Schema{}
+	}
+	return d.schema(reflect.TypeOf(val))
+}
+
+// Returns the schema of a parameter's type.  Parameters are never
+// structs, so the schema is always inlined.
+func ParamSchema(t reflect.Type) *Schema {
+	return NewDocument(Info{}).schema(t)
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case typeUUID:
+		return &Schema{Type: "string", Format: "uuid"}
+	case typeTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case typeDuration:
+		return &Schema{Type: "integer", Format: "int64"}
+	case typeBytes:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	// Custom encodings have no reflectable shape.
+	ptr := reflect.PtrTo(t)
+	switch {
+	case t.Implements(typeJson) || ptr.Implements(typeJson):
+		return &Schema{}
+	case t.Implements(typeText) || ptr.Implements(typeText):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	default:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+
+		name, ok := d.types[t]
+		if !ok {
+			name = d.name(t)
+			d.types[t] = name
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+}
+
+// Returns the schema of a struct, following the rules of encoding/json.
+func (d *Document) object(t reflect.Type) *Schema {
+	ret := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range d.object(embedded).Properties {
+					ret.Properties[k] = v
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		ret.Properties[name] = d.schema(field.Type)
+	}
+	return ret
+}
+
+// Returns a component name of the type (e.g. secret.Secret) that is
+// unique within the document.
+func (d *Document) name(t reflect.Type) (ret string) {
+	ret = fmt.Sprintf("%v.%v", path.Base(t.PkgPath()), t.Name())
+	for i := 2; ; i++ {
+		if _, ok := d.Components.Schemas[ret]; !ok {
+			return
+		}
+		ret = fmt.Sprintf("%v.%v%v", path.Base(t.PkgPath()), t.Name(), i)
+	}
+}
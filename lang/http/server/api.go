@@ -1,6 +1,8 @@
 package server
 
 import (
+	stdctx "context"
+	"crypto/tls"
 	"io"
 	"net/url"
 
@@ -20,11 +22,14 @@ type ServiceBuilder func(*Service)
 // A Service is simply a collection of handlers
 type Service struct {
 	routes map[Route]Handler
+	docs   map[Route]Doc
 }
 
-// Register a handler to the service
-func (s *Service) Register(route Route, fn Handler) {
+// Register a handler to the service, optionally describing the route
+// for the generated api document.
+func (s *Service) Register(route Route, fn Handler, docs ...DocOption) {
 	s.routes[route] = fn
+	s.docs[route] = buildDoc(docs...)
 }
 
 // Build a composite builder
@@ -37,7 +42,7 @@ func Build(fns ...ServiceBuilder) ServiceBuilder {
 }
 
 func buildService(fns ...ServiceBuilder) (ret *Service) {
-	ret = &Service{make(map[Route]Handler)}
+	ret = &Service{make(map[Route]Handler), make(map[Route]Doc)}
 	for _, fn := range fns {
 		fn(ret)
 	}
@@ -83,6 +88,16 @@ type Request interface {
 	ReadPathParam(string, *string) (bool, error)
 	ReadQueryParam(string, *string) (bool, error)
 	ReadBody(*[]byte) error
+
+	// Returns the route that matched the request.
+	Route() Route
+
+	// Returns the state of the tls connection, if any.
+	TLS() *tls.ConnectionState
+
+	// Returns the context of the request, which is done once the
+	// client goes away.
+	Context() stdctx.Context
 }
 
 // A Response is a function that updates a response builder
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/openapi"
+	"github.com/cott-io/stash/lang/mime"
+)
+
+// A doc describes a route for the generated api document.  Routes
+// without docs are still described, though only by their path.
+type Doc struct {
+	Summary   string
+	Tags      []string
+	Secured   bool
+	Params    []DocParam
+	Request   interface{}
+	Responses map[int]interface{}
+}
+
+// A documented parameter.  The type is that of the value the parameter
+// is decoded into.
+type DocParam struct {
+	Name     string
+	In       string
+	Required bool
+	Type     reflect.Type
+}
+
+type DocOption func(*Doc)
+
+func buildDoc(fns ...DocOption) (ret Doc) {
+	ret.Responses = make(map[int]interface{})
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+func DocSummary(summary string, tags ...string) DocOption {
+	return func(d *Doc) {
+		d.Summary, d.Tags = summary, append(d.Tags, tags...)
+	}
+}
+
+// Marks the route as requiring a bearer token.
+func DocSecured() DocOption {
+	return func(d *Doc) {
+		d.Secured = true
+	}
+}
+
+// Documents the path parameters, using the same params given to
+// RequirePathParams.
+func DocPathParams(params ...ParamID) DocOption {
+	return docParams(openapi.InPath, true, params)
+}
+
+// Documents the query parameters, using the same params given to
+// ParseQueryParams.
+func DocQueryParams(params ...ParamID) DocOption {
+	return docParams(openapi.InQuery, false, params)
+}
+
+func docParams(in string, required bool, params []ParamID) DocOption {
+	return func(d *Doc) {
+		for _, fn := range params {
+			name, _, ptr := fn()
+			d.Params = append(d.Params, DocParam{name, in, required, reflect.TypeOf(ptr)})
+		}
+	}
+}
+
+// Documents the body of the request by an example value.
+func DocRequest(val interface{}) DocOption {
+	return func(d *Doc) {
+		d.Request = val
+	}
+}
+
+// Documents a response by its code and an example of its body.  A nil
+// body is a plain text message.
+func DocResponse(code int, val interface{}) DocOption {
+	return func(d *Doc) {
+		d.Responses[code] = val
+	}
+}
+
+var pathVars = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// Generates the api document of the services.
+func OpenAPI(info openapi.Info, fns ...ServiceBuilder) *openapi.Document {
+	svc, doc := buildService(fns...), openapi.NewDocument(info)
+	for _, r := range sortedRoutes(svc) {
+		doc.Add(strings.ToLower(r.Method), pathVars.ReplaceAllString(r.Path, "{$1}"), describe(doc, r, svc.docs[r]))
+	}
+	return doc
+}
+
+// Returns the routes of the services that have no summary.
+func Undocumented(fns ...ServiceBuilder) (ret []Route) {
+	svc := buildService(fns...)
+	for _, r := range sortedRoutes(svc) {
+		if svc.docs[r].Summary == "" {
+			ret = append(ret, r)
+		}
+	}
+	return
+}
+
+func sortedRoutes(svc *Service) (ret []Route) {
+	ret = make([]Route, 0, len(svc.routes))
+	for r := range svc.routes {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		return ret[i].Method < ret[j].Method
+	})
+	return
+}
+
+func describe(doc *openapi.Document, r Route, d Doc) (ret *openapi.Operation) {
+	ret = &openapi.Operation{
+		OperationId: operationId(r),
+		Summary:     d.Summary,
+		Tags:        d.Tags,
+		Responses:   make(map[string]*openapi.Response),
+	}
+
+	documented := make(map[string]bool)
+	for _, p := range d.Params {
+		documented[p.In+p.Name] = true
+		ret.Parameters = append(ret.Parameters, openapi.Parameter{
+			Name:     p.Name,
+			In:       p.In,
+			Required: p.Required,
+			Schema:   openapi.ParamSchema(p.Type),
+		})
+	}
+
+	// Path parameters are required by the spec, so are described
+	// even if the route is not.
+	for _, m := range pathVars.FindAllStringSubmatch(r.Path, -1) {
+		if !documented[openapi.InPath+m[1]] {
+			ret.Parameters = append(ret.Parameters, openapi.Parameter{
+				Name:     m[1],
+				In:       openapi.InPath,
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+
+	if d.Request != nil {
+		ret.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{mime.Json: {Schema: doc.SchemaOf(d.Request)}},
+		}
+	}
+
+	responses := make(map[int]interface{})
+	for code, val := range d.Responses {
+		responses[code] = val
+	}
+
+	if d.Secured {
+		ret.Security = []map[string][]string{{openapi.BearerAuth: {}}}
+		if _, ok := responses[http.StatusUnauthorized]; !ok {
+			responses[http.StatusUnauthorized] = nil
+		}
+	}
+
+	if len(responses) == 0 {
+		ret.Responses["default"] = &openapi.Response{Description: "Undocumented"}
+		return
+	}
+
+	for code, val := range responses {
+		resp := &openapi.Response{Description: http.StatusText(code)}
+		if val != nil {
+			resp.Content = map[string]openapi.MediaType{mime.Json: {Schema: doc.SchemaOf(val)}}
+		} else if code != http.StatusNoContent {
+			resp.Content = map[string]openapi.MediaType{mime.Text: {Schema: &openapi.Schema{Type: "string"}}}
+		}
+		ret.Responses[strconv.Itoa(code)] = resp
+	}
+	return
+}
+
+// Returns an identifier of the route (e.g. get_v1_orgs_orgId).
+func operationId(r Route) string {
+	parts := []string{strings.ToLower(r.Method)}
+	for _, part := range strings.Split(pathVars.ReplaceAllString(r.Path, "$1"), "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// Serves the api document of the services at the path.  The document
+// is generated once, when the handlers are registered.
+func OpenAPIHandlers(path string, info openapi.Info, fns ...ServiceBuilder) ServiceBuilder {
+	return func(svc *Service) {
+		doc := OpenAPI(info, fns...)
+		svc.Register(Get(path), func(env env.Environment, req Request) Response {
+			return Ok(enc.Json, doc)
+		}, DocSummary("Returns this document", "meta"), DocResponse(http.StatusOK, map[string]interface{}{}))
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/openapi"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type docNode struct {
+	Id       uuid.UUID  `json:"id"`
+	Created  time.Time  `json:"created"`
+	Children []*docNode `json:"children,omitempty"`
+	Hidden   string     `json:"-"`
+	docEmbed
+}
+
+type docEmbed struct {
+	Tags map[string]int `json:"tags"`
+}
+
+func docHandlers(s *Service) {
+	s.Register(Put("/nodes/{nodeId}"), func(e env.Environment, r Request) (ret Response) {
+		return StatusNoContent
+	},
+		DocSummary("Saves a node", "nodes"),
+		DocSecured(),
+		DocPathParams(Param("nodeId", UUID, new(uuid.UUID))),
+		DocQueryParams(Param("limit", Uint64, new(*uint64))),
+		DocRequest(docNode{}),
+		DocResponse(http.StatusNoContent, nil))
+
+	s.Register(Get("/nodes/{nodeId}/{path:.*}"), func(e env.Environment, r Request) (ret Response) {
+		return StatusOK
+	})
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPI(openapi.Info{Title: "test", Version: "v1"}, docHandlers)
+
+	put := doc.Paths["/nodes/{nodeId}"]["put"]
+	if !assert.NotNil(t, put) {
+		return
+	}
+	assert.Equal(t, "put_nodes_nodeId", put.OperationId)
+	assert.Equal(t, []string{"nodes"}, put.Tags)
+	assert.Equal(t, []openapi.Parameter{
+		{Name: "nodeId", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+		{Name: "limit", In: "query", Schema: put.Parameters[1].Schema},
+	}, put.Parameters)
+	assert.Equal(t, "integer", put.Parameters[1].Schema.Type)
+	assert.Contains(t, put.Responses, "204")
+	assert.Contains(t, put.Responses, "401")
+
+	ref := put.RequestBody.Content["application/json"].Schema.Ref
+	assert.Equal(t, "#/components/schemas/server.docNode", ref)
+
+	node := doc.Components.Schemas["server.docNode"]
+	if !assert.NotNil(t, node) {
+		return
+	}
+	assert.Equal(t, &openapi.Schema{Type: "string", Format: "date-time"}, node.Properties["created"])
+	assert.Equal(t, ref, node.Properties["children"].Items.Ref)
+	assert.Equal(t, "integer", node.Properties["tags"].AdditionalProperties.Type)
+	assert.NotContains(t, node.Properties, "Hidden")
+
+	get := doc.Paths["/nodes/{nodeId}/{path}"]["get"]
+	if !assert.NotNil(t, get) {
+		return
+	}
+	assert.Len(t, get.Parameters, 2)
+	assert.Contains(t, get.Responses, "default")
+}
+
+func TestUndocumented(t *testing.T) {
+	assert.Equal(t, []Route{Get("/nodes/{nodeId}/{path:.*}")}, Undocumented(docHandlers))
+}
+
+func TestOpenAPIHandlers(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	server, err := Serve(ctx, Build(docHandlers,
+		OpenAPIHandlers("/openapi.json", openapi.Info{Title: "test", Version: "v1"}, docHandlers)))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	var doc openapi.Document
+	assert.Nil(t,
+		server.Connect().Call(
+			client.Get("/openapi.json"),
+			client.ExpectAll(
+				client.ExpectCode(200),
+				client.ExpectStruct(enc.DefaultRegistry, &doc))))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/nodes/{nodeId}")
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/headers"
+	"github.com/pkg/errors"
+)
+
+// Returns the (strong) entity tag of a value, typically the version
+// of a resource.
+var ETag = headers.EntityTag
+
+// Tags the response with the entity tag of the resource.
+func WithETag(tag string) Response {
+	return WithHeader(headers.ETag, tag)
+}
+
+// Evaluates the If-Match and If-None-Match headers of a request against
+// the current tag of the resource.  An empty tag means the resource does
+// not exist.  A failed precondition is returned as a conflict.
+func CheckPreconditions(req Request, tag string) (err error) {
+	var hdr string
+	if req.ReadHeader(headers.IfMatch, &hdr) && !matchesTag(hdr, tag, false) {
+		if tag == "" {
+			err = errors.Wrapf(errs.Conflict, "Expected one of [%v], but the resource does not exist", hdr)
+			return
+		}
+		err = errors.Wrapf(errs.Conflict, "Expected one of [%v], but the resource is at [%v]", hdr, tag)
+		return
+	}
+	if req.ReadHeader(headers.IfNoneMatch, &hdr) && matchesTag(hdr, tag, true) {
+		err = errors.Wrapf(errs.Conflict, "Expected none of [%v], but the resource is at [%v]", hdr, tag)
+		return
+	}
+	return
+}
+
+// Returns whether a list of tags matches the tag.  If-Match requires a
+// strong comparison, so weak tags only match when the comparison is weak.
+func matchesTag(list, tag string, weak bool) bool {
+	if tag == "" {
+		return false
+	}
+
+	if weak {
+		tag = strings.TrimPrefix(tag, "W/")
+	}
+
+	for _, cur := range strings.Split(list, ",") {
+		cur = strings.TrimSpace(cur)
+		if weak {
+			cur = strings.TrimPrefix(cur, "W/")
+		}
+		if cur == "*" || cur == tag {
+			return true
+		}
+	}
+	return false
+}
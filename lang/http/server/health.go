@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cott-io/stash/lang/env"
+)
+
+// The routes at which servers report their health.  A server is healthy
+// while its process is alive, and ready while all of its readiness
+// checks pass and it is not draining.
+var (
+	HealthRoute = Get("/healthz")
+	ReadyRoute  = Get("/readyz")
+)
+
+// The maximum time to wait on any one readiness check.
+var ReadyTimeout = 5 * time.Second
+
+// A check returns an error if some dependency of the server is
+// unavailable.
+type Check func() error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Adds a check to the readiness of the server.
+func WithReadiness(name string, check Check) Option {
+	return func(o *Options) {
+		o.Checks = append(o.Checks, namedCheck{name, check})
+	}
+}
+
+type health struct {
+	checks   []namedCheck
+	draining int32
+}
+
+func (h *health) drain() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+func (h *health) isDraining() bool {
+	return atomic.LoadInt32(&h.draining) == 1
+}
+
+// Runs the checks, returning the failures by name.
+func (h *health) failures() (ret []string) {
+	for _, c := range h.checks {
+		done := make(chan error, 1)
+		go func(c namedCheck) {
+			done <- c.check()
+		}(c)
+
+		timer := time.NewTimer(ReadyTimeout)
+		select {
+		case err := <-done:
+			if err != nil {
+				ret = append(ret, fmt.Sprintf("%v: %v", c.name, err))
+			}
+		case <-timer.C:
+			ret = append(ret, fmt.Sprintf("%v: timed out after [%v]", c.name, ReadyTimeout))
+		}
+		timer.Stop()
+	}
+	return
+}
+
+func (h *health) register(svc *Service) {
+	svc.Register(HealthRoute, func(env env.Environment, req Request) Response {
+		return StatusOK
+	},
+		DocSummary("Returns whether the server is alive", "meta"),
+		DocResponse(http.StatusOK, nil))
+
+	svc.Register(ReadyRoute, func(env env.Environment, req Request) Response {
+		if h.isDraining() {
+			return Reply(WithCode(http.StatusServiceUnavailable), WithMessage("Draining"))
+		}
+
+		if failures := h.failures(); len(failures) > 0 {
+			env.Logger().Error("Server not ready: %v", failures)
+			return Reply(WithCode(http.StatusServiceUnavailable), WithMessage(strings.Join(failures, "\n")))
+		}
+		return StatusOK
+	},
+		DocSummary("Returns whether the server is ready to serve requests", "meta"),
+		DocResponse(http.StatusOK, nil),
+		DocResponse(http.StatusServiceUnavailable, nil))
+}
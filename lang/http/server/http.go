@@ -2,6 +2,8 @@ package server
 
 import (
 	"bytes"
+	stdctx "context"
+	"crypto/tls"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -13,12 +15,13 @@ import (
 )
 
 type request struct {
-	raw  *http.Request
-	vars map[string]string
+	raw   *http.Request
+	route Route
+	vars  map[string]string
 }
 
-func newRequest(raw *http.Request) *request {
-	return &request{raw, mux.Vars(raw)}
+func newRequest(raw *http.Request, route Route) *request {
+	return &request{raw, route, mux.Vars(raw)}
 }
 
 func (r *request) Close() error {
@@ -37,6 +40,18 @@ func (r *request) Remote() string {
 	return r.raw.RemoteAddr
 }
 
+func (r *request) Route() Route {
+	return r.route
+}
+
+func (r *request) TLS() *tls.ConnectionState {
+	return r.raw.TLS
+}
+
+func (r *request) Context() stdctx.Context {
+	return r.raw.Context()
+}
+
 func (r *request) ReadHeader(name string, ptr *string) (ok bool) {
 	*ptr = r.raw.Header.Get(name)
 	defer func() {
@@ -1,13 +1,24 @@
 package server
 
 import (
+	"bytes"
+	stdctx "context"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/enc"
 	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/http/headers"
+	"github.com/cott-io/stash/lang/metrics"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -62,3 +73,358 @@ func TestServer_NonEmptyResponse(t *testing.T) {
 	assert.Equal(t, Return{1}, ret)
 
 }
+
+func TestServer_MetricsMiddleware(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Get("/metrics/{id}"), func(e env.Environment, r Request) (ret Response) {
+			return StatusUnauthorized
+		})
+	}, WithMiddleware(MetricsMiddleware))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	for _, id := range []string{"a", "b"} {
+		assert.Nil(t,
+			server.Connect().Call(
+				client.Get("/metrics/"+id),
+				client.ExpectCode(401)))
+	}
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, metrics.Default.WriteText(&buf)) {
+		return
+	}
+	assert.Contains(t, buf.String(), `stash_http_requests_total{method="GET",route="/metrics/{id}",code="401"} 2`)
+	assert.Contains(t, buf.String(), `stash_http_auth_failures_total{method="GET",route="/metrics/{id}",code="401"} 2`)
+	assert.Contains(t, buf.String(), `stash_http_request_duration_seconds_count{method="GET",route="/metrics/{id}"} 2`)
+}
+
+func TestServer_RateLimitMiddleware(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	rate, err := ParseRate("1/h")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Get("/limited"), func(e env.Environment, r Request) (ret Response) {
+			return StatusOK
+		})
+		s.Register(Get("/open"), func(e env.Environment, r Request) (ret Response) {
+			return StatusOK
+		})
+	}, WithMiddleware(RateLimitMiddleware(NewLimit("ip", rate, ByRemote, Get("/limited")))))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	cl := client.NewDefaultClient(server.Address().String(), client.WithBackoff(client.Backoff{}))
+	assert.Nil(t, cl.Call(client.Get("/limited"), client.ExpectCode(200)))
+	assert.Nil(t, cl.Call(client.Get("/open"), client.ExpectCode(200)))
+
+	var retry string
+	err = cl.Call(client.Get("/limited"), func(r client.Response) error {
+		r.ReadHeader("Retry-After", &retry)
+		return client.ExpectCode(200)(r)
+	})
+	assert.True(t, errors.Is(err, client.ErrTooMany))
+	assert.Equal(t, "3600", retry)
+}
+
+func TestServer_BodyLimitMiddleware(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Post("/small"), func(e env.Environment, r Request) (ret Response) {
+			var body []byte
+			if err := r.ReadBody(&body); err != nil {
+				return BadRequest(err)
+			}
+			return StatusOK
+		})
+		s.Register(Post("/large"), func(e env.Environment, r Request) (ret Response) {
+			var body []byte
+			if err := r.ReadBody(&body); err != nil {
+				return BadRequest(err)
+			}
+			return StatusOK
+		})
+	}, WithMiddleware(BodyLimitMiddleware(4, map[Route]int64{Post("/large"): 16})))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	post := func(path, body string) client.Request {
+		return client.BuildRequest(
+			client.WithMethod("POST"),
+			client.WithPath(path),
+			client.WithBodyRaw("text/plain", strings.NewReader(body)))
+	}
+
+	assert.Nil(t, server.Connect().Call(post("/small", "1234"), client.ExpectCode(200)))
+	assert.True(t, errors.Is(server.Connect().Call(post("/small", "12345"), client.ExpectCode(200)), client.ErrTooLarge))
+	assert.Nil(t, server.Connect().Call(post("/large", "12345"), client.ExpectCode(200)))
+}
+
+func TestServer_Backoff(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	attempts := 0
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Post("/flaky"), func(e env.Environment, r Request) (ret Response) {
+			var body []byte
+			if err := r.ReadBody(&body); err != nil || string(body) != "body" {
+				return StatusBadRequest
+			}
+			if attempts++; attempts < 3 {
+				return TooManyRequests(0)
+			}
+			return StatusOK
+		})
+	})
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	cl := client.NewDefaultClient(server.Address().String(),
+		client.WithBackoff(client.Backoff{Retries: 2, Max: time.Second}))
+	assert.Nil(t, cl.Call(
+		client.BuildRequest(
+			client.WithMethod("POST"),
+			client.WithPath("/flaky"),
+			client.WithBody("text/plain", []byte("body"))),
+		client.ExpectCode(200)))
+	assert.Equal(t, 3, attempts)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	ready, started, release := error(nil), make(chan struct{}), make(chan struct{})
+
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Get("/slow"), func(e env.Environment, r Request) (ret Response) {
+			close(started)
+			<-release
+			return StatusOK
+		})
+	}, WithReadiness("test", func() error {
+		return ready
+	}))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	cl := server.Connect()
+	assert.Nil(t, cl.Call(client.Get("/healthz"), client.ExpectCode(200)))
+	assert.Nil(t, cl.Call(client.Get("/readyz"), client.ExpectCode(200)))
+
+	ready = errors.New("unavailable")
+	assert.Nil(t, cl.Call(client.Get("/readyz"), client.ExpectCode(503)))
+	ready = nil
+
+	slow := make(chan error, 1)
+	go func() {
+		slow <- cl.Call(client.Get("/slow"), client.ExpectCode(200))
+	}()
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Shutdown(0, 5*time.Second)
+	}()
+
+	// Give the server a moment to begin draining.
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, server.health.isDraining())
+
+	close(release)
+	assert.Nil(t, <-slow)
+	assert.Nil(t, <-done)
+	assert.NotNil(t, cl.Call(client.Get("/healthz"), client.ExpectCode(200)))
+}
+
+func TestServer_ShutdownDelay(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	server, err := Serve(ctx, func(s *Service) {})
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	// Other servers of the handler are drained after the delay.
+	var drained time.Time
+	server.OnShutdown(func(stdctx.Context) error {
+		drained = time.Now()
+		return nil
+	})
+
+	cl := server.Connect()
+	assert.Nil(t, cl.Call(client.Get("/readyz"), client.ExpectCode(200)))
+
+	delay, start, done := 500*time.Millisecond, time.Now(), make(chan error, 1)
+	go func() {
+		done <- server.Shutdown(delay, 5*time.Second)
+	}()
+
+	// The server reports that it is not ready, but keeps serving new
+	// requests until the delay has passed.
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, cl.Call(client.Get("/readyz"), client.ExpectCode(503)))
+	assert.Nil(t, server.Connect().Call(client.Get("/healthz"), client.ExpectCode(200)))
+
+	assert.Nil(t, <-done)
+	assert.True(t, time.Since(start) >= delay)
+	assert.True(t, drained.Sub(start) >= delay)
+	assert.NotNil(t, server.Connect().Call(client.Get("/healthz"), client.ExpectCode(200)))
+}
+
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+type scopedDep struct {
+	log context.Logger
+}
+
+func (s *scopedDep) WithLogger(l context.Logger) interface{} {
+	return &scopedDep{l}
+}
+
+func TestServer_RequestIdMiddleware(t *testing.T) {
+	var out lockedBuffer
+
+	ctx := context.NewContextWithLogger(context.NewFormattedLogger(&out, context.Info, context.Logfmt, ""))
+	defer ctx.Close()
+
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Get("/accounts/{id}"), func(e env.Environment, r Request) (ret Response) {
+			var dep *scopedDep
+			e.Assign("dep", &dep)
+			dep.log.Info("From dependency")
+			return StatusOK
+		})
+	},
+		WithDependency("dep", &scopedDep{ctx.Logger()}),
+		WithMiddleware(RouteMiddleware, RequestIdMiddleware))
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	var id string
+	assert.Nil(t,
+		server.Connect().Call(
+			client.Get("/accounts/user@example.com"),
+			client.ExpectAll(
+				client.ExpectCode(200),
+				client.ExpectHeader(headers.RequestId, headers.String, &id))))
+	assert.NotEmpty(t, id)
+	assert.Contains(t, out.String(), `msg="GET /accounts/{id}" request_id=`+id)
+	assert.Contains(t, out.String(), `msg="From dependency" request_id=`+id)
+	assert.NotContains(t, out.String(), "user@example.com")
+
+	// Invalid ids are replaced.
+	for given, valid := range map[string]bool{"ticket-42": true, "not a valid id": false} {
+		assert.Nil(t,
+			server.Connect().Call(
+				client.BuildRequest(
+					client.WithMethod("GET"),
+					client.WithPath("/accounts/1"),
+					client.WithHeader(headers.RequestId, given)),
+				client.ExpectHeader(headers.RequestId, headers.String, &id)))
+		assert.Equal(t, valid, id == given)
+	}
+}
+
+func TestServer_Preconditions(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	var version int32 = 1
+	server, err := Serve(ctx, func(s *Service) {
+		s.Register(Get("/nodes/1"), func(e env.Environment, r Request) (ret Response) {
+			return Reply(StatusOK, WithETag(ETag(atomic.LoadInt32(&version))))
+		})
+		s.Register(Put("/nodes/{id}"), func(e env.Environment, r Request) (ret Response) {
+			var tag string
+			if id, _ := ReadPathParam(r, "id", ""); id == "1" {
+				tag = ETag(atomic.LoadInt32(&version))
+			}
+			if err := CheckPreconditions(r, tag); err != nil {
+				return PreconditionFailed(err)
+			}
+			return Reply(StatusNoContent, WithETag(ETag(atomic.AddInt32(&version, 1))))
+		})
+	})
+	if err != nil {
+		t.FailNow()
+	}
+	defer server.Close()
+
+	var tag string
+	assert.Nil(t,
+		server.Connect().Call(
+			client.Get("/nodes/1"),
+			client.ExpectHeader(headers.ETag, headers.String, &tag)))
+	assert.Equal(t, `"1"`, tag)
+
+	tests := []struct {
+		path string
+		cond client.Request
+		code int
+	}{
+		{"/nodes/1", client.WithIfMatch(`"0"`), http.StatusPreconditionFailed},
+		{"/nodes/1", client.WithIfMatch(`W/"1"`), http.StatusPreconditionFailed},
+		{"/nodes/1", client.WithIfMatch(`"0"`, `"1"`), http.StatusNoContent},
+		{"/nodes/1", client.WithIfMatch(`"1"`), http.StatusPreconditionFailed},
+		{"/nodes/1", client.WithIfMatch("*"), http.StatusNoContent},
+		{"/nodes/1", client.WithIfNoneMatch("*"), http.StatusPreconditionFailed},
+		{"/nodes/1", client.WithIfNoneMatch(`W/"3"`), http.StatusPreconditionFailed},
+		{"/nodes/2", client.WithIfMatch("*"), http.StatusPreconditionFailed},
+		{"/nodes/2", client.WithIfNoneMatch("*"), http.StatusNoContent},
+		{"/nodes/1", client.BuildRequest(), http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		err := server.Connect().Call(
+			client.BuildRequest(client.Put(test.path), test.cond),
+			client.ExpectCode(test.code))
+		assert.Nil(t, err, "%v %v", test.path, test.code)
+	}
+
+	err = server.Connect().Call(
+		client.BuildRequest(client.Put("/nodes/1"), client.WithIfMatch(`"0"`)),
+		client.ExpectCode(http.StatusNoContent))
+	assert.True(t, errs.Is(err, errs.Conflict), "%v", err)
+	assert.True(t, errs.Is(err, client.ErrPrecondition), "%v", err)
+}
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/headers"
+	"github.com/cott-io/stash/lang/metrics"
+	"github.com/pkg/errors"
+)
+
+var rateLimitedTotal = metrics.NewCounter(
+	"stash_http_rate_limited_total", "Number of http requests rejected by a rate limit", "limit", "method", "route")
+
+// A rate is a number of requests per second, allowing bursts of up
+// to some number of requests.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Parses a rate of the form <n>/<unit>, where the unit is one of
+// s, m or h (e.g. 20/s or 600/m).  The burst is the number of requests
+// allowed per unit.
+func ParseRate(str string) (ret Rate, err error) {
+	parts := strings.SplitN(strings.TrimSpace(str), "/", 2)
+	if len(parts) != 2 {
+		err = errors.Wrapf(errs.ArgError, "Invalid rate [%v]. Expected <n>/<s|m|h>", str)
+		return
+	}
+
+	num, err := strconv.Atoi(parts[0])
+	if err != nil || num < 1 {
+		err = errors.Wrapf(errs.ArgError, "Invalid rate [%v]. Expected a positive number of requests", str)
+		return
+	}
+
+	var unit time.Duration
+	switch parts[1] {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid rate [%v]. Expected a unit of [s, m, h]", str)
+		return
+	case "s":
+		unit = time.Second
+	case "m":
+		unit = time.Minute
+	case "h":
+		unit = time.Hour
+	}
+
+	ret = Rate{float64(num) / unit.Seconds(), num}
+	return
+}
+
+// A limiter maintains a token bucket per key.  Buckets start full and
+// are refilled at the rate, so a key may burst up to the rate's burst
+// before being limited.
+type Limiter struct {
+	rate Rate
+	now  func() time.Time
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	sweepAt int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{rate: rate, now: time.Now, buckets: make(map[string]*bucket), sweepAt: 1024}
+}
+
+// Takes a token from the key's bucket.  If the bucket is empty, returns
+// false along with the time until a token is available.
+func (l *Limiter) Allow(key string) (ok bool, wait time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= l.sweepAt {
+		l.sweep(now)
+	}
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{float64(l.rate.Burst), now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.rate.Burst), b.tokens+now.Sub(b.last).Seconds()*l.rate.PerSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait = time.Duration((1 - b.tokens) / l.rate.PerSecond * float64(time.Second))
+	return
+}
+
+// Drops the buckets that would have refilled by now, as they are
+// indistinguishable from new ones.
+func (l *Limiter) sweep(now time.Time) {
+	full := time.Duration(float64(l.rate.Burst) / l.rate.PerSecond * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.sweepAt = len(l.buckets) * 2
+	if l.sweepAt < 1024 {
+		l.sweepAt = 1024
+	}
+}
+
+// A key func returns the key by which a request is limited.  Requests
+// without a key are not limited.
+type KeyFunc func(env.Environment, Request) (string, bool)
+
+// Keys requests by the host of their remote address.
+func ByRemote(_ env.Environment, req Request) (string, bool) {
+	host, _, err := net.SplitHostPort(req.Remote())
+	if err != nil {
+		return req.Remote(), req.Remote() != ""
+	}
+	return host, true
+}
+
+// Keys requests by one of their path parameters (e.g. orgId).
+func ByPathParam(name string) KeyFunc {
+	return func(_ env.Environment, req Request) (ret string, ok bool) {
+		ok, err := req.ReadPathParam(name, &ret)
+		return ret, ok && err == nil
+	}
+}
+
+// A limit applies a rate to the requests sharing a key.  If routes are
+// given, only requests of those routes count against the limit.
+type Limit struct {
+	Name    string
+	Key     KeyFunc
+	Routes  []Route
+	limiter *Limiter
+}
+
+func NewLimit(name string, rate Rate, key KeyFunc, routes ...Route) Limit {
+	return Limit{name, key, routes, NewLimiter(rate)}
+}
+
+func (l Limit) applies(route Route) bool {
+	if len(l.Routes) == 0 {
+		return true
+	}
+	for _, r := range l.Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// Rejects requests exceeding any of the limits with a 429, telling the
+// client when it may retry.
+func RateLimitMiddleware(limits ...Limit) Middleware {
+	return func(h Handler) Handler {
+		return func(e env.Environment, req Request) Response {
+			route := req.Route()
+			for _, l := range limits {
+				if !l.applies(route) {
+					continue
+				}
+
+				key, ok := l.Key(e, req)
+				if !ok {
+					continue
+				}
+
+				if ok, wait := l.limiter.Allow(key); !ok {
+					e.Logger().Info("Rate limit [%v] exceeded by [%v] on [%v %v]", l.Name, key, route.Method, route.Path)
+					rateLimitedTotal.Inc(l.Name, route.Method, route.Path)
+					return TooManyRequests(wait)
+				}
+			}
+			return h(e, req)
+		}
+	}
+}
+
+// Caps the size of request bodies.  Routes without a cap of their own
+// use the default.  Caps less than one are unlimited.
+func BodyLimitMiddleware(def int64, routes map[Route]int64) Middleware {
+	return func(h Handler) Handler {
+		return func(e env.Environment, req Request) Response {
+			max, ok := routes[req.Route()]
+			if !ok {
+				max = def
+			}
+			if max < 1 {
+				return h(e, req)
+			}
+
+			var size int64
+			if ok, err := ParseHeader(req, headers.ContentLength, Int64, &size); err == nil && ok && size > max {
+				return TooLarge(max)
+			}
+
+			limited := &limitedRequest{Request: req, body: &limitedReader{r: req, n: max}}
+			resp := h(e, limited)
+			if limited.body.exceeded {
+				return TooLarge(max)
+			}
+			return resp
+		}
+	}
+}
+
+// A request whose body fails once it exceeds a number of bytes.
+type limitedRequest struct {
+	Request
+	body *limitedReader
+}
+
+func (r *limitedRequest) Read(p []byte) (int, error) {
+	return r.body.Read(p)
+}
+
+func (r *limitedRequest) ReadBody(ptr *[]byte) (err error) {
+	defer func() {
+		err = errs.Or(err, r.Close())
+	}()
+	*ptr, err = ioutil.ReadAll(r.body)
+	return
+}
+
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (n int, err error) {
+	if l.n < 0 {
+		l.exceeded = true
+		return 0, errors.Wrapf(errs.ArgError, "Request body too large")
+	}
+
+	// Read one byte past the limit in order to detect bodies that
+	// exceed it.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err = l.r.Read(p)
+	if l.n -= int64(n); l.n < 0 {
+		l.exceeded = true
+		return n, errors.Wrapf(errs.ArgError, "Request body too large")
+	}
+	return
+}
+
+// Rejects a request as too large for the limit.
+func TooLarge(max int64) Response {
+	return Reply(StatusTooLarge, WithMessage(fmt.Sprintf("Request body exceeds [%v] bytes", max)))
+}
+
+// Rejects a request as exceeding a rate limit.  The client may retry
+// once the duration has passed.
+func TooManyRequests(wait time.Duration) Response {
+	return Reply(
+		WithHeader(headers.RetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds())))),
+		StatusTooManyRequests)
+}
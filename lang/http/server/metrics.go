@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/metrics"
+)
+
+// Request metrics are labeled by route, rather than by url, so that
+// the number of series is bounded by the number of routes.
+var (
+	requestsTotal = metrics.NewCounter(
+		"stash_http_requests_total", "Number of http requests handled", "method", "route", "code")
+	requestDuration = metrics.NewHistogram(
+		"stash_http_request_duration_seconds", "Duration of http requests", metrics.DefaultBuckets, "method", "route")
+	authFailuresTotal = metrics.NewCounter(
+		"stash_http_auth_failures_total", "Number of http requests rejected as unauthorized or forbidden", "method", "route", "code")
+)
+
+// Records the count, duration and status code of requests.
+func MetricsMiddleware(h Handler) Handler {
+	return func(e env.Environment, req Request) Response {
+		start, route := time.Now(), req.Route()
+
+		record := func(code int) {
+			method, status := route.Method, strconv.Itoa(code)
+			requestsTotal.Inc(method, route.Path, status)
+			requestDuration.Observe(time.Since(start).Seconds(), method, route.Path)
+			if code == http.StatusUnauthorized || code == http.StatusForbidden {
+				authFailuresTotal.Inc(method, route.Path, status)
+			}
+		}
+
+		fn := h(e, req)
+		if fn == nil {
+			record(http.StatusOK)
+			return nil
+		}
+
+		return func(r ResponseBuilder) (err error) {
+			rec := &codeRecorder{ResponseBuilder: r, code: http.StatusInternalServerError}
+			if err = fn(rec); err != nil {
+				rec.code = http.StatusInternalServerError
+			}
+			record(rec.code)
+			return
+		}
+	}
+}
+
+// Captures the code set on a response builder.
+type codeRecorder struct {
+	ResponseBuilder
+	code int
+}
+
+func (c *codeRecorder) SetCode(code int) {
+	c.code = code
+	c.ResponseBuilder.SetCode(code)
+}
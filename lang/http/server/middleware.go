@@ -20,7 +20,7 @@ func recoveryHandler(h Handler) Handler {
 	return func(e env.Environment, req Request) (resp Response) {
 		defer func() {
 			if msg := recover(); msg != nil {
-				e.Logger().Error("Handler panic [%v %v]: [%+v]", req.Method(), req.URL(), msg)
+				e.Logger().Error("Handler panic [%v %v]: [%+v]", req.Method(), req.Route().Path, msg)
 				resp = Reply(StatusPanic)
 			}
 		}()
@@ -39,9 +39,11 @@ func TimerMiddleware(h Handler) Handler {
 	}
 }
 
+// Logs the route of each request.  Requests are logged by the paths of
+// their routes, rather than their urls, which may contain identities.
 func RouteMiddleware(h Handler) Handler {
 	return func(e env.Environment, req Request) Response {
-		e.Logger().Info("%v %v", req.Method(), req.URL())
+		e.Logger().Info("%v %v", req.Method(), req.Route().Path)
 		return h(e, req)
 	}
 }
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"regexp"
+
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/http/headers"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The field that carries the id of a request in logs.
+const RequestIdField = "request_id"
+
+// Request ids given by clients are only accepted if they are short
+// and safe to log.
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Correlates the logs of a request by its id.  The id is accepted from
+// the X-Request-Id header, or generated if none (or an invalid one) is
+// given, and is returned in the header of the response.  Handlers are
+// given an environment whose logger, and whose dependencies that log
+// (e.g. sql stores and mail clients), carry the id.
+func RequestIdMiddleware(h Handler) Handler {
+	return func(e env.Environment, req Request) Response {
+		var id string
+		if !req.ReadHeader(headers.RequestId, &id) || !validRequestId.MatchString(id) {
+			id = uuid.NewV4().String()
+		}
+
+		resp := h(env.WithLogger(e, e.Logger().With(RequestIdField, id)), req)
+		if resp == nil {
+			resp = StatusOK
+		}
+		return Reply(WithHeader(headers.RequestId, id), resp)
+	}
+}
@@ -25,6 +25,8 @@ var (
 	StatusTimeout            = Reply(WithCode(http.StatusRequestTimeout), WithMessage("Found"))
 	StatusForbidden          = Reply(WithCode(http.StatusForbidden), WithMessage("Forbidden"))
 	StatusUnauthorized       = Reply(WithCode(http.StatusUnauthorized), WithMessage("Unauthorized"))
+	StatusTooLarge           = Reply(WithCode(http.StatusRequestEntityTooLarge), WithMessage("Request Entity Too Large"))
+	StatusTooManyRequests    = Reply(WithCode(http.StatusTooManyRequests), WithMessage("Too Many Requests"))
 	StatusPanic              = Reply(WithCode(http.StatusInternalServerError), WithMessage("Internal Server Error"))
 )
 
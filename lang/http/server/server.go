@@ -1,10 +1,14 @@
 package server
 
 import (
+	stdctx "context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/http/client"
 	"github.com/cott-io/stash/lang/net"
 	"github.com/gorilla/mux"
@@ -20,6 +24,7 @@ type Options struct {
 	ListenerFunc func() (net.Listener, error)
 	Middleware   []Middleware
 	Dependencies map[string]interface{}
+	Checks       []namedCheck
 }
 
 func buildOptions(fns ...Option) (opts Options) {
@@ -57,24 +62,33 @@ func WithMiddleware(middleware ...Middleware) Option {
 type Server struct {
 	env      env.Environment
 	listener net.Listener
+	server   *http.Server
+	health   *health
+
+	lock     sync.Mutex
+	shutdown []func(stdctx.Context) error
 }
 
 func Serve(ctx context.Context, builder ServiceBuilder, fns ...Option) (ret *Server, err error) {
 	opts := buildOptions(fns...)
 
-	svc, middleware :=
-		buildService(builder), buildMiddleware(opts.Middleware...)
+	svc, middleware, health :=
+		buildService(builder), buildMiddleware(opts.Middleware...), &health{checks: opts.Checks}
+	health.register(svc)
 
 	listener, err := opts.ListenerFunc()
 	if err != nil {
 		return
 	}
 
+	// The http server owns the listener, closing it along with any
+	// open connections.
+	server := &http.Server{}
 	env := env.NewEnvironment(
 		ctx.Sub("Http(%v)", listener.Address().String()), env.WithDependencies(opts.Dependencies))
 	env.Control().Defer(func(err error) {
-		if err := listener.Close(); err != nil {
-			env.Logger().Error("Unable to close listener [%+v]", err)
+		if err := server.Close(); err != nil {
+			env.Logger().Error("Unable to close server [%+v]", err)
 		}
 	})
 
@@ -83,10 +97,10 @@ func Serve(ctx context.Context, builder ServiceBuilder, fns ...Option) (ret *Ser
 	for route, handler := range svc.routes {
 		env.Logger().Info("Adding route [%v %v]", route.Method, route.Path)
 
-		handler := handler
+		route, handler := route, handler
 		router.Handle(route.Path,
 			http.HandlerFunc(func(rawResp http.ResponseWriter, rawReq *http.Request) {
-				req, resp := newRequest(rawReq), newResponseBuilder()
+				req, resp := newRequest(rawReq, route), newResponseBuilder()
 				defer func() {
 					if err := resp.Build(rawResp); err != nil {
 						env.Logger().Error("Error writing response from [%v %v]: %+v", route.Method, route.Path, err)
@@ -107,24 +121,80 @@ func Serve(ctx context.Context, builder ServiceBuilder, fns ...Option) (ret *Ser
 			})).Methods(route.Method)
 	}
 
+	server.Handler = router
 	go func() {
 		env.Logger().Info("Starting")
-		env.Control().Fail(http.Serve(net.GoListener(listener), router))
+		if err := server.Serve(net.GoListener(listener)); err != http.ErrServerClosed {
+			env.Control().Fail(err)
+		}
 		env.Logger().Info("Stopping")
 	}()
 
-	ret = &Server{env, listener}
+	ret = &Server{env: env, listener: listener, server: server, health: health}
 	return
 }
 
+// Closes the server immediately, dropping any in-flight requests.
 func (s *Server) Close() error {
 	return s.env.Close()
 }
 
+// Registers a function to call on shutdown, once the server stops
+// accepting connections.  The function is given the deadline by which
+// requests must complete, and the server is not closed until it returns.
+// Other servers of the same handler (e.g. grpc) are drained this way.
+func (s *Server) OnShutdown(fn func(stdctx.Context) error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shutdown = append(s.shutdown, fn)
+}
+
+// Gracefully shuts down the server.  The server reports that it is
+// not ready and keeps serving for the delay, giving load balancers time
+// to notice.  It then stops accepting connections, and waits up to the
+// timeout for in-flight requests to complete before closing.
+func (s *Server) Shutdown(delay, timeout time.Duration) (err error) {
+	s.health.drain()
+	if delay > 0 {
+		s.env.Logger().Info("Waiting to drain requests [delay=%v]", delay)
+		time.Sleep(delay)
+	}
+
+	s.env.Logger().Info("Draining requests [timeout=%v]", timeout)
+
+	ctx, cancel := stdctx.WithTimeout(stdctx.Background(), timeout)
+	defer cancel()
+
+	s.lock.Lock()
+	hooks := s.shutdown
+	s.lock.Unlock()
+
+	done := make(chan error, len(hooks))
+	for _, fn := range hooks {
+		go func(fn func(stdctx.Context) error) {
+			done <- fn(ctx)
+		}(fn)
+	}
+
+	if err = s.server.Shutdown(ctx); err != nil {
+		s.env.Logger().Error("Unable to drain requests. Closing: %v", err)
+		s.server.Close()
+	}
+	for range hooks {
+		err = errs.Or(err, <-done)
+	}
+	return errs.Or(err, s.Close())
+}
+
 func (s *Server) Address() net.Address {
 	return s.listener.Address()
 }
 
+// Returns the handler of the server's routes, including its middleware.
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
 func (s *Server) Connect() client.Client {
 	return client.NewDefaultClient(s.listener.Address().String())
 }
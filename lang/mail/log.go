@@ -0,0 +1,36 @@
+package mail
+
+import "github.com/cott-io/stash/lang/context"
+
+type loggedClient struct {
+	Client
+	log context.Logger
+}
+
+// Returns a client that logs each mail it sends.  When assigned from
+// the environment of a request, the client logs with its logger.
+func NewLoggedClient(c Client, l context.Logger) Client {
+	return &loggedClient{c, l.Fmt("Mail")}
+}
+
+func (c *loggedClient) WithLogger(l context.Logger) interface{} {
+	return NewLoggedClient(c.Client, l)
+}
+
+func (c *loggedClient) Send(to, subject, msg string) (err error) {
+	defer c.logged(to, subject, &err)
+	return c.Client.Send(to, subject, msg)
+}
+
+func (c *loggedClient) SendHtml(to, subject, plain, html string) (err error) {
+	defer c.logged(to, subject, &err)
+	return c.Client.SendHtml(to, subject, plain, html)
+}
+
+func (c *loggedClient) logged(to, subject string, err *error) {
+	if *err != nil {
+		c.log.Error("Error sending mail [to=%v, subject=%v]: %v", to, subject, *err)
+		return
+	}
+	c.log.Info("Sent mail [to=%v, subject=%v]", to, subject)
+}
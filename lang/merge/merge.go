@@ -0,0 +1,179 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// This package implements a line based three-way merge, in the manner
+// of diff3.  Two edits of a common base are merged by taking every
+// change that was made on only one side.  Regions changed differently
+// on both sides are conflicts, and are written with conflict markers
+// for the user to resolve.
+
+// The markers that surround conflicting regions.
+const (
+	MarkerOurs   = "<<<<<<<"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>>"
+)
+
+type Options struct {
+	Ours   string
+	Theirs string
+}
+
+type Option func(*Options)
+
+func buildOptions(fns ...Option) (ret Options) {
+	ret = Options{Ours: "ours", Theirs: "theirs"}
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+// Labels the sides of conflicts.
+func WithLabels(ours, theirs string) Option {
+	return func(o *Options) {
+		o.Ours, o.Theirs = ours, theirs
+	}
+}
+
+// Merges two edits of a base, returning the merged value and the
+// number of conflicts written into it.
+func Merge(base, ours, theirs []byte, fns ...Option) (ret []byte, conflicts int) {
+	opts := buildOptions(fns...)
+
+	o, a, b := lines(base), lines(ours), lines(theirs)
+	ma, mb := matches(o, a), matches(o, b)
+
+	buf := &bytes.Buffer{}
+
+	// Walk the lines of the base that are unchanged on both sides.
+	// Everything between them has been changed on at least one.
+	var i, j, k int
+	for {
+		next := i
+		for next < len(o) && (ma[next] < 0 || mb[next] < 0) {
+			next++
+		}
+
+		nj, nk := len(a), len(b)
+		if next < len(o) {
+			nj, nk = ma[next], mb[next]
+		}
+
+		if !resolve(buf, o[i:next], a[j:nj], b[k:nk], opts) {
+			conflicts++
+		}
+
+		if next == len(o) {
+			break
+		}
+
+		buf.WriteString(o[next])
+		i, j, k = next+1, nj+1, nk+1
+	}
+
+	ret = buf.Bytes()
+	return
+}
+
+// Writes the resolution of a changed region, returning false if the
+// region is a conflict.
+func resolve(buf *bytes.Buffer, o, a, b []string, opts Options) bool {
+	switch {
+	case equal(a, o):
+		write(buf, b...)
+		return true
+	case equal(b, o), equal(a, b):
+		write(buf, a...)
+		return true
+	}
+
+	fmt.Fprintf(buf, "%v %v\n", MarkerOurs, opts.Ours)
+	writeLines(buf, a)
+	fmt.Fprintf(buf, "%v\n", MarkerSep)
+	writeLines(buf, b)
+	fmt.Fprintf(buf, "%v %v\n", MarkerTheirs, opts.Theirs)
+	return false
+}
+
+func write(buf *bytes.Buffer, lines ...string) {
+	for _, l := range lines {
+		buf.WriteString(l)
+	}
+}
+
+// Writes the lines, terminating the last so that a marker may follow.
+func writeLines(buf *bytes.Buffer, lines []string) {
+	write(buf, lines...)
+	if n := len(lines); n > 0 && lines[n-1][len(lines[n-1])-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Splits the value into lines, retaining their terminators.
+func lines(val []byte) (ret []string) {
+	for len(val) > 0 {
+		i := bytes.IndexByte(val, '\n')
+		if i < 0 {
+			ret = append(ret, string(val))
+			break
+		}
+		ret = append(ret, string(val[:i+1]))
+		val = val[i+1:]
+	}
+	return
+}
+
+// Returns, for every line of a, the index of its match in b or -1 if it
+// has none.  Matches are those of a longest common subsequence, so are
+// in increasing order.
+func matches(a, b []string) (ret []int) {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ret = make([]int, len(a))
+	for i := range ret {
+		ret[i] = -1
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			ret[i] = j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return
+}
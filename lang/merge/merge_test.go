@@ -0,0 +1,48 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	base := "a=1\nb=2\nc=3\nd=4\n"
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		merged    string
+		conflicts int
+	}{
+		{"Unchanged", base, base, base, 0},
+		{"OursOnly", "a=1\nb=20\nc=3\nd=4\n", base, "a=1\nb=20\nc=3\nd=4\n", 0},
+		{"TheirsOnly", base, "a=1\nc=3\nd=4\ne=5\n", "a=1\nc=3\nd=4\ne=5\n", 0},
+		{"Disjoint", "a=10\nb=2\nc=3\nd=4\n", "a=1\nb=2\nc=3\nd=40\ne=5\n", "a=10\nb=2\nc=3\nd=40\ne=5\n", 0},
+		{"Same", "a=1\nb=20\nc=3\nd=4\n", "a=1\nb=20\nc=3\nd=4\n", "a=1\nb=20\nc=3\nd=4\n", 0},
+		{"Conflict", "a=1\nb=20\nc=3\nd=4\n", "a=1\nb=21\nc=3\nd=40\n",
+			"a=1\n<<<<<<< ours\nb=20\n=======\nb=21\n>>>>>>> theirs\nc=3\nd=40\n", 1},
+		{"Unterminated", "a=1\nb=2\nc=3\nd=5", "a=1\nb=2\nc=3\nd=6",
+			"a=1\nb=2\nc=3\n<<<<<<< ours\nd=5\n=======\nd=6\n>>>>>>> theirs\n", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := Merge([]byte(base), []byte(test.ours), []byte(test.theirs))
+			assert.Equal(t, test.merged, string(merged))
+			assert.Equal(t, test.conflicts, conflicts)
+		})
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		merged, conflicts := Merge(nil, []byte("a=1\n"), nil)
+		assert.Equal(t, "a=1\n", string(merged))
+		assert.Equal(t, 0, conflicts)
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		merged, _ := Merge(nil, []byte("a=1\n"), []byte("a=2\n"), WithLabels("yours", "version 3"))
+		assert.Equal(t, "<<<<<<< yours\na=1\n=======\na=2\n>>>>>>> version 3\n", string(merged))
+	})
+}
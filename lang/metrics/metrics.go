@@ -0,0 +1,296 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// This package implements a minimal set of metrics (counters, gauges
+// and histograms), along with their exposition in the prometheus
+// text format.  Metrics are partitioned by a fixed set of labels.
+// Label values should be drawn from small, bounded sets, as every
+// distinct combination is kept for the life of the process.
+
+const (
+	CounterType   = "counter"
+	GaugeType     = "gauge"
+	HistogramType = "histogram"
+)
+
+// The default buckets of histograms of latencies, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// The registry to which the package level constructors add metrics.
+var Default = NewRegistry()
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.Counter(name, help, labels...)
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.Gauge(name, help, labels...)
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.Histogram(name, help, buckets, labels...)
+}
+
+// A registry is a named set of metrics.
+type Registry struct {
+	lock     sync.RWMutex
+	families map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Returns the family of the name, creating it if necessary.  Panics
+// if the name is already registered with another type or labels.
+func (r *Registry) register(name, help, typ string, buckets []float64, labels []string) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if cur, ok := r.families[name]; ok {
+		if cur.typ != typ || strings.Join(cur.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("Metric [%v] already registered as a %v%v", name, cur.typ, cur.labels))
+		}
+		return cur
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series)}
+	r.families[name] = f
+	return f
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, CounterType, nil, labels)}
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, GaugeType, nil, labels)}
+}
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{r.register(name, help, HistogramType, buckets, labels)}
+}
+
+// Writes every metric in the prometheus text format.
+func (r *Registry) WriteText(w io.Writer) (err error) {
+	r.lock.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.lock.RUnlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	buf := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Serves the metrics in the prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+type family struct {
+	name, help, typ string
+	labels          []string
+	buckets         []float64
+
+	lock   sync.RWMutex
+	series map[string]*series
+}
+
+// Returns the series of the label values, creating it if necessary.
+// Missing values are empty and extra values are ignored.
+func (f *family) with(values []string) *series {
+	vals := make([]string, len(f.labels))
+	copy(vals, values)
+
+	key := strings.Join(vals, "\xff")
+
+	f.lock.RLock()
+	s, ok := f.series[key]
+	f.lock.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if s, ok = f.series[key]; ok {
+		return s
+	}
+
+	s = &series{values: vals, counts: make([]uint64, len(f.buckets))}
+	f.series[key] = s
+	return s
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.lock.RLock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.lock.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+
+	fmt.Fprintf(w, "# HELP %v %v\n", f.name, escape(f.help, false))
+	fmt.Fprintf(w, "# TYPE %v %v\n", f.name, f.typ)
+	for _, s := range all {
+		s.lock.Lock()
+		value, sum, count, counts :=
+			s.value, s.sum, s.count, append([]uint64{}, s.counts...)
+		s.lock.Unlock()
+
+		if f.typ != HistogramType {
+			fmt.Fprintf(w, "%v%v %v\n", f.name, f.format(s.values), formatFloat(value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, b := range f.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, f.format(s.values, "le", formatFloat(b)), cumulative)
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, f.format(s.values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", f.name, f.format(s.values), formatFloat(sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", f.name, f.format(s.values), count)
+	}
+}
+
+// Formats the labels of a series, along with any extra name/value
+// pairs.
+func (f *family) format(values []string, extra ...string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", name, escape(values[i], true)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", extra[i], escape(extra[i+1], true)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+type series struct {
+	values []string
+
+	lock   sync.Mutex
+	value  float64  // counters and gauges
+	sum    float64  // histograms
+	count  uint64   // histograms
+	counts []uint64 // histograms, per bucket
+}
+
+// A counter is a value that only increases.
+type Counter struct {
+	f *family
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Adds the delta to the series of the label values.  Negative deltas
+// are ignored.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+
+	s := c.f.with(values)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.value += delta
+}
+
+// A gauge is a value that may go up and down.
+type Gauge struct {
+	f *family
+}
+
+func (g *Gauge) Set(val float64, values ...string) {
+	s := g.f.with(values)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.value = val
+}
+
+func (g *Gauge) Add(delta float64, values ...string) {
+	s := g.f.with(values)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.value += delta
+}
+
+// A histogram counts observations into buckets.
+type Histogram struct {
+	f *family
+}
+
+func (h *Histogram) Observe(val float64, values ...string) {
+	s := h.f.with(values)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sum += val
+	s.count++
+	for i, b := range h.f.buckets {
+		if val <= b {
+			s.counts[i]++
+			break
+		}
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// Escapes a help string or label value.  Only label values escape
+// quotes.
+func escape(str string, quotes bool) string {
+	r := strings.NewReplacer("\\", `\\`, "\n", `\n`)
+	if quotes {
+		r = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+	}
+	return r.Replace(str)
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("requests_total", "Requests served", "route", "code")
+	requests.Inc("/v1/secrets", "200")
+	requests.Inc("/v1/secrets", "200")
+	requests.Add(3, "/v1/\"quoted\"", "401")
+	requests.Add(-1, "/v1/secrets", "200")
+
+	conns := r.Gauge("connections", "Open connections")
+	conns.Set(5)
+	conns.Add(-2)
+
+	latency := r.Histogram("latency_seconds", "Request latency", []float64{1, .1})
+	latency.Observe(.05)
+	latency.Observe(.5)
+	latency.Observe(5)
+
+	var buf bytes.Buffer
+	if !assert.Nil(t, r.WriteText(&buf)) {
+		return
+	}
+
+	assert.Equal(t, `# HELP connections Open connections
+# TYPE connections gauge
+connections 3
+# HELP latency_seconds Request latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 5.55
+latency_seconds_count 3
+# HELP requests_total Requests served
+# TYPE requests_total counter
+requests_total{route="/v1/\"quoted\"",code="401"} 3
+requests_total{route="/v1/secrets",code="200"} 2
+`, buf.String())
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	a := r.Counter("total", "", "route")
+	b := r.Counter("total", "", "route")
+	a.Inc("x")
+	b.Inc("x")
+
+	var buf bytes.Buffer
+	assert.Nil(t, r.WriteText(&buf))
+	assert.Contains(t, buf.String(), `total{route="x"} 2`)
+
+	assert.Panics(t, func() {
+		r.Gauge("total", "", "route")
+	})
+}
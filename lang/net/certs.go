@@ -0,0 +1,203 @@
+package net
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+)
+
+const pinPrefix = "sha256/"
+
+// A cert store holds the certificate of a server and, optionally,
+// the pool of authorities trusted to sign client certificates.  The
+// files are read again on each reload, so that certificates may be
+// rotated without restarting the server.
+type CertStore struct {
+	certFile, keyFile, caFile string
+
+	lock sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+func NewCertStore(certFile, keyFile, caFile string) (ret *CertStore, err error) {
+	ret = &CertStore{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	err = ret.Reload()
+	return
+}
+
+// Reads the certificate files again.  On failure, the previously
+// loaded certificates remain in use.
+func (c *CertStore) Reload() (err error) {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Error loading certificate [%v]: %v", c.certFile, err)
+		return
+	}
+
+	var pool *x509.CertPool
+	if c.caFile != "" {
+		if pool, err = ReadCertPool(c.caFile); err != nil {
+			return
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cert, c.pool = &cert, pool
+	return
+}
+
+// Returns whether client certificates are verified.
+func (c *CertStore) VerifiesClients() bool {
+	return c.caFile != ""
+}
+
+// Returns a server config that always uses the most recently loaded
+// certificates.  If a client authority was given, clients are asked
+// for certificates, which are verified according to the auth type.
+func (c *CertStore) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.lock.RLock()
+			defer c.lock.RUnlock()
+
+			ret := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*c.cert},
+			}
+			if c.pool != nil {
+				ret.ClientCAs, ret.ClientAuth = c.pool, clientAuth
+			}
+			return ret, nil
+		},
+	}
+}
+
+// Reads a bundle of PEM encoded certificates.
+func ReadCertPool(file string) (ret *x509.CertPool, err error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Error reading certificate bundle [%v]: %v", file, err)
+		return
+	}
+
+	ret = x509.NewCertPool()
+	if !ret.AppendCertsFromPEM(raw) {
+		err = errors.Wrapf(errs.ArgError, "No certificates found in bundle [%v]", file)
+	}
+	return
+}
+
+// Returns the identity of a certificate.  This is the first URI,
+// DNS name or email of the subject alternative names, in that order,
+// falling back to the common name of the subject.
+func CertIdentity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	default:
+		return cert.Subject.CommonName
+	}
+}
+
+// Returns the pin of the certificate's public key (e.g. sha256/<base64>).
+func CertPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Verifies that the certificates presented by a peer match one of the
+// pins.  When the chain was verified, any certificate of a verified chain
+// may match.  Otherwise only the leaf may match, since the rest of an
+// unverified chain is whatever the peer chose to send.
+func VerifyPins(pins ...string) func([][]byte, [][]*x509.Certificate) error {
+	matches := func(cert *x509.Certificate) bool {
+		pin := CertPin(cert)
+		for _, cur := range pins {
+			if strings.TrimSpace(cur) == pin {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(raw [][]byte, verified [][]*x509.Certificate) error {
+		for _, chain := range verified {
+			for _, cert := range chain {
+				if matches(cert) {
+					return nil
+				}
+			}
+		}
+
+		if len(verified) == 0 && len(raw) > 0 {
+			leaf, err := x509.ParseCertificate(raw[0])
+			if err != nil {
+				return err
+			}
+			if matches(leaf) {
+				return nil
+			}
+		}
+		return errors.Errorf("No certificate matches the pinned keys %v", pins)
+	}
+}
+
+// The tls options of a client.
+type ClientTLS struct {
+	CAFile   string   // authorities trusted to sign the server's certificate
+	Pins     []string // server public keys that are accepted
+	CertFile string   // certificate presented to servers requiring one
+	KeyFile  string
+}
+
+func (c ClientTLS) Empty() bool {
+	return c.CAFile == "" && len(c.Pins) == 0 && c.CertFile == ""
+}
+
+// Builds the config of a client.  When pins are given without an
+// authority, the server's chain is not verified, so self-signed
+// certificates may be pinned directly.
+func (c ClientTLS) Config() (ret *tls.Config, err error) {
+	ret = &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		if ret.RootCAs, err = ReadCertPool(c.CAFile); err != nil {
+			return
+		}
+	}
+
+	if len(c.Pins) > 0 {
+		for _, pin := range c.Pins {
+			if !strings.HasPrefix(strings.TrimSpace(pin), pinPrefix) {
+				err = errors.Wrapf(errs.ArgError, "Invalid pin [%v]. Expected %v<base64>", pin, pinPrefix)
+				return
+			}
+		}
+
+		ret.VerifyPeerCertificate = VerifyPins(c.Pins...)
+		ret.InsecureSkipVerify = c.CAFile == ""
+	}
+
+	if c.CertFile != "" {
+		cert, e := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if e != nil {
+			err = errors.Wrapf(errs.ArgError, "Error loading certificate [%v]: %v", c.CertFile, e)
+			return
+		}
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return
+}
@@ -0,0 +1,219 @@
+package net
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Writes a self-signed certificate and key to the directory.
+func writeCert(t *testing.T, dir, name string, fn func(*x509.Certificate)) (cert *x509.Certificate, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+	}
+	if fn != nil {
+		fn(tmpl)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	certFile, keyFile =
+		filepath.Join(dir, name+".pem"),
+		filepath.Join(dir, name+".key")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600))
+
+	cert, err = x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return
+}
+
+// Completes a handshake with the listener, returning the certificates
+// presented by the client and the server.
+func handshake(t *testing.T, l Listener, c *tls.Config) (client, server []*x509.Certificate, err error) {
+	done := make(chan []*x509.Certificate, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer conn.Close()
+
+		raw := conn.(*tls.Conn)
+		if err := raw.Handshake(); err != nil {
+			done <- nil
+			return
+		}
+		raw.Write([]byte{1})
+		done <- raw.ConnectionState().PeerCertificates
+	}()
+
+	conn, err := tls.Dial("tcp", l.Address().String(), c)
+	if err != nil {
+		<-done
+		return
+	}
+	defer conn.Close()
+
+	// Under tls 1.3, a rejected client certificate is only reported
+	// once the client reads.
+	if _, err = conn.Read(make([]byte, 1)); err != nil {
+		<-done
+		return
+	}
+
+	server, client = conn.ConnectionState().PeerCertificates, <-done
+	return
+}
+
+func TestCertStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	first, certFile, keyFile := writeCert(t, dir, "server", nil)
+	agent, agentCert, agentKey := writeCert(t, dir, "agent", func(c *x509.Certificate) {
+		c.URIs = []*url.URL{{Scheme: "spiffe", Host: "ci", Path: "/deployer"}}
+	})
+
+	store, err := NewCertStore(certFile, keyFile, agentCert)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, store.VerifiesClients())
+
+	l, err := ListenTLS("localhost:0", store.ServerConfig(tls.RequireAndVerifyClientCert))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+
+	client := ClientTLS{Pins: []string{CertPin(first)}, CertFile: agentCert, KeyFile: agentKey}
+	conf, err := client.Config()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("Identity", func(t *testing.T) {
+		peers, _, err := handshake(t, l, conf)
+		if !assert.Nil(t, err) || !assert.Len(t, peers, 1) {
+			return
+		}
+		assert.Equal(t, "spiffe://ci/deployer", CertIdentity(peers[0]))
+		assert.Equal(t, CertPin(agent), CertPin(peers[0]))
+	})
+
+	t.Run("RequireClientCert", func(t *testing.T) {
+		anon, err := ClientTLS{Pins: client.Pins}.Config()
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, _, err = handshake(t, l, anon)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		second, _, _ := writeCert(t, dir, "server", nil)
+		if !assert.Nil(t, store.Reload()) {
+			return
+		}
+
+		// The old pin no longer matches the server.
+		_, _, err := handshake(t, l, conf)
+		assert.NotNil(t, err)
+
+		client.Pins = []string{CertPin(second)}
+		conf, err := client.Config()
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, servers, err := handshake(t, l, conf)
+		if !assert.Nil(t, err) || !assert.Len(t, servers, 1) {
+			return
+		}
+		assert.Equal(t, CertPin(second), CertPin(servers[0]))
+	})
+
+	t.Run("PinnedIntermediate", func(t *testing.T) {
+		pinned, pinnedFile, _ := writeCert(t, dir, "pinned", nil)
+		_, mitmFile, mitmKey := writeCert(t, dir, "mitm", nil)
+
+		// The pinned certificate is public, so anyone may send it after
+		// their own leaf.
+		leaf, err := ioutil.ReadFile(mitmFile)
+		if !assert.Nil(t, err) {
+			return
+		}
+		rest, err := ioutil.ReadFile(pinnedFile)
+		if !assert.Nil(t, err) {
+			return
+		}
+		chainFile := filepath.Join(dir, "chain.pem")
+		if !assert.Nil(t, ioutil.WriteFile(chainFile, append(leaf, rest...), 0600)) {
+			return
+		}
+
+		cert, err := tls.LoadX509KeyPair(chainFile, mitmKey)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		mitm, err := ListenTLS("localhost:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer mitm.Close()
+
+		conf, err := ClientTLS{Pins: []string{CertPin(pinned)}}.Config()
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, _, err = handshake(t, mitm, conf)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("InvalidPin", func(t *testing.T) {
+		_, err := ClientTLS{Pins: []string{"abc"}}.Config()
+		assert.NotNil(t, err)
+	})
+}
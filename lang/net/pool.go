@@ -6,8 +6,13 @@ import (
 	"time"
 
 	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/metrics"
 )
 
+// The connections of all pools, by state (out or idle).
+var poolConns = metrics.NewGauge(
+	"stash_net_pool_connections", "Number of pooled connections", "state")
+
 type Dialer func(time.Duration) (Connection, error)
 
 type ConnectionPool interface {
@@ -48,9 +53,11 @@ func NewConnectionPool(max int, timeout time.Duration, dial Dialer) ConnectionPo
 
 func (p *connPool) start() {
 	go func() {
-		defer p.closePool()
-
 		out := 0
+		defer func() {
+			poolConns.Add(-float64(out), "out")
+			p.closePool()
+		}()
 
 		var take chan Connection
 		var next Connection
@@ -70,8 +77,10 @@ func (p *connPool) start() {
 				return
 			case take <- next:
 				out++
+				poolConns.Add(1, "out")
 			case conn := <-p.ret:
 				out--
+				poolConns.Add(-1, "out")
 				if conn != nil {
 					p.returnToPool(conn)
 				}
@@ -133,6 +142,8 @@ func (p *connPool) Return(c Connection) {
 
 func (p *connPool) closePool() (err error) {
 	for item := p.conns.Front(); item != nil; item = p.conns.Front() {
+		p.conns.Remove(item)
+		poolConns.Add(-1, "idle")
 		item.Value.(io.Closer).Close()
 	}
 	return
@@ -144,11 +155,13 @@ func (p *connPool) spawn() (Connection, error) {
 
 func (p *connPool) returnToPool(c Connection) {
 	p.conns.PushFront(c)
+	poolConns.Add(1, "idle")
 }
 
 func (p *connPool) takeOrSpawnFromPool() (Connection, error) {
 	if item := p.conns.Front(); item != nil {
 		p.conns.Remove(item)
+		poolConns.Add(-1, "idle")
 		return item.Value.(Connection), nil
 	}
 
@@ -2,6 +2,7 @@ package net
 
 import (
 	"crypto/tls"
+	"net"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,7 +17,7 @@ func NewTLSNetworkWithConfig(c *tls.Config) *TLSNetwork {
 }
 
 func DialTLS(timeout time.Duration, addr string, c *tls.Config) (Connection, error) {
-	conn, err := tls.Dial("tcp", addr, c)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, c)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +35,7 @@ func ListenTLS(addr string, c *tls.Config) (Listener, error) {
 		return nil, err
 	}
 
-	return &TCPListener{listen, &TLSNetwork{}}, nil
+	return &TLSListener{TCPListener{listen, &TLSNetwork{c}}}, nil
 }
 
 type TLSNetwork struct {
@@ -48,3 +49,14 @@ func (t *TLSNetwork) Dial(timeout time.Duration, addr string) (Connection, error
 func (t *TLSNetwork) Listen(addr string) (Listener, error) {
 	return ListenTLS(addr, t.config)
 }
+
+// A TLS listener hands out the raw tls connections, so that
+// servers may inspect the state of the handshake (e.g. the
+// certificates presented by clients).
+type TLSListener struct {
+	TCPListener
+}
+
+func (t *TLSListener) Accept() (Connection, error) {
+	return t.raw.Accept()
+}
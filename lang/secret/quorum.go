@@ -0,0 +1,181 @@
+package secret
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+)
+
+// A quorum splits a secret into n shares, any k of which may be
+// combined to rederive the secret.  Fewer than k shares reveal
+// nothing about the secret.  This is Shamir's scheme: the secret
+// is the intercept of a random polynomial of degree k-1 over a
+// prime field, and each share is a point on the polynomial.
+//
+// * https://en.wikipedia.org/wiki/Shamir%27s_Secret_Sharing
+
+var (
+	ErrQuorum = errors.New("Secret:Quorum")
+)
+
+// The largest secret that may be split.  Secrets are prefixed with
+// a single byte to preserve leading zeros, and must remain smaller
+// than the field.
+const MaxQuorumSecret = 64
+
+// The field is defined by the mersenne prime 2^521-1.
+var quorumPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 521), big.NewInt(1))
+
+// A single point on the polynomial of a quorum.
+type QuorumShare struct {
+	Quorum int
+	X      *big.Int
+	Y      *big.Int
+}
+
+// Splits the secret into n shares, any k of which will rederive it.
+func SplitQuorum(rand io.Reader, secret []byte, k, n int) (ret []QuorumShare, err error) {
+	if k < 1 || n < k {
+		err = errors.Wrapf(errs.ArgError, "Invalid quorum [%v of %v]", k, n)
+		return
+	}
+	if len(secret) == 0 || len(secret) > MaxQuorumSecret {
+		err = errors.Wrapf(errs.ArgError, "Secret must be between 1 and %v bytes", MaxQuorumSecret)
+		return
+	}
+
+	prefixed := crypto.Bytes(append([]byte{1}, secret...))
+	defer prefixed.Destroy()
+
+	coeffs := make([]*big.Int, k)
+	coeffs[0] = new(big.Int).SetBytes(prefixed)
+	for i := 1; i < k; i++ {
+		if coeffs[i], err = generateFieldInt(rand); err != nil {
+			return
+		}
+	}
+	defer destroyInts(coeffs)
+
+	ret = make([]QuorumShare, 0, n)
+	for i := 1; i <= n; i++ {
+		x := big.NewInt(int64(i))
+		ret = append(ret, QuorumShare{k, x, evalPolynomial(coeffs, x)})
+	}
+	return
+}
+
+// Rederives the secret from a quorum of shares.
+func JoinQuorum(shares ...QuorumShare) (ret crypto.Bytes, err error) {
+	if len(shares) == 0 {
+		err = errors.Wrapf(ErrQuorum, "No shares")
+		return
+	}
+
+	k := shares[0].Quorum
+	if len(shares) < k {
+		err = errors.Wrapf(ErrQuorum, "Expected [%v] shares. Only received [%v]", k, len(shares))
+		return
+	}
+
+	shares = shares[:k]
+	for i, s := range shares {
+		if s.Quorum != k || s.X == nil || s.Y == nil {
+			err = errors.Wrapf(ErrQuorum, "Incompatible shares")
+			return
+		}
+		for _, o := range shares[:i] {
+			if s.X.Cmp(o.X) == 0 {
+				err = errors.Wrapf(ErrQuorum, "Duplicate share [%v]", s.X)
+				return
+			}
+		}
+	}
+
+	// Lagrange interpolation at x=0.
+	secret := big.NewInt(0)
+	for i, si := range shares {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for j, sj := range shares {
+			if i == j {
+				continue
+			}
+			num.Mul(num, new(big.Int).Neg(sj.X)).Mod(num, quorumPrime)
+			den.Mul(den, new(big.Int).Sub(si.X, sj.X)).Mod(den, quorumPrime)
+		}
+
+		term := new(big.Int).Mul(si.Y, num)
+		term.Mul(term, new(big.Int).ModInverse(den, quorumPrime))
+		secret.Add(secret, term).Mod(secret, quorumPrime)
+	}
+
+	raw := secret.Bytes()
+	if len(raw) < 2 || raw[0] != 1 {
+		err = errors.Wrapf(ErrQuorum, "Shares do not derive a valid secret")
+		return
+	}
+
+	ret = crypto.Bytes(raw[1:])
+	return
+}
+
+func (s QuorumShare) Destroy() {
+	destroyInts([]*big.Int{s.X, s.Y})
+}
+
+func (s QuorumShare) MarshalJSON() (ret []byte, err error) {
+	err = enc.Json.EncodeBinary(struct {
+		K int      `json:"quorum"`
+		X *big.Int `json:"x,string"`
+		Y *big.Int `json:"y,string"`
+	}{
+		s.Quorum,
+		s.X,
+		s.Y,
+	}, &ret)
+	return
+}
+
+func (s *QuorumShare) UnmarshalJSON(data []byte) (err error) {
+	s.X, s.Y = &big.Int{}, &big.Int{}
+	err = enc.Json.DecodeBinary(data, &struct {
+		K *int     `json:"quorum"`
+		X *big.Int `json:"x,string"`
+		Y *big.Int `json:"y,string"`
+	}{
+		&s.Quorum,
+		s.X,
+		s.Y,
+	})
+	return
+}
+
+func evalPolynomial(coeffs []*big.Int, x *big.Int) *big.Int {
+	ret := big.NewInt(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		ret.Mul(ret, x).Add(ret, coeffs[i]).Mod(ret, quorumPrime)
+	}
+	return ret
+}
+
+func generateFieldInt(rand io.Reader) (*big.Int, error) {
+	ret, err := generateBigInt(rand, (quorumPrime.BitLen()+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	return ret.Mod(ret, quorumPrime), nil
+}
+
+func destroyInts(all []*big.Int) {
+	for _, i := range all {
+		if i == nil {
+			continue
+		}
+		raw := i.Bytes()
+		crypto.NewBytes(raw).Destroy()
+		i.SetInt64(0)
+	}
+}
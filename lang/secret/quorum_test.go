@@ -0,0 +1,65 @@
+package secret
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuorum(t *testing.T) {
+	secret := []byte{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	shares, err := SplitQuorum(crypto.Rand, secret, 3, 5)
+	if !assert.Nil(t, err) || !assert.Equal(t, 5, len(shares)) {
+		return
+	}
+
+	t.Run("AnyQuorum", func(t *testing.T) {
+		for _, idx := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+			var sub []QuorumShare
+			for _, i := range idx {
+				sub = append(sub, shares[i])
+			}
+
+			act, err := JoinQuorum(sub...)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, secret, []byte(act))
+		}
+	})
+
+	t.Run("TooFew", func(t *testing.T) {
+		_, err := JoinQuorum(shares[0], shares[1])
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		_, err := JoinQuorum(shares[0], shares[0], shares[1])
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		var all []QuorumShare
+		for _, s := range shares[2:] {
+			raw, err := json.Marshal(s)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			var cur QuorumShare
+			if !assert.Nil(t, json.Unmarshal(raw, &cur)) {
+				return
+			}
+			all = append(all, cur)
+		}
+
+		act, err := JoinQuorum(all...)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, secret, []byte(act))
+	})
+}
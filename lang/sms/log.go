@@ -0,0 +1,27 @@
+package sms
+
+import "github.com/cott-io/stash/lang/context"
+
+type loggedClient struct {
+	Client
+	log context.Logger
+}
+
+// Returns a client that logs each message it sends.  When assigned
+// from the environment of a request, the client logs with its logger.
+func NewLoggedClient(c Client, l context.Logger) Client {
+	return &loggedClient{c, l.Fmt("Sms")}
+}
+
+func (c *loggedClient) WithLogger(l context.Logger) interface{} {
+	return NewLoggedClient(c.Client, l)
+}
+
+func (c *loggedClient) Send(to, msg string) (err error) {
+	if err = c.Client.Send(to, msg); err != nil {
+		c.log.Error("Error sending message [to=%v]: %v", to, err)
+		return
+	}
+	c.log.Info("Sent message [to=%v]", to)
+	return
+}
@@ -427,6 +427,16 @@ func InUUIDs(vals ...uuid.UUID) (args []interface{}) {
 	return
 }
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Escapes the wildcards of a value that is matched with like.  The
+// predicate must declare the escape character, e.g.:
+//
+//	col like ? escape '\'
+func EscapeLike(val string) string {
+	return likeEscaper.Replace(val)
+}
+
 func LowerAll(vals ...string) (args []string) {
 	for _, v := range vals {
 		args = append(args, strings.ToLower(v))
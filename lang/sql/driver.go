@@ -9,11 +9,15 @@ import (
 
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/metrics"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
+var txDuration = metrics.NewHistogram(
+	"stash_sql_tx_duration_seconds", "Duration of sql transactions", metrics.DefaultBuckets, "outcome")
+
 type DefaultDriver struct {
 	ctx     context.Context
 	log     context.Logger
@@ -29,6 +33,15 @@ func NewDefaultDriver(ctx context.Context, db *sql.DB, d Dialect) Driver {
 	return &DefaultDriver{ctx, ctx.Logger(), db, d}
 }
 
+// Returns a driver that shares the database of the given driver, but
+// logs with the logger (e.g. one carrying the id of a request).
+func WithLogger(d Driver, l context.Logger) Driver {
+	if typed, ok := d.(*DefaultDriver); ok {
+		return &DefaultDriver{typed.ctx, l.Fmt("Sql"), typed.db, typed.dialect}
+	}
+	return d
+}
+
 func (d *DefaultDriver) Close() error {
 	return errs.Or(d.DB().Close(), d.ctx.Close())
 }
@@ -49,12 +62,15 @@ func (d *DefaultDriver) Do(fn func(Tx) error) (err error) {
 	}
 	start := time.Now()
 	defer func() {
+		outcome := "commit"
 		if err != nil {
+			outcome = "rollback"
 			d.log.Error("Executing rollback: [reason=%v]: %v", err, tx.Rollback())
-		} else {
-			err = tx.Commit()
+		} else if err = tx.Commit(); err != nil {
+			outcome = "error"
 		}
 
+		txDuration.Observe(time.Since(start).Seconds(), outcome)
 		d.log.Debug("Tx time [%v]", time.Now().Sub(start))
 	}()
 
@@ -1,6 +1,9 @@
 package sql
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/pkg/errors"
 )
@@ -12,10 +15,19 @@ var (
 
 type PageOption func(*Page)
 
+// The number of rows returned by a page without a limit.
+const DefaultLimit uint64 = 10240
+
 type Page struct {
 	Offset  *uint64 `json:"offset,omitempty"`
 	Limit   *uint64 `json:"limit,omitempty"`
 	OrderBy *string `json:"order_by,omitempty"`
+
+	// Keyset pagination orders the rows by a unique set of keys and
+	// begins each page after the keys of the last row of the one before.
+	// Unlike offsets, keysets are stable under concurrent inserts.
+	Keys  []string      `json:"keys,omitempty"`
+	After []interface{} `json:"after,omitempty"`
 }
 
 func buildPage(fns ...PageOption) (ret Page) {
@@ -55,23 +67,79 @@ func OrderBy(field string) func(*Page) {
 	}
 }
 
+// Orders the rows by the keys (e.g. "b.name", "b.version desc"), which
+// together must be unique.
+func Keyset(keys ...string) func(*Page) {
+	return func(p *Page) {
+		p.Keys = keys
+	}
+}
+
+// Begins the page after the row with the values of the keyset.
+func After(vals ...interface{}) func(*Page) {
+	return func(p *Page) {
+		p.After = vals
+	}
+}
+
 func (s SelectBuilder) Page(page Page) (query SelectBuilder) {
 	query = s
 	if page.Offset != nil && *page.Offset > 0 {
 		query = query.Offset(*page.Offset)
 	}
+	if len(page.Keys) > 0 {
+		if len(page.After) == len(page.Keys) {
+			clause, vals := keysetAfter(page.Keys, page.After)
+			query = query.Where(clause, vals...)
+		}
+		query = query.OrderBy(page.Keys...)
+	}
 	if page.OrderBy != nil && *page.OrderBy != "" {
 		query = query.OrderBy(*page.OrderBy)
 	}
 	if page.Limit != nil && *page.Limit > 0 {
 		query = query.Limit(*page.Limit)
 	} else {
-		query = query.Limit(10240)
+		query = query.Limit(DefaultLimit)
 	}
 
 	return query
 }
 
+// Returns the clause that selects the rows ordered after the values of
+// the keys.  Row value comparisons cannot mix directions, so the clause
+// is expanded, e.g.: (a > ?) or (a = ? and b < ?)
+func keysetAfter(keys []string, after []interface{}) (clause string, vals []interface{}) {
+	var ors []string
+	for i, key := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%v = ?", keyCol(keys[j])))
+			vals = append(vals, after[j])
+		}
+
+		op := ">"
+		if keyDesc(key) {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%v %v ?", keyCol(key), op))
+		vals = append(vals, after[i])
+		ors = append(ors, fmt.Sprintf("(%v)", strings.Join(ands, " and ")))
+	}
+
+	clause = fmt.Sprintf("(%v)", strings.Join(ors, " or "))
+	return
+}
+
+func keyCol(key string) string {
+	return strings.Fields(key)[0]
+}
+
+func keyDesc(key string) bool {
+	fields := strings.Fields(key)
+	return len(fields) > 1 && strings.EqualFold(fields[1], "desc")
+}
+
 func IfNone(cond Query, then, els Atomic) Atomic {
 	return func(tx Tx) (err error) {
 		ok, err := tx.Query(Nil(), cond)
@@ -127,7 +195,13 @@ func QueryOne(query Query, dest Object, found *bool) Atomic {
 
 func QueryPage(query SelectBuilder, dest Buffer, page ...PageOption) Atomic {
 	return func(tx Tx) (err error) {
-		_, err = tx.Scan(dest, query.Page(buildPage(page...)))
+		p := buildPage(page...)
+		if len(p.After) > 0 && len(p.After) != len(p.Keys) {
+			err = errors.Wrapf(errs.ArgError, "Expected [%v] keys to page after. Got [%v]", len(p.Keys), len(p.After))
+			return
+		}
+
+		_, err = tx.Scan(dest, query.Page(p))
 		return
 	}
 }
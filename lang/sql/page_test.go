@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/stretchr/testify/assert"
+)
+
+type pageRow struct {
+	Name string `json:"name"`
+	Idx  int    `json:"idx"`
+}
+
+func TestQueryPage_Keyset(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	driver, err := SqlLiteDialer{}.Embed(ctx)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	schema := NewSchema("page_row", 0).WithStruct(pageRow{}).Build()
+	if !assert.Nil(t, driver.Do(schema.Init)) {
+		return
+	}
+
+	var all []pageRow
+	for _, name := range []string{"c", "a", "b"} {
+		for idx := 0; idx < 3; idx++ {
+			all = append(all, pageRow{name, idx})
+		}
+	}
+
+	var inserts []Query
+	for _, r := range all {
+		inserts = append(inserts, schema.Insert(r))
+	}
+	if !assert.Nil(t, driver.Do(Exec(inserts...))) {
+		return
+	}
+
+	var act []pageRow
+	var after []interface{}
+	for {
+		var buf []pageRow
+		err := driver.Do(
+			QueryPage(
+				schema.SelectAs("p"),
+				Slice(&buf, Struct),
+				Keyset("p.name", "p.idx desc"),
+				After(after...),
+				Limit(2)))
+		if !assert.Nil(t, err) || len(buf) == 0 {
+			break
+		}
+
+		act = append(act, buf...)
+		last := buf[len(buf)-1]
+		after = []interface{}{last.Name, last.Idx}
+	}
+
+	assert.Equal(t, []pageRow{
+		{"a", 2}, {"a", 1}, {"a", 0},
+		{"b", 2}, {"b", 1}, {"b", 0},
+		{"c", 2}, {"c", 1}, {"c", 0},
+	}, act)
+
+	var buf []pageRow
+	err = driver.Do(
+		QueryPage(
+			schema.SelectAs("p"),
+			Slice(&buf, Struct),
+			Keyset("p.name", "p.idx desc"),
+			After("a")))
+	assert.NotNil(t, err)
+}
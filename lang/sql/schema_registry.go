@@ -1,5 +1,12 @@
 package sql
 
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
 // Registers and initializes the schemas with the registry.
 func InitSchemas(driver Driver, reg SchemaRegistry, schemas ...Schema) error {
 	for _, s := range schemas {
@@ -29,11 +36,18 @@ func newRegistrySchema(id string) Schema {
 }
 
 type SchemaRegistry struct {
-	meta Schema
+	meta     Schema
+	versions *schemaVersions
+}
+
+// The versions of the schemas registered by this process.
+type schemaVersions struct {
+	lock sync.Mutex
+	all  map[string]int
 }
 
 func NewSchemaRegistry(id string) SchemaRegistry {
-	return SchemaRegistry{newRegistrySchema(id)}
+	return SchemaRegistry{newRegistrySchema(id), &schemaVersions{all: make(map[string]int)}}
 }
 
 func (r SchemaRegistry) Register(tx Tx, all ...Schema) error {
@@ -45,9 +59,44 @@ func (r SchemaRegistry) Register(tx Tx, all ...Schema) error {
 			return err
 		}
 	}
+
+	r.versions.lock.Lock()
+	defer r.versions.lock.Unlock()
+	for _, cur := range all {
+		r.versions.all[cur.Name] = cur.Version
+	}
 	return nil
 }
 
+// Verifies that the database is reachable and that every schema
+// registered by this process is migrated to its current version.
+func (r SchemaRegistry) Check(driver Driver) error {
+	r.versions.lock.Lock()
+	names, versions := make([]string, 0, len(r.versions.all)), make(map[string]int)
+	for name, version := range r.versions.all {
+		names, versions[name] = append(names, name), version
+	}
+	r.versions.lock.Unlock()
+	sort.Strings(names)
+
+	return driver.Do(func(tx Tx) error {
+		for _, name := range names {
+			var cur int
+			if _, err := tx.Query(Value(&cur),
+				Select("coalesce(max(version), -1)").
+					From(r.meta.Name).
+					Where("name = ?", name)); err != nil {
+				return err
+			}
+
+			if cur < versions[name] {
+				return errors.Errorf("Schema [%v] at version [%v]. Expected [%v]", name, cur, versions[name])
+			}
+		}
+		return nil
+	})
+}
+
 func (r SchemaRegistry) ensure(tx Tx, next Schema) (err error) {
 	if err = next.Init(tx); err != nil {
 		return
@@ -70,15 +119,15 @@ func (r SchemaRegistry) ensure(tx Tx, next Schema) (err error) {
 		return
 	}
 
+	// Deltas are applied in order, recording the version reached.
 	var n int = current.Version
 	defer func() {
 		if e := r.writeEntry(tx, next.Name, n); err == nil {
 			err = e
 		}
 	}()
-	var fn Atomic
-	for n, fn = range deltas {
-		if err = fn(tx); err != nil {
+	for ; n < next.Version; n++ {
+		if err = deltas[n](tx); err != nil {
 			return
 		}
 	}
@@ -0,0 +1,49 @@
+package sql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	driver, err := SqlLiteDialer{}.Embed(ctx)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer driver.Close()
+
+	var applied []int
+	migration := func(n int) Atomic {
+		return func(Tx) error {
+			applied = append(applied, n)
+			return nil
+		}
+	}
+
+	registry := NewSchemaRegistry("test")
+	if !assert.Nil(t, InitSchemas(driver, registry, NewSchema("test2", 0).WithStruct(Test2{}).Build())) {
+		return
+	}
+	assert.Nil(t, registry.Check(driver))
+
+	schema := NewSchema("test2", 3).
+		WithStruct(Test2{}).
+		WithMigration(2, migration(2)).
+		WithMigration(0, migration(0)).
+		WithMigration(1, migration(1)).
+		Build()
+	if !assert.Nil(t, InitSchemas(driver, registry, schema)) {
+		return
+	}
+	assert.Equal(t, []int{0, 1, 2}, applied)
+	assert.Nil(t, registry.Check(driver))
+
+	assert.Nil(t, driver.Do(Exec(DeleteFrom("test").Where("name = ?", "test2"))))
+	assert.NotNil(t, registry.Check(driver))
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/pkg/errors"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,6 +16,7 @@ var (
 	ErrUnsupportedType       = errors.New("Sql:UnsupportedType")
 	ErrUnsupportedConstraint = errors.New("Sql:UnsupportedConstraint")
 	ErrSqliteUnique          = errors.New("UNIQUE constraint failed")
+	ErrPostgresUnique        = errors.New("violates unique constraint")
 	ErrUniqueConstraint      = errors.New("Sql:UniqueConstraint")
 )
 
@@ -23,8 +25,8 @@ type SqlLiteDialect struct {
 
 func (u *SqlLiteDialect) ConvertError(in error) (err error) {
 	err = in
-	if errors.Is(err, ErrSqliteUnique) {
-		err = ErrUniqueConstraint
+	if errs.Is(err, ErrSqliteUnique, ErrPostgresUnique) {
+		err = errors.Wrap(ErrUniqueConstraint, err.Error())
 	}
 	return
 }
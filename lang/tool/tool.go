@@ -2,6 +2,7 @@ package tool
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/libs/auth"
@@ -81,6 +82,16 @@ func (s UintFlag) Build() cli.Flag {
 	return cli.UintFlag{Name: s.Name, Usage: s.Usage, Value: s.Default}
 }
 
+type DurationFlag struct {
+	Name    string
+	Usage   string
+	Default time.Duration
+}
+
+func (s DurationFlag) Build() cli.Flag {
+	return cli.DurationFlag{Name: s.Name, Usage: s.Usage, Value: s.Default}
+}
+
 type StringFlag struct {
 	Name    string
 	Usage   string
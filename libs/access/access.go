@@ -0,0 +1,212 @@
+package access
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrNoRequest = errors.New("Access:NoRequest")
+	ErrDecided   = errors.New("Access:Decided")
+)
+
+const (
+	Pending  Status = "pending"
+	Approved Status = "approved"
+	Denied   Status = "denied"
+	Expired  Status = "expired"
+)
+
+// The longest access that may be requested, unless the approvers
+// of the policy specify otherwise.
+const DefaultMaxDuration = 24 * time.Hour
+
+// The allowed drift between the requested expiry of a grant and the
+// expiry placed on the membership by the approver.
+const ExpiryTolerance = 5 * time.Minute
+
+type Status string
+
+func ParseStatus(str string) (ret Status, err error) {
+	ret = Status(strings.ToLower(strings.TrimSpace(str)))
+	switch ret {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid status [%v]. Expected one of [pending, approved, denied, expired, claimed]", str)
+	case Pending, Approved, Denied, Expired, Claimed:
+	}
+	return
+}
+
+// A request for temporary access to the items of a policy.  Once
+// approved, the requester is granted a membership that expires
+// after the requested duration, at which point it is revoked.
+type Request struct {
+	Id          uuid.UUID      `json:"id"`
+	OrgId       uuid.UUID      `json:"org_id"`
+	PolicyId    uuid.UUID      `json:"policy_id"`
+	Item        string         `json:"item"`
+	RequesterId uuid.UUID      `json:"requester_id"`
+	Actions     policy.Actions `json:"actions"`
+	Duration    time.Duration  `json:"duration"`
+	Reason      string         `json:"reason"`
+	Status      Status         `json:"status"`
+	DeciderId   uuid.UUID      `json:"decider_id"`
+	Comment     string         `json:"comment"`
+	Expires     time.Time      `json:"expires"`
+	MemberVer   int            `json:"member_ver"`
+	Version     int            `json:"version"`
+	Created     time.Time      `json:"created"`
+	Updated     time.Time      `json:"updated"`
+}
+
+func NewRequest(orgId, policyId, requesterId uuid.UUID, item string, dur time.Duration, reason string, actions ...policy.Action) (ret Request, err error) {
+	if len(actions) == 0 {
+		err = errors.Wrapf(errs.ArgError, "Must request at least one action")
+		return
+	}
+	if dur <= 0 {
+		err = errors.Wrapf(errs.ArgError, "Must request a positive duration")
+		return
+	}
+	if strings.TrimSpace(reason) == "" {
+		err = errors.Wrapf(errs.ArgError, "Must provide a reason")
+		return
+	}
+
+	now := time.Now().UTC()
+	ret = Request{
+		Id:          uuid.NewV1(),
+		OrgId:       orgId,
+		PolicyId:    policyId,
+		Item:        item,
+		RequesterId: requesterId,
+		Actions:     policy.Enable(actions...),
+		Duration:    dur,
+		Reason:      reason,
+		Status:      Pending,
+		Created:     now,
+		Updated:     now,
+	}
+	return
+}
+
+func (r Request) Update(fn func(*Request)) (ret Request) {
+	ret = r
+	fn(&ret)
+	ret.Version = r.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+// Approves the request, recording the membership that was granted.
+func (r Request) Approve(deciderId uuid.UUID, comment string, member policy.PolicyMember) (ret Request, err error) {
+	if r.Status != Pending {
+		err = errors.Wrapf(ErrDecided, "Request [%v] is already %v", r.Id, r.Status)
+		return
+	}
+
+	ret = r.Update(func(r *Request) {
+		r.Status = Approved
+		r.DeciderId = deciderId
+		r.Comment = comment
+		r.Expires = member.Conditions.NotAfter
+		r.MemberVer = member.Version
+	})
+	return
+}
+
+func (r Request) Deny(deciderId uuid.UUID, comment string) (ret Request, err error) {
+	if r.Status != Pending {
+		err = errors.Wrapf(ErrDecided, "Request [%v] is already %v", r.Id, r.Status)
+		return
+	}
+
+	ret = r.Update(func(r *Request) {
+		r.Status = Denied
+		r.DeciderId = deciderId
+		r.Comment = comment
+	})
+	return
+}
+
+func (r Request) Expire() (ret Request) {
+	return r.Update(func(r *Request) {
+		r.Status = Expired
+	})
+}
+
+// Verifies that the membership is the one described by the request:
+// the requester is granted exactly the requested actions, and the
+// membership expires after the requested duration.
+func (r Request) VerifyGrant(now time.Time, m policy.PolicyMember) (err error) {
+	if m.OrgId != r.OrgId || m.PolicyId != r.PolicyId || m.MemberId != r.RequesterId || m.MemberType != policy.UserType {
+		err = errors.Wrapf(errs.ArgError, "Membership does not match request [%v]", r.Id)
+		return
+	}
+	if m.Deleted || !m.Actions.Equals(r.Actions) {
+		err = errors.Wrapf(errs.ArgError, "Membership must grant exactly the requested actions %v", r.Actions.Flatten())
+		return
+	}
+
+	expected, actual := now.Add(r.Duration), m.Conditions.NotAfter
+	if actual.IsZero() || actual.Before(expected.Add(-ExpiryTolerance)) || actual.After(expected.Add(ExpiryTolerance)) {
+		err = errors.Wrapf(errs.ArgError, "Membership must expire at [%v]", expected)
+		return
+	}
+	return
+}
+
+func (r Request) GetOrgId() uuid.UUID {
+	return r.OrgId
+}
+
+func (r Request) GetPolicyId() uuid.UUID {
+	return r.PolicyId
+}
+
+// The approvers of a policy.  Anyone with sudo on a policy may
+// approve its requests.  Optionally, the members of a group may
+// also approve them.  The group must itself be a member of the
+// policy in order for its members to grant access.
+type Approvers struct {
+	OrgId       uuid.UUID     `json:"org_id"`
+	PolicyId    uuid.UUID     `json:"policy_id"`
+	GroupId     uuid.UUID     `json:"group_id"`
+	MaxDuration time.Duration `json:"max_duration"`
+	Version     int           `json:"version"`
+	Created     time.Time     `json:"created"`
+	Updated     time.Time     `json:"updated"`
+}
+
+func NewApprovers(orgId, policyId, groupId uuid.UUID, max time.Duration) Approvers {
+	now := time.Now().UTC()
+	return Approvers{
+		OrgId:       orgId,
+		PolicyId:    policyId,
+		GroupId:     groupId,
+		MaxDuration: max,
+		Created:     now,
+		Updated:     now,
+	}
+}
+
+func (a Approvers) Update(fn func(*Approvers)) (ret Approvers) {
+	ret = a
+	fn(&ret)
+	ret.Version = a.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+// Returns the longest duration that may be requested.
+func (a Approvers) Max() time.Duration {
+	if a.MaxDuration <= 0 {
+		return DefaultMaxDuration
+	}
+	return a.MaxDuration
+}
@@ -0,0 +1,74 @@
+package access
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequest_VerifyGrant(t *testing.T) {
+	orgId, policyId, userId := uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	req, err := NewRequest(orgId, policyId, userId, "secret://db", time.Hour, "incident", policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	now := time.Now().UTC()
+	member := policy.PolicyMember{
+		OrgId:      orgId,
+		PolicyId:   policyId,
+		MemberId:   userId,
+		MemberType: policy.UserType,
+		Actions:    policy.Enable(policy.View),
+		Conditions: policy.Conditions{NotAfter: now.Add(time.Hour)},
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.Nil(t, req.VerifyGrant(now, member))
+	})
+
+	t.Run("ExtraActions", func(t *testing.T) {
+		tmp := member
+		tmp.Actions = policy.Enable(policy.View, policy.Sudo)
+		assert.NotNil(t, req.VerifyGrant(now, tmp))
+	})
+
+	t.Run("TooLong", func(t *testing.T) {
+		tmp := member
+		tmp.Conditions.NotAfter = now.Add(2 * time.Hour)
+		assert.NotNil(t, req.VerifyGrant(now, tmp))
+	})
+
+	t.Run("NoExpiry", func(t *testing.T) {
+		tmp := member
+		tmp.Conditions = policy.Conditions{}
+		assert.NotNil(t, req.VerifyGrant(now, tmp))
+	})
+
+	t.Run("WrongMember", func(t *testing.T) {
+		tmp := member
+		tmp.MemberId = uuid.NewV1()
+		assert.NotNil(t, req.VerifyGrant(now, tmp))
+	})
+}
+
+func TestRequest_Decide(t *testing.T) {
+	req, err := NewRequest(uuid.NewV1(), uuid.NewV1(), uuid.NewV1(), "secret://db", time.Hour, "incident", policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	denied, err := req.Deny(uuid.NewV1(), "no")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, Denied, denied.Status)
+	assert.Equal(t, 1, denied.Version)
+
+	_, err = denied.Approve(uuid.NewV1(), "", policy.PolicyMember{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,303 @@
+package access
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Break-glass access covers the case where nobody able to grant
+// access to a policy is reachable.  The pass of the policy is split
+// amongst a set of custodians, a quorum of whom may hand their shares
+// over to a requester.  The requester then recovers the pass and
+// grants themselves a temporary membership.  The server never sees
+// the shares in the clear.
+//
+// The membership expires, but the requester keeps the pass it
+// protects.  Claiming an unlock therefore marks the policy as due a
+// rekey (see policy.Policy), which also retires its escrow.  Once
+// rekeyed, a new escrow must be set up.
+var (
+	ErrNoEscrow = errors.New("Access:NoEscrow")
+	ErrNoUnlock = errors.New("Access:NoUnlock")
+)
+
+const (
+	Claimed Status = "claimed"
+)
+
+const (
+	MinQuorum             = 2
+	DefaultUnlockWindow   = time.Hour
+	DefaultEmergencyGrant = 4 * time.Hour
+)
+
+// A custody is the share of the pass held by a single custodian.
+type Custody struct {
+	CustodianId uuid.UUID           `json:"custodian_id"`
+	Share       policy.MemberSecret `json:"share"`
+}
+
+type Custodies []Custody
+
+func (c Custodies) MarshalBinary() ([]byte, error) {
+	return json.Marshal([]Custody(c))
+}
+
+func (c *Custodies) UnmarshalBinary(raw []byte) error {
+	return json.Unmarshal(raw, (*[]Custody)(c))
+}
+
+// An escrow is the break-glass configuration of a policy.  It is
+// bound to the version of the policy whose pass was split, so any
+// rekey of the policy requires a new escrow.
+type Escrow struct {
+	OrgId     uuid.UUID      `json:"org_id"`
+	PolicyId  uuid.UUID      `json:"policy_id"`
+	PolicyVer int            `json:"policy_ver"`
+	Quorum    int            `json:"quorum"`
+	Window    time.Duration  `json:"window"`
+	Grant     time.Duration  `json:"grant"`
+	Actions   policy.Actions `json:"actions"`
+	Custodies Custodies      `json:"custodies"`
+	Deleted   bool           `json:"deleted"`
+	Version   int            `json:"version"`
+	Created   time.Time      `json:"created"`
+	Updated   time.Time      `json:"updated"`
+}
+
+func NewEscrow(core policy.Policy, quorum int, window, grant time.Duration, custodies []Custody, actions ...policy.Action) (ret Escrow, err error) {
+	now := time.Now().UTC()
+	ret = Escrow{
+		OrgId:     core.OrgId,
+		PolicyId:  core.Id,
+		PolicyVer: core.Version,
+		Quorum:    quorum,
+		Window:    window,
+		Grant:     grant,
+		Actions:   policy.Enable(actions...),
+		Custodies: custodies,
+		Created:   now,
+		Updated:   now,
+	}
+	err = ret.Validate()
+	return
+}
+
+func (e Escrow) Validate() (err error) {
+	if e.Quorum < MinQuorum || e.Quorum > len(e.Custodies) {
+		err = errors.Wrapf(errs.ArgError, "Quorum must be between %v and the number of custodians [%v]", MinQuorum, len(e.Custodies))
+		return
+	}
+	if e.Window <= 0 || e.Grant <= 0 {
+		err = errors.Wrapf(errs.ArgError, "Window and grant must be positive durations")
+		return
+	}
+	if len(e.Actions) == 0 {
+		err = errors.Wrapf(errs.ArgError, "Must grant at least one action")
+		return
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, c := range e.Custodies {
+		if seen[c.CustodianId] {
+			err = errors.Wrapf(errs.ArgError, "Duplicate custodian [%v]", c.CustodianId)
+			return
+		}
+		seen[c.CustodianId] = true
+	}
+	return
+}
+
+func (e Escrow) Update(fn func(*Escrow)) (ret Escrow) {
+	ret = e
+	fn(&ret)
+	ret.Version = e.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+func (e Escrow) Delete() Escrow {
+	return e.Update(func(e *Escrow) {
+		e.Deleted = true
+		e.Custodies = nil
+	})
+}
+
+// Returns the share held by the custodian.
+func (e Escrow) Custody(custodianId uuid.UUID) (ret Custody, ok bool) {
+	for _, c := range e.Custodies {
+		if c.CustodianId == custodianId {
+			return c, true
+		}
+	}
+	return
+}
+
+func (e Escrow) CustodianIds() (ret []uuid.UUID) {
+	for _, c := range e.Custodies {
+		ret = append(ret, c.CustodianId)
+	}
+	return
+}
+
+// A share that has been handed over by a custodian.  It is encrypted
+// for the requester of the unlock.
+type Share struct {
+	CustodianId uuid.UUID           `json:"custodian_id"`
+	Share       policy.MemberSecret `json:"share"`
+	Created     time.Time           `json:"created"`
+}
+
+type Shares []Share
+
+func (s Shares) MarshalBinary() ([]byte, error) {
+	return json.Marshal([]Share(s))
+}
+
+func (s *Shares) UnmarshalBinary(raw []byte) error {
+	return json.Unmarshal(raw, (*[]Share)(s))
+}
+
+func (s Shares) Secrets() (ret []policy.MemberSecret) {
+	for _, cur := range s {
+		ret = append(ret, cur.Share)
+	}
+	return
+}
+
+// An unlock is an emergency request for access to a policy.  It may
+// only be claimed once a quorum of custodians have handed over their
+// shares, and only within its window.
+type Unlock struct {
+	Id          uuid.UUID `json:"id"`
+	OrgId       uuid.UUID `json:"org_id"`
+	PolicyId    uuid.UUID `json:"policy_id"`
+	Item        string    `json:"item"`
+	RequesterId uuid.UUID `json:"requester_id"`
+	Reason      string    `json:"reason"`
+	Quorum      int       `json:"quorum"`
+	EscrowVer   int       `json:"escrow_ver"`
+	Shares      Shares    `json:"shares"`
+	Status      Status    `json:"status"`
+	Expires     time.Time `json:"expires"`
+	Version     int       `json:"version"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+func NewUnlock(e Escrow, requesterId uuid.UUID, item, reason string) (ret Unlock, err error) {
+	if e.Deleted {
+		err = errors.Wrapf(ErrNoEscrow, "Policy [%v] has no break-glass escrow", e.PolicyId)
+		return
+	}
+	if reason == "" {
+		err = errors.Wrapf(errs.ArgError, "Must provide a reason")
+		return
+	}
+
+	now := time.Now().UTC()
+	ret = Unlock{
+		Id:          uuid.NewV1(),
+		OrgId:       e.OrgId,
+		PolicyId:    e.PolicyId,
+		Item:        item,
+		RequesterId: requesterId,
+		Reason:      reason,
+		Quorum:      e.Quorum,
+		EscrowVer:   e.Version,
+		Status:      Pending,
+		Expires:     now.Add(e.Window),
+		Created:     now,
+		Updated:     now,
+	}
+	return
+}
+
+func (u Unlock) Update(fn func(*Unlock)) (ret Unlock) {
+	ret = u
+	fn(&ret)
+	ret.Shares = append(Shares{}, ret.Shares...)
+	ret.Version = u.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+// Returns whether the unlock is still open to shares and claims.
+func (u Unlock) Open(now time.Time) bool {
+	return u.Status == Pending && now.Before(u.Expires)
+}
+
+// Returns whether a quorum of shares has been handed over.
+func (u Unlock) Ready(now time.Time) bool {
+	return u.Open(now) && len(u.Shares) >= u.Quorum
+}
+
+// Records the share of a custodian.
+func (u Unlock) Submit(now time.Time, custodianId uuid.UUID, share policy.MemberSecret) (ret Unlock, err error) {
+	if !u.Open(now) {
+		err = errors.Wrapf(ErrDecided, "Unlock [%v] is no longer open", u.Id)
+		return
+	}
+	if custodianId == u.RequesterId {
+		err = errors.Wrapf(errs.ArgError, "Requesters may not hand over their own shares")
+		return
+	}
+	for _, s := range u.Shares {
+		if s.CustodianId == custodianId {
+			err = errors.Wrapf(errs.StateError, "Share already submitted by [%v]", custodianId)
+			return
+		}
+	}
+
+	ret = u.Update(func(u *Unlock) {
+		u.Shares = append(u.Shares, Share{custodianId, share, now})
+	})
+	return
+}
+
+// Claims the unlock.  An unlock may only be claimed once, after which
+// its policy is due a rekey.
+func (u Unlock) Claim(now time.Time) (ret Unlock, err error) {
+	if !u.Ready(now) {
+		err = errors.Wrapf(ErrDecided, "Unlock [%v] has not reached a quorum [%v of %v] or is no longer open", u.Id, len(u.Shares), u.Quorum)
+		return
+	}
+
+	ret = u.Update(func(u *Unlock) {
+		u.Status = Claimed
+	})
+	return
+}
+
+// Verifies that the membership is the emergency grant described by
+// the escrow.
+func (u Unlock) VerifyGrant(now time.Time, e Escrow, m policy.PolicyMember) (err error) {
+	if m.OrgId != u.OrgId || m.PolicyId != u.PolicyId || m.MemberId != u.RequesterId || m.MemberType != policy.UserType {
+		err = errors.Wrapf(errs.ArgError, "Membership does not match unlock [%v]", u.Id)
+		return
+	}
+	if m.Deleted || !m.Actions.Equals(e.Actions) {
+		err = errors.Wrapf(errs.ArgError, "Membership must grant exactly the actions %v", e.Actions.Flatten())
+		return
+	}
+
+	expected, actual := now.Add(e.Grant), m.Conditions.NotAfter
+	if actual.IsZero() || actual.Before(expected.Add(-ExpiryTolerance)) || actual.After(expected.Add(ExpiryTolerance)) {
+		err = errors.Wrapf(errs.ArgError, "Membership must expire at [%v]", expected)
+		return
+	}
+	return
+}
+
+func (u Unlock) GetOrgId() uuid.UUID {
+	return u.OrgId
+}
+
+func (u Unlock) GetPolicyId() uuid.UUID {
+	return u.PolicyId
+}
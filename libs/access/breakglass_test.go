@@ -0,0 +1,91 @@
+package access
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlock(t *testing.T) {
+	core := policy.Policy{Id: uuid.NewV1(), OrgId: uuid.NewV1(), Version: 3}
+
+	custodians := []Custody{{CustodianId: uuid.NewV1()}, {CustodianId: uuid.NewV1()}, {CustodianId: uuid.NewV1()}}
+
+	t.Run("InvalidQuorum", func(t *testing.T) {
+		_, err := NewEscrow(core, 4, time.Hour, time.Hour, custodians, policy.View)
+		assert.NotNil(t, err)
+	})
+
+	escrow, err := NewEscrow(core, 2, time.Hour, time.Hour, custodians, policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 3, escrow.PolicyVer)
+
+	requesterId := uuid.NewV1()
+	unlock, err := NewUnlock(escrow, requesterId, "secret://db", "outage")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	t.Run("ClaimWithoutQuorum", func(t *testing.T) {
+		_, err := unlock.Claim(now)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("SelfShare", func(t *testing.T) {
+		_, err := unlock.Submit(now, requesterId, policy.MemberSecret{})
+		assert.NotNil(t, err)
+	})
+
+	unlock, err = unlock.Submit(now, custodians[0].CustodianId, policy.MemberSecret{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("DuplicateShare", func(t *testing.T) {
+		_, err := unlock.Submit(now, custodians[0].CustodianId, policy.MemberSecret{})
+		assert.NotNil(t, err)
+	})
+
+	unlock, err = unlock.Submit(now, custodians[1].CustodianId, policy.MemberSecret{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, unlock.Ready(now))
+
+	t.Run("Expired", func(t *testing.T) {
+		_, err := unlock.Claim(now.Add(2 * time.Hour))
+		assert.NotNil(t, err)
+	})
+
+	member := policy.PolicyMember{
+		OrgId:      core.OrgId,
+		PolicyId:   core.Id,
+		MemberId:   requesterId,
+		MemberType: policy.UserType,
+		Actions:    policy.Enable(policy.View),
+		Conditions: policy.Conditions{NotAfter: now.Add(time.Hour)},
+	}
+	assert.Nil(t, unlock.VerifyGrant(now, escrow, member))
+
+	t.Run("ExtraActions", func(t *testing.T) {
+		tmp := member
+		tmp.Actions = policy.Enable(policy.View, policy.Sudo)
+		assert.NotNil(t, unlock.VerifyGrant(now, escrow, tmp))
+	})
+
+	claimed, err := unlock.Claim(now)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, Claimed, claimed.Status)
+
+	_, err = claimed.Claim(now)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,36 @@
+package access
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+type Filter struct {
+	Status      *Status      `json:"status,omitempty"`
+	RequesterId *uuid.UUID   `json:"requester_id,omitempty"`
+	PolicyIds   *[]uuid.UUID `json:"policy_ids,omitempty"`
+}
+
+func BuildFilter(fns ...func(*Filter)) (ret Filter) {
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+func FilterByStatus(status Status) func(*Filter) {
+	return func(f *Filter) {
+		f.Status = &status
+	}
+}
+
+func FilterByRequester(id uuid.UUID) func(*Filter) {
+	return func(f *Filter) {
+		f.RequesterId = &id
+	}
+}
+
+func FilterByPolicies(ids ...uuid.UUID) func(*Filter) {
+	return func(f *Filter) {
+		f.PolicyIds = &ids
+	}
+}
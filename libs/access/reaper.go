@@ -0,0 +1,139 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+)
+
+const (
+	DefaultReapInterval = time.Minute
+	reapPageSize        = 128
+)
+
+// A reaper revokes the memberships granted by approved requests once
+// they expire.  An expired membership is already unusable (it carries
+// a not-after condition), so the reaper simply removes it from the
+// roster of its policy and marks the request as expired.
+//
+// A membership that has been changed since it was granted (e.g. the
+// requester was given standing access) is left in place.
+//
+// The reaper also closes review campaigns whose deadlines have
+// passed, signing their reports with the signer.
+type Reaper struct {
+	ctx      context.Context
+	requests Storage
+	policies policy.Storage
+	log      audit.Storage
+	signer   crypto.Signer
+	interval time.Duration
+}
+
+func NewReaper(ctx context.Context, requests Storage, policies policy.Storage, log audit.Storage, signer crypto.Signer, interval time.Duration) (ret *Reaper) {
+	ret = &Reaper{
+		ctx:      ctx.Sub("AccessReaper"),
+		requests: requests,
+		policies: policies,
+		log:      log,
+		signer:   signer,
+		interval: interval,
+	}
+
+	go ret.run()
+	return
+}
+
+func (r *Reaper) Close() error {
+	return r.ctx.Close()
+}
+
+func (r *Reaper) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Control().Closed():
+			return
+		case <-ticker.C:
+			if _, err := r.Reap(time.Now().UTC()); err != nil {
+				r.ctx.Logger().Error("Error revoking expired access: %v", err)
+			}
+			if _, err := r.CloseDue(time.Now().UTC()); err != nil {
+				r.ctx.Logger().Error("Error closing review campaigns: %v", err)
+			}
+		}
+	}
+}
+
+// Revokes all the grants that expired at or before the given time,
+// returning the number of requests that were expired.
+func (r *Reaper) Reap(now time.Time) (num int, err error) {
+	for {
+		expired, err := r.requests.ListExpiredRequests(now, page.BuildPage(page.Limit(reapPageSize)))
+		if err != nil || len(expired) == 0 {
+			return num, err
+		}
+
+		for _, req := range expired {
+			if err = r.revoke(req); err != nil {
+				return num, err
+			}
+			num++
+		}
+	}
+}
+
+func (r *Reaper) revoke(req Request) (err error) {
+	member, ok, err := r.policies.LoadPolicyMember(req.OrgId, req.PolicyId, req.RequesterId)
+	if err != nil {
+		return
+	}
+
+	if ok && !member.Deleted && member.Version == req.MemberVer {
+		if err = r.policies.SavePolicyMember(member.Delete()); err != nil {
+			return
+		}
+	}
+
+	if err = r.requests.SaveRequest(req.Expire()); err != nil {
+		return
+	}
+
+	if err := r.log.SaveEvents(
+		audit.NewEvent(req.OrgId, req.RequesterId, audit.AccessExpire, audit.PolicyTarget(req.PolicyId),
+			audit.WithDetail(req.Item))); err != nil {
+		r.ctx.Logger().Error("Error recording expiry of request [%v]: %v", req.Id, err)
+	}
+	return
+}
+
+// Closes all the campaigns whose deadlines passed at or before the
+// given time, returning the number of campaigns that were closed.
+func (r *Reaper) CloseDue(now time.Time) (num int, err error) {
+	for {
+		due, err := r.requests.ListDueCampaigns(now, page.BuildPage(page.Limit(reapPageSize)))
+		if err != nil || len(due) == 0 {
+			return num, err
+		}
+
+		for _, c := range due {
+			closed, err := CloseCampaign(r.requests, r.policies, r.signer, c, now)
+			if err != nil {
+				return num, err
+			}
+
+			if err := r.log.SaveEvents(
+				audit.NewEvent(c.OrgId, c.OwnerId, audit.ReviewClose, audit.CampaignTarget(c.Id),
+					audit.WithDetail(closed.Name))); err != nil {
+				r.ctx.Logger().Error("Error recording close of campaign [%v]: %v", c.Id, err)
+			}
+			num++
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package access
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrNoCampaign  = errors.New("Access:NoCampaign")
+	ErrNoEntry     = errors.New("Access:NoEntry")
+	ErrClosed      = errors.New("Access:Closed")
+	ErrNotAssigned = errors.New("Access:NotAssigned")
+)
+
+const (
+	Open   Status = "open"
+	Closed Status = "closed"
+)
+
+const (
+	Undecided Decision = "pending"
+	Keep      Decision = "keep"
+	Revoke    Decision = "revoke"
+
+	// Entries that were not reviewed by the deadline.  These are
+	// revoked when the campaign closes.
+	Lapsed Decision = "lapsed"
+)
+
+type Decision string
+
+func ParseDecision(str string) (ret Decision, err error) {
+	ret = Decision(strings.ToLower(strings.TrimSpace(str)))
+	switch ret {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid decision [%v]. Expected one of [pending, keep, revoke, lapsed]", str)
+	case Undecided, Keep, Revoke, Lapsed:
+	}
+	return
+}
+
+type Ids []uuid.UUID
+
+func (i Ids) MarshalBinary() ([]byte, error) {
+	return json.Marshal([]uuid.UUID(i))
+}
+
+func (i *Ids) UnmarshalBinary(raw []byte) error {
+	return json.Unmarshal(raw, (*[]uuid.UUID)(i))
+}
+
+func (i Ids) Contains(id uuid.UUID) bool {
+	for _, cur := range i {
+		if cur == id {
+			return true
+		}
+	}
+	return false
+}
+
+// An item under review.  The name of the item is only used for
+// display.
+type Scope struct {
+	PolicyId uuid.UUID `json:"policy_id"`
+	Item     string    `json:"item"`
+}
+
+type Scopes []Scope
+
+func (s Scopes) MarshalBinary() ([]byte, error) {
+	return json.Marshal([]Scope(s))
+}
+
+func (s *Scopes) UnmarshalBinary(raw []byte) error {
+	return json.Unmarshal(raw, (*[]Scope)(s))
+}
+
+// A campaign is a periodic review of the memberships of a set of
+// policies.  Every membership is enumerated into an entry that is
+// assigned to the owners of its policy.  Entries that have not been
+// reviewed by the deadline are revoked when the campaign closes, at
+// which point a signed report of every decision is produced.
+type Campaign struct {
+	Id        uuid.UUID        `json:"id"`
+	OrgId     uuid.UUID        `json:"org_id"`
+	Name      string           `json:"name"`
+	OwnerId   uuid.UUID        `json:"owner_id"`
+	Scopes    Scopes           `json:"scopes"`
+	Deadline  time.Time        `json:"deadline"`
+	Status    Status           `json:"status"`
+	Report    []byte           `json:"report,omitempty"`
+	Signature crypto.Signature `json:"signature"`
+	Version   int              `json:"version"`
+	Created   time.Time        `json:"created"`
+	Updated   time.Time        `json:"updated"`
+}
+
+func NewCampaign(orgId, ownerId uuid.UUID, name string, deadline time.Time, scopes ...Scope) (ret Campaign, err error) {
+	if strings.TrimSpace(name) == "" {
+		err = errors.Wrapf(errs.ArgError, "Must provide a name")
+		return
+	}
+	if len(scopes) == 0 {
+		err = errors.Wrapf(errs.ArgError, "Must review at least one item")
+		return
+	}
+
+	now := time.Now().UTC()
+	if !deadline.After(now) {
+		err = errors.Wrapf(errs.ArgError, "Deadline [%v] must be in the future", deadline)
+		return
+	}
+
+	ret = Campaign{
+		Id:       uuid.NewV1(),
+		OrgId:    orgId,
+		Name:     name,
+		OwnerId:  ownerId,
+		Scopes:   scopes,
+		Deadline: deadline.UTC(),
+		Status:   Open,
+		Created:  now,
+		Updated:  now,
+	}
+	return
+}
+
+func (c Campaign) Update(fn func(*Campaign)) (ret Campaign) {
+	ret = c
+	fn(&ret)
+	ret.Version = c.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+// Closes the campaign, attaching the signed report.
+func (c Campaign) Close(report []byte, sig crypto.Signature) (ret Campaign, err error) {
+	if c.Status != Open {
+		err = errors.Wrapf(ErrClosed, "Campaign [%v] is already closed", c.Id)
+		return
+	}
+
+	ret = c.Update(func(c *Campaign) {
+		c.Status = Closed
+		c.Report = report
+		c.Signature = sig
+	})
+	return
+}
+
+// Verifies the report of a closed campaign against the key that
+// signed it.
+func (c Campaign) VerifyReport(key crypto.PublicKey) (err error) {
+	if c.Status != Closed {
+		err = errors.Wrapf(errs.StateError, "Campaign [%v] has not closed", c.Id)
+		return
+	}
+
+	err = c.Signature.Verify(key, c.Report)
+	return
+}
+
+// Returns the decoded report of a closed campaign.
+func (c Campaign) DecodeReport() (ret Report, err error) {
+	if c.Status != Closed {
+		err = errors.Wrapf(errs.StateError, "Campaign [%v] has not closed", c.Id)
+		return
+	}
+
+	err = json.Unmarshal(c.Report, &ret)
+	return
+}
+
+// Returns the report of a closed campaign along with its signature,
+// for handing to auditors.
+func (c Campaign) SignedReport() (ret SignedReport, err error) {
+	if c.Status != Closed {
+		err = errors.Wrapf(errs.StateError, "Campaign [%v] has not closed", c.Id)
+		return
+	}
+
+	ret = SignedReport{json.RawMessage(c.Report), c.Signature}
+	return
+}
+
+// An entry is a single membership under review.  The effective
+// actions of users are the union of every path by which they reach
+// the policy.  Entries for groups, folders and proxies list the
+// actions of the membership itself.
+type ReviewEntry struct {
+	OrgId       uuid.UUID      `json:"org_id"`
+	CampaignId  uuid.UUID      `json:"campaign_id"`
+	PolicyId    uuid.UUID      `json:"policy_id"`
+	Item        string         `json:"item"`
+	MemberId    uuid.UUID      `json:"member_id"`
+	MemberType  policy.Type    `json:"member_type"`
+	MemberVer   int            `json:"member_ver"`
+	Actions     policy.Actions `json:"actions"`
+	Effective   policy.Actions `json:"effective"`
+	ReviewerIds Ids            `json:"reviewer_ids"`
+	Decision    Decision       `json:"decision"`
+	DeciderId   uuid.UUID      `json:"decider_id"`
+	Comment     string         `json:"comment"`
+	Applied     bool           `json:"applied"` // whether a revocation removed the membership
+	Version     int            `json:"version"`
+	Created     time.Time      `json:"created"`
+	Updated     time.Time      `json:"updated"`
+}
+
+// Enumerates the membership into an entry.  The member never reviews
+// their own membership.  Memberships with no other reviewer are
+// assigned to the owner of the campaign.
+func NewReviewEntry(c Campaign, scope Scope, m policy.PolicyMember, effective policy.Actions, owners []uuid.UUID) ReviewEntry {
+	var reviewers Ids
+	for _, id := range owners {
+		if id != m.MemberId && !reviewers.Contains(id) {
+			reviewers = append(reviewers, id)
+		}
+	}
+	if len(reviewers) == 0 {
+		reviewers = Ids{c.OwnerId}
+	}
+
+	now := time.Now().UTC()
+	return ReviewEntry{
+		OrgId:       c.OrgId,
+		CampaignId:  c.Id,
+		PolicyId:    scope.PolicyId,
+		Item:        scope.Item,
+		MemberId:    m.MemberId,
+		MemberType:  m.MemberType,
+		MemberVer:   m.Version,
+		Actions:     m.Actions,
+		Effective:   effective,
+		ReviewerIds: reviewers,
+		Decision:    Undecided,
+		Created:     now,
+		Updated:     now,
+	}
+}
+
+func (e ReviewEntry) Update(fn func(*ReviewEntry)) (ret ReviewEntry) {
+	ret = e
+	fn(&ret)
+	ret.Version = e.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+// Records the decision of a reviewer.  Decisions are final.
+func (e ReviewEntry) Decide(reviewerId uuid.UUID, decision Decision, comment string) (ret ReviewEntry, err error) {
+	if !e.ReviewerIds.Contains(reviewerId) {
+		err = errors.Wrapf(ErrNotAssigned, "Not a reviewer of [%v] on [%v]", e.MemberType.FormatId(e.MemberId), e.Item)
+		return
+	}
+	if e.Decision != Undecided {
+		err = errors.Wrapf(ErrDecided, "Entry is already decided [%v]", e.Decision)
+		return
+	}
+	if decision != Keep && decision != Revoke {
+		err = errors.Wrapf(errs.ArgError, "Invalid decision [%v]. Expected one of [keep, revoke]", decision)
+		return
+	}
+
+	ret = e.Update(func(e *ReviewEntry) {
+		e.Decision = decision
+		e.DeciderId = reviewerId
+		e.Comment = comment
+	})
+	return
+}
+
+func (e ReviewEntry) Lapse() ReviewEntry {
+	return e.Update(func(e *ReviewEntry) {
+		e.Decision = Lapsed
+	})
+}
+
+func (e ReviewEntry) Apply() ReviewEntry {
+	return e.Update(func(e *ReviewEntry) {
+		e.Applied = true
+	})
+}
+
+func (e ReviewEntry) GetOrgId() uuid.UUID {
+	return e.OrgId
+}
+
+func (e ReviewEntry) GetPolicyId() uuid.UUID {
+	return e.PolicyId
+}
+
+type ReviewFilter struct {
+	ReviewerId *uuid.UUID `json:"reviewer_id,omitempty"`
+	Decision   *Decision  `json:"decision,omitempty"`
+}
+
+func BuildReviewFilter(fns ...func(*ReviewFilter)) (ret ReviewFilter) {
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+func FilterByReviewer(id uuid.UUID) func(*ReviewFilter) {
+	return func(f *ReviewFilter) {
+		f.ReviewerId = &id
+	}
+}
+
+func FilterByDecision(d Decision) func(*ReviewFilter) {
+	return func(f *ReviewFilter) {
+		f.Decision = &d
+	}
+}
+
+// The final record of a campaign.
+type Report struct {
+	CampaignId uuid.UUID     `json:"campaign_id"`
+	OrgId      uuid.UUID     `json:"org_id"`
+	Name       string        `json:"name"`
+	OwnerId    uuid.UUID     `json:"owner_id"`
+	Deadline   time.Time     `json:"deadline"`
+	Closed     time.Time     `json:"closed"`
+	Kept       int           `json:"kept"`
+	Revoked    int           `json:"revoked"`
+	Lapsed     int           `json:"lapsed"`
+	Entries    []ReviewEntry `json:"entries"`
+}
+
+// A report as signed by the server.  The signature covers the exact
+// bytes of the report.
+type SignedReport struct {
+	Report    json.RawMessage  `json:"report"`
+	Signature crypto.Signature `json:"signature"`
+}
+
+func NewReport(c Campaign, now time.Time, entries []ReviewEntry) (ret Report) {
+	ret = Report{
+		CampaignId: c.Id,
+		OrgId:      c.OrgId,
+		Name:       c.Name,
+		OwnerId:    c.OwnerId,
+		Deadline:   c.Deadline,
+		Closed:     now,
+		Entries:    append([]ReviewEntry{}, entries...),
+	}
+
+	sort.Slice(ret.Entries, func(i, j int) bool {
+		a, b := ret.Entries[i], ret.Entries[j]
+		if a.Item != b.Item {
+			return a.Item < b.Item
+		}
+		return a.MemberId.String() < b.MemberId.String()
+	})
+
+	for _, e := range ret.Entries {
+		switch e.Decision {
+		case Keep:
+			ret.Kept++
+		case Revoke:
+			ret.Revoked++
+		case Lapsed:
+			ret.Lapsed++
+		}
+	}
+	return
+}
+
+// Revokes the membership of the entry.  A membership that has changed
+// since it was enumerated is left in place, as is the last membership
+// holding sudo on a policy, as removing it would leave the policy
+// without an owner.  Returns whether the membership was removed.
+func RevokeEntry(db policy.Storage, e ReviewEntry) (ok bool, err error) {
+	member, found, err := db.LoadPolicyMember(e.OrgId, e.PolicyId, e.MemberId)
+	if err != nil || !found || member.Deleted || member.Version != e.MemberVer {
+		return
+	}
+
+	if member.Actions.Enabled(policy.Sudo) {
+		all, err := db.ListPolicyMembers(e.OrgId, e.PolicyId, page.BuildPage())
+		if err != nil {
+			return false, err
+		}
+
+		owners := 0
+		for _, m := range all {
+			if m.Actions.Enabled(policy.Sudo) {
+				owners++
+			}
+		}
+		if owners <= 1 {
+			return false, nil
+		}
+	}
+
+	if err = db.SavePolicyMember(member.Delete()); err != nil {
+		return
+	}
+
+	ok = true
+	return
+}
+
+// Closes the campaign.  Entries that were not reviewed are lapsed and
+// revoked, and the report of every decision is signed by the signer.
+func CloseCampaign(db Storage, policies policy.Storage, signer crypto.Signer, c Campaign, now time.Time) (ret Campaign, err error) {
+	if c.Status != Open {
+		err = errors.Wrapf(ErrClosed, "Campaign [%v] is already closed", c.Id)
+		return
+	}
+
+	entries, err := db.ListReviewEntries(c.OrgId, c.Id, ReviewFilter{}, page.BuildPage())
+	if err != nil {
+		return
+	}
+
+	for i, e := range entries {
+		if e.Decision != Undecided {
+			continue
+		}
+
+		e = e.Lapse()
+
+		applied, err := RevokeEntry(policies, e)
+		if err != nil {
+			return ret, err
+		}
+		if applied {
+			e = e.Apply()
+		}
+
+		if err := db.SaveReviewEntries(e); err != nil {
+			return ret, err
+		}
+		entries[i] = e
+	}
+
+	raw, err := json.Marshal(NewReport(c, now, entries))
+	if err != nil {
+		return
+	}
+
+	sig, err := signer.Sign(crypto.Rand, crypto.SHA256, raw)
+	if err != nil {
+		return
+	}
+
+	ret, err = c.Close(raw, sig)
+	if err != nil {
+		return
+	}
+
+	err = db.SaveCampaign(ret)
+	return
+}
@@ -0,0 +1,67 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Storage interface {
+
+	// Saves a new version of an access request.
+	SaveRequest(Request) error
+
+	// Loads the latest version of an access request.
+	LoadRequest(orgId, requestId uuid.UUID) (Request, bool, error)
+
+	// Lists the latest versions of the requests of an org, newest first.
+	ListRequests(orgId uuid.UUID, filter Filter, page page.Page) ([]Request, error)
+
+	// Lists the approved requests (across all orgs) whose grants expired
+	// at or before the given time.
+	ListExpiredRequests(now time.Time, page page.Page) ([]Request, error)
+
+	// Saves the approvers of a policy.
+	SaveApprovers(Approvers) error
+
+	// Loads the approvers of a policy.
+	LoadApprovers(orgId, policyId uuid.UUID) (Approvers, bool, error)
+
+	// Saves the break-glass escrow of a policy.
+	SaveEscrow(Escrow) error
+
+	// Loads the break-glass escrow of a policy.
+	LoadEscrow(orgId, policyId uuid.UUID) (Escrow, bool, error)
+
+	// Saves a new version of an unlock.
+	SaveUnlock(Unlock) error
+
+	// Loads the latest version of an unlock.
+	LoadUnlock(orgId, unlockId uuid.UUID) (Unlock, bool, error)
+
+	// Lists the latest versions of the unlocks of an org, newest first.
+	ListUnlocks(orgId uuid.UUID, filter Filter, page page.Page) ([]Unlock, error)
+
+	// Saves a new version of a review campaign.
+	SaveCampaign(Campaign) error
+
+	// Loads the latest version of a review campaign.
+	LoadCampaign(orgId, campaignId uuid.UUID) (Campaign, bool, error)
+
+	// Lists the latest versions of the campaigns of an org, newest first.
+	ListCampaigns(orgId uuid.UUID, page page.Page) ([]Campaign, error)
+
+	// Lists the open campaigns (across all orgs) whose deadlines passed
+	// at or before the given time.
+	ListDueCampaigns(now time.Time, page page.Page) ([]Campaign, error)
+
+	// Saves new versions of the entries of a campaign.
+	SaveReviewEntries(...ReviewEntry) error
+
+	// Loads the latest version of the entry of a member.
+	LoadReviewEntry(orgId, campaignId, policyId, memberId uuid.UUID) (ReviewEntry, bool, error)
+
+	// Lists the latest versions of the entries of a campaign.
+	ListReviewEntries(orgId, campaignId uuid.UUID, filter ReviewFilter, page page.Page) ([]ReviewEntry, error)
+}
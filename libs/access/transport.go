@@ -0,0 +1,79 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Transport interface {
+
+	// Requests temporary access to the items of a policy.  The approvers
+	// of the policy are notified.
+	RequestAccess(t auth.SignedToken, orgId, policyId uuid.UUID, item string, dur time.Duration, reason string, actions []policy.Action) (Request, error)
+
+	// Loads an access request.
+	LoadRequest(t auth.SignedToken, orgId, requestId uuid.UUID) (Request, bool, error)
+
+	// Lists the access requests of an org.
+	ListRequests(t auth.SignedToken, orgId uuid.UUID, filter Filter, page page.Page) ([]Request, error)
+
+	// Approves a request, granting the membership to the requester.
+	ApproveRequest(t auth.SignedToken, orgId, requestId uuid.UUID, member policy.PolicyMember, comment string) error
+
+	// Denies a request.
+	DenyRequest(t auth.SignedToken, orgId, requestId uuid.UUID, comment string) error
+
+	// Configures the approvers of a policy.
+	SaveApprovers(t auth.SignedToken, a Approvers) error
+
+	// Loads the approvers of a policy.
+	LoadApprovers(t auth.SignedToken, orgId, policyId uuid.UUID) (Approvers, bool, error)
+
+	// Saves the break-glass escrow of a policy.
+	SaveEscrow(t auth.SignedToken, e Escrow) error
+
+	// Loads the break-glass escrow of a policy.
+	LoadEscrow(t auth.SignedToken, orgId, policyId uuid.UUID) (Escrow, bool, error)
+
+	// Requests emergency access to the items of a policy.  The
+	// custodians of the policy are notified.
+	RequestUnlock(t auth.SignedToken, orgId, policyId uuid.UUID, item, reason string) (Unlock, error)
+
+	// Loads an unlock.
+	LoadUnlock(t auth.SignedToken, orgId, unlockId uuid.UUID) (Unlock, bool, error)
+
+	// Lists the unlocks of an org.
+	ListUnlocks(t auth.SignedToken, orgId uuid.UUID, filter Filter, page page.Page) ([]Unlock, error)
+
+	// Hands over a custodian's share, encrypted for the requester.
+	SubmitShare(t auth.SignedToken, orgId, unlockId uuid.UUID, share policy.MemberSecret) error
+
+	// Claims an unlock, granting the emergency membership.
+	ClaimUnlock(t auth.SignedToken, orgId, unlockId uuid.UUID, member policy.PolicyMember) error
+
+	// Starts a review campaign over the items of the policies.  Every
+	// membership is enumerated into an entry and its reviewers are
+	// notified.
+	StartCampaign(t auth.SignedToken, orgId uuid.UUID, name string, deadline time.Time, scopes []Scope) (Campaign, error)
+
+	// Loads a review campaign.
+	LoadCampaign(t auth.SignedToken, orgId, campaignId uuid.UUID) (Campaign, bool, error)
+
+	// Lists the review campaigns of an org.
+	ListCampaigns(t auth.SignedToken, orgId uuid.UUID, page page.Page) ([]Campaign, error)
+
+	// Lists the entries of a review campaign.
+	ListReviewEntries(t auth.SignedToken, orgId, campaignId uuid.UUID, filter ReviewFilter, page page.Page) ([]ReviewEntry, error)
+
+	// Keeps or revokes the membership of an entry.  Revocations take
+	// effect immediately.
+	DecideEntry(t auth.SignedToken, orgId, campaignId, policyId, memberId uuid.UUID, decision Decision, comment string) (ReviewEntry, error)
+
+	// Closes a review campaign ahead of its deadline, revoking any
+	// entries that were not reviewed.
+	CloseCampaign(t auth.SignedToken, orgId, campaignId uuid.UUID) (Campaign, error)
+}
@@ -20,8 +20,8 @@ type Transport interface {
 	// Loads an identity.  Only publicly consumable information is returned
 	LoadIdentity(t auth.SignedToken, id auth.Identity) (Identity, bool, error)
 
-	// Loads an account's identities
-	ListIdentitiesByAccountId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Identity, error)
+	// Loads an account's identities, along with the cursor of the next page
+	ListIdentitiesByAccountId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Identity, page.Cursor, error)
 
 	// Loads an account's identities
 	ListIdentitiesByAccountIds(t auth.SignedToken, acctIds []uuid.UUID) (map[uuid.UUID][]Identity, error)
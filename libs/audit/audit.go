@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	SecretCreate   Action = "secret.create"
+	SecretUpdate   Action = "secret.update"
+	SecretDelete   Action = "secret.delete"
+	SecretRead     Action = "secret.read"
+	SecretDownload Action = "secret.download"
+	PolicyCreate   Action = "policy.create"
+	PolicyGrant    Action = "policy.grant"
+	PolicyRevoke   Action = "policy.revoke"
+	PolicyRekey    Action = "policy.rekey"
+	MemberAdd      Action = "member.add"
+	MemberUpdate   Action = "member.update"
+	MemberRemove   Action = "member.remove"
+	Login          Action = "login"
+	AccessRequest  Action = "access.request"
+	AccessApprove  Action = "access.approve"
+	AccessDeny     Action = "access.deny"
+	AccessExpire   Action = "access.expire"
+	EscrowSave     Action = "breakglass.escrow"
+	UnlockRequest  Action = "breakglass.request"
+	UnlockShare    Action = "breakglass.share"
+	UnlockClaim    Action = "breakglass.claim"
+	ReviewStart    Action = "review.start"
+	ReviewDecide   Action = "review.decide"
+	ReviewClose    Action = "review.close"
+)
+
+const (
+	Success Result = "success"
+	Denied  Result = "denied"
+	Failure Result = "failure"
+)
+
+type Action string
+
+type Result string
+
+// An event is an immutable record of an action taken by an
+// actor against a target within an org.
+type Event struct {
+	Id      uuid.UUID `json:"id"`
+	OrgId   uuid.UUID `json:"org_id"`
+	ActorId uuid.UUID `json:"actor_id"`
+	Device  string    `json:"device,omitempty"`
+	Login   string    `json:"login"`
+	Action  Action    `json:"action"`
+	Target  string    `json:"target"`
+	Remote  string    `json:"remote"`
+	Result  Result    `json:"result"`
+	Detail  string    `json:"detail,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func NewEvent(orgId, actorId uuid.UUID, action Action, target string, fns ...func(*Event)) (ret Event) {
+	ret = Event{
+		Id:      uuid.NewV1(),
+		OrgId:   orgId,
+		ActorId: actorId,
+		Action:  action,
+		Target:  target,
+		Result:  Success,
+		Created: time.Now().UTC(),
+	}
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+// Sets the device and login of the token that performed the action.
+func WithToken(device, login string) func(*Event) {
+	return func(e *Event) {
+		e.Device, e.Login = device, login
+	}
+}
+
+// Sets the remote address of the request that performed the action.
+func WithRemote(remote string) func(*Event) {
+	return func(e *Event) {
+		e.Remote = remote
+	}
+}
+
+// Sets any additional context of the action (e.g. the member that
+// was granted access to a policy)
+func WithDetail(detail string) func(*Event) {
+	return func(e *Event) {
+		e.Detail = detail
+	}
+}
+
+func WithResult(result Result, detail string) func(*Event) {
+	return func(e *Event) {
+		e.Result, e.Detail = result, detail
+	}
+}
+
+// Formats the target of an account-centric event.
+func AccountTarget(acctId uuid.UUID) string {
+	return fmt.Sprintf("user://%v", acctId)
+}
+
+// Formats the target of a review campaign event.
+func CampaignTarget(campaignId uuid.UUID) string {
+	return fmt.Sprintf("review://%v", campaignId)
+}
+
+// Formats the target of a policy-centric event.
+func PolicyTarget(policyId uuid.UUID) string {
+	return fmt.Sprintf("policy://%v", policyId)
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// A file sink writes events as json lines to a file.  Once the file
+// exceeds its maximum size, it is rotated to <path>.1 and any
+// previously rotated files are shifted.  At most maxFiles rotated
+// files are kept.
+type FileSink struct {
+	lock     sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func NewFileSink(path string, maxSize int64, maxFiles int) (ret *FileSink, err error) {
+	ret = &FileSink{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	err = ret.open()
+	return
+}
+
+func (f *FileSink) Write(e Event) (err error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return errors.Wrapf(ErrSinkClosed, "Audit file [%v] closed", f.path)
+	}
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(raw))+1 > f.maxSize {
+		if err = f.rotate(); err != nil {
+			return
+		}
+	}
+
+	n, err := f.file.Write(append(raw, '\n'))
+	f.size += int64(n)
+	return
+}
+
+func (f *FileSink) Close() (err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return
+	}
+
+	err, f.file = f.file.Close(), nil
+	return
+}
+
+func (f *FileSink) open() (err error) {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to open audit file [%v]", f.path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return
+	}
+
+	f.file, f.size = file, info.Size()
+	return
+}
+
+func (f *FileSink) rotate() (err error) {
+	if err = f.file.Close(); err != nil {
+		return
+	}
+
+	if f.maxFiles > 0 {
+		os.Remove(rotated(f.path, f.maxFiles))
+		for i := f.maxFiles - 1; i > 0; i-- {
+			if err = os.Rename(rotated(f.path, i), rotated(f.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return
+			}
+		}
+
+		if err = os.Rename(f.path, rotated(f.path, 1)); err != nil {
+			return
+		}
+	} else {
+		if err = os.Remove(f.path); err != nil {
+			return
+		}
+	}
+
+	return f.open()
+}
+
+func rotated(path string, i int) string {
+	return fmt.Sprintf("%v.%v", path, i)
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type Filter struct {
+	ActorId *uuid.UUID `json:"actor_id,omitempty"`
+	Target  *string    `json:"target,omitempty"`
+	Action  *Action    `json:"action,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+func BuildFilter(fns ...func(*Filter)) (ret Filter) {
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+func FilterByActor(id uuid.UUID) func(*Filter) {
+	return func(f *Filter) {
+		f.ActorId = &id
+	}
+}
+
+// Filters events whose target begins with the given prefix.
+func FilterByTarget(prefix string) func(*Filter) {
+	return func(f *Filter) {
+		f.Target = &prefix
+	}
+}
+
+func FilterByAction(action Action) func(*Filter) {
+	return func(f *Filter) {
+		f.Action = &action
+	}
+}
+
+func FilterSince(t time.Time) func(*Filter) {
+	return func(f *Filter) {
+		f.Since = &t
+	}
+}
@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/metrics"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSinkClosed = errors.New("Audit:SinkClosed")
+)
+
+// The events that never reached a sink, by reason (full or error).
+var sinkDropped = metrics.NewCounter(
+	"stash_audit_sink_dropped_total", "Number of audit events dropped by sinks", "reason")
+
+// A sink ships audit events out of stash (e.g. to a SIEM).
+type Sink interface {
+	io.Closer
+
+	// Writes an event to the sink.
+	Write(Event) error
+}
+
+// A json sink writes each event as a single line of json.
+type JsonSink struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+func NewJsonSink(out io.Writer) *JsonSink {
+	return &JsonSink{out: out}
+}
+
+func (j *JsonSink) Write(e Event) (err error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	_, err = j.out.Write(append(raw, '\n'))
+	return
+}
+
+func (j *JsonSink) Close() error {
+	return nil
+}
+
+// Writes events to all the given sinks.  Every sink is attempted
+// and the first error is returned.
+type MultiSink []Sink
+
+func (m MultiSink) Write(e Event) (err error) {
+	for _, s := range m {
+		if e := s.Write(e); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (m MultiSink) Close() (err error) {
+	for _, s := range m {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// A buffered sink decouples the writer from a (potentially slow)
+// sink.  Writes never block - if the buffer is full, the event is
+// dropped and counted.  Buffered events are flushed on close, and
+// writes after close are rejected.
+type BufferedSink struct {
+	ctx     context.Context
+	sink    Sink
+	events  chan Event
+	done    chan struct{}
+	dropped uint64
+
+	// Guards the closed flag, so that no event is buffered once the
+	// buffer has been flushed.
+	lock   sync.Mutex
+	closed bool
+}
+
+func NewBufferedSink(ctx context.Context, sink Sink, size int) (ret *BufferedSink) {
+	ret = &BufferedSink{
+		ctx:    ctx.Sub("AuditSink"),
+		sink:   sink,
+		events: make(chan Event, size),
+		done:   make(chan struct{}),
+	}
+	go ret.run()
+	return
+}
+
+// Returns the number of events that have been dropped.
+func (b *BufferedSink) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *BufferedSink) Write(e Event) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return errors.Wrapf(ErrSinkClosed, "Audit sink closed")
+	}
+
+	select {
+	case b.events <- e:
+	default:
+		sinkDropped.Inc("full")
+		b.ctx.Logger().Error("Audit sink full. Dropped event [%v] (total=%v)",
+			e.Id, atomic.AddUint64(&b.dropped, 1))
+	}
+	return nil
+}
+
+func (b *BufferedSink) Close() error {
+	b.shut()
+	b.ctx.Close()
+	<-b.done
+	return b.sink.Close()
+}
+
+func (b *BufferedSink) run() {
+	defer close(b.done)
+	for {
+		select {
+		case <-b.ctx.Control().Closed():
+			// The context may be closed by its parent, rather than by Close.
+			b.shut()
+			for {
+				select {
+				case e := <-b.events:
+					b.write(e)
+				default:
+					return
+				}
+			}
+		case e := <-b.events:
+			b.write(e)
+		}
+	}
+}
+
+// Stops accepting writes.
+func (b *BufferedSink) shut() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+}
+
+func (b *BufferedSink) write(e Event) {
+	if err := b.sink.Write(e); err != nil {
+		b.ctx.Logger().Error("Error writing audit event [%v]: %v", e.Id, err)
+	}
+}
+
+// Returns a storage that additionally writes every saved event to the sink.
+// Events are durable once stored, so failing to ship them to the sink
+// is counted rather than failing the caller.
+func WithSink(store Storage, sink Sink) Storage {
+	return &sinkStorage{store, sink}
+}
+
+type sinkStorage struct {
+	Storage
+	sink Sink
+}
+
+func (s *sinkStorage) SaveEvents(events ...Event) (err error) {
+	if err = s.Storage.SaveEvents(events...); err != nil {
+		return
+	}
+
+	for _, e := range events {
+		if s.sink.Write(e) != nil {
+			sinkDropped.Inc("error")
+		}
+	}
+	return
+}
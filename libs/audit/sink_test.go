@@ -0,0 +1,268 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowSink struct {
+	lock    sync.Mutex
+	delay   time.Duration
+	written []Event
+}
+
+func (s *slowSink) Write(e Event) error {
+	time.Sleep(s.delay)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.written = append(s.written, e)
+	return nil
+}
+
+func (s *slowSink) Close() error {
+	return nil
+}
+
+func TestBufferedSink(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	slow := &slowSink{delay: 50 * time.Millisecond}
+	sink := NewBufferedSink(ctx, slow, 2)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, sink.Write(NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a")))
+	}
+
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+
+	dropped := int(sink.Dropped())
+	assert.True(t, dropped > 0)
+
+	assert.Nil(t, sink.Close())
+	assert.Equal(t, 10-dropped, len(slow.written))
+	assert.NotNil(t, sink.Write(NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a")))
+}
+
+// Writes racing with close are either flushed or rejected.
+func TestBufferedSink_CloseRace(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	slow := &slowSink{}
+	sink := NewBufferedSink(ctx, slow, 1024)
+
+	var accepted int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := sink.Write(NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a"))
+				if err != nil {
+					assert.True(t, errs.Is(err, ErrSinkClosed), "%v", err)
+					return
+				}
+				atomic.AddInt64(&accepted, 1)
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	assert.Nil(t, sink.Close())
+	wg.Wait()
+
+	assert.Equal(t, uint64(0), sink.Dropped())
+	assert.Equal(t, int(atomic.LoadInt64(&accepted)), len(slow.written))
+
+	err := sink.Write(NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a"))
+	assert.True(t, errs.Is(err, ErrSinkClosed), "%v", err)
+}
+
+type memStorage struct {
+	events []Event
+}
+
+func (m *memStorage) SaveEvents(events ...Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memStorage) ListEvents(uuid.UUID, Filter, page.Page) ([]Event, error) {
+	return m.events, nil
+}
+
+func TestWithSink_Closed(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	sink := NewBufferedSink(ctx, &slowSink{}, 1)
+	assert.Nil(t, sink.Close())
+
+	// Events are stored even when the sink is unavailable.
+	store := &memStorage{}
+	assert.Nil(t, WithSink(store, sink).SaveEvents(NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a")))
+	assert.Equal(t, 1, len(store.events))
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+
+	event := NewEvent(uuid.NewV1(), uuid.NewV1(), SecretDownload, "secret:///prod/db")
+	raw, _ := json.Marshal(event)
+
+	sink, err := NewFileSink(path, int64(2*(len(raw)+1)), 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for i := 0; i < 7; i++ {
+		assert.Nil(t, sink.Write(event))
+	}
+	assert.Nil(t, sink.Close())
+
+	for _, file := range []string{path, path + ".1", path + ".2"} {
+		lines, err := readLines(file)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var act Event
+		assert.Nil(t, json.Unmarshal([]byte(lines[0]), &act))
+		assert.Equal(t, event.Id, act.Id)
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "stash")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	event := NewEvent(uuid.NewV1(), uuid.NewV1(), SecretRead, "secret:///prod/db",
+		WithResult(Denied, "Unauthorized"))
+	if !assert.Nil(t, sink.Write(event)) {
+		return
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, "<84>1 "))
+	assert.Contains(t, msg, " stash ")
+	assert.Contains(t, msg, "[audit@32473 org=\""+event.OrgId.String()+"\"")
+	assert.Contains(t, msg, "target=\"secret:///prod/db\"")
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+
+	msgs := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					prefix, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+
+					n, err := strconv.Atoi(strings.TrimSpace(prefix))
+					if err != nil {
+						return
+					}
+
+					buf := make([]byte, n)
+					if _, err := io.ReadFull(r, buf); err != nil {
+						return
+					}
+					msgs <- string(buf)
+				}
+			}()
+		}
+	}()
+
+	sink, err := NewSyslogSink("tcp", l.Addr().String(), "stash")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer sink.Close()
+
+	event := NewEvent(uuid.NewV1(), uuid.NewV1(), Login, "user://a")
+	for i := 0; i < 2; i++ {
+		if !assert.Nil(t, sink.Write(event)) {
+			return
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(5 * time.Second):
+			t.FailNow()
+		case msg := <-msgs:
+			assert.True(t, strings.HasPrefix(msg, "<86>1 "))
+			assert.True(t, strings.HasSuffix(msg, "}"))
+		}
+	}
+}
+
+func readLines(file string) (ret []string, err error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	ret = strings.Split(strings.TrimSpace(string(raw)), "\n")
+	return
+}
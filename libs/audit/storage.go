@@ -0,0 +1,17 @@
+package audit
+
+import (
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+// The audit log is append-only.  Events may never be updated
+// or deleted once saved.
+type Storage interface {
+
+	// Appends events to the log.
+	SaveEvents(...Event) error
+
+	// Lists the events of an org matching the filter, newest first.
+	ListEvents(orgId uuid.UUID, filter Filter, page page.Page) ([]Event, error)
+}
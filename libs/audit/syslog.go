@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// The facility of all messages (security/authorization messages)
+	SyslogFacility = 10
+
+	// The structured data id of the audit event fields.
+	SyslogDataId = "audit@32473"
+)
+
+const (
+	severityWarning = 4
+	severityInfo    = 6
+)
+
+// A syslog sink writes events as RFC 5424 messages over udp or tcp.
+// TCP messages are framed using octet counting (RFC 6587) and the
+// connection is re-established on failure.
+type SyslogSink struct {
+	lock     sync.Mutex
+	network  string
+	addr     string
+	hostname string
+	appName  string
+	timeout  time.Duration
+	conn     net.Conn
+}
+
+func NewSyslogSink(network, addr, appName string) (ret *SyslogSink, err error) {
+	switch network {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Unsupported syslog network [%v]. Expected ['udp','tcp']", network)
+		return
+	case "udp", "tcp":
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	ret = &SyslogSink{
+		network:  network,
+		addr:     addr,
+		hostname: hostname,
+		appName:  appName,
+		timeout:  10 * time.Second,
+	}
+
+	ret.lock.Lock()
+	defer ret.lock.Unlock()
+	err = ret.dial()
+	return
+}
+
+func (s *SyslogSink) Write(e Event) (err error) {
+	msg, err := FormatSyslog(s.hostname, s.appName, e)
+	if err != nil {
+		return
+	}
+
+	if s.network == "tcp" {
+		msg = fmt.Sprintf("%v %v", len(msg), msg)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// a single reconnect is attempted for stream connections.
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				return
+			}
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		if _, err = s.conn.Write([]byte(msg)); err == nil {
+			return
+		}
+
+		s.conn.Close()
+		s.conn = nil
+	}
+	return
+}
+
+func (s *SyslogSink) Close() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return
+	}
+
+	err, s.conn = s.conn.Close(), nil
+	return
+}
+
+func (s *SyslogSink) dial() (err error) {
+	s.conn, err = net.DialTimeout(s.network, s.addr, s.timeout)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to connect to syslog [%v://%v]", s.network, s.addr)
+	}
+	return
+}
+
+// Formats an event as an RFC 5424 syslog message.  The fields of the
+// event are included as structured data and the message body is the
+// json encoded event.
+func FormatSyslog(hostname, appName string, e Event) (ret string, err error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	severity := severityInfo
+	if e.Result != Success {
+		severity = severityWarning
+	}
+
+	ret = fmt.Sprintf("<%v>1 %v %v %v %v %v [%v org=\"%v\" actor=\"%v\" action=\"%v\" target=\"%v\" result=\"%v\" remote=\"%v\"] %s",
+		SyslogFacility*8+severity,
+		e.Created.UTC().Format(time.RFC3339Nano),
+		syslogHeader(hostname, 255),
+		syslogHeader(appName, 48),
+		os.Getpid(),
+		syslogHeader(string(e.Action), 32),
+		SyslogDataId,
+		e.OrgId,
+		e.ActorId,
+		syslogParam(string(e.Action)),
+		syslogParam(e.Target),
+		syslogParam(string(e.Result)),
+		syslogParam(e.Remote),
+		body)
+	return
+}
+
+// Header fields must be printable ascii without spaces.
+func syslogHeader(val string, max int) string {
+	val = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, val)
+	if val == "" {
+		return "-"
+	}
+	if len(val) > max {
+		return val[:max]
+	}
+	return val
+}
+
+var syslogEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func syslogParam(val string) string {
+	return syslogEscaper.Replace(val)
+}
@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Transport interface {
+
+	// Lists the audit events of an org matching the filter, newest first.
+	ListEvents(t auth.SignedToken, orgId uuid.UUID, filter Filter, page page.Page) ([]Event, error)
+}
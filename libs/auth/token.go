@@ -44,6 +44,7 @@ type AccountToken struct {
 	Expires      int64     `json:"expires"`
 	LoginUri     string    `json:"login_uri"`
 	LoginVersion int       `json:"login_version"`
+	DeviceId     string    `json:"device_id,omitempty"`
 }
 
 func (c AccountToken) Expired(now time.Time) bool {
@@ -95,6 +96,12 @@ func ClaimLogin(uri string, ver int) Builder {
 	}
 }
 
+func ClaimDevice(id string) Builder {
+	return func(c *Claim) {
+		c.Account.DeviceId = id
+	}
+}
+
 func ClaimExpires(ttl time.Duration) Builder {
 	return func(c *Claim) {
 		c.Account.Expires = time.Now().Add(ttl).Unix()
@@ -134,8 +141,8 @@ func Assert(cond bool, fmt string, args ...interface{}) (ret error) {
 
 func ParseClaims(req headers.Headers, pub crypto.PublicKey) (ret Claim, err error) {
 	raw, err := jwt.ReadToken(req, pub, &ret)
-	if err != nil {
-		err = errs.Or(err, errors.Wrapf(ErrTokenInvalid, "Invalid token"))
+	if err != nil || raw == nil {
+		err = errs.Or(err, errors.Wrapf(ErrTokenInvalid, "Missing token"))
 		return
 	}
 	ret = *(raw.Claims.(*Claim))
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/headers"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenHeaders map[string]string
+
+func (t tokenHeaders) ReadHeader(name string, ptr *string) (ok bool) {
+	*ptr, ok = t[name]
+	return
+}
+
+func TestParseClaims(t *testing.T) {
+	key, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	acctId := uuid.NewV1()
+	signed, err := SignClaims(key, BuildClaim(ClaimAccount(ByEmail("user@example.com"), acctId), ClaimExpires(time.Minute)))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	claim, err := ParseAndAssertClaims(tokenHeaders{headers.Authorization: fmt.Sprintf("Bearer %v", signed)}, key.Public())
+	if assert.Nil(t, err) {
+		assert.Equal(t, acctId, claim.Account.Id)
+	}
+
+	_, err = ParseAndAssertClaims(tokenHeaders{}, key.Public())
+	assert.True(t, errs.Is(err, ErrTokenInvalid))
+}
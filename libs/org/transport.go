@@ -39,11 +39,11 @@ type Transport interface {
 	// Loads a membership for a given account and org.
 	LoadMember(t auth.SignedToken, orgId, acctId uuid.UUID) (Member, bool, error)
 
-	// Lists the memberships for an account
-	ListMembersByAccountId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Member, error)
+	// Lists the memberships for an account, along with the cursor of the next page
+	ListMembersByAccountId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Member, page.Cursor, error)
 
-	// Lists the memberships for an organization
-	ListMembersByOrgId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Member, error)
+	// Lists the memberships for an organization, along with the cursor of the next page
+	ListMembersByOrgId(t auth.SignedToken, acctId uuid.UUID, opts page.Page) ([]Member, page.Cursor, error)
 
 	// Loads a particular subscription
 	LoadSubscription(t auth.SignedToken, orgId uuid.UUID) (SubscriptionSummary, bool, error)
@@ -54,6 +54,6 @@ type Transport interface {
 	// Lists the subscriptions of an account
 	DeleteSubscription(t auth.SignedToken, orgId uuid.UUID) error
 
-	// Lists the subscriptions of an account
-	ListInvoices(t auth.SignedToken, orgId uuid.UUID, opts page.Page) ([]billing.Invoice, error)
+	// Lists the invoices of an organization, along with the cursor of the next page
+	ListInvoices(t auth.SignedToken, orgId uuid.UUID, opts page.Page) ([]billing.Invoice, page.Cursor, error)
 }
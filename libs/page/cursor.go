@@ -0,0 +1,78 @@
+package page
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrCursor = errors.New("Page:InvalidCursor")
+)
+
+// A cursor is an opaque position in a listing, from which the listing
+// may be continued.  Cursors carry the keys of the last item listed, and
+// are signed by the server that issued them for the listing they were
+// issued by.  The empty cursor marks the end of a listing.
+type Cursor string
+
+// Returns a cursor to the keys, signed for the scope of a listing.
+func NewCursor(signer crypto.Signer, scope string, keys ...interface{}) (ret Cursor, err error) {
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		err = errors.Wrapf(err, "Error encoding cursor keys [%v]", keys)
+		return
+	}
+
+	sig, err := signer.Sign(crypto.Rand, crypto.SHA256, cursorBytes(scope, raw))
+	if err != nil {
+		return
+	}
+
+	ret = Cursor(
+		base64.RawURLEncoding.EncodeToString(raw) + "." +
+			base64.RawURLEncoding.EncodeToString(sig.Data))
+	return
+}
+
+// Verifies that the cursor was issued for the scope, returning its keys.
+// Numeric keys are returned as json numbers.
+func (c Cursor) Keys(pub crypto.PublicKey, scope string) (ret []interface{}, err error) {
+	parts := strings.Split(string(c), ".")
+	if len(parts) != 2 {
+		err = errors.Wrapf(ErrCursor, "Malformed cursor")
+		return
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		err = errors.Wrapf(ErrCursor, "Malformed cursor keys [%v]", err)
+		return
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = errors.Wrapf(ErrCursor, "Malformed cursor signature [%v]", err)
+		return
+	}
+
+	if err = pub.Verify(crypto.SHA256, cursorBytes(scope, raw), sig); err != nil {
+		err = errors.Wrapf(ErrCursor, "Cursor was not issued for this listing")
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err = dec.Decode(&ret); err != nil {
+		err = errors.Wrapf(ErrCursor, "Malformed cursor keys [%v]", err)
+	}
+	return
+}
+
+func cursorBytes(scope string, keys []byte) []byte {
+	return append([]byte(scope+"\x00"), keys...)
+}
@@ -0,0 +1,56 @@
+package page
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	key, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	id := uuid.NewV1()
+	cursor, err := NewCursor(key, "/v1/orgs/1/secrets_list", "/name", id, 3)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	keys, err := cursor.Keys(key.Public(), "/v1/orgs/1/secrets_list")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, []interface{}{"/name", id.String(), json.Number("3")}, keys)
+
+	other, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// The keys are replaced with ["/other"].
+	tampered := Cursor("WyIvb3RoZXIiXQ." + strings.SplitN(string(cursor), ".", 2)[1])
+
+	tests := map[string]struct {
+		cursor Cursor
+		key    crypto.PublicKey
+		scope  string
+	}{
+		"WrongScope": {cursor, key.Public(), "/v1/orgs/2/secrets_list"},
+		"WrongKey":   {cursor, other.Public(), "/v1/orgs/1/secrets_list"},
+		"Malformed":  {"abc", key.Public(), "/v1/orgs/1/secrets_list"},
+		"Tampered":   {tampered, key.Public(), "/v1/orgs/1/secrets_list"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := test.cursor.Keys(test.key, test.scope)
+			assert.True(t, errs.Is(err, ErrCursor), "%v", err)
+		})
+	}
+}
@@ -2,13 +2,19 @@ package page
 
 type PageOption func(*Page)
 
+// The number of items in a page that was not given a limit.
+const DefaultLimit uint64 = 10240
+
 // The paging options
 type Page struct {
-	AfterId *string `json:"after_id,omitempty"`
+	Cursor  *Cursor `json:"cursor,omitempty"`
 	Offset  *uint64 `json:"offset,omitempty"`
 	Limit   *uint64 `json:"limit,omitempty"`
 	OrderBy *string `json:"order_by,omitempty"`
 	Desc    *bool   `json:"desc,omitempty"`
+
+	// The keys of a verified cursor, after which stores begin the page.
+	After []interface{} `json:"-"`
 }
 
 func BuildPage(fns ...PageOption) (ret Page) {
@@ -41,3 +47,33 @@ func OffsetPtr(num *uint64) PageOption {
 		o.Offset = num
 	}
 }
+
+// Continues a listing from the cursor.  Empty cursors are ignored.
+func After(cursor Cursor) PageOption {
+	return func(o *Page) {
+		if cursor != "" {
+			o.Cursor = &cursor
+		}
+	}
+}
+
+// Returns the maximum number of items in the page.
+func (p Page) Size() uint64 {
+	if p.Limit != nil && *p.Limit > 0 {
+		return *p.Limit
+	}
+	return DefaultLimit
+}
+
+// Walks the pages of a listing, beginning with the given page, until the
+// listing is exhausted or fn returns an error.  Each page continues from
+// the cursor returned by the page before it.
+func Walk(p Page, fn func(Page) (Cursor, error)) (err error) {
+	for {
+		var next Cursor
+		if next, err = fn(p); err != nil || next == "" {
+			return
+		}
+		p.Cursor, p.Offset = &next, nil
+	}
+}
@@ -0,0 +1,36 @@
+package page
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalk(t *testing.T) {
+	var pages []Page
+	err := Walk(BuildPage(Offset(1), Limit(2)), func(p Page) (next Cursor, err error) {
+		pages = append(pages, p)
+		if len(pages) < 3 {
+			next = Cursor(fmt.Sprint(len(pages)))
+		}
+		return
+	})
+	if !assert.Nil(t, err) || !assert.Len(t, pages, 3) {
+		return
+	}
+
+	assert.Equal(t, uint64(1), *pages[0].Offset)
+	assert.Nil(t, pages[0].Cursor)
+	for i, p := range pages[1:] {
+		assert.Nil(t, p.Offset)
+		assert.Equal(t, Cursor(fmt.Sprint(i+1)), *p.Cursor)
+		assert.Equal(t, uint64(2), p.Size())
+	}
+
+	expected := errors.New("stop")
+	assert.Equal(t, expected, Walk(Page{}, func(p Page) (Cursor, error) {
+		return "next", expected
+	}))
+}
@@ -2,6 +2,8 @@ package policy
 
 import (
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -52,6 +54,9 @@ const (
 	Delete Action = "delete"
 )
 
+// Actions map each action to whether it is allowed or denied.  A
+// denied action is never held, regardless of any allow or sudo, so
+// denies take precedence when actions are merged.
 type Actions map[Action]bool
 
 func NewEmptyActions() Actions {
@@ -63,8 +68,15 @@ func Unflatten(all []Action) (ret Actions) {
 	return
 }
 
+// Denied actions are encoded with a leading '!'.
+const denyPrefix = "!"
+
 func (a Actions) MarshalBinary() (ret []byte, err error) {
-	return json.Marshal(a.Flatten())
+	all := a.Flatten()
+	for _, act := range a.Denials() {
+		all = append(all, Action(denyPrefix+string(act)))
+	}
+	return json.Marshal(all)
 }
 
 func (a *Actions) UnmarshalBinary(raw []byte) (err error) {
@@ -75,6 +87,10 @@ func (a *Actions) UnmarshalBinary(raw []byte) (err error) {
 
 	*a = NewEmptyActions()
 	for _, act := range all {
+		if str := string(act); strings.HasPrefix(str, denyPrefix) {
+			(*a)[Action(strings.TrimPrefix(str, denyPrefix))] = false
+			continue
+		}
 		(*a)[act] = true
 	}
 	return
@@ -88,6 +104,7 @@ func (a Actions) Copy() (ret Actions) {
 	return
 }
 
+// Returns the allowed actions.  Sudo subsumes every other action.
 func (a Actions) Flatten() (ret []Action) {
 	if a[Sudo] {
 		return []Action{Sudo}
@@ -102,6 +119,19 @@ func (a Actions) Flatten() (ret []Action) {
 	return
 }
 
+// Returns the denied actions, sorted.
+func (a Actions) Denials() (ret []Action) {
+	for act, v := range a {
+		if !v {
+			ret = append(ret, act)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return
+}
+
 func (a Actions) Enable(all ...Action) (ret Actions) {
 	ret = a.Copy()
 	for _, act := range all {
@@ -110,24 +140,62 @@ func (a Actions) Enable(all ...Action) (ret Actions) {
 	return
 }
 
+// Marks the actions as denied, replacing any allow.
+func (a Actions) Deny(all ...Action) (ret Actions) {
+	ret = a.Copy()
+	for _, act := range all {
+		ret[act] = false
+	}
+	return
+}
+
+// Returns the union of the actions.  An action denied by either is
+// denied in the result.
+func (a Actions) Merge(o Actions) (ret Actions) {
+	ret = a.Copy()
+	for act, v := range o {
+		if !v || !ret.Denied(act) {
+			ret[act] = v
+		}
+	}
+	return
+}
+
 func (a Actions) Authorize(fn Authorizer) (err error) {
 	err = fn(a)
 	return
 }
 
+// Removes the actions, leaving any denies in place.
 func (a Actions) Disable(all ...Action) (ret Actions) {
 	ret = a.Copy()
 	for _, act := range all {
-		ret[act] = false
+		if ret[act] {
+			delete(ret, act)
+		}
 	}
 	return
 }
 
+// Returns whether the action is explicitly allowed.
 func (a Actions) Enabled(act Action) (ok bool) {
-	_, ok = a[act]
+	ok = a[act]
 	return
 }
 
+// Returns whether the action is explicitly denied.
+func (a Actions) Denied(act Action) (ok bool) {
+	v, ok := a[act]
+	ok = ok && !v
+	return
+}
+
+// Returns whether the action is held, either directly or by way of
+// sudo, and is not denied.
+func (a Actions) Holds(act Action) bool {
+	return !a.Denied(act) && (a.Enabled(act) || a.Enabled(Sudo))
+}
+
 func (a Actions) Equals(o Actions) (ok bool) {
 	if len(a) != len(o) {
 		return
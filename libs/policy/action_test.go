@@ -0,0 +1,35 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActions_Deny(t *testing.T) {
+	a := Enable(Sudo).Deny(View)
+	assert.True(t, a.Holds(Edit))
+	assert.False(t, a.Holds(View))
+	assert.NotNil(t, a.Authorize(Has(View)))
+	assert.Nil(t, a.Authorize(Has(View, Edit)))
+
+	// Denies take precedence whichever side of a merge they are on.
+	assert.True(t, Enable(View).Merge(Enable().Deny(View)).Denied(View))
+	assert.True(t, Enable().Deny(View).Merge(Enable(View)).Denied(View))
+
+	// Disabling an action leaves its denies in place.
+	assert.True(t, a.Disable(Sudo, View).Denied(View))
+	assert.False(t, a.Disable(Sudo).Enabled(Sudo))
+
+	raw, err := a.MarshalBinary()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var b Actions
+	if !assert.Nil(t, b.UnmarshalBinary(raw)) {
+		return
+	}
+	assert.True(t, a.Equals(b))
+	assert.Equal(t, []Action{View}, b.Denials())
+}
@@ -51,7 +51,7 @@ func SliceOf(val interface{}) (ret []Item) {
 
 // Returns the enabled actions for the batch of secured items.
 // Requires a consistent org id for the entire batch.
-func CollectActions(db Storage, userId uuid.UUID, all ...Item) (ret map[uuid.UUID]Actions, err error) {
+func CollectActions(db Storage, ctx Context, userId uuid.UUID, all ...Item) (ret map[uuid.UUID]Actions, err error) {
 	ret = make(map[uuid.UUID]Actions)
 	if len(all) == 0 {
 		return
@@ -68,7 +68,7 @@ func CollectActions(db Storage, userId uuid.UUID, all ...Item) (ret map[uuid.UUI
 		policyIds = append(policyIds, cur.GetPolicyId())
 	}
 
-	ret, err = db.LoadEnabledActions(orgId, userId, dedup(policyIds)...)
+	ret, err = db.LoadEnabledActions(ctx, orgId, userId, dedup(policyIds)...)
 	return
 }
 
@@ -84,8 +84,10 @@ func dedup(ids []uuid.UUID) (ret []uuid.UUID) {
 }
 
 // Validates that the user has the appopriate actions for an object.
-func EnsurePolicyActions(db Storage, orgId, userId, policyId uuid.UUID, actions ...Action) (err error) {
-	enabled, err := db.LoadEnabledActions(orgId, userId, policyId)
+// Memberships whose conditions are not satisfied by the context are
+// ignored.
+func EnsurePolicyActions(db Storage, ctx Context, orgId, userId, policyId uuid.UUID, actions ...Action) (err error) {
+	enabled, err := db.LoadEnabledActions(ctx, orgId, userId, policyId)
 	if err != nil {
 		return
 	}
@@ -135,21 +137,19 @@ var HasAny = Has
 
 func Any() Authorizer {
 	return func(a Actions) (err error) {
-		if len(a) == 0 {
+		if len(a.Flatten()) == 0 {
 			err = errors.Wrapf(auth.ErrUnauthorized, "Expected at least one action enabled.")
 		}
 		return
 	}
 }
 
+// Authorizes actions that hold any of the given actions.  Denied
+// actions are never held, even by way of sudo.
 func Has(all ...Action) Authorizer {
 	return func(a Actions) (err error) {
-		if a.Enabled(Sudo) {
-			return
-		}
-
 		for _, cur := range all {
-			if a.Enabled(cur) {
+			if a.Holds(cur) {
 				return
 			}
 		}
@@ -168,15 +168,18 @@ func Assert(cond bool, msg string, args ...interface{}) Authorizer {
 	}
 }
 
-func Authorize(db Storage, userId uuid.UUID, fn Authorizer, items ...Item) (err error) {
-	enabled, err := CollectActions(db, userId, items...)
+func Authorize(db Storage, ctx Context, userId uuid.UUID, fn Authorizer, items ...Item) (err error) {
+	enabled, err := CollectActions(db, ctx, userId, items...)
 	if err != nil {
 		return
 	}
 
-	for _, act := range enabled {
-		if act.Enabled(Sudo) {
-			return
+	// Items the user holds no membership on are checked against
+	// empty actions.
+	for _, item := range items {
+		act := enabled[item.GetPolicyId()]
+		if act.Enabled(Sudo) && len(act.Denials()) == 0 {
+			continue
 		}
 		if err = fn(act); err != nil {
 			return
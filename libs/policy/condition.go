@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrCondition = errors.New("Policy:Condition")
+)
+
+// The login protocols that a membership may require.  These
+// correspond to the schemes of the login uris of a token.
+const (
+	PasswordLogin  = "password"
+	SignatureLogin = "signature"
+)
+
+// Conditions restrict when a membership may be exercised.  An empty
+// set of conditions is always satisfied.  Each non-empty condition
+// must be satisfied for the membership to apply.
+type Conditions struct {
+	NotBefore time.Time `json:"not_before,omitempty"`
+	NotAfter  time.Time `json:"not_after,omitempty"`
+	Cidrs     []string  `json:"cidrs,omitempty"`
+	Logins    []string  `json:"logins,omitempty"`
+	Devices   []string  `json:"devices,omitempty"`
+}
+
+// The context of a request against which conditions are evaluated.
+type Context struct {
+	Now      time.Time
+	Remote   net.IP
+	LoginUri string
+	DeviceId string
+}
+
+// Returns the context of a request made by the bearer of the claim.
+func NewContext(claim auth.Claim, remote string) Context {
+	return Context{
+		Now:      time.Now().UTC(),
+		Remote:   net.ParseIP(remote),
+		LoginUri: claim.Account.LoginUri,
+		DeviceId: claim.Account.DeviceId,
+	}
+}
+
+// Returns the protocol of the login used to authenticate.
+func (c Context) Login() string {
+	parts := strings.SplitN(c.LoginUri, "://", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+func (c Conditions) Empty() bool {
+	return c.NotBefore.IsZero() &&
+		c.NotAfter.IsZero() &&
+		len(c.Cidrs) == 0 &&
+		len(c.Logins) == 0 &&
+		len(c.Devices) == 0
+}
+
+func (c Conditions) Validate() (err error) {
+	if !c.NotBefore.IsZero() && !c.NotAfter.IsZero() && !c.NotBefore.Before(c.NotAfter) {
+		err = errors.Wrapf(errs.ArgError, "Condition not-before [%v] must precede not-after [%v]", c.NotBefore, c.NotAfter)
+		return
+	}
+	for _, cidr := range c.Cidrs {
+		if _, _, err = net.ParseCIDR(cidr); err != nil {
+			err = errors.Wrapf(errs.ArgError, "Invalid cidr [%v]", cidr)
+			return
+		}
+	}
+	for _, login := range c.Logins {
+		switch login {
+		default:
+			err = errors.Wrapf(errs.ArgError, "Invalid login [%v]. Expected one of [%v, %v]", login, PasswordLogin, SignatureLogin)
+			return
+		case PasswordLogin, SignatureLogin:
+		}
+	}
+	for _, device := range c.Devices {
+		if strings.TrimSpace(device) == "" {
+			err = errors.Wrapf(errs.ArgError, "Empty device id")
+			return
+		}
+	}
+	return
+}
+
+// Evaluates the conditions against the context, returning an
+// error describing the first condition that is not satisfied.
+func (c Conditions) Evaluate(ctx Context) (err error) {
+	if !c.NotBefore.IsZero() && ctx.Now.Before(c.NotBefore) {
+		err = errors.Wrapf(ErrCondition, "Membership is not valid until [%v]", c.NotBefore)
+		return
+	}
+	if !c.NotAfter.IsZero() && !ctx.Now.Before(c.NotAfter) {
+		err = errors.Wrapf(ErrCondition, "Membership expired at [%v]", c.NotAfter)
+		return
+	}
+	if len(c.Cidrs) > 0 && !containsIP(c.Cidrs, ctx.Remote) {
+		err = errors.Wrapf(ErrCondition, "Source address [%v] is not allowed", ctx.Remote)
+		return
+	}
+	if len(c.Logins) > 0 && !contains(c.Logins, ctx.Login()) {
+		err = errors.Wrapf(ErrCondition, "Login protocol [%v] is not allowed. Expected one of %v", ctx.Login(), c.Logins)
+		return
+	}
+	if len(c.Devices) > 0 && (ctx.DeviceId == "" || !contains(c.Devices, ctx.DeviceId)) {
+		err = errors.Wrapf(ErrCondition, "Device [%v] is not allowed", ctx.DeviceId)
+		return
+	}
+	return
+}
+
+func (c Conditions) String() string {
+	var all []string
+	if !c.NotBefore.IsZero() {
+		all = append(all, "after "+c.NotBefore.Format(time.RFC3339))
+	}
+	if !c.NotAfter.IsZero() {
+		all = append(all, "until "+c.NotAfter.Format(time.RFC3339))
+	}
+	if len(c.Cidrs) > 0 {
+		all = append(all, "from "+strings.Join(c.Cidrs, ","))
+	}
+	if len(c.Logins) > 0 {
+		all = append(all, "via "+strings.Join(c.Logins, ","))
+	}
+	if len(c.Devices) > 0 {
+		all = append(all, "on "+strings.Join(c.Devices, ","))
+	}
+	return strings.Join(all, " ")
+}
+
+func (c Conditions) MarshalBinary() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *Conditions) UnmarshalBinary(raw []byte) error {
+	type conditions Conditions
+	return json.Unmarshal(raw, (*conditions)(c))
+}
+
+func containsIP(cidrs []string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, n, err := net.ParseCIDR(cidr); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(all []string, val string) bool {
+	for _, cur := range all {
+		if cur == val {
+			return true
+		}
+	}
+	return false
+}
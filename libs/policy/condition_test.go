@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditions_Evaluate(t *testing.T) {
+	now := time.Now().UTC()
+
+	ctx := Context{
+		Now:      now,
+		Remote:   net.ParseIP("10.1.2.3"),
+		LoginUri: "signature://abc",
+		DeviceId: "laptop",
+	}
+
+	tests := []struct {
+		name string
+		cond Conditions
+		ok   bool
+	}{
+		{"Empty", Conditions{}, true},
+		{"Window", Conditions{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)}, true},
+		{"NotYetValid", Conditions{NotBefore: now.Add(time.Hour)}, false},
+		{"Expired", Conditions{NotAfter: now}, false},
+		{"Cidr", Conditions{Cidrs: []string{"192.168.0.0/16", "10.0.0.0/8"}}, true},
+		{"CidrMismatch", Conditions{Cidrs: []string{"192.168.0.0/16"}}, false},
+		{"Login", Conditions{Logins: []string{SignatureLogin}}, true},
+		{"LoginMismatch", Conditions{Logins: []string{PasswordLogin}}, false},
+		{"Device", Conditions{Devices: []string{"laptop"}}, true},
+		{"DeviceMismatch", Conditions{Devices: []string{"desktop"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cond.Evaluate(ctx)
+			if test.ok {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, ErrCondition, errors.Cause(err))
+		})
+	}
+
+	t.Run("MissingContext", func(t *testing.T) {
+		cond := Conditions{Cidrs: []string{"0.0.0.0/0"}, Devices: []string{"laptop"}}
+		assert.NotNil(t, cond.Evaluate(Context{Now: now}))
+	})
+}
+
+func TestConditions_Validate(t *testing.T) {
+	now := time.Now().UTC()
+
+	assert.Nil(t, Conditions{}.Validate())
+	assert.Nil(t, Conditions{NotBefore: now, NotAfter: now.Add(time.Hour), Cidrs: []string{"10.0.0.0/8"}}.Validate())
+	assert.NotNil(t, Conditions{NotBefore: now, NotAfter: now}.Validate())
+	assert.NotNil(t, Conditions{Cidrs: []string{"10.0.0.0"}}.Validate())
+	assert.NotNil(t, Conditions{Logins: []string{"mfa"}}.Validate())
+	assert.NotNil(t, Conditions{Devices: []string{" "}}.Validate())
+}
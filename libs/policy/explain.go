@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cott-io/stash/libs/auth"
+	uuid "github.com/satori/go.uuid"
+)
+
+// A hop is a single membership along the path from a policy to a
+// user.
+type Hop struct {
+	PolicyId   uuid.UUID  `json:"policy_id"`
+	MemberId   uuid.UUID  `json:"member_id"`
+	MemberType Type       `json:"member_type"`
+	Actions    Actions    `json:"actions"`
+	Conditions Conditions `json:"conditions"`
+	Name       *string    `json:"name,omitempty"` // only set for user and group members
+}
+
+func NewHop(m PolicyMember) Hop {
+	return Hop{
+		PolicyId:   m.PolicyId,
+		MemberId:   m.MemberId,
+		MemberType: m.MemberType,
+		Actions:    m.Actions,
+		Conditions: m.Conditions,
+	}
+}
+
+func (h Hop) Format() string {
+	if h.Name != nil {
+		return h.MemberType.FormatName(*h.Name)
+	}
+	return h.MemberType.FormatId(h.MemberId)
+}
+
+// A path is a chain of memberships through which a user reaches a
+// policy, ordered from the policy inwards.  The last hop is the
+// membership of the user.
+type Path struct {
+	Hops  []Hop  `json:"hops"`
+	Error string `json:"error,omitempty"` // set when a condition along the path is unsatisfied
+}
+
+// Returns the actions that the path grants on its policy.  These are
+// the actions of the outermost membership.  Folders pass on the
+// actions held on the folder, so a path through a folder also grants
+// the actions of the membership beneath it.  Any actions denied by
+// those memberships are denied on the path.
+func (p Path) Granted() (ret Actions) {
+	ret = NewEmptyActions()
+	for _, h := range p.Hops {
+		ret = ret.Merge(h.Actions)
+		if h.MemberType != FolderType {
+			return
+		}
+	}
+	return
+}
+
+// Returns whether the path grants the action, either directly or by
+// way of sudo.
+func (p Path) Grants(act Action) bool {
+	return p.Granted().Holds(act)
+}
+
+// Returns whether the path denies the action.
+func (p Path) Denies(act Action) bool {
+	return p.Granted().Denied(act)
+}
+
+// An explanation describes every path by which a user reaches a
+// policy.  A user must also be a member of the org for any path to
+// be usable.
+type Explanation struct {
+	OrgId    uuid.UUID `json:"org_id"`
+	PolicyId uuid.UUID `json:"policy_id"`
+	MemberId uuid.UUID `json:"member_id"`
+	Role     auth.Role `json:"role"`
+	Paths    []Path    `json:"paths"`
+}
+
+// Explains the paths of a user, recording on each path the first
+// condition that is not satisfied.
+func Explain(orgId, policyId, memberId uuid.UUID, role auth.Role, paths []Path, eval func(Conditions) error) (ret Explanation) {
+	ret = Explanation{
+		OrgId:    orgId,
+		PolicyId: policyId,
+		MemberId: memberId,
+		Role:     role,
+		Paths:    []Path{},
+	}
+
+	for _, p := range paths {
+		p.Error = ""
+		for _, h := range p.Hops {
+			if err := eval(h.Conditions); err != nil {
+				p.Error = err.Error()
+				break
+			}
+		}
+		ret.Paths = append(ret.Paths, p)
+	}
+	return
+}
+
+// Returns the actions the user holds on the policy.  A deny on any
+// usable path overrides the allows of every other path.
+func (e Explanation) Enabled() (ret Actions) {
+	ret = NewEmptyActions()
+	if e.Role < auth.Member {
+		return
+	}
+
+	for _, p := range e.Paths {
+		if p.Error == "" {
+			ret = ret.Merge(p.Granted())
+		}
+	}
+	return
+}
+
+// Returns the paths that would grant the action, whether or not
+// they are currently usable.  The action may still be denied by
+// another path.
+func (e Explanation) PathsTo(act Action) (ret []Path) {
+	for _, p := range e.Paths {
+		if p.Grants(act) {
+			ret = append(ret, p)
+		}
+	}
+	return
+}
+
+// Returns the paths that deny the action, whether or not they are
+// currently usable.
+func (e Explanation) PathsDenying(act Action) (ret []Path) {
+	for _, p := range e.Paths {
+		if p.Denies(act) {
+			ret = append(ret, p)
+		}
+	}
+	return
+}
+
+// An effect is the change in the actions a user holds on a policy
+// that would result from saving a membership.
+type Effect struct {
+	MemberId uuid.UUID      `json:"member_id"`
+	Before   Actions        `json:"before"`
+	After    Actions        `json:"after"`
+	User     *auth.Identity `json:"user,omitempty"`
+}
+
+func (e Effect) Format() string {
+	if e.User != nil {
+		return Type(UserType).FormatName(auth.FormatFriendlyIdentity(*e.User))
+	}
+	return Type(UserType).FormatId(e.MemberId)
+}
+
+// Returns the actions the user would gain.
+func (e Effect) Gained() []Action {
+	return difference(e.After, e.Before)
+}
+
+// Returns the actions the user would lose.
+func (e Effect) Lost() []Action {
+	return difference(e.Before, e.After)
+}
+
+// Returns the actions held in a that are not held in b.  Sudo holds
+// every action that is not denied.
+func difference(a, b Actions) (ret []Action) {
+	seen := make(map[Action]bool)
+	for _, act := range append(a.Flatten(), b.Denials()...) {
+		if !seen[act] && a.Holds(act) && !b.Holds(act) {
+			seen[act] = true
+			ret = append(ret, act)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return
+}
+
+// Returns only the validity window of the conditions.  The remaining
+// conditions depend upon the context of a request, which is unknown
+// when reasoning about the access of other users.
+func (c Conditions) Schedule() Conditions {
+	return Conditions{NotBefore: c.NotBefore, NotAfter: c.NotAfter}
+}
+
+// Returns an evaluator of conditions against the context.
+func Evaluator(ctx Context) func(Conditions) error {
+	return func(c Conditions) error {
+		return c.Evaluate(ctx)
+	}
+}
+
+// Returns an evaluator of the schedules of conditions at the time.
+func ScheduleEvaluator(now time.Time) func(Conditions) error {
+	return func(c Conditions) error {
+		return c.Schedule().Evaluate(Context{Now: now})
+	}
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath_Granted(t *testing.T) {
+	group := Path{Hops: []Hop{
+		{MemberType: GroupType, Actions: Enable(View)},
+		{MemberType: UserType, Actions: Enable(Sudo)},
+	}}
+	assert.True(t, group.Grants(View))
+	assert.False(t, group.Grants(Edit))
+
+	folder := Path{Hops: []Hop{
+		{MemberType: FolderType, Actions: Enable()},
+		{MemberType: GroupType, Actions: Enable(Edit)},
+		{MemberType: UserType, Actions: Enable(Sudo)},
+	}}
+	assert.True(t, folder.Grants(Edit))
+	assert.False(t, folder.Grants(View))
+
+	denied := Path{Hops: []Hop{
+		{MemberType: FolderType, Actions: Enable().Deny(View)},
+		{MemberType: UserType, Actions: Enable(Sudo)},
+	}}
+	assert.True(t, denied.Grants(Edit))
+	assert.False(t, denied.Grants(View))
+	assert.True(t, denied.Denies(View))
+}
+
+func TestExplanation_Enabled(t *testing.T) {
+	e := Explanation{Role: auth.Member, Paths: []Path{
+		{Hops: []Hop{{MemberType: GroupType, Actions: Enable(View)}}},
+		{Hops: []Hop{{MemberType: GroupType, Actions: Enable().Deny(View)}}},
+	}}
+	assert.False(t, e.Enabled().Holds(View))
+	assert.Len(t, e.PathsTo(View), 1)
+	assert.Len(t, e.PathsDenying(View), 1)
+
+	e.Paths[1].Error = "expired"
+	assert.True(t, e.Enabled().Holds(View))
+}
+
+func TestEffect(t *testing.T) {
+	e := Effect{Before: Enable(View, Delete), After: Enable(View, Edit)}
+	assert.Equal(t, []Action{Edit}, e.Gained())
+	assert.Equal(t, []Action{Delete}, e.Lost())
+
+	e = Effect{Before: Enable(View), After: Enable(Sudo)}
+	assert.Equal(t, []Action{Sudo}, e.Gained())
+	assert.Empty(t, e.Lost())
+
+	e = Effect{Before: Enable(Sudo), After: Enable(Sudo).Deny(View)}
+	assert.Empty(t, e.Gained())
+	assert.Equal(t, []Action{View}, e.Lost())
+}
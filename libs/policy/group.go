@@ -28,7 +28,7 @@ type GroupInfo struct {
 }
 
 // Joins the tags on to the collection of blobs.
-func DecorateGroups(db Storage, userId uuid.UUID, groups ...Group) (ret []GroupInfo, err error) {
+func DecorateGroups(db Storage, ctx Context, userId uuid.UUID, groups ...Group) (ret []GroupInfo, err error) {
 	if len(groups) == 0 {
 		return
 	}
@@ -38,7 +38,7 @@ func DecorateGroups(db Storage, userId uuid.UUID, groups ...Group) (ret []GroupI
 		policyIds = append(policyIds, g.PolicyId)
 	}
 
-	actions, err := db.LoadEnabledActions(groups[0].OrgId, userId, policyIds...)
+	actions, err := db.LoadEnabledActions(ctx, groups[0].OrgId, userId, policyIds...)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"github.com/cott-io/stash/lang/dag"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrCycle = errors.New("Policy:Cycle")
+)
+
+// The maximum depth of nested groups that is resolved when
+// locating the access path of a user.
+const MaxGroupDepth = 16
+
+// Resolves the policy of a group or proxy member.  Proxies are
+// identified by their policy ids, groups by their group ids.
+func ResolveMemberPolicy(db Storage, orgId, memberId uuid.UUID) (ret uuid.UUID, err error) {
+	_, ok, err := db.LoadPolicy(orgId, memberId)
+	if err != nil || ok {
+		ret = memberId
+		return
+	}
+
+	groups, err := db.ListGroups(orgId, BuildGroupFilter(WithGroupIds(memberId)), page.BuildPage())
+	if err != nil {
+		return
+	}
+	if len(groups) == 0 {
+		err = errors.Wrapf(ErrNoGroup, "No such group [%v]", memberId)
+		return
+	}
+
+	ret = groups[0].PolicyId
+	return
+}
+
+// Ensures that adding the member to its policy would not introduce
+// a cycle into the hierarchy of groups.  Granting a group access to
+// one of its own (transitive) members is not allowed.
+func EnsureAcyclic(db Storage, m PolicyMember) (err error) {
+	if m.MemberType == UserType {
+		return
+	}
+
+	src, err := ResolveMemberPolicy(db, m.OrgId, m.MemberId)
+	if err != nil {
+		return
+	}
+
+	builder := dag.NewBuilder().
+		AddVertex(src.String(), nil)
+	if src != m.PolicyId {
+		builder = builder.AddVertex(m.PolicyId.String(), nil)
+	}
+	builder = builder.AddEdge(src.String(), m.PolicyId.String())
+
+	// Walk outwards from the target policy, collecting every
+	// membership it (transitively) holds.
+	visited := map[uuid.UUID]struct{}{m.PolicyId: {}, src: {}}
+	for frontier, depth := []uuid.UUID{m.PolicyId}, 0; len(frontier) > 0; depth++ {
+		if depth > MaxGroupDepth {
+			err = errors.Wrapf(errs.ArgError, "Groups may not be nested more than [%v] deep", MaxGroupDepth)
+			return
+		}
+
+		var next []uuid.UUID
+		for _, cur := range frontier {
+			held, err := db.ListMemberships(m.OrgId, cur, page.BuildPage())
+			if err != nil {
+				return err
+			}
+
+			for _, h := range held {
+				if _, ok := visited[h.PolicyId]; !ok {
+					visited[h.PolicyId] = struct{}{}
+					builder = builder.AddVertex(h.PolicyId.String(), nil)
+					next = append(next, h.PolicyId)
+				}
+				builder = builder.AddEdge(cur.String(), h.PolicyId.String())
+			}
+		}
+		frontier = next
+	}
+
+	if _, err = builder.Build(); err != nil {
+		err = errors.Wrapf(ErrCycle, "Adding member [%v] to policy [%v] would create a cycle: %v", m.MemberId, m.PolicyId, err)
+	}
+	return
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
@@ -26,10 +27,11 @@ var (
 )
 
 const (
-	NoneType  = "none"
-	UserType  = "user"
-	GroupType = "group"
-	ProxyType = "proxy"
+	NoneType   = "none"
+	UserType   = "user"
+	GroupType  = "group"
+	ProxyType  = "proxy"
+	FolderType = "folder"
 )
 
 type Type string
@@ -91,9 +93,37 @@ type PolicyLock struct {
 	Core       Policy       `json:"core"`
 	CoreMember PolicyMember `json:"core_member"`
 
-	// Optional fields.  Set depending on the access path!
-	Group       Policy       `json:"proxy1,omitempty"`
-	GroupMember PolicyMember `json:"proxy1_member,omitempty"`
+	// The chain of group policies through which the user reaches
+	// the core policy, ordered from the user outwards.  Empty if
+	// the user is a direct member.
+	Chain []PolicyLink `json:"chain,omitempty"`
+}
+
+// A link is a single hop in the chain of a policy lock: the
+// membership of the previous hop (or the user) in a group policy.
+type PolicyLink struct {
+	Policy Policy       `json:"policy"`
+	Member PolicyMember `json:"member"`
+}
+
+// Decodes the lock, accepting the encoding used before chains were
+// introduced, in which a single group was carried in the proxy1 fields.
+func (u *PolicyLock) UnmarshalJSON(in []byte) (err error) {
+	type lock PolicyLock
+
+	tmp := struct {
+		*lock
+		Group       *Policy       `json:"proxy1,omitempty"`
+		GroupMember *PolicyMember `json:"proxy1_member,omitempty"`
+	}{lock: (*lock)(u)}
+	if err = json.Unmarshal(in, &tmp); err != nil {
+		return
+	}
+
+	if len(u.Chain) == 0 && tmp.Group != nil && tmp.Group.Key.Pub != nil && tmp.GroupMember != nil {
+		u.Chain = []PolicyLink{{Policy: *tmp.Group, Member: *tmp.GroupMember}}
+	}
+	return
 }
 
 // Returns the org id of the policy
@@ -114,9 +144,10 @@ func (u PolicyLock) PublicKey() (ret crypto.PublicKey) {
 	return
 }
 
-// Returns the policy's strength option.
+// Returns the actions enabled on the core policy.  Memberships
+// within the chain only grant entry to the next hop.
 func (u PolicyLock) Actions() (ret Actions) {
-	ret = Unflatten(append(u.CoreMember.Actions.Flatten(), u.GroupMember.Actions.Flatten()...))
+	ret = Unflatten(u.CoreMember.Actions.Flatten()).Deny(u.CoreMember.Actions.Denials()...)
 	return
 }
 
@@ -126,15 +157,44 @@ func (u PolicyLock) Strength() (ret crypto.Strength) {
 	return
 }
 
-// Decrypts the secret using the caller's private key.
-func (p PolicyLock) RecoverSecret(rand io.Reader, callerKey crypto.PrivateKey) (ret crypto.Bytes, err error) {
-	temp := callerKey
-	if p.Group.Key.Pub != nil {
-		temp, err = p.Group.RecoverPrivateKey(rand, p.GroupMember, temp)
+// Evaluates the conditions of every membership along the chain.
+func (u PolicyLock) Evaluate(ctx Context) (err error) {
+	for _, l := range u.Chain {
+		if err = l.Member.Conditions.Evaluate(ctx); err != nil {
+			return
+		}
+	}
+	err = u.CoreMember.Conditions.Evaluate(ctx)
+	return
+}
+
+// Returns the ids of the group policies in the chain.
+func (u PolicyLock) Path() (ret []uuid.UUID) {
+	for _, l := range u.Chain {
+		ret = append(ret, l.Policy.Id)
+	}
+	return
+}
+
+// Walks the chain, returning the private key of the last hop.
+func (p PolicyLock) recoverChainKey(rand io.Reader, callerKey crypto.PrivateKey) (ret crypto.PrivateKey, err error) {
+	ret = callerKey
+	for _, l := range p.Chain {
+		ret, err = l.Policy.RecoverPrivateKey(rand, l.Member, ret)
 		if err != nil {
+			err = errors.Wrapf(err, "Error recovering key of group policy [%v]", l.Policy.Id)
 			return
 		}
 	}
+	return
+}
+
+// Decrypts the secret using the caller's private key.
+func (p PolicyLock) RecoverSecret(rand io.Reader, callerKey crypto.PrivateKey) (ret crypto.Bytes, err error) {
+	temp, err := p.recoverChainKey(rand, callerKey)
+	if err != nil {
+		return
+	}
 
 	ret, err = p.Core.RecoverSecret(rand, p.CoreMember, temp)
 	return
@@ -142,12 +202,9 @@ func (p PolicyLock) RecoverSecret(rand io.Reader, callerKey crypto.PrivateKey) (
 
 // Decrypts the secret using the caller's private key.
 func (p PolicyLock) RecoverPrivateKey(rand io.Reader, callerKey crypto.PrivateKey) (ret crypto.PrivateKey, err error) {
-	temp := callerKey
-	if p.Group.Key.Pub != nil {
-		temp, err = p.Group.RecoverPrivateKey(rand, p.GroupMember, temp)
-		if err != nil {
-			return
-		}
+	temp, err := p.recoverChainKey(rand, callerKey)
+	if err != nil {
+		return
 	}
 
 	ret, err = p.Core.RecoverPrivateKey(rand, p.CoreMember, temp)
@@ -156,13 +213,11 @@ func (p PolicyLock) RecoverPrivateKey(rand io.Reader, callerKey crypto.PrivateKe
 
 // Adds a member directly to the policy
 func (p PolicyLock) AddMember(rand io.Reader, callerKey crypto.PrivateKey, memberId uuid.UUID, memberType Type, memberKey crypto.PublicKey, actions ...Action) (ret PolicyMember, err error) {
-	temp := callerKey
-	if p.Group.Key.Pub != nil {
-		temp, err = p.Group.RecoverPrivateKey(rand, p.GroupMember, temp)
-		if err != nil {
-			return
-		}
+	temp, err := p.recoverChainKey(rand, callerKey)
+	if err != nil {
+		return
 	}
+
 	ret, err = p.Core.AddMember(rand, p.CoreMember, temp, memberId, memberType, memberKey, actions...)
 	return
 }
@@ -179,6 +234,10 @@ func (p PolicyLock) AddMember(rand io.Reader, callerKey crypto.PrivateKey, membe
 // The specific values of actions are specific to the asset that is
 // under management of the policy.
 //
+// A policy whose pass may have been exposed outside its roster (e.g.
+// by a break-glass unlock) is due a rekey.  It remains due until
+// rekeyed.
+//
 type Policy struct {
 	OrgId    uuid.UUID               `json:"org_id"`
 	Id       uuid.UUID               `json:"id"`
@@ -189,41 +248,18 @@ type Policy struct {
 	Key      crypto.KeyPair          `json:"key"`
 	Secret   crypto.SaltedCipherText `json:"secret"`
 	Strength crypto.Strength         `json:"strength"`
+	RekeyDue bool                    `json:"rekey_due"`
 }
 
 func GenPolicyUnsafe(rand io.Reader,
 	orgId, ownerId uuid.UUID, ownerKey crypto.PublicKey, ownerType Type, strength crypto.Strength, actions ...Action) (policy PolicyLock, secret []byte, err error) {
 
-	// the pass is the secret that's actually shared amongst the members
-	// that allows them to decrypt the actual secret.  For the purposes
-	// of further encryption, the secret should be preferred.
-	pass, err := strength.GenNonce(rand)
+	pass, pair, secret, err := genPolicyKeys(rand, strength)
 	if err != nil {
 		return
 	}
 	defer crypto.Bytes(pass).Destroy()
 
-	// This is the private key of the policy.  This gives an identity to the
-	// policy and can be used to make 'this' policy the member of another.
-	priv, err := strength.GenKey(rand, crypto.RSA)
-	if err != nil {
-		return
-	}
-	defer crypto.Destroy(priv)
-
-	// Generate the encrypted key pair
-	pair, err := strength.GenKeyPair(rand, enc.Json, priv, pass)
-	if err != nil {
-		return
-	}
-
-	// This is the secret of the policy.  This is the value that may be used
-	// as a seed for further encryption.
-	secret, err = strength.GenNonce(rand)
-	if err != nil {
-		return
-	}
-
 	accessor, err := GenMemberSecret(rand, ownerKey, pass, strength)
 	if err != nil {
 		return
@@ -317,7 +353,13 @@ func (p Policy) AddMember(rand io.Reader, caller PolicyMember, callerKey crypto.
 	if err != nil {
 		return
 	}
+	defer crypto.Bytes(pass).Destroy()
+
+	ret, err = p.addMember(rand, pass, memberId, memberType, memberKey, actions...)
+	return
+}
 
+func (p Policy) addMember(rand io.Reader, pass []byte, memberId uuid.UUID, memberType Type, memberKey crypto.PublicKey, actions ...Action) (ret PolicyMember, err error) {
 	accessor, err := GenMemberSecret(rand, memberKey, pass, p.Strength)
 	if err != nil {
 		return
@@ -349,6 +391,7 @@ type PolicyMember struct {
 	Updated    time.Time    `json:"updated"`
 	Actions    Actions      `json:"actions"`
 	Pass       MemberSecret `json:"pass"`
+	Conditions Conditions   `json:"conditions"`
 }
 
 func (p PolicyMember) Update(fn func(*PolicyMember)) (ret PolicyMember) {
@@ -357,7 +400,7 @@ func (p PolicyMember) Update(fn func(*PolicyMember)) (ret PolicyMember) {
 	ret.Updated = time.Now().UTC()
 	ret.Version = p.Version + 1
 	if ret.Actions.Enabled(Sudo) {
-		ret.Actions = Enable(Sudo)
+		ret.Actions = Enable(Sudo).Deny(ret.Actions.Denials()...)
 	}
 	return
 }
@@ -367,10 +410,12 @@ func (p PolicyMember) Decrypt(rand io.Reader, priv crypto.PrivateKey) (ret []byt
 	return
 }
 
+// Restores the membership with the given actions.  Any actions that
+// the membership denies remain denied.
 func (p PolicyMember) Restore(actions ...Action) (ret PolicyMember) {
 	ret = p.Update(func(n *PolicyMember) {
 		n.Deleted = false
-		n.Actions = Unflatten(actions)
+		n.Actions = Unflatten(actions).Deny(p.Actions.Denials()...)
 	})
 	return
 }
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/cott-io/stash/lang/crypto"
@@ -55,3 +56,102 @@ func TestPolicy_Secret_SetAndAccept(t *testing.T) {
 
 	assert.Equal(t, []byte(secret), []byte(secret2))
 }
+
+func TestPolicyLock_LegacyJson(t *testing.T) {
+	s := crypto.Moderate
+
+	orgId, acctId1, acctId2 :=
+		uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	acctKey1, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey2, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := GenPolicy(crypto.Rand, orgId, acctId1, acctKey1.Public(), UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	secret, err := core.RecoverSecret(crypto.Rand, acctKey1)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	group, err := GenPolicy(crypto.Rand, orgId, acctId2, acctKey2.Public(), UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	groupMember, err := core.AddMember(crypto.Rand, acctKey1, group.Id(), GroupType, group.PublicKey(), Sudo)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	type legacyLock struct {
+		Core        Policy       `json:"core"`
+		CoreMember  PolicyMember `json:"core_member"`
+		Group       Policy       `json:"proxy1,omitempty"`
+		GroupMember PolicyMember `json:"proxy1_member,omitempty"`
+	}
+
+	t.Run("Group", func(t *testing.T) {
+		raw, err := json.Marshal(legacyLock{core.Core, groupMember, group.Core, group.CoreMember})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var lock PolicyLock
+		if !assert.Nil(t, json.Unmarshal(raw, &lock)) {
+			return
+		}
+		assert.Equal(t, []uuid.UUID{group.Id()}, lock.Path())
+
+		secret2, err := lock.RecoverSecret(crypto.Rand, acctKey2)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secret), []byte(secret2))
+
+		// Re-encoded locks use the chain.
+		raw, err = json.Marshal(lock)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var next PolicyLock
+		if !assert.Nil(t, json.Unmarshal(raw, &next)) {
+			return
+		}
+		assert.Equal(t, []uuid.UUID{group.Id()}, next.Path())
+
+		secret3, err := next.RecoverSecret(crypto.Rand, acctKey2)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secret), []byte(secret3))
+	})
+
+	t.Run("Direct", func(t *testing.T) {
+		raw, err := json.Marshal(legacyLock{Core: core.Core, CoreMember: core.CoreMember})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var lock PolicyLock
+		if !assert.Nil(t, json.Unmarshal(raw, &lock)) {
+			return
+		}
+		assert.Empty(t, lock.Chain)
+
+		secret2, err := lock.RecoverSecret(crypto.Rand, acctKey1)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secret), []byte(secret2))
+	})
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/secret"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Splits the pass of the policy into shares, any quorum of which
+// may be combined to recover it.  Each share is encrypted for the
+// corresponding key.  Holding a quorum of shares is equivalent to
+// holding sudo on the policy.
+func (p PolicyLock) SplitPass(rand io.Reader, callerKey crypto.PrivateKey, quorum int, keys ...crypto.PublicKey) (ret []MemberSecret, err error) {
+	temp, err := p.recoverChainKey(rand, callerKey)
+	if err != nil {
+		return
+	}
+
+	pass, err := p.CoreMember.Decrypt(rand, temp)
+	if err != nil {
+		err = errors.Wrapf(err, "Error decrypting membership pass")
+		return
+	}
+	defer crypto.Bytes(pass).Destroy()
+
+	shares, err := secret.SplitQuorum(rand, pass, quorum, len(keys))
+	if err != nil {
+		return
+	}
+
+	for i, share := range shares {
+		raw, err := json.Marshal(share)
+		if err != nil {
+			return nil, err
+		}
+
+		encrypted, err := GenMemberSecret(rand, keys[i], raw, p.Strength())
+		crypto.Bytes(raw).Destroy()
+		share.Destroy()
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, encrypted)
+	}
+	return
+}
+
+// Re-encrypts a share of the pass for another key.
+func RewrapShare(rand io.Reader, share MemberSecret, priv crypto.PrivateKey, next crypto.PublicKey, strength crypto.Strength) (ret MemberSecret, err error) {
+	return rewrapMemberSecret(rand, share, priv, next, strength)
+}
+
+// Recovers the pass of the policy from a quorum of shares, and adds
+// the member to the policy.
+func (p Policy) AddMemberByQuorum(rand io.Reader, shares []MemberSecret, priv crypto.PrivateKey, memberId uuid.UUID, memberType Type, memberKey crypto.PublicKey, actions ...Action) (ret PolicyMember, err error) {
+	var all []secret.QuorumShare
+	for _, s := range shares {
+		raw, err := s.Decrypt(rand, priv)
+		if err != nil {
+			return ret, errors.Wrapf(err, "Error decrypting share")
+		}
+
+		var share secret.QuorumShare
+		err = json.Unmarshal(raw, &share)
+		crypto.Bytes(raw).Destroy()
+		if err != nil {
+			return ret, err
+		}
+		defer share.Destroy()
+
+		all = append(all, share)
+	}
+
+	pass, err := secret.JoinQuorum(all...)
+	if err != nil {
+		return
+	}
+	defer pass.Destroy()
+
+	// Ensure the shares belong to the current keys of the policy.
+	key, err := p.Key.Decrypt(enc.Json, pass)
+	if err != nil {
+		err = errors.Wrapf(secret.ErrQuorum, "Shares do not unlock policy [%v]", p.Id)
+		return
+	}
+	crypto.Destroy(key)
+
+	ret, err = p.addMember(rand, pass, memberId, memberType, memberKey, actions...)
+	return
+}
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/cott-io/stash/lang/crypto"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Quorum(t *testing.T) {
+	s := crypto.Moderate
+
+	orgId, ownerId, requesterId :=
+		uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	var keys []crypto.PrivateKey
+	for i := 0; i < 4; i++ {
+		key, err := s.GenKey(crypto.Rand, crypto.RSA)
+		if !assert.Nil(t, err) {
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	ownerKey, custodianKeys, requesterKey := keys[0], keys[1:3], keys[3]
+
+	core, err := GenPolicy(crypto.Rand, orgId, ownerId, ownerKey.Public(), UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	secret, err := core.RecoverSecret(crypto.Rand, ownerKey)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	shares, err := core.SplitPass(crypto.Rand, ownerKey, 2, custodianKeys[0].Public(), custodianKeys[1].Public())
+	if !assert.Nil(t, err) || !assert.Equal(t, 2, len(shares)) {
+		return
+	}
+
+	// Custodians hand their shares over to the requester.
+	var handed []MemberSecret
+	for i, share := range shares {
+		rewrapped, err := RewrapShare(crypto.Rand, share, custodianKeys[i], requesterKey.Public(), s)
+		if !assert.Nil(t, err) {
+			return
+		}
+		handed = append(handed, rewrapped)
+	}
+
+	t.Run("TooFew", func(t *testing.T) {
+		_, err := core.Core.AddMemberByQuorum(crypto.Rand, handed[:1], requesterKey, requesterId, UserType, requesterKey.Public(), View)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		member, err := core.Core.AddMemberByQuorum(crypto.Rand, handed, requesterKey, requesterId, UserType, requesterKey.Public(), View)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		act, err := core.Core.RecoverSecret(crypto.Rand, member, requesterKey)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secret), act)
+	})
+}
@@ -0,0 +1,149 @@
+package policy
+
+import (
+	"io"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// A member key pairs a current membership of a policy with
+// the public key of the member.
+type MemberKey struct {
+	Member PolicyMember
+	Key    crypto.PublicKey
+}
+
+// A rekey is the complete set of changes required to replace
+// the keys of a policy.  It is applied atomically.
+//
+// The held memberships are the memberships the policy itself
+// holds in other policies.  Their passes are protected by the
+// policy's key pair, so they must be re-wrapped alongside it.
+type Rekey struct {
+	Policy  Policy         `json:"policy"`
+	Members []PolicyMember `json:"members"`
+	Held    []PolicyMember `json:"held"`
+}
+
+// Generates a new pass, key pair and secret for the policy. Every
+// member of the roster is issued the new pass and each of the held
+// memberships is re-wrapped using the new public key.  The new
+// secret is returned so that any dependent assets may be
+// re-encrypted.  Callers are responsible for destroying it.
+//
+// Once applied, any secret derived from the previous keys is
+// no longer recoverable through the policy.
+func (p PolicyLock) Rekey(rand io.Reader, callerKey crypto.PrivateKey, roster []MemberKey, held []PolicyMember) (ret Rekey, secret []byte, err error) {
+	prev, err := p.RecoverPrivateKey(rand, callerKey)
+	if err != nil {
+		err = errors.Wrapf(err, "Error recovering policy key [%v]", p.Id())
+		return
+	}
+	defer crypto.Destroy(prev)
+
+	strength := p.Strength()
+
+	pass, pair, secret, err := genPolicyKeys(rand, strength)
+	if err != nil {
+		return
+	}
+	defer crypto.Bytes(pass).Destroy()
+
+	ciphertext, err := strength.SaltAndEncrypt(rand, pass, secret)
+	if err != nil {
+		return
+	}
+
+	ret.Policy = p.Core.Update(func(n *Policy) {
+		n.Key = pair
+		n.Secret = ciphertext
+		n.RekeyDue = false
+	})
+
+	for _, m := range roster {
+		if m.Member.PolicyId != p.Id() {
+			err = errors.Wrapf(ErrNotAMember, "Member [%v] not a member of policy [%v]", m.Member.MemberId, p.Id())
+			return
+		}
+
+		accessor, err := GenMemberSecret(rand, m.Key, pass, strength)
+		if err != nil {
+			return ret, secret, err
+		}
+
+		ret.Members = append(ret.Members, m.Member.Update(func(n *PolicyMember) {
+			n.Pass = accessor
+		}))
+	}
+
+	for _, h := range held {
+		if h.OrgId != p.OrgId() {
+			err = errors.Wrapf(ErrNotAMember, "Policy [%v] does not hold membership in [%v]", p.Id(), h.PolicyId)
+			return
+		}
+
+		accessor, err := rewrapMemberSecret(rand, h.Pass, prev, pair.Pub, strength)
+		if err != nil {
+			return ret, secret, errors.Wrapf(err, "Error re-wrapping membership of policy [%v]", h.PolicyId)
+		}
+
+		ret.Held = append(ret.Held, h.Update(func(n *PolicyMember) {
+			n.Pass = accessor
+		}))
+	}
+	return
+}
+
+// Returns the ids of the policies in which the rekeyed policy
+// holds a membership.
+func (r Rekey) HeldPolicyIds() (ret []uuid.UUID) {
+	for _, h := range r.Held {
+		ret = append(ret, h.PolicyId)
+	}
+	return
+}
+
+// Generates the pass, key pair and secret of a policy.
+func genPolicyKeys(rand io.Reader, strength crypto.Strength) (pass []byte, pair crypto.KeyPair, secret []byte, err error) {
+
+	// the pass is the secret that's actually shared amongst the members
+	// that allows them to decrypt the actual secret.  For the purposes
+	// of further encryption, the secret should be preferred.
+	pass, err = strength.GenNonce(rand)
+	if err != nil {
+		return
+	}
+
+	// This is the private key of the policy.  This gives an identity to the
+	// policy and can be used to make 'this' policy the member of another.
+	priv, err := strength.GenKey(rand, crypto.RSA)
+	if err != nil {
+		return
+	}
+	defer crypto.Destroy(priv)
+
+	// Generate the encrypted key pair
+	pair, err = strength.GenKeyPair(rand, enc.Json, priv, pass)
+	if err != nil {
+		return
+	}
+
+	// This is the secret of the policy.  This is the value that may be used
+	// as a seed for further encryption.
+	secret, err = strength.GenNonce(rand)
+	return
+}
+
+func rewrapMemberSecret(rand io.Reader, cur MemberSecret, prev crypto.PrivateKey, next crypto.PublicKey, strength crypto.Strength) (ret MemberSecret, err error) {
+	pass, err := cur.Decrypt(rand, prev)
+	if err != nil {
+		return
+	}
+	defer crypto.Bytes(pass).Destroy()
+
+	ret, err = GenMemberSecret(rand, next, pass, strength)
+	return
+}
@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"time"
+
 	"github.com/cott-io/stash/libs/page"
 	uuid "github.com/satori/go.uuid"
 )
@@ -16,6 +18,10 @@ type Storage interface {
 	// Saves a policy and the first member
 	SavePolicy(Policy, PolicyMember) error
 
+	// Saves a new version of a policy.  Its keys may only be replaced
+	// by a rekey.
+	UpdatePolicy(Policy) error
+
 	// Saves a policy member
 	SavePolicyMember(PolicyMember) error
 
@@ -33,9 +39,29 @@ type Storage interface {
 
 	// Loads the lists of the enabled actions for all the given policies in the context
 	// of the user's permissions.  The returned actions are indexed by their corresponding
-	// policy ids.
-	LoadEnabledActions(orgId, userId uuid.UUID, policyIds ...uuid.UUID) (map[uuid.UUID]Actions, error)
+	// policy ids.  Memberships whose conditions are not satisfied by the context do not
+	// contribute any actions.
+	LoadEnabledActions(ctx Context, orgId, userId uuid.UUID, policyIds ...uuid.UUID) (map[uuid.UUID]Actions, error)
+
+	// Loads the policy lock for the given user.  The lock holds the shortest
+	// chain of memberships whose conditions are satisfied by the context, or
+	// the shortest chain if none are.
+	LoadPolicyLock(ctx Context, orgId, policyId, userId uuid.UUID) (PolicyLock, bool, error)
+
+	// Lists the active memberships held by the member across all policies.
+	// When given the id of a group's policy, the memberships held by the
+	// group are included.
+	ListMemberships(orgId, memberId uuid.UUID, page page.Page) ([]PolicyMember, error)
+
+	// Atomically replaces the keys of a policy.
+	RekeyPolicy(Rekey) error
+
+	// Lists every chain of memberships through which the user may reach
+	// the policy.  Conditions are not evaluated.
+	ExplainPolicy(orgId, policyId, userId uuid.UUID) ([]Path, error)
 
-	// Loads the policy lock for the given user
-	LoadPolicyLock(orgId, policyId, userId uuid.UUID) (PolicyLock, bool, error)
+	// Returns the changes to the actions of users on the policy that would
+	// result from saving the membership.  Only the schedules of conditions
+	// are evaluated, and only the users whose actions change are returned.
+	SimulatePolicyMember(now time.Time, m PolicyMember) ([]Effect, error)
 }
@@ -93,8 +93,8 @@ type Transport interface {
 	// Save a group
 	SaveGroup(auth.SignedToken, Group) error
 
-	// List/search groups
-	ListGroups(t auth.SignedToken, orgId uuid.UUID, filter GroupFilter, page page.Page) ([]GroupInfo, error)
+	// List/search groups, along with the cursor of the next page
+	ListGroups(t auth.SignedToken, orgId uuid.UUID, filter GroupFilter, page page.Page) ([]GroupInfo, page.Cursor, error)
 
 	// Saves a policy and the first member
 	CreatePolicy(auth.SignedToken, Policy, PolicyMember) error
@@ -113,4 +113,16 @@ type Transport interface {
 
 	// Loads the policy lock for the given user.
 	LoadPolicyLock(t auth.SignedToken, orgId, policyId, memberId uuid.UUID) (PolicyLock, bool, error)
+
+	// Lists the active memberships held by the member.
+	ListMemberships(t auth.SignedToken, orgId, memberId uuid.UUID, page page.Page) ([]PolicyMember, error)
+
+	// Replaces the keys of a policy.
+	RekeyPolicy(auth.SignedToken, Rekey) error
+
+	// Explains how the member reaches the policy.
+	ExplainPolicy(t auth.SignedToken, orgId, policyId, memberId uuid.UUID) (Explanation, error)
+
+	// Previews the effect of saving the membership.
+	SimulatePolicyMember(auth.SignedToken, PolicyMember) ([]Effect, error)
 }
@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type EventType string
+
+const (
+	// Emitted whenever a new version of a secret is saved
+	SecretEvent EventType = "secret"
+
+	// Emitted whenever the membership of a secret's policy changes
+	AccessEvent EventType = "access"
+)
+
+// An event is an entry in an org's change feed.  Events are
+// append-only and are assigned a monotonically increasing
+// sequence number (per org) by the storage layer.
+type Event struct {
+	OrgId    uuid.UUID `json:"org_id"`
+	Seq      int       `json:"seq"`
+	Type     EventType `json:"type"`
+	SecretId uuid.UUID `json:"secret_id"`
+	PolicyId uuid.UUID `json:"policy_id"`
+	Name     string    `json:"name"`
+	Version  int       `json:"version"`
+	Deleted  bool      `json:"deleted"`
+	ActorId  uuid.UUID `json:"actor_id"`
+	Created  time.Time `json:"created"`
+}
+
+// Returns the event describing the given secret update.
+func NewSecretEvent(s Secret) Event {
+	return Event{
+		OrgId:    s.OrgId,
+		Type:     SecretEvent,
+		SecretId: s.Id,
+		PolicyId: s.PolicyId,
+		Name:     s.Name,
+		Version:  s.Version,
+		Deleted:  s.Deleted,
+		ActorId:  s.AuthorId,
+		Created:  time.Now().UTC(),
+	}
+}
+
+// Returns an event describing an access change to the secret.
+func NewAccessEvent(s Secret, actorId uuid.UUID) (ret Event) {
+	ret = NewSecretEvent(s)
+	ret.Type = AccessEvent
+	ret.ActorId = actorId
+	return
+}
@@ -13,14 +13,15 @@ var (
 )
 
 type Filter struct {
-	Prefix  *string      `json:"prefix,omitempty"`
-	Like    *string      `json:"like,omitempty"`
-	Type    *string      `json:"type,omitempty"`
-	Names   *[]string    `json:"names,omitempty"`
-	Ids     *[]uuid.UUID `json:"ids,omitempty"`
-	Tags    *[]string    `json:"tags,omitempty"`
-	Deleted *bool        `json:"deleted,omitempty"`
-	Hidden  *bool        `json:"hidden,omitempty"`
+	Prefix    *string      `json:"prefix,omitempty"`
+	Like      *string      `json:"like,omitempty"`
+	Type      *string      `json:"type,omitempty"`
+	Names     *[]string    `json:"names,omitempty"`
+	Ids       *[]uuid.UUID `json:"ids,omitempty"`
+	PolicyIds *[]uuid.UUID `json:"policy_ids,omitempty"`
+	Tags      *[]string    `json:"tags,omitempty"`
+	Deleted   *bool        `json:"deleted,omitempty"`
+	Hidden    *bool        `json:"hidden,omitempty"`
 }
 
 func BuildFilter(fns ...func(*Filter)) (ret Filter) {
@@ -94,6 +95,16 @@ func FilterByIds(ids ...uuid.UUID) func(*Filter) {
 	}
 }
 
+func FilterByPolicyIds(ids ...uuid.UUID) func(*Filter) {
+	return func(f *Filter) {
+		if f.PolicyIds == nil {
+			f.PolicyIds = &[]uuid.UUID{}
+		}
+
+		*f.PolicyIds = append(*f.PolicyIds, ids...)
+	}
+}
+
 func FilterByName(name string) func(*Filter) {
 	return func(f *Filter) {
 		if f.Names == nil {
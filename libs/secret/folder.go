@@ -0,0 +1,171 @@
+package secret
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrNoFolder = errors.New("Secret:NoFolder")
+)
+
+// A folder attaches a policy to a path prefix.  Secrets created under
+// the prefix are given the folder as a member, and the members of the
+// folder inherit the actions they hold on the folder.  The actions of
+// a secret are therefore the union of its own and those of every
+// ancestor folder.
+type Folder struct {
+	OrgId    uuid.UUID `json:"org_id"`
+	Id       uuid.UUID `json:"id"`
+	PolicyId uuid.UUID `json:"policy_id"`
+	Path     string    `json:"path"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+	Version  int       `json:"version"`
+	Deleted  bool      `json:"deleted"`
+}
+
+func NewFolder(orgId, policyId uuid.UUID, path string) (ret Folder, err error) {
+	path, err = CleanFolderPath(path)
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	ret = Folder{
+		OrgId:    orgId,
+		Id:       uuid.NewV1(),
+		PolicyId: policyId,
+		Path:     path,
+		Created:  now,
+		Updated:  now,
+	}
+	return
+}
+
+func (f Folder) GetOrgId() uuid.UUID {
+	return f.OrgId
+}
+
+func (f Folder) GetPolicyId() uuid.UUID {
+	return f.PolicyId
+}
+
+// Returns whether the secret lives under the folder.
+func (f Folder) Contains(name string) bool {
+	return strings.HasPrefix(name, f.Path+"/")
+}
+
+func (f Folder) Update(fn func(*Folder)) (ret Folder) {
+	ret = f
+	fn(&ret)
+	ret.Updated = time.Now().UTC()
+	ret.Version = f.Version + 1
+	return
+}
+
+func (f Folder) Delete() Folder {
+	return f.Update(func(f *Folder) {
+		f.Deleted = true
+	})
+}
+
+// Normalizes a folder path.  Folder paths follow the rules of secret
+// names, but may not end in a slash.
+func CleanFolderPath(path string) (ret string, err error) {
+	ret = strings.TrimRight(path, "/")
+	if ret == "" {
+		err = errors.Wrapf(errs.ArgError, "Folders may not be attached to the root")
+		return
+	}
+	if err = VerifyName(ret); err != nil {
+		return
+	}
+	if strings.Contains(ret, "//") {
+		err = errors.Wrapf(errs.ArgError, "Invalid folder [%v]", path)
+	}
+	return
+}
+
+// Returns the paths of the folders under which the secret lives,
+// from the outermost inwards.
+func FolderPaths(name string) (ret []string) {
+	for i := 1; i < len(name); i++ {
+		if name[i] == '/' && name[i-1] != '/' {
+			ret = append(ret, name[:i])
+		}
+	}
+	return
+}
+
+type FolderInfo struct {
+	Folder  `json:"folder"`
+	Actions policy.Actions `json:"actions"`
+}
+
+// Joins the caller's actions on to the folders.
+func DecorateFolders(db policy.Storage, ctx policy.Context, userId uuid.UUID, folders ...Folder) (ret []FolderInfo, err error) {
+	if len(folders) == 0 {
+		ret = []FolderInfo{}
+		return
+	}
+
+	var policyIds []uuid.UUID
+	for _, f := range folders {
+		policyIds = append(policyIds, f.PolicyId)
+	}
+
+	actions, err := db.LoadEnabledActions(ctx, folders[0].OrgId, userId, policyIds...)
+	if err != nil {
+		return
+	}
+
+	for _, f := range folders {
+		ret = append(ret, FolderInfo{Folder: f, Actions: actions[f.PolicyId]})
+	}
+	return
+}
+
+type FolderFilter struct {
+	Ids    *[]uuid.UUID `json:"ids,omitempty"`
+	Paths  *[]string    `json:"paths,omitempty"`
+	Prefix *string      `json:"prefix,omitempty"`
+}
+
+func BuildFolderFilter(fns ...func(*FolderFilter)) (ret FolderFilter) {
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+func FilterFolderIds(ids ...uuid.UUID) func(*FolderFilter) {
+	return func(f *FolderFilter) {
+		if f.Ids == nil {
+			f.Ids = &[]uuid.UUID{}
+		}
+
+		*f.Ids = append(*f.Ids, ids...)
+	}
+}
+
+func FilterFolderPaths(paths ...string) func(*FolderFilter) {
+	return func(f *FolderFilter) {
+		if f.Paths == nil {
+			f.Paths = &[]string{}
+		}
+
+		*f.Paths = append(*f.Paths, paths...)
+	}
+}
+
+func FilterFolderPrefix(prefix string) func(*FolderFilter) {
+	return func(f *FolderFilter) {
+		f.Prefix = &prefix
+	}
+}
@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+)
+
+const (
+	PasswordRotator RotatorType = "password"
+	RSARotator      RotatorType = "rsa"
+	Ed25519Rotator  RotatorType = "ed25519"
+	CommandRotator  RotatorType = "command"
+)
+
+var commandName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// A rotator type identifies how the next value of a secret is generated.
+type RotatorType string
+
+func ParseRotatorType(str string) (ret RotatorType, err error) {
+	ret = RotatorType(strings.ToLower(strings.TrimSpace(str)))
+	switch ret {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid rotator [%v]. Expected one of [password, rsa, ed25519, command]", str)
+	case PasswordRotator, RSARotator, Ed25519Rotator, CommandRotator:
+	}
+	return
+}
+
+// A rotation describes the schedule and method by which a secret's
+// value is regenerated.  A secret may depend on other secrets (e.g.
+// a connection string derived from a password), in which case it is
+// always rotated after its dependencies.
+//
+// The command of a command rotator is only a name.  The command it
+// runs is configured locally by each machine that rotates the secret,
+// so that editors of a secret cannot run commands on those machines.
+type Rotation struct {
+	Rotator  RotatorType   `json:"rotator,omitempty"`
+	Interval time.Duration `json:"interval,omitempty"`
+	Command  string        `json:"command,omitempty"`
+	Depends  []string      `json:"depends,omitempty"`
+	Last     time.Time     `json:"last,omitempty"`
+}
+
+func (r Rotation) Enabled() bool {
+	return r.Rotator != ""
+}
+
+// Returns whether the rotation is scheduled and has elapsed.
+func (r Rotation) Due(now time.Time) bool {
+	return r.Enabled() && r.Interval > 0 && !now.Before(r.Last.Add(r.Interval))
+}
+
+func (r Rotation) Validate() (err error) {
+	if _, err = ParseRotatorType(string(r.Rotator)); err != nil {
+		return
+	}
+	if r.Rotator == CommandRotator && !commandName.MatchString(r.Command) {
+		err = errors.Wrapf(errs.ArgError, "Invalid command [%v]. Command rotators require the name of a locally configured command", r.Command)
+		return
+	}
+	if r.Interval < 0 {
+		err = errors.Wrapf(errs.ArgError, "Rotation interval must not be negative")
+		return
+	}
+	for _, d := range r.Depends {
+		if err = VerifyName(d); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r Rotation) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *Rotation) UnmarshalBinary(raw []byte) error {
+	type rotation Rotation
+	return json.Unmarshal(raw, (*rotation)(r))
+}
+
+func (b Builder) SetRotation(r Rotation) Builder {
+	return b.And(func(b *Secret) {
+		b.Rotation = r
+	})
+}
@@ -14,6 +14,7 @@ import (
 
 var (
 	ErrNoSecret = errors.New("Secret:NoSecret")
+	ErrQuota    = errors.New("Secret:Quota")
 )
 
 var (
@@ -93,7 +94,7 @@ func ToSecretSummaries(all []Secret) (ret []SecretSummary) {
 }
 
 // Joins the tags on to the collection of secrets.
-func DecorateSecrets(policies policy.Storage, userId uuid.UUID, secrets ...Secret) (ret []SecretSummary, err error) {
+func DecorateSecrets(policies policy.Storage, ctx policy.Context, userId uuid.UUID, secrets ...Secret) (ret []SecretSummary, err error) {
 	if len(secrets) == 0 {
 		ret = []SecretSummary{}
 		return
@@ -106,7 +107,7 @@ func DecorateSecrets(policies policy.Storage, userId uuid.UUID, secrets ...Secre
 			append(policyIds, b.PolicyId)
 	}
 
-	actions, err := policies.LoadEnabledActions(secrets[0].OrgId, userId, policyIds...)
+	actions, err := policies.LoadEnabledActions(ctx, secrets[0].OrgId, userId, policyIds...)
 	if err != nil {
 		return
 	}
@@ -136,6 +137,7 @@ type Secret struct {
 	AuthorId    uuid.UUID        `json:"author_id"`
 	AuthorSig   crypto.Signature `json:"author_sig" sql:"author_sig,string"`
 	Comment     string           `json:"comment"`
+	Rotation    Rotation         `json:"rotation"`
 }
 
 func (b Secret) Format() string {
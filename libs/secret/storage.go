@@ -25,6 +25,29 @@ type Storage interface {
 	// Saves the blocks for a given secret stream
 	SaveBlocks(...Block) error
 
+	// Saves the blocks for a given secret stream, unless they would take the
+	// org's usage beyond the quota, in which case ErrQuota is returned.  The
+	// usage is checked and the blocks saved atomically.  Quotas less than one
+	// are unlimited.
+	SaveBlocksWithin(quota int64, blocks ...Block) error
+
 	// Load the blocks for a given stream
 	LoadBlocks(orgId uuid.UUID, streamId uuid.UUID, page page.Page) ([]Block, error)
+
+	// Returns the number of bytes of block data stored by the org
+	LoadBlockUsage(orgId uuid.UUID) (int64, error)
+
+	// Appends events to the org's change feed.  Sequence numbers are assigned
+	// by the store and any value on the input is ignored.
+	SaveEvents(...Event) error
+
+	// Lists the events of an org's change feed that were recorded after the
+	// given sequence number, optionally restricted to secrets under a prefix.
+	ListEvents(orgId uuid.UUID, prefix string, after int, page page.Page) ([]Event, error)
+
+	// Saves a folder.  Only one live folder may exist at a path.
+	SaveFolder(Folder) error
+
+	// Lists the live folders of an org, ordered by path.
+	ListFolders(orgId uuid.UUID, filter FolderFilter, page page.Page) ([]Folder, error)
 }
@@ -1,6 +1,8 @@
 package secret
 
 import (
+	"time"
+
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/pkg/errors"
@@ -17,11 +19,13 @@ type Transport interface {
 	// will be thrown if a concurrent update takes place.
 	SaveSecret(auth.SignedToken, Secret) error
 
-	// Searches the secrets for an organization using the provided filter.
-	ListSecrets(token auth.SignedToken, orgId uuid.UUID, filter Filter, page page.Page) ([]SecretSummary, error)
+	// Searches the secrets for an organization using the provided filter.  Returns
+	// the cursor of the next page, if there may be one.
+	ListSecrets(token auth.SignedToken, orgId uuid.UUID, filter Filter, page page.Page) ([]SecretSummary, page.Cursor, error)
 
-	// Loads a secret using its unique id.
-	ListSecretVersions(token auth.SignedToken, orgId, secretId uuid.UUID, page page.Page) ([]Secret, error)
+	// Lists the versions of a secret, latest first.  Returns the cursor of the
+	// next page, if there may be one.
+	ListSecretVersions(token auth.SignedToken, orgId, secretId uuid.UUID, page page.Page) ([]Secret, page.Cursor, error)
 
 	// Loads a secret using its unique id.
 	LoadSecret(token auth.SignedToken, orgId, secretId uuid.UUID, version int) (Secret, bool, error)
@@ -31,4 +35,48 @@ type Transport interface {
 
 	// Loads a page of blocks for the given secret.  If the version is set to -1, then the latest is returned.
 	LoadBlocks(token auth.SignedToken, orgId, secretId uuid.UUID, version int, page page.Page) ([]Block, error)
+
+	// Lists the change events recorded after the given sequence number.  If no events
+	// are available, the server waits up to the given duration for new ones to arrive.
+	// The sequence number to resume from is returned along with the events.
+	ListEvents(token auth.SignedToken, orgId uuid.UUID, prefix string, after int, wait time.Duration) ([]Event, int, error)
+
+	// Saves a folder.  The folder may be at any version.
+	SaveFolder(token auth.SignedToken, folder Folder) error
+
+	// Lists the folders of an organization, along with the caller's actions.
+	ListFolders(token auth.SignedToken, orgId uuid.UUID, filter FolderFilter, page page.Page) ([]FolderInfo, error)
+}
+
+// A transport that streams blocks, rather than saving and loading them
+// a page at a time.
+type BlockStreamer interface {
+
+	// Opens a stream on which to save blocks.
+	UploadBlocks(token auth.SignedToken) (BlockUpload, error)
+
+	// Streams the blocks of a secret in pages of the given size, calling the
+	// function with each.  If the version is set to -1, then the latest is returned.
+	DownloadBlocks(token auth.SignedToken, orgId, secretId uuid.UUID, version int, limit uint64, fn func([]Block) error) error
+}
+
+// A stream of blocks to be saved.  Blocks are saved in the order they
+// are sent.  A failed save may be returned by any later call.
+type BlockUpload interface {
+
+	// Sends a batch of blocks.
+	Send(...Block) error
+
+	// Closes the stream, waiting for the blocks sent to be saved.
+	Close() error
+}
+
+// Returns a transport that streams blocks with the given streamer.
+func WithBlockStreamer(t Transport, s BlockStreamer) Transport {
+	return &streamingTransport{t, s}
+}
+
+type streamingTransport struct {
+	Transport
+	BlockStreamer
 }
@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+type DispatchOptions struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	PrivateIPs  bool
+}
+
+func WithWorkers(num int) func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.Workers = num
+	}
+}
+
+func WithQueueSize(num int) func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.QueueSize = num
+	}
+}
+
+func WithMaxAttempts(num int) func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.MaxAttempts = num
+	}
+}
+
+func WithBackoff(min, max time.Duration) func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.Backoff, o.MaxBackoff = min, max
+	}
+}
+
+func WithTimeout(timeout time.Duration) func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.Timeout = timeout
+	}
+}
+
+// Allows deliveries to private and loopback addresses.  Intended
+// for testing only.
+func WithPrivateIPs() func(*DispatchOptions) {
+	return func(o *DispatchOptions) {
+		o.PrivateIPs = true
+	}
+}
+
+func BuildDispatchOptions(fns ...func(*DispatchOptions)) (ret DispatchOptions) {
+	ret = DispatchOptions{
+		Workers:     4,
+		QueueSize:   1024,
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  5 * time.Minute,
+		Timeout:     10 * time.Second,
+	}
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+// Returns the delay before the given (zero-indexed) attempt.
+func (o DispatchOptions) delay(attempt int) (ret time.Duration) {
+	ret = o.Backoff
+	for i := 1; i < attempt && ret < o.MaxBackoff; i++ {
+		ret *= 2
+	}
+	if ret > o.MaxBackoff {
+		ret = o.MaxBackoff
+	}
+	return
+}
+
+type job struct {
+	hook     Webhook
+	delivery Delivery
+	retry    bool
+}
+
+// A dispatcher delivers events to the webhooks of an org.
+// Publishing never blocks the caller - events are fanned out
+// and delivered by a pool of background workers.  Failed
+// deliveries are retried with exponential backoff until the
+// maximum number of attempts is reached.
+type Dispatcher struct {
+	ctx    context.Context
+	store  Storage
+	signer crypto.Signer
+	client *http.Client
+	opts   DispatchOptions
+	events chan Event
+	jobs   chan job
+}
+
+func NewDispatcher(ctx context.Context, store Storage, signer crypto.Signer, fns ...func(*DispatchOptions)) (ret *Dispatcher) {
+	opts := BuildDispatchOptions(fns...)
+
+	ret = &Dispatcher{
+		ctx:    ctx.Sub("Webhooks"),
+		store:  store,
+		signer: signer,
+		client: newClient(opts),
+		opts:   opts,
+		events: make(chan Event, opts.QueueSize),
+		jobs:   make(chan job, opts.QueueSize),
+	}
+
+	go ret.fanout()
+	for i := 0; i < opts.Workers; i++ {
+		go ret.work()
+	}
+	return
+}
+
+// Returns an http client that refuses to connect to private addresses.
+// The address is verified after it is resolved, so hosts that resolve
+// (or are redirected) to private addresses are refused as well.
+func newClient(opts DispatchOptions) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+		Control: func(network, addr string, _ syscall.RawConn) (err error) {
+			if opts.PrivateIPs {
+				return
+			}
+
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return
+			}
+			return VerifyIP(net.ParseIP(host))
+		},
+	}
+
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: opts.Timeout,
+			MaxIdleConns:        16,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+func (d *Dispatcher) Close() error {
+	return d.ctx.Close()
+}
+
+// Publishes an event to all subscribing webhooks of the event's org.
+func (d *Dispatcher) Publish(e Event) {
+	select {
+	case d.events <- e:
+	default:
+		d.ctx.Logger().Error("Event queue full. Dropping event [%v] for org [%v]", e.Type, e.OrgId)
+	}
+}
+
+// Synchronously attempts a single delivery of the event to the webhook.
+// The attempt is recorded in the delivery log.
+func (d *Dispatcher) Deliver(hook Webhook, e Event) (ret Delivery, err error) {
+	ret = d.attempt(hook, NewDelivery(hook, e))
+	err = d.store.SaveDelivery(ret)
+	return
+}
+
+// Schedules another attempt of an existing delivery.  Redeliveries
+// are attempted once and are not retried.
+func (d *Dispatcher) Redeliver(orgId, deliveryId uuid.UUID) (err error) {
+	prev, ok, err := d.store.LoadDelivery(orgId, deliveryId)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = errors.Wrapf(ErrNoDelivery, "No such delivery [%v]", deliveryId)
+		return
+	}
+
+	hook, ok, err := d.store.LoadWebhook(orgId, prev.HookId)
+	if err != nil {
+		return
+	}
+	if !ok || hook.Deleted {
+		err = errors.Wrapf(ErrNoWebhook, "No such webhook [%v]", prev.HookId)
+		return
+	}
+
+	if !d.enqueue(job{hook, prev.Next(), false}) {
+		err = errors.Wrapf(errs.StateError, "Delivery queue is full")
+	}
+	return
+}
+
+func (d *Dispatcher) enqueue(j job) bool {
+	select {
+	case d.jobs <- j:
+		return true
+	case <-d.ctx.Control().Closed():
+		return false
+	default:
+		d.ctx.Logger().Error("Delivery queue full. Dropping delivery [%v]", j.delivery.Id)
+		return false
+	}
+}
+
+func (d *Dispatcher) fanout() {
+	for {
+		select {
+		case <-d.ctx.Control().Closed():
+			return
+		case e := <-d.events:
+			hooks, err := d.store.ListWebhooks(e.OrgId, page.BuildPage())
+			if err != nil {
+				d.ctx.Logger().Error("Error loading webhooks for org [%v]: %v", e.OrgId, err)
+				continue
+			}
+
+			for _, h := range hooks {
+				if h.Subscribes(e.Type) {
+					d.enqueue(job{h, NewDelivery(h, e), true})
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	for {
+		select {
+		case <-d.ctx.Control().Closed():
+			return
+		case j := <-d.jobs:
+			result := d.attempt(j.hook, j.delivery)
+			if err := d.store.SaveDelivery(result); err != nil {
+				d.ctx.Logger().Error("Error saving delivery [%v]: %v", result.Id, err)
+			}
+
+			if result.Success || !j.retry || result.Attempt+1 >= d.opts.MaxAttempts {
+				continue
+			}
+
+			next := job{j.hook, result.Next(), true}
+			time.AfterFunc(d.opts.delay(next.delivery.Attempt), func() {
+				d.enqueue(next)
+			})
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(hook Webhook, delivery Delivery) (ret Delivery) {
+	ret = delivery
+	start := time.Now()
+	defer func() {
+		ret.Duration = time.Since(start)
+	}()
+
+	code, err := d.post(hook, delivery)
+	ret.Code = code
+	if err != nil {
+		ret.Error = err.Error()
+		return
+	}
+
+	ret.Success = code >= 200 && code < 300
+	if !ret.Success {
+		ret.Error = fmt.Sprintf("Unexpected status [%v]", code)
+	}
+	return
+}
+
+func (d *Dispatcher) post(hook Webhook, delivery Delivery) (code int, err error) {
+	payload, err := delivery.Event.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	sig, err := Sign(d.signer, hook, payload, time.Now())
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest("POST", hook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.Event.Type))
+	req.Header.Set(HeaderDelivery, delivery.Id.String())
+	req.Header.Set(HeaderSignature, sig)
+	if hook.Method == ServerKey {
+		req.Header.Set(HeaderKeyId, d.signer.Public().ID())
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	io.Copy(ioutil.Discard, resp.Body)
+	code = resp.StatusCode
+	return
+}
@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type memStore struct {
+	lock       sync.Mutex
+	hooks      map[uuid.UUID]Webhook
+	deliveries []Delivery
+}
+
+func newMemStore() *memStore {
+	return &memStore{hooks: make(map[uuid.UUID]Webhook)}
+}
+
+func (m *memStore) SaveWebhook(w Webhook) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.hooks[w.Id] = w
+	return nil
+}
+
+func (m *memStore) LoadWebhook(orgId, hookId uuid.UUID) (ret Webhook, ok bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ret, ok = m.hooks[hookId]
+	return
+}
+
+func (m *memStore) ListWebhooks(orgId uuid.UUID, page page.Page) (ret []Webhook, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, w := range m.hooks {
+		if w.OrgId == orgId && !w.Deleted {
+			ret = append(ret, w)
+		}
+	}
+	return
+}
+
+func (m *memStore) SaveDelivery(d Delivery) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func (m *memStore) LoadDelivery(orgId, deliveryId uuid.UUID) (ret Delivery, ok bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, d := range m.deliveries {
+		if d.Id == deliveryId {
+			ret, ok = d, true
+		}
+	}
+	return
+}
+
+func (m *memStore) ListDeliveries(orgId, hookId uuid.UUID, page page.Page) (ret []Delivery, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, d := range m.deliveries {
+		if d.HookId == hookId {
+			ret = append(ret, d)
+		}
+	}
+	return
+}
+
+func (m *memStore) all() []Delivery {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]Delivery{}, m.deliveries...)
+}
+
+type capture struct {
+	Header http.Header
+	Body   []byte
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	key, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var fail bool
+	var lock sync.Mutex
+	calls := make(chan capture, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		calls <- capture{r.Header, body}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := newMemStore()
+	dispatcher := NewDispatcher(ctx, store, key,
+		WithBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithMaxAttempts(3),
+		WithPrivateIPs())
+	defer dispatcher.Close()
+
+	orgId := uuid.NewV1()
+
+	// The test server listens on loopback, which registration refuses.
+	hmacHook, err := NewWebhook(orgId, "https://203.0.113.1/hook", HMAC, SecretCreate)
+	if !assert.Nil(t, err) {
+		return
+	}
+	hmacHook.Url = server.URL
+	if !assert.Nil(t, store.SaveWebhook(hmacHook)) {
+		return
+	}
+
+	keyHook, err := NewWebhook(orgId, "https://203.0.113.1/hook", ServerKey, Login)
+	if !assert.Nil(t, err) {
+		return
+	}
+	keyHook.Url = server.URL
+	if !assert.Nil(t, store.SaveWebhook(keyHook)) {
+		return
+	}
+
+	t.Run("Publish_HMAC", func(t *testing.T) {
+		dispatcher.Publish(NewEvent(orgId, SecretCreate, uuid.NewV1(), "secret:///a"))
+
+		select {
+		case <-time.After(5 * time.Second):
+			t.Fail()
+		case c := <-calls:
+			assert.Equal(t, string(SecretCreate), c.Header.Get(HeaderEvent))
+			assert.Nil(t, VerifyHMAC(hmacHook.Secret, c.Body, c.Header.Get(HeaderSignature), DefaultTolerance))
+			assert.NotNil(t, VerifyHMAC([]byte("wrong"), c.Body, c.Header.Get(HeaderSignature), DefaultTolerance))
+		}
+	})
+
+	t.Run("Publish_ServerKey", func(t *testing.T) {
+		dispatcher.Publish(NewEvent(orgId, Login, uuid.NewV1(), "user://a"))
+
+		select {
+		case <-time.After(5 * time.Second):
+			t.Fail()
+		case c := <-calls:
+			assert.Equal(t, key.Public().ID(), c.Header.Get(HeaderKeyId))
+			assert.Nil(t, VerifyServerKey(key.Public(), c.Body, c.Header.Get(HeaderSignature), DefaultTolerance))
+		}
+	})
+
+	t.Run("Publish_Unsubscribed", func(t *testing.T) {
+		dispatcher.Publish(NewEvent(orgId, MemberAdd, uuid.NewV1(), "user://a"))
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-calls:
+			t.Fail()
+		}
+	})
+
+	t.Run("Publish_Retry", func(t *testing.T) {
+		lock.Lock()
+		fail = true
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			fail = false
+			lock.Unlock()
+		}()
+
+		dispatcher.Publish(NewEvent(orgId, SecretCreate, uuid.NewV1(), "secret:///b"))
+		for i := 0; i < 3; i++ {
+			select {
+			case <-time.After(5 * time.Second):
+				t.FailNow()
+			case <-calls:
+			}
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-calls:
+			t.Fail()
+		}
+	})
+
+	t.Run("Deliver", func(t *testing.T) {
+		d, err := dispatcher.Deliver(hmacHook, NewEvent(orgId, Ping, uuid.NewV1(), ""))
+		if !assert.Nil(t, err) {
+			return
+		}
+		<-calls
+		assert.True(t, d.Success)
+		assert.Equal(t, http.StatusNoContent, d.Code)
+	})
+
+	t.Run("Deliver_PrivateIP", func(t *testing.T) {
+		strict := NewDispatcher(ctx, store, key)
+		defer strict.Close()
+
+		d, err := strict.Deliver(hmacHook, NewEvent(orgId, Ping, uuid.NewV1(), ""))
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.False(t, d.Success)
+		assert.Contains(t, d.Error, "is private")
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-calls:
+			t.Fail()
+		}
+	})
+
+	t.Run("Redeliver", func(t *testing.T) {
+		var failed Delivery
+		for _, d := range store.all() {
+			if !d.Success {
+				failed = d
+			}
+		}
+
+		if !assert.Nil(t, dispatcher.Redeliver(orgId, failed.Id)) {
+			return
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+			t.Fail()
+		case c := <-calls:
+			assert.Equal(t, failed.Id.String(), c.Header.Get(HeaderDelivery))
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	SecretCreate EventType = "secret.create"
+	SecretUpdate EventType = "secret.update"
+	SecretDelete EventType = "secret.delete"
+	PolicyGrant  EventType = "policy.grant"
+	PolicyRevoke EventType = "policy.revoke"
+	BreakGlass   EventType = "policy.breakglass"
+	MemberAdd    EventType = "member.add"
+	MemberRemove EventType = "member.remove"
+	Login        EventType = "login"
+	Ping         EventType = "ping"
+)
+
+var (
+	AllEvents = []EventType{
+		SecretCreate,
+		SecretUpdate,
+		SecretDelete,
+		PolicyGrant,
+		PolicyRevoke,
+		BreakGlass,
+		MemberAdd,
+		MemberRemove,
+		Login,
+	}
+)
+
+type EventType string
+
+func ParseEventType(str string) (ret EventType, err error) {
+	ret = EventType(strings.ToLower(strings.TrimSpace(str)))
+	for _, e := range AllEvents {
+		if e == ret {
+			return
+		}
+	}
+
+	err = errors.Wrapf(ErrNotAnEvent, "Invalid event [%v]", str)
+	return
+}
+
+func ParseEventTypes(all ...string) (ret []EventType, err error) {
+	for _, str := range all {
+		typ, err := ParseEventType(str)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, typ)
+	}
+	return
+}
+
+// An event is the payload delivered to webhooks.
+type Event struct {
+	Id      uuid.UUID `json:"id"`
+	OrgId   uuid.UUID `json:"org_id"`
+	Type    EventType `json:"type"`
+	ActorId uuid.UUID `json:"actor_id"`
+	Target  string    `json:"target"`
+	Subject string    `json:"subject,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func NewEvent(orgId uuid.UUID, typ EventType, actorId uuid.UUID, target string) Event {
+	return Event{
+		Id:      uuid.NewV1(),
+		OrgId:   orgId,
+		Type:    typ,
+		ActorId: actorId,
+		Target:  target,
+		Created: time.Now().UTC(),
+	}
+}
+
+// Formats the target of an account-centric event.
+func AccountTarget(acctId uuid.UUID) string {
+	return fmt.Sprintf("user://%v", acctId)
+}
+
+// Returns a copy of the event with the secondary entity of the
+// event set (e.g. the member that was granted access to a policy)
+func (e Event) WithSubject(subject string) (ret Event) {
+	ret, ret.Subject = e, subject
+	return
+}
+
+func (e Event) MarshalBinary() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e *Event) UnmarshalBinary(raw []byte) error {
+	type event Event
+	return json.Unmarshal(raw, (*event)(e))
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+)
+
+// Headers attached to every webhook delivery.
+const (
+	HeaderEvent     = "X-Stash-Event"
+	HeaderDelivery  = "X-Stash-Delivery"
+	HeaderSignature = "X-Stash-Signature"
+	HeaderKeyId     = "X-Stash-Key-Id"
+)
+
+// The window within which a signature's timestamp is accepted by
+// default, guarding against the replay of old deliveries.
+const DefaultTolerance = 5 * time.Minute
+
+// Computes the signature header of a payload at the given time.  The
+// timestamp is signed along with the payload, as <unix>.<payload>.  HMAC
+// signatures are formatted as t=<unix>,sha256=<hex>, while server key
+// signatures are formatted as t=<unix>,<hash>=<base64>.
+func Sign(signer crypto.Signer, hook Webhook, payload []byte, at time.Time) (ret string, err error) {
+	ts := at.Unix()
+	switch hook.Method {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Unsupported signing method [%v]", hook.Method)
+	case HMAC:
+		ret = fmt.Sprintf("t=%v,sha256=%v", ts, hex.EncodeToString(hmacSum(hook.Secret, signed(ts, payload))))
+	case ServerKey:
+		sig, err := signer.Sign(crypto.Rand, crypto.SHA256, signed(ts, payload))
+		if err != nil {
+			return "", err
+		}
+
+		ret = fmt.Sprintf("t=%v,%v=%v", ts, strings.ToLower(string(sig.Hash)), base64.StdEncoding.EncodeToString(sig.Data))
+	}
+	return
+}
+
+// Verifies an HMAC signature header against the payload.  Signatures
+// made outside the tolerance of now are rejected.  A tolerance of zero
+// accepts signatures of any age.
+func VerifyHMAC(secret []byte, payload []byte, header string, tolerance time.Duration) (err error) {
+	ts, hash, sig, err := parseSignature(header, tolerance)
+	if err != nil {
+		return
+	}
+
+	if hash != crypto.SHA256 {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Unsupported signature hash [%v]", hash)
+		return
+	}
+
+	raw, err := hex.DecodeString(sig)
+	if err != nil {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Invalid signature format")
+		return
+	}
+
+	if !hmac.Equal(raw, hmacSum(secret, signed(ts, payload))) {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Signature mismatch")
+	}
+	return
+}
+
+// Verifies a server key signature header against the payload.  Signatures
+// made outside the tolerance of now are rejected.  A tolerance of zero
+// accepts signatures of any age.
+func VerifyServerKey(key crypto.PublicKey, payload []byte, header string, tolerance time.Duration) (err error) {
+	ts, hash, sig, err := parseSignature(header, tolerance)
+	if err != nil {
+		return
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Invalid signature format")
+		return
+	}
+
+	err = key.Verify(hash, signed(ts, payload), raw)
+	return
+}
+
+// Parses a signature header into its timestamp, hash and encoded
+// signature, checking the timestamp against the tolerance.
+func parseSignature(header string, tolerance time.Duration) (ts int64, hash crypto.Hash, sig string, err error) {
+	var hasTs bool
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			err = errors.Wrapf(crypto.ErrInvalidSignature, "Invalid signature format")
+			return
+		}
+
+		switch kv[0] {
+		case "t":
+			if ts, err = strconv.ParseInt(kv[1], 10, 64); err != nil {
+				err = errors.Wrapf(crypto.ErrInvalidSignature, "Invalid signature timestamp [%v]", kv[1])
+				return
+			}
+			hasTs = true
+		default:
+			if hash, err = crypto.ParseHash(strings.ToUpper(kv[0])); err != nil {
+				err = errors.Wrapf(crypto.ErrInvalidSignature, "Unsupported signature hash [%v]", kv[0])
+				return
+			}
+			sig = kv[1]
+		}
+	}
+
+	if !hasTs || sig == "" {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Invalid signature format")
+		return
+	}
+
+	if age := time.Since(time.Unix(ts, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		err = errors.Wrapf(crypto.ErrInvalidSignature, "Signature timestamp [%v] outside tolerance [%v]", ts, tolerance)
+	}
+	return
+}
+
+// Returns the signed material of a payload.
+func signed(ts int64, payload []byte) []byte {
+	return append([]byte(fmt.Sprintf("%v.", ts)), payload...)
+}
+
+func hmacSum(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
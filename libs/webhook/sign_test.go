@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	key, err := crypto.GenRSAKey(crypto.Rand, 1024)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	hmacHook, err := NewWebhook(uuid.NewV1(), "https://203.0.113.1/hook", HMAC, SecretCreate)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	keyHook, err := NewWebhook(uuid.NewV1(), "https://203.0.113.1/hook", ServerKey, SecretCreate)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	payload, now, old := []byte(`{"type":"secret.create"}`), time.Now(), time.Now().Add(-2*DefaultTolerance)
+
+	t.Run("HMAC", func(t *testing.T) {
+		header, err := Sign(key, hmacHook, payload, now)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Regexp(t, regexp.MustCompile(`^t=\d+,sha256=[0-9a-f]+$`), header)
+
+		assert.Nil(t, VerifyHMAC(hmacHook.Secret, payload, header, DefaultTolerance))
+		assert.NotNil(t, VerifyHMAC(hmacHook.Secret, []byte("{}"), header, DefaultTolerance))
+		assert.NotNil(t, VerifyHMAC([]byte("wrong"), payload, header, DefaultTolerance))
+	})
+
+	t.Run("HMAC_Expired", func(t *testing.T) {
+		header, err := Sign(key, hmacHook, payload, old)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		err = VerifyHMAC(hmacHook.Secret, payload, header, DefaultTolerance)
+		assert.True(t, errs.Is(err, crypto.ErrInvalidSignature), "%v", err)
+		assert.Nil(t, VerifyHMAC(hmacHook.Secret, payload, header, 0))
+	})
+
+	t.Run("HMAC_Replayed", func(t *testing.T) {
+		header, err := Sign(key, hmacHook, payload, old)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		// The timestamp is signed, so may not be refreshed.
+		replayed := strings.Replace(header, fmt.Sprintf("t=%v", old.Unix()), fmt.Sprintf("t=%v", now.Unix()), 1)
+		err = VerifyHMAC(hmacHook.Secret, payload, replayed, DefaultTolerance)
+		assert.True(t, errs.Is(err, crypto.ErrInvalidSignature), "%v", err)
+	})
+
+	t.Run("ServerKey", func(t *testing.T) {
+		header, err := Sign(key, keyHook, payload, now)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Regexp(t, regexp.MustCompile(`^t=\d+,sha256=[A-Za-z0-9+/=]+$`), header)
+
+		assert.Nil(t, VerifyServerKey(key.Public(), payload, header, DefaultTolerance))
+		assert.NotNil(t, VerifyServerKey(key.Public(), []byte("{}"), header, DefaultTolerance))
+	})
+
+	t.Run("ServerKey_Expired", func(t *testing.T) {
+		header, err := Sign(key, keyHook, payload, old)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		err = VerifyServerKey(key.Public(), payload, header, DefaultTolerance)
+		assert.True(t, errs.Is(err, crypto.ErrInvalidSignature), "%v", err)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, header := range []string{"", "sha256=abcd", "t=1", "t=x,sha256=abcd", "t=1,md5=abcd"} {
+			err := VerifyHMAC(hmacHook.Secret, payload, header, 0)
+			assert.True(t, errs.Is(err, crypto.ErrInvalidSignature), "%v: %v", header, err)
+		}
+	})
+}
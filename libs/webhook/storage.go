@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Storage interface {
+
+	// Saves or updates a webhook.
+	SaveWebhook(Webhook) error
+
+	// Loads the latest version of a webhook.
+	LoadWebhook(orgId, hookId uuid.UUID) (Webhook, bool, error)
+
+	// Lists the active webhooks of an org.
+	ListWebhooks(orgId uuid.UUID, page page.Page) ([]Webhook, error)
+
+	// Records a delivery attempt.
+	SaveDelivery(Delivery) error
+
+	// Loads the latest attempt of a delivery.
+	LoadDelivery(orgId, deliveryId uuid.UUID) (Delivery, bool, error)
+
+	// Lists the latest attempt of each delivery for a webhook, newest first.
+	ListDeliveries(orgId, hookId uuid.UUID, page page.Page) ([]Delivery, error)
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+type Transport interface {
+
+	// Registers a new webhook.  The returned webhook includes
+	// the HMAC secret, which is never returned again.
+	CreateWebhook(t auth.SignedToken, orgId uuid.UUID, url string, method SignMethod, events []EventType) (Webhook, error)
+
+	// Lists the webhooks of an org.  Secrets are not included.
+	ListWebhooks(t auth.SignedToken, orgId uuid.UUID, page page.Page) ([]Webhook, error)
+
+	// Deletes a webhook.
+	DeleteWebhook(t auth.SignedToken, orgId, hookId uuid.UUID) error
+
+	// Synchronously delivers a ping event to the webhook.
+	TestWebhook(t auth.SignedToken, orgId, hookId uuid.UUID) (Delivery, error)
+
+	// Lists the deliveries of a webhook.
+	ListDeliveries(t auth.SignedToken, orgId, hookId uuid.UUID, page page.Page) ([]Delivery, error)
+
+	// Schedules a previous delivery to be attempted again.
+	Redeliver(t auth.SignedToken, orgId, deliveryId uuid.UUID) error
+}
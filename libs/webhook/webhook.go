@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrNoWebhook  = errors.New("Webhook:NoWebhook")
+	ErrNoDelivery = errors.New("Webhook:NoDelivery")
+	ErrNotAnEvent = errors.New("Webhook:NotAnEvent")
+	ErrPrivateIP  = errors.New("Webhook:PrivateIP")
+)
+
+// The networks webhooks may not target.  Delivering to these would
+// let callers reach services that are only visible to the server.
+var privateNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10")
+
+// The signing methods supported by webhooks.
+const (
+
+	// Payloads are signed using an HMAC-SHA256 of a shared secret.
+	HMAC SignMethod = "hmac"
+
+	// Payloads are signed using the server's signing key.
+	ServerKey SignMethod = "server"
+)
+
+type SignMethod string
+
+func ParseSignMethod(str string) (ret SignMethod, err error) {
+	ret = SignMethod(strings.ToLower(strings.TrimSpace(str)))
+	switch ret {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid signing method [%v]. Expected [hmac, server]", str)
+	case HMAC, ServerKey:
+	}
+	return
+}
+
+type Webhook struct {
+	Id      uuid.UUID  `json:"id"`
+	OrgId   uuid.UUID  `json:"org_id"`
+	Version int        `json:"version"`
+	Url     string     `json:"url"`
+	Events  EventTypes `json:"events"`
+	Method  SignMethod `json:"method"`
+	Secret  []byte     `json:"secret,omitempty"`
+	Created time.Time  `json:"created"`
+	Updated time.Time  `json:"updated"`
+	Deleted bool       `json:"deleted"`
+}
+
+// Returns a new webhook.  If the signing method is HMAC, a random secret
+// is generated.
+func NewWebhook(orgId uuid.UUID, addr string, method SignMethod, events ...EventType) (ret Webhook, err error) {
+	if err = VerifyUrl(addr); err != nil {
+		return
+	}
+
+	if len(events) == 0 {
+		events = AllEvents
+	}
+
+	now := time.Now().UTC()
+	ret = Webhook{
+		Id:      uuid.NewV1(),
+		OrgId:   orgId,
+		Url:     addr,
+		Events:  EventTypes(events),
+		Method:  method,
+		Created: now,
+		Updated: now,
+	}
+
+	if method == HMAC {
+		ret.Secret, err = crypto.GenNonce(crypto.Rand, 32)
+	}
+	return
+}
+
+// Returns true if the webhook subscribes to the event type.
+func (w Webhook) Subscribes(typ EventType) bool {
+	for _, e := range w.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the webhook without its secret.
+func (w Webhook) Redact() (ret Webhook) {
+	ret, ret.Secret = w, nil
+	return
+}
+
+func (w Webhook) Update(fn func(*Webhook)) (ret Webhook) {
+	ret = w
+	fn(&ret)
+	ret.Version = w.Version + 1
+	ret.Updated = time.Now().UTC()
+	return
+}
+
+func WebhookDelete(w *Webhook) {
+	w.Deleted = true
+}
+
+func VerifyUrl(addr string) (err error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid url [%v]: %v", addr, err)
+		return
+	}
+
+	switch u.Scheme {
+	default:
+		err = errors.Wrapf(errs.ArgError, "Invalid url [%v]. Must be http or https", addr)
+		return
+	case "http", "https":
+		if u.Host == "" {
+			err = errors.Wrapf(errs.ArgError, "Invalid url [%v]. Missing host", addr)
+			return
+		}
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		err = errors.Wrapf(errs.ArgError, "Invalid url [%v]. Must not target a local host", addr)
+		return
+	}
+
+	// Hosts that cannot be resolved yet are accepted.  Every delivery
+	// verifies the address it connects to.
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, _ = net.LookupIP(host)
+	}
+
+	for _, ip := range ips {
+		if err = VerifyIP(ip); err != nil {
+			err = errors.Wrapf(errs.ArgError, "Invalid url [%v]: %v", addr, err)
+			return
+		}
+	}
+	return
+}
+
+// Returns an error if the address is loopback, link-local, private or
+// otherwise not publicly routable.
+func VerifyIP(ip net.IP) (err error) {
+	if ip.IsMulticast() {
+		err = errors.Wrapf(ErrPrivateIP, "Address [%v] is multicast", ip)
+		return
+	}
+
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			err = errors.Wrapf(ErrPrivateIP, "Address [%v] is private", ip)
+			return
+		}
+	}
+	return
+}
+
+func parseCIDRs(all ...string) (ret []*net.IPNet) {
+	for _, cidr := range all {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		ret = append(ret, n)
+	}
+	return
+}
+
+type EventTypes []EventType
+
+func (e EventTypes) MarshalBinary() (ret []byte, err error) {
+	return json.Marshal([]EventType(e))
+}
+
+func (e *EventTypes) UnmarshalBinary(raw []byte) (err error) {
+	return json.Unmarshal(raw, (*[]EventType)(e))
+}
+
+// A delivery is a record of an attempt to deliver an event to
+// a webhook.  Every attempt is recorded, with the latest attempt
+// representing the state of the delivery.
+type Delivery struct {
+	Id       uuid.UUID     `json:"id"`
+	OrgId    uuid.UUID     `json:"org_id"`
+	HookId   uuid.UUID     `json:"hook_id"`
+	Attempt  int           `json:"attempt"`
+	Event    Event         `json:"event"`
+	Code     int           `json:"code"`
+	Error    string        `json:"error"`
+	Success  bool          `json:"success"`
+	Created  time.Time     `json:"created"`
+	Duration time.Duration `json:"duration"`
+}
+
+func NewDelivery(hook Webhook, e Event) Delivery {
+	return Delivery{
+		Id:      uuid.NewV1(),
+		OrgId:   hook.OrgId,
+		HookId:  hook.Id,
+		Event:   e,
+		Created: time.Now().UTC(),
+	}
+}
+
+// Returns the next attempt of the delivery.
+func (d Delivery) Next() (ret Delivery) {
+	ret = Delivery{
+		Id:      d.Id,
+		OrgId:   d.OrgId,
+		HookId:  d.HookId,
+		Attempt: d.Attempt + 1,
+		Event:   d.Event,
+		Created: time.Now().UTC(),
+	}
+	return
+}
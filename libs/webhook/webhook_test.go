@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyUrl(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://203.0.113.1/hook", true},
+		{"http://[2001:db8::1]:8080/hook", true},
+		{"ftp://203.0.113.1/hook", false},
+		{"https:///hook", false},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://172.16.0.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://224.0.0.1/hook", false},
+	}
+
+	for _, test := range tests {
+		err := VerifyUrl(test.url)
+		if test.ok {
+			assert.Nil(t, err, test.url)
+		} else {
+			assert.True(t, errs.Is(err, errs.ArgError), "%v: %v", test.url, err)
+		}
+	}
+}
+
+func TestVerifyIP(t *testing.T) {
+	assert.Nil(t, VerifyIP(net.ParseIP("8.8.8.8")))
+	assert.True(t, errs.Is(VerifyIP(net.ParseIP("127.0.0.53")), ErrPrivateIP))
+	assert.True(t, errs.Is(VerifyIP(net.ParseIP("fe80::abcd")), ErrPrivateIP))
+}
@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cott-io/stash/cli/client/access"
 	"github.com/cott-io/stash/cli/client/account"
+	"github.com/cott-io/stash/cli/client/audit"
+	"github.com/cott-io/stash/cli/client/folder"
 	"github.com/cott-io/stash/cli/client/group"
 	"github.com/cott-io/stash/cli/client/identity"
 	"github.com/cott-io/stash/cli/client/member"
 	"github.com/cott-io/stash/cli/client/org"
+	"github.com/cott-io/stash/cli/client/policy"
+	"github.com/cott-io/stash/cli/client/review"
 	"github.com/cott-io/stash/cli/client/secret"
 	"github.com/cott-io/stash/lang/tool"
 )
@@ -34,6 +39,12 @@ Stash is a secret management platform.
 		member.Commands,
 		group.Commands,
 		secret.Commands,
+		folder.Commands,
+		secret.RotateDaemonCommand,
+		audit.Commands,
+		access.Commands,
+		review.Commands,
+		policy.Commands,
 	)
 )
 
@@ -24,6 +24,8 @@ Stash is a secret management platform.
 `,
 		},
 		server.RunCommand,
+		server.OpenAPICommand,
+		server.ConfigCommands,
 	)
 )
 
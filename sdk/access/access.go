@@ -0,0 +1,129 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func RequestAccess(s session.Session, orgId, policyId uuid.UUID, item string, dur time.Duration, reason string, actions ...policy.Action) (ret access.Request, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().RequestAccess(token, orgId, policyId, item, dur, reason, actions)
+	return
+}
+
+func RequireRequest(s session.Session, orgId, requestId uuid.UUID) (ret access.Request, err error) {
+	ret, ok, err := LoadRequest(s, orgId, requestId)
+	if !ok {
+		err = errs.Or(err, errors.Wrapf(access.ErrNoRequest, "No such request [%v]", requestId))
+	}
+	return
+}
+
+func LoadRequest(s session.Session, orgId, requestId uuid.UUID) (ret access.Request, ok bool, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, ok, err = s.Options().Access().LoadRequest(token, orgId, requestId)
+	return
+}
+
+func ListRequests(s session.Session, orgId uuid.UUID, filter access.Filter, opts ...page.PageOption) (ret []access.Request, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().ListRequests(token, orgId, filter, page.BuildPage(opts...))
+	return
+}
+
+// Approves a request.  The approver must be able to unlock the
+// policy, as the policy key is encrypted for the requester here.
+// The resulting membership expires after the requested duration.
+func ApproveRequest(s session.Session, req access.Request, comment string) (err error) {
+	lock, err := policies.RequirePolicyLock(s, req.OrgId, req.PolicyId, s.AccountId())
+	if err != nil {
+		return
+	}
+
+	priv, err := s.Secret().RecoverKey()
+	if err != nil {
+		return
+	}
+	defer priv.Destroy()
+
+	pub, err := policies.UserType.GetPublicKey(s, req.OrgId, req.RequesterId)
+	if err != nil || pub == nil {
+		err = errs.Or(err, errors.Wrapf(errs.StateError, "Unable to download public key [%v]", req.RequesterId))
+		return
+	}
+
+	member, err := lock.AddMember(crypto.Rand, priv, req.RequesterId, policy.UserType, pub, req.Actions.Flatten()...)
+	if err != nil {
+		return
+	}
+	member.Conditions = policy.Conditions{NotAfter: time.Now().UTC().Add(req.Duration)}
+
+	// An earlier grant may not have been revoked yet.
+	prev, ok, err := policies.LoadPolicyMember(s, req.OrgId, req.PolicyId, req.RequesterId)
+	if err != nil {
+		return
+	}
+	if ok {
+		member.Version = prev.Version + 1
+	}
+
+	token, err := s.FetchToken(auth.WithOrgId(req.OrgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().ApproveRequest(token, req.OrgId, req.Id, member, comment)
+	return
+}
+
+func DenyRequest(s session.Session, orgId, requestId uuid.UUID, comment string) (err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().DenyRequest(token, orgId, requestId, comment)
+	return
+}
+
+func SaveApprovers(s session.Session, a access.Approvers) (err error) {
+	token, err := s.FetchToken(auth.WithOrgId(a.OrgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().SaveApprovers(token, a)
+	return
+}
+
+func LoadApprovers(s session.Session, orgId, policyId uuid.UUID) (ret access.Approvers, ok bool, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, ok, err = s.Options().Access().LoadApprovers(token, orgId, policyId)
+	return
+}
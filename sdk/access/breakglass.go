@@ -0,0 +1,232 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Splits the pass of the policy amongst the custodians.  The caller
+// must hold sudo on the policy.  Any rekey of the policy invalidates
+// the escrow, so it must be set up again afterwards.
+func SetupEscrow(s session.Session, orgId, policyId uuid.UUID, quorum int, window, grant time.Duration, custodianIds []uuid.UUID, actions ...policy.Action) (err error) {
+	lock, err := policies.RequirePolicyLock(s, orgId, policyId, s.AccountId())
+	if err != nil {
+		return
+	}
+
+	priv, err := s.Secret().RecoverKey()
+	if err != nil {
+		return
+	}
+	defer priv.Destroy()
+
+	keys := make([]crypto.PublicKey, 0, len(custodianIds))
+	for _, id := range custodianIds {
+		pub, err := policies.UserType.GetPublicKey(s, orgId, id)
+		if err != nil || pub == nil {
+			return errs.Or(err, errors.Wrapf(errs.StateError, "Unable to download public key [%v]", id))
+		}
+		keys = append(keys, pub)
+	}
+
+	shares, err := lock.SplitPass(crypto.Rand, priv, quorum, keys...)
+	if err != nil {
+		return
+	}
+
+	custodies := make([]access.Custody, 0, len(shares))
+	for i, share := range shares {
+		custodies = append(custodies, access.Custody{CustodianId: custodianIds[i], Share: share})
+	}
+
+	escrow, err := access.NewEscrow(lock.Core, quorum, window, grant, custodies, actions...)
+	if err != nil {
+		return
+	}
+
+	// Replacing an escrow must follow its latest version.
+	prev, ok, err := LoadEscrow(s, orgId, policyId)
+	if err != nil {
+		return
+	}
+	if ok {
+		escrow.Version = prev.Version + 1
+	}
+
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().SaveEscrow(token, escrow)
+	return
+}
+
+func RemoveEscrow(s session.Session, orgId, policyId uuid.UUID) (err error) {
+	escrow, err := RequireEscrow(s, orgId, policyId)
+	if err != nil {
+		return
+	}
+
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().SaveEscrow(token, escrow.Delete())
+	return
+}
+
+func RequireEscrow(s session.Session, orgId, policyId uuid.UUID) (ret access.Escrow, err error) {
+	ret, ok, err := LoadEscrow(s, orgId, policyId)
+	if !ok || ret.Deleted {
+		err = errs.Or(err, errors.Wrapf(access.ErrNoEscrow, "Policy [%v] has no break-glass escrow", policyId))
+	}
+	return
+}
+
+func LoadEscrow(s session.Session, orgId, policyId uuid.UUID) (ret access.Escrow, ok bool, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, ok, err = s.Options().Access().LoadEscrow(token, orgId, policyId)
+	return
+}
+
+func RequestUnlock(s session.Session, orgId, policyId uuid.UUID, item, reason string) (ret access.Unlock, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().RequestUnlock(token, orgId, policyId, item, reason)
+	return
+}
+
+func RequireUnlock(s session.Session, orgId, unlockId uuid.UUID) (ret access.Unlock, err error) {
+	ret, ok, err := LoadUnlock(s, orgId, unlockId)
+	if !ok {
+		err = errs.Or(err, errors.Wrapf(access.ErrNoUnlock, "No such unlock [%v]", unlockId))
+	}
+	return
+}
+
+func LoadUnlock(s session.Session, orgId, unlockId uuid.UUID) (ret access.Unlock, ok bool, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, ok, err = s.Options().Access().LoadUnlock(token, orgId, unlockId)
+	return
+}
+
+func ListUnlocks(s session.Session, orgId uuid.UUID, filter access.Filter, opts ...page.PageOption) (ret []access.Unlock, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().ListUnlocks(token, orgId, filter, page.BuildPage(opts...))
+	return
+}
+
+// Hands the caller's share of the pass over to the requester of the
+// unlock.  The share is re-encrypted for the requester, so only they
+// are able to combine it.
+func SubmitShare(s session.Session, u access.Unlock) (err error) {
+	escrow, err := RequireEscrow(s, u.OrgId, u.PolicyId)
+	if err != nil {
+		return
+	}
+
+	custody, ok := escrow.Custody(s.AccountId())
+	if !ok {
+		err = errors.Wrapf(auth.ErrUnauthorized, "Not a custodian of policy [%v]", u.PolicyId)
+		return
+	}
+
+	core, err := policies.RequirePolicyById(s, u.OrgId, u.PolicyId)
+	if err != nil {
+		return
+	}
+
+	priv, err := s.Secret().RecoverKey()
+	if err != nil {
+		return
+	}
+	defer priv.Destroy()
+
+	pub, err := policies.UserType.GetPublicKey(s, u.OrgId, u.RequesterId)
+	if err != nil || pub == nil {
+		err = errs.Or(err, errors.Wrapf(errs.StateError, "Unable to download public key [%v]", u.RequesterId))
+		return
+	}
+
+	share, err := policy.RewrapShare(crypto.Rand, custody.Share, priv, pub, core.Strength)
+	if err != nil {
+		return
+	}
+
+	token, err := s.FetchToken(auth.WithOrgId(u.OrgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().SubmitShare(token, u.OrgId, u.Id, share)
+	return
+}
+
+// Combines the shares of the unlock and grants the caller the
+// emergency membership described by the escrow.
+func ClaimUnlock(s session.Session, u access.Unlock) (ret policy.PolicyMember, err error) {
+	escrow, err := RequireEscrow(s, u.OrgId, u.PolicyId)
+	if err != nil {
+		return
+	}
+
+	core, err := policies.RequirePolicyById(s, u.OrgId, u.PolicyId)
+	if err != nil {
+		return
+	}
+
+	priv, err := s.Secret().RecoverKey()
+	if err != nil {
+		return
+	}
+	defer priv.Destroy()
+
+	ret, err = core.AddMemberByQuorum(crypto.Rand, u.Shares.Secrets(), priv, s.AccountId(), policy.UserType, priv.Public(), escrow.Actions.Flatten()...)
+	if err != nil {
+		return
+	}
+	ret.Conditions = policy.Conditions{NotAfter: time.Now().UTC().Add(escrow.Grant)}
+
+	prev, ok, err := policies.LoadPolicyMember(s, u.OrgId, u.PolicyId, s.AccountId())
+	if err != nil {
+		return
+	}
+	if ok {
+		ret.Version = prev.Version + 1
+	}
+
+	token, err := s.FetchToken(auth.WithOrgId(u.OrgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Access().ClaimUnlock(token, u.OrgId, u.Id, ret)
+	return
+}
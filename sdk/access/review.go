@@ -0,0 +1,86 @@
+package access
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Starts a review campaign over the items.  The caller must be a
+// director of the org.
+func StartCampaign(s session.Session, orgId uuid.UUID, name string, deadline time.Time, scopes ...access.Scope) (ret access.Campaign, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().StartCampaign(token, orgId, name, deadline, scopes)
+	return
+}
+
+func LoadCampaign(s session.Session, orgId, campaignId uuid.UUID) (ret access.Campaign, ok bool, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, ok, err = s.Options().Access().LoadCampaign(token, orgId, campaignId)
+	return
+}
+
+func RequireCampaign(s session.Session, orgId, campaignId uuid.UUID) (ret access.Campaign, err error) {
+	ret, ok, err := LoadCampaign(s, orgId, campaignId)
+	if err != nil || !ok {
+		err = errs.Or(err, errors.Wrapf(access.ErrNoCampaign, "No such campaign [%v]", campaignId))
+	}
+	return
+}
+
+func ListCampaigns(s session.Session, orgId uuid.UUID, opts ...page.PageOption) (ret []access.Campaign, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().ListCampaigns(token, orgId, page.BuildPage(opts...))
+	return
+}
+
+func ListReviewEntries(s session.Session, orgId, campaignId uuid.UUID, filter access.ReviewFilter, opts ...page.PageOption) (ret []access.ReviewEntry, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().ListReviewEntries(token, orgId, campaignId, filter, page.BuildPage(opts...))
+	return
+}
+
+// Keeps or revokes the membership of an entry assigned to the caller.
+func DecideEntry(s session.Session, orgId, campaignId, policyId, memberId uuid.UUID, decision access.Decision, comment string) (ret access.ReviewEntry, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().DecideEntry(token, orgId, campaignId, policyId, memberId, decision, comment)
+	return
+}
+
+// Closes a campaign ahead of its deadline.  Entries that have not been
+// reviewed are revoked.
+func CloseCampaign(s session.Session, orgId, campaignId uuid.UUID) (ret access.Campaign, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Access().CloseCampaign(token, orgId, campaignId)
+	return
+}
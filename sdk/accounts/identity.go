@@ -92,10 +92,33 @@ func ListIdentities(s session.Session, opts ...page.PageOption) (ret []account.I
 		return
 	}
 
-	ret, err = s.Options().Accounts().ListIdentitiesByAccountId(token, s.AccountId(), page.BuildPage(opts...))
+	ret, _, err = s.Options().Accounts().ListIdentitiesByAccountId(token, s.AccountId(), page.BuildPage(opts...))
 	return
 }
 
+// Iterates all the identities of the account, a page at a time.  Iteration
+// stops at the first error returned by fn.
+func EachIdentity(s session.Session, fn func(account.Identity) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken()
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Accounts().ListIdentitiesByAccountId(token, s.AccountId(), p)
+		if err != nil {
+			return
+		}
+
+		for _, i := range all {
+			if err = fn(i); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 // Returns the complete list of identities for a given account.
 func ListIdentitiesByAccountIds(s session.Session, ids []uuid.UUID) (ret map[uuid.UUID][]account.Identity, err error) {
 	token, err := s.FetchToken()
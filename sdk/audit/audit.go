@@ -0,0 +1,19 @@
+package audit
+
+import (
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/sdk/session"
+	uuid "github.com/satori/go.uuid"
+)
+
+func ListEvents(s session.Session, orgId uuid.UUID, filter audit.Filter, opts ...page.PageOption) (ret []audit.Event, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Audit().ListEvents(token, orgId, filter, page.BuildPage(opts...))
+	return
+}
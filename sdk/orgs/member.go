@@ -30,22 +30,68 @@ func ListMemberships(s session.Session, opts ...page.PageOption) (ret []org.Memb
 		return
 	}
 
-	ret, err = s.Options().Orgs().ListMembersByAccountId(
+	ret, _, err = s.Options().Orgs().ListMembersByAccountId(
 		token, s.AccountId(), page.BuildPage(opts...))
 	return
 }
 
+// Iterates all the memberships of the account, a page at a time.  Iteration
+// stops at the first error returned by fn.
+func EachMembership(s session.Session, fn func(org.Member) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken()
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Orgs().ListMembersByAccountId(token, s.AccountId(), p)
+		if err != nil {
+			return
+		}
+
+		for _, m := range all {
+			if err = fn(m); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 func ListMembersByOrgId(s session.Session, orgId uuid.UUID, opts ...page.PageOption) (ret []org.Member, err error) {
 	token, err := s.FetchToken(auth.WithOrgId(orgId))
 	if err != nil {
 		return
 	}
 
-	ret, err = s.Options().Orgs().ListMembersByOrgId(
+	ret, _, err = s.Options().Orgs().ListMembersByOrgId(
 		token, orgId, page.BuildPage(opts...))
 	return
 }
 
+// Iterates all the members of the org, a page at a time.  Iteration stops
+// at the first error returned by fn.
+func EachMemberByOrgId(s session.Session, orgId uuid.UUID, fn func(org.Member) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken(auth.WithOrgId(orgId))
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Orgs().ListMembersByOrgId(token, orgId, p)
+		if err != nil {
+			return
+		}
+
+		for _, m := range all {
+			if err = fn(m); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 func LoadMember(s session.Session, orgId, acctId uuid.UUID) (ret org.Member, ok bool, err error) {
 	token, err := s.FetchToken(auth.WithOrgId(orgId))
 	if err != nil {
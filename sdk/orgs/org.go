@@ -7,6 +7,7 @@ import (
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
+	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/sdk/session"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
@@ -81,6 +82,29 @@ func Cancel(s session.Session, id uuid.UUID) (err error) {
 	return
 }
 
+// Iterates all the invoices of the org, a page at a time.  Iteration stops
+// at the first error returned by fn.
+func EachInvoice(s session.Session, id uuid.UUID, fn func(billing.Invoice) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken(auth.WithOrgId(id))
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Orgs().ListInvoices(token, id, p)
+		if err != nil {
+			return
+		}
+
+		for _, i := range all {
+			if err = fn(i); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 // Purchases an org.  The provided org will be the public identity of the
 // group associated with the org.  A side-effect of org creation
 // is that the session owner is automatically added as a member at the Owner level.
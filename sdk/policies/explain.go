@@ -0,0 +1,60 @@
+package policies
+
+import (
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+func ExplainPolicy(s session.Session, orgId, policyId, memberId uuid.UUID) (ret policy.Explanation, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Policies().ExplainPolicy(token, orgId, policyId, memberId)
+	return
+}
+
+// Previews the effect of granting the actions to the member.  As with
+// GrantPolicyMember, the actions replace those already held.
+func SimulateGrant(s session.Session, orgId, policyId uuid.UUID, memberType MemberType, memberId uuid.UUID, cond policy.Conditions, denies []policy.Action, actions ...policy.Action) (ret []policy.Effect, err error) {
+	return SimulatePolicyMember(s, policy.PolicyMember{
+		OrgId:      orgId,
+		PolicyId:   policyId,
+		MemberType: memberType.Type(),
+		MemberId:   memberId,
+		Actions:    policy.Enable(actions...).Deny(denies...),
+		Conditions: cond,
+	})
+}
+
+// Previews the effect of revoking the actions from the member.  If no
+// actions are given, the effect of deleting the membership is returned.
+func SimulateRevoke(s session.Session, orgId, policyId, memberId uuid.UUID, actions ...policy.Action) (ret []policy.Effect, err error) {
+	orig, ok, err := LoadPolicyMember(s, orgId, policyId, memberId)
+	if !ok {
+		err = errs.Or(err, errors.Wrapf(policy.ErrNotAMember, "Not currently a member"))
+		return
+	}
+
+	updated := orig.Restore(orig.Actions.Disable(actions...).Flatten()...)
+	if len(actions) == 0 {
+		updated = orig.Delete()
+	}
+
+	return SimulatePolicyMember(s, updated)
+}
+
+func SimulatePolicyMember(s session.Session, m policy.PolicyMember) (ret []policy.Effect, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(m.OrgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Policies().SimulatePolicyMember(token, m)
+	return
+}
@@ -129,10 +129,33 @@ func ListGroups(s session.Session, orgId uuid.UUID, filter policy.GroupFilter, o
 		return
 	}
 
-	ret, err = s.Options().Policies().ListGroups(token, orgId, filter, page.BuildPage(opt...))
+	ret, _, err = s.Options().Policies().ListGroups(token, orgId, filter, page.BuildPage(opt...))
 	return
 }
 
+// Iterates all the groups matching the filter, a page at a time.  Iteration
+// stops at the first error returned by fn.
+func EachGroup(s session.Session, orgId uuid.UUID, filter policy.GroupFilter, fn func(policy.GroupInfo) error, opt ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opt...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken(auth.WithOrgId(orgId))
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Policies().ListGroups(token, orgId, filter, p)
+		if err != nil {
+			return
+		}
+
+		for _, g := range all {
+			if err = fn(g); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 func RequireGroupById(s session.Session, orgId, groupId uuid.UUID) (ret policy.Group, err error) {
 	ret, ok, err := LoadGroupById(s, orgId, groupId)
 	if err != nil || !ok {
@@ -0,0 +1,448 @@
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/dag"
+	"github.com/cott-io/stash/lang/enc"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/mime"
+	"github.com/cott-io/stash/lang/ref"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// An ACL file declares the groups of an org and the members of items.
+// Items and members are given as references (e.g. secret://app/db.pass,
+// group://backend), each mapped to the actions it is granted.  An empty
+// list of actions grants the defaults of the item.  Actions prefixed
+// with '!' are denied to the member, overriding any grant.  A secret
+// reference
+// with an absolute path (e.g. secret:///app/prod) declares the members
+// of the folder at that path.
+//
+// Example:
+//
+//	groups:
+//	  backend:
+//	    description: Backend engineers
+//	    members:
+//	      user://alice@example.com: [view]
+//
+//	grants:
+//	  secret:///app/prod:
+//	    group://backend: [view]
+//	    group://interns: ["!view"]
+type ACLFile struct {
+	Groups map[string]GroupDecl           `json:"groups"`
+	Grants map[string]map[string][]string `json:"grants"`
+}
+
+type GroupDecl struct {
+	Description string              `json:"description"`
+	Members     map[string][]string `json:"members"`
+}
+
+// Decodes an ACL file, choosing the encoding by the extension of the
+// file.  Files without a known extension are assumed to be yaml.
+func ReadACLFile(file string, raw []byte) (ret ACLFile, err error) {
+	var dec enc.Decoder
+	switch ref.Pointer(file).Mime() {
+	default:
+		dec = enc.Yaml
+	case mime.Json:
+		dec = enc.Json
+	case mime.Toml:
+		dec = enc.Toml
+	}
+
+	if err = dec.DecodeBinary(raw, &ret); err != nil {
+		err = errors.Wrapf(errs.ArgError, "Invalid acl file [%v]: %v", file, err)
+	}
+	return
+}
+
+// Returns the names of the declared groups, sorted.
+func (f ACLFile) groupNames() (ret []string) {
+	for name := range f.Groups {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return
+}
+
+// Returns the declared members of every item, keyed by item.  The
+// members of a group are declared on the group's item.
+func (f ACLFile) items() (ret map[string]map[string][]string, err error) {
+	ret = make(map[string]map[string][]string)
+
+	add := func(item string, members map[string][]string) error {
+		item = normalizeItem(item)
+		if _, ok := ret[item]; ok {
+			return errors.Wrapf(errs.ArgError, "Item [%v] is declared more than once", item)
+		}
+		if members == nil {
+			members = make(map[string][]string)
+		}
+		ret[item] = members
+		return nil
+	}
+
+	for name, g := range f.Groups {
+		if err = add(policy.Type(policy.GroupType).FormatName(name), g.Members); err != nil {
+			return
+		}
+	}
+	for item, members := range f.Grants {
+		if err = add(item, members); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Items without a protocol are secrets, and secrets with absolute
+// paths are folders.
+func normalizeItem(item string) string {
+	ptr := ref.Pointer(item)
+	switch ptr.Protocol() {
+	case "":
+		return normalizeItem(ptr.SetProtocol("secret").Raw())
+	case "secret":
+		if doc := ptr.Document(); strings.HasPrefix(doc, "/") {
+			return policy.Type(policy.FolderType).FormatName(strings.Trim(doc, "/"))
+		}
+	}
+	return item
+}
+
+type ChangeKind string
+
+const (
+	CreateGroupChange ChangeKind = "create"
+	GrantChange       ChangeKind = "grant"
+	UpdateChange      ChangeKind = "update"
+	RevokeChange      ChangeKind = "revoke"
+)
+
+// A change is a single step of a plan.  Groups are created before
+// any change that refers to them.
+type Change struct {
+	Kind        ChangeKind
+	Item        string
+	Member      string
+	MemberId    uuid.UUID // unset for members that do not exist yet
+	Before      policy.Actions
+	After       policy.Actions
+	Conditions  policy.Conditions // kept when the actions of a member are updated
+	Description string            // only set when creating a group
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case CreateGroupChange:
+		return fmt.Sprintf("create %v", c.Item)
+	case RevokeChange:
+		return fmt.Sprintf("revoke %v from %v", c.Member, c.Item)
+	default:
+		return fmt.Sprintf("%v %v on %v %v", c.Kind, c.Member, c.Item, formatActions(c.After))
+	}
+}
+
+// Whether the change refers to the item, either as the item being
+// changed or as the member.
+func (c Change) RefersTo(item string) bool {
+	return c.Item == item || c.Member == item
+}
+
+// A plan is the ordered set of changes that brings the live state of
+// an org in line with an ACL file.
+type Plan struct {
+	OrgId   uuid.UUID
+	Changes []Change
+}
+
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Builds the graph of the changes of the plan.  Every change that
+// refers to a group depends on the creation of that group.
+func (p Plan) Graph() (ret *dag.Graph, err error) {
+	builder := dag.NewBuilder()
+	for i, c := range p.Changes {
+		builder = builder.AddVertex(strconv.Itoa(i), c)
+	}
+
+	for i, c := range p.Changes {
+		if c.Kind != CreateGroupChange {
+			continue
+		}
+		for j, o := range p.Changes {
+			if i != j && o.RefersTo(c.Item) {
+				builder = builder.AddEdge(strconv.Itoa(i), strconv.Itoa(j))
+			}
+		}
+	}
+
+	ret, err = builder.Build()
+	return
+}
+
+// A desired membership of an item.
+type desiredMember struct {
+	Ref     string
+	Id      uuid.UUID
+	Actions policy.Actions
+}
+
+// Diffs the live members of an item against the desired members.
+// Members holding sudo that are not declared are left in place, as
+// removing them could lock the owners out of the item.  Ownership
+// is managed with acl commands instead.
+func diffMembers(item string, live []policy.PolicyMemberInfo, desired []desiredMember) (ret []Change) {
+	byId := make(map[uuid.UUID]policy.PolicyMemberInfo)
+	for _, m := range live {
+		if !m.Deleted {
+			byId[m.MemberId] = m
+		}
+	}
+
+	for _, d := range desired {
+		cur, ok := byId[d.Id]
+		if d.Id == uuid.Nil || !ok {
+			ret = append(ret, Change{Kind: GrantChange, Item: item, Member: d.Ref, MemberId: d.Id, After: d.Actions})
+			continue
+		}
+
+		delete(byId, d.Id)
+		if !cur.Actions.Equals(d.Actions) {
+			ret = append(ret, Change{
+				Kind:       UpdateChange,
+				Item:       item,
+				Member:     d.Ref,
+				MemberId:   d.Id,
+				Before:     cur.Actions,
+				After:      d.Actions,
+				Conditions: cur.Conditions,
+			})
+		}
+	}
+
+	var revoked []Change
+	for _, m := range byId {
+		if m.Actions.Enabled(policy.Sudo) {
+			continue
+		}
+		revoked = append(revoked, Change{Kind: RevokeChange, Item: item, Member: m.Format(), MemberId: m.MemberId, Before: m.Actions})
+	}
+	sort.Slice(revoked, func(i, j int) bool {
+		return revoked[i].Member < revoked[j].Member
+	})
+
+	ret = append(ret, revoked...)
+	return
+}
+
+// Plans the changes needed to bring the org in line with the file.
+// Only the items declared in the file are considered.  Secrets and
+// folders must already exist, but missing groups are created.
+func PlanACL(s session.Session, orgId uuid.UUID, file ACLFile) (ret Plan, err error) {
+	items, err := file.items()
+	if err != nil {
+		return
+	}
+
+	ret = Plan{OrgId: orgId}
+
+	created := make(map[string]bool)
+	for _, name := range file.groupNames() {
+		_, ok, err := LoadGroupByName(s, orgId, name)
+		if err != nil {
+			return ret, err
+		}
+		if !ok {
+			item := policy.Type(policy.GroupType).FormatName(name)
+			ret.Changes = append(ret.Changes, Change{Kind: CreateGroupChange, Item: item, Description: file.Groups[name].Description})
+			created[item] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(items))
+	for item := range items {
+		sorted = append(sorted, item)
+	}
+	sort.Strings(sorted)
+
+	for _, item := range sorted {
+		itemRef, err := ParseItemRef(item)
+		if err != nil {
+			return ret, err
+		}
+
+		var live []policy.PolicyMemberInfo
+		if !created[item] {
+			policyId, err := itemRef.GetPolicyId(s, orgId)
+			if err != nil {
+				return ret, err
+			}
+
+			if live, err = ListPolicyMembers(s, orgId, policyId); err != nil {
+				return ret, err
+			}
+		}
+
+		var desired []desiredMember
+		for _, member := range sortedKeys(items[item]) {
+			memberRef, err := ParseMemberRef(member)
+			if err != nil {
+				return ret, err
+			}
+
+			actions, err := parseDeclaredActions(itemRef, items[item][member])
+			if err != nil {
+				return ret, errors.Wrapf(err, "Invalid actions of [%v] on [%v]", member, item)
+			}
+
+			var memberId uuid.UUID
+			if !created[memberRef.String()] {
+				if memberId, err = memberRef.GetMemberId(s, orgId); err != nil {
+					return ret, err
+				}
+			}
+
+			desired = append(desired, desiredMember{memberRef.String(), memberId, actions})
+		}
+
+		ret.Changes = append(ret.Changes, diffMembers(item, live, desired)...)
+	}
+	return
+}
+
+// Parses the declared actions of a member.  A member that is only
+// denied actions is granted nothing.
+func parseDeclaredActions(itemRef ItemRef, strs []string) (ret policy.Actions, err error) {
+	if len(strs) == 0 {
+		ret = policy.Enable(itemRef.Type.DefaultActions()...)
+		return
+	}
+
+	var actions, denies []policy.Action
+	for _, str := range strs {
+		deny := strings.HasPrefix(str, "!")
+
+		act, err := itemRef.ParseAction(strings.TrimPrefix(str, "!"))
+		if err != nil {
+			return nil, err
+		}
+
+		if deny {
+			denies = append(denies, act)
+		} else {
+			actions = append(actions, act)
+		}
+	}
+
+	ret = policy.Enable(actions...).Deny(denies...)
+	return
+}
+
+// Applies the plan.  Changes are applied in dependency order and the
+// observer is called with the outcome of each.  Changes that depend
+// upon a failed change are skipped.  The caller must hold sudo on
+// every item being changed.
+func ApplyPlan(s session.Session, plan Plan, strength crypto.Strength, observer func(Change, error)) (err error) {
+	if plan.Empty() {
+		return
+	}
+
+	graph, err := plan.Graph()
+	if err != nil {
+		return
+	}
+
+	locks := make(map[string]policy.PolicyLock)
+	lock := func(itemRef ItemRef) (ret policy.PolicyLock, err error) {
+		if ret, ok := locks[itemRef.String()]; ok {
+			return ret, nil
+		}
+
+		policyId, err := itemRef.GetPolicyId(s, plan.OrgId)
+		if err != nil {
+			return
+		}
+
+		ret, err = RequirePolicyLock(s, plan.OrgId, policyId, s.AccountId())
+		if err != nil {
+			return
+		}
+
+		locks[itemRef.String()] = ret
+		return
+	}
+
+	err = graph.Traverse(func(_ <-chan struct{}, v dag.Vertex) (err error) {
+		change := v.Data.(Change)
+		defer func() {
+			observer(change, err)
+		}()
+
+		itemRef, err := ParseItemRef(change.Item)
+		if err != nil {
+			return
+		}
+
+		if change.Kind == CreateGroupChange {
+			_, err = CreateGroup(s, plan.OrgId, ref.Pointer(change.Item).Document(), change.Description, strength)
+			return
+		}
+
+		lock, err := lock(itemRef)
+		if err != nil {
+			return
+		}
+
+		if change.Kind == RevokeChange {
+			err = RevokePolicyMember(s, lock, change.MemberId)
+			return
+		}
+
+		memberRef, err := ParseMemberRef(change.Member)
+		if err != nil {
+			return
+		}
+
+		memberId := change.MemberId
+		if memberId == uuid.Nil {
+			if memberId, err = memberRef.GetMemberId(s, plan.OrgId); err != nil {
+				return
+			}
+		}
+
+		err = GrantPolicyMember(s, lock, memberRef.Type, memberId, change.Conditions, change.After.Denials(), change.After.Flatten()...)
+		return
+	})
+	return
+}
+
+func formatActions(a policy.Actions) (ret []string) {
+	ret = policy.ToStrings(a.Flatten())
+	for _, act := range a.Denials() {
+		ret = append(ret, "!"+string(act))
+	}
+	return
+}
+
+func sortedKeys(m map[string][]string) (ret []string) {
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return
+}
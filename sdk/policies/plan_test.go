@@ -0,0 +1,128 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadACLFile(t *testing.T) {
+	yaml := `
+groups:
+  backend:
+    description: Backend engineers
+    members:
+      user://alice@example.com: [view]
+
+grants:
+  secret:///app/prod:
+    group://backend: [view]
+  app/db.pass:
+    group://backend: []
+`
+
+	toml := `
+[groups.backend]
+description = "Backend engineers"
+
+[groups.backend.members]
+"user://alice@example.com" = ["view"]
+
+[grants."secret:///app/prod"]
+"group://backend" = ["view"]
+
+[grants."app/db.pass"]
+"group://backend" = []
+`
+
+	for file, raw := range map[string]string{"acl.yaml": yaml, "acl.toml": toml} {
+		acl, err := ReadACLFile(file, []byte(raw))
+		if !assert.Nil(t, err, file) {
+			return
+		}
+		assert.Equal(t, "Backend engineers", acl.Groups["backend"].Description, file)
+
+		items, err := acl.items()
+		if !assert.Nil(t, err, file) {
+			return
+		}
+		assert.Equal(t, []string{"view"}, items["group://backend"]["user://alice@example.com"], file)
+		assert.Equal(t, []string{"view"}, items["folder://app/prod"]["group://backend"], file)
+		assert.Contains(t, items, "secret://app/db.pass", file)
+	}
+
+	dup, err := ReadACLFile("acl.yaml", []byte(`
+groups:
+  backend: {}
+grants:
+  group://backend: {}
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = dup.items()
+	assert.NotNil(t, err)
+}
+
+func TestDiffMembers(t *testing.T) {
+	keep, update, remove, owner := uuid.NewV1(), uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	live := []policy.PolicyMemberInfo{
+		{PolicyMember: policy.PolicyMember{MemberId: keep, MemberType: policy.UserType, Actions: policy.Enable(policy.View)}},
+		{PolicyMember: policy.PolicyMember{MemberId: update, MemberType: policy.UserType, Actions: policy.Enable(policy.View)}},
+		{PolicyMember: policy.PolicyMember{MemberId: remove, MemberType: policy.GroupType, Actions: policy.Enable(policy.View)}},
+		{PolicyMember: policy.PolicyMember{MemberId: owner, MemberType: policy.UserType, Actions: policy.Enable(policy.Sudo)}},
+	}
+
+	changes := diffMembers("secret://db", live, []desiredMember{
+		{"user://keep", keep, policy.Enable(policy.View)},
+		{"user://update", update, policy.Enable(policy.View, policy.Edit)},
+		{"group://new", uuid.Nil, policy.Enable(policy.View)},
+	})
+	if !assert.Equal(t, 3, len(changes)) {
+		return
+	}
+
+	assert.Equal(t, UpdateChange, changes[0].Kind)
+	assert.Equal(t, update, changes[0].MemberId)
+	assert.Equal(t, GrantChange, changes[1].Kind)
+	assert.Equal(t, "group://new", changes[1].Member)
+	assert.Equal(t, RevokeChange, changes[2].Kind)
+	assert.Equal(t, remove, changes[2].MemberId)
+
+	plan := Plan{Changes: append([]Change{{Kind: CreateGroupChange, Item: "group://new"}}, changes...)}
+
+	graph, err := plan.Graph()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	entry := graph.Entry()
+	for _, v := range entry {
+		assert.NotEqual(t, "group://new", v.Data.(Change).Member)
+	}
+}
+
+func TestParseDeclaredActions(t *testing.T) {
+	itemRef, err := ParseItemRef("group://backend")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	actions, err := parseDeclaredActions(itemRef, []string{"edit", "!delete"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, actions.Enabled(policy.Edit))
+	assert.True(t, actions.Denied(policy.Delete))
+
+	actions, err = parseDeclaredActions(itemRef, []string{"!delete"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Empty(t, actions.Flatten())
+	assert.Equal(t, []policy.Action{policy.Delete}, actions.Denials())
+}
@@ -100,7 +100,10 @@ func LoadPolicyLock(s session.Session, orgId, policyId, memberId uuid.UUID) (ret
 	return
 }
 
-func GrantPolicyMember(s session.Session, lock policy.PolicyLock, memberType MemberType, memberId uuid.UUID, actions ...policy.Action) (err error) {
+// Grants the actions to the member, replacing any it already holds.
+// Denied actions are withheld from the member, even where another
+// membership would grant them.
+func GrantPolicyMember(s session.Session, lock policy.PolicyLock, memberType MemberType, memberId uuid.UUID, cond policy.Conditions, denies []policy.Action, actions ...policy.Action) (err error) {
 	priv, err := s.Secret().RecoverKey()
 	if err != nil {
 		return
@@ -117,6 +120,17 @@ func GrantPolicyMember(s session.Session, lock policy.PolicyLock, memberType Mem
 	if err != nil {
 		return
 	}
+	member.Conditions = cond
+	member.Actions = member.Actions.Deny(denies...)
+
+	// Replacing a membership writes over its latest version.
+	orig, ok, err := LoadPolicyMember(s, lock.OrgId(), lock.Id(), memberId)
+	if err != nil {
+		return
+	}
+	if ok {
+		member.Version = orig.Version + 1
+	}
 
 	err = SavePolicyMember(s, member)
 	return
@@ -0,0 +1,122 @@
+package policies
+
+import (
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	rosterPageSize = 256
+)
+
+// Lists the active memberships of the member across all policies of the org.
+func ListMemberships(s session.Session, orgId, memberId uuid.UUID) (ret []policy.PolicyMember, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	for offset := uint64(0); ; offset += rosterPageSize {
+		next, err := s.Options().Policies().ListMemberships(token, orgId, memberId,
+			page.BuildPage(page.Offset(offset), page.Limit(rosterPageSize)))
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, next...)
+		if len(next) < rosterPageSize {
+			return ret, nil
+		}
+	}
+}
+
+// Prepares the replacement of the pass, key pair and secret of the
+// policy.  The pass is re-issued to every remaining member, so once
+// committed, any member that has been revoked no longer has a path to
+// the policy's secrets.  Nothing is saved until the rekey is committed.
+//
+// The new secret is returned so that the caller may re-encrypt any
+// assets protected by the policy before committing.  The caller must
+// destroy it.
+func PrepareRekey(s session.Session, lock policy.PolicyLock) (rekey policy.Rekey, secret crypto.Bytes, err error) {
+	if err = policy.Has(policy.Sudo)(lock.Actions()); err != nil {
+		return
+	}
+
+	priv, err := s.Secret().RecoverKey()
+	if err != nil {
+		return
+	}
+	defer priv.Destroy()
+
+	roster, err := listRosterKeys(s, lock.OrgId(), lock.Id())
+	if err != nil {
+		return
+	}
+
+	memberships, err := ListMemberships(s, lock.OrgId(), lock.Id())
+	if err != nil {
+		return
+	}
+
+	rekey, raw, err := lock.Rekey(crypto.Rand, priv, roster, memberships)
+	if err != nil {
+		return
+	}
+
+	secret = crypto.Bytes(raw)
+	return
+}
+
+// Commits a prepared rekey, replacing the keys of the policy.  Returns
+// the ids of the policies in which this policy holds a membership.
+// Their passes were protected by the previous keys and should be
+// considered exposed to the revoked members.
+func CommitRekey(s session.Session, rekey policy.Rekey) (held []uuid.UUID, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(rekey.Policy.OrgId))
+	if err != nil {
+		return
+	}
+
+	if err = s.Options().Policies().RekeyPolicy(token, rekey); err != nil {
+		return
+	}
+
+	held = rekey.HeldPolicyIds()
+	return
+}
+
+// Loads the current roster of the policy along with the public key of each member.
+func listRosterKeys(s session.Session, orgId, policyId uuid.UUID) (ret []policy.MemberKey, err error) {
+	for offset := uint64(0); ; offset += rosterPageSize {
+		members, err := ListPolicyMembers(s, orgId, policyId,
+			page.Offset(offset), page.Limit(rosterPageSize))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range members {
+			typ, ok := StaticMemberTypes.LookupByProtocol(string(m.MemberType))
+			if !ok {
+				return nil, errors.Wrapf(errs.StateError, "Unable to rekey member [%v] of unknown type [%v]", m.MemberId, m.MemberType)
+			}
+
+			pub, err := typ.GetPublicKey(s, orgId, m.MemberId)
+			if err != nil || pub == nil {
+				return nil, errs.Or(err, errors.Wrapf(errs.StateError, "Unable to download public key [%v]", m.MemberId))
+			}
+
+			ret = append(ret, policy.MemberKey{Member: m.PolicyMember, Key: pub})
+		}
+
+		if len(members) < rosterPageSize {
+			return ret, nil
+		}
+	}
+}
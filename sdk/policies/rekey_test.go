@@ -0,0 +1,107 @@
+package policies
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// Rekeys may only change the passes of members.  Their conditions are
+// kept, whatever the client sends.
+func TestRekey_Conditions(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	server, err := httptest.StartDefaultServer(ctx)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	var sessions []session.Session
+	for i := 0; i < 2; i++ {
+		key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+			return
+		}
+
+		err = session.Register(ctx,
+			auth.ByKey(key.Public()),
+			auth.WithSignature(key, crypto.Moderate),
+			session.WithClient(server.Connect()))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+			return
+		}
+
+		s, err := session.Authenticate(ctx,
+			auth.ByKey(key.Public()),
+			auth.WithSignature(key, crypto.Moderate),
+			session.WithClient(server.Connect()))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+			return
+		}
+		sessions = append(sessions, s)
+	}
+
+	owner, member := sessions[0], sessions[1]
+
+	o, err := orgs.Purchase(owner, "rekey-conditions")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	if !assert.Nil(t, orgs.CreateMember(owner, o.Id, member.AccountId(), auth.Member)) {
+		t.FailNow()
+		return
+	}
+
+	lock, err := CreatePolicy(owner, o.Id, crypto.Moderate, policy.Sudo)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	cond := policy.Conditions{Cidrs: []string{"10.0.0.0/8"}}
+	if !assert.Nil(t, GrantPolicyMember(owner, lock, UserType, member.AccountId(), cond, nil, policy.View)) {
+		t.FailNow()
+		return
+	}
+
+	lock, err = RequirePolicyLock(owner, o.Id, lock.Id(), owner.AccountId())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	rekey, secret, err := PrepareRekey(owner, lock)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer secret.Destroy()
+
+	for i := range rekey.Members {
+		rekey.Members[i].Conditions = policy.Conditions{}
+	}
+
+	if _, err = CommitRekey(owner, rekey); !assert.Nil(t, err) {
+		return
+	}
+
+	m, err := RequirePolicyMember(owner, o.Id, lock.Id(), member.AccountId())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, cond.Cidrs, m.Conditions.Cidrs)
+}
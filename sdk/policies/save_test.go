@@ -0,0 +1,143 @@
+package policies
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/org"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// Saves must be made over the latest version of a resource.
+func TestSave_Conditional(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	server, err := httptest.StartDefaultServer(ctx)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	err = session.Register(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	s, err := session.Authenticate(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	o, err := orgs.Purchase(s, "conditional")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	t.Run("Org", func(t *testing.T) {
+		token, err := s.FetchToken(auth.WithOrgId(o.Id))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		first := o.Update(func(o *org.Org) { o.Name = "conditional-a" })
+		if !assert.Nil(t, s.Options().Orgs().SaveOrg(token, first)) {
+			return
+		}
+
+		err = s.Options().Orgs().SaveOrg(token, o.Update(func(o *org.Org) { o.Name = "conditional-b" }))
+		assert.True(t, errs.Is(err, client.ErrPrecondition), "%v", err)
+
+		cur, _, err := orgs.LoadById(s, o.Id)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, first.Name, cur.Name)
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		group, err := CreateGroup(s, o.Id, "group", "", crypto.Minimal)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		err = SaveGroup(s, group)
+		assert.True(t, errs.Is(err, client.ErrPrecondition), "%v", err)
+
+		first := group.Update(func(g *policy.Group) { g.Description = "a" })
+		if !assert.Nil(t, SaveGroup(s, first)) {
+			return
+		}
+
+		err = SaveGroup(s, group.Update(func(g *policy.Group) { g.Description = "b" }))
+		assert.True(t, errs.Is(err, client.ErrPrecondition), "%v", err)
+
+		cur, err := RequireGroupById(s, o.Id, group.Id)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, "a", cur.Description)
+	})
+
+	t.Run("PolicyMember", func(t *testing.T) {
+		group, err := CreateGroup(s, o.Id, "members", "", crypto.Minimal)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		lock, err := CreatePolicy(s, o.Id, crypto.Minimal, policy.Sudo)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		if !assert.Nil(t, GrantPolicyMember(s, lock, GroupType, group.Id, policy.Conditions{}, nil, policy.View)) {
+			return
+		}
+
+		orig, _, err := LoadPolicyMember(s, o.Id, lock.Id(), group.Id)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		// Granting again replaces the membership.
+		if !assert.Nil(t, GrantPolicyMember(s, lock, GroupType, group.Id, policy.Conditions{}, nil, policy.Edit)) {
+			return
+		}
+
+		err = SavePolicyMember(s, orig.Update(func(m *policy.PolicyMember) { m.Actions = policy.Enable(policy.Sudo) }))
+		assert.True(t, errs.Is(err, client.ErrPrecondition), "%v", err)
+
+		cur, _, err := LoadPolicyMember(s, o.Id, lock.Id(), group.Id)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, orig.Version+1, cur.Version)
+		assert.True(t, cur.Actions.Enabled(policy.Edit))
+		assert.False(t, cur.Actions.Enabled(policy.Sudo))
+	})
+}
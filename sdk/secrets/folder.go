@@ -0,0 +1,234 @@
+package secrets
+
+import (
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	FolderType = folderItem{}
+)
+
+func init() {
+	policies.StaticItemTypes.Register(FolderType)
+	policies.StaticMemberTypes.Register(FolderType)
+}
+
+// Implements the folder member type and item type interfaces.  As a
+// member, a folder is identified by the id of its policy.
+type folderItem struct{}
+
+func (f folderItem) Name() string {
+	return policy.FolderType
+}
+
+func (f folderItem) Type() policy.Type {
+	return policy.FolderType
+}
+
+func (f folderItem) AllActions() []policies.ActionInfo {
+	return SecretType.AllActions()
+}
+
+func (f folderItem) DefaultActions() []policy.Action {
+	return SecretType.DefaultActions()
+}
+
+func (f folderItem) ParseAction(str string) (policy.Action, error) {
+	return secret.ParseAction(str)
+}
+
+func (f folderItem) GetPolicyIdByName(s session.Session, orgId uuid.UUID, path string) (id uuid.UUID, err error) {
+	folder, err := RequireFolderByPath(s, orgId, path)
+	if err != nil {
+		return
+	}
+
+	id = folder.PolicyId
+	return
+}
+
+func (f folderItem) GetPolicyIdByUUID(s session.Session, orgId, folderId uuid.UUID) (id uuid.UUID, err error) {
+	folder, err := RequireFolderById(s, orgId, folderId)
+	if err != nil {
+		return
+	}
+
+	id = folder.PolicyId
+	return
+}
+
+func (f folderItem) GetMemberId(s session.Session, orgId uuid.UUID, path string) (id uuid.UUID, err error) {
+	return f.GetPolicyIdByName(s, orgId, path)
+}
+
+func (f folderItem) GetPublicKey(s session.Session, orgId, policyId uuid.UUID) (pub crypto.PublicKey, err error) {
+	policy, err := policies.RequirePolicyById(s, orgId, policyId)
+	if err != nil {
+		return
+	}
+
+	pub = policy.Key.Pub
+	return
+}
+
+// Creates a folder at the path.  The caller is given sudo on the
+// folder.  Existing secrets are not attached automatically.
+func CreateFolder(s session.Session, orgId uuid.UUID, path string, strength crypto.Strength) (ret secret.Folder, err error) {
+	path, err = secret.CleanFolderPath(path)
+	if err != nil {
+		return
+	}
+
+	if _, ok, err := LoadFolderByPath(s, orgId, path); err != nil || ok {
+		return ret, errs.Or(err, errors.Wrapf(errs.StateError, "Folder [%v] already exists", path))
+	}
+
+	lock, err := policies.CreatePolicy(s, orgId, strength, policy.Sudo)
+	if err != nil {
+		return
+	}
+
+	ret, err = secret.NewFolder(orgId, lock.Id(), path)
+	if err != nil {
+		return
+	}
+
+	err = SaveFolder(s, ret)
+	return
+}
+
+func SaveFolder(s session.Session, f secret.Folder) (err error) {
+	token, err := s.FetchToken(auth.WithOrgId(f.OrgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Secrets().SaveFolder(token, f)
+	return
+}
+
+func ListFolders(s session.Session, orgId uuid.UUID, filter secret.FolderFilter, opts ...page.PageOption) (ret []secret.FolderInfo, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Secrets().ListFolders(token, orgId, filter, page.BuildPage(opts...))
+	return
+}
+
+func RequireFolderByPath(s session.Session, orgId uuid.UUID, path string) (ret secret.Folder, err error) {
+	ret, ok, err := LoadFolderByPath(s, orgId, path)
+	if err != nil || !ok {
+		err = errs.Or(err, errors.Wrapf(secret.ErrNoFolder, "No such folder [%v]", path))
+	}
+	return
+}
+
+func LoadFolderByPath(s session.Session, orgId uuid.UUID, path string) (ret secret.Folder, ok bool, err error) {
+	path, err = secret.CleanFolderPath(path)
+	if err != nil {
+		return
+	}
+
+	all, err := ListFolders(s, orgId, secret.BuildFolderFilter(secret.FilterFolderPaths(path)), page.Limit(1))
+	if err != nil || len(all) != 1 {
+		return
+	}
+
+	ret, ok = all[0].Folder, true
+	return
+}
+
+func RequireFolderById(s session.Session, orgId, folderId uuid.UUID) (ret secret.Folder, err error) {
+	all, err := ListFolders(s, orgId, secret.BuildFolderFilter(secret.FilterFolderIds(folderId)), page.Limit(1))
+	if err != nil || len(all) != 1 {
+		err = errs.Or(err, errors.Wrapf(secret.ErrNoFolder, "No such folder [%v]", folderId))
+		return
+	}
+
+	ret = all[0].Folder
+	return
+}
+
+// Returns the folders under which the secret lives, from the
+// outermost inwards.
+func ListAncestorFolders(s session.Session, orgId uuid.UUID, name string) (ret []secret.Folder, err error) {
+	paths := secret.FolderPaths(name)
+	if len(paths) == 0 {
+		return
+	}
+
+	all, err := ListFolders(s, orgId, secret.BuildFolderFilter(secret.FilterFolderPaths(paths...)))
+	if err != nil {
+		return
+	}
+
+	for _, f := range all {
+		ret = append(ret, f.Folder)
+	}
+	return
+}
+
+// Attaches the folders to the policy of a secret.  The folders are
+// granted no actions of their own.  Instead, their members inherit
+// the actions they hold on the folder.
+func AttachFolders(s session.Session, lock policy.PolicyLock, folders ...secret.Folder) (err error) {
+	for _, f := range folders {
+		_, ok, err := policies.LoadPolicyMember(s, lock.OrgId(), lock.Id(), f.PolicyId)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+
+		if err := policies.GrantPolicyMember(s, lock, FolderType, f.PolicyId, policy.Conditions{}, nil); err != nil {
+			return errors.Wrapf(err, "Error attaching folder [%v]", f.Path)
+		}
+	}
+	return
+}
+
+// Attaches the folder to every existing secret beneath it on which
+// the caller holds sudo.  Returns the names of the secrets that were
+// attached and of those that were skipped.
+func AttachExisting(s session.Session, f secret.Folder) (attached, skipped []string, err error) {
+	all, err := Search(s, f.OrgId,
+		secret.BuildFilter(
+			secret.FilterByPrefix(f.Path+"/"),
+			secret.FilterShowHidden(true)))
+	if err != nil {
+		return
+	}
+
+	for _, cur := range all {
+		if !f.Contains(cur.Name) {
+			continue
+		}
+		if !cur.Actions.Enabled(policy.Sudo) {
+			skipped = append(skipped, cur.Name)
+			continue
+		}
+
+		lock, err := policies.RequirePolicyLock(s, f.OrgId, cur.PolicyId, s.AccountId())
+		if err != nil {
+			return attached, skipped, err
+		}
+
+		if err := AttachFolders(s, lock, f); err != nil {
+			return attached, skipped, err
+		}
+		attached = append(attached, cur.Name)
+	}
+	return
+}
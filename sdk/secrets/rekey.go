@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	// The number of attempts made to save each re-encrypted secret
+	// once the policy has been rekeyed, and the delay between them.
+	rekeyAttempts = 3
+	rekeyBackoff  = 500 * time.Millisecond
+)
+
+// Rekeys the policy and re-encrypts the latest version of every
+// secret it protects.  Previous versions remain encrypted under the
+// old keys and are no longer readable.
+//
+// Every secret is re-encrypted and uploaded under the new keys before
+// the keys of the policy are replaced.  If any of them fails, the
+// policy is left untouched.  Once replaced, the new versions are saved,
+// retrying any that fail.  Secrets that still could not be saved are
+// reported in the error, along with the streams that hold their values.
+//
+// Returns the number of secrets re-encrypted along with the ids of
+// the policies in which the rekeyed policy holds a membership.  These
+// are returned whenever the policy was rekeyed, even on error.
+func Rekey(s session.Session, orgId, policyId uuid.UUID) (num int, held []uuid.UUID, err error) {
+	lock, err := policies.RequirePolicyLock(s, orgId, policyId, s.AccountId())
+	if err != nil {
+		return
+	}
+
+	items, err := listByPolicy(s, orgId, policyId)
+	if err != nil {
+		return
+	}
+
+	rekey, pass, err := policies.PrepareRekey(s, lock)
+	if err != nil {
+		return
+	}
+	defer pass.Destroy()
+
+	staged := make([]secret.Secret, 0, len(items))
+	for _, item := range items {
+		next, err := stage(s, item, pass, lock.Strength(),
+			fmt.Sprintf("Re-encrypted after rekey of policy [%v]", policyId))
+		if err != nil {
+			return 0, nil, errors.Wrapf(err, "Unable to re-encrypt [%v]. Policy [%v] was not rekeyed", item.Name, policyId)
+		}
+		staged = append(staged, next)
+	}
+
+	if held, err = policies.CommitRekey(s, rekey); err != nil {
+		return
+	}
+
+	var failed []string
+	for _, next := range staged {
+		if err := saveStaged(s, next); err != nil {
+			failed = append(failed, fmt.Sprintf("%v (stream %v)", next.Name, next.StreamId))
+			continue
+		}
+		num++
+	}
+
+	if len(failed) > 0 {
+		err = errors.Wrapf(errs.StateError, "Policy [%v] was rekeyed, but unable to save %v", policyId, failed)
+	}
+	return
+}
+
+// Reads the latest version of the secret and uploads it encrypted
+// under the given pass, returning the unsaved next version.
+func stage(s session.Session, item secret.Secret, pass crypto.Bytes, strength crypto.Strength, comment string) (ret secret.Secret, err error) {
+	buf := &bytes.Buffer{}
+	defer func() {
+		crypto.Bytes(buf.Bytes()).Destroy()
+	}()
+
+	if err = Read(s, item, buf); err != nil {
+		return
+	}
+
+	ret, err = upload(s, pass, strength, item.Update().SetComment(comment), bytes.NewReader(buf.Bytes()))
+	return
+}
+
+func saveStaged(s session.Session, next secret.Secret) (err error) {
+	for i := 0; i < rekeyAttempts; i++ {
+		if i > 0 {
+			time.Sleep(rekeyBackoff)
+		}
+		if err = SaveSecret(s, next); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// Rekeys the given policies.  The policies in which a rekeyed policy
+// holds a membership are rekeyed as well, since their passes were
+// protected by the replaced keys.  Each policy is reported to the
+// observer, which may be nil.
+func RekeyAll(s session.Session, orgId uuid.UUID, policyIds []uuid.UUID, fn func(policyId uuid.UUID, num int, err error)) (err error) {
+	visited := make(map[uuid.UUID]struct{})
+
+	var failed int
+	for queue := policyIds; len(queue) > 0; {
+		policyId := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[policyId]; ok {
+			continue
+		}
+		visited[policyId] = struct{}{}
+
+		num, held, err := Rekey(s, orgId, policyId)
+		if fn != nil {
+			fn(policyId, num, err)
+		}
+		if err != nil {
+			failed++
+		}
+
+		queue = append(queue, held...)
+	}
+
+	if failed > 0 {
+		err = errors.Wrapf(errs.StateError, "Unable to rekey [%v] of [%v] policies", failed, len(visited))
+	}
+	return
+}
+
+func listByPolicy(s session.Session, orgId, policyId uuid.UUID) (ret []secret.Secret, err error) {
+	const size = 256
+	for offset := uint64(0); ; offset += size {
+		all, err := Search(s, orgId,
+			secret.BuildFilter(
+				secret.FilterByPolicyIds(policyId),
+				secret.FilterShowHidden(true)),
+			page.Offset(offset), page.Limit(size))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sec := range all {
+			ret = append(ret, sec.Secret)
+		}
+
+		if len(all) < size {
+			return ret, nil
+		}
+	}
+}
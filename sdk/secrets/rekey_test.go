@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cott-io/stash/http/server/httpsecret"
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/env"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/policies"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+var saveSecretRoute = http.Post("/v1/orgs/{orgId}/secrets")
+
+// Fails the next requests of a route.
+type faults struct {
+	lock  sync.Mutex
+	route map[http.Route]int
+}
+
+func (f *faults) Fail(r http.Route, num int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.route[r] = num
+}
+
+func (f *faults) Middleware(h http.Handler) http.Handler {
+	return func(e env.Environment, req http.Request) http.Response {
+		f.lock.Lock()
+		num := f.route[req.Route()]
+		if num > 0 {
+			f.route[req.Route()] = num - 1
+		}
+		f.lock.Unlock()
+		if num > 0 {
+			return http.StatusPanic
+		}
+		return h(e, req)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	rekeyBackoff = 0
+
+	faults := &faults{route: make(map[http.Route]int)}
+
+	server, err := httptest.StartDefaultServer(ctx, http.WithMiddleware(faults.Middleware))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	err = session.Register(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	s, err := session.Authenticate(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	o, err := orgs.Purchase(s, "rekey")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	first, err := Create(s, secret.NewSecret().SetOrg(o.Id).SetName("/a"), strings.NewReader("a"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	_, err = Write(s, secret.NewSecret().SetOrg(o.Id).SetName("/b").SetPolicy(first.PolicyId), strings.NewReader("b"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	policyVersion := func() int {
+		p, err := policies.RequirePolicyById(s, o.Id, first.PolicyId)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		return p.Version
+	}
+
+	assertValues := func(t *testing.T) {
+		for name, val := range map[string]string{"/a": "a", "/b": "b"} {
+			cur, err := RequireByName(s, o.Id, name)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			buf := &bytes.Buffer{}
+			if !assert.Nil(t, Read(s, cur.Secret, buf)) {
+				return
+			}
+			assert.Equal(t, val, buf.String())
+		}
+	}
+
+	t.Run("FailedUpload", func(t *testing.T) {
+		before := policyVersion()
+
+		faults.Fail(httpsecret.SaveBlocksRoute, 1)
+
+		num, held, err := Rekey(s, o.Id, first.PolicyId)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, num)
+		assert.Empty(t, held)
+		assert.Equal(t, before, policyVersion())
+		assertValues(t)
+	})
+
+	t.Run("RetriedSave", func(t *testing.T) {
+		before := policyVersion()
+
+		faults.Fail(saveSecretRoute, 1)
+
+		num, _, err := Rekey(s, o.Id, first.PolicyId)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, num)
+		assert.True(t, policyVersion() > before)
+		assertValues(t)
+	})
+
+	t.Run("FailedSave", func(t *testing.T) {
+		before := policyVersion()
+
+		faults.Fail(saveSecretRoute, rekeyAttempts)
+
+		num, _, err := Rekey(s, o.Id, first.PolicyId)
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, num)
+		assert.True(t, policyVersion() > before)
+		assert.Contains(t, err.Error(), "stream")
+	})
+}
@@ -0,0 +1,328 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/dag"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/lang/term"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// A rotator generates the next value of a secret from its current value.
+type Rotator func(cur secret.Secret, val []byte) ([]byte, error)
+
+// Returns the rotator of a rotation.  Command rotators run the named
+// command from the given (locally configured) commands.
+func NewRotator(r secret.Rotation, commands map[string]string) (ret Rotator, err error) {
+	if err = r.Validate(); err != nil {
+		return
+	}
+
+	switch r.Rotator {
+	case secret.PasswordRotator:
+		ret = RotatePassword
+	case secret.RSARotator:
+		ret = RotateRSA
+	case secret.Ed25519Rotator:
+		ret = RotateEd25519
+	case secret.CommandRotator:
+		cmd, ok := commands[r.Command]
+		if !ok {
+			err = errors.Wrapf(errs.StateError, "Command [%v] is not configured on this machine", r.Command)
+			return
+		}
+		ret = RotateCommand(cmd)
+	}
+	return
+}
+
+func RotatePassword(secret.Secret, []byte) (ret []byte, err error) {
+	pass, err := crypto.GenPass(crypto.Rand,
+		crypto.WithPassStrength(crypto.Strong),
+		crypto.WithNumbers(),
+		crypto.WithSymbols())
+	if err != nil {
+		return
+	}
+
+	ret = []byte(pass)
+	return
+}
+
+func RotateRSA(secret.Secret, []byte) (ret []byte, err error) {
+	key, err := crypto.GenRSAKey(crypto.Rand, crypto.Strong.KeySize())
+	if err != nil {
+		return
+	}
+	defer key.Destroy()
+
+	ret, err = crypto.MarshalPemPrivateKey(key, crypto.EncodePKCS1)
+	return
+}
+
+func RotateEd25519(secret.Secret, []byte) (ret []byte, err error) {
+	_, key, err := ed25519.GenerateKey(crypto.Rand)
+	if err != nil {
+		return
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return
+	}
+
+	ret = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return
+}
+
+// Returns a rotator that runs a command in the system shell.  The
+// current value is provided on stdin and the next value is read
+// from stdout.
+func RotateCommand(cmd string) Rotator {
+	return func(cur secret.Secret, val []byte) (ret []byte, err error) {
+		out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+
+		err = term.Exec(term.SystemShell,
+			term.SystemIO.
+				SwapIn(bytes.NewReader(val)).
+				SwapOut(out).
+				SwapErr(errOut),
+			cmd,
+			term.WithEnv(map[string]string{
+				"STASH_SECRET_NAME":    cur.Name,
+				"STASH_SECRET_VERSION": strconv.Itoa(cur.Version),
+			}))
+		if err != nil {
+			err = errors.Wrapf(err, "Rotation command failed: %v", errOut.String())
+			return
+		}
+
+		ret = bytes.TrimRight(out.Bytes(), "\r\n")
+		if len(ret) == 0 {
+			err = errors.Wrapf(errs.StateError, "Rotation command produced no value")
+		}
+		return
+	}
+}
+
+type RotateOptions struct {
+	Parallelism int
+	Commands    map[string]string
+	Canceler    <-chan struct{}
+	Observer    func(prev, next secret.Secret, err error)
+}
+
+func RotateParallelism(n int) func(*RotateOptions) {
+	return func(o *RotateOptions) {
+		o.Parallelism = n
+	}
+}
+
+// Configures the commands available to command rotators, by name.
+func RotateCommands(cmds map[string]string) func(*RotateOptions) {
+	return func(o *RotateOptions) {
+		o.Commands = cmds
+	}
+}
+
+func RotateCanceler(c <-chan struct{}) func(*RotateOptions) {
+	return func(o *RotateOptions) {
+		o.Canceler = c
+	}
+}
+
+// Registers a function that is called once each secret has been rotated.
+func RotateObserver(fn func(prev, next secret.Secret, err error)) func(*RotateOptions) {
+	return func(o *RotateOptions) {
+		o.Observer = fn
+	}
+}
+
+func BuildRotateOptions(fns ...func(*RotateOptions)) (ret RotateOptions) {
+	ret = RotateOptions{
+		Parallelism: 4,
+		Observer:    func(secret.Secret, secret.Secret, error) {},
+	}
+	for _, fn := range fns {
+		fn(&ret)
+	}
+	return
+}
+
+// Rotates a single secret, writing its next value as a new version.
+func Rotate(s session.Session, cur secret.Secret, o ...func(*RotateOptions)) (next secret.Secret, err error) {
+	rotator, err := NewRotator(cur.Rotation, BuildRotateOptions(o...).Commands)
+	if err != nil {
+		err = errors.Wrapf(err, "Unable to rotate secret [%v]", cur.Name)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Read(s, cur, buf); err != nil {
+		return
+	}
+
+	val, err := rotator(cur, buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	rot := cur.Rotation
+	rot.Last = now
+
+	next, err = Write(s,
+		cur.Update().
+			SetRotation(rot).
+			SetComment(fmt.Sprintf("Rotated by [%v] rotator at [%v]", rot.Rotator, now.Format(time.RFC3339))),
+		bytes.NewReader(val))
+	return
+}
+
+// Rotates all the secrets.  A secret is only rotated once all of its
+// dependencies (within the input set) have been successfully rotated.
+// Independent secrets are rotated in parallel.
+func RotateAll(s session.Session, all []secret.Secret, o ...func(*RotateOptions)) (ret []secret.Secret, err error) {
+	opts := BuildRotateOptions(o...)
+
+	graph, err := RotationGraph(all)
+	if err != nil {
+		return
+	}
+
+	var lock sync.Mutex
+	err = graph.Traverse(func(_ <-chan struct{}, v dag.Vertex) (err error) {
+		cur := v.Data.(secret.Secret)
+
+		next, err := Rotate(s, cur, RotateCommands(opts.Commands))
+		opts.Observer(cur, next, err)
+		if err != nil {
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		ret = append(ret, next)
+		return
+	},
+		dag.WithParallelism(opts.Parallelism),
+		dag.WithCanceler(opts.Canceler))
+	return
+}
+
+// Builds the dependency graph of the secrets.  Dependencies that are
+// not members of the input set are ignored.
+func RotationGraph(all []secret.Secret) (ret *dag.Graph, err error) {
+	builder := dag.NewBuilder()
+
+	byName := make(map[string]secret.Secret)
+	for _, sec := range all {
+		builder, byName[sec.Name] = builder.AddVertex(sec.Name, sec), sec
+	}
+
+	for _, sec := range all {
+		for _, dep := range sec.Rotation.Depends {
+			if _, ok := byName[dep]; ok {
+				builder = builder.AddEdge(dep, sec.Name)
+			}
+		}
+	}
+
+	ret, err = builder.Build()
+	if err != nil {
+		err = errors.Wrapf(errs.StateError, "Invalid rotation dependencies: %v", err)
+	}
+	return
+}
+
+// Lists all the secrets of an org that have rotation enabled.
+func ListRotations(s session.Session, orgId uuid.UUID) (ret []secret.Secret, err error) {
+	const size = 256
+	for offset := uint64(0); ; offset += size {
+		all, err := Search(s, orgId, secret.BuildFilter(), page.Offset(offset), page.Limit(size))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sec := range all {
+			if sec.Rotation.Enabled() {
+				ret = append(ret, sec.Secret)
+			}
+		}
+
+		if len(all) < size {
+			return ret, nil
+		}
+	}
+}
+
+// Plans the rotation of the named secrets.  Every rotation-enabled
+// secret that (transitively) depends on a named secret is rotated too.
+func PlanRotation(s session.Session, orgId uuid.UUID, names ...string) (ret []secret.Secret, err error) {
+	enabled, err := ListRotations(s, orgId)
+	if err != nil {
+		return
+	}
+
+	ret, err = planRotation(enabled, names...)
+	return
+}
+
+func planRotation(enabled []secret.Secret, names ...string) (ret []secret.Secret, err error) {
+	byName := make(map[string]secret.Secret)
+	for _, sec := range enabled {
+		byName[sec.Name] = sec
+	}
+
+	planned := make(map[string]bool)
+
+	var visit func(string)
+	visit = func(name string) {
+		if planned[name] {
+			return
+		}
+
+		planned[name] = true
+		ret = append(ret, byName[name])
+		for _, sec := range enabled {
+			for _, dep := range sec.Rotation.Depends {
+				if dep == name {
+					visit(sec.Name)
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			err = errors.Wrapf(errs.StateError, "Secret [%v] does not have a rotation configured", name)
+			return
+		}
+		visit(name)
+	}
+	return
+}
+
+// Returns the names of the rotation-enabled secrets whose schedules have elapsed.
+func CollectDue(all []secret.Secret, now time.Time) (ret []string) {
+	for _, sec := range all {
+		if sec.Rotation.Due(now) {
+			ret = append(ret, sec.Name)
+		}
+	}
+	return
+}
@@ -0,0 +1,196 @@
+package secrets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/dag"
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRotating(name string, last time.Time, interval time.Duration, deps ...string) secret.Secret {
+	return secret.Secret{
+		Name: name,
+		Rotation: secret.Rotation{
+			Rotator:  secret.PasswordRotator,
+			Interval: interval,
+			Depends:  deps,
+			Last:     last,
+		},
+	}
+}
+
+func names(all []secret.Secret) (ret []string) {
+	for _, s := range all {
+		ret = append(ret, s.Name)
+	}
+	return
+}
+
+func TestRotationGraph(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []secret.Secret
+		cycle bool
+		order [][2]string // pairs of (before, after)
+	}{
+		{
+			name: "Independent",
+			input: []secret.Secret{
+				newRotating("/a", time.Time{}, 0),
+				newRotating("/b", time.Time{}, 0),
+			},
+		},
+		{
+			name: "Chain",
+			input: []secret.Secret{
+				newRotating("/url", time.Time{}, 0, "/pass"),
+				newRotating("/dsn", time.Time{}, 0, "/url"),
+				newRotating("/pass", time.Time{}, 0),
+			},
+			order: [][2]string{{"/pass", "/url"}, {"/url", "/dsn"}},
+		},
+		{
+			name: "Diamond",
+			input: []secret.Secret{
+				newRotating("/d", time.Time{}, 0, "/b", "/c"),
+				newRotating("/b", time.Time{}, 0, "/a"),
+				newRotating("/c", time.Time{}, 0, "/a"),
+				newRotating("/a", time.Time{}, 0),
+			},
+			order: [][2]string{{"/a", "/b"}, {"/a", "/c"}, {"/b", "/d"}, {"/c", "/d"}},
+		},
+		{
+			name: "ExternalDependency",
+			input: []secret.Secret{
+				newRotating("/a", time.Time{}, 0, "/not-rotated"),
+			},
+		},
+		{
+			name: "Cycle",
+			input: []secret.Secret{
+				newRotating("/a", time.Time{}, 0, "/b"),
+				newRotating("/b", time.Time{}, 0, "/a"),
+			},
+			cycle: true,
+		},
+		{
+			name: "SelfCycle",
+			input: []secret.Secret{
+				newRotating("/a", time.Time{}, 0, "/a"),
+			},
+			cycle: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph, err := RotationGraph(test.input)
+			if test.cycle {
+				assert.True(t, errs.Is(err, errs.StateError), "%v", err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			pos := make(map[string]int)
+			err = graph.Traverse(func(_ <-chan struct{}, v dag.Vertex) error {
+				pos[v.Id] = len(pos)
+				return nil
+			}, dag.WithParallelism(1))
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			assert.Equal(t, len(test.input), len(pos))
+			for _, o := range test.order {
+				assert.True(t, pos[o[0]] < pos[o[1]], "Expected [%v] before [%v]", o[0], o[1])
+			}
+		})
+	}
+}
+
+func TestPlanRotation(t *testing.T) {
+	enabled := []secret.Secret{
+		newRotating("/pass", time.Time{}, 0),
+		newRotating("/url", time.Time{}, 0, "/pass"),
+		newRotating("/dsn", time.Time{}, 0, "/url"),
+		newRotating("/other", time.Time{}, 0),
+	}
+
+	tests := []struct {
+		name   string
+		input  []string
+		expect []string
+		err    bool
+	}{
+		{"Leaf", []string{"/dsn"}, []string{"/dsn"}, false},
+		{"Transitive", []string{"/pass"}, []string{"/pass", "/url", "/dsn"}, false},
+		{"Overlapping", []string{"/url", "/pass"}, []string{"/url", "/dsn", "/pass"}, false},
+		{"Multiple", []string{"/other", "/url"}, []string{"/other", "/url", "/dsn"}, false},
+		{"NotEnabled", []string{"/missing"}, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := planRotation(enabled, test.input...)
+			if test.err {
+				assert.True(t, errs.Is(err, errs.StateError), "%v", err)
+				return
+			}
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, test.expect, names(plan))
+		})
+	}
+}
+
+func TestCollectDue(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		input  secret.Secret
+		expect bool
+	}{
+		{"Elapsed", newRotating("/a", now.Add(-2*time.Hour), time.Hour), true},
+		{"Exact", newRotating("/a", now.Add(-time.Hour), time.Hour), true},
+		{"NotElapsed", newRotating("/a", now.Add(-time.Minute), time.Hour), false},
+		{"NeverRotated", newRotating("/a", time.Time{}, time.Hour), true},
+		{"Unscheduled", newRotating("/a", time.Time{}, 0), false},
+		{"Disabled", secret.Secret{Name: "/a", Rotation: secret.Rotation{Interval: time.Hour}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			due := CollectDue([]secret.Secret{test.input}, now)
+			assert.Equal(t, test.expect, len(due) == 1)
+		})
+	}
+}
+
+func TestNewRotator_Command(t *testing.T) {
+	rot := secret.Rotation{Rotator: secret.CommandRotator, Command: "db-url"}
+
+	_, err := NewRotator(rot, nil)
+	assert.True(t, errs.Is(err, errs.StateError), "%v", err)
+
+	fn, err := NewRotator(rot, map[string]string{"db-url": "echo next"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	val, err := fn(secret.Secret{Name: "/db/url"}, []byte("cur"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "next", string(val))
+
+	// Commands are never read from the secret itself.
+	_, err = NewRotator(secret.Rotation{Rotator: secret.CommandRotator, Command: "echo pwned"}, map[string]string{"echo pwned": "echo pwned"})
+	assert.True(t, errs.Is(err, errs.ArgError), "%v", err)
+}
@@ -23,10 +23,33 @@ func Search(s session.Session, orgId uuid.UUID, filter secret.Filter, opts ...pa
 		return
 	}
 
-	ret, err = s.Options().Secrets().ListSecrets(token, orgId, filter, page.BuildPage(opts...))
+	ret, _, err = s.Options().Secrets().ListSecrets(token, orgId, filter, page.BuildPage(opts...))
 	return
 }
 
+// Iterates all the secrets matching the filter, a page at a time.  Iteration
+// stops at the first error returned by fn.
+func SearchEach(s session.Session, orgId uuid.UUID, filter secret.Filter, fn func(secret.SecretSummary) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken(auth.WithOrgId(orgId))
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Secrets().ListSecrets(token, orgId, filter, p)
+		if err != nil {
+			return
+		}
+
+		for _, sec := range all {
+			if err = fn(sec); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 func RequireById(s session.Session, orgId, id uuid.UUID) (ret secret.SecretSummary, err error) {
 	ret, ok, err := LoadById(s, orgId, id)
 	if !ok {
@@ -67,10 +90,33 @@ func ListVersions(s session.Session, orgId, secretId uuid.UUID, opts ...page.Pag
 		return
 	}
 
-	ret, err = s.Options().Secrets().ListSecretVersions(token, orgId, secretId, page.BuildPage(opts...))
+	ret, _, err = s.Options().Secrets().ListSecretVersions(token, orgId, secretId, page.BuildPage(opts...))
 	return
 }
 
+// Iterates all the versions of a secret, latest first and a page at a time.
+// Iteration stops at the first error returned by fn.
+func EachVersion(s session.Session, orgId, secretId uuid.UUID, fn func(secret.Secret) error, opts ...page.PageOption) (err error) {
+	return page.Walk(page.BuildPage(opts...), func(p page.Page) (next page.Cursor, err error) {
+		token, err := s.FetchToken(auth.WithOrgId(orgId))
+		if err != nil {
+			return
+		}
+
+		all, next, err := s.Options().Secrets().ListSecretVersions(token, orgId, secretId, p)
+		if err != nil {
+			return
+		}
+
+		for _, sec := range all {
+			if err = fn(sec); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
 func LoadVersion(s session.Session, orgId, secretId uuid.UUID, version int) (ret secret.Secret, ok bool, err error) {
 	token, err := s.FetchToken(auth.WithOrgId(orgId))
 	if err != nil {
@@ -134,11 +180,21 @@ func Create(s session.Session, proto secret.Builder, data io.Reader, o ...func(*
 	}
 	opts := BuildStreamOptions(o...)
 
+	folders, err := ListAncestorFolders(s, init.OrgId, init.Name)
+	if err != nil {
+		return
+	}
+
 	policy, err := policies.CreatePolicy(s, init.OrgId, opts.Strength, policy.Sudo)
 	if err != nil {
 		return ret, err
 	}
 
+	// The members of every enclosing folder inherit access.
+	if err = AttachFolders(s, policy, folders...); err != nil {
+		return
+	}
+
 	ret, err = Write(s, proto.SetPolicy(policy.Id()), data, o...)
 	return
 }
@@ -202,7 +258,30 @@ func WriteProxy(s session.Session, memberKey crypto.PrivateKey, memberId uuid.UU
 	}
 	defer pass.Destroy()
 
-	salt, err := lock.Strength().GenSalt(crypto.Rand)
+	next, err = upload(s, pass, lock.Strength(), proto, data, o...)
+	if err != nil {
+		return
+	}
+
+	err = SaveSecret(s, next)
+	return
+}
+
+// Encrypts and uploads the data using the secret of the policy, returning
+// the next version of the secret.  The version is not saved.
+func upload(s session.Session, pass crypto.Bytes, strength crypto.Strength, proto secret.Builder, data io.Reader, o ...func(*StreamOptions)) (next secret.Secret, err error) {
+	cur, err := proto.Compile()
+	if err != nil {
+		return
+	}
+
+	// Blocks are never cleaned up, so fail before streaming any when the
+	// save would lose to a version written in the meantime.
+	if err = requireLatest(s, cur); err != nil {
+		return
+	}
+
+	salt, err := strength.GenSalt(crypto.Rand)
 	if err != nil {
 		return
 	}
@@ -210,7 +289,7 @@ func WriteProxy(s session.Session, memberKey crypto.PrivateKey, memberId uuid.UU
 	streamId := uuid.NewV1()
 
 	writer := NewBlockWriter(
-		crypto.Rand, cur.OrgId, streamId, salt, pass, lock.Strength().Cipher())
+		crypto.Rand, cur.OrgId, streamId, salt, pass, strength.Cipher())
 
 	token, err := s.FetchToken(auth.WithOrgId(cur.OrgId))
 	if err != nil {
@@ -228,7 +307,7 @@ func WriteProxy(s session.Session, memberKey crypto.PrivateKey, memberId uuid.UU
 	}
 	defer priv.Destroy()
 
-	sig, err := priv.Sign(crypto.Rand, lock.Strength().Hash(), hash)
+	sig, err := priv.Sign(crypto.Rand, strength.Hash(), hash)
 	if err != nil {
 		return
 	}
@@ -238,11 +317,26 @@ func WriteProxy(s session.Session, memberKey crypto.PrivateKey, memberId uuid.UU
 		SetAuthor(s.AccountId(), sig).
 		SetSalt(salt).
 		Compile()
+	return
+}
+
+// Returns an error unless the version is the next one to be written.
+func requireLatest(s session.Session, next secret.Secret) (err error) {
+	all, err := Search(s, next.OrgId,
+		secret.BuildFilter(
+			secret.FilterByIds(next.Id),
+			secret.FilterShowDeleted(true)),
+		page.Limit(1))
 	if err != nil {
 		return
 	}
 
-	err = SaveSecret(s, next)
+	switch {
+	case len(all) == 0 && next.Version > 0:
+		err = errors.Wrapf(secret.ErrNoSecret, "No such secret [%v]", next.Id)
+	case len(all) == 1 && all[0].Version != next.Version-1:
+		err = errors.Wrapf(errs.Conflict, "Secret [%v] has already been updated to version [%v]", next.Name, all[0].Version)
+	}
 	return
 }
 
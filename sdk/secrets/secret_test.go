@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cott-io/stash/http/server/httpsecret"
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite_Stale(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	var uploads int32
+	counter := func(h http.Handler) http.Handler {
+		return func(e env.Environment, req http.Request) http.Response {
+			if req.Route() == httpsecret.SaveBlocksRoute {
+				atomic.AddInt32(&uploads, 1)
+			}
+			return h(e, req)
+		}
+	}
+
+	server, err := httptest.StartDefaultServer(ctx, http.WithMiddleware(counter))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	err = session.Register(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	s, err := session.Authenticate(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithClient(server.Connect()))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	o, err := orgs.Purchase(s, "stale")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	first, err := Create(s, secret.NewSecret().SetOrg(o.Id).SetName("/a"), strings.NewReader("a"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	if _, err = Write(s, first.Update(), strings.NewReader("b")); !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	before := atomic.LoadInt32(&uploads)
+
+	_, err = Write(s, first.Update(), strings.NewReader("c"))
+	assert.True(t, errs.Is(err, errs.Conflict), "%v", err)
+	assert.Equal(t, before, atomic.LoadInt32(&uploads))
+
+	cur, err := RequireByName(s, o.Id, "/a")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if !assert.Nil(t, Read(s, cur.Secret, buf)) {
+		return
+	}
+	assert.Equal(t, "b", buf.String())
+}
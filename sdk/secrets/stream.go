@@ -80,7 +80,8 @@ type BlockReader interface {
 }
 
 // Downloads the block stream for the secret, decrypts it and writes to the dst.
-// Upon completion, a digest of the value is returned
+// Blocks are streamed if the client is a secret.BlockStreamer, and otherwise
+// loaded a page at a time. Upon completion, a digest of the value is returned
 func Download(client secret.Transport, t auth.SignedToken, cur secret.Secret, r BlockReader, dst io.Writer, o ...func(*StreamOptions)) (digest []byte, err error) {
 	opts := BuildStreamOptions(o...)
 
@@ -91,6 +92,20 @@ func Download(client secret.Transport, t auth.SignedToken, cur secret.Secret, r
 
 	done, blocks := make(chan error), make(chan []secret.Block)
 	go func() {
+		send := func(batch []secret.Block) error {
+			select {
+			case <-opts.Canceler:
+				return errs.CanceledError
+			case blocks <- batch:
+				return nil
+			}
+		}
+
+		if streamer, ok := client.(secret.BlockStreamer); ok {
+			done <- streamer.DownloadBlocks(t, cur.OrgId, cur.Id, cur.Version, opts.BatchSize, send)
+			return
+		}
+
 		for i := 0; i < cur.StreamSize; {
 			batch, err := client.LoadBlocks(t, cur.OrgId, cur.Id, cur.Version,
 				page.BuildPage(
@@ -101,11 +116,9 @@ func Download(client secret.Transport, t auth.SignedToken, cur secret.Secret, r
 				return
 			}
 
-			select {
-			case <-opts.Canceler:
-				done <- errs.CanceledError
+			if err := send(batch); err != nil {
+				done <- err
 				return
-			case blocks <- batch:
 			}
 
 			i += len(batch)
@@ -139,7 +152,9 @@ func Download(client secret.Transport, t auth.SignedToken, cur secret.Secret, r
 	return
 }
 
-// Streams the data from the input reader to the cloud.
+// Streams the data from the input reader to the cloud.  Blocks are sent on
+// a single stream if the client is a secret.BlockStreamer, and otherwise
+// saved a batch at a time.
 func Upload(client secret.Transport, token auth.SignedToken, w BlockWriter, data io.Reader, o ...func(*StreamOptions)) (digest []byte, num int, err error) {
 	opts := BuildStreamOptions(o...)
 
@@ -193,12 +208,30 @@ func Upload(client secret.Transport, token auth.SignedToken, w BlockWriter, data
 		}
 	}()
 
+	save := func(batch ...secret.Block) error {
+		return client.SaveBlocks(token, batch...)
+	}
+
+	// Streamed batches are saved in the order sent.  Failures may be
+	// reported by later sends, or once the stream is closed.
+	if streamer, ok := client.(secret.BlockStreamer); ok {
+		var stream secret.BlockUpload
+		if stream, err = streamer.UploadBlocks(token); err != nil {
+			return
+		}
+		defer func() {
+			err = errs.Or(err, stream.Close())
+		}()
+
+		save = stream.Send
+	}
+
 	upload := func(batch []secret.Block) error {
 		defer func() {
 			num += len(batch)
 		}()
 
-		return client.SaveBlocks(token, batch...)
+		return save(batch...)
 	}
 
 	var batch []secret.Block
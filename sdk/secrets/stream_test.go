@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cott-io/stash/grpc/grpcserver"
+	"github.com/cott-io/stash/http/server/httpsecret"
+	"github.com/cott-io/stash/http/server/httptest"
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/env"
+	"github.com/cott-io/stash/lang/errs"
+	http "github.com/cott-io/stash/lang/http/server"
+	"github.com/cott-io/stash/lang/net"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/orgs"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// Blocks are streamed over grpc, and saved by the http handlers.
+func TestStream_Grpc(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Info)
+	defer ctx.Close()
+
+	var uploads int32
+	counter := func(h http.Handler) http.Handler {
+		return func(e env.Environment, req http.Request) http.Response {
+			if req.Route() == httpsecret.SaveBlocksRoute {
+				atomic.AddInt32(&uploads, 1)
+			}
+			return h(e, req)
+		}
+	}
+
+	server, err := httptest.StartDefaultServer(ctx, http.WithMiddleware(counter))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+	defer server.Close()
+
+	listener, err := net.ListenTCP4("localhost:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	grpc := grpcserver.Serve(ctx, server.Handler(), listener)
+	defer grpc.Close()
+
+	addr := listener.Address().String()
+
+	key, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	err = session.Register(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithGrpc(addr, nil))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	s, err := session.Authenticate(ctx,
+		auth.ByKey(key.Public()),
+		auth.WithSignature(key, crypto.Moderate),
+		session.WithGrpc(addr, nil))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	o, err := orgs.Purchase(s, "grpc")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+		return
+	}
+
+	_, ok := s.Options().Secrets().(secret.BlockStreamer)
+	assert.True(t, ok)
+
+	// The last page of blocks is short, or else empty.
+	for _, size := range []int{100, 96} {
+		data := strings.Repeat("a", size)
+
+		before := atomic.LoadInt32(&uploads)
+
+		sec, err := Create(s, secret.NewSecret().SetOrg(o.Id).SetName(fmt.Sprintf("/%v", size)), strings.NewReader(data),
+			WithBlockSize(16), WithBatchSize(2))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		blocks := (size + 15) / 16
+		assert.Equal(t, blocks, sec.StreamSize)
+		assert.Equal(t, int32((blocks+1)/2), atomic.LoadInt32(&uploads)-before)
+
+		buf := &bytes.Buffer{}
+		if !assert.Nil(t, Read(s, sec, buf, WithBatchSize(2))) {
+			return
+		}
+		assert.Equal(t, data, buf.String())
+	}
+
+	t.Run("Stale", func(t *testing.T) {
+		first, err := Create(s, secret.NewSecret().SetOrg(o.Id).SetName("/stale"), strings.NewReader("a"))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		if _, err = Write(s, first.Update(), strings.NewReader("b")); !assert.Nil(t, err) {
+			return
+		}
+
+		_, err = Write(s, first.Update(), strings.NewReader("c"))
+		assert.True(t, errs.Is(err, errs.Conflict), "%v", err)
+	})
+}
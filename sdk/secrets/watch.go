@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"time"
+
+	"github.com/cott-io/stash/lang/errs"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/sdk/session"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+type WatchOptions struct {
+	After    int
+	Wait     time.Duration
+	Canceler <-chan struct{}
+}
+
+func WatchAfter(seq int) func(*WatchOptions) {
+	return func(w *WatchOptions) {
+		w.After = seq
+	}
+}
+
+func WatchWait(wait time.Duration) func(*WatchOptions) {
+	return func(w *WatchOptions) {
+		w.Wait = wait
+	}
+}
+
+func WatchCanceler(cancel <-chan struct{}) func(*WatchOptions) {
+	return func(w *WatchOptions) {
+		w.Canceler = cancel
+	}
+}
+
+func BuildWatchOptions(o ...func(*WatchOptions)) (ret WatchOptions) {
+	ret = WatchOptions{0, 30 * time.Second, nil}
+	for _, fn := range o {
+		fn(&ret)
+	}
+	return
+}
+
+// A watcher iterates the change feed of an organization.  Events
+// are returned in the order they were recorded.
+type Watcher struct {
+	session session.Session
+	orgId   uuid.UUID
+	prefix  string
+	opts    WatchOptions
+	buf     []secret.Event
+	next    int
+}
+
+// Returns a watcher over all the events of secrets under the given prefix.
+func Watch(s session.Session, orgId uuid.UUID, prefix string, o ...func(*WatchOptions)) *Watcher {
+	return &Watcher{session: s, orgId: orgId, prefix: prefix, opts: BuildWatchOptions(o...)}
+}
+
+// Returns the sequence number of the last event returned by the watcher.
+func (w *Watcher) Seq() int {
+	return w.opts.After
+}
+
+// Blocks until the next event is available or the watcher's canceler is closed.
+func (w *Watcher) Next() (ret secret.Event, err error) {
+	for len(w.buf) == 0 {
+		select {
+		default:
+		case <-w.opts.Canceler:
+			err = errors.Wrapf(errs.CanceledError, "Watch canceled")
+			return
+		}
+
+		var token auth.SignedToken
+		token, err = w.session.FetchToken(auth.WithOrgId(w.orgId))
+		if err != nil {
+			return
+		}
+
+		w.buf, w.next, err = w.session.Options().Secrets().ListEvents(token, w.orgId, w.prefix, w.opts.After, w.opts.Wait)
+		if err != nil {
+			return
+		}
+		if len(w.buf) == 0 {
+			w.opts.After = w.next
+		}
+	}
+
+	ret, w.buf = w.buf[0], w.buf[1:]
+	w.opts.After = ret.Seq
+	if len(w.buf) == 0 {
+		w.opts.After = w.next
+	}
+	return
+}
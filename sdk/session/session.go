@@ -1,13 +1,19 @@
 package session
 
 import (
+	"crypto/tls"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/cott-io/stash/grpc/grpcclient"
+	"github.com/cott-io/stash/http/client/httpaccess"
 	"github.com/cott-io/stash/http/client/httpaccount"
+	"github.com/cott-io/stash/http/client/httpaudit"
 	"github.com/cott-io/stash/http/client/httporg"
 	"github.com/cott-io/stash/http/client/httppolicy"
 	"github.com/cott-io/stash/http/client/httpsecret"
+	"github.com/cott-io/stash/http/client/httpwebhook"
 	"github.com/cott-io/stash/lang/config"
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/crypto"
@@ -15,13 +21,17 @@ import (
 	"github.com/cott-io/stash/lang/env"
 	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/http/client"
+	"github.com/cott-io/stash/lang/net"
 	"github.com/cott-io/stash/lang/path"
 	"github.com/cott-io/stash/lang/secret"
+	"github.com/cott-io/stash/libs/access"
 	"github.com/cott-io/stash/libs/account"
+	"github.com/cott-io/stash/libs/audit"
 	"github.com/cott-io/stash/libs/auth"
 	"github.com/cott-io/stash/libs/org"
 	"github.com/cott-io/stash/libs/policy"
 	secrt "github.com/cott-io/stash/libs/secret"
+	"github.com/cott-io/stash/libs/webhook"
 	"github.com/denisbrodbeck/machineid"
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
@@ -49,18 +59,85 @@ func getSessionAddr(c config.Config) (ret string, err error) {
 	return
 }
 
+// The address of the server's grpc transport.  If set, sessions use grpc
+// in place of http.
+func getSessionGrpcAddr(c config.Config) (ret string, err error) {
+	err = c.GetOrDefault("stash.session.grpc_addr", config.String, &ret, "")
+	return
+}
+
 func getSessionStrength(c config.Config) (ret crypto.Strength, err error) {
 	err = c.GetOrDefault("stash.session.strength", config.Strength, &ret, DefaultStrength)
 	return
 }
 
+// Returns the tls options of the session.  Pins are given as a comma
+// separated list.
+func getSessionTLS(c config.Config) (ret net.ClientTLS, err error) {
+	var pins string
+	if err = c.GetOrDefault("stash.session.ca", config.String, &ret.CAFile, ""); err != nil {
+		return
+	}
+	if err = c.GetOrDefault("stash.session.pins", config.String, &pins, ""); err != nil {
+		return
+	}
+	if err = c.GetOrDefault("stash.session.cert", config.String, &ret.CertFile, ""); err != nil {
+		return
+	}
+	if err = c.GetOrDefault("stash.session.cert_key", config.String, &ret.KeyFile, ""); err != nil {
+		return
+	}
+
+	for _, pin := range strings.Split(pins, ",") {
+		if pin = strings.TrimSpace(pin); pin != "" {
+			ret.Pins = append(ret.Pins, pin)
+		}
+	}
+
+	for _, file := range []*string{&ret.CAFile, &ret.CertFile, &ret.KeyFile} {
+		if *file != "" {
+			if *file, err = path.Expand(*file); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
 func getSessionClient(c config.Config) (ret client.Client, err error) {
 	addr, err := getSessionAddr(c)
 	if err != nil {
 		return
 	}
 
-	ret = client.NewDefaultClient(addr)
+	grpcAddr, err := getSessionGrpcAddr(c)
+	if err != nil {
+		return
+	}
+
+	opts, err := getSessionTLS(c)
+	if err != nil {
+		return
+	}
+
+	var conf *tls.Config
+	if !opts.Empty() {
+		if conf, err = opts.Config(); err != nil {
+			return
+		}
+	}
+
+	if grpcAddr != "" {
+		ret, err = newGrpcClient(grpcAddr, conf)
+		return
+	}
+
+	if conf == nil {
+		ret = client.NewDefaultClient(addr)
+		return
+	}
+
+	ret = client.NewDefaultClient(addr, client.WithTLS(conf))
 	return
 }
 
@@ -98,8 +175,26 @@ func (o Options) Policies() policy.Transport {
 	return httppolicy.NewClient(o.Client, enc.DefaultRegistry)
 }
 
-func (o Options) Secrets() secrt.Transport {
-	return httpsecret.NewClient(o.Client, enc.DefaultRegistry)
+// Returns the secrets transport.  Clients that stream blocks (e.g. those
+// of grpc) stream them in place of paging them.
+func (o Options) Secrets() (ret secrt.Transport) {
+	ret = httpsecret.NewClient(o.Client, enc.DefaultRegistry)
+	if streamer, ok := o.Client.(secrt.BlockStreamer); ok {
+		ret = secrt.WithBlockStreamer(ret, streamer)
+	}
+	return
+}
+
+func (o Options) Webhooks() webhook.Transport {
+	return httpwebhook.NewClient(o.Client, enc.DefaultRegistry)
+}
+
+func (o Options) Audit() audit.Transport {
+	return httpaudit.NewClient(o.Client, enc.DefaultRegistry)
+}
+
+func (o Options) Access() access.Transport {
+	return httpaccess.NewClient(o.Client, enc.DefaultRegistry)
 }
 
 func buildOptions(opts ...Option) (ret Options, err error) {
@@ -156,6 +251,22 @@ func WithClient(client client.Client) Option {
 	}
 }
 
+// Uses the grpc transport of the server at the address.  Connections
+// are encrypted if given a tls config.
+func WithGrpc(addr string, conf *tls.Config) Option {
+	return func(s *Options) (err error) {
+		s.Client, err = newGrpcClient(addr, conf)
+		return
+	}
+}
+
+func newGrpcClient(addr string, conf *tls.Config) (client.Client, error) {
+	if conf == nil {
+		return grpcclient.NewClient(addr)
+	}
+	return grpcclient.NewClient(addr, grpcclient.WithTLS(conf))
+}
+
 type Session interface {
 	env.Environment
 
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
+	"github.com/cott-io/stash/sdk/session"
+	uuid "github.com/satori/go.uuid"
+)
+
+func Create(s session.Session, orgId uuid.UUID, url string, method webhook.SignMethod, events ...webhook.EventType) (ret webhook.Webhook, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Webhooks().CreateWebhook(token, orgId, url, method, events)
+	return
+}
+
+func List(s session.Session, orgId uuid.UUID, opts ...page.PageOption) (ret []webhook.Webhook, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Webhooks().ListWebhooks(token, orgId, page.BuildPage(opts...))
+	return
+}
+
+func Delete(s session.Session, orgId, hookId uuid.UUID) (err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Webhooks().DeleteWebhook(token, orgId, hookId)
+	return
+}
+
+func Test(s session.Session, orgId, hookId uuid.UUID) (ret webhook.Delivery, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Webhooks().TestWebhook(token, orgId, hookId)
+	return
+}
+
+func ListDeliveries(s session.Session, orgId, hookId uuid.UUID, opts ...page.PageOption) (ret []webhook.Delivery, err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	ret, err = s.Options().Webhooks().ListDeliveries(token, orgId, hookId, page.BuildPage(opts...))
+	return
+}
+
+func Redeliver(s session.Session, orgId, deliveryId uuid.UUID) (err error) {
+	token, err := s.FetchToken(auth.WithOrgId(orgId))
+	if err != nil {
+		return
+	}
+
+	err = s.Options().Webhooks().Redeliver(token, orgId, deliveryId)
+	return
+}
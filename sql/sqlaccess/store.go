@@ -0,0 +1,391 @@
+package sqlaccess
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	SchemaRequest = sql.NewSchema("access_request", 0).
+		WithStruct(access.Request{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_request_by_id", "org_id", "id", "version"),
+			sql.NewIndex("access_request_by_status", "status", "expires")).
+		Build()
+)
+
+var (
+	SchemaApprovers = sql.NewSchema("access_approvers", 0).
+		WithStruct(access.Approvers{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_approvers_by_policy", "org_id", "policy_id", "version")).
+		Build()
+)
+
+var (
+	SchemaEscrow = sql.NewSchema("access_escrow", 0).
+		WithStruct(access.Escrow{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_escrow_by_policy", "org_id", "policy_id", "version")).
+		Build()
+)
+
+var (
+	SchemaUnlock = sql.NewSchema("access_unlock", 0).
+		WithStruct(access.Unlock{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_unlock_by_id", "org_id", "id", "version")).
+		Build()
+)
+
+var (
+	SchemaCampaign = sql.NewSchema("access_campaign", 0).
+		WithStruct(access.Campaign{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_campaign_by_id", "org_id", "id", "version"),
+			sql.NewIndex("access_campaign_by_status", "status", "deadline")).
+		Build()
+)
+
+var (
+	SchemaReviewEntry = sql.NewSchema("access_review_entry", 0).
+		WithStruct(access.ReviewEntry{}).
+		WithIndices(
+			sql.NewUniqueIndex("access_review_entry_by_member", "org_id", "campaign_id", "policy_id", "member_id", "version")).
+		Build()
+)
+
+type SqlStore struct {
+	db sql.Driver
+}
+
+func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (access.Storage, error) {
+	if err := sql.InitSchemas(db, schemas, SchemaRequest, SchemaApprovers, SchemaEscrow, SchemaUnlock, SchemaCampaign, SchemaReviewEntry); err != nil {
+		return nil, err
+	}
+	return &SqlStore{db}, nil
+}
+
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
+func (s *SqlStore) SaveRequest(r access.Request) (err error) {
+	err = s.db.Do(sql.Exec(SchemaRequest.Insert(r)))
+	return
+}
+
+func (s *SqlStore) LoadRequest(orgId, requestId uuid.UUID) (ret access.Request, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaRequest.SelectAs("r").
+				Where("r.org_id = ?", orgId).
+				Where("r.id = ?", requestId).
+				Where(latestRequest("r")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListRequests(orgId uuid.UUID, filter access.Filter, page page.Page) (ret []access.Request, err error) {
+	query := SchemaRequest.SelectAs("r").
+		Where("r.org_id = ?", orgId).
+		Where(latestRequest("r"))
+	if filter.Status != nil {
+		query = query.Where("r.status = ?", string(*filter.Status))
+	}
+	if filter.RequesterId != nil {
+		query = query.Where("r.requester_id = ?", *filter.RequesterId)
+	}
+	if filter.PolicyIds != nil {
+		if len(*filter.PolicyIds) == 0 {
+			return
+		}
+		query = query.WhereIn("r.policy_id in (%v)", sql.InUUIDs(*filter.PolicyIds...)...)
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query.OrderBy("r.created desc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) ListExpiredRequests(now time.Time, page page.Page) (ret []access.Request, err error) {
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaRequest.SelectAs("r").
+				Where("r.status = ?", string(access.Approved)).
+				Where("r.expires <= ?", now.UTC()).
+				Where(latestRequest("r")).
+				OrderBy("r.expires asc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) SaveApprovers(a access.Approvers) (err error) {
+	err = s.db.Do(sql.Exec(SchemaApprovers.Insert(a)))
+	return
+}
+
+func (s *SqlStore) LoadApprovers(orgId, policyId uuid.UUID) (ret access.Approvers, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaApprovers.SelectAs("a").
+				Where("a.org_id = ?", orgId).
+				Where("a.policy_id = ?", policyId).
+				Where(latestApprovers("a")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) SaveEscrow(e access.Escrow) (err error) {
+	err = s.db.Do(sql.Exec(SchemaEscrow.Insert(e)))
+	return
+}
+
+func (s *SqlStore) LoadEscrow(orgId, policyId uuid.UUID) (ret access.Escrow, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaEscrow.SelectAs("e").
+				Where("e.org_id = ?", orgId).
+				Where("e.policy_id = ?", policyId).
+				Where(latestEscrow("e")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) SaveUnlock(u access.Unlock) (err error) {
+	err = s.db.Do(sql.Exec(SchemaUnlock.Insert(u)))
+	return
+}
+
+func (s *SqlStore) LoadUnlock(orgId, unlockId uuid.UUID) (ret access.Unlock, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaUnlock.SelectAs("u").
+				Where("u.org_id = ?", orgId).
+				Where("u.id = ?", unlockId).
+				Where(latestUnlock("u")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListUnlocks(orgId uuid.UUID, filter access.Filter, page page.Page) (ret []access.Unlock, err error) {
+	query := SchemaUnlock.SelectAs("u").
+		Where("u.org_id = ?", orgId).
+		Where(latestUnlock("u"))
+	if filter.Status != nil {
+		query = query.Where("u.status = ?", string(*filter.Status))
+	}
+	if filter.RequesterId != nil {
+		query = query.Where("u.requester_id = ?", *filter.RequesterId)
+	}
+	if filter.PolicyIds != nil {
+		if len(*filter.PolicyIds) == 0 {
+			return
+		}
+		query = query.WhereIn("u.policy_id in (%v)", sql.InUUIDs(*filter.PolicyIds...)...)
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query.OrderBy("u.created desc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) SaveCampaign(c access.Campaign) (err error) {
+	err = s.db.Do(sql.Exec(SchemaCampaign.Insert(c)))
+	return
+}
+
+func (s *SqlStore) LoadCampaign(orgId, campaignId uuid.UUID) (ret access.Campaign, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaCampaign.SelectAs("c").
+				Where("c.org_id = ?", orgId).
+				Where("c.id = ?", campaignId).
+				Where(latestCampaign("c")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListCampaigns(orgId uuid.UUID, page page.Page) (ret []access.Campaign, err error) {
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaCampaign.SelectAs("c").
+				Where("c.org_id = ?", orgId).
+				Where(latestCampaign("c")).
+				OrderBy("c.created desc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) ListDueCampaigns(now time.Time, page page.Page) (ret []access.Campaign, err error) {
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaCampaign.SelectAs("c").
+				Where("c.status = ?", string(access.Open)).
+				Where("c.deadline <= ?", now.UTC()).
+				Where(latestCampaign("c")).
+				OrderBy("c.deadline asc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) SaveReviewEntries(entries ...access.ReviewEntry) (err error) {
+	if len(entries) == 0 {
+		return
+	}
+
+	inserts := make([]sql.Query, 0, len(entries))
+	for _, e := range entries {
+		inserts = append(inserts, SchemaReviewEntry.Insert(e))
+	}
+
+	err = s.db.Do(sql.Exec(inserts...))
+	return
+}
+
+func (s *SqlStore) LoadReviewEntry(orgId, campaignId, policyId, memberId uuid.UUID) (ret access.ReviewEntry, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaReviewEntry.SelectAs("e").
+				Where("e.org_id = ?", orgId).
+				Where("e.campaign_id = ?", campaignId).
+				Where("e.policy_id = ?", policyId).
+				Where("e.member_id = ?", memberId).
+				Where(latestReviewEntry("e")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListReviewEntries(orgId, campaignId uuid.UUID, filter access.ReviewFilter, page page.Page) (ret []access.ReviewEntry, err error) {
+	query := SchemaReviewEntry.SelectAs("e").
+		Where("e.org_id = ?", orgId).
+		Where("e.campaign_id = ?", campaignId).
+		Where(latestReviewEntry("e"))
+	if filter.ReviewerId != nil {
+		query = query.Where("e.reviewer_ids like ?", "%"+filter.ReviewerId.String()+"%")
+	}
+	if filter.Decision != nil {
+		query = query.Where("e.decision = ?", string(*filter.Decision))
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query.OrderBy("e.item asc, e.member_id asc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func latestRequest(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_request as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestApprovers(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_approvers as o
+			where
+				o.org_id = %v.org_id
+				and o.policy_id = %v.policy_id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestEscrow(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_escrow as o
+			where
+				o.org_id = %v.org_id
+				and o.policy_id = %v.policy_id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestUnlock(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_unlock as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestCampaign(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_campaign as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestReviewEntry(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				access_review_entry as o
+			where
+				o.org_id = %v.org_id
+				and o.campaign_id = %v.campaign_id
+				and o.policy_id = %v.policy_id
+				and o.member_id = %v.member_id
+				and o.version > %v.version
+		)`, alias, alias, alias, alias, alias)
+}
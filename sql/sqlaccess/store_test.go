@@ -0,0 +1,439 @@
+package sqlaccess
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/access"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/policy"
+	"github.com/cott-io/stash/sql/sqlaudit"
+	"github.com/cott-io/stash/sql/sqlpolicy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	orgId, policyId, userId := uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	req, err := access.NewRequest(orgId, policyId, userId, "secret://db", time.Hour, "incident", policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("SaveRequest", func(t *testing.T) {
+		assert.Nil(t, store.SaveRequest(req))
+	})
+
+	t.Run("LoadRequest", func(t *testing.T) {
+		act, ok, err := store.LoadRequest(orgId, req.Id)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, access.Pending, act.Status)
+		assert.Equal(t, time.Hour, act.Duration)
+		assert.True(t, act.Actions.Equals(req.Actions))
+	})
+
+	t.Run("ListRequests", func(t *testing.T) {
+		act, err := store.ListRequests(orgId, access.BuildFilter(access.FilterByStatus(access.Pending)), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(act)) {
+			return
+		}
+		assert.Equal(t, req.Id, act[0].Id)
+
+		act, err = store.ListRequests(orgId, access.BuildFilter(access.FilterByPolicies()), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, act)
+	})
+
+	t.Run("ListExpiredRequests", func(t *testing.T) {
+		member := policy.PolicyMember{Conditions: policy.Conditions{NotAfter: time.Now().UTC().Add(time.Hour)}}
+
+		approved, err := req.Approve(uuid.NewV1(), "ok", member)
+		if !assert.Nil(t, err) || !assert.Nil(t, store.SaveRequest(approved)) {
+			return
+		}
+
+		act, err := store.ListExpiredRequests(time.Now().UTC(), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, act)
+
+		act, err = store.ListExpiredRequests(time.Now().UTC().Add(2*time.Hour), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(act)) {
+			return
+		}
+		assert.Equal(t, access.Approved, act[0].Status)
+
+		assert.Nil(t, store.SaveRequest(approved.Expire()))
+		act, err = store.ListExpiredRequests(time.Now().UTC().Add(2*time.Hour), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, act)
+	})
+
+	t.Run("Approvers", func(t *testing.T) {
+		groupId := uuid.NewV1()
+
+		approvers := access.NewApprovers(orgId, policyId, groupId, 8*time.Hour)
+		if !assert.Nil(t, store.SaveApprovers(approvers)) {
+			return
+		}
+		if !assert.Nil(t, store.SaveApprovers(approvers.Update(func(a *access.Approvers) {
+			a.MaxDuration = 4 * time.Hour
+		}))) {
+			return
+		}
+
+		act, ok, err := store.LoadApprovers(orgId, policyId)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, groupId, act.GroupId)
+		assert.Equal(t, 4*time.Hour, act.Max())
+	})
+}
+
+func TestAccessReaper(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	schemas := sql.NewSchemaRegistry("iron")
+
+	store, err := NewSqlStore(db, schemas)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	policies, err := sqlpolicy.NewSqlStore(db, schemas)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	log, err := sqlaudit.NewSqlStore(db, schemas)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	s := crypto.Moderate
+
+	orgId, ownerId, userId := uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	ownerKey, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	userKey, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, ownerId, ownerKey.Public(), policy.UserType, s, policy.Sudo)
+	if !assert.Nil(t, err) || !assert.Nil(t, policies.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+
+	req, err := access.NewRequest(orgId, core.Id(), userId, "secret://db", time.Hour, "incident", policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	member, err := core.AddMember(crypto.Rand, ownerKey, userId, policy.UserType, userKey.Public(), policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+	member.Conditions = policy.Conditions{NotAfter: time.Now().UTC().Add(req.Duration)}
+
+	if !assert.Nil(t, req.VerifyGrant(time.Now().UTC(), member)) {
+		return
+	}
+
+	approved, err := req.Approve(ownerId, "", member)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, policies.SavePolicyMember(member)) || !assert.Nil(t, store.SaveRequest(approved)) {
+		return
+	}
+
+	reaper := access.NewReaper(ctx, store, policies, log, ownerKey, time.Hour)
+	defer reaper.Close()
+
+	num, err := reaper.Reap(time.Now().UTC())
+	if !assert.Nil(t, err) || !assert.Equal(t, 0, num) {
+		return
+	}
+
+	num, err = reaper.Reap(time.Now().UTC().Add(2 * time.Hour))
+	if !assert.Nil(t, err) || !assert.Equal(t, 1, num) {
+		return
+	}
+
+	_, ok, err := policies.LoadPolicyMember(orgId, core.Id(), userId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, ok)
+
+	act, ok, err := store.LoadRequest(orgId, req.Id)
+	if !assert.Nil(t, err) || !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, access.Expired, act.Status)
+
+	num, err = reaper.Reap(time.Now().UTC().Add(2 * time.Hour))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 0, num)
+}
+
+func TestBreakGlassStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	orgId, requesterId := uuid.NewV1(), uuid.NewV1()
+	custodians := []uuid.UUID{uuid.NewV1(), uuid.NewV1(), uuid.NewV1()}
+
+	var custodies []access.Custody
+	for _, id := range custodians {
+		custodies = append(custodies, access.Custody{CustodianId: id})
+	}
+
+	escrow, err := access.NewEscrow(policy.Policy{OrgId: orgId, Id: uuid.NewV1()}, 2,
+		access.DefaultUnlockWindow, access.DefaultEmergencyGrant, custodies, policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("Escrow", func(t *testing.T) {
+		if !assert.Nil(t, store.SaveEscrow(escrow)) {
+			return
+		}
+
+		act, ok, err := store.LoadEscrow(orgId, escrow.PolicyId)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 2, act.Quorum)
+		assert.Equal(t, custodians, act.CustodianIds())
+		assert.True(t, act.Actions.Equals(escrow.Actions))
+	})
+
+	unlock, err := access.NewUnlock(escrow, requesterId, "secret://db", "outage")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("Unlock", func(t *testing.T) {
+		if !assert.Nil(t, store.SaveUnlock(unlock)) {
+			return
+		}
+
+		now := time.Now().UTC()
+		for _, id := range custodians[:2] {
+			next, err := unlock.Submit(now, id, policy.MemberSecret{})
+			if !assert.Nil(t, err) || !assert.Nil(t, store.SaveUnlock(next)) {
+				return
+			}
+			unlock = next
+		}
+
+		_, err := unlock.Submit(now, custodians[0], policy.MemberSecret{})
+		assert.NotNil(t, err)
+
+		act, ok, err := store.LoadUnlock(orgId, unlock.Id)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 2, len(act.Shares))
+		assert.True(t, act.Ready(now))
+		assert.False(t, act.Ready(now.Add(2*access.DefaultUnlockWindow)))
+
+		claimed, err := act.Claim(now)
+		if !assert.Nil(t, err) || !assert.Nil(t, store.SaveUnlock(claimed)) {
+			return
+		}
+
+		_, err = claimed.Claim(now)
+		assert.NotNil(t, err)
+
+		all, err := store.ListUnlocks(orgId, access.BuildFilter(access.FilterByRequester(requesterId)), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(all)) {
+			return
+		}
+		assert.Equal(t, access.Claimed, all[0].Status)
+	})
+}
+
+func TestReviewStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	schemas := sql.NewSchemaRegistry("iron")
+
+	store, err := NewSqlStore(db, schemas)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	policies, err := sqlpolicy.NewSqlStore(db, schemas)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	s := crypto.Moderate
+
+	orgId, ownerId, userId := uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	ownerKey, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	userKey, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, ownerId, ownerKey.Public(), policy.UserType, s, policy.Sudo)
+	if !assert.Nil(t, err) || !assert.Nil(t, policies.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+
+	member, err := core.AddMember(crypto.Rand, ownerKey, userId, policy.UserType, userKey.Public(), policy.View)
+	if !assert.Nil(t, err) || !assert.Nil(t, policies.SavePolicyMember(member)) {
+		return
+	}
+
+	scope := access.Scope{PolicyId: core.Id(), Item: "secret://db"}
+
+	campaign, err := access.NewCampaign(orgId, ownerId, "q3", time.Now().UTC().Add(time.Hour), scope)
+	if !assert.Nil(t, err) || !assert.Nil(t, store.SaveCampaign(campaign)) {
+		return
+	}
+
+	owners := []uuid.UUID{ownerId}
+	if !assert.Nil(t, store.SaveReviewEntries(
+		access.NewReviewEntry(campaign, scope, core.CoreMember, core.CoreMember.Actions, owners),
+		access.NewReviewEntry(campaign, scope, member, member.Actions, owners))) {
+		return
+	}
+
+	t.Run("Entries", func(t *testing.T) {
+		mine, err := store.ListReviewEntries(orgId, campaign.Id,
+			access.BuildReviewFilter(access.FilterByReviewer(ownerId)), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 2, len(mine)) {
+			return
+		}
+
+		none, err := store.ListReviewEntries(orgId, campaign.Id,
+			access.BuildReviewFilter(access.FilterByReviewer(userId)), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, none)
+
+		entry, ok, err := store.LoadReviewEntry(orgId, campaign.Id, core.Id(), ownerId)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+
+		_, err = entry.Decide(userId, access.Keep, "")
+		assert.NotNil(t, err)
+
+		kept, err := entry.Decide(ownerId, access.Keep, "still the owner")
+		if !assert.Nil(t, err) || !assert.Nil(t, store.SaveReviewEntries(kept)) {
+			return
+		}
+
+		_, err = kept.Decide(ownerId, access.Revoke, "")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		due, err := store.ListDueCampaigns(time.Now().UTC(), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Empty(t, due)
+
+		due, err = store.ListDueCampaigns(time.Now().UTC().Add(2*time.Hour), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(due)) {
+			return
+		}
+
+		closed, err := access.CloseCampaign(store, policies, ownerKey, due[0], time.Now().UTC())
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, ok, err := policies.LoadPolicyMember(orgId, core.Id(), userId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.False(t, ok)
+
+		act, ok, err := store.LoadCampaign(orgId, campaign.Id)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, access.Closed, act.Status)
+		assert.Nil(t, act.VerifyReport(ownerKey.Public()))
+		assert.NotNil(t, act.VerifyReport(userKey.Public()))
+
+		report, err := act.DecodeReport()
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, report.Kept)
+		assert.Equal(t, 1, report.Lapsed)
+
+		_, err = access.CloseCampaign(store, policies, ownerKey, closed, time.Now().UTC())
+		assert.NotNil(t, err)
+	})
+}
@@ -3,6 +3,7 @@ package sqlaccount
 import (
 	"fmt"
 
+	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/libs/account"
 	"github.com/cott-io/stash/libs/auth"
@@ -67,6 +68,11 @@ func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (account.Storage, er
 	return &SqlStore{db}, nil
 }
 
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
 func (s *SqlStore) CreateAccount(id account.Identity, login account.Login, secret account.Secret, shard account.LoginShard, settings account.Settings) (err error) {
 	return s.db.Do(
 		sql.ExpectNone(
@@ -140,9 +146,10 @@ func (s *SqlStore) ListIdentities(accountId uuid.UUID, page page.Page) (ret []ac
 		sql.QueryPage(
 			SchemaIdentity.SelectAs("i").
 				Where("i.account_id = ?", accountId).
-				Where(latestIdentity("i")).
-				OrderBy("i.uri desc"),
+				Where(latestIdentity("i")),
 			sql.Slice(&ret, sql.Struct),
+			sql.Keyset("i.uri desc"),
+			sql.After(page.After...),
 			sql.LimitPtr(page.Limit),
 			sql.OffsetPtr(page.Offset)))
 	return
@@ -0,0 +1,69 @@
+package sqlaudit
+
+import (
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	SchemaEvent = sql.NewSchema("audit_event", 0).
+		WithStruct(audit.Event{}).
+		WithIndices(
+			sql.NewUniqueIndex("audit_event_by_id", "org_id", "id"),
+			sql.NewIndex("audit_event_by_created", "org_id", "created"),
+			sql.NewIndex("audit_event_by_actor", "org_id", "actor_id", "created")).
+		Build()
+)
+
+type SqlStore struct {
+	db sql.Driver
+}
+
+func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (audit.Storage, error) {
+	if err := sql.InitSchemas(db, schemas, SchemaEvent); err != nil {
+		return nil, err
+	}
+	return &SqlStore{db}, nil
+}
+
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
+func (s *SqlStore) SaveEvents(events ...audit.Event) error {
+	var inserts []sql.Query
+	for _, e := range events {
+		inserts = append(inserts, SchemaEvent.Insert(e))
+	}
+	return s.db.Do(sql.Exec(inserts...))
+}
+
+func (s *SqlStore) ListEvents(orgId uuid.UUID, filter audit.Filter, page page.Page) (ret []audit.Event, err error) {
+	query := SchemaEvent.SelectAs("e").
+		Where("e.org_id = ?", orgId).
+		OrderBy("e.created desc")
+	if filter.ActorId != nil {
+		query = query.Where("e.actor_id = ?", *filter.ActorId)
+	}
+	if filter.Target != nil {
+		query = query.Where(`e.target like ? escape '\'`, sql.EscapeLike(*filter.Target)+"%")
+	}
+	if filter.Action != nil {
+		query = query.Where("e.action = ?", *filter.Action)
+	}
+	if filter.Since != nil {
+		query = query.Where("e.created >= ?", filter.Since.UTC())
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query,
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
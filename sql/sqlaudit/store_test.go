@@ -0,0 +1,91 @@
+package sqlaudit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/audit"
+	"github.com/cott-io/stash/libs/page"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	orgId, alice, bob := uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	old := audit.NewEvent(orgId, alice, audit.Login, audit.AccountTarget(alice),
+		audit.WithToken("device-1", "key://abc"),
+		audit.WithRemote("10.0.0.1:5000"))
+	old.Created = old.Created.Add(-time.Hour)
+
+	read := audit.NewEvent(orgId, alice, audit.SecretDownload, "secret:///prod/db")
+	denied := audit.NewEvent(orgId, bob, audit.SecretRead, "secret:///prod/api",
+		audit.WithResult(audit.Denied, "Unauthorized"))
+	other := audit.NewEvent(uuid.NewV1(), bob, audit.Login, audit.AccountTarget(bob))
+
+	t.Run("SaveEvents", func(t *testing.T) {
+		assert.Nil(t, store.SaveEvents(old, read, denied, other))
+	})
+
+	t.Run("ListEvents", func(t *testing.T) {
+		events, err := store.ListEvents(orgId, audit.BuildFilter(), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 3, len(events)) {
+			return
+		}
+		assert.Equal(t, old.Id, events[2].Id)
+		assert.Equal(t, old.Device, events[2].Device)
+		assert.Equal(t, old.Login, events[2].Login)
+		assert.Equal(t, old.Remote, events[2].Remote)
+	})
+
+	t.Run("ListEvents_Actor", func(t *testing.T) {
+		events, err := store.ListEvents(orgId, audit.BuildFilter(audit.FilterByActor(bob)), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(events)) {
+			return
+		}
+		assert.Equal(t, audit.Denied, events[0].Result)
+	})
+
+	t.Run("ListEvents_Target", func(t *testing.T) {
+		events, err := store.ListEvents(orgId, audit.BuildFilter(audit.FilterByTarget("secret:///prod")), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2, len(events))
+	})
+
+	t.Run("ListEvents_TargetWildcards", func(t *testing.T) {
+		for _, prefix := range []string{"secret:///%", "secret:///pro_", `secret:///\`} {
+			events, err := store.ListEvents(orgId, audit.BuildFilter(audit.FilterByTarget(prefix)), page.BuildPage())
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, 0, len(events), prefix)
+		}
+	})
+
+	t.Run("ListEvents_Since", func(t *testing.T) {
+		events, err := store.ListEvents(orgId,
+			audit.BuildFilter(audit.FilterSince(time.Now().Add(-time.Minute))), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2, len(events))
+	})
+}
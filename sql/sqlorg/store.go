@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/libs/org"
 	"github.com/cott-io/stash/libs/page"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -51,6 +54,11 @@ func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (org.Storage, error)
 	return &SqlStore{db}, nil
 }
 
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
 func (s *SqlStore) CreateOrg(org org.Org, sub org.Subscription, member org.Member) (err error) {
 	err = s.db.Do(
 		sql.ExpectNone(
@@ -75,6 +83,12 @@ func (s *SqlStore) SaveOrg(org org.Org) (err error) {
 				Where(latestOrg("o")).
 				Where("o.id != ?", org.Id)).
 			ThenExec(SchemaOrg.Insert(org)))
+	switch {
+	case errs.Is(err, sql.ErrNotEmpty):
+		err = errors.Wrapf(errs.Conflict, "Org [%v] already exists", org.Name)
+	case errs.Is(err, sql.ErrUniqueConstraint):
+		err = errors.Wrapf(errs.Conflict, "Org [%v] already exists at version [%v]", org.Name, org.Version)
+	}
 	return
 }
 
@@ -181,6 +195,8 @@ func (s *SqlStore) ListMembers(filter org.Filter, page page.Page) (ret []org.Mem
 	err = s.db.Do(
 		sql.QueryPage(query,
 			sql.Slice(&ret, sql.Struct),
+			sql.Keyset("m.org_id", "m.account_id"),
+			sql.After(page.After...),
 			sql.OffsetPtr(page.Offset),
 			sql.LimitPtr(page.Limit)))
 	return
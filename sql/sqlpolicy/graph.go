@@ -0,0 +1,370 @@
+package sqlpolicy
+
+import (
+	"sort"
+
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+)
+
+// A member graph is the set of memberships reachable by walking
+// inwards from a set of policies, through any groups (or proxies)
+// that are members of them.  Walks are bounded by the maximum
+// group depth, so graphs containing cycles are tolerated.
+type memberGraph struct {
+	policies   map[uuid.UUID]policy.Policy         // indexed by policy id
+	members    map[uuid.UUID][]policy.PolicyMember // indexed by policy id
+	principals map[uuid.UUID]uuid.UUID             // member id to the policy it represents
+}
+
+func (s *SqlStore) loadMemberGraph(orgId uuid.UUID, policyIds ...uuid.UUID) (ret memberGraph, err error) {
+	ret = memberGraph{
+		policies:   make(map[uuid.UUID]policy.Policy),
+		members:    make(map[uuid.UUID][]policy.PolicyMember),
+		principals: make(map[uuid.UUID]uuid.UUID)}
+
+	if err = s.loadGraphPolicies(orgId, policyIds, ret.policies); err != nil {
+		return
+	}
+
+	visited, tried :=
+		make(map[uuid.UUID]bool),
+		make(map[uuid.UUID]bool)
+	for _, id := range policyIds {
+		visited[id] = true
+	}
+
+	frontier := policyIds
+	for depth := 0; len(frontier) > 0 && depth <= policy.MaxGroupDepth; depth++ {
+		var members []policy.PolicyMember
+		err = s.db.Do(
+			sql.QueryPage(
+				SchemaPolicyMember.SelectAs("m").
+					Where("m.org_id = ?", orgId).
+					WhereIn("m.policy_id in (%v)", sql.InUUIDs(frontier...)...).
+					Where("not m.deleted").
+					Where(latestPolicyMember("m")),
+				sql.Slice(&members, sql.Struct)))
+		if err != nil {
+			return
+		}
+
+		var unresolved []uuid.UUID
+		for _, m := range members {
+			ret.members[m.PolicyId] = append(ret.members[m.PolicyId], m)
+			if !tried[m.MemberId] {
+				tried[m.MemberId] = true
+				unresolved = append(unresolved, m.MemberId)
+			}
+		}
+		if len(unresolved) == 0 {
+			break
+		}
+
+		var groups []policy.Group
+		err = s.db.Do(
+			sql.QueryPage(
+				SchemaGroup.SelectAs("g").
+					Where("g.org_id = ?", orgId).
+					WhereIn("g.id in (%v)", sql.InUUIDs(unresolved...)...).
+					Where("not g.deleted").
+					Where(latestGroup("g")),
+				sql.Slice(&groups, sql.Struct)))
+		if err != nil {
+			return
+		}
+
+		// Proxies are identified by their policy ids, groups by
+		// their group ids.  Anything else is assumed to be a user.
+		candidates, groupPolicies :=
+			append([]uuid.UUID{}, unresolved...),
+			make(map[uuid.UUID]uuid.UUID)
+		for _, g := range groups {
+			groupPolicies[g.Id] = g.PolicyId
+			candidates = append(candidates, g.PolicyId)
+		}
+		if err = s.loadGraphPolicies(orgId, candidates, ret.policies); err != nil {
+			return
+		}
+
+		frontier = nil
+		for _, id := range unresolved {
+			policyId, ok := id, false
+			if _, ok = ret.policies[id]; !ok {
+				if policyId, ok = groupPolicies[id]; ok {
+					_, ok = ret.policies[policyId]
+				}
+			}
+			if !ok {
+				continue
+			}
+
+			ret.principals[id] = policyId
+			if !visited[policyId] {
+				visited[policyId] = true
+				frontier = append(frontier, policyId)
+			}
+		}
+	}
+	return
+}
+
+func (s *SqlStore) loadGraphPolicies(orgId uuid.UUID, policyIds []uuid.UUID, dst map[uuid.UUID]policy.Policy) (err error) {
+	if len(policyIds) == 0 {
+		return
+	}
+
+	var policies []policy.Policy
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaPolicy.SelectAs("p").
+				Where("p.org_id = ?", orgId).
+				WhereIn("p.id in (%v)", sql.InUUIDs(policyIds...)...).
+				Where("not p.deleted").
+				Where(latestPolicy("p")),
+			sql.Slice(&policies, sql.Struct)))
+	if err != nil {
+		return
+	}
+
+	for _, p := range policies {
+		dst[p.Id] = p
+	}
+	return
+}
+
+// Returns the lock containing the shortest chain of memberships
+// from the user to the policy whose conditions are satisfied by the
+// context.  If every chain is blocked by a condition, the shortest
+// chain is returned so that the caller may report the condition.
+func (g memberGraph) Lock(ctx policy.Context, policyId, userId uuid.UUID) (ret policy.PolicyLock, ok bool) {
+	ret, ok = g.lock(policyId, userId, func(m policy.PolicyMember) bool {
+		return m.Conditions.Evaluate(ctx) == nil
+	})
+	if ok {
+		return
+	}
+
+	ret, ok = g.lock(policyId, userId, func(policy.PolicyMember) bool {
+		return true
+	})
+	return
+}
+
+// Returns the lock containing the shortest chain of admitted
+// memberships from the user to the policy.
+func (g memberGraph) lock(policyId, userId uuid.UUID, admit func(policy.PolicyMember) bool) (ret policy.PolicyLock, ok bool) {
+	core, ok := g.policies[policyId]
+	if !ok {
+		return
+	}
+
+	// Each hop records the membership a policy holds in the next
+	// policy on the way out to the core.
+	type hop struct {
+		Next   uuid.UUID
+		Member policy.PolicyMember
+	}
+
+	hops, visited, queue :=
+		make(map[uuid.UUID]hop),
+		map[uuid.UUID]bool{policyId: true},
+		[]uuid.UUID{policyId}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for _, m := range g.members[cur] {
+			if m.MemberId != userId || !admit(m) {
+				continue
+			}
+
+			member := m
+			for cur != policyId {
+				ret.Chain = append(ret.Chain, policy.PolicyLink{Policy: g.policies[cur], Member: member})
+				member, cur = hops[cur].Member, hops[cur].Next
+			}
+
+			ret.Core, ret.CoreMember = core, member
+			return
+		}
+
+		for _, m := range g.members[cur] {
+			next, found := g.principals[m.MemberId]
+			if !found || visited[next] || !admit(m) {
+				continue
+			}
+
+			visited[next] = true
+			hops[next] = hop{cur, m}
+			queue = append(queue, next)
+		}
+	}
+
+	ok = false
+	return
+}
+
+// Returns the actions enabled for the user on each of the policies.
+// The user is granted the union of the actions of every membership
+// through which the policy may be reached, less any action that one
+// of those memberships denies.  Memberships whose conditions are not
+// satisfied by the context are skipped.
+//
+// Folders additionally pass on the actions that the user holds on the
+// folder itself, so the actions of a secret are the union of its own
+// and those of its folders.
+func (g memberGraph) EnabledActions(ctx policy.Context, userId uuid.UUID, policyIds ...uuid.UUID) (ret []EnabledActions) {
+	return g.enabledActions(ctx, userId, g.Reachable(ctx, userId), 0, policyIds...)
+}
+
+func (g memberGraph) enabledActions(ctx policy.Context, userId uuid.UUID, reachable map[uuid.UUID]bool, depth int, policyIds ...uuid.UUID) (ret []EnabledActions) {
+	for _, id := range policyIds {
+		for _, m := range g.members[id] {
+			if m.Conditions.Evaluate(ctx) != nil {
+				continue
+			}
+
+			via, ok := g.principals[m.MemberId]
+			if m.MemberId != userId && !(ok && reachable[via]) {
+				continue
+			}
+
+			ret = append(ret, EnabledActions{id, m.Actions})
+			if m.MemberType != policy.FolderType || !ok || depth >= policy.MaxGroupDepth {
+				continue
+			}
+
+			for _, inherited := range g.enabledActions(ctx, userId, reachable, depth+1, via) {
+				ret = append(ret, EnabledActions{id, inherited.Actions})
+			}
+		}
+	}
+	return
+}
+
+// Returns the policies of which the user is a direct or transitive member.
+func (g memberGraph) Reachable(ctx policy.Context, userId uuid.UUID) (ret map[uuid.UUID]bool) {
+	ret = make(map[uuid.UUID]bool)
+	for changed := true; changed; {
+		changed = false
+		for policyId, members := range g.members {
+			if ret[policyId] {
+				continue
+			}
+
+			for _, m := range members {
+				if m.Conditions.Evaluate(ctx) != nil {
+					continue
+				}
+
+				via, ok := g.principals[m.MemberId]
+				if m.MemberId == userId || (ok && ret[via]) {
+					ret[policyId], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// Returns every chain of memberships from the policy to the user,
+// ordered from the policy inwards.  Chains are bounded by the
+// maximum group depth.
+func (g memberGraph) Paths(policyId, userId uuid.UUID) (ret []policy.Path) {
+	var walk func(cur uuid.UUID, hops []policy.Hop, onPath map[uuid.UUID]bool)
+	walk = func(cur uuid.UUID, hops []policy.Hop, onPath map[uuid.UUID]bool) {
+		for _, m := range g.members[cur] {
+			path := append(hops[:len(hops):len(hops)], policy.NewHop(m))
+			if m.MemberId == userId {
+				ret = append(ret, policy.Path{Hops: path})
+				continue
+			}
+
+			next, ok := g.principals[m.MemberId]
+			if !ok || onPath[next] || len(path) > policy.MaxGroupDepth {
+				continue
+			}
+
+			onPath[next] = true
+			walk(next, path, onPath)
+			delete(onPath, next)
+		}
+	}
+
+	walk(policyId, nil, map[uuid.UUID]bool{policyId: true})
+	return
+}
+
+// Returns the ids of the users holding a membership anywhere in the
+// graph, in a stable order.
+func (g memberGraph) Users() (ret []uuid.UUID) {
+	found := make(map[uuid.UUID]bool)
+	for _, members := range g.members {
+		for _, m := range members {
+			if m.MemberType == policy.UserType && !found[m.MemberId] {
+				found[m.MemberId] = true
+				ret = append(ret, m.MemberId)
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].String() < ret[j].String()
+	})
+	return
+}
+
+// Returns a copy of the graph in which memberships are restricted by
+// the schedules of their conditions only.
+func (g memberGraph) Schedule() (ret memberGraph) {
+	ret = g.copy()
+	for id, members := range ret.members {
+		for i := range members {
+			members[i].Conditions = members[i].Conditions.Schedule()
+		}
+		ret.members[id] = members
+	}
+	return
+}
+
+// Returns a copy of the graph with the membership saved.  The via
+// policy is the policy that the member represents, if any.
+func (g memberGraph) With(m policy.PolicyMember, via uuid.UUID) (ret memberGraph) {
+	ret = g.copy()
+
+	var members []policy.PolicyMember
+	for _, cur := range ret.members[m.PolicyId] {
+		if cur.MemberId != m.MemberId {
+			members = append(members, cur)
+		}
+	}
+	if !m.Deleted {
+		members = append(members, m)
+	}
+	ret.members[m.PolicyId] = members
+
+	if _, ok := ret.policies[via]; ok {
+		ret.principals[m.MemberId] = via
+	}
+	return
+}
+
+func (g memberGraph) copy() (ret memberGraph) {
+	ret = memberGraph{
+		policies:   make(map[uuid.UUID]policy.Policy),
+		members:    make(map[uuid.UUID][]policy.PolicyMember),
+		principals: make(map[uuid.UUID]uuid.UUID)}
+	for k, v := range g.policies {
+		ret.policies[k] = v
+	}
+	for k, v := range g.members {
+		ret.members[k] = append([]policy.PolicyMember{}, v...)
+	}
+	for k, v := range g.principals {
+		ret.principals[k] = v
+	}
+	return
+}
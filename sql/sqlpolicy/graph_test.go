@@ -0,0 +1,95 @@
+package sqlpolicy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cott-io/stash/libs/policy"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemberGraph_Lock(t *testing.T) {
+	orgId, userId := uuid.NewV1(), uuid.NewV1()
+
+	// The user reaches the core through the team directly, and through
+	// the dept, of which the team is a member.
+	core, dept, team :=
+		policy.Policy{OrgId: orgId, Id: uuid.NewV1()},
+		policy.Policy{OrgId: orgId, Id: uuid.NewV1()},
+		policy.Policy{OrgId: orgId, Id: uuid.NewV1()}
+	deptGroupId, teamGroupId := uuid.NewV1(), uuid.NewV1()
+
+	office := policy.Conditions{Cidrs: []string{"10.0.0.0/8"}}
+
+	newGraph := func(direct policy.Conditions) memberGraph {
+		return memberGraph{
+			policies: map[uuid.UUID]policy.Policy{
+				core.Id: core,
+				dept.Id: dept,
+				team.Id: team,
+			},
+			members: map[uuid.UUID][]policy.PolicyMember{
+				core.Id: {
+					{OrgId: orgId, PolicyId: core.Id, MemberId: teamGroupId, MemberType: policy.GroupType, Conditions: direct},
+					{OrgId: orgId, PolicyId: core.Id, MemberId: deptGroupId, MemberType: policy.GroupType},
+				},
+				dept.Id: {
+					{OrgId: orgId, PolicyId: dept.Id, MemberId: teamGroupId, MemberType: policy.GroupType},
+				},
+				team.Id: {
+					{OrgId: orgId, PolicyId: team.Id, MemberId: userId, MemberType: policy.UserType},
+				},
+			},
+			principals: map[uuid.UUID]uuid.UUID{
+				deptGroupId: dept.Id,
+				teamGroupId: team.Id,
+			},
+		}
+	}
+
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name   string
+		direct policy.Conditions
+		ctx    policy.Context
+		path   []uuid.UUID
+	}{
+		{"Unconditional", policy.Conditions{}, policy.Context{Now: now}, []uuid.UUID{team.Id}},
+		{"Satisfied", office, policy.Context{Now: now, Remote: net.ParseIP("10.1.2.3")}, []uuid.UUID{team.Id}},
+		{"Blocked", office, policy.Context{Now: now, Remote: net.ParseIP("192.168.1.1")}, []uuid.UUID{team.Id, dept.Id}},
+		{"Expired", policy.Conditions{NotAfter: now.Add(-time.Hour)}, policy.Context{Now: now}, []uuid.UUID{team.Id, dept.Id}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lock, ok := newGraph(test.direct).Lock(test.ctx, core.Id, userId)
+			if !assert.True(t, ok) {
+				return
+			}
+			assert.Equal(t, test.path, lock.Path())
+			assert.Nil(t, lock.Evaluate(test.ctx))
+		})
+	}
+
+	t.Run("AllBlocked", func(t *testing.T) {
+		graph := newGraph(office)
+		graph.members[team.Id][0].Conditions = office
+
+		ctx := policy.Context{Now: now, Remote: net.ParseIP("192.168.1.1")}
+
+		lock, ok := graph.Lock(ctx, core.Id, userId)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, []uuid.UUID{team.Id}, lock.Path())
+		assert.NotNil(t, lock.Evaluate(ctx))
+	})
+
+	t.Run("NotAMember", func(t *testing.T) {
+		_, ok := newGraph(policy.Conditions{}).Lock(policy.Context{Now: now}, core.Id, uuid.NewV1())
+		assert.False(t, ok)
+	})
+}
@@ -2,26 +2,38 @@ package sqlpolicy
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
 var (
-	SchemaPolicy = sql.NewSchema("policy", 0).
+	SchemaPolicy = sql.NewSchema("policy", 1).
 		WithStruct(policy.Policy{}).
 		WithIndices(
 			sql.NewUniqueIndex("policy_id", "org_id", "id", "version")).
+		WithMigration(0,
+			sql.Exec(
+				sql.AddColumn("policy",
+					sql.NewColumn("rekey_due", sql.Bool)))).
 		Build()
 )
 
 var (
-	SchemaPolicyMember = sql.NewSchema("policy_member", 0).
+	SchemaPolicyMember = sql.NewSchema("policy_member", 1).
 		WithStruct(policy.PolicyMember{}).
 		WithIndices(
 			sql.NewUniqueIndex("policy_member_id", "org_id", "policy_id", "member_id", "version")).
+		WithMigration(0,
+			sql.Exec(
+				sql.AddColumn("policy_member",
+					sql.NewColumn("conditions", sql.Bytes)))).
 		Build()
 )
 
@@ -50,17 +62,29 @@ func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (policy.Storage, err
 	return &SqlStore{db}, nil
 }
 
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
 func (s *SqlStore) SaveGroup(group policy.Group) (err error) {
 	if group.Deleted {
 		return s.DeleteGroup(group)
 	}
 
-	return s.db.Do(
+	err = s.db.Do(
 		sql.ExpectNone(
 			selectGroupByName(group.OrgId, group.Name).
 				Where(latestGroup("g")).
 				Where("g.id != ?", group.Id)).
 			ThenExec(SchemaGroup.Insert(group)))
+	switch {
+	case errs.Is(err, sql.ErrNotEmpty):
+		err = errors.Wrapf(errs.Conflict, "Group [%v] already exists", group.Name)
+	case errs.Is(err, sql.ErrUniqueConstraint):
+		err = errors.Wrapf(errs.Conflict, "Group [%v] already exists at version [%v]", group.Name, group.Version)
+	}
+	return
 }
 
 func (s *SqlStore) DeleteGroup(group policy.Group) (err error) {
@@ -76,8 +100,7 @@ func (s *SqlStore) ListGroups(orgId uuid.UUID, filter policy.GroupFilter, page p
 	query := sql.Select(SchemaGroup.Cols().As("g")...).
 		From(SchemaGroup.As("g")).
 		Where("g.org_id = ?", orgId).
-		Where(latestGroup("g")).
-		OrderBy("g.name desc")
+		Where(latestGroup("g"))
 	if filter.Names != nil {
 		query = query.WhereIn("g.name in (%v)", sql.InStrings(*filter.Names...)...)
 	}
@@ -88,6 +111,8 @@ func (s *SqlStore) ListGroups(orgId uuid.UUID, filter policy.GroupFilter, page p
 		sql.QueryPage(
 			query,
 			sql.Slice(&ret, sql.Struct),
+			sql.Keyset("g.name desc", "g.id desc"),
+			sql.After(page.After...),
 			sql.LimitPtr(page.Limit),
 			sql.OffsetPtr(page.Offset)))
 	return
@@ -100,12 +125,24 @@ func (s *SqlStore) SavePolicy(p policy.Policy, m policy.PolicyMember) (err error
 			SchemaPolicyMember.Insert(m)))
 }
 
+func (s *SqlStore) UpdatePolicy(p policy.Policy) (err error) {
+	err = s.db.Do(sql.Exec(SchemaPolicy.Insert(p)))
+	if errs.Is(err, sql.ErrUniqueConstraint) {
+		err = errors.Wrapf(errs.Conflict, "Policy [%v] already exists at version [%v]", p.Id, p.Version)
+	}
+	return
+}
+
 func (s *SqlStore) SavePolicyMember(m policy.PolicyMember) (err error) {
 	if m.Deleted {
 		return s.DeletePolicyMember(m)
 	}
 
-	return s.db.Do(sql.Exec(SchemaPolicyMember.Insert(m)))
+	err = s.db.Do(sql.Exec(SchemaPolicyMember.Insert(m)))
+	if errs.Is(err, sql.ErrUniqueConstraint) {
+		err = errors.Wrapf(errs.Conflict, "Member [%v] of policy [%v] already exists at version [%v]", m.MemberId, m.PolicyId, m.Version)
+	}
+	return
 }
 
 func (s *SqlStore) PurgePolicyMember(orgId, memberId uuid.UUID) (err error) {
@@ -155,121 +192,110 @@ func (s *SqlStore) ListPolicyMembers(orgId, policyId uuid.UUID, page page.Page)
 	return
 }
 
-func (s *SqlStore) LoadPolicyLock(orgId, policyId, userId uuid.UUID) (ret policy.PolicyLock, ok bool, err error) {
-	ret, ok, err = s.LoadPolicyViaDirect(orgId, policyId, userId)
-	if err != nil || ok {
+func (s *SqlStore) LoadPolicyLock(ctx policy.Context, orgId, policyId, userId uuid.UUID) (ret policy.PolicyLock, ok bool, err error) {
+	graph, err := s.loadMemberGraph(orgId, policyId)
+	if err != nil {
 		return
 	}
-	ret, ok, err = s.LoadPolicyViaProxy1(orgId, policyId, userId)
+
+	ret, ok = graph.Lock(ctx, policyId, userId)
 	return
 }
 
-func (s *SqlStore) LoadEnabledActions(orgId, userId uuid.UUID, policyIds ...uuid.UUID) (ret map[uuid.UUID]policy.Actions, err error) {
-	var part1 []EnabledActions
-	var part2 []EnabledActions
-	err = s.db.Do(
-		s.QueryEnabledActionsViaDirect(orgId, userId, policyIds, &part1).
-			Then(s.QueryEnabledActionsViaProxy1(orgId, userId, policyIds, &part2)))
+func (s *SqlStore) LoadEnabledActions(ctx policy.Context, orgId, userId uuid.UUID, policyIds ...uuid.UUID) (ret map[uuid.UUID]policy.Actions, err error) {
+	if len(policyIds) == 0 {
+		ret = make(map[uuid.UUID]policy.Actions)
+		return
+	}
+
+	graph, err := s.loadMemberGraph(orgId, policyIds...)
 	if err != nil {
 		return
 	}
 
-	ret = indexActionsById(append(part1, part2...))
+	ret = indexActionsById(graph.EnabledActions(ctx, userId, policyIds...))
 	return
 }
 
-func (s *SqlStore) QueryEnabledActionsViaDirect(orgId, userId uuid.UUID, policyIds []uuid.UUID, dst *[]EnabledActions) sql.Atomic {
-	return sql.QueryPage(
-		sql.Select(
-			SchemaPolicyMember.Cols().Only("policy_id", "actions").As("pm")...).
-			From(SchemaPolicyMember.As("pm")).
-			Where("pm.org_id = ?", orgId).
-			Where("pm.member_id = ?", userId).
-			WhereIn("pm.policy_id in (%v)", sql.InUUIDs(policyIds...)...).
-			Where(latestPolicyMember("pm")).
-			Where(`not pm.deleted`),
-		sql.Slice(dst, sql.Struct))
+func (s *SqlStore) ExplainPolicy(orgId, policyId, userId uuid.UUID) (ret []policy.Path, err error) {
+	graph, err := s.loadMemberGraph(orgId, policyId)
+	if err != nil {
+		return
+	}
+
+	ret = graph.Paths(policyId, userId)
+	return
 }
 
-func (s *SqlStore) QueryEnabledActionsViaProxy1(orgId, userId uuid.UUID, policyIds []uuid.UUID, dst *[]EnabledActions) (ret sql.Atomic) {
-	return sql.QueryPage(
-		sql.Select(
-			SchemaPolicyMember.Cols().Only("policy_id", "actions").As("direct_member")...).
-			From(
-				SchemaPolicyMember.As("direct_member"),
-				SchemaPolicy.As("proxy1"),
-				SchemaPolicyMember.As("proxy1_member")).
-			Where("proxy1_member.org_id = ?", orgId).
-			Where("proxy1_member.member_id = ?", userId).
-			Where(latestPolicyMember("proxy1_member")).
-			Where("not proxy1_member.deleted").
-			Where("proxy1.org_id = proxy1_member.org_id").
-			Where("proxy1.id = proxy1_member.policy_id").
-			Where(latestPolicy("proxy1")).
-			Where("not proxy1.deleted").
-			Where("direct_member.org_id = proxy1.org_id").
-			Where("direct_member.member_id = proxy1.id").
-			WhereIn("direct_member.policy_id in (%v)", sql.InUUIDs(policyIds...)...).
-			Where(latestPolicyMember("direct_member")).
-			Where("not direct_member.deleted"),
-		sql.Slice(dst, sql.Struct))
+func (s *SqlStore) SimulatePolicyMember(now time.Time, m policy.PolicyMember) (ret []policy.Effect, err error) {
+	roots := []uuid.UUID{m.PolicyId}
+
+	var via uuid.UUID
+	if m.MemberType != policy.UserType {
+		if via, err = policy.ResolveMemberPolicy(s, m.OrgId, m.MemberId); err != nil {
+			return
+		}
+		roots = append(roots, via)
+	}
+
+	graph, err := s.loadMemberGraph(m.OrgId, roots...)
+	if err != nil {
+		return
+	}
+
+	m.Conditions = m.Conditions.Schedule()
+
+	ctx, before := policy.Context{Now: now}, graph.Schedule()
+	after := before.With(m, via)
+
+	ret = []policy.Effect{}
+	for _, userId := range union(before.Users(), after.Users()) {
+		prev, next :=
+			indexActionsById(before.EnabledActions(ctx, userId, m.PolicyId))[m.PolicyId],
+			indexActionsById(after.EnabledActions(ctx, userId, m.PolicyId))[m.PolicyId]
+		if !prev.Equals(next) {
+			ret = append(ret, policy.Effect{MemberId: userId, Before: prev, After: next})
+		}
+	}
+	return
 }
 
-func (s *SqlStore) LoadPolicyViaDirect(orgId, policyId, userId uuid.UUID) (ret policy.PolicyLock, ok bool, err error) {
+func (s *SqlStore) ListMemberships(orgId, memberId uuid.UUID, page page.Page) (ret []policy.PolicyMember, err error) {
 	err = s.db.Do(
-		sql.QueryOne(
-			sql.Select(
-				SchemaPolicy.Cols().As("p").
-					Union(SchemaPolicyMember.Cols().As("m"))...).
-				From(
-					SchemaPolicy.As("p"),
-					SchemaPolicyMember.As("m")).
-				Where(`m.org_id = ?`, orgId).
-				Where(`m.policy_id = ?`, policyId).
-				Where(`m.member_id = ?`, userId).
+		sql.QueryPage(
+			SchemaPolicyMember.SelectAs("m").
+				Where("m.org_id = ?", orgId).
+				Where(`(
+	m.member_id = ?
+	or m.member_id in (
+		select
+			g.id
+		from
+			policy_group as g
+		where
+			g.org_id = m.org_id
+			and g.policy_id = ?
+			and not g.deleted
+			and `+latestGroup("g")+`))`, memberId, memberId).
+				Where("not m.deleted").
 				Where(latestPolicyMember("m")).
-				Where(`not m.deleted`).
-				Where(`p.org_id = m.org_id`).
-				Where(`p.id = m.policy_id`).
-				Where(latestPolicy("p")),
-			sql.MultiStruct(&ret),
-			&ok))
+				OrderBy("m.policy_id"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
 	return
 }
 
-func (s *SqlStore) LoadPolicyViaProxy1(orgId, policyId, userId uuid.UUID) (ret policy.PolicyLock, ok bool, err error) {
-	err = s.db.Do(
-		sql.QueryOne(
-			sql.Select(
-				SchemaPolicy.Cols().As("direct").
-					Union(SchemaPolicyMember.Cols().As("direct_member")).
-					Union(SchemaPolicy.Cols().As("proxy1")).
-					Union(SchemaPolicyMember.Cols().As("proxy1_member"))...).
-				From(
-					SchemaPolicy.As("direct"),
-					SchemaPolicyMember.As("direct_member"),
-					SchemaPolicy.As("proxy1"),
-					SchemaPolicyMember.As("proxy1_member")).
-				Where("proxy1_member.org_id = ?", orgId).
-				Where("proxy1_member.member_id = ?", userId).
-				Where(latestPolicyMember("proxy1_member")).
-				Where("not proxy1_member.deleted").
-				Where("proxy1.org_id = proxy1_member.org_id").
-				Where("proxy1.id = proxy1_member.policy_id").
-				Where(latestPolicy("proxy1")).
-				Where("not proxy1.deleted").
-				Where("direct_member.org_id = proxy1.org_id").
-				Where("direct_member.member_id = proxy1.id").
-				Where("direct_member.policy_id = ?", policyId).
-				Where(latestPolicyMember("direct_member")).
-				Where("not direct_member.deleted").
-				Where("direct.org_id = direct_member.org_id").
-				Where("direct.id = direct_member.policy_id").
-				Where(latestPolicy("direct")).
-				Where("not direct.deleted"),
-			sql.MultiStruct(&ret),
-			&ok))
-	return
+func (s *SqlStore) RekeyPolicy(r policy.Rekey) (err error) {
+	inserts := []sql.Query{SchemaPolicy.Insert(r.Policy)}
+	for _, m := range r.Members {
+		inserts = append(inserts, SchemaPolicyMember.Insert(m))
+	}
+	for _, h := range r.Held {
+		inserts = append(inserts, SchemaPolicyMember.Insert(h))
+	}
+
+	return s.db.Do(sql.Exec(inserts...))
 }
 
 func indexActionsById(actions []EnabledActions) (ret map[uuid.UUID]policy.Actions) {
@@ -280,7 +306,18 @@ func indexActionsById(actions []EnabledActions) (ret map[uuid.UUID]policy.Action
 			sum = policy.Enable()
 		}
 
-		ret[a.Id] = sum.Enable(a.Actions.Flatten()...)
+		ret[a.Id] = sum.Merge(a.Actions)
+	}
+	return
+}
+
+func union(a, b []uuid.UUID) (ret []uuid.UUID) {
+	found := make(map[uuid.UUID]bool)
+	for _, id := range append(append([]uuid.UUID{}, a...), b...) {
+		if !found[id] {
+			found[id] = true
+			ret = append(ret, id)
+		}
 	}
 	return
 }
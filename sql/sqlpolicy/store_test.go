@@ -1,13 +1,19 @@
 package sqlpolicy
 
 import (
+	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/cott-io/stash/lang/context"
 	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/auth"
+	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/policy"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,7 +50,7 @@ func TestPolicyStore_DirectMember(t *testing.T) {
 		return
 	}
 
-	lock, ok, err := store.LoadPolicyLock(orgId, core.Id(), acctId1)
+	lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId1)
 	if !assert.Nil(t, err) || !assert.True(t, ok) {
 		return
 	}
@@ -62,6 +68,93 @@ func TestPolicyStore_DirectMember(t *testing.T) {
 	assert.Equal(t, []byte(secretExp), []byte(secretAct))
 }
 
+func TestPolicyStore_Upgrade(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	// The tables as they were before memberships had conditions and
+	// policies could be due a rekey.
+	without := func(schema sql.Schema, name string) sql.Schema {
+		var cols []sql.Column
+		for _, c := range schema.Columns {
+			if c.Name != name {
+				cols = append(cols, c)
+			}
+		}
+		return sql.NewSchema(schema.Name, 0).
+			WithColumns(cols...).
+			WithIndices(schema.Indices...).
+			Build()
+	}
+
+	registry := sql.NewSchemaRegistry("iron")
+	if !assert.Nil(t, sql.InitSchemas(db, registry,
+		without(SchemaPolicy, "rekey_due"),
+		without(SchemaPolicyMember, "conditions"))) {
+		return
+	}
+
+	store, err := NewSqlStore(db, registry)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, registry.Check(db))
+
+	orgId, acctId := uuid.NewV1(), uuid.NewV1()
+
+	acctKey, err := crypto.Moderate.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, acctId, acctKey.Public(), policy.UserType, crypto.Moderate)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, store.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+
+	// Rows saved before the upgrade have neither column.
+	if !assert.Nil(t, db.Do(sql.Exec(sql.QueryFn(func(sql.Dialect) (string, []interface{}, error) {
+		return "update policy_member set conditions = null", nil, nil
+	}), sql.QueryFn(func(sql.Dialect) (string, []interface{}, error) {
+		return "update policy set rekey_due = null", nil, nil
+	})))) {
+		return
+	}
+
+	member, ok, err := store.LoadPolicyMember(orgId, core.Id(), acctId)
+	if !assert.Nil(t, err) || !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, policy.Conditions{}, member.Conditions)
+
+	cur, ok, err := store.LoadPolicy(orgId, core.Id())
+	if !assert.Nil(t, err) || !assert.True(t, ok) {
+		return
+	}
+	assert.False(t, cur.RekeyDue)
+
+	due := cur.Update(func(p *policy.Policy) { p.RekeyDue = true })
+	if !assert.Nil(t, store.UpdatePolicy(due)) {
+		return
+	}
+	assert.True(t, errs.Is(store.UpdatePolicy(due), errs.Conflict))
+
+	cur, _, err = store.LoadPolicy(orgId, core.Id())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, cur.RekeyDue)
+	assert.Equal(t, due.Version, cur.Version)
+}
+
 func TestPolicyStore_ProxyMember(t *testing.T) {
 	ctx := context.NewContext(os.Stdout, context.Debug)
 
@@ -114,7 +207,7 @@ func TestPolicyStore_ProxyMember(t *testing.T) {
 		return
 	}
 
-	lock, ok, err := store.LoadPolicyLock(orgId, core.Id(), acctId2)
+	lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId2)
 	if !assert.Nil(t, err) || !assert.True(t, ok) {
 		return
 	}
@@ -192,7 +285,7 @@ func TestPolicyStore_Proxy2Member(t *testing.T) {
 		return
 	}
 
-	lock, ok, err := store.LoadPolicyLock(orgId, core.Id(), acctId2)
+	lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId2)
 	if !assert.Nil(t, err) || !assert.True(t, ok) {
 		return
 	}
@@ -216,3 +309,596 @@ func TestPolicyStore_Proxy2Member(t *testing.T) {
 	//
 	// fmt.Println(enc.Json.MustEncode(actions))
 }
+
+func TestPolicyStore_Rekey(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	s := crypto.Moderate
+
+	orgId, acctId1, acctId2, acctId3 :=
+		uuid.NewV1(), uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	acctKey1, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey2, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey3, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, acctId1, acctKey1.Public(), policy.UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	proxy, err := policy.GenPolicy(crypto.Rand, orgId, acctId2, acctKey2.Public(), policy.UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	proxyMember, err := core.AddMember(crypto.Rand, acctKey1, proxy.Id(), policy.ProxyType, proxy.PublicKey())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	revoked, err := proxy.AddMember(crypto.Rand, acctKey2, acctId3, policy.UserType, acctKey3.Public())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, store.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicy(proxy.Core, proxy.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(proxyMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(revoked)) {
+		return
+	}
+
+	prevSecret, err := proxy.RecoverSecret(crypto.Rand, acctKey2)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	coreSecret, err := core.RecoverSecret(crypto.Rand, acctKey1)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("ListMemberships", func(t *testing.T) {
+		held, err := store.ListMemberships(orgId, proxy.Id(), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(held)) {
+			return
+		}
+		assert.Equal(t, core.Id(), held[0].PolicyId)
+	})
+
+	t.Run("RekeyPolicy", func(t *testing.T) {
+		if !assert.Nil(t, store.SavePolicyMember(revoked.Delete())) {
+			return
+		}
+
+		members, err := store.ListPolicyMembers(orgId, proxy.Id(), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(members)) {
+			return
+		}
+
+		held, err := store.ListMemberships(orgId, proxy.Id(), page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		lock, _, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, proxy.Id(), acctId2)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		rekey, secret, err := lock.Rekey(crypto.Rand, acctKey2,
+			[]policy.MemberKey{{Member: members[0], Key: acctKey2.Public()}}, held)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []uuid.UUID{core.Id()}, rekey.HeldPolicyIds())
+
+		if !assert.Nil(t, store.RekeyPolicy(rekey)) {
+			return
+		}
+
+		next, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, proxy.Id(), acctId2)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, proxy.Core.Version+1, next.Core.Version)
+
+		nextSecret, err := next.RecoverSecret(crypto.Rand, acctKey2)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, secret, []byte(nextSecret))
+		assert.NotEqual(t, []byte(prevSecret), []byte(nextSecret))
+
+		_, ok, err = store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, proxy.Id(), acctId3)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.False(t, ok)
+
+		// the held membership must be accessible using the new key
+		proxyKey, err := next.RecoverPrivateKey(crypto.Rand, acctKey2)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		held, err = store.ListMemberships(orgId, proxy.Id(), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(held)) {
+			return
+		}
+
+		coreLock, _, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId1)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		secret2, err := coreLock.Core.RecoverSecret(crypto.Rand, held[0], proxyKey)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(coreSecret), []byte(secret2))
+	})
+}
+
+func TestPolicyStore_NestedGroups(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	s := crypto.Moderate
+
+	orgId, acctId1, acctId2, acctId3 :=
+		uuid.NewV1(), uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	acctKey1, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey2, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey3, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, acctId1, acctKey1.Public(), policy.UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	dept, err := policy.GenPolicy(crypto.Rand, orgId, acctId2, acctKey2.Public(), policy.UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	team, err := policy.GenPolicy(crypto.Rand, orgId, acctId3, acctKey3.Public(), policy.UserType, s)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	deptGroup, err := policy.NewGroup(orgId, dept.Id(), "dept", "")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	teamGroup, err := policy.NewGroup(orgId, team.Id(), "team", "")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	deptMember, err := core.AddMember(crypto.Rand, acctKey1, deptGroup.Id, policy.GroupType, dept.PublicKey(), policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	teamMember, err := dept.AddMember(crypto.Rand, acctKey2, teamGroup.Id, policy.GroupType, team.PublicKey())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, store.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicy(dept.Core, dept.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicy(team.Core, team.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SaveGroup(deptGroup)) {
+		return
+	}
+	if !assert.Nil(t, store.SaveGroup(teamGroup)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(deptMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(teamMember)) {
+		return
+	}
+
+	t.Run("LoadPolicyLock", func(t *testing.T) {
+		lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId3)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, []uuid.UUID{team.Id(), dept.Id()}, lock.Path())
+
+		secretExp, err := core.RecoverSecret(crypto.Rand, acctKey1)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		secretAct, err := lock.RecoverSecret(crypto.Rand, acctKey3)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secretExp), []byte(secretAct))
+	})
+
+	req := policy.Context{Now: time.Now().UTC()}
+
+	t.Run("LoadEnabledActions", func(t *testing.T) {
+		actions, err := store.LoadEnabledActions(req, orgId, acctId3, core.Id(), dept.Id(), team.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.View))
+		assert.False(t, actions[core.Id()].Enabled(policy.Sudo))
+		assert.True(t, actions[team.Id()].Enabled(policy.Sudo))
+
+		actions, err = store.LoadEnabledActions(req, orgId, acctId1, dept.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, ok := actions[dept.Id()]
+		assert.False(t, ok)
+	})
+
+	t.Run("ListMemberships", func(t *testing.T) {
+		held, err := store.ListMemberships(orgId, dept.Id(), page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(held)) {
+			return
+		}
+		assert.Equal(t, core.Id(), held[0].PolicyId)
+	})
+
+	t.Run("LoadEnabledActions_Conditions", func(t *testing.T) {
+		expiring := teamMember.Update(func(m *policy.PolicyMember) {
+			m.Conditions = policy.Conditions{
+				NotAfter: req.Now.Add(time.Hour),
+				Cidrs:    []string{"10.0.0.0/8"},
+			}
+		})
+		if !assert.Nil(t, store.SavePolicyMember(expiring)) {
+			return
+		}
+
+		inside := req
+		inside.Remote = net.ParseIP("10.1.2.3")
+
+		actions, err := store.LoadEnabledActions(inside, orgId, acctId3, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.View))
+
+		outside := inside
+		outside.Remote = net.ParseIP("192.168.1.1")
+
+		actions, err = store.LoadEnabledActions(outside, orgId, acctId3, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, ok := actions[core.Id()]
+		assert.False(t, ok)
+
+		expired := inside
+		expired.Now = req.Now.Add(2 * time.Hour)
+
+		actions, err = store.LoadEnabledActions(expired, orgId, acctId3, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, ok = actions[core.Id()]
+		assert.False(t, ok)
+
+		lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId3)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Nil(t, lock.Evaluate(inside))
+		assert.NotNil(t, lock.Evaluate(expired))
+	})
+
+	t.Run("EnsureAcyclic", func(t *testing.T) {
+		cycle, err := team.AddMember(crypto.Rand, acctKey3, deptGroup.Id, policy.GroupType, dept.PublicKey())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, policy.ErrCycle, errors.Cause(policy.EnsureAcyclic(store, cycle)))
+
+		self, err := team.AddMember(crypto.Rand, acctKey3, teamGroup.Id, policy.GroupType, team.PublicKey())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, policy.ErrCycle, errors.Cause(policy.EnsureAcyclic(store, self)))
+
+		assert.Nil(t, policy.EnsureAcyclic(store, deptMember))
+	})
+
+	t.Run("ExplainPolicy", func(t *testing.T) {
+		paths, err := store.ExplainPolicy(orgId, core.Id(), acctId3)
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(paths)) {
+			return
+		}
+		if !assert.Equal(t, 3, len(paths[0].Hops)) {
+			return
+		}
+		assert.Equal(t, deptGroup.Id, paths[0].Hops[0].MemberId)
+		assert.Equal(t, teamGroup.Id, paths[0].Hops[1].MemberId)
+		assert.Equal(t, acctId3, paths[0].Hops[2].MemberId)
+		assert.True(t, paths[0].Grants(policy.View))
+		assert.False(t, paths[0].Grants(policy.Edit))
+
+		outside := req
+		outside.Remote = net.ParseIP("192.168.1.1")
+
+		exp := policy.Explain(orgId, core.Id(), acctId3, auth.Member, paths, policy.Evaluator(outside))
+		assert.NotEmpty(t, exp.Paths[0].Error)
+		assert.False(t, exp.Enabled().Enabled(policy.View))
+
+		exp = policy.Explain(orgId, core.Id(), acctId3, auth.Member, paths, policy.ScheduleEvaluator(req.Now))
+		assert.Empty(t, exp.Paths[0].Error)
+		assert.True(t, exp.Enabled().Enabled(policy.View))
+
+		exp = policy.Explain(orgId, core.Id(), acctId3, auth.None, paths, policy.ScheduleEvaluator(req.Now))
+		assert.False(t, exp.Enabled().Enabled(policy.View))
+	})
+
+	t.Run("SimulatePolicyMember", func(t *testing.T) {
+		grant, err := core.AddMember(crypto.Rand, acctKey1, acctId2, policy.UserType, acctKey2.Public(), policy.Edit)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		effects, err := store.SimulatePolicyMember(req.Now, grant)
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(effects)) {
+			return
+		}
+		assert.Equal(t, acctId2, effects[0].MemberId)
+		assert.Equal(t, []policy.Action{policy.Edit}, effects[0].Gained())
+		assert.Empty(t, effects[0].Lost())
+
+		effects, err = store.SimulatePolicyMember(req.Now, deptMember.Delete())
+		if !assert.Nil(t, err) || !assert.Equal(t, 2, len(effects)) {
+			return
+		}
+		for _, e := range effects {
+			assert.Equal(t, []policy.Action{policy.View}, e.Lost())
+		}
+
+		// Nothing is saved.
+		actions, err := store.LoadEnabledActions(req, orgId, acctId2, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.View))
+		assert.False(t, actions[core.Id()].Enabled(policy.Edit))
+	})
+}
+
+func TestPolicyStore_FolderInheritance(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	s := crypto.Moderate
+
+	orgId, acctId1, acctId2 :=
+		uuid.NewV1(), uuid.NewV1(), uuid.NewV1()
+
+	acctKey1, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+	acctKey2, err := s.GenKey(crypto.Rand, crypto.RSA)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	core, err := policy.GenPolicy(crypto.Rand, orgId, acctId1, acctKey1.Public(), policy.UserType, s, policy.Sudo)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	folder, err := policy.GenPolicy(crypto.Rand, orgId, acctId1, acctKey1.Public(), policy.UserType, s, policy.Sudo)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// Folders are attached without any actions of their own.
+	folderMember, err := core.AddMember(crypto.Rand, acctKey1, folder.Id(), policy.FolderType, folder.PublicKey())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	viewer, err := folder.AddMember(crypto.Rand, acctKey1, acctId2, policy.UserType, acctKey2.Public(), policy.View)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	direct, err := core.AddMember(crypto.Rand, acctKey1, acctId2, policy.UserType, acctKey2.Public(), policy.Edit)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, store.SavePolicy(core.Core, core.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicy(folder.Core, folder.CoreMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(folderMember)) {
+		return
+	}
+	if !assert.Nil(t, store.SavePolicyMember(viewer)) {
+		return
+	}
+
+	req := policy.Context{Now: time.Now().UTC()}
+
+	t.Run("Inherited", func(t *testing.T) {
+		actions, err := store.LoadEnabledActions(req, orgId, acctId2, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.View))
+		assert.False(t, actions[core.Id()].Enabled(policy.Edit))
+		assert.False(t, actions[core.Id()].Enabled(policy.Sudo))
+
+		lock, ok, err := store.LoadPolicyLock(policy.Context{Now: time.Now().UTC()}, orgId, core.Id(), acctId2)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+
+		secretExp, err := core.RecoverSecret(crypto.Rand, acctKey1)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		secretAct, err := lock.RecoverSecret(crypto.Rand, acctKey2)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []byte(secretExp), []byte(secretAct))
+	})
+
+	t.Run("Union", func(t *testing.T) {
+		if !assert.Nil(t, store.SavePolicyMember(direct)) {
+			return
+		}
+
+		actions, err := store.LoadEnabledActions(req, orgId, acctId2, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.View))
+		assert.True(t, actions[core.Id()].Enabled(policy.Edit))
+	})
+
+	t.Run("Conditions", func(t *testing.T) {
+		expired := viewer.Update(func(m *policy.PolicyMember) {
+			m.Conditions = policy.Conditions{NotAfter: req.Now.Add(-time.Hour)}
+		})
+		if !assert.Nil(t, store.SavePolicyMember(expired)) {
+			return
+		}
+
+		actions, err := store.LoadEnabledActions(req, orgId, acctId2, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.False(t, actions[core.Id()].Enabled(policy.View))
+		assert.True(t, actions[core.Id()].Enabled(policy.Edit))
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		cur, ok, err := store.LoadPolicyMember(orgId, folder.Id(), acctId2)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		if !assert.Nil(t, store.SavePolicyMember(cur.Update(func(m *policy.PolicyMember) {
+			m.Conditions = policy.Conditions{}
+		}))) {
+			return
+		}
+
+		// A deny on the direct membership overrides the view
+		// inherited from the folder.
+		denied := direct.Update(func(m *policy.PolicyMember) {
+			m.Actions = m.Actions.Deny(policy.View)
+		})
+		if !assert.Nil(t, store.SavePolicyMember(denied)) {
+			return
+		}
+
+		actions, err := store.LoadEnabledActions(req, orgId, acctId2, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.False(t, actions[core.Id()].Enabled(policy.View))
+		assert.True(t, actions[core.Id()].Denied(policy.View))
+		assert.True(t, actions[core.Id()].Enabled(policy.Edit))
+
+		assert.NotNil(t, policy.Authorize(store, req, acctId2, policy.Has(policy.View), policy.Addr(orgId, core.Id())))
+		assert.Nil(t, policy.Authorize(store, req, acctId2, policy.Has(policy.Edit), policy.Addr(orgId, core.Id())))
+	})
+
+	t.Run("DenySudo", func(t *testing.T) {
+		folderDeny := folderMember.Update(func(m *policy.PolicyMember) {
+			m.Actions = m.Actions.Deny(policy.Delete)
+		})
+		if !assert.Nil(t, store.SavePolicyMember(folderDeny)) {
+			return
+		}
+
+		// A deny on the folder's membership applies to everyone
+		// reaching the secret through the folder, even its owners.
+		actions, err := store.LoadEnabledActions(req, orgId, acctId1, core.Id())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.True(t, actions[core.Id()].Enabled(policy.Sudo))
+		assert.True(t, actions[core.Id()].Holds(policy.View))
+		assert.False(t, actions[core.Id()].Holds(policy.Delete))
+
+		assert.NotNil(t, policy.Authorize(store, req, acctId1, policy.Has(policy.Delete), policy.Addr(orgId, core.Id())))
+		assert.Nil(t, policy.Authorize(store, req, acctId1, policy.Has(policy.Sudo), policy.Addr(orgId, core.Id())))
+	})
+}
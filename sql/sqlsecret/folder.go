@@ -0,0 +1,71 @@
+package sqlsecret
+
+import (
+	"fmt"
+
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/secret"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	SchemaFolder = sql.NewSchema("secret_folder", 0).
+		WithStruct(secret.Folder{}).
+		WithIndices(
+			sql.NewUniqueIndex("secret_folder_id", "org_id", "id", "version"),
+			sql.NewIndex("secret_folder_by_path", "org_id", "path")).
+		Build()
+)
+
+func (s *SqlStore) SaveFolder(f secret.Folder) (err error) {
+	return s.db.Do(
+		sql.ExpectNone(
+			SchemaFolder.SelectAs("f").
+				Where("f.org_id = ?", f.OrgId).
+				Where("f.path = ?", f.Path).
+				Where("f.id != ?", f.Id).
+				Where("not f.deleted").
+				Where(latestFolder("f"))).
+			ThenExec(SchemaFolder.Insert(f)))
+}
+
+func (s *SqlStore) ListFolders(orgId uuid.UUID, filter secret.FolderFilter, page page.Page) (ret []secret.Folder, err error) {
+	query := SchemaFolder.SelectAs("f").
+		Where("f.org_id = ?", orgId).
+		Where("not f.deleted").
+		Where(latestFolder("f")).
+		OrderBy("f.path")
+
+	if filter.Ids != nil {
+		query = query.WhereIn("f.id in (%v)", sql.InUUIDs(*filter.Ids...)...)
+	}
+	if filter.Paths != nil {
+		query = query.WhereIn("f.path in (%v)", sql.InStrings(*filter.Paths...)...)
+	}
+	if filter.Prefix != nil {
+		query = query.Where(`f.path like ? escape '\'`, sql.EscapeLike(*filter.Prefix)+"%")
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query,
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func latestFolder(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				secret_folder as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
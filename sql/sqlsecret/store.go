@@ -2,19 +2,27 @@ package sqlsecret
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/secret"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
 var (
-	SchemaSecret = sql.NewSchema("secret", 0).
+	SchemaSecret = sql.NewSchema("secret", 1).
 		WithStruct(secret.Secret{}).
 		WithIndices(
 			sql.NewUniqueIndex("secret_id", "org_id", "id", "version"),
 			sql.NewIndex("secret_by_name", "org_id", "name")).
+		WithMigration(0,
+			sql.Exec(
+				sql.AddColumn("secret",
+					sql.NewColumn("rotation", sql.Bytes)))).
 		Build()
 )
 
@@ -33,33 +41,95 @@ var (
 		Build()
 )
 
+var (
+	SchemaEvent = sql.NewSchema("secret_event", 0).
+		WithStruct(secret.Event{}).
+		WithIndices(
+			sql.NewUniqueIndex("secret_event_seq", "org_id", "seq")).
+		Build()
+)
+
+var (
+	SchemaEventCounter = sql.NewSchema("secret_event_counter", 0).
+		WithStruct(EventCounter{}).
+		WithIndices(
+			sql.NewUniqueIndex("secret_event_counter_id", "org_id")).
+		Build()
+)
+
+var (
+	SchemaBlockUsage = sql.NewSchema("secret_block_usage", 1).
+		WithStruct(BlockUsage{}).
+		WithIndices(
+			sql.NewUniqueIndex("secret_block_usage_id", "org_id")).
+		WithMigration(0, sql.Exec(sql.Raw(`
+			update secret_block_usage
+			set
+				bytes = (
+					select
+						coalesce(sum(length(b.data)), 0)
+					from
+						secret_block as b
+					where
+						b.org_id = secret_block_usage.org_id)`))).
+		Build()
+)
+
 type Tag struct {
 	OrgId    uuid.UUID
 	SecretId uuid.UUID
 	Name     string
 }
 
+// The bytes of block data stored by an org.  Uploads add to the usage
+// and purges subtract from it, locking the org's row, so that quotas
+// are checked against the usage of one upload at a time.
+type BlockUsage struct {
+	OrgId uuid.UUID
+	Bytes int64
+}
+
+// The last sequence number allocated to an org's feed.
+type EventCounter struct {
+	OrgId uuid.UUID
+	Seq   int
+}
+
 type SqlStore struct {
 	db sql.Driver
 }
 
 func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (secret.Storage, error) {
-	if err := sql.InitSchemas(db, schemas, SchemaSecret, SchemaBlock, SchemaTag); err != nil {
+	if err := sql.InitSchemas(db, schemas, SchemaSecret, SchemaBlock, SchemaTag, SchemaEvent, SchemaEventCounter, SchemaBlockUsage, SchemaFolder); err != nil {
 		return nil, err
 	}
 	return &SqlStore{db}, nil
 }
 
-func (s *SqlStore) SaveSecret(secret secret.Secret) (err error) {
-	return s.db.Do(
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
+func (s *SqlStore) SaveSecret(sec secret.Secret) (err error) {
+	err = s.db.Do(
 		sql.ExpectNone(
-			selectSecretByName(secret.OrgId, secret.Name).
+			selectSecretByName(sec.OrgId, sec.Name).
 				Where(latestSecret("b")).
 				Where("not b.deleted").
-				Where("b.id != ?", secret.Id)).
-			ThenExec(SchemaSecret.Insert(secret)).
+				Where("b.id != ?", sec.Id)).
+			ThenExec(SchemaSecret.Insert(sec)).
+			Then(
+				purgeOldSecretQuery(sec.OrgId, sec.Id)).
 			Then(
-				purgeOldSecretQuery(secret.OrgId, secret.Id)))
+				appendEvents(secret.NewSecretEvent(sec))))
+	switch {
+	case errs.Is(err, sql.ErrNotEmpty):
+		err = errors.Wrapf(errs.Conflict, "Secret [%v] already exists", sec.Name)
+	case errs.Is(err, sql.ErrUniqueConstraint):
+		err = errors.Wrapf(errs.Conflict, "Secret [%v] already exists at version [%v]", sec.Name, sec.Version)
+	}
+	return
 }
 
 func (s *SqlStore) LoadSecretByName(orgId uuid.UUID, name string, version int) (ret secret.Secret, ok bool, err error) {
@@ -100,8 +170,7 @@ func (s *SqlStore) ListSecrets(orgId uuid.UUID, filter secret.Filter, page page.
 	query := sql.Select(SchemaSecret.Cols().As("b")...).
 		From(SchemaSecret.As("b")).
 		Where("b.org_id = ?", orgId).
-		Where(latestSecret("b")).
-		OrderBy("b.name")
+		Where(latestSecret("b"))
 
 	if filter.Type != nil {
 		query = query.Where("b.type = ?", *filter.Type)
@@ -121,9 +190,12 @@ func (s *SqlStore) ListSecrets(orgId uuid.UUID, filter secret.Filter, page page.
 	if filter.Ids != nil {
 		query = query.WhereIn("b.id in (%v)", sql.InUUIDs(*filter.Ids...)...)
 	}
+	if filter.PolicyIds != nil {
+		query = query.WhereIn("b.policy_id in (%v)", sql.InUUIDs(*filter.PolicyIds...)...)
+	}
 
 	if filter.Hidden == nil || !*filter.Hidden {
-		if filter.Ids == nil && filter.Names == nil {
+		if filter.Ids == nil && filter.Names == nil && filter.PolicyIds == nil {
 			query = query.Where("lower(b.name) not like ?", ".%")
 		}
 	}
@@ -144,6 +216,8 @@ func (s *SqlStore) ListSecrets(orgId uuid.UUID, filter secret.Filter, page page.
 		sql.QueryPage(
 			query,
 			sql.Slice(&ret, sql.Struct),
+			sql.Keyset("b.name", "b.id"),
+			sql.After(page.After...),
 			sql.LimitPtr(page.Limit),
 			sql.OffsetPtr(page.Offset)))
 	return
@@ -155,20 +229,141 @@ func (s *SqlStore) ListSecretVersions(orgId, secretId uuid.UUID, page page.Page)
 			sql.Select(SchemaSecret.Cols().As("b")...).
 				From(SchemaSecret.As("b")).
 				Where(`b.org_id = ?`, orgId).
-				Where(`b.id  = ?`, secretId).
-				OrderBy("b.version desc"),
+				Where(`b.id  = ?`, secretId),
 			sql.Slice(&ret, sql.Struct),
+			sql.Keyset("b.version desc"),
+			sql.After(page.After...),
 			sql.LimitPtr(page.Limit),
 			sql.OffsetPtr(page.Offset)))
 	return
 }
 
 func (s *SqlStore) SaveBlocks(blocks ...secret.Block) error {
-	var inserts []sql.Query
+	return s.SaveBlocksWithin(0, blocks...)
+}
+
+func (s *SqlStore) SaveBlocksWithin(quota int64, blocks ...secret.Block) error {
+	return s.db.Do(func(tx sql.Tx) (err error) {
+		var orgIds []uuid.UUID
+		seen := make(map[uuid.UUID]bool)
+		for _, b := range blocks {
+			if !seen[b.OrgId] {
+				seen[b.OrgId] = true
+				orgIds = append(orgIds, b.OrgId)
+			}
+		}
+
+		for _, orgId := range orgIds {
+			if err = initBlockUsage(tx, orgId); err != nil {
+				return
+			}
+		}
+
+		for _, b := range blocks {
+			if _, err = tx.Exec(SchemaBlock.Insert(b)); err != nil {
+				return
+			}
+		}
+
+		if err = addBlockUsage(tx, blocks...); err != nil {
+			return
+		}
+
+		if quota <= 0 {
+			return
+		}
+
+		for _, orgId := range orgIds {
+			if err = checkBlockUsage(tx, orgId, quota); err != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+// Records the org's usage, if it has none.  Orgs whose blocks were saved
+// before usage was recorded are counted once, here.
+func initBlockUsage(tx sql.Tx, orgId uuid.UUID) (err error) {
+	n, err := tx.Exec(sql.Raw(`
+		insert into secret_block_usage (org_id, bytes)
+		values
+			($1, 0)
+		on conflict do nothing`, orgId))
+	if err != nil || n == 0 {
+		return
+	}
+
+	_, err = tx.Exec(sql.Raw(`
+		update secret_block_usage
+		set
+			bytes = (
+				select
+					coalesce(sum(length(b.data)), 0)
+				from
+					secret_block as b
+				where
+					b.org_id = $1)
+		where
+			org_id = $2`, orgId, orgId))
+	return
+}
+
+// Adds the bytes of the saved blocks to the usage of their orgs.  The
+// update locks each org's usage until the transaction completes, so
+// that quotas are checked against one upload at a time.
+func addBlockUsage(tx sql.Tx, blocks ...secret.Block) (err error) {
+	type stream struct {
+		orgId, streamId uuid.UUID
+	}
+
+	var streams []stream
+	idxs := make(map[stream][]interface{})
 	for _, b := range blocks {
-		inserts = append(inserts, SchemaBlock.Insert(b))
+		key := stream{b.OrgId, b.StreamId}
+		if _, ok := idxs[key]; !ok {
+			streams = append(streams, key)
+		}
+		idxs[key] = append(idxs[key], b.Idx)
 	}
-	return s.db.Do(sql.Exec(inserts...))
+
+	for _, cur := range streams {
+		var bytes int64
+		if _, err = tx.Query(sql.Value(&bytes),
+			sql.Select("coalesce(sum(length(b.data)), 0)").
+				From(SchemaBlock.As("b")).
+				Where("b.org_id = ?", cur.orgId).
+				Where("b.stream_id = ?", cur.streamId).
+				WhereIn("b.idx in (%v)", idxs[cur]...)); err != nil {
+			return
+		}
+
+		if _, err = tx.Exec(sql.Raw(`
+			update secret_block_usage
+			set
+				bytes = bytes + $1
+			where
+				org_id = $2`, bytes, cur.orgId)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Fails if the org's usage exceeds the quota.
+func checkBlockUsage(tx sql.Tx, orgId uuid.UUID, quota int64) (err error) {
+	var usage int64
+	if _, err = tx.Query(sql.Value(&usage),
+		sql.Select("u.bytes").
+			From(SchemaBlockUsage.As("u")).
+			Where("u.org_id = ?", orgId)); err != nil {
+		return
+	}
+
+	if usage > quota {
+		err = errors.Wrapf(secret.ErrQuota, "Org storage quota of [%v] bytes exceeded", quota)
+	}
+	return
 }
 
 func (s *SqlStore) AddTag(orgId, secretId uuid.UUID, tags ...string) (err error) {
@@ -234,6 +429,120 @@ func (s *SqlStore) LoadBlocks(orgId, streamId uuid.UUID, page page.Page) (ret []
 	return
 }
 
+func (s *SqlStore) LoadBlockUsage(orgId uuid.UUID) (ret int64, err error) {
+	var found bool
+	if err = s.db.Do(
+		sql.QueryOne(
+			sql.Select("u.bytes").
+				From(SchemaBlockUsage.As("u")).
+				Where("u.org_id = ?", orgId),
+			sql.Value(&ret), &found)); err != nil || found {
+		return
+	}
+
+	// The org has not saved blocks since usage was recorded.
+	err = s.db.Do(
+		sql.QueryOne(
+			sql.Select("coalesce(sum(length(b.data)), 0)").
+				From(SchemaBlock.As("b")).
+				Where("b.org_id = ?", orgId),
+			sql.Value(&ret), &found))
+	return
+}
+
+func (s *SqlStore) SaveEvents(events ...secret.Event) error {
+	return s.db.Do(appendEvents(events...))
+}
+
+func (s *SqlStore) ListEvents(orgId uuid.UUID, prefix string, after int, page page.Page) (ret []secret.Event, err error) {
+	query := SchemaEvent.SelectAs("e").
+		Where("e.org_id = ?", orgId).
+		Where("e.seq > ?", after).
+		OrderBy("e.seq asc")
+	if prefix != "" {
+		query = query.Where(`lower(e.name) like ? escape '\'`, sql.EscapeLike(strings.ToLower(prefix))+"%")
+	}
+
+	err = s.db.Do(
+		sql.QueryPage(
+			query,
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+// Appends the events to the end of their org's feed.  Sequence
+// numbers are reserved from the org's counter within the enclosing
+// transaction, which serializes concurrent writers.
+func appendEvents(events ...secret.Event) sql.Atomic {
+	return func(tx sql.Tx) (err error) {
+		var orgIds []uuid.UUID
+		counts := make(map[uuid.UUID]int)
+		for _, e := range events {
+			if counts[e.OrgId] == 0 {
+				orgIds = append(orgIds, e.OrgId)
+			}
+			counts[e.OrgId]++
+		}
+
+		seqs := make(map[uuid.UUID]int)
+		for _, orgId := range orgIds {
+			if seqs[orgId], err = reserveEvents(tx, orgId, counts[orgId]); err != nil {
+				return
+			}
+		}
+
+		for _, e := range events {
+			seqs[e.OrgId]++
+			e.Seq = seqs[e.OrgId]
+			if _, err = tx.Exec(SchemaEvent.Insert(e)); err != nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+// Reserves num sequence numbers from the org's counter, returning the
+// number preceding them.  Updating the counter locks its row until the
+// transaction completes, so concurrent writers wait on one another
+// rather than colliding on the sequence index.
+func reserveEvents(tx sql.Tx, orgId uuid.UUID, num int) (ret int, err error) {
+	// Feeds recorded before the counter existed continue from their
+	// latest event.
+	if _, err = tx.Exec(sql.Raw(`
+		insert into secret_event_counter (org_id, seq)
+		select
+			$1, coalesce(max(e.seq), 0)
+		from
+			secret_event as e
+		where
+			e.org_id = $2
+		on conflict do nothing`, orgId, orgId)); err != nil {
+		return
+	}
+
+	if _, err = tx.Exec(sql.Raw(`
+		update secret_event_counter
+		set
+			seq = seq + $1
+		where
+			org_id = $2`, num, orgId)); err != nil {
+		return
+	}
+
+	if _, err = tx.Query(sql.Value(&ret),
+		sql.Select("c.seq").
+			From(SchemaEventCounter.As("c")).
+			Where("c.org_id = ?", orgId)); err != nil {
+		return
+	}
+
+	ret -= num
+	return
+}
+
 func latestSecret(alias string) string {
 	return fmt.Sprintf(`
 		not exists (
@@ -256,6 +565,29 @@ func selectSecretByName(orgId uuid.UUID, name string) sql.SelectBuilder {
 
 func purgeOldSecretQuery(orgId, newId uuid.UUID) sql.Atomic {
 	return sql.Exec(
+		sql.Raw(`
+			update secret_block_usage
+			set
+				bytes = bytes - (
+					select
+						coalesce(sum(length(b.data)), 0)
+					from
+						secret_block as b
+					where
+						b.org_id = $1
+						and b.stream_id in (
+							select
+								old.stream_id
+							from
+								secret as new, secret as old
+							where
+								new.org_id = $2
+								and new.id = $3
+								and old.org_id = new.org_id
+								and old.name = new.name
+								and old.id != new.id))
+			where
+				org_id = $4`, orgId, orgId, newId, orgId),
 		SchemaBlock.Delete().
 			Where(`org_id = ?`, orgId).
 			Where(`stream_id in (
@@ -1,10 +1,14 @@
 package sqlsecret
 
 import (
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/crypto"
+	"github.com/cott-io/stash/lang/errs"
 	"github.com/cott-io/stash/lang/sql"
 	"github.com/cott-io/stash/libs/page"
 	"github.com/cott-io/stash/libs/secret"
@@ -47,7 +51,157 @@ func TestSecretStorage(t *testing.T) {
 	})
 
 	t.Run("Insert_Stale", func(t *testing.T) {
-		assert.NotNil(t, store.SaveSecret(sec))
+		err := store.SaveSecret(sec)
+		assert.True(t, errs.Is(err, errs.Conflict), "%v", err)
+	})
+
+	t.Run("Insert_DuplicateName", func(t *testing.T) {
+		dup := secret.NewSecret().
+			SetOrg(sec.OrgId).
+			SetStream(uuid.NewV1(), 0).
+			SetName(sec.Name).
+			MustCompile()
+
+		err := store.SaveSecret(dup)
+		assert.True(t, errs.Is(err, errs.Conflict), "%v", err)
+	})
+
+	t.Run("BlockUsage", func(t *testing.T) {
+		orgId, streamId := uuid.NewV1(), uuid.NewV1()
+
+		usage, err := store.LoadBlockUsage(orgId)
+		if !assert.Nil(t, err) || !assert.Equal(t, int64(0), usage) {
+			return
+		}
+
+		if !assert.Nil(t, store.SaveBlocks(
+			secret.Block{OrgId: orgId, StreamId: streamId, Idx: 0, Data: crypto.CipherText{Data: []byte("block0")}},
+			secret.Block{OrgId: orgId, StreamId: streamId, Idx: 1, Data: crypto.CipherText{Data: []byte("block1")}})) {
+			return
+		}
+
+		usage, err = store.LoadBlockUsage(orgId)
+		assert.Nil(t, err)
+		assert.True(t, usage >= 12)
+
+		other, err := store.LoadBlockUsage(uuid.NewV1())
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), other)
+	})
+
+	t.Run("BlockUsage_Purge", func(t *testing.T) {
+		orgId, oldStream := uuid.NewV1(), uuid.NewV1()
+
+		old := secret.NewSecret().
+			SetOrg(orgId).
+			SetStream(oldStream, 1).
+			SetName("/purge").
+			SetDeleted(true).
+			MustCompile()
+		if !assert.Nil(t, store.SaveSecret(old)) {
+			return
+		}
+
+		if !assert.Nil(t, store.SaveBlocks(
+			secret.Block{OrgId: orgId, StreamId: oldStream, Data: crypto.CipherText{Data: []byte("block")}})) {
+			return
+		}
+
+		usage, err := store.LoadBlockUsage(orgId)
+		if !assert.Nil(t, err) || !assert.True(t, usage > 0) {
+			return
+		}
+
+		// Recreating the secret purges the blocks of the old one.
+		if !assert.Nil(t, store.SaveSecret(secret.NewSecret().
+			SetOrg(orgId).
+			SetStream(uuid.NewV1(), 0).
+			SetName("/purge").
+			MustCompile())) {
+			return
+		}
+
+		usage, err = store.LoadBlockUsage(orgId)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), usage)
+	})
+
+	t.Run("BlockUsage_Unrecorded", func(t *testing.T) {
+		orgId := uuid.NewV1()
+
+		block := func() secret.Block {
+			return secret.Block{OrgId: orgId, StreamId: uuid.NewV1(), Data: crypto.CipherText{Data: []byte("block")}}
+		}
+
+		if !assert.Nil(t, store.SaveBlocks(block())) {
+			return
+		}
+
+		size, err := store.LoadBlockUsage(orgId)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		// Blocks saved before usage was recorded are counted once.
+		if !assert.Nil(t, db.Do(sql.Exec(sql.Raw(`delete from secret_block_usage where org_id = $1`, orgId)))) {
+			return
+		}
+
+		usage, err := store.LoadBlockUsage(orgId)
+		if !assert.Nil(t, err) || !assert.Equal(t, size, usage) {
+			return
+		}
+
+		if !assert.Nil(t, store.SaveBlocksWithin(2*size, block())) {
+			return
+		}
+
+		usage, err = store.LoadBlockUsage(orgId)
+		assert.Nil(t, err)
+		assert.Equal(t, 2*size, usage)
+
+		err = store.SaveBlocksWithin(2*size, block())
+		assert.True(t, errs.Is(err, secret.ErrQuota), "%v", err)
+	})
+
+	t.Run("BlockQuota_Concurrent", func(t *testing.T) {
+		orgId := uuid.NewV1()
+
+		block := func() secret.Block {
+			return secret.Block{OrgId: orgId, StreamId: uuid.NewV1(), Data: crypto.CipherText{Data: []byte("block")}}
+		}
+
+		if !assert.Nil(t, store.SaveBlocksWithin(0, block())) {
+			return
+		}
+
+		size, err := store.LoadBlockUsage(orgId)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		// Room for three blocks in all.
+		done := make(chan error, 8)
+		for i := 0; i < cap(done); i++ {
+			go func() {
+				done <- store.SaveBlocksWithin(3*size, block())
+			}()
+		}
+
+		var saved int
+		for i := 0; i < cap(done); i++ {
+			err := <-done
+			if err == nil {
+				saved++
+				continue
+			}
+			assert.True(t, errs.Is(err, secret.ErrQuota), "%v", err)
+		}
+		assert.Equal(t, 2, saved)
+
+		usage, err := store.LoadBlockUsage(orgId)
+		assert.Nil(t, err)
+		assert.Equal(t, 3*size, usage)
 	})
 
 	t.Run("LoadSecrets_EmptyFilter", func(t *testing.T) {
@@ -63,6 +217,107 @@ func TestSecretStorage(t *testing.T) {
 		}
 	})
 
+	t.Run("ListEvents", func(t *testing.T) {
+		act, err := store.ListEvents(sec.OrgId, "", 0, page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(act)) {
+			return
+		}
+		assert.Equal(t, 1, act[0].Seq)
+		assert.Equal(t, secret.SecretEvent, act[0].Type)
+		assert.Equal(t, sec.Id, act[0].SecretId)
+		assert.Equal(t, sec.Name, act[0].Name)
+	})
+
+	t.Run("SaveEvents", func(t *testing.T) {
+		if !assert.Nil(t, store.SaveEvents(
+			secret.NewAccessEvent(sec, uuid.NewV1()),
+			secret.NewAccessEvent(sec, uuid.NewV1()))) {
+			return
+		}
+
+		act, err := store.ListEvents(sec.OrgId, "", 1, page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 2, len(act)) {
+			return
+		}
+		assert.Equal(t, 2, act[0].Seq)
+		assert.Equal(t, 3, act[1].Seq)
+		assert.Equal(t, secret.AccessEvent, act[1].Type)
+	})
+
+	t.Run("SaveEvents_Concurrent", func(t *testing.T) {
+		done := make(chan error, 8)
+		for i := 0; i < cap(done); i++ {
+			go func() {
+				done <- store.SaveEvents(secret.NewAccessEvent(sec, uuid.NewV1()))
+			}()
+		}
+		for i := 0; i < cap(done); i++ {
+			assert.Nil(t, <-done)
+		}
+
+		act, err := store.ListEvents(sec.OrgId, "", 3, page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 8, len(act)) {
+			return
+		}
+		for i, e := range act {
+			assert.Equal(t, 4+i, e.Seq)
+		}
+	})
+
+	t.Run("SaveEvents_NoCounter", func(t *testing.T) {
+		orgId := uuid.NewV1()
+
+		// Feeds recorded before the counter continue from their latest event.
+		legacy := secret.NewAccessEvent(sec, uuid.NewV1())
+		legacy.OrgId, legacy.Seq = orgId, 50
+		if !assert.Nil(t, db.Do(sql.Exec(SchemaEvent.Insert(legacy)))) {
+			return
+		}
+
+		next := secret.NewAccessEvent(sec, uuid.NewV1())
+		next.OrgId = orgId
+		if !assert.Nil(t, store.SaveEvents(next)) {
+			return
+		}
+
+		act, err := store.ListEvents(orgId, "", 50, page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(act)) {
+			return
+		}
+		assert.Equal(t, 51, act[0].Seq)
+	})
+
+	t.Run("ListEvents_Prefix", func(t *testing.T) {
+		act, err := store.ListEvents(sec.OrgId, "/other", 0, page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 0, len(act))
+	})
+
+	t.Run("SaveSecret_Rotation", func(t *testing.T) {
+		rot := secret.Rotation{
+			Rotator:  secret.PasswordRotator,
+			Interval: 24 * time.Hour,
+			Depends:  []string{"/other"},
+			Last:     time.Now().UTC().Truncate(time.Second),
+		}
+
+		next, err := sec.Update().SetRotation(rot).Compile()
+		if !assert.Nil(t, err) || !assert.Nil(t, store.SaveSecret(next)) {
+			return
+		}
+
+		act, ok, err := store.LoadSecretByName(sec.OrgId, sec.Name, -1)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, rot.Rotator, act.Rotation.Rotator)
+		assert.Equal(t, rot.Interval, act.Rotation.Interval)
+		assert.Equal(t, rot.Depends, act.Rotation.Depends)
+		assert.True(t, rot.Last.Equal(act.Rotation.Last))
+	})
+
 	// o.Run("LoadSecrets_Limit0", func(t *testing.T) {
 	// limit := uint64(0)
 	// act, err := store.LoadSecrets(secret.OrgId, secret.Filter{}, page.BuildPage(func(o *page.Page) {
@@ -131,3 +386,142 @@ func TestSecretStorage(t *testing.T) {
 	// assert.Equal(t, block1, act[1])
 	// })
 }
+
+func TestFolderStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	orgId := uuid.NewV1()
+
+	parent, err := secret.NewFolder(orgId, uuid.NewV1(), "/payments/")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "/payments", parent.Path)
+
+	child, err := secret.NewFolder(orgId, uuid.NewV1(), "/payments/eu")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if !assert.Nil(t, store.SaveFolder(parent)) || !assert.Nil(t, store.SaveFolder(child)) {
+		return
+	}
+
+	t.Run("Duplicate", func(t *testing.T) {
+		dup, err := secret.NewFolder(orgId, uuid.NewV1(), "/payments")
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.NotNil(t, store.SaveFolder(dup))
+	})
+
+	t.Run("Ancestors", func(t *testing.T) {
+		all, err := store.ListFolders(orgId,
+			secret.BuildFolderFilter(
+				secret.FilterFolderPaths(secret.FolderPaths("/payments/eu/stripe.key")...)),
+			page.Page{})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []secret.Folder{parent, child}, all)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if !assert.Nil(t, store.SaveFolder(child.Delete())) {
+			return
+		}
+
+		all, err := store.ListFolders(orgId, secret.BuildFolderFilter(secret.FilterFolderPrefix("/payments")), page.Page{})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []secret.Folder{parent}, all)
+	})
+
+	t.Run("Prefix_Wildcards", func(t *testing.T) {
+		other, err := secret.NewFolder(orgId, uuid.NewV1(), "/pay_ments")
+		if !assert.Nil(t, err) || !assert.Nil(t, store.SaveFolder(other)) {
+			return
+		}
+
+		all, err := store.ListFolders(orgId, secret.BuildFolderFilter(secret.FilterFolderPrefix("/pay_")), page.Page{})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []secret.Folder{other}, all)
+	})
+}
+
+func TestSecretStorage_Upgrade(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	// The table as it was before secrets could be rotated.
+	old := sql.NewSchema(SchemaSecret.Name, 0).
+		WithColumns(withoutColumn(SchemaSecret.Columns, "rotation")...).
+		WithIndices(SchemaSecret.Indices...).
+		Build()
+
+	registry := sql.NewSchemaRegistry("iron")
+	if !assert.Nil(t, sql.InitSchemas(db, registry, old)) {
+		return
+	}
+
+	store, err := NewSqlStore(db, registry)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, registry.Check(db))
+
+	sec := secret.NewSecret().
+		SetOrg(uuid.NewV1()).
+		SetStream(uuid.NewV1(), 0).
+		SetName("/name").
+		MustCompile()
+	if !assert.Nil(t, store.SaveSecret(sec)) {
+		return
+	}
+
+	// Rows written before the upgrade have no rotation.
+	if !assert.Nil(t, db.Do(sql.Exec(setNull(SchemaSecret.Name, "rotation")))) {
+		return
+	}
+
+	act, ok, err := store.LoadSecretByName(sec.OrgId, sec.Name, -1)
+	if !assert.Nil(t, err) || !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, sec.Id, act.Id)
+	assert.Equal(t, secret.Rotation{}, act.Rotation)
+}
+
+func withoutColumn(all []sql.Column, name string) (ret []sql.Column) {
+	for _, c := range all {
+		if c.Name != name {
+			ret = append(ret, c)
+		}
+	}
+	return
+}
+
+func setNull(table, col string) sql.Query {
+	return sql.QueryFn(func(sql.Dialect) (string, []interface{}, error) {
+		return fmt.Sprintf("update %v set %v = null", table, col), nil, nil
+	})
+}
@@ -0,0 +1,134 @@
+package sqlwebhook
+
+import (
+	"fmt"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	SchemaWebhook = sql.NewSchema("webhook", 0).
+		WithStruct(webhook.Webhook{}).
+		WithIndices(
+			sql.NewUniqueIndex("webhook_by_id", "org_id", "id", "version")).
+		Build()
+)
+
+var (
+	SchemaDelivery = sql.NewSchema("webhook_delivery", 0).
+		WithStruct(webhook.Delivery{}).
+		WithIndices(
+			sql.NewUniqueIndex("webhook_delivery_by_id", "org_id", "id", "attempt"),
+			sql.NewIndex("webhook_delivery_by_hook", "org_id", "hook_id", "created")).
+		Build()
+)
+
+type SqlStore struct {
+	db sql.Driver
+}
+
+func NewSqlStore(db sql.Driver, schemas sql.SchemaRegistry) (webhook.Storage, error) {
+	if err := sql.InitSchemas(db, schemas, SchemaWebhook, SchemaDelivery); err != nil {
+		return nil, err
+	}
+	return &SqlStore{db}, nil
+}
+
+// Returns the store, logging with the logger.
+func (s *SqlStore) WithLogger(l context.Logger) interface{} {
+	return &SqlStore{sql.WithLogger(s.db, l)}
+}
+
+func (s *SqlStore) SaveWebhook(w webhook.Webhook) (err error) {
+	err = s.db.Do(sql.Exec(SchemaWebhook.Insert(w)))
+	return
+}
+
+func (s *SqlStore) LoadWebhook(orgId, hookId uuid.UUID) (ret webhook.Webhook, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaWebhook.SelectAs("w").
+				Where("w.org_id = ?", orgId).
+				Where("w.id = ?", hookId).
+				Where(latestWebhook("w")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListWebhooks(orgId uuid.UUID, page page.Page) (ret []webhook.Webhook, err error) {
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaWebhook.SelectAs("w").
+				Where("w.org_id = ?", orgId).
+				Where("not w.deleted").
+				Where(latestWebhook("w")).
+				OrderBy("w.created asc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func (s *SqlStore) SaveDelivery(d webhook.Delivery) (err error) {
+	err = s.db.Do(sql.Exec(SchemaDelivery.Insert(d)))
+	return
+}
+
+func (s *SqlStore) LoadDelivery(orgId, deliveryId uuid.UUID) (ret webhook.Delivery, ok bool, err error) {
+	err = s.db.Do(
+		sql.QueryOne(
+			SchemaDelivery.SelectAs("d").
+				Where("d.org_id = ?", orgId).
+				Where("d.id = ?", deliveryId).
+				Where(latestDelivery("d")),
+			sql.Struct(&ret),
+			&ok))
+	return
+}
+
+func (s *SqlStore) ListDeliveries(orgId, hookId uuid.UUID, page page.Page) (ret []webhook.Delivery, err error) {
+	err = s.db.Do(
+		sql.QueryPage(
+			SchemaDelivery.SelectAs("d").
+				Where("d.org_id = ?", orgId).
+				Where("d.hook_id = ?", hookId).
+				Where(latestDelivery("d")).
+				OrderBy("d.created desc"),
+			sql.Slice(&ret, sql.Struct),
+			sql.LimitPtr(page.Limit),
+			sql.OffsetPtr(page.Offset)))
+	return
+}
+
+func latestWebhook(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				webhook as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.version > %v.version
+		)`, alias, alias, alias)
+}
+
+func latestDelivery(alias string) string {
+	return fmt.Sprintf(`
+		not exists (
+			select
+				1
+			from
+				webhook_delivery as o
+			where
+				o.org_id = %v.org_id
+				and o.id = %v.id
+				and o.attempt > %v.attempt
+		)`, alias, alias, alias)
+}
@@ -0,0 +1,86 @@
+package sqlwebhook
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cott-io/stash/lang/context"
+	"github.com/cott-io/stash/lang/sql"
+	"github.com/cott-io/stash/libs/page"
+	"github.com/cott-io/stash/libs/webhook"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookStorage(t *testing.T) {
+	ctx := context.NewContext(os.Stdout, context.Debug)
+	defer ctx.Close()
+
+	db, e := sql.NewSqlLiteDialer().Embed(ctx)
+	if !assert.Nil(t, e) {
+		return
+	}
+
+	store, err := NewSqlStore(db, sql.NewSchemaRegistry("iron"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	orgId := uuid.NewV1()
+
+	hook, err := webhook.NewWebhook(orgId, "https://example.com/hook", webhook.HMAC, webhook.SecretCreate, webhook.Login)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	t.Run("SaveWebhook", func(t *testing.T) {
+		assert.Nil(t, store.SaveWebhook(hook))
+	})
+
+	t.Run("LoadWebhook", func(t *testing.T) {
+		act, ok, err := store.LoadWebhook(orgId, hook.Id)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, hook.Url, act.Url)
+		assert.Equal(t, hook.Events, act.Events)
+		assert.Equal(t, hook.Secret, act.Secret)
+	})
+
+	delivery := webhook.NewDelivery(hook, webhook.NewEvent(orgId, webhook.Login, uuid.NewV1(), "user://a"))
+
+	t.Run("SaveDelivery", func(t *testing.T) {
+		assert.Nil(t, store.SaveDelivery(delivery))
+		assert.Nil(t, store.SaveDelivery(delivery.Next()))
+	})
+
+	t.Run("LoadDelivery", func(t *testing.T) {
+		act, ok, err := store.LoadDelivery(orgId, delivery.Id)
+		if !assert.Nil(t, err) || !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 1, act.Attempt)
+		assert.Equal(t, delivery.Event.Id, act.Event.Id)
+		assert.Equal(t, webhook.Login, act.Event.Type)
+	})
+
+	t.Run("ListDeliveries", func(t *testing.T) {
+		act, err := store.ListDeliveries(orgId, hook.Id, page.BuildPage())
+		if !assert.Nil(t, err) || !assert.Equal(t, 1, len(act)) {
+			return
+		}
+		assert.Equal(t, 1, act[0].Attempt)
+	})
+
+	t.Run("DeleteWebhook", func(t *testing.T) {
+		if !assert.Nil(t, store.SaveWebhook(hook.Update(webhook.WebhookDelete))) {
+			return
+		}
+
+		act, err := store.ListWebhooks(orgId, page.BuildPage())
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 0, len(act))
+	})
+}